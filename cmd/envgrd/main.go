@@ -1,306 +1,1683 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sync"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/charset"
 	"github.com/jenian/envgrd/internal/config"
 	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/lsp"
+	"github.com/jenian/envgrd/internal/notify"
 	"github.com/jenian/envgrd/internal/output"
-	"github.com/jenian/envgrd/internal/parser"
-	"github.com/jenian/envgrd/internal/scanner"
+	"github.com/jenian/envgrd/internal/triage"
+	"github.com/jenian/envgrd/pkg/envgrd"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
 )
 
 // Version is set at build time via -ldflags
 var Version = "dev"
 
-// envVarData holds processed environment variable data
-type envVarData struct {
-	envVars              map[string]string // All env vars (from files + exported)
-	envVarsFromFilesOnly map[string]string // Only vars from .env files (for unused check)
-	relEnvKeySources     map[string]string // Relative paths to source files
+var (
+	rootCmd = &cobra.Command{
+		Use:               "envgrd",
+		Short:             "Scan codebase for environment variable usages",
+		Long:              "A CLI tool that scans codebases for environment variable usages and compares them with .env files.",
+		PersistentPreRunE: loadPlugins,
+	}
+
+	scanCmd = &cobra.Command{
+		Use:   "scan [path]",
+		Short: "Scan a codebase for environment variable usages",
+		Long:  "Recursively scan a directory for environment variable usages and compare with .env files.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runScan,
+	}
+
+	initSchemaCmd = &cobra.Command{
+		Use:   "init-schema",
+		Short: "Print a schema template",
+		Long:  "Print a template of .envgrd.schema.json, which declares the environment variables of the project.",
+		RunE:  runInitSchema,
+	}
+
+	initConfigCmd = &cobra.Command{
+		Use:   "init-config",
+		Short: "Create a .envgrd.config file in the current directory",
+		Long:  "Creates a .envgrd.config file with default configuration in the current directory.",
+		RunE:  runInitConfig,
+	}
+
+	lspCmd = &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a Language Server Protocol server over stdio",
+		Long:  "Run envgrd as a Language Server Protocol server on stdin/stdout, publishing diagnostics for missing, unused, and dynamic environment variables, with hover and go-to-definition support.",
+		Args:  cobra.NoArgs,
+		RunE:  runLSP,
+	}
+
+	graphCmd = &cobra.Command{
+		Use:   "graph [path]",
+		Short: "Export the dependency graph of env files, variables and code",
+		Long:  "Scan a directory and print a graph linking the env files defining each variable to the source files (or top-level directories) reading it, in Graphviz DOT or Mermaid format.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runGraph,
+	}
+
+	generateCmd = &cobra.Command{
+		Use:   "generate [path]",
+		Short: "Generate a typed accessor module for the variables the code reads",
+		Long:  "Scan a directory and print a typed accessor module for the environment variables the code reads and the config requires: TypeScript ProcessEnv declarations with a validated env object, a Go struct with envconfig tags, or a pydantic Settings class.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runGenerate,
+	}
+
+	listCmd = &cobra.Command{
+		Use:   "list [path]",
+		Short: "List the environment variable usages found in code",
+		Long:  "Scan a directory and print every environment variable usage found in code. With --json, the usages are written as a usages file that envgrd scan --usages-file analyzes without parsing the code again, e.g. in a later CI job. --ndjson writes the same file one usage per line as files are parsed, keeping memory flat on very large repositories.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runList,
+	}
+
+	servicesCmd = &cobra.Command{
+		Use:   "services [path]",
+		Short: "Report the variables each service reads but its own environment doesn't define",
+		Long:  "Scan a directory and check the code of each deployable unit (a docker-compose service built from a directory of the scan, or an entry of the services config mapping code directories to manifests such as Kubernetes ConfigMaps) against its own environment only, listing per service the variables its manifests are missing. Exits with code 2 when a service misses variables.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runServices,
+	}
+
+	validateCmd = &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Validate env file values against the schema and the formats names imply",
+		Long:  "Check the values of the env files a scan of the directory (default: current directory) loads, without parsing code: against the type, allowed values and format (url, host:port, port, path, base64, uuid) the schema or the comments above its key in env files (# type: int) declare for each variable, and against the format its name implies (url for *_URL and *_URI, port for *_PORT, uuid for *_UUID). Example files, empty values and values with unresolved references are skipped. Exits with code 1 when a value is invalid.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runValidate,
+	}
+
+	explainCmd = &cobra.Command{
+		Use:   "explain <VAR> [path]",
+		Short: "Show what a scan knows about a variable",
+		Long:  "Scan a directory (default: current directory) and print what it knows about one variable: its description, type, format and whether it's required, as declared in the schema or in comments above its key in env files (# description: ..., # type: int, # required), the env files defining it with their redacted values, the code reading it and the findings reporting it.",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE:  runExplain,
+	}
+
+	mergeCmd = &cobra.Command{
+		Use:   "merge <report.json>...",
+		Short: "Merge the JSON reports of separate scans",
+		Long:  "Combine the JSON reports of separate envgrd scan --json runs (e.g., per-language shards or per-service scans in parallel CI jobs) into one report, deduplicating findings. A variable is only reported as unused when no report defining it reads it.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runMerge,
+	}
+
+	convertCmd = &cobra.Command{
+		Use:   "convert",
+		Short: "Convert an env file into another format",
+		Long:  "Convert an env file (or any other supported source, e.g. a docker-compose file or a ConfigMap) into a Kubernetes ConfigMap, the environment of a docker-compose service, Helm values, a .env file or a shell export script. Deployment formats keep secret-looking variables in a Secret (or out of the file), and comments above a variable are kept.",
+		Args:  cobra.NoArgs,
+		RunE:  runConvert,
+	}
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Work with the .envgrd.config file",
+	}
+
+	configCheckCmd = &cobra.Command{
+		Use:   "check [path]",
+		Short: "Validate the .envgrd.config file",
+		Long:  "Report syntax errors, unknown keys, values of the wrong type and invalid globs or regular expressions in the config file of a directory (default: current directory), with line numbers. Exits with code 1 when problems are found.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runConfigCheck,
+	}
+
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the parse cache",
+		Long:  "Manage the cache of extracted environment variable usages, keyed by file content hash.",
+	}
+
+	cacheClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached parse results",
+		Args:  cobra.NoArgs,
+		RunE:  runCacheClear,
+	}
+
+	doctorCmd = &cobra.Command{
+		Use:   "doctor [path]",
+		Short: "Check the installation and show what a scan would use",
+		Long:  "Check that the grammar of every language loads and that the config file of the directory (default: current directory) is valid, list the env files a scan would load in precedence order, and print whether reports are colored and the settings the environment and the config apply. Exits with code 1 when a check fails. Attach its output when reporting a problem.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runDoctor,
+	}
+
+	cacheEnvCmd = &cobra.Command{
+		Use:   "cache-env [path]",
+		Short: "Snapshot the env definitions for fast single-file scans",
+		Long:  "Parse the env files a scan of the directory (default: current directory) loads and store their definitions in the cache, so scan --stdin (e.g., an editor checking a buffer on every keystroke) reads them from the snapshot instead of parsing every env file again. Scans refresh the snapshot themselves when an env file changes; run this when opening a project to warm it.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runCacheEnv,
+	}
+
+	versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print the version number",
+		Long:  "Print the version number of envgrd",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(Version)
+		},
+	}
+
+	// Flags
+	scanPath     string
+	envFile      string
+	ignoreEnv    []string
+	jsonOutput   bool
+	ndjsonOutput bool
+	plugins      []string
+	langs        []string
+	outputFormat string
+	silent       bool
+	skipUnused   bool
+	debug        bool
+	noHeader     bool
+	quiet        bool
+	notifyURL    string
+	notifyFormat string
+	notifyOn     string
+	noColor      bool
+	noDynamic    bool
+	minConf      string
+	owner        string
+	service      string
+	schemaFile   string
+	groupBy      string
+	blameUnused  bool
+	includeGlobs []string
+	excludeGlobs []string
+	scanTimeout  time.Duration
+	failOn       []string
+	cacheDir     string
+	noCache      bool
+	concurrency  int
+	maxFileSize  string
+	followLinks  bool
+	maxDepth     int
+	showStats    bool
+	sinceLastRun bool
+	stateFile    string
+	strictParse  bool
+	interactive  bool
+	onlyFilter   []string
+	keyFilter    []string
+	excludeKeys  []string
+	inFiles      []string
+	maxLocations int
+	showAll      bool
+	showValues   string
+	wide         bool
+	verbosity    int
+	logFormat    string
+	configFile   string
+	profile      string
+	graphFormat  string
+	consumers    string
+	target       string
+	goPackage    string
+	convertFrom  string
+	convertTo    string
+	convertName  string
+	usagesFile   string
+	stdinSource  bool
+	listFiles    bool
+	stdinName    string
+	compareTo    string
+	failNewOnly  bool
+	caseFold     bool
+	pathSep      string
+	parseTimeout time.Duration
+	reposFile    string
+	policyRef    string
+	policySum    string
+)
+
+func init() {
+	scanCmd.Flags().StringVarP(&scanPath, "path", "p", ".", "Path to scan (default: current directory)")
+	scanCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	scanCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	scanCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output results in JSON format")
+	scanCmd.Flags().StringVar(&outputFormat, "format", "", "Output format: text, json, badge (shields.io endpoint JSON), or exec:<command> (pipes the JSON report into command)")
+	scanCmd.Flags().BoolVar(&silent, "silent", false, "Silent mode (exit code only)")
+	scanCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print findings: no header, progress or warnings (errors are still reported on stderr)")
+	scanCmd.Flags().StringVar(&notifyURL, "notify-webhook", "", "POST a summary of the findings to this URL when they reach the --notify-on severity")
+	scanCmd.Flags().StringVar(&notifyFormat, "notify-format", "json", "Webhook payload: json (summary) or slack (incoming-webhook message)")
+	scanCmd.Flags().StringVar(&notifyOn, "notify-on", "error", "Least severe finding that triggers the webhook: error, warning or info")
+	scanCmd.Flags().BoolVar(&skipUnused, "skip-unused", false, "Skip reporting unused variables")
+	scanCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	scanCmd.Flags().BoolVar(&noHeader, "no-header", false, "Skip printing the header")
+	scanCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colors in text output (also disabled by NO_COLOR or when stdout is not a terminal)")
+	scanCmd.Flags().BoolVar(&noDynamic, "no-dynamic", false, "Disable dynamic pattern detection (skip partial matches from runtime-evaluated expressions)")
+	scanCmd.Flags().BoolVar(&caseFold, "case-insensitive", runtime.GOOS == "windows", "Match variable names regardless of case, as Windows does, and report case-only mismatches (default on Windows)")
+	scanCmd.Flags().StringVar(&minConf, "min-confidence", "low", "Only report dynamic patterns with at least this confidence: high, medium or low")
+	scanCmd.Flags().StringVar(&owner, "owner", "", "Only report findings in files owned by this CODEOWNERS owner (e.g., @org/backend)")
+	scanCmd.Flags().StringVar(&service, "service", "", "Only check the code of this service (docker-compose service or services config entry) against its own environment")
+	scanCmd.Flags().StringVar(&schemaFile, "schema", "", "Schema file declaring the variables, relative to the path (default: the config's schema, else .envgrd.schema.json)")
+	scanCmd.Flags().StringSliceVar(&onlyFilter, "only", []string{}, "Only report these finding categories: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference, forbidden")
+	scanCmd.Flags().StringSliceVar(&keyFilter, "key", []string{}, "Only report variables matching these names, globs (e.g., 'STRIPE_*') or /regular expressions/")
+	scanCmd.Flags().StringSliceVar(&excludeKeys, "exclude-key", []string{}, "Don't report variables matching these names, globs or /regular expressions/")
+	scanCmd.Flags().StringSliceVar(&inFiles, "in-file", []string{}, "Only report usages and definitions in files matching these patterns (e.g., 'src/payments/**')")
+	scanCmd.Flags().IntVar(&maxLocations, "max-locations", 5, "List at most this many locations per finding and count the rest (0 = all)")
+	scanCmd.Flags().BoolVar(&showAll, "show-all", false, "List every location of each finding (same as --max-locations 0)")
+	scanCmd.Flags().StringVar(&pathSep, "path-separator", "native", "Separator of reported file paths: native, slash (identical reports on every OS) or backslash")
+	scanCmd.Flags().BoolVar(&wide, "wide", false, "Don't cut code snippets to the terminal width (useful when piping into files)")
+	scanCmd.Flags().StringVar(&showValues, "show-values", "redacted", "How env file values are shown: never, redacted (hide secrets, mask the rest) or full")
+	scanCmd.Flags().StringVar(&groupBy, "group-by", "", "Also list findings grouped by: owner (CODEOWNERS)")
+	scanCmd.Flags().BoolVar(&blameUnused, "blame", false, "Run git blame on env files to show when and by whom each unused variable was last modified")
+	scanCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
+	scanCmd.Flags().StringSliceVar(&langs, "lang", nil, "Languages to scan (e.g., go,typescript), all by default")
+	scanCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+	scanCmd.Flags().StringSliceVar(&failOn, "fail-on", []string{}, "Finding categories that fail the run: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference, forbidden, any, none (default any)")
+	scanCmd.Flags().IntVar(&concurrency, "concurrency", 0, "Number of files to parse in parallel (default: number of CPUs)")
+	scanCmd.Flags().BoolVar(&followLinks, "follow-symlinks", false, "Follow symlinked files and directories (symlink cycles are detected)")
+	scanCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Only scan files up to this many levels below the path (1 = top level only, 0 = unlimited)")
+	scanCmd.Flags().StringVar(&maxFileSize, "max-file-size", "5MB", "Skip source files larger than this (e.g. 500KB, 10MB; 0 disables the limit)")
+	scanCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory for the parse cache (default: user cache directory, e.g. ~/.cache/envgrd)")
+	scanCmd.Flags().BoolVar(&noCache, "no-cache", false, "Disable the parse cache and re-parse every file")
+	scanCmd.Flags().BoolVar(&showStats, "stats", false, "Report per-phase timings, the slowest files and peak memory (also included in JSON output)")
+	scanCmd.Flags().BoolVar(&sinceLastRun, "since-last-run", false, "Only report findings that are new since the previous --since-last-run scan, plus the ones that were fixed")
+	scanCmd.Flags().StringVar(&compareTo, "compare-to", "", "Classify findings as new, fixed or unchanged compared to the JSON report of a previous scan (envgrd scan --json)")
+	scanCmd.Flags().BoolVar(&failNewOnly, "fail-on-new-only", false, "Only fail the run on findings that are new compared to --compare-to")
+	scanCmd.Flags().StringVar(&stateFile, "state-file", "", "State file used by --since-last-run (default: .envgrd.state in the scanned path)")
+	scanCmd.Flags().BoolVar(&strictParse, "strict-parse", false, "Fail the run (exit code 5) if any file could not be parsed or analyzed")
+	scanCmd.Flags().BoolVar(&interactive, "interactive", false, "Walk through the findings one by one to ignore them in the config, add them to .env.example or baseline them")
+	scanCmd.Flags().StringVar(&usagesFile, "usages-file", "", "Analyze the usages of this file (written by envgrd list --json, - for stdin) instead of parsing the code")
+	scanCmd.Flags().BoolVar(&stdinSource, "stdin", false, "Parse a single file read from stdin (e.g., an unsaved editor buffer) and only report its findings; needs --stdin-filename")
+	scanCmd.Flags().BoolVar(&listFiles, "list-files", false, "Print the files the scan would parse or leave out, with the reason, and the env files it would load, without parsing anything")
+	scanCmd.Flags().StringVar(&stdinName, "stdin-filename", "", "Path of the file read with --stdin, relative to the current directory; selects its language and the reported file")
+	scanCmd.Flags().DurationVar(&parseTimeout, "parse-timeout", envgrd.DefaultParseTimeout, "Give up on a file whose parsing takes longer and report it as not analyzed (0 disables the limit)")
+	scanCmd.Flags().StringVar(&policyRef, "policy", "", "Enforce a central policy file (https URL or path) over the local configs, which can add to its rules but not weaken them")
+	scanCmd.Flags().StringVar(&policySum, "policy-sha256", "", "SHA-256 checksum pinning the --policy file, required for URLs")
+	scanCmd.Flags().StringVar(&reposFile, "repos", "", "Scan the repositories (paths or git URLs) listed in this YAML file and report them together")
+	scanCmd.Flags().DurationVar(&scanTimeout, "timeout", 0, "Abort the scan after this duration (e.g. 30s, 5m; 0 disables the timeout)")
+
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Graph format: dot or mermaid")
+	graphCmd.Flags().StringVar(&consumers, "consumers", "file", "Group consuming code by file or by top-level directory (dir), e.g. per service")
+	graphCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	graphCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	graphCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
+	graphCmd.Flags().StringSliceVar(&langs, "lang", nil, "Languages to scan (e.g., go,typescript), all by default")
+	graphCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+
+	generateCmd.Flags().StringVar(&target, "target", "", "Accessor module to generate: ts, go or python")
+	generateCmd.Flags().StringVar(&goPackage, "package", "config", "Package name of the generated Go file")
+	generateCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	generateCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	generateCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
+	generateCmd.Flags().StringSliceVar(&langs, "lang", nil, "Languages to scan (e.g., go,typescript), all by default")
+	generateCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+	_ = generateCmd.MarkFlagRequired("target")
+
+	listCmd.Flags().BoolVar(&jsonOutput, "json", false, "Write the usages as a usages file for envgrd scan --usages-file")
+	listCmd.Flags().BoolVar(&ndjsonOutput, "ndjson", false, "Stream the usages as a newline-delimited usages file while files are parsed, for very large repositories")
+	listCmd.Flags().StringVar(&pathSep, "path-separator", "native", "Separator of listed file paths: native, slash (identical lists on every OS) or backslash")
+	listCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	listCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	listCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
+	listCmd.Flags().StringSliceVar(&langs, "lang", nil, "Languages to scan (e.g., go,typescript), all by default")
+	listCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+
+	servicesCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the report in JSON format")
+	servicesCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	servicesCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	servicesCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
+	servicesCmd.Flags().StringSliceVar(&langs, "lang", nil, "Languages to scan (e.g., go,typescript), all by default")
+	servicesCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+
+	validateCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the invalid values in JSON format")
+	validateCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	validateCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	validateCmd.Flags().StringVar(&schemaFile, "schema", "", "Schema file declaring the variables, relative to the path (default: the config's schema, else .envgrd.schema.json)")
+	validateCmd.Flags().StringVar(&showValues, "show-values", "redacted", "How env file values are shown: never, redacted (hide secrets, mask the rest) or full")
+
+	explainCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the explanation in JSON format")
+	explainCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	explainCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	explainCmd.Flags().StringVar(&schemaFile, "schema", "", "Schema file declaring the variables, relative to the path (default: the config's schema, else .envgrd.schema.json)")
+	explainCmd.Flags().StringVar(&showValues, "show-values", "redacted", "How env file values are shown: never, redacted (hide secrets, mask the rest) or full")
+	explainCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
+	explainCmd.Flags().StringSliceVar(&langs, "lang", nil, "Languages to scan (e.g., go,typescript), all by default")
+	explainCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+
+	mergeCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the merged report in JSON format")
+	mergeCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colors in text output (also disabled by NO_COLOR or when stdout is not a terminal)")
+	mergeCmd.Flags().StringSliceVar(&failOn, "fail-on", []string{}, "Finding categories of the merged report that fail the run: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference, forbidden, any, none (default any)")
+
+	convertCmd.Flags().StringVar(&convertFrom, "from", ".env", "Env file to convert")
+	convertCmd.Flags().StringVar(&convertTo, "to", "", "Output: k8s-configmap, compose, helm, env (.env file) or shell (export script)")
+	convertCmd.Flags().StringVar(&convertName, "name", "app", "Name of the ConfigMap (the Secret is named <name>-secret, without a -config suffix) or compose service")
+	_ = convertCmd.MarkFlagRequired("to")
+
+	lspCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging to stderr")
+
+	cacheClearCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory of the parse cache (default: user cache directory, e.g. ~/.cache/envgrd)")
+	cacheEnvCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory of the cache (default: user cache directory, e.g. ~/.cache/envgrd)")
+	cacheEnvCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	cacheEnvCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	doctorCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
+	doctorCmd.Flags().StringSliceVar(&ignoreEnv, "ignore-env-file", nil, "Env files to skip, as globs (e.g., .env.test), can be repeated")
+	cacheCmd.AddCommand(cacheClearCmd)
+	configCmd.AddCommand(configCheckCmd)
+
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Increase log verbosity on stderr (-v debug, -vv trace)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format for stderr: text or json")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config profile to apply (e.g., ci or local, defined under profiles: in the config)")
+	rootCmd.PersistentFlags().StringSliceVar(&plugins, "plugin", nil, "Language plugin to load: a Go plugin (.so) registering grammars with envgrd.RegisterLanguage, repeatable")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to use instead of searching the scanned directory (.envgrd.config, .envgrd.yaml, package.json, ...)")
+
+	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(initSchemaCmd)
+	rootCmd.AddCommand(initConfigCmd)
+	rootCmd.AddCommand(lspCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(servicesCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(cacheEnvCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(versionCmd)
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	if reposFile != "" {
+		return runScanRepos(cmd, args)
+	}
+
+	// Get scan path
+	path := scanPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	policy, err := loadPolicy(cmd.Flags(), cfg)
+	if err != nil {
+		return err
+	}
+	cfg = cfg.WithPolicy(policy)
+	if err := applySettings(cmd.Flags(), cfg); err != nil {
+		return err
+	}
+
+	opts, err := scanOptions(path, cfg)
+	if err != nil {
+		return err
+	}
+	opts.Policy = policy
+	if maxLocations < 0 {
+		return fmt.Errorf("--max-locations must not be negative, got %d", maxLocations)
+	}
+	if showAll {
+		maxLocations = 0
+	}
+	redactor, err := output.NewRedactor(showValues, cfg.Redaction)
+	if err != nil {
+		return err
+	}
+	if groupBy != "" && groupBy != output.GroupByOwner {
+		return fmt.Errorf("unknown --group-by %q (supported: owner)", groupBy)
+	}
+	if quiet && (verbosity > 0 || debug) {
+		return fmt.Errorf("--quiet can't be combined with --verbose or --debug")
+	}
+	webhook := notify.Webhook{URL: notifyURL, Format: notifyFormat}
+	if err := config.ValidateNotifyFormat(notifyFormat); err != nil {
+		return fmt.Errorf("invalid --notify-format: %w", err)
+	}
+	if webhook.On, err = config.ParseSeverity(notifyOn); err != nil {
+		return fmt.Errorf("invalid --notify-on: %w", err)
+	}
+	if err := validatePathSeparator(); err != nil {
+		return err
+	}
+	if failNewOnly && compareTo == "" {
+		return fmt.Errorf("--fail-on-new-only needs a report to compare to (--compare-to)")
+	}
+	if compareTo != "" && sinceLastRun {
+		return fmt.Errorf("--compare-to can't be combined with --since-last-run")
+	}
+	if interactive && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("--interactive needs a terminal to read answers from")
+	}
+	if listFiles && (usagesFile != "" || stdinSource || interactive) {
+		return fmt.Errorf("--list-files can't be combined with --usages-file, --stdin or --interactive")
+	}
+	if usagesFile != "" {
+		if opts.Usages, err = readUsagesFile(usagesFile); err != nil {
+			return err
+		}
+	}
+	if stdinSource {
+		if opts.SourceFile, opts.Source, err = readStdinSource(); err != nil {
+			return err
+		}
+	} else if stdinName != "" {
+		return fmt.Errorf("--stdin-filename needs --stdin")
+	}
+	if !noCache {
+		// Without a usable cache directory the scan simply runs uncached
+		if dir, err := resolveCacheDir(); err == nil {
+			opts.CacheDir = dir
+			// Single-file scans run on every keystroke, parsing every env file each time would dominate them
+			opts.EnvSnapshot = stdinSource
+		}
+	}
+	if !silent {
+		logger, err := newLogger()
+		if err != nil {
+			return err
+		}
+		opts.Logger = logger
+	}
+	if listFiles {
+		return printScanPlan(opts)
+	}
+
+	format := outputFormat
+	if format == "" && jsonOutput {
+		format = "json"
+	}
+	reporter, err := output.NewReporter(format)
+	if err != nil {
+		return err
+	}
+	if noColor {
+		reporter = withoutColor(reporter)
+	}
+	failPolicy, err := output.ParseFailOn(failOn)
+	if err != nil {
+		return err
+	}
+
+	if interactive && format != "" && format != "text" {
+		return fmt.Errorf("--interactive can't be combined with --format %s", format)
+	}
+
+	// Print header unless disabled or in machine-readable/quiet/silent mode
+	// Machine-readable reports are the only output on stdout, progress and warnings go to stderr
+	if !noHeader && (format == "" || format == "text") && !quiet && !silent {
+		printHeader()
+	}
+
+	// Abort gracefully on Ctrl-C / SIGTERM (e.g., a cancelled CI job) or when the timeout expires
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if scanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, scanTimeout)
+		defer cancel()
+	}
+
+	// OTEL_* variables enable tracing, spans are exported once the scan is done
+	tracer := tracerFromEnv(opts.Logger)
+	result, err := envgrd.Scan(envgrd.WithTracer(ctx, tracer), opts)
+	if flushErr := tracer.Flush(context.Background()); flushErr != nil {
+		logging.OrDiscard(opts.Logger).Warn(flushErr.Error())
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("scan timed out after %s", scanTimeout)
+		}
+		return err
+	}
+
+	if sinceLastRun {
+		result, err = compareWithLastRun(result, logging.OrDiscard(opts.Logger))
+		if err != nil {
+			return err
+		}
+	}
+
+	if compareTo != "" {
+		if result, err = result.CompareTo(compareTo); err != nil {
+			return err
+		}
+	}
+
+	dynamic := !noDynamic
+	if interactive {
+		return triageFindings(result, cfg, skipUnused, dynamic)
+	}
+	if pathSep != envgrd.SeparatorNative {
+		result.ConvertPaths(pathSep)
+	}
+	reportOpts := output.Options{SkipUnused: skipUnused, Dynamic: dynamic, GroupBy: groupBy, MaxLocations: maxLocations, Redactor: redactor, Wide: wide}
+	if !silent {
+		if err := reporter.Report(os.Stdout, result.ScanResult, reportOpts); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+	if webhook.URL != "" {
+		name := path
+		if abs, err := filepath.Abs(path); err == nil {
+			name = filepath.Base(abs)
+		}
+		sent, err := webhook.Notify(ctx, name, result.ScanResult, reportOpts)
+		if err != nil {
+			return fmt.Errorf("failed to send webhook notification: %w", err)
+		}
+		if sent {
+			logging.OrDiscard(opts.Logger).Info("Sent webhook notification")
+		}
+	}
+
+	failing := result
+	if failNewOnly {
+		failing = result.NewOnly()
+	}
+	if code := failing.ExitCode(failPolicy, skipUnused, dynamic); code != output.ExitOK {
+		os.Exit(code)
+	}
+	if strictParse && len(result.ParseErrors) > 0 {
+		os.Exit(output.ExitParseErrors)
+	}
+
+	return nil
+}
+
+// runScanRepos scans the repositories listed in the --repos file, concurrently, and writes their combined report
+// The config next to the repos file (or --config) provides the settings of the command line
+func runScanRepos(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 || cmd.Flags().Changed("path") {
+		return fmt.Errorf("--repos can't be combined with a path to scan")
+	}
+	for _, name := range []string{"stdin", "usages-file", "interactive", "list-files", "since-last-run", "compare-to", "notify-webhook"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--repos can't be combined with --%s", name)
+		}
+	}
+	repos, repoConcurrency, err := envgrd.LoadRepos(reposFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(filepath.Dir(reposFile))
+	if err != nil {
+		return err
+	}
+	policy, err := loadPolicy(cmd.Flags(), cfg)
+	if err != nil {
+		return err
+	}
+	cfg = cfg.WithPolicy(policy)
+	if err := applySettings(cmd.Flags(), cfg); err != nil {
+		return err
+	}
+	opts, err := scanOptions("", cfg)
+	if err != nil {
+		return err
+	}
+	opts.Policy = policy
+	if maxLocations < 0 {
+		return fmt.Errorf("--max-locations must not be negative, got %d", maxLocations)
+	}
+	if showAll {
+		maxLocations = 0
+	}
+	redactor, err := output.NewRedactor(showValues, cfg.Redaction)
+	if err != nil {
+		return err
+	}
+	if groupBy != "" && groupBy != output.GroupByOwner {
+		return fmt.Errorf("unknown --group-by %q (supported: owner)", groupBy)
+	}
+	if err := validatePathSeparator(); err != nil {
+		return err
+	}
+	format := outputFormat
+	if format == "" && jsonOutput {
+		format = "json"
+	}
+	if format != "" && format != "text" && format != "json" {
+		return fmt.Errorf("--repos can't be combined with --format %s (supported: text, json)", format)
+	}
+	failPolicy, err := output.ParseFailOn(failOn)
+	if err != nil {
+		return err
+	}
+	if !noCache {
+		if dir, err := resolveCacheDir(); err == nil {
+			opts.CacheDir = dir
+		}
+	}
+	if !silent {
+		logger, err := newLogger()
+		if err != nil {
+			return err
+		}
+		opts.Logger = logger
+	}
+	if !noHeader && format != "json" && !quiet && !silent {
+		printHeader()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if scanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, scanTimeout)
+		defer cancel()
+	}
+
+	dynamic := !noDynamic
+	parseErrors := false
+	reports := make([]output.RepoReport, 0, len(repos))
+	for _, scanned := range envgrd.ScanRepos(ctx, repos, opts, repoConcurrency) {
+		report := output.RepoReport{Name: scanned.Repo.Name, Source: scanned.Repo.Source(), Err: scanned.Err}
+		if scanned.Err != nil {
+			if errors.Is(scanned.Err, context.DeadlineExceeded) {
+				report.Err = fmt.Errorf("scan timed out after %s", scanTimeout)
+			}
+			reports = append(reports, report)
+			continue
+		}
+		if pathSep != envgrd.SeparatorNative {
+			scanned.Result.ConvertPaths(pathSep)
+		}
+		report.Result = &scanned.Result.ScanResult
+		report.ExitCode = scanned.Result.ExitCode(failPolicy, skipUnused, dynamic)
+		parseErrors = parseErrors || len(scanned.Result.ParseErrors) > 0
+		reports = append(reports, report)
+	}
+
+	if !silent {
+		text := output.NewTextReporter()
+		if noColor {
+			text.Color = false
+		}
+		reportOpts := output.Options{SkipUnused: skipUnused, Dynamic: dynamic, GroupBy: groupBy, MaxLocations: maxLocations, Redactor: redactor, Wide: wide}
+		if err := output.WriteRepos(os.Stdout, reports, format == "json", text, reportOpts); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	}
+	if code := output.ReposExitCode(reports); code != output.ExitOK {
+		os.Exit(code)
+	}
+	if strictParse && parseErrors {
+		os.Exit(output.ExitParseErrors)
+	}
+	return nil
+}
+
+// scanOptions builds the options of envgrd scan from its flags
+func scanOptions(path string, cfg *envgrd.Config) (envgrd.Options, error) {
+	opts := envgrd.Options{
+		Path:            path,
+		IncludeGlobs:    includeGlobs,
+		Languages:       langs,
+		ExcludeGlobs:    excludeGlobs,
+		Concurrency:     concurrency,
+		FollowSymlinks:  followLinks,
+		MaxDepth:        maxDepth,
+		Stats:           showStats,
+		Owner:           owner,
+		Service:         service,
+		Schema:          schemaFile,
+		Only:            onlyFilter,
+		Keys:            keyFilter,
+		ExcludeKeys:     excludeKeys,
+		InFiles:         inFiles,
+		Blame:           blameUnused && !skipUnused,
+		Profile:         profile,
+		CaseInsensitive: caseFold,
+	}
+	if configFile != "" {
+		opts.Config = cfg
+	}
+	if envFile != "" {
+		opts.EnvFiles = []string{envFile}
+	}
+	opts.IgnoreEnvFiles = ignoreEnv
+	maxSize, err := parseByteSize(maxFileSize)
+	if err != nil {
+		return opts, fmt.Errorf("invalid --max-file-size: %w", err)
+	}
+	opts.MaxFileSize = maxSize
+	if maxSize == 0 {
+		opts.MaxFileSize = -1 // No limit
+	}
+	opts.ParseTimeout = parseTimeout
+	if parseTimeout <= 0 {
+		opts.ParseTimeout = -1 // No limit
+	}
+	if maxDepth < 0 {
+		return opts, fmt.Errorf("--max-depth must not be negative, got %d", maxDepth)
+	}
+	if concurrency < 0 {
+		return opts, fmt.Errorf("--concurrency must not be negative, got %d", concurrency)
+	}
+	if opts.MinConfidence, err = envgrd.ParseConfidence(minConf); err != nil {
+		return opts, fmt.Errorf("invalid --min-confidence: %w", err)
+	}
+	return opts, nil
+}
+
+// loadPolicy loads the policy of --policy (or ENVGRD_POLICY), nil without one
+// It's loaded before the other settings are applied, so that the config's settings can't weaken it
+func loadPolicy(flags *pflag.FlagSet, cfg *envgrd.Config) (*envgrd.Policy, error) {
+	if err := applySettings(flags, cfg, "policy", "policy-sha256"); err != nil {
+		return nil, err
+	}
+	if policySum != "" && policyRef == "" {
+		return nil, fmt.Errorf("--policy-sha256 needs a --policy")
+	}
+	if policyRef == "" {
+		return nil, nil
+	}
+	return envgrd.LoadPolicy(policyRef, policySum)
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "include", "exclude", "lang", "env-file", "ignore-env-file"); err != nil {
+		return err
+	}
+	if graphFormat != output.GraphDOT && graphFormat != output.GraphMermaid {
+		return fmt.Errorf("unknown --format %q (supported: dot, mermaid)", graphFormat)
+	}
+	if consumers != output.ConsumersFile && consumers != output.ConsumersDir {
+		return fmt.Errorf("unknown --consumers %q (supported: file, dir)", consumers)
+	}
+
+	result, err := scanForExport(path, cfg)
+	if err != nil {
+		return err
+	}
+	return output.WriteGraph(os.Stdout, output.BuildGraph(result.ScanResult, consumers), graphFormat)
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "include", "exclude", "lang", "env-file", "ignore-env-file"); err != nil {
+		return err
+	}
+	if target != output.AccessorsTypeScript && target != output.AccessorsGo && target != output.AccessorsPython {
+		return fmt.Errorf("unknown --target %q (supported: ts, go, python)", target)
+	}
+
+	result, err := scanForExport(path, cfg)
+	if err != nil {
+		return err
+	}
+	return output.WriteAccessors(os.Stdout, output.BuildAccessors(result.ScanResult), target, goPackage)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "include", "exclude", "lang", "env-file", "ignore-env-file", "path-separator"); err != nil {
+		return err
+	}
+	if err := validatePathSeparator(); err != nil {
+		return err
+	}
+	if ndjsonOutput {
+		if jsonOutput {
+			return fmt.Errorf("--ndjson can't be combined with --json")
+		}
+		return streamUsages(path, cfg)
+	}
+
+	result, err := scanForExport(path, cfg)
+	if err != nil {
+		return err
+	}
+	if pathSep != envgrd.SeparatorNative {
+		result.ConvertPaths(pathSep)
+	}
+	usages := result.CodeKeys
+	sortUsages(usages)
+	if jsonOutput {
+		return envgrd.WriteUsages(os.Stdout, usages)
+	}
+	for _, usage := range usages {
+		key := usage.Key
+		if usage.FullExpr != "" {
+			key = usage.FullExpr
+		}
+		fmt.Printf("%s:%d %s\n", usage.File, usage.Line, key)
+	}
+	return nil
+}
+
+func runServices(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "include", "exclude", "lang", "env-file", "ignore-env-file"); err != nil {
+		return err
+	}
+
+	result, err := scanForExport(path, cfg)
+	if err != nil {
+		return err
+	}
+	if err := output.WriteServiceGaps(os.Stdout, result.ServiceGaps, jsonOutput); err != nil {
+		return err
+	}
+	for _, gap := range result.ServiceGaps {
+		if len(gap.Missing) > 0 {
+			os.Exit(output.ExitMissing)
+		}
+	}
+	return nil
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "env-file", "ignore-env-file", "schema", "show-values"); err != nil {
+		return err
+	}
+	redactor, err := output.NewRedactor(showValues, cfg.Redaction)
+	if err != nil {
+		return err
+	}
+
+	opts, err := exportOptions(path, cfg)
+	if err != nil {
+		return err
+	}
+	opts.Schema = schemaFile
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	invalid, err := envgrd.Validate(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := output.WriteInvalidValues(os.Stdout, invalid, jsonOutput, redactor); err != nil {
+		return err
+	}
+	if len(invalid) > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 1 {
+		path = args[1]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "include", "exclude", "lang", "env-file", "ignore-env-file", "schema", "show-values"); err != nil {
+		return err
+	}
+	redactor, err := output.NewRedactor(showValues, cfg.Redaction)
+	if err != nil {
+		return err
+	}
+
+	opts, err := exportOptions(path, cfg)
+	if err != nil {
+		return err
+	}
+	opts.Schema = schemaFile
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := envgrd.Scan(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return output.WriteExplanation(os.Stdout, result.Explain(args[0]), jsonOutput, redactor)
+}
+
+// streamUsages writes the usages of list --ndjson file by file as they are parsed, so that memory stays flat
+// however large the repository; files come in no particular order, the usages of a file sorted by line
+func streamUsages(path string, cfg *envgrd.Config) error {
+	opts, err := exportOptions(path, cfg)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	root, files, err := envgrd.Discover(ctx, opts)
+	if err != nil {
+		return err
+	}
+	stream := envgrd.NewUsagesStream(os.Stdout)
+	err = envgrd.NewEngine(opts).StreamFiles(ctx, files, root, func(file envgrd.FileUsages) error {
+		for i := range file.Usages {
+			file.Usages[i].File = envgrd.ConvertPath(file.Usages[i].File, pathSep)
+		}
+		sortUsages(file.Usages)
+		return stream.Write(file.Usages)
+	})
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("scan aborted: %w", err)
+	}
+	return stream.Close()
 }
 
-var (
-	rootCmd = &cobra.Command{
-		Use:   "envgrd",
-		Short: "Scan codebase for environment variable usages",
-		Long:  "A CLI tool that scans codebases for environment variable usages and compares them with .env files.",
+// sortUsages orders usages by file, line and key
+func sortUsages(usages []envgrd.EnvUsage) {
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].File != usages[j].File {
+			return usages[i].File < usages[j].File
+		}
+		if usages[i].Line != usages[j].Line {
+			return usages[i].Line < usages[j].Line
+		}
+		return usages[i].Key < usages[j].Key
+	})
+}
+
+// validatePathSeparator checks the --path-separator value
+func validatePathSeparator() error {
+	if !slices.Contains(envgrd.PathSeparators, pathSep) {
+		return fmt.Errorf("unknown --path-separator %q (supported: %s)", pathSep, strings.Join(envgrd.PathSeparators, ", "))
 	}
+	return nil
+}
 
-	scanCmd = &cobra.Command{
-		Use:   "scan [path]",
-		Short: "Scan a codebase for environment variable usages",
-		Long:  "Recursively scan a directory for environment variable usages and compare with .env files.",
-		Args:  cobra.MaximumNArgs(1),
-		RunE:  runScan,
+// printScanPlan prints the files and env files a scan with opts would read, see --list-files
+func printScanPlan(opts envgrd.Options) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	plan, err := envgrd.Plan(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for i := range plan.Files {
+		plan.Files[i].Path = envgrd.ConvertPath(plan.Files[i].Path, pathSep)
 	}
 
-	initSchemaCmd = &cobra.Command{
-		Use:   "init-schema",
-		Short: "Generate a schema template (stub for future feature)",
-		Long:  "Generate a JSON schema template for environment variable validation.",
-		RunE:  runInitSchema,
+	if jsonOutput || outputFormat == "json" {
+		type envSource struct {
+			File  string `json:"file"`
+			Kind  string `json:"kind"`
+			Scope string `json:"scope,omitempty"`
+		}
+		report := struct {
+			Root       string                `json:"root"`
+			Files      []envgrd.FileDecision `json:"files"`
+			EnvSources []envSource           `json:"env_files"`
+		}{Root: plan.Root, Files: plan.Files, EnvSources: []envSource{}}
+		for _, source := range plan.EnvSources {
+			report.EnvSources = append(report.EnvSources, envSource{File: envgrd.ConvertPath(source.File, pathSep), Kind: source.Kind, Scope: source.Scope})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
 	}
 
-	initConfigCmd = &cobra.Command{
-		Use:   "init-config",
-		Short: "Create a .envgrd.config file in the current directory",
-		Long:  "Creates a .envgrd.config file with default configuration in the current directory.",
-		RunE:  runInitConfig,
+	fmt.Printf("Files in %s:\n", plan.Root)
+	for _, file := range plan.Files {
+		switch {
+		case file.Included:
+			fmt.Printf("  ✓ %s [%s]: %s\n", file.Path, file.Language, file.Reason)
+		case file.Dir:
+			fmt.Printf("  - %s/: %s\n", file.Path, file.Reason)
+		default:
+			fmt.Printf("  - %s: %s\n", file.Path, file.Reason)
+		}
+	}
+	fmt.Println("\nEnv files (in load order, later files override earlier ones):")
+	if len(plan.EnvSources) == 0 {
+		fmt.Println("  - none found, only the exported environment is used")
+	}
+	for _, source := range plan.EnvSources {
+		scope := ""
+		if source.Scope != "" {
+			scope = fmt.Sprintf(" (nested config in %s)", source.Scope)
+		}
+		fmt.Printf("  %s [%s]%s\n", envgrd.ConvertPath(source.File, pathSep), source.Kind, scope)
 	}
+	return nil
+}
 
-	versionCmd = &cobra.Command{
-		Use:   "version",
-		Short: "Print the version number",
-		Long:  "Print the version number of envgrd",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(Version)
-		},
+// readStdinSource reads the file of --stdin and returns it with its absolute path
+func readStdinSource() (string, []byte, error) {
+	switch {
+	case stdinName == "":
+		return "", nil, fmt.Errorf("--stdin needs the path of the file it reads (--stdin-filename)")
+	case usagesFile != "":
+		return "", nil, fmt.Errorf("--stdin can't be combined with --usages-file")
+	case interactive:
+		return "", nil, fmt.Errorf("--stdin can't be combined with --interactive")
+	case sinceLastRun:
+		return "", nil, fmt.Errorf("--stdin can't be combined with --since-last-run")
+	}
+	name, err := filepath.Abs(stdinName)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid --stdin-filename: %w", err)
 	}
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return name, content, nil
+}
 
-	// Flags
-	scanPath     string
-	envFile      string
-	jsonOutput   bool
-	silent       bool
-	skipUnused   bool
-	debug        bool
-	noHeader     bool
-	noDynamic    bool
-	includeGlobs []string
-	excludeGlobs []string
-)
+// readUsagesFile reads the usages file of --usages-file, or stdin for -
+func readUsagesFile(path string) ([]envgrd.EnvUsage, error) {
+	if path == "-" {
+		return envgrd.ReadUsages(os.Stdin)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usages file: %w", err)
+	}
+	defer file.Close()
+	usages, err := envgrd.ReadUsages(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return usages, nil
+}
 
-func init() {
-	scanCmd.Flags().StringVarP(&scanPath, "path", "p", ".", "Path to scan (default: current directory)")
-	scanCmd.Flags().StringVar(&envFile, "env-file", "", "Additional .env file to load")
-	scanCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output results in JSON format")
-	scanCmd.Flags().BoolVar(&silent, "silent", false, "Silent mode (exit code only)")
-	scanCmd.Flags().BoolVar(&skipUnused, "skip-unused", false, "Skip reporting unused variables")
-	scanCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
-	scanCmd.Flags().BoolVar(&noHeader, "no-header", false, "Skip printing the header")
-	scanCmd.Flags().BoolVar(&noDynamic, "no-dynamic", false, "Disable dynamic pattern detection (skip partial matches from runtime-evaluated expressions)")
-	scanCmd.Flags().StringSliceVar(&includeGlobs, "include", []string{}, "Glob patterns to include")
-	scanCmd.Flags().StringSliceVar(&excludeGlobs, "exclude", []string{}, "Glob patterns to exclude")
+func runMerge(cmd *cobra.Command, args []string) error {
+	failPolicy, err := output.ParseFailOn(failOn)
+	if err != nil {
+		return err
+	}
+	reports := make([]output.JSONOutput, 0, len(args))
+	for _, path := range args {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to read report: %w", err)
+		}
+		report, err := output.ReadReport(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		reports = append(reports, report)
+	}
 
-	rootCmd.AddCommand(scanCmd)
-	rootCmd.AddCommand(initSchemaCmd)
-	rootCmd.AddCommand(initConfigCmd)
-	rootCmd.AddCommand(versionCmd)
-}
+	merged := output.MergeReports(reports)
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(merged); err != nil {
+			return err
+		}
+	} else {
+		text := output.NewTextReporter()
+		text.Color = text.Color && !noColor
+		if err := output.WriteMergedText(os.Stdout, merged, text); err != nil {
+			return err
+		}
+	}
 
-func runScan(cmd *cobra.Command, args []string) error {
-	// Get scan path
-	path := scanPath
-	if len(args) > 0 {
-		path = args[0]
+	// Unused variables and dynamic patterns are only in the reports of scans that checked them
+	if code := output.ExitCode(output.ReportResult(merged), failPolicy, false, true); code != output.ExitOK {
+		os.Exit(code)
 	}
+	return nil
+}
 
-	// Resolve absolute path
-	absPath, err := filepath.Abs(path)
+func runConvert(cmd *cobra.Command, args []string) error {
+	switch convertTo {
+	case output.ConvertConfigMap, output.ConvertCompose, output.ConvertHelm, output.ConvertEnv, output.ConvertShell:
+	default:
+		return fmt.Errorf("unknown --to %q (supported: k8s-configmap, compose, helm, env, shell)", convertTo)
+	}
+	content, _, err := charset.ReadFile(convertFrom)
 	if err != nil {
-		return fmt.Errorf("invalid path: %w", err)
+		return fmt.Errorf("failed to read %s: %w", convertFrom, err)
 	}
-
-	// Check if path exists
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		return fmt.Errorf("path does not exist: %s", absPath)
+	values, lines, err := envfile.ParseFile(convertFrom)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", convertFrom, err)
 	}
+	comments := envfile.Comments(content, lines)
 
-	fileScanner := scanner.NewScanner()
-	if len(includeGlobs) > 0 {
-		fileScanner.SetIncludeGlobs(includeGlobs)
+	// Keep the order of the file
+	vars := make([]output.EnvVar, 0, len(values))
+	for key, value := range values {
+		vars = append(vars, output.EnvVar{Key: key, Value: value, Comment: comments[key]})
 	}
-	if len(excludeGlobs) > 0 {
-		fileScanner.SetExcludeGlobs(excludeGlobs)
+	sort.Slice(vars, func(i, j int) bool {
+		if lines[vars[i].Key] != lines[vars[j].Key] {
+			return lines[vars[i].Key] < lines[vars[j].Key]
+		}
+		return vars[i].Key < vars[j].Key
+	})
+	return output.WriteConversion(os.Stdout, vars, convertTo, convertName)
+}
+
+// scanForExport runs the scan of commands exporting the results in another form (graph, generate),
+// with the --include, --exclude and --env-file flags
+func scanForExport(path string, cfg *envgrd.Config) (*envgrd.Result, error) {
+	opts, err := exportOptions(path, cfg)
+	if err != nil {
+		return nil, err
 	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return envgrd.Scan(ctx, opts)
+}
 
-	envLoader := envfile.NewLoader()
+// exportOptions returns the scan options of the commands exporting what a scan finds
+func exportOptions(path string, cfg *envgrd.Config) (envgrd.Options, error) {
+	opts := envgrd.Options{
+		Path:         path,
+		IncludeGlobs: includeGlobs,
+		Languages:    langs,
+		ExcludeGlobs: excludeGlobs,
+		Profile:      profile,
+	}
+	if configFile != "" {
+		opts.Config = cfg
+	}
 	if envFile != "" {
-		envLoader.AddEnvFile(envFile)
+		opts.EnvFiles = []string{envFile}
+	}
+	opts.IgnoreEnvFiles = ignoreEnv
+	if dir, err := resolveCacheDir(); err == nil {
+		opts.CacheDir = dir
 	}
+	logger, err := newLogger()
+	if err != nil {
+		return opts, err
+	}
+	opts.Logger = logger
+	return opts, nil
+}
 
-	tsParser := parser.NewParser()
-	tsParser.SetDebug(debug)
+func runLSP(cmd *cobra.Command, args []string) error {
+	server := lsp.NewServer(os.Stdin, os.Stdout, Version)
+	logger, err := newLogger()
+	if err != nil {
+		return err
+	}
+	server.SetLogger(logger)
+	return server.Run()
+}
 
-	// Print header unless disabled or in JSON/silent mode
-	if !noHeader && !jsonOutput && !silent {
-		printHeader()
+// envPrefix prefixes the environment variables setting flags, e.g. ENVGRD_FAIL_ON for --fail-on
+const envPrefix = "ENVGRD_"
+
+// loadConfig loads the --config file (or ENVGRD_CONFIG), or else the config of the scanned directory,
+// and applies --profile (or ENVGRD_PROFILE)
+func loadConfig(path string) (*envgrd.Config, error) {
+	if configFile == "" {
+		configFile = os.Getenv(envPrefix + "CONFIG")
+	}
+	if profile == "" {
+		profile = os.Getenv(envPrefix + "PROFILE")
 	}
 
-	cfg, err := config.LoadConfig(absPath)
+	var cfg *envgrd.Config
+	var err error
+	if configFile != "" {
+		cfg, err = envgrd.LoadConfigFile(configFile)
+	} else {
+		cfg, err = envgrd.LoadConfig(path)
+	}
 	if err != nil {
-		if !silent {
-			fmt.Fprintf(os.Stderr, "Warning: failed to load .envgrd.config: %v\n", err)
+		return nil, err
+	}
+	return cfg.WithProfile(profile)
+}
+
+// loadPlugins loads the language plugins of --plugin, or else of ENVGRD_PLUGIN (comma-separated)
+func loadPlugins(cmd *cobra.Command, args []string) error {
+	if len(plugins) == 0 {
+		if value := os.Getenv(envPrefix + "PLUGIN"); value != "" {
+			plugins = strings.Split(value, ",")
+		}
+	}
+	for _, path := range plugins {
+		if err := envgrd.LoadLanguagePlugin(strings.TrimSpace(path)); err != nil {
+			return err
 		}
-		// Continue with default config
-		cfg = &config.Config{}
 	}
+	return nil
+}
+
+// applySettings sets the flags that weren't given on the command line from ENVGRD_* environment variables,
+// or else from the scan settings of cfg: command line > environment > config > defaults
+// When names are given, only those flags are set
+func applySettings(flags *pflag.FlagSet, cfg *envgrd.Config, names ...string) error {
+	settings := cfg.ScanSettings.Flags()
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" || f.Name == "path" || f.Name == "config" || f.Name == "profile" {
+			return
+		}
+		if len(names) > 0 && !slices.Contains(names, f.Name) {
+			return
+		}
+		value, source, ok := setting(f.Name, cfg, settings)
+		if !ok {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", source, setErr)
+		}
+	})
+	return err
+}
 
-	if len(cfg.Ignores.Folders) > 0 {
-		fileScanner.AddExcludeDirs(cfg.Ignores.Folders)
+// setting returns the value of the flag name set by its ENVGRD_* variable or else by the config's settings
+// (cfg.ScanSettings.Flags()), with where it comes from
+func setting(name string, cfg *envgrd.Config, settings map[string]string) (value string, source string, ok bool) {
+	key := strings.ReplaceAll(name, "-", "_")
+	source = envPrefix + strings.ToUpper(key)
+	if value = os.Getenv(source); value != "" { // Empty variables count as unset
+		return value, source, true
 	}
+	value, ok = settings[name]
+	return value, fmt.Sprintf("%s setting %s", filepath.Base(cfg.File), key), ok
+}
 
-	if !silent {
-		fmt.Fprintf(os.Stderr, "Scanning %s...\n", absPath)
+// withoutColor turns off the ANSI colors of a text reporter (--no-color); other reporters have none
+func withoutColor(reporter output.Reporter) output.Reporter {
+	if text, ok := reporter.(output.TextReporter); ok {
+		text.Color = false
+		return text
 	}
-	files, err := fileScanner.Scan(absPath)
+	return reporter
+}
+
+// tracerFromEnv returns the tracer the OTEL_* variables configure, or nil when they don't enable tracing
+// Settings envgrd doesn't support (e.g., the grpc protocol) disable tracing with a warning rather than
+// failing the scan
+func tracerFromEnv(logger *slog.Logger) *envgrd.Tracer {
+	tracer, err := envgrd.TracerFromEnv("envgrd", Version)
 	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
+		logging.OrDiscard(logger).Warn(fmt.Sprintf("Tracing disabled: %v", err))
+		return nil
 	}
+	return tracer
+}
 
-	if !silent {
-		report := reportFileCounts(files)
-		fmt.Fprintf(os.Stderr, "%s\n", report)
+// newLogger builds the stderr logger from --verbose, --debug, --quiet and --log-format
+func newLogger() (*slog.Logger, error) {
+	level := logging.LevelForVerbosity(verbosity)
+	if debug {
+		level = logging.LevelTrace
+	}
+	if quiet {
+		level = slog.LevelError
+	}
+	return logging.New(os.Stderr, level, logFormat)
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return err
 	}
+	if err := envgrd.ClearCache(dir); err != nil {
+		return err
+	}
+	fmt.Printf("Cleared cache in %s\n", dir)
+	return nil
+}
 
-	envData, err := loadEnvironmentVariables(envLoader, absPath)
+func runCacheEnv(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(cmd.Flags(), cfg, "env-file", "ignore-env-file"); err != nil {
+		return err
+	}
+	opts := envgrd.Options{Path: path, Profile: profile}
+	if configFile != "" {
+		opts.Config = cfg
+	}
+	if envFile != "" {
+		opts.EnvFiles = []string{envFile}
+	}
+	opts.IgnoreEnvFiles = ignoreEnv
+	if opts.CacheDir, err = resolveCacheDir(); err != nil {
+		return err
+	}
+	if opts.Logger, err = newLogger(); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	info, err := envgrd.CacheEnv(ctx, opts)
 	if err != nil {
 		return err
 	}
+	fmt.Printf("Cached %d variables from %d env files in %s\n", info.Variables, info.EnvFiles, info.File)
+	return nil
+}
 
-	allUsages := parseFiles(tsParser, files, absPath, silent)
+func runDoctor(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	fmt.Printf("envgrd %s (%s, %s/%s)\n", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	failed := false
 
-	result := analyzer.Analyze(allUsages, envData.envVars, envData.envVarsFromFilesOnly, envData.relEnvKeySources, cfg)
+	fmt.Println("\nConfig:")
+	file := configFile
+	if file == "" {
+		file = os.Getenv(envPrefix + "CONFIG")
+	}
+	if file == "" {
+		var err error
+		if file, err = envgrd.FindConfig(path); err != nil {
+			return err
+		}
+	}
+	if file == "" {
+		fmt.Printf("  - no config file in %s, using the defaults\n", path)
+	} else if problems, err := envgrd.CheckConfig(file); err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		failed = true
+	} else if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("  ✗ %s: %v\n", file, problem)
+		}
+		failed = true
+	} else {
+		fmt.Printf("  ✓ %s is valid\n", file)
+	}
+	// An invalid config doesn't stop the other checks, scans fall back to the defaults too
+	cfg, err := loadConfig(path)
+	if err != nil {
+		if file != "" && !failed {
+			fmt.Printf("  ✗ %v\n", err)
+			failed = true
+		}
+		cfg = &envgrd.Config{}
+	} else if profile != "" {
+		fmt.Printf("  ✓ profile %s applied\n", profile)
+	}
 
-	dynamic := !noDynamic
-	if err := output.Format(result, jsonOutput, silent, skipUnused, dynamic); err != nil {
-		return fmt.Errorf("failed to format output: %w", err)
+	fmt.Println("\nGrammars:")
+	var loaded []string
+	for _, check := range envgrd.CheckGrammars() {
+		if check.Err != nil {
+			fmt.Printf("  ✗ %s: %v\n", check.Language, check.Err)
+			failed = true
+			continue
+		}
+		loaded = append(loaded, check.Language)
+	}
+	if len(loaded) > 0 {
+		fmt.Printf("  ✓ %s\n", strings.Join(loaded, ", "))
 	}
 
-	if output.HasIssues(result, skipUnused, dynamic) {
-		os.Exit(1)
+	fmt.Println("\nEnv files (in load order, later files override earlier ones):")
+	if err := applySettings(cmd.Flags(), cfg, "env-file", "ignore-env-file"); err != nil {
+		return err
+	}
+	opts := envgrd.Options{Path: path, Profile: profile}
+	if configFile != "" {
+		opts.Config = cfg
+	}
+	if envFile != "" {
+		opts.EnvFiles = []string{envFile}
+	}
+	opts.IgnoreEnvFiles = ignoreEnv
+	sources, err := envgrd.EnvSources(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		fmt.Println("  - none found, only the exported environment is used")
+	}
+	for _, source := range sources {
+		scope := ""
+		if source.Scope != "" {
+			scope = fmt.Sprintf(" (nested config in %s)", source.Scope)
+		}
+		if source.Err != nil {
+			fmt.Printf("  ✗ %s [%s]: %v%s\n", source.File, source.Kind, source.Err, scope)
+			failed = true
+			continue
+		}
+		fmt.Printf("  ✓ %s [%s]: %d variables%s\n", source.File, source.Kind, source.Variables, scope)
+	}
+	if len(cfg.Precedence) > 0 {
+		fmt.Printf("  precedence (highest first): %s\n", strings.Join(cfg.Precedence, ", "))
+	} else {
+		fmt.Println("  precedence: load order, exported variables don't override env files")
+	}
+
+	fmt.Println("\nTerminal:")
+	if enabled, reason := output.ColorSupport(); enabled {
+		fmt.Printf("  colors enabled (%s)\n", reason)
+	} else {
+		fmt.Printf("  colors disabled (%s)\n", reason)
+	}
+
+	fmt.Println("\nSettings (from ENVGRD_* variables and the config):")
+	settings := cfg.ScanSettings.Flags()
+	found := false
+	scanCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "path" || f.Name == "config" || f.Name == "profile" {
+			return
+		}
+		if value, source, ok := setting(f.Name, cfg, settings); ok {
+			fmt.Printf("  %s = %s (%s)\n", f.Name, value, source)
+			found = true
+		}
+	})
+	if !found {
+		fmt.Println("  - none, scans use the flag defaults")
 	}
 
+	if failed {
+		os.Exit(1)
+	}
 	return nil
 }
 
-// reportFileCounts generates a formatted report string of file counts by language
-func reportFileCounts(files []scanner.FileInfo) string {
-	// Count files by language
-	langCounts := make(map[string]int)
-	for _, file := range files {
-		lang := string(file.Language)
-		if lang == "" {
-			lang = "unknown"
-		}
-		langCounts[lang]++
-	}
-
-	// Build report string
-	var reportParts []string
-	langOrder := []string{"javascript", "typescript", "go", "python", "rust", "java"}
-	for _, lang := range langOrder {
-		if count, ok := langCounts[lang]; ok && count > 0 {
-			// Use short names for display
-			shortName := lang
-			switch lang {
-			case "javascript":
-				shortName = "js"
-			case "typescript":
-				shortName = "ts"
-			}
-			reportParts = append(reportParts, fmt.Sprintf("%s: %d", shortName, count))
-			delete(langCounts, lang)
-		}
+func runConfigCheck(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
 	}
-	// Add any remaining languages
-	for lang, count := range langCounts {
-		if count > 0 {
-			reportParts = append(reportParts, fmt.Sprintf("%s: %d", lang, count))
+	file := configFile
+	if file == "" {
+		file = os.Getenv(envPrefix + "CONFIG")
+	}
+	if file == "" {
+		var err error
+		if file, err = envgrd.FindConfig(path); err != nil {
+			return err
+		}
+		if file == "" {
+			fmt.Printf("No config file in %s\n", path)
+			return nil
 		}
 	}
 
-	if len(reportParts) > 0 {
-		reportStr := ""
-		for i, part := range reportParts {
-			if i > 0 {
-				reportStr += ", "
-			}
-			reportStr += part
+	problems, err := envgrd.CheckConfig(file)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Printf("✓ %s is valid\n", file)
+		return nil
+	}
+	for _, problem := range problems {
+		if problem.Line > 0 {
+			fmt.Printf("%s:%d: %s\n", file, problem.Line, problem.Message)
+		} else {
+			fmt.Printf("%s: %s\n", file, problem.Message)
 		}
-		return fmt.Sprintf("Found %d files (%s)", len(files), reportStr)
 	}
-	return fmt.Sprintf("Found %d files to parse", len(files))
+	os.Exit(1)
+	return nil
 }
 
-// loadEnvironmentVariables loads and processes environment variables from files and exported env
-func loadEnvironmentVariables(envLoader *envfile.Loader, absPath string) (*envVarData, error) {
-	// Load environment variables from files and merge with exported env
-	envVars, envVarsFromFilesOnly, envKeySources, err := envLoader.LoadWithExportedEnv(absPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load env files: %w", err)
+// compareWithLastRun narrows result to the changes since the state recorded by the previous run,
+// then records the full result as the new state
+func compareWithLastRun(result *envgrd.Result, logger *slog.Logger) (*envgrd.Result, error) {
+	path := stateFile
+	if path == "" {
+		path = filepath.Join(result.Root, envgrd.DefaultStateFile)
 	}
 
-	// Make source file paths relative to scan root for better display
-	relEnvKeySources := make(map[string]string)
-	for k, sourcePath := range envKeySources {
-		if rel, err := filepath.Rel(absPath, sourcePath); err == nil && rel != "" {
-			relEnvKeySources[k] = rel
-		} else {
-			// Fallback to just the filename if relative path fails
-			relEnvKeySources[k] = filepath.Base(sourcePath)
-		}
+	prev, err := envgrd.LoadState(path)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil {
+		logger.Info(fmt.Sprintf("No previous state in %s, reporting all findings as new", path))
+	} else {
+		logger.Info(fmt.Sprintf("Comparing with the scan from %s", prev.ScannedAt.Local().Format(time.RFC1123)))
 	}
 
-	return &envVarData{
-		envVars:              envVars,
-		envVarsFromFilesOnly: envVarsFromFilesOnly,
-		relEnvKeySources:     relEnvKeySources,
-	}, nil
+	if err := envgrd.SaveState(path, result); err != nil {
+		return nil, err
+	}
+	return result.Since(prev), nil
 }
 
-// parses all files in parallel and returns environment variable usages
-func parseFiles(tsParser *parser.Parser, files []scanner.FileInfo, absPath string, silent bool) []analyzer.EnvUsage {
-	var allUsages []analyzer.EnvUsage
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	workers := make(chan struct{}, 10)
-
-	for _, file := range files {
-		wg.Add(1)
-		workers <- struct{}{} // Acquire worker
+// triageFindings asks what to do about each finding of result, then prints what was done
+// Ignores go to the root config file (.envgrd.config when there is none), placeholders to the
+// first example env file the scan loaded (.env.example when there is none)
+func triageFindings(result *envgrd.Result, cfg *envgrd.Config, skipUnused bool, dynamic bool) error {
+	findings := triage.Findings(result.ScanResult, skipUnused, dynamic)
+	if len(findings) == 0 {
+		fmt.Println("✓ Nothing to triage")
+		return nil
+	}
 
-		go func(f scanner.FileInfo) {
-			defer wg.Done()
-			defer func() { <-workers }() // Release worker
+	session := &triage.Session{
+		In:          os.Stdin,
+		Out:         os.Stdout,
+		ConfigFile:  cfg.File,
+		ExampleFile: filepath.Join(result.Root, ".env.example"),
+		StateFile:   stateFile,
+	}
+	if session.ConfigFile == "" {
+		session.ConfigFile = filepath.Join(result.Root, envgrd.ConfigFileName)
+	}
+	if drift := result.ExampleDrift; drift != nil && len(drift.Examples) > 0 {
+		session.ExampleFile = filepath.Join(result.Root, drift.Examples[0])
+	}
+	if session.StateFile == "" {
+		session.StateFile = filepath.Join(result.Root, envgrd.DefaultStateFile)
+	}
 
-			usages, err := tsParser.ParseFile(f.Path, string(f.Language), absPath)
-			if err != nil {
-				// Log error but continue
-				if !silent {
-					fmt.Fprintf(os.Stderr, "Warning: failed to parse %s: %v\n", f.Path, err)
-				}
-				return
-			}
+	summary := session.Run(findings)
+	fmt.Printf("\nTriaged %d findings: %d ignored, %d added to %s, %d baselined, %d skipped\n",
+		len(findings), summary[triage.Ignore], summary[triage.Example], filepath.Base(session.ExampleFile), summary[triage.Baseline], summary[triage.Skip])
+	return nil
+}
 
-			// Mark usages from ignored folders
-			if f.InIgnoredPath {
-				for i := range usages {
-					usages[i].InIgnoredPath = true
-				}
-			}
+// parseByteSize parses sizes like "512", "500KB" or "5MB" (binary multiples, case-insensitive)
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
 
-			mu.Lock()
-			allUsages = append(allUsages, usages...)
-			mu.Unlock()
-		}(file)
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("expected a non-negative size like 500KB or 5MB")
 	}
+	return size * multiplier, nil
+}
 
-	wg.Wait()
-	return allUsages
+// resolveCacheDir returns --cache-dir or the default cache directory
+func resolveCacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return envgrd.DefaultCacheDir()
 }
 
 func runInitSchema(cmd *cobra.Command, args []string) error {
-	// Stub for future schema feature
 	schema := `{
   "PORT": "number",
-  "LOG_LEVEL": ["debug", "info", "warn", "error"]
+  "LOG_LEVEL": ["debug", "info", "warn", "error"],
+  "DATABASE_URL": {"format": "url", "description": "Primary database"}
 }`
 	fmt.Println(schema)
 	return nil
@@ -318,13 +1695,31 @@ func runInitConfig(cmd *cobra.Command, args []string) error {
 	configContent := `# .envgrd.config
 # Configuration file for envgrd
 
+# Configs to inherit from: built-in presets (node, go-service, k8s), files relative
+# to this one or https URLs pinned by their sha256; lists add to the inherited ones,
+# other values replace them
+extends:
+  # - node
+  # - https://example.com/org/envgrd.yml#sha256=<checksum>
+
 ignores:
   # Variables that are configured in custom ways (not in .env files or standard configs)
   # These will not be reported as missing
   missing:
     # - CUSTOM_API_KEY
     # - EXTERNAL_SERVICE_TOKEN
-    # Add more variable names here as needed
+    # - "AWS_*"
+    # - /^KUBERNETES_/
+    # Add more variable names, globs or /regular expressions/ here as needed
+
+  # Variables that will not be reported as unused
+  unused:
+    # - "FEATURE_*"
+
+  # Variables to ignore only in some directories
+  paths:
+    # tools:
+    #   - DEBUG_TOKEN
   
   # Folders to ignore when scanning (useful for config directories that aren't actual code)
   folders:
@@ -333,6 +1728,126 @@ ignores:
     # - k8s
     # - deployments
     # Add more folder names here as needed
+
+# More env files to load, relative to this file (a .envgrd.config in a subdirectory
+# extends this one for the files beneath it, see the README)
+env_files:
+  # - config/app.env
+
+# Schema file declaring the variables (see envgrd init-schema), relative to this file;
+# required entries no code reads and variables missing from it are reported
+# schema: .envgrd.schema.json
+
+# Variables that must be defined in env files even if no code reads them
+# (e.g., consumed by a third-party binary or terraform); never reported as unused
+required:
+  # - TF_VAR_region
+
+# Defaults for scan flags, named like the flags with underscores
+# (flags on the command line and ENVGRD_* environment variables take precedence)
+# include: ["src/**"]
+# exclude: ["**/generated/**"]
+# fail_on: [missing]
+# skip_unused: true
+# concurrency: 4
+# format: json
+
+# Named overrides selected with --profile (e.g., envgrd scan --profile ci)
+# profiles:
+#   ci:
+#     fail_on: missing
+#     format: json
+#   local:
+#     skip_unused: true
+
+# Env files the unused check applies to (globs; patterns without a slash match the file name)
+unused:
+  # Only report unused variables defined in these files (default: all loaded files)
+  sources:
+    # - .env
+  # Never report unused variables defined in these files
+  exclude_sources:
+    # - docker-compose*.yml
+    # - k8s/*
+  # Skip example files such as .env.example or env.sample
+  # exclude_examples: true
+
+# Source kinds from highest to lowest priority when a variable is defined in several places
+# (env, envrc, docker-compose, k8s, systemd, shell, exported); default keeps load order, files over exported
+precedence:
+  # - exported
+  # - env
+
+# Public-prefix check of client-side code (vite, next, cra or none; detected from package.json by default)
+frontend:
+  # framework: vite
+  # Directories with client-side code (default depends on the framework, e.g. src for vite)
+  client_dirs:
+    # - src
+  # Directories inside client_dirs that only run on the server
+  server_dirs:
+    # - src/server
+
+# Variables provided by the OS, CI or runtimes (PATH, HOME, CI, NODE_ENV, GOPATH, ...) are never reported as missing
+system_vars:
+  # Names or globs to add to the built-in list
+  extra:
+    # - "BUILDKITE_*"
+  # Report the built-in variables as missing like any other
+  # disabled: true
+
+# Naming-convention rules for variable names in code and env files (reported as style findings)
+naming:
+  # upper_snake_case: true
+  # prefix: MYAPP_
+  # max_length: 40
+  forbidden_words:
+    # - TMP
+  # Names or globs the rules don't apply to
+  exempt:
+    # - NODE_ENV
+
+# How usages in test files (*_test.go, *.spec.ts, tests/, ...) are treated: include (default), exclude or report
+tests:
+  # mode: report
+  # More test file globs, or directory names ending in a slash
+  patterns:
+    # - "*.e2e.ts"
+
+# Deprecated variables with a migration hint; remaining usages and definitions are reported
+deprecated:
+  # OLD_DB_URL: "use DATABASE_URL"
+
+# Variables that must never be used or defined, with a remediation message
+forbidden:
+  # AWS_SECRET_ACCESS_KEY: "use the SDK credential chain"
+
+# How to fix the findings of variables, shown with them
+remediation:
+  # DATABASE_URL: "Request access via #infra"
+
+severity:
+  # Finding categories: error, warning or info (info findings never fail the run)
+  # missing: error
+  # unused: warning
+  # dynamic: info
+  # optional: info
+  # test: info
+  # undocumented: warning
+  # stale: warning
+  # unread: warning
+  # undeclared: warning
+  # unprefixed: warning
+  # exposed: warning
+  # style: warning
+  # deprecated: warning
+  # type: error
+  # placeholder: warning
+  # reference: warning
+  # forbidden: error
+  # Per-variable overrides by name or glob
+  variables:
+    # "LEGACY_*": info
 `
 
 	// Write the config file
@@ -358,6 +1873,6 @@ func printHeader() {
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(output.ExitInternalError)
 	}
 }
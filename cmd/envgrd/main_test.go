@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/output"
+	"github.com/jenian/envgrd/pkg/envgrd"
+	"github.com/spf13/pflag"
+)
+
+func TestApplySettings(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		settings    envgrd.ScanSettings
+		wantFormat  string
+		wantNoColor bool
+		wantErr     string
+	}{
+		{name: "defaults"},
+		{name: "config", settings: envgrd.ScanSettings{Format: "json", NoColor: &yes}, wantFormat: "json", wantNoColor: true},
+		{name: "env over config", env: map[string]string{"ENVGRD_FORMAT": "badge"}, settings: envgrd.ScanSettings{Format: "json"}, wantFormat: "badge"},
+		{name: "cli over env", args: []string{"--format", "text"}, env: map[string]string{"ENVGRD_FORMAT": "badge"}, settings: envgrd.ScanSettings{Format: "json"}, wantFormat: "text"},
+		{name: "empty env falls through to config", env: map[string]string{"ENVGRD_FORMAT": "", "ENVGRD_NO_COLOR": ""}, settings: envgrd.ScanSettings{Format: "json", NoColor: &yes}, wantFormat: "json", wantNoColor: true},
+		{name: "no color from env", env: map[string]string{"ENVGRD_NO_COLOR": "true"}, wantNoColor: true},
+		{name: "no color from cli over env", args: []string{"--no-color=false"}, env: map[string]string{"ENVGRD_NO_COLOR": "true"}},
+		{name: "invalid env", env: map[string]string{"ENVGRD_NO_COLOR": "maybe"}, wantErr: "ENVGRD_NO_COLOR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVGRD_FORMAT", "")
+			t.Setenv("ENVGRD_NO_COLOR", "")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			var format string
+			var noColor bool
+			flags := pflag.NewFlagSet("scan", pflag.ContinueOnError)
+			flags.StringVar(&format, "format", "", "")
+			flags.BoolVar(&noColor, "no-color", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := applySettings(flags, &envgrd.Config{File: ".envgrd.config", ScanSettings: tt.settings})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error about %s, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applySettings failed: %v", err)
+			}
+			if format != tt.wantFormat || noColor != tt.wantNoColor {
+				t.Errorf("Expected --format %q and --no-color %v, got %q and %v", tt.wantFormat, tt.wantNoColor, format, noColor)
+			}
+		})
+	}
+}
+
+func TestWithoutColor(t *testing.T) {
+	result := analyzer.ScanResult{
+		Missing: map[string][]analyzer.EnvUsage{"MISSING_VAR": {{Key: "MISSING_VAR", File: "app.js", Line: 3}}},
+	}
+	report := func(reporter output.Reporter) string {
+		var buf bytes.Buffer
+		if err := reporter.Report(&buf, result, output.Options{}); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		return buf.String()
+	}
+
+	colored := output.TextReporter{Color: true}
+	if !strings.Contains(report(colored), "\x1b[") {
+		t.Fatal("Expected ANSI codes in the colored report")
+	}
+	if got := report(withoutColor(colored)); strings.Contains(got, "\x1b[") || !strings.Contains(got, "MISSING_VAR") {
+		t.Errorf("Expected the report without ANSI codes, got %q", got)
+	}
+	if _, ok := withoutColor(output.JSONReporter{}).(output.JSONReporter); !ok {
+		t.Error("Expected other reporters to be left as they are")
+	}
+}
+
+func TestTracerFromEnv(t *testing.T) {
+	for _, name := range []string{"OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TIMEOUT", "TRACEPARENT"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+
+	tests := []struct {
+		protocol string
+		enabled  bool
+	}{
+		{"", true},
+		{"http/json", true},
+		{"grpc", false},
+		{"http/protobuf", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", tt.protocol)
+			var logs bytes.Buffer
+			logger, err := logging.New(&logs, slog.LevelWarn, "text")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tracer := tracerFromEnv(logger)
+			if (tracer != nil) != tt.enabled {
+				t.Errorf("Expected tracing enabled %v, got %v", tt.enabled, tracer)
+			}
+			if warned := strings.Contains(logs.String(), "Tracing disabled"); warned == tt.enabled {
+				t.Errorf("Expected a warning only when tracing is disabled, got %q", logs.String())
+			}
+		})
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "console")
+	if tracer := tracerFromEnv(nil); tracer != nil {
+		t.Errorf("Expected tracing off with an unsupported exporter, got %v", tracer)
+	}
+}
@@ -1,6 +1,8 @@
 package e2e
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -139,13 +141,12 @@ func runScanTest(t *testing.T, repoName string, envVars map[string]string) {
 	outputStr := string(output)
 	normalizedOutput := normalizeOutput(outputStr)
 
-	// Handle exit code (exit code 1 is expected when there are unused variables)
+	// Handle exit code (2-4 are expected when missing, unused or dynamic findings are reported)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
-			if exitError.ExitCode() != 1 {
+			if code := exitError.ExitCode(); code < 2 || code > 4 {
 				t.Fatalf("Unexpected exit code: %d\nOutput: %s", exitError.ExitCode(), outputStr)
 			}
-			// Exit code 1 is expected when unused variables are found
 		} else {
 			t.Fatalf("envgrd scan failed: %v\nOutput: %s", err, outputStr)
 		}
@@ -182,3 +183,79 @@ func TestE2E_ExportedVars(t *testing.T) {
 	}
 	runScanTest(t, "mock-repo-exported", envVars)
 }
+
+func TestE2E_FailOnExitCodes(t *testing.T) {
+	// mock-repo has both missing and unused variables
+	mockRepo := setupMockRepo(t, "mock-repo")
+	binaryPath := getBinaryPath()
+
+	tests := []struct {
+		failOn   string
+		expected int
+	}{
+		{"", 2},
+		{"missing", 2},
+		{"unused", 3},
+		{"dynamic", 0},
+		{"none", 0},
+		{"bogus", 10},
+	}
+
+	for _, tt := range tests {
+		args := []string{"scan", mockRepo, "--silent"}
+		if tt.failOn != "" {
+			args = append(args, "--fail-on", tt.failOn)
+		}
+		err := exec.Command(binaryPath, args...).Run()
+
+		code := 0
+		if exitError, ok := err.(*exec.ExitError); ok {
+			code = exitError.ExitCode()
+		} else if err != nil {
+			t.Fatalf("envgrd scan failed: %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("--fail-on %q: expected exit code %d, got %d", tt.failOn, tt.expected, code)
+		}
+	}
+}
+
+func TestE2E_QuietAndJSONOutput(t *testing.T) {
+	mockRepo := setupMockRepo(t, "mock-repo")
+	binaryPath := getBinaryPath()
+
+	run := func(args ...string) (string, string) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(binaryPath, append([]string{"scan", mockRepo}, args...)...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if _, ok := err.(*exec.ExitError); !ok {
+				t.Fatalf("envgrd scan failed: %v", err)
+			}
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	// JSON reports are the only thing on stdout, progress goes to stderr
+	stdout, stderr := run("--json")
+	var report map[string]any
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Errorf("--json stdout is not a JSON document: %v\n%s", err, stdout)
+	}
+	if !strings.Contains(stderr, "Scanning") {
+		t.Errorf("Expected progress on stderr with --json, got %q", stderr)
+	}
+
+	stdout, stderr = run("--quiet")
+	if stderr != "" {
+		t.Errorf("Expected nothing on stderr with --quiet, got %q", stderr)
+	}
+	if strings.Contains(stdout, "Version:") || !strings.Contains(stdout, "UNUSED_VAR") {
+		t.Errorf("Expected findings without a header with --quiet, got %q", stdout)
+	}
+
+	if stdout, stderr = run("--quiet", "--json"); stderr != "" || !json.Valid([]byte(stdout)) {
+		t.Errorf("Expected only JSON with --quiet --json, got stdout %q and stderr %q", stdout, stderr)
+	}
+}
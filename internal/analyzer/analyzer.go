@@ -1,9 +1,11 @@
 package analyzer
 
 import (
-	"strings"
+	"log/slog"
+	"sort"
 
 	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/logging"
 )
 
 // Analyze compares code-discovered environment variables with those in .env files
@@ -12,12 +14,22 @@ import (
 // envKeySources: maps variable key to source file path
 // cfg: configuration for ignoring variables
 func Analyze(codeUsages []EnvUsage, envVars map[string]string, envVarsFromFiles map[string]string, envKeySources map[string]string, cfg *config.Config) ScanResult {
+	return AnalyzeWithLogger(logging.Discard(), codeUsages, envVars, envVarsFromFiles, envKeySources, cfg)
+}
+
+// AnalyzeWithLogger is like Analyze but traces every ignored or suppressed finding at debug level
+func AnalyzeWithLogger(logger *slog.Logger, codeUsages []EnvUsage, envVars map[string]string, envVarsFromFiles map[string]string, envKeySources map[string]string, cfg *config.Config) ScanResult {
+	logger = logging.OrDiscard(logger)
+	codeUsages = classifyTestUsages(codeUsages, cfg)
 	result := ScanResult{
 		CodeKeys:            codeUsages,
 		EnvKeys:             envVarsFromFiles, // Store .env file vars for display purposes
 		EnvKeySources:       envKeySources,    // Store source file for each variable
 		Missing:             make(map[string][]EnvUsage),
 		PartialMatches:      make(map[string][]EnvUsage),
+		Confidence:          make(map[string]Confidence),
+		OptionalMissing:     make(map[string][]EnvUsage),
+		TestMissing:         make(map[string][]EnvUsage),
 		Unused:              []string{},
 		IgnoredMissing:      0,
 		IgnoredFromFolders:  0,
@@ -42,11 +54,66 @@ func Analyze(codeUsages []EnvUsage, envVars map[string]string, envVarsFromFiles
 
 	// Track unique variables from ignored folders that would have been missing
 	ignoredFolderVars := make(map[string]bool)
+	// Track variables provided by the OS, CI or a runtime that would have been missing
+	systemVars := make(map[string]bool)
 
 	// Find missing keys (in code but not in envVars - checks both .env and exported env)
 	// Filter out ignored variables and variables from ignored folders
 	for key, usages := range codeKeys {
 		if _, exists := envVars[key]; !exists {
+			// Env files of a nested config only define variables for the files beneath it, and the environment
+			// of a service for the files of the service
+			if cfg != nil && (len(cfg.Scopes) > 0 || len(cfg.Services) > 0) {
+				var undefined []EnvUsage
+				for _, usage := range usages {
+					if !cfg.DefinedIn(key, usage.File) {
+						undefined = append(undefined, usage)
+					}
+				}
+				if len(undefined) == 0 {
+					continue
+				}
+				usages = undefined
+			}
+
+			// Usages in test files don't make a variable missing in production
+			if mode := cfg.TestMode(); mode != config.TestModeInclude {
+				var production, tests []EnvUsage
+				for _, usage := range usages {
+					if usage.InTest {
+						tests = append(tests, usage)
+					} else {
+						production = append(production, usage)
+					}
+				}
+				if len(production) == 0 {
+					if mode == config.TestModeReport {
+						result.TestMissing[key] = tests
+					} else {
+						logger.Debug("ignoring missing variable only used in tests", "key", key)
+						result.IgnoredTests++
+					}
+					continue
+				}
+				usages = production
+			}
+
+			// Drop usages under directories where ignores.paths or a nested config ignores the variable
+			if cfg != nil && (len(cfg.Ignores.Paths) > 0 || len(cfg.Scopes) > 0) {
+				var kept []EnvUsage
+				for _, usage := range usages {
+					if !cfg.ShouldIgnoreMissingIn(key, usage.File) {
+						kept = append(kept, usage)
+					}
+				}
+				if len(kept) == 0 {
+					logger.Debug("ignoring missing variable only used in paths ignoring it (ignores.paths or a nested config)", "key", key)
+					result.IgnoredMissing++
+					continue
+				}
+				usages = kept
+			}
+
 			// Check if all usages are from ignored folders
 			allInIgnoredFolders := true
 			hasIgnoredFolderUsage := false
@@ -60,12 +127,21 @@ func Analyze(codeUsages []EnvUsage, envVars map[string]string, envVarsFromFiles
 			
 			// If all usages are from ignored folders, count it but don't report as missing
 			if allInIgnoredFolders && hasIgnoredFolderUsage {
+				logger.Debug("ignoring missing variable only used in ignored folders", "key", key, "usages", len(usages))
 				ignoredFolderVars[key] = true
 				continue
 			}
 			
+			// Variables like PATH or CI are set by the environment the code runs in, not by the project
+			if cfg.IsSystemVar(key) {
+				logger.Debug("not reporting well-known system variable as missing", "key", key)
+				systemVars[key] = true
+				continue
+			}
+
 			// Check if this variable should be ignored via config
 			if cfg != nil && cfg.ShouldIgnoreMissing(key) {
+				logger.Debug("ignoring missing variable listed in ignores.missing", "key", key)
 				result.IgnoredMissing++
 			} else {
 				// Only include usages that are NOT from ignored folders
@@ -75,62 +151,197 @@ func Analyze(codeUsages []EnvUsage, envVars map[string]string, envVarsFromFiles
 						nonIgnoredUsages = append(nonIgnoredUsages, usage)
 					}
 				}
-				if len(nonIgnoredUsages) > 0 {
+				if len(nonIgnoredUsages) > 0 && allOptional(nonIgnoredUsages) {
+					// Every lookup has a default, so the code still works without the variable
+					result.OptionalMissing[key] = nonIgnoredUsages
+				} else if len(nonIgnoredUsages) > 0 {
 					result.Missing[key] = nonIgnoredUsages
 				}
 			}
 		}
 	}
 	
+	// Required variables must be defined even if no code reads them (e.g., used by a third-party binary)
+	if cfg != nil {
+		for _, key := range cfg.Required {
+			result.Required = append(result.Required, key)
+			if _, exists := envVars[key]; exists {
+				continue
+			}
+			if _, reported := result.Missing[key]; reported {
+				continue
+			}
+			if usages, optional := result.OptionalMissing[key]; optional {
+				// Defaults in code don't help whoever else requires the variable
+				delete(result.OptionalMissing, key)
+				result.Missing[key] = usages
+				continue
+			}
+			if cfg.ShouldIgnoreMissing(key) {
+				if _, counted := codeKeys[key]; !counted {
+					logger.Debug("ignoring missing required variable listed in ignores.missing", "key", key)
+					result.IgnoredMissing++
+				}
+				continue
+			}
+			delete(ignoredFolderVars, key)
+			if systemVars[key] {
+				// Declaring a system variable as required means the project must provide it after all
+				delete(systemVars, key)
+				result.Missing[key] = codeKeys[key]
+				continue
+			}
+			result.Missing[key] = []EnvUsage{}
+		}
+		sort.Strings(result.Required)
+	}
+
 	// Count unique variables from ignored folders
 	result.IgnoredFromFolders = len(ignoredFolderVars)
+	result.IgnoredSystem = len(systemVars)
 
-	// Handle partial matches - check if any env vars contain the partial string
+	// Handle partial matches - expand each dynamic pattern to the defined variables it can resolve to
+	// Variable reference patterns (e.g., process.env[a]) have no static part, so they always stay unresolved
+	dynamicKeys := make(map[string]bool)
 	for key, usages := range partialKeys {
-		// Check if this is a variable reference pattern (e.g., process.env[a])
-		// These should always be reported as partial matches since we can't determine
-		// the actual env var name at static analysis time
-		isVarRef := false
-		for _, usage := range usages {
-			if usage.IsVarRef {
-				isVarRef = true
-				break
-			}
-		}
-		
-		if isVarRef {
-			// Always report variable reference patterns as partial matches
+		matches := expandDynamic(usages, envVars)
+		if len(matches) == 0 {
 			result.PartialMatches[key] = usages
+			result.Confidence[key] = dynamicConfidence(usages, envVars)
 			continue
 		}
-		
-		// For string-based partial matches, check if any env vars contain the partial string
-		hasMatch := false
-		for envKey := range envVars {
-			// Check if any env var contains the partial string
-			// This works for prefix patterns (e.g., "MY_" from "MY_" + var)
-			// suffix patterns (e.g., "_VAR" from var + "_VAR")
-			// and middle patterns (e.g., "fff" from "asdf" + var + "fff")
-			if strings.Contains(envKey, key) {
-				hasMatch = true
-				break
-			}
+		logger.Debug("dynamic pattern matches defined variables, not reporting", "pattern", key, "matches", matches)
+		if result.DynamicMatches == nil {
+			result.DynamicMatches = make(map[string][]string)
 		}
-		
-		// If no match found, add to partial matches
-		if !hasMatch {
-			result.PartialMatches[key] = usages
+		result.DynamicMatches[key] = matches
+		for _, match := range matches {
+			dynamicKeys[match] = true
 		}
 	}
 
 	// Find unused keys (in .env files but not in code)
 	// Only check envVarsFromFiles, not exported environment variables
 	for key := range envVarsFromFiles {
+		if cfg != nil && cfg.IsRequired(key) {
+			logger.Debug("not reporting required variable as unused", "key", key)
+			continue
+		}
+		if dynamicKeys[key] {
+			logger.Debug("not reporting variable used via dynamic access as unused", "key", key)
+			continue
+		}
 		if _, exists := codeKeys[key]; !exists {
+			// Acknowledged variables, e.g., feature flags provisioned ahead of the code that reads them
+			if cfg.ShouldIgnoreUnused(key) || cfg.ShouldIgnoreUnusedIn(key, envKeySources[key]) {
+				logger.Debug("ignoring unused variable listed in ignores", "key", key)
+				result.IgnoredUnused++
+				continue
+			}
+			if !cfg.ReportsUnusedFrom(envKeySources[key]) {
+				logger.Debug("not reporting unused variable from excluded source", "key", key, "source", envKeySources[key])
+				continue
+			}
 			result.Unused = append(result.Unused, key)
 		}
 	}
 
+	// Classify every finding by severity
+	result.Severities = make(map[string]config.Severity)
+	for key := range result.Missing {
+		result.Severities[key] = cfg.SeverityFor(config.CategoryMissing, key)
+	}
+	for key := range result.OptionalMissing {
+		result.Severities[key] = cfg.SeverityFor(config.CategoryOptional, key)
+	}
+	for key := range result.TestMissing {
+		result.Severities[key] = cfg.SeverityFor(config.CategoryTest, key)
+	}
+	for key := range result.PartialMatches {
+		result.Severities[key] = cfg.SeverityFor(config.CategoryDynamic, key)
+	}
+	for _, key := range result.Unused {
+		result.Severities[key] = cfg.SeverityFor(config.CategoryUnused, key)
+	}
+
 	return result
 }
 
+
+// classifyTestUsages returns a copy of usages with InTest set for usages in test files
+func classifyTestUsages(usages []EnvUsage, cfg *config.Config) []EnvUsage {
+	classified := make([]EnvUsage, len(usages))
+	isTest := make(map[string]bool)
+	for i, usage := range usages {
+		test, seen := isTest[usage.File]
+		if !seen {
+			test = cfg.IsTestFile(usage.File)
+			isTest[usage.File] = test
+		}
+		usage.InTest = test
+		classified[i] = usage
+	}
+	return classified
+}
+
+// allOptional reports whether every usage falls back to a default value
+func allOptional(usages []EnvUsage) bool {
+	for _, usage := range usages {
+		if !usage.IsOptional {
+			return false
+		}
+	}
+	return true
+}
+
+// conflicting reports whether two definitions of the same environment set different values, unless they
+// belong to different services, whose environments are separate
+func conflicting(defs []Definition) bool {
+	for i, def := range defs {
+		for _, other := range defs[:i] {
+			if other.Environment != def.Environment || other.Value == def.Value {
+				continue
+			}
+			if other.Service != "" && def.Service != "" && other.Service != def.Service {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveIndex returns the index of the definition that takes effect: the last one for the default
+// environment, or the last one if every definition is environment-specific
+func effectiveIndex(defs []Definition) int {
+	for i := len(defs) - 1; i >= 0; i-- {
+		if defs[i].Environment == "" {
+			return i
+		}
+	}
+	return len(defs) - 1
+}
+
+// DetectConflicts returns the variables whose definitions (in load order) don't all have the same value
+// Redefinitions with an identical value are not conflicts, and neither are different values for different
+// environments (e.g., the env and env_production blocks of a PM2 ecosystem file) or different docker-compose
+// services
+func DetectConflicts(definitions map[string][]Definition) []Conflict {
+	var conflicts []Conflict
+	for key, defs := range definitions {
+		if len(defs) < 2 {
+			continue
+		}
+		if conflicting(defs) {
+			conflicts = append(conflicts, Conflict{
+				Key:         key,
+				Definitions: defs,
+				Effective:   effectiveIndex(defs),
+			})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Key < conflicts[j].Key
+	})
+	return conflicts
+}
@@ -1,9 +1,11 @@
 package analyzer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/schema"
 )
 
 func TestAnalyze_MissingKeys(t *testing.T) {
@@ -139,3 +141,736 @@ func TestAnalyze_IgnoredMissing(t *testing.T) {
 	}
 }
 
+
+func TestAnalyze_Severities(t *testing.T) {
+	codeUsages := []EnvUsage{
+		{Key: "DATABASE_URL", File: "db.go", Line: 20},
+		{Key: "LEGACY_TOKEN", File: "legacy.go", Line: 5},
+	}
+	envFileVars := map[string]string{"UNUSED_VAR": "1"}
+
+	cfg := &config.Config{
+		Severity: config.SeverityConfig{
+			Unused:    config.SeverityError,
+			Variables: map[string]config.Severity{"LEGACY_*": config.SeverityInfo},
+		},
+	}
+
+	result := Analyze(codeUsages, envFileVars, envFileVars, map[string]string{}, cfg)
+
+	expected := map[string]config.Severity{
+		"DATABASE_URL": config.SeverityError,
+		"LEGACY_TOKEN": config.SeverityInfo,
+		"UNUSED_VAR":   config.SeverityError,
+	}
+	for key, want := range expected {
+		if got := result.Severities[key]; got != want {
+			t.Errorf("Expected %s severity %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestAnalyze_Required(t *testing.T) {
+	codeUsages := []EnvUsage{
+		{Key: "DATABASE_URL", File: "db.go", Line: 20},
+	}
+	envFileVars := map[string]string{
+		"DATABASE_URL":  "postgres://localhost",
+		"TF_VAR_region": "eu-west-1",
+	}
+
+	cfg := &config.Config{
+		Required: []string{"TF_VAR_region", "DATADOG_API_KEY", "IGNORED_KEY"},
+		Ignores: config.IgnoresConfig{
+			Missing: []string{"IGNORED_KEY"},
+		},
+	}
+
+	result := Analyze(codeUsages, envFileVars, envFileVars, map[string]string{}, cfg)
+
+	if _, ok := result.Missing["DATADOG_API_KEY"]; !ok {
+		t.Error("DATADOG_API_KEY is required and absent, should be missing")
+	}
+	if _, ok := result.Missing["IGNORED_KEY"]; ok {
+		t.Error("IGNORED_KEY is listed in ignores.missing, should not be missing")
+	}
+	if result.IgnoredMissing != 1 {
+		t.Errorf("Expected 1 ignored missing variable, got %d", result.IgnoredMissing)
+	}
+	if len(result.Unused) != 0 {
+		t.Errorf("Required variables should never be unused, got %v", result.Unused)
+	}
+	if !result.IsRequired("TF_VAR_region") || result.IsRequired("DATABASE_URL") {
+		t.Errorf("Unexpected required list: %v", result.Required)
+	}
+}
+
+func TestAnalyze_OptionalMissing(t *testing.T) {
+	codeUsages := []EnvUsage{
+		{Key: "PORT", File: "server.js", Line: 2, IsOptional: true},
+		{Key: "HOST", File: "server.js", Line: 3, IsOptional: true},
+		{Key: "HOST", File: "worker.js", Line: 7},
+		{Key: "REGION", File: "deploy.js", Line: 1, IsOptional: true},
+	}
+	envVars := map[string]string{}
+
+	cfg := &config.Config{Required: []string{"REGION"}}
+	result := Analyze(codeUsages, envVars, envVars, map[string]string{}, cfg)
+
+	if _, ok := result.OptionalMissing["PORT"]; !ok {
+		t.Error("PORT only has lookups with defaults, should be optional")
+	}
+	if _, ok := result.Missing["HOST"]; !ok {
+		t.Error("HOST has a lookup without a default, should be missing")
+	}
+	if _, ok := result.Missing["REGION"]; !ok {
+		t.Error("REGION is required, should be missing despite its default")
+	}
+	if len(result.OptionalMissing) != 1 {
+		t.Errorf("Expected 1 optional variable, got %v", result.OptionalMissing)
+	}
+	if got := result.SeverityOf(config.CategoryOptional, "PORT"); got != config.SeverityInfo {
+		t.Errorf("Expected optional severity info, got %q", got)
+	}
+}
+
+func TestAnalyze_UnusedSources(t *testing.T) {
+	envFileVars := map[string]string{
+		"APP_SECRET":   "1",
+		"COMPOSE_ONLY": "2",
+		"EXAMPLE_ONLY": "3",
+	}
+	envKeySources := map[string]string{
+		"APP_SECRET":   ".env",
+		"COMPOSE_ONLY": "docker-compose.yml",
+		"EXAMPLE_ONLY": ".env.example",
+	}
+
+	cfg := &config.Config{
+		Unused: config.UnusedConfig{
+			ExcludeSources:  []string{"docker-compose*.yml"},
+			ExcludeExamples: true,
+		},
+	}
+
+	result := Analyze(nil, envFileVars, envFileVars, envKeySources, cfg)
+
+	if len(result.Unused) != 1 || result.Unused[0] != "APP_SECRET" {
+		t.Errorf("Expected only APP_SECRET to be unused, got %v", result.Unused)
+	}
+}
+
+func TestDetectConflicts(t *testing.T) {
+	definitions := map[string][]Definition{
+		"API_KEY": {
+			{File: ".env", Line: 1, Value: "dev"},
+			{File: "docker-compose.yml", Line: 9, Value: "prod"},
+		},
+		"SAME_VALUE": {
+			{File: ".env", Line: 2, Value: "1"},
+			{File: ".env.local", Line: 1, Value: "1"},
+		},
+		"SINGLE": {
+			{File: ".env", Line: 3, Value: "x"},
+		},
+	}
+
+	conflicts := DetectConflicts(definitions)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", conflicts)
+	}
+	conflict := conflicts[0]
+	if conflict.Key != "API_KEY" || len(conflict.Definitions) != 2 {
+		t.Errorf("Unexpected conflict: %+v", conflict)
+	}
+	if effective := conflict.Definitions[conflict.Effective]; effective.File != "docker-compose.yml" {
+		t.Errorf("Expected the last definition to take effect, got %+v", effective)
+	}
+}
+
+func TestDetectConflicts_Services(t *testing.T) {
+	definitions := map[string][]Definition{
+		// Each compose service has its own environment
+		"LOG_LEVEL": {
+			{File: "docker-compose.yml", Line: 5, Value: "info", Service: "api"},
+			{File: "docker-compose.yml", Line: 9, Value: "debug", Service: "worker"},
+		},
+		// A shared env file still conflicts with a service
+		"API_URL": {
+			{File: ".env", Line: 1, Value: "http://localhost"},
+			{File: "docker-compose.yml", Line: 6, Value: "http://api", Service: "api"},
+		},
+	}
+
+	conflicts := DetectConflicts(definitions)
+	if len(conflicts) != 1 || conflicts[0].Key != "API_URL" {
+		t.Errorf("Expected only API_URL to conflict, got %+v", conflicts)
+	}
+}
+
+func TestDetectConflicts_Environments(t *testing.T) {
+	definitions := map[string][]Definition{
+		// Different values for different environments of a PM2 app are expected
+		"API_URL": {
+			{File: "ecosystem.config.js", Line: 5, Value: "http://localhost"},
+			{File: "ecosystem.config.js", Line: 8, Value: "https://api.example.com", Environment: "production"},
+		},
+		"LOG_LEVEL": {
+			{File: "ecosystem.config.js", Line: 6, Value: "debug"},
+			{File: "ecosystem.config.js", Line: 9, Value: "warn", Environment: "production"},
+			{File: ".env", Line: 1, Value: "info"},
+		},
+	}
+
+	conflicts := DetectConflicts(definitions)
+	if len(conflicts) != 1 || conflicts[0].Key != "LOG_LEVEL" {
+		t.Fatalf("Expected only LOG_LEVEL to conflict, got %+v", conflicts)
+	}
+	if conflicts[0].Effective != 2 {
+		t.Errorf("Expected the .env definition to take effect, got %d", conflicts[0].Effective)
+	}
+	if got := effectiveIndex(definitions["API_URL"][1:]); got != 0 {
+		t.Errorf("Expected the only definition to take effect, got %d", got)
+	}
+}
+
+func TestDetectExampleDrift(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "API_KEY", File: "main.go", Line: 3},
+		{Key: "NEW_FLAG", File: "main.go", Line: 4},
+		{Key: "FEATURE_", File: "main.go", Line: 5, IsPartial: true},
+	}
+	definitions := map[string][]Definition{
+		"API_KEY":      {{File: ".env.example", Line: 1, Kind: "env"}, {File: ".env", Line: 1, Kind: "env"}},
+		"OLD_FLAG":     {{File: ".env.example", Line: 2, Kind: "env"}},
+		"FEATURE_BETA": {{File: ".env.example", Line: 3, Kind: "env"}},
+		"LOCAL_ONLY":   {{File: ".env", Line: 2, Kind: "env"}},
+		"COMPOSE_ONLY": {{File: "docker-compose.yml", Line: 7, Kind: "docker-compose"}},
+	}
+
+	drift := DetectExampleDrift(usages, definitions, &config.Config{})
+	if drift == nil {
+		t.Fatal("Expected drift to be detected")
+	}
+	if len(drift.Examples) != 1 || drift.Examples[0] != ".env.example" {
+		t.Errorf("Unexpected examples: %v", drift.Examples)
+	}
+	if len(drift.Undocumented) != 2 || len(drift.Undocumented["NEW_FLAG"]) != 1 {
+		t.Errorf("Expected NEW_FLAG (code) and LOCAL_ONLY (.env) to be undocumented, got %v", drift.Undocumented)
+	}
+	if defs := drift.Definitions["LOCAL_ONLY"]; len(defs) != 1 || defs[0].File != ".env" {
+		t.Errorf("Expected LOCAL_ONLY definition in .env, got %+v", defs)
+	}
+	if len(drift.Stale) != 1 || drift.Stale[0] != "OLD_FLAG" {
+		t.Errorf("Expected only OLD_FLAG to be stale (FEATURE_BETA matches a dynamic prefix), got %v", drift.Stale)
+	}
+
+	delete(definitions, "OLD_FLAG")
+	delete(definitions, "API_KEY")
+	delete(definitions, "FEATURE_BETA")
+	if drift := DetectExampleDrift(usages, definitions, &config.Config{}); drift != nil {
+		t.Errorf("Expected no drift without example files, got %+v", drift)
+	}
+}
+
+func TestDetectFrontendLeaks(t *testing.T) {
+	next, _ := FrameworkByName(config.FrameworkNext)
+	usages := []EnvUsage{
+		{Key: "NEXT_PUBLIC_API_URL", File: "components/Nav.tsx", Line: 1},
+		{Key: "DATABASE_URL", File: "pages/index.tsx", Line: 2},
+		{Key: "DATABASE_URL", File: "pages/api/users.ts", Line: 3},
+		{Key: "NODE_ENV", File: "components/Nav.tsx", Line: 4},
+		{Key: "NEXT_PUBLIC_AUTH_TOKEN", File: "lib/auth.ts", Line: 5},
+	}
+	definitions := map[string][]Definition{
+		"NEXT_PUBLIC_DB_PASSWORD": {{File: ".env", Line: 1, Kind: "env"}},
+		"NEXT_PUBLIC_API_URL":     {{File: ".env", Line: 2, Kind: "env"}},
+	}
+
+	leaks := DetectFrontendLeaks(next, usages, definitions, &config.Config{})
+	if len(leaks.Unprefixed) != 1 || len(leaks.Unprefixed["DATABASE_URL"]) != 1 {
+		t.Errorf("Expected only the pages/index.tsx DATABASE_URL usage to be unprefixed, got %v", leaks.Unprefixed)
+	}
+	if len(leaks.Exposed) != 2 || len(leaks.Exposed["NEXT_PUBLIC_AUTH_TOKEN"]) != 1 || leaks.Definitions["NEXT_PUBLIC_DB_PASSWORD"] == nil {
+		t.Errorf("Expected NEXT_PUBLIC_AUTH_TOKEN and NEXT_PUBLIC_DB_PASSWORD to be exposed, got %v", leaks.Exposed)
+	}
+
+	cfg := &config.Config{Frontend: config.FrontendConfig{ClientDirs: []string{"lib"}, SecretWords: []string{"PASSWORD"}}}
+	leaks = DetectFrontendLeaks(next, usages, definitions, cfg)
+	if len(leaks.Unprefixed) != 0 {
+		t.Errorf("Expected no unprefixed usages under lib, got %v", leaks.Unprefixed)
+	}
+	if _, ok := leaks.Exposed["NEXT_PUBLIC_DB_PASSWORD"]; !ok || len(leaks.Exposed) != 1 {
+		t.Errorf("Expected only NEXT_PUBLIC_DB_PASSWORD to be exposed, got %v", leaks.Exposed)
+	}
+}
+
+func TestDetectFrontendLeaks_NextConfigEnv(t *testing.T) {
+	next, _ := FrameworkByName(config.FrameworkNext)
+	usages := []EnvUsage{
+		{Key: "API_URL", File: "components/Nav.tsx", Line: 1},
+	}
+	definitions := map[string][]Definition{
+		"API_URL":       {{File: "next.config.js", Line: 3, Kind: "next-config"}},
+		"STRIPE_SECRET": {{File: "next.config.js", Line: 4, Kind: "next-config"}},
+		"DB_PASSWORD":   {{File: ".env", Line: 1, Kind: "env"}},
+	}
+
+	// The env block of next.config.js is inlined into client code, whatever the prefix
+	leaks := DetectFrontendLeaks(next, usages, definitions, &config.Config{})
+	if len(leaks.Unprefixed) != 0 {
+		t.Errorf("Expected API_URL from next.config.js not to be unprefixed, got %v", leaks.Unprefixed)
+	}
+	if _, ok := leaks.Exposed["STRIPE_SECRET"]; !ok || len(leaks.Exposed) != 1 {
+		t.Errorf("Expected only STRIPE_SECRET to be exposed, got %v", leaks.Exposed)
+	}
+}
+
+func TestDetectStyleViolations(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "apiKey", File: "app.js", Line: 1},
+		{Key: "MYAPP_PORT", File: "app.js", Line: 2},
+		{Key: "legacy_", File: "app.js", Line: 3, IsPartial: true},
+	}
+	definitions := map[string][]Definition{
+		"apiKey":  {{File: ".env", Line: 1}},
+		"DB_HOST": {{File: ".env", Line: 2}},
+	}
+	cfg := &config.Config{Naming: config.NamingConfig{UpperSnakeCase: true, Prefix: "MYAPP_"}}
+
+	violations := DetectStyleViolations(usages, definitions, cfg)
+	if len(violations) != 2 || violations[0].Key != "DB_HOST" || violations[1].Key != "apiKey" {
+		t.Fatalf("Expected DB_HOST and apiKey to break the rules, got %+v", violations)
+	}
+	if apiKey := violations[1]; len(apiKey.Problems) != 2 || len(apiKey.Usages) != 1 || len(apiKey.Definitions) != 1 || apiKey.Severity != config.SeverityWarning {
+		t.Errorf("Unexpected apiKey violation: %+v", apiKey)
+	}
+
+	if violations := DetectStyleViolations(usages, definitions, &config.Config{}); violations != nil {
+		t.Errorf("Expected no violations without naming rules, got %+v", violations)
+	}
+}
+
+func TestAnalyzeSystemVars(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "CI", File: "main.go", Line: 1},
+		{Key: "GOPATH", File: "main.go", Line: 2},
+		{Key: "APP_PORT", File: "main.go", Line: 3},
+	}
+
+	result := Analyze(usages, map[string]string{}, map[string]string{}, map[string]string{}, &config.Config{})
+	if len(result.Missing) != 1 || result.Missing["APP_PORT"] == nil {
+		t.Errorf("Expected only APP_PORT to be missing, got %v", result.Missing)
+	}
+	if result.IgnoredSystem != 2 {
+		t.Errorf("Expected 2 system variables, got %d", result.IgnoredSystem)
+	}
+
+	cfg := &config.Config{Required: []string{"CI"}, SystemVars: config.SystemVarsConfig{Extra: []string{"APP_*"}}}
+	result = Analyze(usages, map[string]string{}, map[string]string{}, map[string]string{}, cfg)
+	if len(result.Missing) != 1 || len(result.Missing["CI"]) != 1 {
+		t.Errorf("Expected the required system variable CI to be missing with its usage, got %v", result.Missing)
+	}
+	if result.IgnoredSystem != 2 {
+		t.Errorf("Expected GOPATH and APP_PORT to be system variables, got %d", result.IgnoredSystem)
+	}
+}
+
+func TestAnalyzeIgnorePatterns(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "AWS_REGION", File: "main.go", Line: 1},
+		{Key: "DEBUG_TOKEN", File: "tools/seed.go", Line: 2},
+		{Key: "SEED_COUNT", File: "tools/seed.go", Line: 3},
+		{Key: "SEED_COUNT", File: "main.go", Line: 4},
+	}
+	envFromFiles := map[string]string{"FEATURE_BETA": "1", "STALE": "x"}
+	cfg := &config.Config{Ignores: config.IgnoresConfig{
+		Missing: []string{"/^AWS_/"},
+		Unused:  []string{"FEATURE_*"},
+		Paths:   map[string][]string{"tools": {"DEBUG_TOKEN", "SEED_*"}},
+	}}
+
+	result := Analyze(usages, envFromFiles, envFromFiles, map[string]string{"FEATURE_BETA": ".env", "STALE": ".env"}, cfg)
+	if len(result.Missing) != 1 || len(result.Missing["SEED_COUNT"]) != 1 || result.Missing["SEED_COUNT"][0].File != "main.go" {
+		t.Errorf("Expected only the main.go usage of SEED_COUNT to be missing, got %v", result.Missing)
+	}
+	if result.IgnoredMissing != 2 {
+		t.Errorf("Expected AWS_REGION and DEBUG_TOKEN to be ignored, got %d", result.IgnoredMissing)
+	}
+	if len(result.Unused) != 1 || result.Unused[0] != "STALE" {
+		t.Errorf("Expected only STALE to be unused, got %v", result.Unused)
+	}
+	if result.IgnoredUnused != 1 {
+		t.Errorf("Expected FEATURE_BETA to be counted as ignored, got %d", result.IgnoredUnused)
+	}
+}
+
+func TestDetectDeprecated(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "OLD_DB_URL", File: "db.go", Line: 7},
+		{Key: "DATABASE_URL", File: "db.go", Line: 8},
+	}
+	definitions := map[string][]Definition{
+		"OLD_DB_URL":  {{File: ".env", Line: 1}},
+		"OLD_API_KEY": {{File: ".env", Line: 2}},
+	}
+	cfg := &config.Config{Deprecated: map[string]string{"OLD_*": "migrate to the new names"}}
+
+	deprecated := DetectDeprecated(usages, definitions, cfg)
+	if len(deprecated) != 2 || deprecated[0].Key != "OLD_API_KEY" || deprecated[1].Key != "OLD_DB_URL" {
+		t.Fatalf("Expected OLD_API_KEY and OLD_DB_URL, got %+v", deprecated)
+	}
+	if v := deprecated[1]; len(v.Usages) != 1 || len(v.Definitions) != 1 || v.Hint != "migrate to the new names" {
+		t.Errorf("Unexpected OLD_DB_URL finding: %+v", v)
+	}
+	if v := deprecated[0]; len(v.Usages) != 0 || len(v.Definitions) != 1 {
+		t.Errorf("Expected OLD_API_KEY to be flagged only for removal, got %+v", v)
+	}
+}
+
+func TestDetectForbidden(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "AWS_SECRET_ACCESS_KEY", File: "s3.go", Line: 12},
+		{Key: "AWS_", File: "s3.go", Line: 14, IsPartial: true},
+		{Key: "AWS_REGION", File: "s3.go", Line: 13},
+	}
+	definitions := map[string][]Definition{
+		"AWS_SECRET_ACCESS_KEY": {{File: ".env", Line: 4}},
+		"GITHUB_PAT_DEPLOY":     {{File: ".env", Line: 5}},
+	}
+	cfg := &config.Config{Forbidden: config.ForbiddenConfig{
+		"AWS_SECRET_ACCESS_KEY": "use the SDK credential chain",
+		"GITHUB_PAT_*":          "",
+	}}
+
+	forbidden := DetectForbidden(usages, definitions, cfg)
+	if len(forbidden) != 2 || forbidden[0].Key != "AWS_SECRET_ACCESS_KEY" || forbidden[1].Key != "GITHUB_PAT_DEPLOY" {
+		t.Fatalf("Expected AWS_SECRET_ACCESS_KEY and GITHUB_PAT_DEPLOY, got %+v", forbidden)
+	}
+	if v := forbidden[0]; len(v.Usages) != 1 || len(v.Definitions) != 1 || v.Message != "use the SDK credential chain" || v.Severity != config.SeverityError {
+		t.Errorf("Unexpected AWS_SECRET_ACCESS_KEY finding: %+v", v)
+	}
+	if DetectForbidden(usages, definitions, &config.Config{}) != nil {
+		t.Error("Expected no findings without forbidden variables")
+	}
+}
+
+func TestDetectTypeMismatches(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "PORT", File: "main.go", Line: 7, Type: "integer"},
+		{Key: "PORT", File: "main.go", Line: 9},
+		{Key: "DEBUG", File: "main.go", Line: 8, Type: "boolean"},
+		{Key: "RATIO", File: "main.go", Line: 10, Type: "number"},
+	}
+	definitions := map[string][]Definition{
+		"PORT":  {{File: ".env", Line: 1, Value: "abc"}, {File: ".env.example", Line: 1, Value: "<port>"}, {File: ".env.prod", Line: 1, Value: "8080"}},
+		"DEBUG": {{File: ".env", Line: 2, Value: "true"}},
+		"RATIO": {{File: ".env", Line: 3, Value: "${DEFAULT_RATIO}"}},
+	}
+
+	mismatches := DetectTypeMismatches(usages, definitions, nil)
+	if len(mismatches) != 1 {
+		t.Fatalf("Expected only PORT to mismatch, got %+v", mismatches)
+	}
+	m := mismatches[0]
+	if m.Key != "PORT" || m.Type != "integer" || m.Severity != config.SeverityError {
+		t.Errorf("Unexpected finding: %+v", m)
+	}
+	if len(m.Usages) != 1 || len(m.Definitions) != 1 || m.Definitions[0].File != ".env" {
+		t.Errorf("Expected the .env value and the parsing usage, got %+v", m)
+	}
+}
+
+func TestDetectPlaceholders(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "API_KEY", File: "api.go", Line: 3},
+		{Key: "STRIPE_KEY", File: "pay.go", Line: 4},
+		{Key: "SENTRY_DSN", File: "main.go", Line: 5},
+		{Key: "LOG_LEVEL", File: "main.go", Line: 6, IsOptional: true},
+		{Key: "DB_PASSWORD", File: "db.go", Line: 7},
+		{Key: "TOKEN", File: "auth.go", Line: 8},
+		{Key: "REGION", File: "aws.go", Line: 9},
+	}
+	definitions := map[string][]Definition{
+		"API_KEY":     {{File: ".env", Line: 1, Value: "changeme", Kind: "env"}},
+		"STRIPE_KEY":  {{File: ".env", Line: 2, Value: "<your-key-here>", Kind: "env"}},
+		"SENTRY_DSN":  {{File: ".env", Line: 3, Value: "", Kind: "env"}},
+		"LOG_LEVEL":   {{File: ".env", Line: 4, Value: "", Kind: "env"}},
+		"DB_PASSWORD": {{File: "docker-compose.yml", Line: 5, Value: "", Kind: "docker-compose"}},
+		"TOKEN":       {{File: ".env", Line: 6, Value: "xxx", Kind: "env"}, {File: ".env.local", Line: 1, Value: "t0k3n", Kind: "env"}},
+		"REGION":      {{File: ".env.example", Line: 1, Value: "TODO", Kind: "env"}},
+	}
+
+	placeholders := DetectPlaceholders(usages, definitions, nil)
+	var got []string
+	for _, p := range placeholders {
+		got = append(got, p.Key)
+	}
+	// LOG_LEVEL has a fallback, DB_PASSWORD passes through from the host, TOKEN is overridden, REGION only in an example
+	if want := "API_KEY, SENTRY_DSN, STRIPE_KEY"; strings.Join(got, ", ") != want {
+		t.Fatalf("Expected %s, got %v", want, got)
+	}
+	if !placeholders[1].Empty || placeholders[0].Empty || placeholders[0].Severity != config.SeverityWarning {
+		t.Errorf("Unexpected findings: %+v", placeholders)
+	}
+}
+
+func TestDetectUnresolvedReferences(t *testing.T) {
+	definitions := map[string][]Definition{
+		"DATABASE_URL": {{File: ".env", Line: 3, Value: "postgres://${DB_HOST}/app", Kind: "env", Unresolved: []string{"DB_HOST"}}},
+		"CACHE_DIR":    {{File: ".env", Line: 4, Value: "${HOME}/cache", Kind: "env", Unresolved: []string{"HOME"}}},
+		"API_URL":      {{File: ".env.example", Line: 1, Value: "${API_HOST}/v1", Kind: "env", Unresolved: []string{"API_HOST"}}},
+		"REGION":       {{File: ".env", Line: 5, Value: "eu-west-1", Kind: "env"}},
+	}
+
+	refs := DetectUnresolvedReferences(definitions, nil)
+	// HOME is a system variable, API_URL is only in an example file
+	if len(refs) != 1 || refs[0].Key != "DATABASE_URL" || strings.Join(refs[0].References, ", ") != "DB_HOST" {
+		t.Fatalf("Expected DATABASE_URL to reference DB_HOST, got %+v", refs)
+	}
+	if refs[0].Severity != config.SeverityWarning || refs[0].Definition.Line != 3 {
+		t.Errorf("Unexpected finding: %+v", refs[0])
+	}
+}
+
+func TestIsPlaceholder(t *testing.T) {
+	for _, value := range []string{"changeme", "TODO", "<your-key-here>", "xxx", "XXXX-XXXX", "your_api_key_here", "****"} {
+		if !IsPlaceholder(value) {
+			t.Errorf("Expected %q to be a placeholder", value)
+		}
+	}
+	for _, value := range []string{"production", "sk_live_123", "xx", "todo-app", "<html>x"} {
+		if IsPlaceholder(value) {
+			t.Errorf("Expected %q not to be a placeholder", value)
+		}
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	definitions := map[string][]Definition{
+		"DATABASE_URL": {{File: ".env", Line: 1, Value: "localhost:5432"}, {File: ".env.example", Line: 1, Value: "<url>"}},
+		"API_PORT":     {{File: ".env", Line: 2, Value: "70000"}},
+		"LOG_LEVEL":    {{File: ".env", Line: 3, Value: "verbose"}},
+		"WORKERS":      {{File: ".env", Line: 4, Value: "many"}},
+		"CALLBACK_URL": {{File: ".env", Line: 5, Value: "/callback"}},
+		"REDIS_URL":    {{File: ".env", Line: 6, Value: "${CACHE_URL}"}},
+	}
+	s := &schema.Schema{Variables: map[string]schema.Variable{
+		"LOG_LEVEL":    {Enum: []string{"debug", "info"}},
+		"WORKERS":      {Type: "integer"},
+		"CALLBACK_URL": {Format: schema.FormatNone},
+	}}
+
+	invalid := ValidateValues(definitions, s, t.TempDir())
+	var got []string
+	for _, v := range invalid {
+		rule := v.Rule
+		if v.Inferred {
+			rule += " (inferred)"
+		}
+		got = append(got, v.Key+": "+rule)
+	}
+	want := "API_PORT: port (inferred), DATABASE_URL: url (inferred), LOG_LEVEL: enum, WORKERS: type"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestAnalyzeTestUsages(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "TEST_DB", File: "db_test.go", Line: 1},
+		{Key: "DB_URL", File: "db.go", Line: 2},
+		{Key: "DB_URL", File: "db_test.go", Line: 3},
+	}
+	empty := map[string]string{}
+
+	result := Analyze(usages, empty, empty, empty, &config.Config{})
+	if len(result.Missing) != 2 {
+		t.Errorf("Expected test usages to count by default, got %v", result.Missing)
+	}
+
+	result = Analyze(usages, empty, empty, empty, &config.Config{Tests: config.TestsConfig{Mode: config.TestModeExclude}})
+	if len(result.Missing) != 1 || len(result.Missing["DB_URL"]) != 1 || result.IgnoredTests != 1 {
+		t.Errorf("Expected only the production usage of DB_URL to be missing, got %v (ignored %d)", result.Missing, result.IgnoredTests)
+	}
+
+	result = Analyze(usages, empty, empty, empty, &config.Config{Tests: config.TestsConfig{Mode: config.TestModeReport}})
+	if len(result.TestMissing) != 1 || result.TestMissing["TEST_DB"] == nil || !result.TestMissing["TEST_DB"][0].InTest {
+		t.Errorf("Expected TEST_DB to be reported as test-only, got %v", result.TestMissing)
+	}
+	if result.SeverityOf(config.CategoryTest, "TEST_DB") != config.SeverityInfo {
+		t.Errorf("Expected test-only variables to be info, got %q", result.SeverityOf(config.CategoryTest, "TEST_DB"))
+	}
+}
+
+func TestAnalyzeDynamicMatches(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "FEATURE_", FullExpr: `"FEATURE_" + name`, File: "flags.js", Line: 1, IsPartial: true},
+		{Key: "_URL", FullExpr: `service + "_URL"`, File: "client.js", Line: 2, IsPartial: true},
+		{Key: "LEGACY_", FullExpr: `"LEGACY_" + name`, File: "old.js", Line: 3, IsPartial: true},
+		{Key: "name", FullExpr: "process.env[name]", File: "any.js", Line: 4, IsPartial: true, IsVarRef: true},
+	}
+	envVars := map[string]string{"FEATURE_A": "1", "FEATURE_B": "0", "API_URL": "x", "UNUSED_KEY": "y", "MY_FEATURE_C": "1"}
+
+	result := Analyze(usages, envVars, envVars, map[string]string{}, &config.Config{})
+	if got := result.DynamicMatches[`"FEATURE_" + name`]; len(got) != 2 || got[0] != "FEATURE_A" || got[1] != "FEATURE_B" {
+		t.Errorf("Expected the FEATURE_ prefix to resolve to FEATURE_A and FEATURE_B, got %v", got)
+	}
+	if got := result.DynamicMatches[`service + "_URL"`]; len(got) != 1 || got[0] != "API_URL" {
+		t.Errorf("Expected the _URL suffix to resolve to API_URL, got %v", got)
+	}
+	if _, ok := result.PartialMatches[`"LEGACY_" + name`]; !ok {
+		t.Error("Expected the unmatched LEGACY_ prefix to stay unresolved")
+	}
+	if _, ok := result.PartialMatches["process.env[name]"]; !ok {
+		t.Error("Expected the variable reference to stay unresolved")
+	}
+	if len(result.Unused) != 2 {
+		t.Errorf("Expected only UNUSED_KEY and MY_FEATURE_C to be unused, got %v", result.Unused)
+	}
+}
+
+func TestDynamicConfidence(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "FEATURE_", FullExpr: `"FEATURE_" + name`, File: "flags.js", Line: 1, IsPartial: true},
+		{Key: "DB_", FullExpr: `"DB_" + name`, File: "db.js", Line: 2, IsPartial: true},
+		{Key: "X", FullExpr: `"X" + lookup(cfg)[0]`, File: "any.js", Line: 3, IsPartial: true},
+		{Key: "name", FullExpr: "process.env[name]", File: "any.js", Line: 4, IsPartial: true, IsVarRef: true},
+	}
+	envVars := map[string]string{"API_KEY": "x"}
+
+	result := Analyze(usages, envVars, envVars, map[string]string{}, &config.Config{})
+	want := map[string]Confidence{
+		`"FEATURE_" + name`:    ConfidenceHigh,
+		`"DB_" + name`:         ConfidenceMedium,
+		`"X" + lookup(cfg)[0]`: ConfidenceLow,
+		"process.env[name]":    ConfidenceLow,
+	}
+	for key, confidence := range want {
+		if got := result.Confidence[key]; got != confidence {
+			t.Errorf("Expected %s to have %s confidence, got %q", key, confidence, got)
+		}
+	}
+
+	result.FilterConfidence(ConfidenceMedium)
+	if len(result.PartialMatches) != 2 || result.IgnoredDynamic != 2 {
+		t.Errorf("Expected the two low-confidence patterns to be dropped, got %v (ignored %d)", result.PartialMatches, result.IgnoredDynamic)
+	}
+
+	if c, err := ParseConfidence("MEDIUM"); err != nil || c != ConfidenceMedium {
+		t.Errorf("Expected MEDIUM to parse, got %q (%v)", c, err)
+	}
+	if _, err := ParseConfidence("certain"); err == nil {
+		t.Error("Expected an unknown confidence to be rejected")
+	}
+}
+
+func TestFilterOwner(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "API_URL", File: "web/app.js", Line: 1, Owners: []string{"@org/web"}},
+		{Key: "API_URL", File: "api/main.go", Line: 1, Owners: []string{"@org/api"}},
+		{Key: "DB_URL", File: "api/db.go", Line: 2, Owners: []string{"@org/api"}},
+		{Key: "SCRIPT_TOKEN", File: "scripts/run.sh", Line: 3},
+	}
+	envVars := map[string]string{"OLD_FLAG": "1"}
+	result := Analyze(usages, envVars, envVars, map[string]string{"OLD_FLAG": ".env"}, &config.Config{})
+	result.EnvKeyOwners = map[string][]string{"OLD_FLAG": {"@org/web"}}
+
+	groups := result.ByOwner(false, true)
+	if got := groups["@org/api"]; got == nil || len(got.Missing) != 2 {
+		t.Errorf("Expected @org/api to own API_URL and DB_URL, got %+v", got)
+	}
+	if got := groups["@org/web"]; got == nil || len(got.Missing) != 1 || len(got.Unused) != 1 {
+		t.Errorf("Expected @org/web to own API_URL and OLD_FLAG, got %+v", got)
+	}
+	if got := groups[NoOwner]; got == nil || len(got.Missing) != 1 || got.Missing[0] != "SCRIPT_TOKEN" {
+		t.Errorf("Expected SCRIPT_TOKEN to be unowned, got %+v", got)
+	}
+
+	result.FilterOwner("@ORG/WEB")
+	if len(result.Missing) != 1 || len(result.Missing["API_URL"]) != 1 || result.Missing["API_URL"][0].File != "web/app.js" {
+		t.Errorf("Expected only the web usage of API_URL to remain, got %v", result.Missing)
+	}
+	if len(result.Unused) != 1 {
+		t.Errorf("Expected OLD_FLAG to remain unused, got %v", result.Unused)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "STRIPE_KEY", File: "src/payments/stripe.js", Line: 1},
+		{Key: "STRIPE_KEY", File: "src/web/checkout.js", Line: 2},
+		{Key: "STRIPE_WEBHOOK", File: "src/payments/hooks/webhook.js", Line: 3},
+		{Key: "DB_URL", File: "src/db.js", Line: 4},
+	}
+	envVars := map[string]string{"STRIPE_OLD": "1", "LEGACY": "1"}
+	sources := map[string]string{"STRIPE_OLD": "src/payments/.env", "LEGACY": ".env"}
+	analyze := func() ScanResult {
+		return Analyze(usages, envVars, envVars, sources, &config.Config{})
+	}
+
+	filter, err := NewFilter(nil, []string{"STRIPE_*"}, []string{"STRIPE_WEBHOOK"}, []string{"src/payments/**"})
+	if err != nil {
+		t.Fatalf("NewFilter failed: %v", err)
+	}
+	result := analyze()
+	result.Filter(filter)
+	if len(result.Missing) != 1 || len(result.Missing["STRIPE_KEY"]) != 1 || result.Missing["STRIPE_KEY"][0].File != "src/payments/stripe.js" {
+		t.Errorf("Expected only the payments usage of STRIPE_KEY, got %v", result.Missing)
+	}
+	if len(result.Unused) != 1 || result.Unused[0] != "STRIPE_OLD" {
+		t.Errorf("Expected only STRIPE_OLD to remain unused, got %v", result.Unused)
+	}
+
+	filter, err = NewFilter([]string{"unused"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewFilter failed: %v", err)
+	}
+	result = analyze()
+	result.Filter(filter)
+	if len(result.Missing) != 0 || len(result.Unused) != 2 {
+		t.Errorf("Expected only unused findings, got %v and %v", result.Missing, result.Unused)
+	}
+
+	if filter, err := NewFilter(nil, nil, nil, nil); filter != nil || err != nil {
+		t.Errorf("Expected no filter, got %v, %v", filter, err)
+	}
+	if _, err := NewFilter([]string{"missing,secrets"}, nil, nil, nil); err == nil || !strings.Contains(err.Error(), `unknown category "secrets"`) {
+		t.Errorf("Expected an unknown category error, got %v", err)
+	}
+	if _, err := NewFilter(nil, []string{"/[/"}, nil, nil); err == nil {
+		t.Error("Expected an invalid pattern error")
+	}
+}
+
+func TestDetectSchemaDrift(t *testing.T) {
+	s := &schema.Schema{Variables: map[string]schema.Variable{
+		"PORT":         {Type: "number", Required: true},
+		"FEATURE_BETA": {Type: "boolean", Required: true},
+		"LEGACY_TOKEN": {Required: true},
+		"SENTRY_DSN":   {Required: false},
+	}}
+	usages := []EnvUsage{
+		{Key: "PORT", File: "main.go", Line: 3},
+		{Key: "FEATURE_", File: "flags.go", Line: 9, IsPartial: true},
+		{Key: "REDIS_URL", File: "cache.go", Line: 4},
+		{Key: "TOOL_TOKEN", File: "tools/main.go", Line: 2, InIgnoredPath: true},
+		{Key: "PATH", File: "main.go", Line: 5},
+	}
+	cfg := &config.Config{Severity: config.SeverityConfig{Undeclared: config.SeverityError}}
+
+	drift := DetectSchemaDrift(usages, s, ".envgrd.schema.json", cfg)
+	// FEATURE_BETA can be read through the FEATURE_ pattern, SENTRY_DSN is optional
+	if len(drift.Unread) != 1 || drift.Unread[0] != "LEGACY_TOKEN" {
+		t.Errorf("Expected LEGACY_TOKEN to be unread, got %v", drift.Unread)
+	}
+	if len(drift.Undeclared) != 1 || len(drift.Undeclared["REDIS_URL"]) != 1 {
+		t.Errorf("Expected REDIS_URL to be undeclared, got %v", drift.Undeclared)
+	}
+	if severity := drift.SeverityOf(config.CategoryUndeclared, "REDIS_URL"); severity != config.SeverityError {
+		t.Errorf("Expected the configured severity, got %s", severity)
+	}
+	if DetectSchemaDrift(usages, nil, "", cfg) != nil {
+		t.Error("Expected no drift without a schema")
+	}
+}
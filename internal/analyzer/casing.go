@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// MatchCase returns a copy of usages where variables that are only defined with another casing take
+// the defined name, as on Windows where variable names are case-insensitive (Path in code reads PATH),
+// and the case-only mismatches, sorted by key
+// Dynamic patterns are left as they are
+func MatchCase(usages []EnvUsage, envVars map[string]string) ([]EnvUsage, []CaseMismatch) {
+	folded := make(map[string][]string)
+	for key := range envVars {
+		lower := strings.ToLower(key)
+		folded[lower] = append(folded[lower], key)
+	}
+	for _, keys := range folded {
+		sort.Strings(keys)
+	}
+
+	matched := make([]EnvUsage, len(usages))
+	mismatches := make(map[string]*CaseMismatch)
+	for i, usage := range usages {
+		matched[i] = usage
+		if usage.IsPartial || usage.IsVarRef {
+			continue
+		}
+		if _, exists := envVars[usage.Key]; exists {
+			continue
+		}
+		candidates := folded[strings.ToLower(usage.Key)]
+		if len(candidates) == 0 {
+			continue
+		}
+		// Several casings of one name can only all be defined on case-sensitive systems, take the first
+		defined := candidates[0]
+		matched[i].Key = defined
+		mismatch, ok := mismatches[usage.Key]
+		if !ok {
+			mismatch = &CaseMismatch{Key: usage.Key, Defined: defined}
+			mismatches[usage.Key] = mismatch
+		}
+		mismatch.Usages = append(mismatch.Usages, usage)
+	}
+
+	sorted := make([]CaseMismatch, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		sorted = append(sorted, *mismatch)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	return matched, sorted
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestMatchCase(t *testing.T) {
+	usages := []EnvUsage{
+		{Key: "Path", File: "main.go", Line: 1},
+		{Key: "api_key", File: "main.go", Line: 2},
+		{Key: "api_key", File: "util.go", Line: 7},
+		{Key: "HOME", File: "main.go", Line: 3},
+		{Key: "MISSING", File: "main.go", Line: 4},
+		{Key: "path", File: "main.go", Line: 5, IsPartial: true},
+	}
+	envVars := map[string]string{"PATH": "/bin", "API_KEY": "abc", "Api_Key": "def", "HOME": "/root"}
+
+	matched, mismatches := MatchCase(usages, envVars)
+
+	wantKeys := []string{"PATH", "API_KEY", "API_KEY", "HOME", "MISSING", "path"}
+	for i, usage := range matched {
+		if usage.Key != wantKeys[i] {
+			t.Errorf("Expected usage %d to match %s, got %s", i, wantKeys[i], usage.Key)
+		}
+	}
+	if usages[0].Key != "Path" {
+		t.Error("Expected the usages passed in to be left as they are")
+	}
+
+	if len(mismatches) != 2 {
+		t.Fatalf("Expected 2 case-only mismatches, got %+v", mismatches)
+	}
+	if mismatches[0].Key != "Path" || mismatches[0].Defined != "PATH" || len(mismatches[0].Usages) != 1 {
+		t.Errorf("Unexpected first mismatch: %+v", mismatches[0])
+	}
+	if mismatches[1].Key != "api_key" || mismatches[1].Defined != "API_KEY" || len(mismatches[1].Usages) != 2 {
+		t.Errorf("Unexpected second mismatch: %+v", mismatches[1])
+	}
+	if mismatches[1].Usages[0].Key != "api_key" {
+		t.Errorf("Expected mismatch usages to keep the casing of the code, got %s", mismatches[1].Usages[0].Key)
+	}
+}
@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"slices"
+	"sort"
+)
+
+// Finding categories of Findings, the same as the --fail-on ones
+const (
+	FindingMissing     = "missing" // Including optional and test-only variables
+	FindingUnused      = "unused"
+	FindingDynamic     = "dynamic"
+	FindingExample     = "example" // Undocumented and stale variables
+	FindingSchema      = "schema"  // Unread and undeclared variables
+	FindingFrontend    = "frontend"
+	FindingStyle       = "style"
+	FindingDeprecated  = "deprecated"
+	FindingType        = "type"        // Values code can't parse as the type it expects
+	FindingPlaceholder = "placeholder" // Empty or placeholder values
+	FindingReference   = "reference"   // Unresolved ${VAR} references in env files
+	FindingForbidden   = "forbidden"   // Forbidden variables used or defined
+)
+
+// FindingCategories lists the categories of Findings in report order
+var FindingCategories = []string{FindingMissing, FindingDynamic, FindingUnused, FindingExample, FindingSchema, FindingFrontend, FindingStyle, FindingDeprecated, FindingType, FindingPlaceholder, FindingReference, FindingForbidden}
+
+// Findings maps finding categories to the sorted keys of their findings, empty categories are left out
+type Findings map[string][]string
+
+// Add records a finding of key in category
+func (f Findings) Add(category string, keys ...string) {
+	for _, key := range keys {
+		i, found := slices.BinarySearch(f[category], key)
+		if !found {
+			f[category] = slices.Insert(f[category], i, key)
+		}
+	}
+}
+
+// Has reports whether key is a finding of category
+func (f Findings) Has(category string, key string) bool {
+	_, found := slices.BinarySearch(f[category], key)
+	return found
+}
+
+// Count returns the number of findings
+func (f Findings) Count() int {
+	count := 0
+	for _, keys := range f {
+		count += len(keys)
+	}
+	return count
+}
+
+// Comparison classifies the findings of a scan against a previous report (with --compare-to)
+type Comparison struct {
+	Baseline  string   // Report the scan was compared to
+	New       Findings // Findings the baseline doesn't have
+	Fixed     Findings // Findings of the baseline that are gone
+	Unchanged Findings // Findings in both
+}
+
+// Findings returns the keys of the result's findings by category
+func (r ScanResult) Findings() Findings {
+	findings := make(Findings)
+	findings.Add(FindingMissing, mapKeys(r.Missing)...)
+	findings.Add(FindingMissing, mapKeys(r.OptionalMissing)...)
+	findings.Add(FindingMissing, mapKeys(r.TestMissing)...)
+	findings.Add(FindingDynamic, mapKeys(r.PartialMatches)...)
+	findings.Add(FindingUnused, r.Unused...)
+	if drift := r.ExampleDrift; drift != nil {
+		findings.Add(FindingExample, mapKeys(drift.Undocumented)...)
+		findings.Add(FindingExample, drift.Stale...)
+	}
+	if drift := r.SchemaDrift; drift != nil {
+		findings.Add(FindingSchema, drift.Unread...)
+		findings.Add(FindingSchema, mapKeys(drift.Undeclared)...)
+	}
+	if frontend := r.Frontend; frontend != nil {
+		findings.Add(FindingFrontend, mapKeys(frontend.Unprefixed)...)
+		findings.Add(FindingFrontend, mapKeys(frontend.Exposed)...)
+	}
+	for _, violation := range r.Style {
+		findings.Add(FindingStyle, violation.Key)
+	}
+	for _, deprecated := range r.Deprecated {
+		findings.Add(FindingDeprecated, deprecated.Key)
+	}
+	for _, mismatch := range r.TypeMismatches {
+		findings.Add(FindingType, mismatch.Key)
+	}
+	for _, placeholder := range r.Placeholders {
+		findings.Add(FindingPlaceholder, placeholder.Key)
+	}
+	for _, ref := range r.UnresolvedRefs {
+		findings.Add(FindingReference, ref.Key)
+	}
+	for _, forbidden := range r.Forbidden {
+		findings.Add(FindingForbidden, forbidden.Key)
+	}
+	return findings
+}
+
+// Compare classifies the current findings against the previous ones, of the baseline report
+func Compare(current Findings, previous Findings, baseline string) *Comparison {
+	comparison := &Comparison{Baseline: baseline, New: make(Findings), Fixed: make(Findings), Unchanged: make(Findings)}
+	for category, keys := range current {
+		for _, key := range keys {
+			if previous.Has(category, key) {
+				comparison.Unchanged.Add(category, key)
+			} else {
+				comparison.New.Add(category, key)
+			}
+		}
+	}
+	for category, keys := range previous {
+		for _, key := range keys {
+			if !current.Has(category, key) {
+				comparison.Fixed.Add(category, key)
+			}
+		}
+	}
+	return comparison
+}
+
+// Keep drops every finding that isn't in keep, e.g. to only fail on the new findings of a comparison
+// Conflicts are left as they are, they aren't findings of a category
+func (r *ScanResult) Keep(keep Findings) {
+	keepUsages := func(category string, findings map[string][]EnvUsage) map[string][]EnvUsage {
+		kept := make(map[string][]EnvUsage)
+		for key, usages := range findings {
+			if keep.Has(category, key) {
+				kept[key] = usages
+			}
+		}
+		return kept
+	}
+	keepKeys := func(category string, keys []string) []string {
+		kept := []string{}
+		for _, key := range keys {
+			if keep.Has(category, key) {
+				kept = append(kept, key)
+			}
+		}
+		return kept
+	}
+
+	r.Missing = keepUsages(FindingMissing, r.Missing)
+	r.OptionalMissing = keepUsages(FindingMissing, r.OptionalMissing)
+	r.TestMissing = keepUsages(FindingMissing, r.TestMissing)
+	r.PartialMatches = keepUsages(FindingDynamic, r.PartialMatches)
+	r.Unused = keepKeys(FindingUnused, r.Unused)
+	if drift := r.ExampleDrift; drift != nil {
+		kept := *drift
+		kept.Undocumented = keepUsages(FindingExample, drift.Undocumented)
+		kept.Stale = keepKeys(FindingExample, drift.Stale)
+		r.ExampleDrift = &kept
+	}
+	if drift := r.SchemaDrift; drift != nil {
+		kept := *drift
+		kept.Unread = keepKeys(FindingSchema, drift.Unread)
+		kept.Undeclared = keepUsages(FindingSchema, drift.Undeclared)
+		r.SchemaDrift = &kept
+	}
+	if frontend := r.Frontend; frontend != nil {
+		kept := *frontend
+		kept.Unprefixed = keepUsages(FindingFrontend, frontend.Unprefixed)
+		kept.Exposed = keepUsages(FindingFrontend, frontend.Exposed)
+		r.Frontend = &kept
+	}
+	r.Style = slices.DeleteFunc(slices.Clone(r.Style), func(v StyleViolation) bool { return !keep.Has(FindingStyle, v.Key) })
+	r.Deprecated = slices.DeleteFunc(slices.Clone(r.Deprecated), func(d DeprecatedVar) bool { return !keep.Has(FindingDeprecated, d.Key) })
+	r.TypeMismatches = slices.DeleteFunc(slices.Clone(r.TypeMismatches), func(m TypeMismatch) bool { return !keep.Has(FindingType, m.Key) })
+	r.Placeholders = slices.DeleteFunc(slices.Clone(r.Placeholders), func(p PlaceholderValue) bool { return !keep.Has(FindingPlaceholder, p.Key) })
+	r.UnresolvedRefs = slices.DeleteFunc(slices.Clone(r.UnresolvedRefs), func(u UnresolvedReference) bool { return !keep.Has(FindingReference, u.Key) })
+	r.Forbidden = slices.DeleteFunc(slices.Clone(r.Forbidden), func(f ForbiddenVar) bool { return !keep.Has(FindingForbidden, f.Key) })
+}
+
+// mapKeys returns the sorted keys of findings
+func mapKeys(findings map[string][]EnvUsage) []string {
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
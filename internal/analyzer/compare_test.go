@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	result := ScanResult{
+		Missing:         map[string][]EnvUsage{"NEW_KEY": {{Key: "NEW_KEY"}}, "OLD_KEY": {{Key: "OLD_KEY"}}},
+		OptionalMissing: map[string][]EnvUsage{"PORT": {{Key: "PORT", IsOptional: true}}},
+		Unused:          []string{"LEGACY"},
+		Style:           []StyleViolation{{Key: "apiKey"}},
+	}
+	previous := Findings{FindingMissing: {"FIXED_KEY", "OLD_KEY", "PORT"}, FindingStyle: {"apiKey"}, FindingUnused: {"GONE"}}
+
+	comparison := Compare(result.Findings(), previous, "previous.json")
+
+	if want := (Findings{FindingMissing: {"NEW_KEY"}, FindingUnused: {"LEGACY"}}); !reflect.DeepEqual(comparison.New, want) {
+		t.Errorf("Expected new findings %v, got %v", want, comparison.New)
+	}
+	if want := (Findings{FindingMissing: {"FIXED_KEY"}, FindingUnused: {"GONE"}}); !reflect.DeepEqual(comparison.Fixed, want) {
+		t.Errorf("Expected fixed findings %v, got %v", want, comparison.Fixed)
+	}
+	if want := (Findings{FindingMissing: {"OLD_KEY", "PORT"}, FindingStyle: {"apiKey"}}); !reflect.DeepEqual(comparison.Unchanged, want) {
+		t.Errorf("Expected unchanged findings %v, got %v", want, comparison.Unchanged)
+	}
+}
+
+func TestScanResult_Keep(t *testing.T) {
+	result := ScanResult{
+		Missing:      map[string][]EnvUsage{"NEW_KEY": {{Key: "NEW_KEY"}}, "OLD_KEY": {{Key: "OLD_KEY"}}},
+		Unused:       []string{"LEGACY", "NEW_UNUSED"},
+		ExampleDrift: &ExampleDrift{Stale: []string{"STALE"}},
+		Deprecated:   []DeprecatedVar{{Key: "OLD_URL"}},
+	}
+	original := result.Unused
+
+	result.Keep(Findings{FindingMissing: {"NEW_KEY"}, FindingUnused: {"NEW_UNUSED"}})
+
+	if len(result.Missing) != 1 || result.Missing["NEW_KEY"] == nil {
+		t.Errorf("Expected only NEW_KEY to be missing, got %v", result.Missing)
+	}
+	if !reflect.DeepEqual(result.Unused, []string{"NEW_UNUSED"}) {
+		t.Errorf("Expected only NEW_UNUSED to be unused, got %v", result.Unused)
+	}
+	if len(result.ExampleDrift.Stale) != 0 || len(result.Deprecated) != 0 {
+		t.Errorf("Expected no drift or deprecated findings, got %v and %v", result.ExampleDrift.Stale, result.Deprecated)
+	}
+	if len(original) != 2 {
+		t.Errorf("Expected the original unused list to be left alone, got %v", original)
+	}
+}
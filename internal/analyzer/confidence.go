@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Confidence estimates how likely an unresolved dynamic pattern refers to a real environment variable
+type Confidence string
+
+// Confidence levels, from the most to the least reliable
+const (
+	ConfidenceHigh   Confidence = "high"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceLow    Confidence = "low"
+)
+
+// ParseConfidence parses a confidence level (case-insensitive); an empty value is low, i.e., no filtering
+func ParseConfidence(value string) (Confidence, error) {
+	switch c := Confidence(strings.ToLower(strings.TrimSpace(value))); c {
+	case "":
+		return ConfidenceLow, nil
+	case ConfidenceHigh, ConfidenceMedium, ConfidenceLow:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown confidence %q (supported: high, medium, low)", value)
+	}
+}
+
+// AtLeast reports whether c is as reliable as min
+func (c Confidence) AtLeast(min Confidence) bool {
+	return c.rank() >= min.rank()
+}
+
+// rank orders confidence levels, unknown values rank like low
+func (c Confidence) rank() int {
+	switch c {
+	case ConfidenceHigh:
+		return 2
+	case ConfidenceMedium:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// plainOperand matches operands that are a plain identifier or field access, which a reader can trace to a value
+var plainOperand = regexp.MustCompile(`^[A-Za-z_$][\w$]*(\.[A-Za-z_$][\w$]*)*$`)
+
+// dynamicConfidence scores the usages of an unresolved dynamic pattern:
+// a long static part, plain operands (name rather than lookup(cfg)[i]) and an env key sharing
+// a static part (e.g., FEATURE_ with feature_a defined) each raise the confidence
+func dynamicConfidence(usages []EnvUsage, envVars map[string]string) Confidence {
+	best := ConfidenceLow
+	for _, usage := range usages {
+		parts := dynamicParts(usage)
+
+		score := 0
+		switch static := staticLength(parts); {
+		case static >= 6:
+			score += 2
+		case static >= 3:
+			score++
+		}
+		if operandsTraceable(parts) {
+			score++
+		}
+		if sharesLiteral(parts, envVars) {
+			score++
+		}
+
+		confidence := ConfidenceLow
+		switch {
+		case score >= 3:
+			confidence = ConfidenceHigh
+		case score == 2:
+			confidence = ConfidenceMedium
+		}
+		if confidence.rank() > best.rank() {
+			best = confidence
+		}
+	}
+	return best
+}
+
+// operandsTraceable reports whether every operand of a dynamic expression is a plain identifier
+func operandsTraceable(parts []dynamicPart) bool {
+	for _, part := range parts {
+		if part.Operand != "" && !plainOperand.MatchString(part.Operand) {
+			return false
+		}
+	}
+	return true
+}
+
+// sharesLiteral reports whether a defined variable contains one of the expression's literals, ignoring case
+func sharesLiteral(parts []dynamicPart, envVars map[string]string) bool {
+	for _, part := range parts {
+		literal := strings.ToUpper(strings.Trim(part.Literal, "_"))
+		if len(literal) < 2 {
+			continue
+		}
+		for envKey := range envVars {
+			if strings.Contains(strings.ToUpper(envKey), literal) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterConfidence drops the unresolved dynamic patterns whose confidence is below min,
+// counting them in IgnoredDynamic
+func (r *ScanResult) FilterConfidence(min Confidence) {
+	for key := range r.PartialMatches {
+		if r.Confidence[key].AtLeast(min) {
+			continue
+		}
+		delete(r.PartialMatches, key)
+		delete(r.Confidence, key)
+		r.IgnoredDynamic++
+	}
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// DetectDeprecated finds the code usages and env file definitions of variables listed as deprecated in the config
+// Dynamic patterns and usages in ignored folders are not reported
+func DetectDeprecated(codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) []DeprecatedVar {
+	if cfg == nil || len(cfg.Deprecated) == 0 {
+		return nil
+	}
+
+	found := make(map[string]*DeprecatedVar)
+	deprecated := func(key string) *DeprecatedVar {
+		if v, ok := found[key]; ok {
+			return v
+		}
+		hint, ok := cfg.DeprecationHint(key)
+		if !ok {
+			found[key] = nil
+			return nil
+		}
+		found[key] = &DeprecatedVar{Key: key, Hint: hint, Severity: cfg.SeverityFor(config.CategoryDeprecated, key)}
+		return found[key]
+	}
+
+	for _, usage := range codeUsages {
+		if usage.IsPartial || usage.InIgnoredPath {
+			continue
+		}
+		if v := deprecated(usage.Key); v != nil {
+			v.Usages = append(v.Usages, usage)
+		}
+	}
+	for key, defs := range definitions {
+		if v := deprecated(key); v != nil {
+			v.Definitions = defs
+		}
+	}
+
+	var result []DeprecatedVar
+	for _, v := range found {
+		if v != nil {
+			result = append(result, *v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+)
+
+// DetectExampleDrift compares the keys of example env files with code usages and the real .env files
+// Other sources (docker-compose, k8s, ...) often define variables for other services, so they're not compared
+// It returns nil when no example file defines anything
+func DetectExampleDrift(codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) *ExampleDrift {
+	exampleKeys := make(map[string][]Definition)
+	realKeys := make(map[string][]Definition)
+	examples := make(map[string]bool)
+	for key, defs := range definitions {
+		for _, def := range defs {
+			if envfile.IsExampleFile(def.File) {
+				exampleKeys[key] = append(exampleKeys[key], def)
+				examples[def.File] = true
+			} else if def.Kind == "env" {
+				realKeys[key] = append(realKeys[key], def)
+			}
+		}
+	}
+	if len(examples) == 0 {
+		return nil
+	}
+
+	drift := &ExampleDrift{
+		Undocumented: make(map[string][]EnvUsage),
+		Stale:        []string{},
+		Definitions:  make(map[string][]Definition),
+		Severities:   make(map[string]config.Severity),
+	}
+	for file := range examples {
+		drift.Examples = append(drift.Examples, file)
+	}
+	sort.Strings(drift.Examples)
+
+	// Usages in ignored folders don't need documenting, but they still keep an example key in use
+	usedKeys := make(map[string]bool)
+	var partials []string
+	for _, usage := range codeUsages {
+		if usage.IsPartial {
+			if !usage.IsVarRef {
+				partials = append(partials, usage.Key)
+			}
+			continue
+		}
+		usedKeys[usage.Key] = true
+		if usage.InIgnoredPath {
+			continue
+		}
+		if _, listed := exampleKeys[usage.Key]; !listed {
+			drift.Undocumented[usage.Key] = append(drift.Undocumented[usage.Key], usage)
+		}
+	}
+
+	for key, defs := range realKeys {
+		if _, listed := exampleKeys[key]; listed {
+			continue
+		}
+		if _, used := drift.Undocumented[key]; !used {
+			drift.Undocumented[key] = []EnvUsage{}
+		}
+		drift.Definitions[key] = defs
+	}
+
+	for key, defs := range exampleKeys {
+		if usedKeys[key] || (cfg != nil && cfg.IsRequired(key)) || matchesPartial(key, partials) {
+			continue
+		}
+		drift.Stale = append(drift.Stale, key)
+		drift.Definitions[key] = defs
+	}
+	sort.Strings(drift.Stale)
+
+	for key := range drift.Undocumented {
+		drift.Severities[key] = cfg.SeverityFor(config.CategoryUndocumented, key)
+	}
+	for _, key := range drift.Stale {
+		drift.Severities[key] = cfg.SeverityFor(config.CategoryStale, key)
+	}
+	return drift
+}
+
+// matchesPartial reports whether key contains the static part of a dynamic pattern (e.g., "MY_" from "MY_" + var)
+func matchesPartial(key string, partials []string) bool {
+	for _, partial := range partials {
+		if partial != "" && strings.Contains(key, partial) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// stringLiteral matches the quoted string literals of a dynamic expression
+var stringLiteral = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|'(?:[^'\\\\]|\\\\.)*'|`[^`]*`")
+
+// dynamicPart is a piece of a dynamic expression: a string literal or a runtime operand
+type dynamicPart struct {
+	Literal string // Unquoted literal text, empty for operands
+	Operand string // Operand source text (e.g., name, getService()), empty for literals
+}
+
+// dynamicParts splits a dynamic usage into its literals and operands, in order
+// Usages without a full expression are a single literal (their static key), variable references a single operand
+func dynamicParts(usage EnvUsage) []dynamicPart {
+	if usage.FullExpr == "" {
+		if usage.IsVarRef {
+			return []dynamicPart{{Operand: usage.Key}}
+		}
+		if usage.Key == "" {
+			return nil
+		}
+		return []dynamicPart{{Literal: usage.Key}}
+	}
+
+	expr := strings.TrimSpace(usage.FullExpr)
+	literals := stringLiteral.FindAllStringIndex(expr, -1)
+	if usage.IsVarRef || len(literals) == 0 {
+		return []dynamicPart{{Operand: expr}}
+	}
+
+	var parts []dynamicPart
+	end := 0
+	for _, loc := range literals {
+		if operand := dynamicOperand(expr[end:loc[0]]); operand != "" {
+			parts = append(parts, dynamicPart{Operand: operand})
+		}
+		parts = append(parts, dynamicPart{Literal: expr[loc[0]+1 : loc[1]-1]})
+		end = loc[1]
+	}
+	if operand := dynamicOperand(expr[end:]); operand != "" {
+		parts = append(parts, dynamicPart{Operand: operand})
+	}
+	return parts
+}
+
+// dynamicOperand returns the operand between two literals without the concatenation operators
+func dynamicOperand(between string) string {
+	return strings.Trim(between, " \t\n+")
+}
+
+// dynamicPattern returns a regular expression matching the variable names a dynamic usage can resolve to,
+// or nil when the expression has no static part (e.g., process.env[name])
+// "FEATURE_" + name matches FEATURE_*, name + "_URL" matches *_URL and "A_" + x + "_B" matches A_*_B
+func dynamicPattern(usage EnvUsage) *regexp.Regexp {
+	parts := dynamicParts(usage)
+	if staticLength(parts) == 0 {
+		return nil
+	}
+	if usage.FullExpr == "" {
+		return regexp.MustCompile(regexp.QuoteMeta(usage.Key))
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, part := range parts {
+		if part.Operand != "" {
+			pattern.WriteString(".*")
+		} else {
+			pattern.WriteString(regexp.QuoteMeta(part.Literal))
+		}
+	}
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
+
+// staticLength returns the number of characters of a dynamic expression known at analysis time
+func staticLength(parts []dynamicPart) int {
+	n := 0
+	for _, part := range parts {
+		n += len(part.Literal)
+	}
+	return n
+}
+
+// expandDynamic returns the defined variables that the dynamic usages of a pattern can resolve to, sorted
+func expandDynamic(usages []EnvUsage, envVars map[string]string) []string {
+	var matches []string
+	seen := make(map[string]bool)
+	for _, usage := range usages {
+		pattern := dynamicPattern(usage)
+		if pattern == nil {
+			continue
+		}
+		for envKey := range envVars {
+			if !seen[envKey] && pattern.MatchString(envKey) {
+				seen[envKey] = true
+				matches = append(matches, envKey)
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
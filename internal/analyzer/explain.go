@@ -0,0 +1,36 @@
+package analyzer
+
+import (
+	"github.com/jenian/envgrd/internal/schema"
+)
+
+// Explanation gathers what a scan knows about one variable, see ScanResult.Explain
+type Explanation struct {
+	Key         string
+	Declaration *schema.Variable // Declaration of the schema file or env file comments, nil when undeclared
+	Definitions []Definition     // Env file definitions, in load order
+	Usages      []EnvUsage       // Code usages, dynamic patterns left out
+	Findings    []string         // Categories of the findings reporting the variable, in FindingCategories order
+}
+
+// Explain returns the declaration, definitions, usages and findings of key
+func (r ScanResult) Explain(key string) Explanation {
+	e := Explanation{Key: key, Definitions: r.Definitions[key]}
+	if r.Schema != nil {
+		if v, ok := r.Schema.Variables[key]; ok {
+			e.Declaration = &v
+		}
+	}
+	for _, usage := range r.CodeKeys {
+		if usage.Key == key && !usage.IsPartial {
+			e.Usages = append(e.Usages, usage)
+		}
+	}
+	findings := r.Findings()
+	for _, category := range FindingCategories {
+		if findings.Has(category, key) {
+			e.Findings = append(e.Findings, category)
+		}
+	}
+	return e
+}
@@ -0,0 +1,291 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/owners"
+)
+
+// FilterCategories are the finding categories --only accepts, named like the --fail-on categories
+var FilterCategories = []string{"missing", "unused", "dynamic", "example", "schema", "frontend", "style", "deprecated", "type", "placeholder", "reference", "forbidden"}
+
+// Filter narrows the findings of a scan result after analysis, see NewFilter
+type Filter struct {
+	only        map[string]bool  // Categories to keep, all when empty
+	keys        []string         // Name patterns of the variables to keep, all when empty
+	excludeKeys []string         // Name patterns of the variables to drop
+	files       []*regexp.Regexp // Paths of the usages and definitions to keep, all when empty
+}
+
+// NewFilter parses filters: categories of FilterCategories, name patterns (exact names, globs such as
+// STRIPE_* or /regular expressions/) of the variables to keep and to drop, and gitignore-style path
+// patterns (e.g., src/payments/**) of the files whose usages and definitions are kept
+// Values may be comma-separated. Returns nil when no filter is given
+func NewFilter(only []string, keys []string, excludeKeys []string, files []string) (*Filter, error) {
+	f := &Filter{only: make(map[string]bool)}
+	for _, category := range splitValues(only) {
+		category = strings.ToLower(category)
+		if !contains(FilterCategories, category) {
+			return nil, fmt.Errorf("unknown category %q (supported: %s)", category, strings.Join(FilterCategories, ", "))
+		}
+		f.only[category] = true
+	}
+	f.keys = splitValues(keys)
+	f.excludeKeys = splitValues(excludeKeys)
+	if err := config.ValidateNamePatterns(append(append([]string{}, f.keys...), f.excludeKeys...)); err != nil {
+		return nil, err
+	}
+	for _, pattern := range splitValues(files) {
+		re, err := owners.CompilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+		f.files = append(f.files, re)
+	}
+
+	if len(f.only) == 0 && len(f.keys) == 0 && len(f.excludeKeys) == 0 && len(f.files) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// splitValues splits comma-separated values, dropping empty ones
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+	return split
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// category reports whether findings of a category are kept
+func (f *Filter) category(name string) bool {
+	return len(f.only) == 0 || f.only[name]
+}
+
+// key reports whether findings of a variable (or dynamic pattern) are kept
+func (f *Filter) key(key string) bool {
+	for _, pattern := range f.excludeKeys {
+		if config.MatchesName(pattern, key) {
+			return false
+		}
+	}
+	if len(f.keys) == 0 {
+		return true
+	}
+	for _, pattern := range f.keys {
+		if config.MatchesName(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// file reports whether a usage or definition in file (relative to the scan root) is kept
+func (f *Filter) file(file string) bool {
+	if len(f.files) == 0 {
+		return true
+	}
+	file = strings.TrimPrefix(filepath.ToSlash(file), "./")
+	for _, re := range f.files {
+		if re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// usages returns the usages in kept files
+func (f *Filter) usages(usages []EnvUsage) []EnvUsage {
+	if len(f.files) == 0 {
+		return usages
+	}
+	var kept []EnvUsage
+	for _, usage := range usages {
+		if f.file(usage.File) {
+			kept = append(kept, usage)
+		}
+	}
+	return kept
+}
+
+// definitions returns the definitions in kept files
+func (f *Filter) definitions(definitions []Definition) []Definition {
+	if len(f.files) == 0 {
+		return definitions
+	}
+	var kept []Definition
+	for _, definition := range definitions {
+		if f.file(definition.File) {
+			kept = append(kept, definition)
+		}
+	}
+	return kept
+}
+
+// findings filters findings grouped by key; with a file filter, a finding is kept with its usages
+// in kept files, or if it has no usages, when one of its definitions is in a kept file
+func (f *Filter) findings(category string, findings map[string][]EnvUsage, definitions map[string][]Definition) {
+	for key, usages := range findings {
+		if !f.category(category) || !f.key(key) {
+			delete(findings, key)
+			continue
+		}
+		if len(f.files) == 0 {
+			continue
+		}
+		if len(usages) == 0 && definitions != nil {
+			if len(f.definitions(definitions[key])) == 0 {
+				delete(findings, key)
+			}
+			continue
+		}
+		if kept := f.usages(usages); len(kept) == 0 {
+			delete(findings, key)
+		} else {
+			findings[key] = kept
+		}
+	}
+}
+
+// Filter drops the findings the filter doesn't keep, like FilterOwner does for an owner
+// Conflicts are only kept without a category filter, since --fail-on has no category for them
+func (r *ScanResult) Filter(f *Filter) {
+	if f == nil {
+		return
+	}
+
+	f.findings("missing", r.Missing, nil)
+	f.findings("missing", r.OptionalMissing, nil)
+	f.findings("missing", r.TestMissing, nil)
+	f.findings("dynamic", r.PartialMatches, nil)
+
+	unused := []string{}
+	for _, key := range r.Unused {
+		if f.category("unused") && f.key(key) && f.file(r.EnvKeySources[key]) {
+			unused = append(unused, key)
+		}
+	}
+	r.Unused = unused
+
+	if drift := r.ExampleDrift; drift != nil {
+		f.findings("example", drift.Undocumented, drift.Definitions)
+		stale := []string{}
+		for _, key := range drift.Stale {
+			if f.category("example") && f.key(key) && f.keepsAny(nil, drift.Definitions[key]) {
+				stale = append(stale, key)
+			}
+		}
+		drift.Stale = stale
+	}
+	if drift := r.SchemaDrift; drift != nil {
+		unread := []string{}
+		for _, key := range drift.Unread {
+			if f.category("schema") && f.key(key) && f.file(drift.Schema) {
+				unread = append(unread, key)
+			}
+		}
+		drift.Unread = unread
+		f.findings("schema", drift.Undeclared, nil)
+	}
+	if frontend := r.Frontend; frontend != nil {
+		f.findings("frontend", frontend.Unprefixed, nil)
+		f.findings("frontend", frontend.Exposed, frontend.Definitions)
+	}
+
+	var style []StyleViolation
+	for _, violation := range r.Style {
+		if f.category("style") && f.key(violation.Key) && f.keepsAny(violation.Usages, violation.Definitions) {
+			violation.Usages = f.usages(violation.Usages)
+			violation.Definitions = f.definitions(violation.Definitions)
+			style = append(style, violation)
+		}
+	}
+	r.Style = style
+
+	var deprecated []DeprecatedVar
+	for _, d := range r.Deprecated {
+		if f.category("deprecated") && f.key(d.Key) && f.keepsAny(d.Usages, d.Definitions) {
+			d.Usages = f.usages(d.Usages)
+			d.Definitions = f.definitions(d.Definitions)
+			deprecated = append(deprecated, d)
+		}
+	}
+	r.Deprecated = deprecated
+
+	var typeMismatches []TypeMismatch
+	for _, mismatch := range r.TypeMismatches {
+		if f.category("type") && f.key(mismatch.Key) && f.keepsAny(mismatch.Usages, mismatch.Definitions) {
+			mismatch.Usages = f.usages(mismatch.Usages)
+			mismatch.Definitions = f.definitions(mismatch.Definitions)
+			typeMismatches = append(typeMismatches, mismatch)
+		}
+	}
+	r.TypeMismatches = typeMismatches
+
+	var placeholders []PlaceholderValue
+	for _, placeholder := range r.Placeholders {
+		if f.category("placeholder") && f.key(placeholder.Key) && f.keepsAny(placeholder.Usages, []Definition{placeholder.Definition}) {
+			placeholder.Usages = f.usages(placeholder.Usages)
+			placeholders = append(placeholders, placeholder)
+		}
+	}
+	r.Placeholders = placeholders
+
+	var unresolvedRefs []UnresolvedReference
+	for _, ref := range r.UnresolvedRefs {
+		if f.category("reference") && f.key(ref.Key) && f.keepsAny(nil, []Definition{ref.Definition}) {
+			unresolvedRefs = append(unresolvedRefs, ref)
+		}
+	}
+	r.UnresolvedRefs = unresolvedRefs
+
+	var forbidden []ForbiddenVar
+	for _, v := range r.Forbidden {
+		if f.category("forbidden") && f.key(v.Key) && f.keepsAny(v.Usages, v.Definitions) {
+			v.Usages = f.usages(v.Usages)
+			v.Definitions = f.definitions(v.Definitions)
+			forbidden = append(forbidden, v)
+		}
+	}
+	r.Forbidden = forbidden
+
+	var conflicts []Conflict
+	for _, conflict := range r.Conflicts {
+		if len(f.only) == 0 && f.key(conflict.Key) && f.keepsAny(nil, conflict.Definitions) {
+			conflicts = append(conflicts, conflict) // All definitions stay to show which one wins
+		}
+	}
+	r.Conflicts = conflicts
+
+	var mismatches []CaseMismatch
+	for _, mismatch := range r.CaseMismatches {
+		if len(f.only) == 0 && (f.key(mismatch.Key) || f.key(mismatch.Defined)) && f.keepsAny(mismatch.Usages, mismatch.Definitions) {
+			mismatch.Usages = f.usages(mismatch.Usages)
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	r.CaseMismatches = mismatches
+}
+
+// keepsAny reports whether a usage or a definition is in a kept file
+func (f *Filter) keepsAny(usages []EnvUsage, definitions []Definition) bool {
+	return len(f.files) == 0 || len(f.usages(usages)) > 0 || len(f.definitions(definitions)) > 0
+}
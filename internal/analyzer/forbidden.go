@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// DetectForbidden finds the code usages and env file definitions of variables the config or its policy
+// forbids. Dynamic patterns and usages in ignored folders are not reported
+func DetectForbidden(codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) []ForbiddenVar {
+	if !cfg.HasForbidden() {
+		return nil
+	}
+
+	found := make(map[string]*ForbiddenVar)
+	forbidden := func(key string) *ForbiddenVar {
+		if v, ok := found[key]; ok {
+			return v
+		}
+		message, ok := cfg.ForbiddenMessage(key)
+		if !ok {
+			found[key] = nil
+			return nil
+		}
+		found[key] = &ForbiddenVar{Key: key, Message: message, Severity: cfg.SeverityFor(config.CategoryForbidden, key)}
+		return found[key]
+	}
+
+	for _, usage := range codeUsages {
+		if usage.IsPartial || usage.InIgnoredPath {
+			continue
+		}
+		if v := forbidden(usage.Key); v != nil {
+			v.Usages = append(v.Usages, usage)
+		}
+	}
+	for key, defs := range definitions {
+		if v := forbidden(key); v != nil {
+			v.Definitions = defs
+		}
+	}
+
+	var result []ForbiddenVar
+	for _, v := range found {
+		if v != nil {
+			result = append(result, *v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// Framework describes how a frontend framework exposes env variables to the browser
+type Framework struct {
+	Name       string   // vite, next or cra
+	Package    string   // npm dependency that identifies the framework in package.json
+	Prefix     string   // Only variables with this prefix are bundled into client code
+	ClientDirs []string // Default directories with client-side code
+	ServerDirs []string // Default directories inside ClientDirs that only run on the server
+}
+
+// Frameworks lists the supported frontend frameworks, in package.json detection order
+var Frameworks = []Framework{
+	{
+		Name:       config.FrameworkNext,
+		Package:    "next",
+		Prefix:     "NEXT_PUBLIC_",
+		ClientDirs: []string{"app", "pages", "components", "src/app", "src/pages", "src/components"},
+		ServerDirs: []string{"app/api", "pages/api", "src/app/api", "src/pages/api"},
+	},
+	{
+		Name:       config.FrameworkVite,
+		Package:    "vite",
+		Prefix:     "VITE_",
+		ClientDirs: []string{"src"},
+	},
+	{
+		Name:       config.FrameworkCRA,
+		Package:    "react-scripts",
+		Prefix:     "REACT_APP_",
+		ClientDirs: []string{"src"},
+	},
+}
+
+// defaultSecretWords mark a variable name as secret when one of its underscore-separated parts matches
+var defaultSecretWords = []string{"SECRET", "PASSWORD", "PASSWD", "PRIVATE", "TOKEN", "CREDENTIAL", "CREDENTIALS"}
+
+// bundlerVariables are replaced by every bundler, so they're fine without the public prefix
+var bundlerVariables = map[string]bool{"NODE_ENV": true}
+
+// FrameworkByName returns the framework with the given name
+func FrameworkByName(name string) (Framework, bool) {
+	for _, framework := range Frameworks {
+		if framework.Name == name {
+			return framework, true
+		}
+	}
+	return Framework{}, false
+}
+
+// DetectFrontendLeaks checks client-side usages for the framework's public prefix,
+// and public variables (in code or env files) for secret-looking names
+func DetectFrontendLeaks(framework Framework, codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) *FrontendLeaks {
+	clientDirs, serverDirs := framework.ClientDirs, framework.ServerDirs
+	secretWords := defaultSecretWords
+	if cfg != nil {
+		if len(cfg.Frontend.ClientDirs) > 0 {
+			clientDirs = cfg.Frontend.ClientDirs
+		}
+		if len(cfg.Frontend.ServerDirs) > 0 {
+			serverDirs = cfg.Frontend.ServerDirs
+		}
+		if len(cfg.Frontend.SecretWords) > 0 {
+			secretWords = cfg.Frontend.SecretWords
+		}
+	}
+
+	leaks := &FrontendLeaks{
+		Framework:   framework.Name,
+		Prefix:      framework.Prefix,
+		Unprefixed:  make(map[string][]EnvUsage),
+		Exposed:     make(map[string][]EnvUsage),
+		Definitions: make(map[string][]Definition),
+		Severities:  make(map[string]config.Severity),
+	}
+
+	for _, usage := range codeUsages {
+		if usage.IsPartial || usage.InIgnoredPath {
+			continue
+		}
+		if strings.HasPrefix(usage.Key, framework.Prefix) {
+			if looksSecret(usage.Key, framework.Prefix, secretWords) {
+				leaks.Exposed[usage.Key] = append(leaks.Exposed[usage.Key], usage)
+			}
+			continue
+		}
+		if !bundlerVariables[usage.Key] && !inlinedByConfig(definitions[usage.Key]) && config.IsClientFile(usage.File, clientDirs, serverDirs) {
+			leaks.Unprefixed[usage.Key] = append(leaks.Unprefixed[usage.Key], usage)
+		}
+	}
+
+	// Public variables are bundled even if no scanned code reads them (e.g., read by a library)
+	// Keys of a framework config's env block are bundled too, whatever their prefix
+	for key, defs := range definitions {
+		prefix := framework.Prefix
+		if inlinedByConfig(defs) {
+			prefix = ""
+		}
+		if !strings.HasPrefix(key, prefix) || !looksSecret(key, prefix, secretWords) {
+			continue
+		}
+		if _, found := leaks.Exposed[key]; !found {
+			leaks.Exposed[key] = []EnvUsage{}
+		}
+		leaks.Definitions[key] = defs
+	}
+
+	for key := range leaks.Unprefixed {
+		leaks.Severities[key] = cfg.SeverityFor(config.CategoryUnprefixed, key)
+	}
+	for key := range leaks.Exposed {
+		leaks.Severities[key] = cfg.SeverityFor(config.CategoryExposed, key)
+	}
+	return leaks
+}
+
+// inlinedByConfig reports whether a variable is defined in the env block of a Next.js config, which
+// the framework inlines into client code like a prefixed variable
+func inlinedByConfig(defs []Definition) bool {
+	for _, def := range defs {
+		if def.Kind == "next-config" {
+			return true
+		}
+	}
+	return false
+}
+
+// looksSecret reports whether the part of key after prefix contains one of the secret words
+func looksSecret(key string, prefix string, words []string) bool {
+	for _, part := range strings.Split(strings.TrimPrefix(key, prefix), "_") {
+		for _, word := range words {
+			if strings.EqualFold(part, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// DetectStyleViolations checks the names used in code and defined in env files against the config's naming rules
+// Dynamic patterns and usages in ignored folders are not checked
+func DetectStyleViolations(codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) []StyleViolation {
+	if !cfg.NamingEnabled() {
+		return nil
+	}
+
+	violations := make(map[string]*StyleViolation)
+	violation := func(key string) *StyleViolation {
+		if v, seen := violations[key]; seen {
+			return v
+		}
+		problems := cfg.NamingProblems(key)
+		if len(problems) == 0 {
+			violations[key] = nil
+			return nil
+		}
+		violations[key] = &StyleViolation{
+			Key:      key,
+			Problems: problems,
+			Severity: cfg.SeverityFor(config.CategoryStyle, key),
+		}
+		return violations[key]
+	}
+
+	for _, usage := range codeUsages {
+		if usage.IsPartial || usage.InIgnoredPath {
+			continue
+		}
+		if v := violation(usage.Key); v != nil {
+			v.Usages = append(v.Usages, usage)
+		}
+	}
+	for key, defs := range definitions {
+		if v := violation(key); v != nil {
+			v.Definitions = defs
+		}
+	}
+
+	var result []StyleViolation
+	for _, v := range violations {
+		if v != nil {
+			result = append(result, *v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
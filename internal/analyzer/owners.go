@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// NoOwner groups findings in files that no CODEOWNERS rule matches
+const NoOwner = "(no owner)"
+
+// OwnedBy reports whether owner is one of owners, ignoring case like GitHub does
+func OwnedBy(owners []string, owner string) bool {
+	for _, o := range owners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterOwner keeps the missing, optional, test-only and dynamic findings used in files owned by owner
+// (dropping the usages of other owners) and the unused variables defined in files owned by owner
+func (r *ScanResult) FilterOwner(owner string) {
+	filter := func(findings map[string][]EnvUsage) {
+		for key, usages := range findings {
+			var owned []EnvUsage
+			for _, usage := range usages {
+				if OwnedBy(usage.Owners, owner) {
+					owned = append(owned, usage)
+				}
+			}
+			if len(owned) == 0 {
+				delete(findings, key)
+			} else {
+				findings[key] = owned
+			}
+		}
+	}
+	filter(r.Missing)
+	filter(r.OptionalMissing)
+	filter(r.TestMissing)
+	filter(r.PartialMatches)
+
+	var unused []string
+	for _, key := range r.Unused {
+		if OwnedBy(r.EnvKeyOwners[key], owner) {
+			unused = append(unused, key)
+		}
+	}
+	r.Unused = unused
+}
+
+// OwnerFindings are the finding keys attributed to one owner
+type OwnerFindings struct {
+	Missing []string // Missing variables used in the owner's files
+	Unused  []string // Unused variables defined in the owner's env files
+	Dynamic []string // Unresolved dynamic patterns in the owner's files
+}
+
+// ByOwner groups the missing, unused and dynamic findings by owner, each list sorted
+// A finding used in files of several owners is listed under each of them, unowned findings under NoOwner
+func (r *ScanResult) ByOwner(skipUnused bool, dynamic bool) map[string]*OwnerFindings {
+	groups := make(map[string]*OwnerFindings)
+	group := func(owner string) *OwnerFindings {
+		if groups[owner] == nil {
+			groups[owner] = &OwnerFindings{}
+		}
+		return groups[owner]
+	}
+	ownersOf := func(usages []EnvUsage) []string {
+		seen := make(map[string]bool)
+		var owners []string
+		for _, usage := range usages {
+			if len(usage.Owners) == 0 && !seen[NoOwner] {
+				seen[NoOwner] = true
+				owners = append(owners, NoOwner)
+			}
+			for _, owner := range usage.Owners {
+				if !seen[owner] {
+					seen[owner] = true
+					owners = append(owners, owner)
+				}
+			}
+		}
+		if len(owners) == 0 {
+			owners = append(owners, NoOwner)
+		}
+		return owners
+	}
+
+	for key, usages := range r.Missing {
+		for _, owner := range ownersOf(usages) {
+			group(owner).Missing = append(group(owner).Missing, key)
+		}
+	}
+	if dynamic {
+		for key, usages := range r.PartialMatches {
+			for _, owner := range ownersOf(usages) {
+				group(owner).Dynamic = append(group(owner).Dynamic, key)
+			}
+		}
+	}
+	if !skipUnused {
+		for _, key := range r.Unused {
+			owners := r.EnvKeyOwners[key]
+			if len(owners) == 0 {
+				owners = []string{NoOwner}
+			}
+			for _, owner := range owners {
+				group(owner).Unused = append(group(owner).Unused, key)
+			}
+		}
+	}
+
+	for _, g := range groups {
+		sort.Strings(g.Missing)
+		sort.Strings(g.Unused)
+		sort.Strings(g.Dynamic)
+	}
+	return groups
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Path separators of ConvertPaths
+const (
+	SeparatorNative    = "native"    // As the OS writes paths, backslashes on Windows
+	SeparatorSlash     = "slash"     // Forward slashes, the same on every OS (e.g., for snapshots)
+	SeparatorBackslash = "backslash" // Backslashes, as on Windows
+)
+
+// PathSeparators lists the separators ConvertPaths accepts
+var PathSeparators = []string{SeparatorNative, SeparatorSlash, SeparatorBackslash}
+
+// ConvertPath returns path with the separator, see ConvertPaths
+func ConvertPath(path string, separator string) string {
+	switch separator {
+	case SeparatorSlash:
+		return strings.ReplaceAll(path, `\`, "/")
+	case SeparatorBackslash:
+		return strings.ReplaceAll(path, "/", `\`)
+	}
+	return filepath.FromSlash(path)
+}
+
+// ConvertPaths rewrites the file paths of the result in place to use the separator, so that
+// reports of the same tree are identical on every OS
+func (r *ScanResult) ConvertPaths(separator string) {
+	convert := func(path string) string { return ConvertPath(path, separator) }
+	usages := func(usages []EnvUsage) {
+		for i := range usages {
+			usages[i].File = convert(usages[i].File)
+		}
+	}
+	definitions := func(definitions []Definition) {
+		for i := range definitions {
+			definitions[i].File = convert(definitions[i].File)
+		}
+	}
+
+	usages(r.CodeKeys)
+	for _, findings := range []map[string][]EnvUsage{r.Missing, r.PartialMatches, r.OptionalMissing, r.TestMissing} {
+		for _, found := range findings {
+			usages(found)
+		}
+	}
+	for key, file := range r.EnvKeySources {
+		r.EnvKeySources[key] = convert(file)
+	}
+	for _, defs := range r.Definitions {
+		definitions(defs)
+	}
+	for i := range r.ParseErrors {
+		r.ParseErrors[i].File = convert(r.ParseErrors[i].File)
+	}
+	for _, conflict := range r.Conflicts {
+		definitions(conflict.Definitions)
+	}
+	for _, mismatch := range r.CaseMismatches {
+		usages(mismatch.Usages)
+		definitions(mismatch.Definitions)
+	}
+	if drift := r.ExampleDrift; drift != nil {
+		for i := range drift.Examples {
+			drift.Examples[i] = convert(drift.Examples[i])
+		}
+		for _, found := range drift.Undocumented {
+			usages(found)
+		}
+		for _, defs := range drift.Definitions {
+			definitions(defs)
+		}
+	}
+	if drift := r.SchemaDrift; drift != nil {
+		drift.Schema = convert(drift.Schema)
+		for _, found := range drift.Undeclared {
+			usages(found)
+		}
+	}
+	if frontend := r.Frontend; frontend != nil {
+		for _, found := range frontend.Unprefixed {
+			usages(found)
+		}
+		for _, found := range frontend.Exposed {
+			usages(found)
+		}
+		for _, defs := range frontend.Definitions {
+			definitions(defs)
+		}
+	}
+	for _, violation := range r.Style {
+		usages(violation.Usages)
+		definitions(violation.Definitions)
+	}
+	for _, deprecated := range r.Deprecated {
+		usages(deprecated.Usages)
+		definitions(deprecated.Definitions)
+	}
+	for _, mismatch := range r.TypeMismatches {
+		usages(mismatch.Usages)
+		definitions(mismatch.Definitions)
+	}
+	for i := range r.Placeholders {
+		usages(r.Placeholders[i].Usages)
+		r.Placeholders[i].Definition.File = convert(r.Placeholders[i].Definition.File)
+	}
+	for i := range r.UnresolvedRefs {
+		r.UnresolvedRefs[i].Definition.File = convert(r.UnresolvedRefs[i].Definition.File)
+	}
+	for _, forbidden := range r.Forbidden {
+		usages(forbidden.Usages)
+		definitions(forbidden.Definitions)
+	}
+	for _, gap := range r.ServiceGaps {
+		for i := range gap.Dirs {
+			gap.Dirs[i] = convert(gap.Dirs[i])
+		}
+		for i := range gap.Manifests {
+			gap.Manifests[i] = convert(gap.Manifests[i])
+		}
+		for _, found := range gap.Missing {
+			usages(found)
+		}
+	}
+	if r.Stats != nil {
+		for i := range r.Stats.SlowestFiles {
+			r.Stats.SlowestFiles[i].Path = convert(r.Stats.SlowestFiles[i].Path)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestConvertPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		separator string
+		want      string
+	}{
+		{`src\app\main.js`, SeparatorSlash, "src/app/main.js"},
+		{"src/app/main.js", SeparatorSlash, "src/app/main.js"},
+		{"src/app/main.js", SeparatorBackslash, `src\app\main.js`},
+		{`src\app/main.js`, SeparatorBackslash, `src\app\main.js`},
+	}
+	for _, tt := range tests {
+		if got := ConvertPath(tt.path, tt.separator); got != tt.want {
+			t.Errorf("ConvertPath(%q, %s) = %q, want %q", tt.path, tt.separator, got, tt.want)
+		}
+	}
+}
+
+func TestScanResult_ConvertPaths(t *testing.T) {
+	usage := EnvUsage{Key: "API_KEY", File: `src\app.js`, Line: 1}
+	definition := Definition{File: `config\.env`, Line: 2}
+	result := ScanResult{
+		CodeKeys:      []EnvUsage{usage},
+		Missing:       map[string][]EnvUsage{"API_KEY": {usage}},
+		EnvKeySources: map[string]string{"PORT": `config\.env`},
+		ParseErrors:   []ParseError{{File: `src\broken.js`}},
+		Conflicts:     []Conflict{{Key: "PORT", Definitions: []Definition{definition}}},
+		ExampleDrift:  &ExampleDrift{Examples: []string{`config\.env.example`}, Definitions: map[string][]Definition{"PORT": {definition}}},
+		Deprecated:    []DeprecatedVar{{Key: "OLD", Usages: []EnvUsage{usage}, Definitions: []Definition{definition}}},
+	}
+
+	result.ConvertPaths(SeparatorSlash)
+
+	for _, got := range []string{
+		result.CodeKeys[0].File,
+		result.Missing["API_KEY"][0].File,
+		result.Deprecated[0].Usages[0].File,
+	} {
+		if got != "src/app.js" {
+			t.Errorf("Expected usage path src/app.js, got %q", got)
+		}
+	}
+	for _, got := range []string{
+		result.EnvKeySources["PORT"],
+		result.Conflicts[0].Definitions[0].File,
+		result.ExampleDrift.Definitions["PORT"][0].File,
+		result.Deprecated[0].Definitions[0].File,
+	} {
+		if got != "config/.env" {
+			t.Errorf("Expected definition path config/.env, got %q", got)
+		}
+	}
+	if result.ParseErrors[0].File != "src/broken.js" || result.ExampleDrift.Examples[0] != "config/.env.example" {
+		t.Errorf("Unexpected paths: %q, %q", result.ParseErrors[0].File, result.ExampleDrift.Examples[0])
+	}
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+)
+
+// placeholderWords are values left to be filled in, compared case-insensitively
+var placeholderWords = map[string]bool{
+	"changeme": true, "change_me": true, "change-me": true, "replaceme": true, "replace_me": true, "replace-me": true,
+	"todo": true, "tbd": true, "fixme": true, "placeholder": true,
+}
+
+// placeholderPattern matches values such as <your-key-here>, your_api_key_here, xxx or XXXX-XXXX
+var placeholderPattern = regexp.MustCompile(`(?i)^(<[^<>]*>|your[-_ ].*[-_ ]here|[x*]{3,}([-_.][x*]{3,})*)$`)
+
+// emptyValueKinds are the source kinds where an empty value sets the variable to "" (in docker-compose and
+// deployment manifests, it passes the variable through from the host or takes it from a secret)
+var emptyValueKinds = map[string]bool{"env": true, "envrc": true}
+
+// IsPlaceholder reports whether value looks like a placeholder rather than a real value
+func IsPlaceholder(value string) bool {
+	value = strings.TrimSpace(value)
+	return placeholderWords[strings.ToLower(value)] || placeholderPattern.MatchString(value)
+}
+
+// DetectPlaceholders finds the variables read in code whose effective definition is empty or a placeholder
+// Example files are not checked, and empty values are fine when every lookup has a fallback
+func DetectPlaceholders(codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) []PlaceholderValue {
+	usages := make(map[string][]EnvUsage)
+	for _, usage := range codeUsages {
+		if !usage.IsPartial && !usage.InIgnoredPath {
+			usages[usage.Key] = append(usages[usage.Key], usage)
+		}
+	}
+
+	var result []PlaceholderValue
+	for key, keyUsages := range usages {
+		def, ok := effectiveDefinition(definitions[key])
+		if !ok {
+			continue
+		}
+		empty := strings.TrimSpace(def.Value) == ""
+		switch {
+		case empty && (!emptyValueKinds[def.Kind] || allOptional(keyUsages)):
+			continue
+		case !empty && !IsPlaceholder(def.Value):
+			continue
+		}
+		result = append(result, PlaceholderValue{
+			Key:        key,
+			Definition: def,
+			Empty:      empty,
+			Severity:   cfg.SeverityFor(config.CategoryPlaceholder, key),
+			Usages:     keyUsages,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// effectiveDefinition returns the definition that takes effect among those outside example files: the last
+// one for the default environment, or the last one if every definition is environment-specific
+func effectiveDefinition(defs []Definition) (Definition, bool) {
+	var last *Definition
+	for i := len(defs) - 1; i >= 0; i-- {
+		if envfile.IsExampleFile(defs[i].File) {
+			continue
+		}
+		if defs[i].Environment == "" {
+			return defs[i], true
+		}
+		if last == nil {
+			last = &defs[i]
+		}
+	}
+	if last == nil {
+		return Definition{}, false
+	}
+	return *last, true
+}
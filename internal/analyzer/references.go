@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+)
+
+// DetectUnresolvedReferences reports the env file values with ${VAR} references to variables that are
+// neither defined nor exported (see envfile.Resolve), one finding per definition
+// Example files are not checked, and system or ignored variables are expected to be set at runtime, except
+// by the $(VAR) references of Kubernetes containers, which only see the container's own env list
+func DetectUnresolvedReferences(definitions map[string][]Definition, cfg *config.Config) []UnresolvedReference {
+	var result []UnresolvedReference
+	for key, defs := range definitions {
+		for _, def := range defs {
+			if len(def.Unresolved) == 0 || envfile.IsExampleFile(def.File) {
+				continue
+			}
+			var refs []string
+			for _, ref := range def.Unresolved {
+				if (def.Kind == "k8s" || !cfg.IsSystemVar(ref)) && !cfg.ShouldIgnoreMissing(ref) {
+					refs = append(refs, ref)
+				}
+			}
+			if len(refs) == 0 {
+				continue
+			}
+			result = append(result, UnresolvedReference{
+				Key:        key,
+				References: refs,
+				Definition: def,
+				Severity:   cfg.SeverityFor(config.CategoryReference, key),
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Key != result[j].Key {
+			return result[i].Key < result[j].Key
+		}
+		if result[i].Definition.File != result[j].Definition.File {
+			return result[i].Definition.File < result[j].Definition.File
+		}
+		return result[i].Definition.Line < result[j].Definition.Line
+	})
+	return result
+}
@@ -0,0 +1,22 @@
+package analyzer
+
+import "github.com/jenian/envgrd/internal/config"
+
+// DetectRemediation returns the remediation hints the config sets for the variables with findings,
+// keyed by variable, or nil when none are set
+func DetectRemediation(findings Findings, cfg *config.Config) map[string]string {
+	var hints map[string]string
+	for _, keys := range findings {
+		for _, key := range keys {
+			hint, ok := cfg.RemediationHint(key)
+			if !ok || hint == "" {
+				continue
+			}
+			if hints == nil {
+				hints = make(map[string]string)
+			}
+			hints[key] = hint
+		}
+	}
+	return hints
+}
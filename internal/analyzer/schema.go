@@ -0,0 +1,60 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/schema"
+)
+
+// DetectSchemaDrift compares the variables declared in the schema with code usages: required entries no code
+// reads are likely dead, and variables code reads without an entry have drifted from the schema
+// Usages in ignored folders, system variables and ignored variables don't need declaring, but usages in
+// ignored folders and dynamic patterns still keep an entry in use. It returns nil without a schema
+func DetectSchemaDrift(codeUsages []EnvUsage, s *schema.Schema, schemaFile string, cfg *config.Config) *SchemaDrift {
+	if s == nil {
+		return nil
+	}
+
+	drift := &SchemaDrift{
+		Schema:     schemaFile,
+		Unread:     []string{},
+		Undeclared: make(map[string][]EnvUsage),
+		Severities: make(map[string]config.Severity),
+	}
+
+	usedKeys := make(map[string]bool)
+	var partials []string
+	for _, usage := range codeUsages {
+		if usage.IsPartial {
+			if !usage.IsVarRef {
+				partials = append(partials, usage.Key)
+			}
+			continue
+		}
+		usedKeys[usage.Key] = true
+		if usage.InIgnoredPath || s.Declares(usage.Key) {
+			continue
+		}
+		if cfg.IsSystemVar(usage.Key) || cfg.ShouldIgnoreMissing(usage.Key) || cfg.ShouldIgnoreMissingIn(usage.Key, usage.File) {
+			continue
+		}
+		drift.Undeclared[usage.Key] = append(drift.Undeclared[usage.Key], usage)
+	}
+
+	for _, key := range s.Required() {
+		if usedKeys[key] || (cfg != nil && cfg.IsRequired(key)) || matchesPartial(key, partials) {
+			continue
+		}
+		drift.Unread = append(drift.Unread, key)
+	}
+
+	for key := range drift.Undeclared {
+		drift.Severities[key] = cfg.SeverityFor(config.CategoryUndeclared, key)
+	}
+	for _, key := range drift.Unread {
+		drift.Severities[key] = cfg.SeverityFor(config.CategoryUnread, key)
+	}
+	sort.Strings(drift.Unread)
+	return drift
+}
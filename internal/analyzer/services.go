@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// DetectServiceGaps checks the code of each deployable unit against its own environment (see config.Service),
+// rather than against every env file of the repository, so a variable one service defines doesn't hide
+// that another one reading it lacks it
+// Dynamic patterns, lookups with a default, usages in tests or ignored folders, system variables and
+// ignored variables are left out
+func DetectServiceGaps(codeUsages []EnvUsage, cfg *config.Config) []ServiceGap {
+	if cfg == nil || len(cfg.Services) == 0 {
+		return nil
+	}
+	var gaps []ServiceGap
+	for _, service := range cfg.Services {
+		gap := ServiceGap{
+			Service:   service.Name,
+			Dirs:      slices.Clone(service.Dirs),
+			Manifests: slices.Clone(service.Manifests),
+			Defined:   len(service.Defined),
+			Missing:   make(map[string][]EnvUsage),
+		}
+		for _, usage := range codeUsages {
+			if usage.IsPartial || usage.IsOptional || usage.InTest || usage.InIgnoredPath || !service.Contains(usage.File) {
+				continue
+			}
+			if service.Defined[usage.Key] || cfg.IsSystemVar(usage.Key) || cfg.ShouldIgnoreMissing(usage.Key) || cfg.ShouldIgnoreMissingIn(usage.Key, usage.File) {
+				continue
+			}
+			gap.Missing[usage.Key] = append(gap.Missing[usage.Key], usage)
+		}
+		gaps = append(gaps, gap)
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Service < gaps[j].Service })
+	return gaps
+}
@@ -1,5 +1,14 @@
 package analyzer
 
+import (
+	"sort"
+
+	"github.com/jenian/envgrd/internal/blame"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/schema"
+	"github.com/jenian/envgrd/internal/stats"
+)
+
 // EnvUsage represents a single usage of an environment variable in code
 type EnvUsage struct {
 	Key          string // The environment variable key
@@ -10,6 +19,10 @@ type EnvUsage struct {
 	IsPartial    bool   // True if this is a partial match from dynamic code (e.g., "prefix_" + var)
 	IsVarRef     bool   // True if this is a variable reference pattern (e.g., process.env[a])
 	FullExpr     string // Full expression for dynamic patterns (e.g., "prefix_" + var)
+	IsOptional   bool   // True if the lookup falls back to a default (e.g., process.env.KEY || "default")
+	Type         string // Type code parses the value as (e.g., integer for strconv.Atoi(os.Getenv("PORT"))), empty when unknown
+	InTest       bool   // True if the usage is in a test file (e.g., *_test.go, *.spec.ts, tests/)
+	Owners       []string // Owners of the file from CODEOWNERS (e.g., @org/backend), empty without a CODEOWNERS file
 }
 
 // EnvFile represents a parsed environment file
@@ -23,10 +36,258 @@ type ScanResult struct {
 	CodeKeys           []EnvUsage            // All env var usages found in code
 	EnvKeys            map[string]string     // All env vars from .env files
 	EnvKeySources      map[string]string     // Maps env var key to source file path
-	Missing            map[string][]EnvUsage  // Missing keys (in code but not in .env) grouped by key
-	PartialMatches     map[string][]EnvUsage  // Partial matches (dynamic code patterns) grouped by prefix/suffix
+	EnvKeyLines        map[string]int        // Maps env var key to the line it's defined on in its source file (0 if unknown)
+	EnvKeyOwners       map[string][]string   // Maps env var key to the CODEOWNERS owners of its source file, nil without a CODEOWNERS file
+	Definitions        map[string][]Definition // Every definition of each env file variable, in load order
+	Missing            map[string][]EnvUsage  // Missing keys (in code or required by config, but not in .env) grouped by key
+	PartialMatches     map[string][]EnvUsage  // Partial matches (dynamic code patterns) grouped by prefix/suffix that match no defined variable
+	Confidence         map[string]Confidence  // Confidence of each unresolved dynamic pattern, keyed like PartialMatches
+	DynamicMatches     map[string][]string    // Dynamic code patterns mapped to the defined variables they can resolve to (sorted), nil when none resolved
+	OptionalMissing    map[string][]EnvUsage  // Keys not in .env whose every usage falls back to a default, grouped by key
+	TestMissing        map[string][]EnvUsage  // Keys not in .env only used in test files, grouped by key (only in the report test mode)
 	Unused             []string              // Unused keys (in .env but not in code)
+	UnusedBlame        map[string]blame.Line // Last change of each unused variable's definition from git blame, only set when requested
 	IgnoredMissing     int                   // Count of missing variables that were ignored via config
+	IgnoredUnused      int                   // Count of unused variables that were ignored via config
 	IgnoredFromFolders int                   // Count of unique variables found in ignored folders
+	IgnoredSystem      int                   // Count of missing variables provided by the OS, CI or a runtime (e.g., PATH, CI)
+	IgnoredTests       int                   // Count of missing variables only used in test files (only in the exclude test mode)
+	IgnoredDynamic     int                   // Count of unresolved dynamic patterns below the minimum confidence, see FilterConfidence
+	Severities         map[string]config.Severity // Severity of each finding, keyed like Missing, OptionalMissing, TestMissing, PartialMatches and Unused
+	Stats              *stats.Stats               // Scan timings and memory usage, only set when requested
+	Fixed              *FixedFindings             // Findings of the previous run that are gone, only set with --since-last-run
+	Comparison         *Comparison                // Findings classified against a previous report, only set with --compare-to
+	ParseErrors        []ParseError               // Files that could not be analyzed, sorted by file
+	Required           []string                   // Variables declared in the config's required list, sorted
+	Conflicts          []Conflict                 // Variables defined with different values in several env files, sorted by key
+	CaseMismatches     []CaseMismatch             // Variables read with another casing than they're defined with, only in case-insensitive mode, sorted by key
+	ExampleDrift       *ExampleDrift              // Differences between example env files and code, nil when no example file was loaded
+	SchemaDrift        *SchemaDrift               // Differences between the variable schema and code, nil without a schema
+	Schema             *schema.Schema             // Variables declared in the schema file and in env file comments, nil when none are
+	Policy             *config.Policy             // Central rules the scan enforced over the config (--policy), nil without one
+	Frontend           *FrontendLeaks             // Public-prefix findings of client-side code, nil when no frontend framework is used
+	Style              []StyleViolation           // Names breaking the config's naming rules, sorted by key
+	Deprecated         []DeprecatedVar            // Deprecated variables still used or defined, sorted by key
+	TypeMismatches     []TypeMismatch             // Env file values code can't parse as the type it expects, sorted by key
+	Placeholders       []PlaceholderValue         // Variables read in code with an empty or placeholder value, sorted by key
+	UnresolvedRefs     []UnresolvedReference      // Env file values referencing undefined variables, sorted by key
+	Forbidden          []ForbiddenVar             // Forbidden variables used or defined, sorted by key
+	ServiceGaps        []ServiceGap               // Variables each deployable unit reads but doesn't define, sorted by service
+	Remediation        map[string]string          // Configured remediation hints of the variables with findings, nil when none are set
+}
+
+// ParseError records a source file that could not be analyzed, so its usages are unknown
+type ParseError struct {
+	File     string // File path relative to the scan root
+	Language string // Language the file was parsed as
+	Error    string // Why parsing or querying failed
+}
+
+// Definition is one definition of a variable in an env file
+type Definition struct {
+	File  string // Env file path relative to the scan root
+	Line  int    // Line of the definition, 0 if unknown
+	Value string // Raw value, redact before displaying
+	Kind  string // Source kind of the file (e.g., env, docker-compose, k8s)
+	// Environment is the environment block of the definition (e.g., production for the env_production
+	// block of a PM2 ecosystem file), empty for the default environment
+	Environment string
+	// Service is the docker-compose service whose environment block holds the definition, empty for
+	// files without services
+	Service string
+	// Unresolved lists the variables of ${VAR} references in the value that are neither defined nor exported
+	Unresolved []string
+}
+
+// ServiceGap is a deployable unit (see config.Service) with the variables its code reads that its own
+// environment doesn't define
+type ServiceGap struct {
+	Service   string
+	Dirs      []string              // Code directories, relative to the scan root
+	Manifests []string              // Env files defining its environment, relative to the scan root
+	Defined   int                   // Number of variables its environment defines
+	Missing   map[string][]EnvUsage // Usages in its code of each variable its environment doesn't define
+}
+
+// Conflict is a variable defined with different values in several env files
+type Conflict struct {
+	Key         string
+	Definitions []Definition // In load order
+	Effective   int          // Index of the definition that takes effect (later files override earlier ones)
+}
+
+// CaseMismatch is a variable read in code with another casing than it's defined with, which only
+// matches where variable names are case-insensitive (e.g., Path in code and PATH in .env on Windows)
+type CaseMismatch struct {
+	Key         string       // Name as read in code
+	Defined     string       // Name as defined
+	Usages      []EnvUsage   // Usages with the code casing
+	Definitions []Definition // Definitions in env files, empty for exported variables
+}
+
+// ExampleDrift compares example env files (e.g., .env.example) with code usages and the real env files
+type ExampleDrift struct {
+	Examples     []string                   // Example files that were compared, relative to the scan root, sorted
+	Undocumented map[string][]EnvUsage      // Keys used in code or defined in real env files but missing from every example (usages are empty for keys only in env files)
+	Stale        []string                   // Keys listed in an example but never used in code, sorted
+	Definitions  map[string][]Definition    // Where undocumented keys are defined in real env files and stale keys in examples
+	Severities   map[string]config.Severity // Severity of each finding (undocumented and stale keys never overlap)
+}
+
+// SeverityOf returns the severity of a drift finding for key, falling back to the category default
+func (d *ExampleDrift) SeverityOf(category string, key string) config.Severity {
+	if severity, ok := d.Severities[key]; ok {
+		return severity
+	}
+	return config.DefaultSeverity(category)
+}
+
+// Count returns the number of drift findings
+func (d *ExampleDrift) Count() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.Undocumented) + len(d.Stale)
+}
+
+// SchemaDrift compares the variables declared in the schema (see schema.Schema) with code usages
+type SchemaDrift struct {
+	Schema     string                     // Schema file that was compared, relative to the scan root
+	Unread     []string                   // Keys the schema declares required but no code reads, sorted
+	Undeclared map[string][]EnvUsage      // Keys read in code that the schema doesn't declare
+	Severities map[string]config.Severity // Severity of each finding (unread and undeclared keys never overlap)
+}
+
+// SeverityOf returns the severity of a schema finding for key, falling back to the category default
+func (d *SchemaDrift) SeverityOf(category string, key string) config.Severity {
+	if severity, ok := d.Severities[key]; ok {
+		return severity
+	}
+	return config.DefaultSeverity(category)
+}
+
+// Count returns the number of schema findings
+func (d *SchemaDrift) Count() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.Unread) + len(d.Undeclared)
+}
+
+// FrontendLeaks lists client-side usages without the framework's public prefix, and secrets exposed with it
+type FrontendLeaks struct {
+	Framework   string                     // Framework whose prefix was checked (vite, next or cra)
+	Prefix      string                     // Public prefix of the framework (e.g., VITE_)
+	Unprefixed  map[string][]EnvUsage      // Keys read in client-side code without the prefix, so they're undefined in the browser
+	Exposed     map[string][]EnvUsage      // Secret-looking keys with the prefix (usages are empty for keys only in env files)
+	Definitions map[string][]Definition    // Where exposed keys are defined
+	Severities  map[string]config.Severity // Severity of each finding (unprefixed and exposed keys never overlap)
 }
 
+// SeverityOf returns the severity of a frontend finding for key, falling back to the category default
+func (f *FrontendLeaks) SeverityOf(category string, key string) config.Severity {
+	if severity, ok := f.Severities[key]; ok {
+		return severity
+	}
+	return config.DefaultSeverity(category)
+}
+
+// Count returns the number of frontend findings
+func (f *FrontendLeaks) Count() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.Unprefixed) + len(f.Exposed)
+}
+
+// StyleViolation is a variable name that breaks naming-convention rules
+type StyleViolation struct {
+	Key         string
+	Problems    []string        // Broken rules (e.g., "not UPPER_SNAKE_CASE", "missing prefix MYAPP_")
+	Severity    config.Severity // Severity of the finding
+	Usages      []EnvUsage      // Where the name is used in code
+	Definitions []Definition    // Where the name is defined in env files
+}
+
+// DeprecatedVar is a deprecated variable that is still used in code or defined in env files
+type DeprecatedVar struct {
+	Key         string
+	Hint        string          // Migration hint from the config (e.g., "use DATABASE_URL")
+	Severity    config.Severity // Severity of the finding
+	Usages      []EnvUsage      // Remaining usages in code
+	Definitions []Definition    // Definitions in env files that can be removed
+}
+
+// ForbiddenVar is a forbidden variable that is used in code or defined in env files
+type ForbiddenVar struct {
+	Key         string
+	Message     string          // Remediation message from the config (e.g., "use the SDK credential chain")
+	Severity    config.Severity // Severity of the finding
+	Usages      []EnvUsage      // Usages in code
+	Definitions []Definition    // Definitions in env files
+}
+
+// TypeMismatch is a variable whose env file values code can't parse as the type it expects
+// (e.g., PORT=abc read with strconv.Atoi)
+type TypeMismatch struct {
+	Key         string
+	Type        string          // Type code parses the value as (e.g., integer)
+	Severity    config.Severity // Severity of the finding
+	Usages      []EnvUsage      // Usages parsing the value as Type
+	Definitions []Definition    // Definitions whose value isn't a valid Type
+}
+
+// PlaceholderValue is a variable read in code whose effective env file value is empty or a placeholder
+// (e.g., changeme or <your-key-here>), which passes the missing check but breaks at runtime
+type PlaceholderValue struct {
+	Key        string
+	Definition Definition      // Effective definition of the variable
+	Empty      bool            // The value is empty rather than a placeholder
+	Severity   config.Severity // Severity of the finding
+	Usages     []EnvUsage      // Where the variable is read in code
+}
+
+// UnresolvedReference is an env file value with ${VAR} references to variables that are neither defined
+// nor exported, which expand to an empty string at runtime
+type UnresolvedReference struct {
+	Key        string          // Variable whose value holds the references
+	References []string        // Referenced variables that can't be resolved
+	Definition Definition      // Definition holding the value
+	Severity   config.Severity // Severity of the finding
+}
+
+// InvalidValue is an env file value that fails the validation of its variable, see ValidateValues
+type InvalidValue struct {
+	Key        string
+	Definition Definition
+	Rule       string // What the value was checked against: a format of the schema package (e.g., url), type or enum
+	Inferred   bool   // The format was inferred from the name instead of declared in the schema
+	Problem    string // What's wrong with the value (e.g., "port 70000 is out of range 1-65535")
+}
+
+// FixedFindings lists findings from a previous run that no longer occur
+type FixedFindings struct {
+	Missing []string
+	Unused  []string
+	Dynamic []string
+}
+
+// Count returns the total number of fixed findings
+func (f *FixedFindings) Count() int {
+	return len(f.Missing) + len(f.Unused) + len(f.Dynamic)
+}
+
+// SeverityOf returns the severity of the finding for key, falling back to the category default
+func (r ScanResult) SeverityOf(category string, key string) config.Severity {
+	if severity, ok := r.Severities[key]; ok {
+		return severity
+	}
+	return config.DefaultSeverity(category)
+}
+
+
+// IsRequired reports whether key is declared in the config's required list
+func (r ScanResult) IsRequired(key string) bool {
+	i := sort.SearchStrings(r.Required, key)
+	return i < len(r.Required) && r.Required[i] == key
+}
@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/schema"
+)
+
+// ValidateValues checks the env file values of each variable against the type, allowed values and format
+// the schema declares for it, or the format its name implies (e.g., url for DATABASE_URL) without a schema
+// entry. Relative paths are resolved from the directory of their env file, relative to dir. Like type mismatches, example files, empty values and values
+// with references are not checked; each definition reports its first problem, sorted by key
+func ValidateValues(definitions map[string][]Definition, s *schema.Schema, dir string) []InvalidValue {
+	var invalid []InvalidValue
+	for key, defs := range definitions {
+		var declared schema.Variable
+		if s != nil {
+			declared = s.Variables[key]
+		}
+		format := s.Format(key)
+		for _, def := range defs {
+			if envfile.IsExampleFile(def.File) || !checkable(def.Value) {
+				continue
+			}
+			finding := InvalidValue{Key: key, Definition: def}
+			switch {
+			case declared.Type != "" && !schema.Matches(declared.Type, def.Value):
+				finding.Rule, finding.Problem = "type", fmt.Sprintf("expected a value of type %s", declared.Type)
+			case len(declared.Enum) > 0 && !slices.Contains(declared.Enum, strings.TrimSpace(def.Value)):
+				finding.Rule, finding.Problem = "enum", fmt.Sprintf("expected one of %s", strings.Join(declared.Enum, ", "))
+			case format != "":
+				finding.Rule, finding.Inferred, finding.Problem = format, declared.Format == "", schema.CheckFormat(format, def.Value, definitionDir(dir, def.File))
+			}
+			if finding.Problem != "" {
+				invalid = append(invalid, finding)
+			}
+		}
+	}
+	sort.SliceStable(invalid, func(i, j int) bool {
+		return invalid[i].Key < invalid[j].Key
+	})
+	return invalid
+}
+
+// definitionDir returns the directory of an env file, relative to dir unless absolute
+func definitionDir(dir string, file string) string {
+	if filepath.IsAbs(file) {
+		return filepath.Dir(file)
+	}
+	return filepath.Join(dir, filepath.Dir(file))
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/schema"
+)
+
+// DetectTypeMismatches checks env file values against the type code parses them as (e.g., strconv.Atoi or
+// parseInt read an integer), reporting one finding per variable and type
+// Example files, empty values and values with references (${VAR}, $(VAR)) resolved elsewhere are not checked
+func DetectTypeMismatches(codeUsages []EnvUsage, definitions map[string][]Definition, cfg *config.Config) []TypeMismatch {
+	parsed := make(map[string]map[string][]EnvUsage)
+	for _, usage := range codeUsages {
+		if usage.Type == "" || usage.IsPartial || usage.InIgnoredPath {
+			continue
+		}
+		if parsed[usage.Key] == nil {
+			parsed[usage.Key] = make(map[string][]EnvUsage)
+		}
+		parsed[usage.Key][usage.Type] = append(parsed[usage.Key][usage.Type], usage)
+	}
+
+	var result []TypeMismatch
+	for key, types := range parsed {
+		for typ, usages := range types {
+			var invalid []Definition
+			for _, def := range definitions[key] {
+				if envfile.IsExampleFile(def.File) || !checkable(def.Value) {
+					continue
+				}
+				if !schema.Matches(typ, def.Value) {
+					invalid = append(invalid, def)
+				}
+			}
+			if len(invalid) > 0 {
+				result = append(result, TypeMismatch{
+					Key:         key,
+					Type:        typ,
+					Severity:    cfg.SeverityFor(config.CategoryType, key),
+					Usages:      usages,
+					Definitions: invalid,
+				})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Key != result[j].Key {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// checkable reports whether a value is set literally, so its type can be checked
+func checkable(value string) bool {
+	return strings.TrimSpace(value) != "" && !strings.Contains(value, "${") && !strings.Contains(value, "$(")
+}
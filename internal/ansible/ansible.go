@@ -0,0 +1,148 @@
+// Package ansible reads environment variables from Ansible playbooks and role tasks: the variables
+// set by environment: dictionaries, and the controller variables read by env lookups
+package ansible
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variable is an environment variable set or read by an Ansible file
+type Variable struct {
+	Name     string
+	Value    string // Value set by an environment: dictionary, empty for lookups
+	Line     int    // 1-based line of the key or lookup
+	Optional bool   // The lookup has a default
+}
+
+// IsAnsibleFile reports whether path is an Ansible playbook or role task file: a YAML file in a
+// playbooks directory or a role's tasks or handlers, or one named like site.yml or deploy-playbook.yml
+func IsAnsibleFile(path string) bool {
+	ext := filepath.Ext(path)
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	name := strings.TrimSuffix(segments[len(segments)-1], ext)
+	if name == "site" || strings.Contains(name, "playbook") {
+		return true
+	}
+	if len(segments) >= 2 && segments[len(segments)-2] == "playbooks" {
+		return true
+	}
+	// roles/<role>/tasks/<file>
+	if n := len(segments); n >= 4 && segments[n-4] == "roles" {
+		return segments[n-2] == "tasks" || segments[n-2] == "handlers"
+	}
+	return false
+}
+
+// varReference matches an environment: value that is a single variable, e.g. "{{ proxy_env }}"
+var varReference = regexp.MustCompile(`^\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}$`)
+
+// Environment returns the variables set by the environment: dictionaries of plays, blocks and tasks,
+// including dictionaries defined under vars: and passed by name (environment: "{{ proxy_env }}")
+func Environment(content []byte) ([]Variable, error) {
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		documents = append(documents, &document)
+	}
+
+	// Dictionaries defined under vars:, by name
+	dictionaries := make(map[string]*yaml.Node)
+	for _, document := range documents {
+		walkMappings(document, func(key, value *yaml.Node) {
+			if key.Value != "vars" || value.Kind != yaml.MappingNode {
+				return
+			}
+			for i := 0; i+1 < len(value.Content); i += 2 {
+				if value.Content[i+1].Kind == yaml.MappingNode {
+					dictionaries[value.Content[i].Value] = value.Content[i+1]
+				}
+			}
+		})
+	}
+
+	var variables []Variable
+	for _, document := range documents {
+		walkMappings(document, func(key, value *yaml.Node) {
+			if key.Value != "environment" {
+				return
+			}
+			if value.Kind == yaml.ScalarNode {
+				match := varReference.FindStringSubmatch(value.Value)
+				if match == nil || dictionaries[match[1]] == nil {
+					return
+				}
+				value = dictionaries[match[1]]
+			}
+			if value.Kind != yaml.MappingNode {
+				return
+			}
+			for i := 0; i+1 < len(value.Content); i += 2 {
+				name, val := value.Content[i], value.Content[i+1]
+				if val.Kind != yaml.ScalarNode {
+					continue
+				}
+				variables = append(variables, Variable{Name: name.Value, Value: val.Value, Line: name.Line})
+			}
+		})
+	}
+	return variables, nil
+}
+
+// walkMappings calls visit with every key and value of the mappings below node
+func walkMappings(node *yaml.Node, visit func(key, value *yaml.Node)) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			visit(node.Content[i], node.Content[i+1])
+		}
+	}
+	for _, child := range node.Content {
+		walkMappings(child, visit)
+	}
+}
+
+var (
+	// envLookup matches env lookups and queries, e.g. lookup('env', 'HOME') or query("ansible.builtin.env", "A", "B")
+	envLookup = regexp.MustCompile(`\b(?:lookup|query|q)\(\s*['"](?:ansible\.builtin\.)?env['"]\s*((?:,\s*[^,()]+)+)\)`)
+	// lookupName matches a quoted variable name among the lookup's arguments
+	lookupName = regexp.MustCompile(`^['"]([A-Za-z_][A-Za-z0-9_]*)['"]$`)
+)
+
+// Lookups returns the controller variables read by env lookups, with default= making them optional
+func Lookups(content []byte) []Variable {
+	var variables []Variable
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, match := range envLookup.FindAllStringSubmatch(line, -1) {
+			var names []string
+			optional := false
+			for _, arg := range strings.Split(match[1], ",") {
+				arg = strings.TrimSpace(arg)
+				if strings.HasPrefix(arg, "default") {
+					optional = true
+				} else if name := lookupName.FindStringSubmatch(arg); name != nil {
+					names = append(names, name[1])
+				}
+			}
+			for _, name := range names {
+				variables = append(variables, Variable{Name: name, Line: i + 1, Optional: optional})
+			}
+		}
+	}
+	return variables
+}
@@ -0,0 +1,78 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsAnsibleFile(t *testing.T) {
+	tests := map[string]bool{
+		"site.yml":                      true,
+		"deploy-playbook.yaml":          true,
+		"playbooks/web.yml":             true,
+		"roles/app/tasks/main.yml":      true,
+		"infra/roles/db/handlers/x.yml": true,
+		"roles/app/defaults/main.yml":   false,
+		"docker-compose.yml":            false,
+		"roles/app/tasks/main.py":       false,
+	}
+	for path, want := range tests {
+		if got := IsAnsibleFile(path); got != want {
+			t.Errorf("IsAnsibleFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	content := `- hosts: web
+  vars:
+    proxy_env:
+      HTTP_PROXY: http://proxy.local:8080
+  environment:
+    APP_ENV: production
+  tasks:
+    - name: Install packages
+      apt:
+        name: nginx
+      environment: "{{ proxy_env }}"
+    - name: Migrate
+      command: ./migrate
+      environment:
+        DATABASE_URL: "{{ lookup('env', 'DATABASE_URL') }}"
+`
+	variables, err := Environment([]byte(content))
+	if err != nil {
+		t.Fatalf("Environment() error: %v", err)
+	}
+	want := []Variable{
+		{Name: "APP_ENV", Value: "production", Line: 6},
+		{Name: "HTTP_PROXY", Value: "http://proxy.local:8080", Line: 4},
+		{Name: "DATABASE_URL", Value: "{{ lookup('env', 'DATABASE_URL') }}", Line: 15},
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("Environment() = %+v, want %+v", variables, want)
+	}
+
+	if _, err := Environment([]byte("- hosts: [\n")); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
+
+func TestLookups(t *testing.T) {
+	content := `- name: Deploy
+  vars:
+    token: "{{ lookup('env', 'DEPLOY_TOKEN') }}"
+    paths: "{{ query('ansible.builtin.env', 'HOME', 'PATH') }}"
+    region: "{{ lookup('env', 'AWS_REGION', default='us-east-1') }}"
+    other: "{{ lookup('file', 'NOT_ENV') }}"
+`
+	want := []Variable{
+		{Name: "DEPLOY_TOKEN", Line: 3},
+		{Name: "HOME", Line: 4},
+		{Name: "PATH", Line: 4},
+		{Name: "AWS_REGION", Line: 5, Optional: true},
+	}
+	if got := Lookups([]byte(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookups() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package blame
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line is the last change of a line according to git blame
+type Line struct {
+	Commit string    // Abbreviated commit hash
+	Author string    // Author name
+	Time   time.Time // Author time
+}
+
+// uncommitted is the hash git blame reports for lines that aren't committed yet
+const uncommitted = "0000000000000000000000000000000000000000"
+
+// File runs git blame on a file (relative to root) and returns the last change of each line by line number
+// Uncommitted lines are left out; files outside a git repository or not tracked return an error
+func File(ctx context.Context, root string, file string) (map[int]Line, error) {
+	cmd := exec.CommandContext(ctx, "git", "blame", "--porcelain", "--", file)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git blame %s: %s", file, msg)
+		}
+		return nil, fmt.Errorf("git blame %s: %w", file, err)
+	}
+	return parsePorcelain(out)
+}
+
+// parsePorcelain parses the output of git blame --porcelain
+// Each line starts with a "<hash> <original line> <final line>" header; the commit's author lines
+// only follow the first header of each commit, and the line content is prefixed with a tab
+func parsePorcelain(out []byte) (map[int]Line, error) {
+	commits := make(map[string]*Line)
+	lines := make(map[int]Line)
+
+	var hash string
+	var final int
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			if commit := commits[hash]; commit != nil && hash != uncommitted {
+				lines[final] = *commit
+			}
+		case strings.HasPrefix(text, "author "):
+			commits[hash].Author = strings.TrimPrefix(text, "author ")
+		case strings.HasPrefix(text, "author-time "):
+			seconds, err := strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid author-time %q", text)
+			}
+			commits[hash].Time = time.Unix(seconds, 0)
+		default:
+			fields := strings.Fields(text)
+			if len(fields) < 3 || len(fields[0]) != len(uncommitted) {
+				continue // Other commit headers (committer, summary, filename, ...)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			hash, final = fields[0], n
+			if commits[hash] == nil {
+				commits[hash] = &Line{Commit: hash[:7]}
+			}
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// Age renders how long ago t was, e.g. "3 days", "14 months" or "3 years"
+func Age(t time.Time, now time.Time) string {
+	days := int(now.Sub(t).Hours() / 24)
+	switch {
+	case days < 1:
+		return "less than a day"
+	case days < 60:
+		return plural(days, "day")
+	case days < 730:
+		return plural(days*12/365, "month")
+	default:
+		return plural(days/365, "year")
+	}
+}
+
+// plural formats a count with a singular or plural unit
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,59 @@
+package blame
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePorcelain(t *testing.T) {
+	out := []byte(`1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c 1 1 2
+author Dana Smith
+author-mail <dana@example.com>
+author-time 1700000000
+author-tz +0000
+summary Add env
+filename .env.production
+	API_URL=https://api.example.com
+1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c 2 2
+	LEGACY_FLAG=1
+0000000000000000000000000000000000000000 3 3 1
+author Not Committed Yet
+author-time 1800000000
+filename .env.production
+	NEW_FLAG=1
+`)
+
+	lines, err := parsePorcelain(out)
+	if err != nil {
+		t.Fatalf("parsePorcelain failed: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 committed lines, got %+v", lines)
+	}
+	want := Line{Commit: "1f2e3d4", Author: "Dana Smith", Time: time.Unix(1700000000, 0)}
+	if lines[2] != want {
+		t.Errorf("Expected line 2 to be %+v, got %+v", want, lines[2])
+	}
+	if _, ok := lines[3]; ok {
+		t.Error("Expected uncommitted lines to be left out")
+	}
+}
+
+func TestAge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		then time.Time
+		want string
+	}{
+		{now.Add(-time.Hour), "less than a day"},
+		{now.AddDate(0, 0, -1), "1 day"},
+		{now.AddDate(0, 0, -45), "45 days"},
+		{now.AddDate(0, -14, 0), "14 months"},
+		{now.AddDate(-3, 0, 0), "3 years"},
+	}
+	for _, tt := range tests {
+		if got := Age(tt.then, now); got != tt.want {
+			t.Errorf("Age(%s) = %q, want %q", tt.then, got, tt.want)
+		}
+	}
+}
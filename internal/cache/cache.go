@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// formatVersion is part of every key; bump it whenever extraction changes so stale entries are never reused
+const formatVersion = 4
+
+// Cache stores extracted usages on disk keyed by a hash of the file content
+// Entries don't depend on where the file lives, so renamed or copied files still hit
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the default cache directory (e.g., ~/.cache/envgrd on Linux)
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+	}
+	return filepath.Join(base, "envgrd"), nil
+}
+
+// New creates a cache rooted at dir; the directory is created on first write
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Key returns the cache key for content parsed as lang with the given query
+func Key(lang string, query string, content []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{strconv.Itoa(formatVersion), lang, query} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get returns the cached usages for key
+// File is left empty in the returned usages; callers fill it in for the file being scanned
+func (c *Cache) Get(key string) ([]analyzer.EnvUsage, bool) {
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var usages []analyzer.EnvUsage
+	if err := json.Unmarshal(data, &usages); err != nil {
+		// Treat corrupt entries as misses, they are overwritten by the next Put
+		return nil, false
+	}
+	return usages, true
+}
+
+// Put stores usages under key
+func (c *Cache) Put(key string, usages []analyzer.EnvUsage) error {
+	// Strip per-file fields so the entry can be shared by identical files
+	entry := make([]analyzer.EnvUsage, len(usages))
+	for i, usage := range usages {
+		usage.File = ""
+		usage.InIgnoredPath = false
+		entry[i] = usage
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	path := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temp file and rename so concurrent scans never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// EnvSnapshotPath returns the file holding the env definitions snapshot of the scan root
+// (see envfile.Snapshot), one per root so scans of different repositories don't evict each other
+func (c *Cache) EnvSnapshotPath(root string) string {
+	hash := sha256.Sum256([]byte(root))
+	return filepath.Join(c.dir, "env", hex.EncodeToString(hash[:8])+".json")
+}
+
+// Clear removes every cached entry and env snapshot
+func (c *Cache) Clear() error {
+	for _, dir := range []string{"usages", "env"} {
+		if err := os.RemoveAll(filepath.Join(c.dir, dir)); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// entryPath shards entries by the first two hex characters to keep directories small
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, "usages", key[:2], key+".json")
+}
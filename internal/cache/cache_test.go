@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+func TestCache_PutGet(t *testing.T) {
+	c := New(t.TempDir())
+	key := Key("javascript", "(query)", []byte("process.env.API_KEY"))
+
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Expected miss on empty cache")
+	}
+
+	usages := []analyzer.EnvUsage{{Key: "API_KEY", File: "src/app.js", Line: 1, InIgnoredPath: true}}
+	if err := c.Put(key, usages); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	got, ok := c.Get(key)
+	if !ok {
+		t.Fatal("Expected hit after Put")
+	}
+	if len(got) != 1 || got[0].Key != "API_KEY" || got[0].Line != 1 {
+		t.Errorf("Unexpected cached usages: %+v", got)
+	}
+	if got[0].File != "" || got[0].InIgnoredPath {
+		t.Errorf("Expected per-file fields to be stripped, got %+v", got[0])
+	}
+}
+
+func TestKey(t *testing.T) {
+	base := Key("javascript", "(query)", []byte("a"))
+	if base != Key("javascript", "(query)", []byte("a")) {
+		t.Error("Expected keys to be deterministic")
+	}
+	for _, other := range []string{
+		Key("typescript", "(query)", []byte("a")),
+		Key("javascript", "(other)", []byte("a")),
+		Key("javascript", "(query)", []byte("b")),
+	} {
+		if other == base {
+			t.Error("Expected language, query and content to change the key")
+		}
+	}
+}
+
+func TestCache_CorruptEntryAndClear(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+	key := Key("go", "(query)", []byte("package main"))
+
+	path := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+	if _, ok := c.Get(key); ok {
+		t.Error("Expected corrupt entry to be a miss")
+	}
+
+	snapshotPath := c.EnvSnapshotPath("/repo")
+	if err := os.MkdirAll(filepath.Dir(snapshotPath), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(snapshotPath, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "usages")); !os.IsNotExist(err) {
+		t.Errorf("Expected usages directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		t.Errorf("Expected env snapshot to be removed, got %v", err)
+	}
+}
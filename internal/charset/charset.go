@@ -0,0 +1,153 @@
+// Package charset detects the text encoding of source and env files and transcodes them to UTF-8,
+// so files saved by Windows editors (UTF-16 with a BOM) or in Latin-1 are read like any other file
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings returned by Detect
+const (
+	UTF8    = "utf-8"
+	UTF8BOM = "utf-8 with BOM"
+	UTF16LE = "utf-16le"
+	UTF16BE = "utf-16be"
+	Latin1  = "latin-1" // ISO-8859-1, the fallback for invalid UTF-8 without control characters
+)
+
+// ErrUndecodable is returned for content that isn't text in a supported encoding (e.g., binary data)
+var ErrUndecodable = errors.New("not text in a supported encoding (UTF-8, UTF-16 or Latin-1)")
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// Detect returns the encoding of content, or "" when it isn't text in a supported encoding
+// content may be the start of a longer file: a character cut off at the end is not an error
+// UTF-16 is detected by its BOM, or without one when every other byte is NUL (ASCII text)
+func Detect(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return UTF8BOM
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return UTF16BE
+	}
+
+	if bytes.IndexByte(content, 0) >= 0 {
+		return detectUTF16(content)
+	}
+	if validUTF8(content) {
+		return UTF8
+	}
+	for _, b := range content {
+		if isControl(b) {
+			return ""
+		}
+	}
+	return Latin1
+}
+
+// validUTF8 is like utf8.Valid, but accepts a rune cut off at the end of content
+func validUTF8(content []byte) bool {
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(content)
+		}
+		content = content[size:]
+	}
+	return true
+}
+
+// detectUTF16 detects UTF-16 text without a BOM from its NUL bytes: the high bytes of ASCII characters
+func detectUTF16(content []byte) string {
+	var evenNUL, oddNUL int
+	for i, b := range content {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenNUL++
+		} else {
+			oddNUL++
+		}
+	}
+	pairs := len(content) / 2
+	switch {
+	case pairs < 2:
+		return ""
+	case evenNUL == 0 && oddNUL*4 >= pairs*3:
+		return UTF16LE
+	case oddNUL == 0 && evenNUL*4 >= pairs*3:
+		return UTF16BE
+	}
+	return ""
+}
+
+// isControl reports whether b is an ASCII control character that doesn't appear in text files
+func isControl(b byte) bool {
+	switch b {
+	case '\t', '\n', '\v', '\f', '\r', 0x1b: // 0x1b is ESC, found in ANSI color codes
+		return false
+	}
+	return b < 0x20 || b == 0x7f
+}
+
+// Decode returns content transcoded to UTF-8 without a BOM, and the encoding it was detected in
+// UTF-8 content is returned as is
+func Decode(content []byte) ([]byte, string, error) {
+	encoding := Detect(content)
+	switch encoding {
+	case UTF8:
+		return content, encoding, nil
+	case UTF8BOM:
+		return content[len(bomUTF8):], encoding, nil
+	case UTF16LE, UTF16BE:
+		return decodeUTF16(content, encoding == UTF16BE), encoding, nil
+	case Latin1:
+		decoded := make([]byte, 0, len(content)*2)
+		for _, b := range content {
+			decoded = utf8.AppendRune(decoded, rune(b))
+		}
+		return decoded, encoding, nil
+	}
+	return nil, "", ErrUndecodable
+}
+
+// decodeUTF16 transcodes UTF-16 content to UTF-8, dropping its BOM and a trailing odd byte
+// Unpaired surrogates become U+FFFD
+func decodeUTF16(content []byte, bigEndian bool) []byte {
+	units := make([]uint16, 0, len(content)/2)
+	for i := 0; i+1 < len(content); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(content[i])<<8|uint16(content[i+1]))
+		} else {
+			units = append(units, uint16(content[i+1])<<8|uint16(content[i]))
+		}
+	}
+	if len(units) > 0 && units[0] == 0xfeff {
+		units = units[1:]
+	}
+	decoded := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		decoded = utf8.AppendRune(decoded, r)
+	}
+	return decoded
+}
+
+// ReadFile reads a file with os.ReadFile and transcodes it to UTF-8 with Decode
+func ReadFile(path string) ([]byte, string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return Decode(content)
+}
@@ -0,0 +1,73 @@
+package charset
+
+import (
+	"errors"
+	"testing"
+)
+
+// utf16LE encodes ASCII text as UTF-16LE
+func utf16LE(text string) []byte {
+	var encoded []byte
+	for _, c := range []byte(text) {
+		encoded = append(encoded, c, 0)
+	}
+	return encoded
+}
+
+// utf16BE encodes ASCII text as UTF-16BE
+func utf16BE(text string) []byte {
+	var encoded []byte
+	for _, c := range []byte(text) {
+		encoded = append(encoded, 0, c)
+	}
+	return encoded
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		want     string
+		encoding string
+	}{
+		{"utf8", []byte("API_KEY=é\n"), "API_KEY=é\n", UTF8},
+		{"utf8 with BOM", append([]byte{0xef, 0xbb, 0xbf}, "API_KEY=1\n"...), "API_KEY=1\n", UTF8BOM},
+		{"utf16le with BOM", append([]byte{0xff, 0xfe}, utf16LE("API_KEY=1\r\n")...), "API_KEY=1\r\n", UTF16LE},
+		{"utf16be with BOM", append([]byte{0xfe, 0xff}, utf16BE("API_KEY=1\n")...), "API_KEY=1\n", UTF16BE},
+		{"utf16le without BOM", utf16LE("API_KEY=1\n"), "API_KEY=1\n", UTF16LE},
+		{"utf16le surrogate pair", []byte{0xff, 0xfe, 0x3d, 0xd8, 0x00, 0xde}, "😀", UTF16LE},
+		{"latin1", []byte{'#', ' ', 'c', 'a', 'f', 0xe9, '\n'}, "# café\n", Latin1},
+		{"empty", []byte{}, "", UTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, encoding, err := Decode(tt.content)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if string(decoded) != tt.want || encoding != tt.encoding {
+				t.Errorf("Decode() = %q (%s), want %q (%s)", decoded, encoding, tt.want, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestDecode_Undecodable(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"nul bytes":              {'p', 'k', 'g', 0, 1, 2},
+		"invalid utf8 with ctrl": {'a', 0xff, 0x01},
+		"mixed nul positions":    {0, 'a', 'b', 0, 0, 'c'},
+	} {
+		if _, _, err := Decode(content); !errors.Is(err, ErrUndecodable) {
+			t.Errorf("%s: expected ErrUndecodable, got %v", name, err)
+		}
+	}
+}
+
+func TestDetect_Truncated(t *testing.T) {
+	euro := []byte("€")
+	if got := Detect(append([]byte("x = "), euro[:2]...)); got != UTF8 {
+		t.Errorf("Expected a rune cut off at the end to be UTF-8, got %q", got)
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a mistake in a config file
+type Problem struct {
+	Line    int    // Line of the mistake, 0 when unknown
+	Message string // What is wrong
+}
+
+// Error renders the problem with its line, e.g. `line 3: unknown key "ignore" (did you mean "ignores"?)`
+func (p Problem) Error() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// configType is the type config files are decoded into
+var configType = reflect.TypeOf(Config{})
+
+// yamlLine finds the line number yaml.v3 puts in its error messages
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Check validates a config file (see Find) and returns every problem found: YAML syntax errors,
+// unknown keys, values of the wrong type, and invalid settings such as malformed globs or regular
+// expressions (including the ones of extended configs)
+func Check(file string) ([]Problem, error) {
+	data, offset, err := readSource(file)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return []Problem{{Message: "no envgrd section"}}, nil
+	}
+
+	problems := checkSource(file, data)
+	for i := range problems {
+		if offset < 0 {
+			problems[i].Line = 0
+		} else if problems[i].Line > 0 {
+			problems[i].Line += offset
+		}
+	}
+	return problems, nil
+}
+
+// checkSource validates the YAML of a config file
+func checkSource(file string, data []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return yamlProblems(err)
+	}
+	node, err := documentMapping(&doc)
+	if err != nil {
+		return []Problem{{Line: 1, Message: err.Error()}}
+	}
+	stripped := *node // takeExtends leaves node's keys untouched, so problems can be located in it
+	if _, err := takeExtends(&stripped); err != nil {
+		return []Problem{{Line: locate(node, "\"extends\""), Message: err.Error()}}
+	}
+
+	problems := checkKeys(&stripped, configType)
+	var decoded Config
+	problems = append(problems, yamlProblems(stripped.Decode(&decoded))...)
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+		return problems
+	}
+
+	// Settings are validated on the config merged with the configs it extends, and with each profile
+	config, err := LoadFile(file)
+	if err != nil {
+		message := strings.TrimPrefix(err.Error(), "failed to parse config file: ")
+		return []Problem{{Line: locate(node, message), Message: message}}
+	}
+	for _, name := range config.ProfileNames() {
+		if _, err := config.WithProfile(name); err != nil {
+			problems = append(problems, Problem{Line: locate(node, err.Error()), Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// documentMapping returns the top-level mapping of a parsed config, an empty mapping for an empty file
+func documentMapping(doc *yaml.Node) (*yaml.Node, error) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if node := doc.Content[0]; node.Kind == yaml.MappingNode {
+		return node, nil
+	}
+	return nil, fmt.Errorf("config must be a mapping")
+}
+
+// checkKeys reports the mapping keys under node that t has no field for, recursing into known keys
+func checkKeys(node *yaml.Node, t reflect.Type) []Problem {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var problems []Problem
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Value == "<<" {
+				continue // Merge key, the merged mapping is checked where it's defined
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				problems = append(problems, Problem{Line: key.Line, Message: unknownKey(key.Value, fields)})
+				continue
+			}
+			problems = append(problems, checkKeys(node.Content[i+1], field)...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			problems = append(problems, checkKeys(node.Content[i], t.Elem())...)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			problems = append(problems, checkKeys(item, t.Elem())...)
+		}
+	}
+	return problems
+}
+
+// yamlFields returns the types of a struct's fields by YAML key, including inlined structs
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if options == "inline" {
+			for key, inner := range yamlFields(field.Type) {
+				fields[key] = inner
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// unknownKey describes an unknown key, suggesting the closest known one when it looks like a typo
+func unknownKey(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for known := range fields {
+		if d := editDistance(strings.ToLower(key), known); d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown key %q (did you mean %q?)", key, best)
+	}
+	return fmt.Sprintf("unknown key %q", key)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// yamlProblems converts a YAML syntax or type error into problems with line numbers
+func yamlProblems(err error) []Problem {
+	if err == nil {
+		return nil
+	}
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	problems := make([]Problem, 0, len(messages))
+	for _, message := range messages {
+		problem := Problem{Message: message}
+		if m := yamlLine.FindStringSubmatch(message); m != nil {
+			problem.Line, _ = strconv.Atoi(m[1])
+			problem.Message = m[2]
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// locate finds the line of the first quoted value of message (e.g., an invalid glob) in the config, or 0
+func locate(node *yaml.Node, message string) int {
+	for i := strings.IndexByte(message, '"'); i >= 0; {
+		quoted, err := strconv.QuotedPrefix(message[i:])
+		if err == nil {
+			value, _ := strconv.Unquote(quoted)
+			if line := findScalar(node, value); line > 0 {
+				return line
+			}
+			i += len(quoted)
+		} else {
+			i++
+		}
+		next := strings.IndexByte(message[i:], '"')
+		if next < 0 {
+			break
+		}
+		i += next
+	}
+	return 0
+}
+
+// findScalar returns the line of the first key or value equal to value under node, or 0
+func findScalar(node *yaml.Node, value string) int {
+	if node.Kind == yaml.ScalarNode && node.Value == value {
+		return node.Line
+	}
+	for _, child := range node.Content {
+		if line := findScalar(child, value); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
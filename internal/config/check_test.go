@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // "line: message prefix"
+	}{
+		{"valid", "extends: node\nignores:\n  missing: [A, \"B_*\"]\nskip_unused: true\n", nil},
+		{"empty", "", nil},
+		{"misspelled section", "ignore:\n  missing: [A]\n", []string{`1: unknown key "ignore" (did you mean "ignores"?)`}},
+		{"nested keys", "ignores:\n  folders: [x]\n  mising: [B]\nnaming:\n  max_lenght: 3\n", []string{
+			`3: unknown key "mising" (did you mean "missing"?)`,
+			`5: unknown key "max_lenght" (did you mean "max_length"?)`,
+		}},
+		{"no suggestion", "colour: red\n", []string{`1: unknown key "colour"`}},
+		{"wrong type", "concurrency: lots\nrequired: A\n", []string{
+			"1: cannot unmarshal !!str `lots` into int",
+			"2: cannot unmarshal !!str `A` into []string",
+		}},
+		{"invalid regex", "ignores:\n  unused:\n    - A\n    - \"/[a/\"\n", []string{`4: invalid ignores config: unused: invalid regular expression "/[a/"`}},
+		{"syntax error", "ignores:\n  missing: [A\n", []string{"1: did not find expected ',' or ']'"}},
+		{"unknown preset", "required: [A]\nextends: rails\n", []string{`2: extends "rails": unknown preset`}},
+		{"not a mapping", "- a\n- b\n", []string{"1: config must be a mapping"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, FileName), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			problems, err := Check(filepath.Join(tmpDir, FileName))
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.want), problems)
+			}
+			for i, want := range tt.want {
+				line, message, _ := strings.Cut(want, ": ")
+				got := problems[i]
+				if !strings.HasPrefix(got.Message, message) || !strings.HasPrefix(got.Error(), "line "+line+":") {
+					t.Errorf("Problem %d = %q, want %q", i, got.Error(), want)
+				}
+			}
+		})
+	}
+
+	if _, err := Check(filepath.Join(t.TempDir(), FileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error without a config file, got %v", err)
+	}
+}
+
+func TestLoadConfig_UnknownKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, FileName), []byte("ignores:\n  folder: [x]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	_, err := LoadConfig(tmpDir)
+	if err == nil || !strings.Contains(err.Error(), `line 2: unknown key "folder" (did you mean "folders"?)`) {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+}
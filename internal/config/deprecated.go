@@ -0,0 +1,35 @@
+package config
+
+import "sort"
+
+// DeprecationHint returns the migration hint of a deprecated variable
+// Exact names win over globs and regular expressions, which are tried in order
+func (c *Config) DeprecationHint(varName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	return lookupName(c.Deprecated, varName)
+}
+
+// lookupName returns the value of the entry whose name pattern matches varName
+// Exact names win over globs and regular expressions, which are tried in order
+func lookupName(entries map[string]string, varName string) (string, bool) {
+	if len(entries) == 0 {
+		return "", false
+	}
+	if value, ok := entries[varName]; ok {
+		return value, true
+	}
+
+	patterns := make([]string, 0, len(entries))
+	for pattern := range entries {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if MatchesName(pattern, varName) {
+			return entries[pattern], true
+		}
+	}
+	return "", false
+}
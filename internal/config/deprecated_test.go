@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestDeprecationHint(t *testing.T) {
+	cfg := &Config{Deprecated: map[string]string{
+		"OLD_DB_URL":  "use DATABASE_URL",
+		"LEGACY_*":    "remove",
+		"LEGACY_MODE": "use APP_MODE",
+	}}
+
+	tests := []struct {
+		key      string
+		wantHint string
+		wantOK   bool
+	}{
+		{"OLD_DB_URL", "use DATABASE_URL", true},
+		{"LEGACY_MODE", "use APP_MODE", true},
+		{"LEGACY_FLAG", "remove", true},
+		{"DATABASE_URL", "", false},
+	}
+	for _, tt := range tests {
+		hint, ok := cfg.DeprecationHint(tt.key)
+		if hint != tt.wantHint || ok != tt.wantOK {
+			t.Errorf("DeprecationHint(%q) = %q, %v, want %q, %v", tt.key, hint, ok, tt.wantHint, tt.wantOK)
+		}
+	}
+}
+
+func TestRemediationHint(t *testing.T) {
+	cfg := &Config{Remediation: map[string]string{
+		"DATABASE_URL": "Request access via #infra",
+		"/^STRIPE_/":   "Ask #payments for a test key",
+	}}
+	if hint, ok := cfg.RemediationHint("STRIPE_SECRET"); !ok || hint != "Ask #payments for a test key" {
+		t.Errorf("Expected the STRIPE_ hint, got %q, %v", hint, ok)
+	}
+	if _, ok := cfg.RemediationHint("PORT"); ok {
+		t.Error("Expected no hint for PORT")
+	}
+	if _, ok := (*Config)(nil).RemediationHint("DATABASE_URL"); ok {
+		t.Error("Expected no hint without a config")
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddIgnore adds key to a list under ignores (missing or unused) of a YAML config file, creating
+// the file if it doesn't exist. Comments and the other settings are kept; a key that is already
+// listed is left alone. The envgrd sections of package.json and pyproject.toml are not edited
+func AddIgnore(file string, list string, key string) error {
+	if list != "missing" && list != "unused" {
+		return fmt.Errorf("unknown ignore list %q (supported: missing, unused)", list)
+	}
+	if filepath.Base(file) == packageJSON || filepath.Ext(file) == ".toml" {
+		return fmt.Errorf("%s can't be edited automatically, add %s to ignores.%s by hand", filepath.Base(file), key, list)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root, err := documentMapping(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	ignores := mappingValue(root, "ignores", yaml.MappingNode)
+	values := mappingValue(ignores, list, yaml.SequenceNode)
+	if ignores == nil || values == nil {
+		return fmt.Errorf("failed to edit config file: ignores.%s is not a list", list)
+	}
+	for _, value := range values.Content {
+		if value.Value == key {
+			return nil
+		}
+	}
+	values.Content = append(values.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key})
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.WriteFile(file, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in mapping, adding it as an empty node of kind if it's
+// missing or null. Returns nil if the value has another kind
+func mappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	if mapping == nil {
+		return nil
+	}
+	tag := "!!map"
+	if kind == yaml.SequenceNode {
+		tag = "!!seq"
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		value := mapping.Content[i+1]
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			value.Kind, value.Tag, value.Value, value.Style = kind, tag, "", 0
+		}
+		if value.Kind != kind {
+			return nil
+		}
+		return value
+	}
+	value := &yaml.Node{Kind: kind, Tag: tag}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
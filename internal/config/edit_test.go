@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddIgnore(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, FileName)
+	writeConfigFile(t, file, `# Project config
+ignores:
+  missing:
+    # - EXAMPLE
+  unused: [OLD]
+required: [A]
+`)
+	for _, add := range [][2]string{{"missing", "CI_TOKEN"}, {"unused", "LEGACY"}, {"unused", "OLD"}} {
+		if err := AddIgnore(file, add[0], add[1]); err != nil {
+			t.Fatalf("AddIgnore failed: %v", err)
+		}
+	}
+
+	cfg, err := LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Ignores.Missing, []string{"CI_TOKEN"}) || !reflect.DeepEqual(cfg.Ignores.Unused, []string{"OLD", "LEGACY"}) {
+		t.Errorf("Unexpected ignores: %+v", cfg.Ignores)
+	}
+	if !reflect.DeepEqual(cfg.Required, []string{"A"}) {
+		t.Errorf("Expected the other settings to be kept, got %v", cfg.Required)
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), "# Project config") {
+		t.Errorf("Expected comments to be kept:\n%s", data)
+	}
+
+	created := filepath.Join(tmpDir, "new", FileName)
+	os.Mkdir(filepath.Dir(created), 0755)
+	if err := AddIgnore(created, "missing", "A"); err != nil {
+		t.Fatalf("AddIgnore failed: %v", err)
+	}
+	if cfg, err := LoadFile(created); err != nil || !cfg.ShouldIgnoreMissing("A") {
+		t.Errorf("Expected a new config ignoring A, got %v, %v", cfg, err)
+	}
+
+	if err := AddIgnore(filepath.Join(tmpDir, "package.json"), "missing", "A"); err == nil || !strings.Contains(err.Error(), "by hand") {
+		t.Errorf("Expected package.json to be refused, got %v", err)
+	}
+	if err := AddIgnore(file, "folders", "A"); err == nil {
+		t.Error("Expected an error for an unknown list")
+	}
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Presets are the built-in configs a config can extend by name
+var Presets = map[string]string{
+	// Node.js services and frontends: build output, and variables set by npm and Node itself
+	"node": `
+ignores:
+  folders: [dist, build, coverage, .next, .nuxt, .turbo]
+system_vars:
+  extra: ["npm_*", "NODE_OPTIONS", "NODE_EXTRA_CA_CERTS", "NODE_DEBUG", "INIT_CWD"]
+tests:
+  patterns: ["*.e2e.*", "__tests__/", "__mocks__/", "cypress/", "playwright/"]
+`,
+	// Go services: vendored code and test data, Go toolchain variables, UPPER_SNAKE_CASE names
+	"go-service": `
+ignores:
+  folders: [vendor, testdata]
+system_vars:
+  extra: ["GO*", "CGO_*"]
+naming:
+  upper_snake_case: true
+`,
+	// Workloads on Kubernetes: variables injected by the kubelet and the downward API
+	"k8s": `
+ignores:
+  missing: ["/_SERVICE_(HOST|PORT)(_[A-Z0-9_]+)?$/"]
+  folders: [charts, helm]
+system_vars:
+  extra: ["KUBERNETES_*", "POD_NAME", "POD_NAMESPACE", "POD_IP", "NODE_NAME", "SERVICE_ACCOUNT"]
+`,
+}
+
+// maxExtendsDepth limits how deeply configs can extend each other
+const maxExtendsDepth = 10
+
+// fetchTimeout bounds the download of a config extended by URL
+const fetchTimeout = 10 * time.Second
+
+// maxFetchSize bounds the size of a config or policy fetched from a URL
+const maxFetchSize = 1 << 20
+
+// fetchClient downloads configs and policies from URLs
+var fetchClient = http.DefaultClient
+
+// pinPrefix separates an extended URL from the sha256 checksum pinning it (e.g., https://host/envgrd.yml#sha256=<hex>)
+const pinPrefix = "#sha256="
+
+// PresetNames returns the names of the built-in presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveExtends parses a config and merges it over the configs listed in its extends key, in order
+// source names the config in errors and cycle detection, dir resolves relative file references
+// Returns the merged mapping node (without extends) and the config's own extends list
+func resolveExtends(data []byte, source string, dir string, chain []string) (*yaml.Node, []string, error) {
+	for _, seen := range chain {
+		if seen == source {
+			return nil, nil, fmt.Errorf("extends cycle: %s -> %s", strings.Join(chain, " -> "), source)
+		}
+	}
+	if len(chain) >= maxExtendsDepth {
+		return nil, nil, fmt.Errorf("extends is nested more than %d levels deep", maxExtendsDepth)
+	}
+	chain = append(chain, source)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	node, err := documentMapping(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extends, err := takeExtends(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Misspelled keys would otherwise be silently ignored
+	if problems := checkKeys(node, configType); len(problems) > 0 {
+		return nil, nil, problems[0]
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, ref := range extends {
+		baseData, baseSource, baseDir, err := readExtended(ref, dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("extends %q: %w", ref, err)
+		}
+		base, _, err := resolveExtends(baseData, baseSource, baseDir, chain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("extends %q: %w", ref, err)
+		}
+		merged = mergeNodes("", merged, base)
+	}
+	return mergeNodes("", merged, node), extends, nil
+}
+
+// takeExtends removes the extends key from a config mapping and returns its references
+// extends takes a single reference or a list
+func takeExtends(node *yaml.Node) ([]string, error) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "extends" {
+			continue
+		}
+		value := node.Content[i+1]
+		node.Content = append(node.Content[:i:i], node.Content[i+2:]...)
+
+		var refs []string
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Value != "" {
+				refs = []string{value.Value}
+			}
+		case yaml.SequenceNode:
+			if err := value.Decode(&refs); err != nil {
+				return nil, fmt.Errorf("invalid extends: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid extends: expected a preset, file or URL, or a list of them")
+		}
+		return refs, nil
+	}
+	return nil, nil
+}
+
+// readExtended loads an extends reference: a preset name, an https URL pinned by its sha256 checksum or a
+// file path relative to dir
+// Returns the config, its source name and the directory its own relative references resolve against
+func readExtended(ref string, dir string) ([]byte, string, string, error) {
+	if preset, ok := Presets[ref]; ok {
+		return []byte(preset), "preset:" + ref, dir, nil
+	}
+	// Remote configs can change what a scan reports, so they must come unaltered from where the config says
+	if strings.HasPrefix(ref, "http://") {
+		return nil, "", "", fmt.Errorf("only https URLs are supported")
+	}
+	if strings.HasPrefix(ref, "https://") {
+		url, checksum, ok := strings.Cut(ref, pinPrefix)
+		if !ok || checksum == "" {
+			return nil, "", "", fmt.Errorf("a config fetched from a URL needs its sha256 checksum (%s%s<hex>)", url, pinPrefix)
+		}
+		data, err := fetchConfig(url)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if _, err := verifyChecksum(data, checksum); err != nil {
+			return nil, "", "", err
+		}
+		return data, ref, dir, nil
+	}
+
+	file := ref
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) && !strings.ContainsAny(ref, `/\.`) {
+		return nil, "", "", fmt.Errorf("unknown preset (supported: %s) and no such file", strings.Join(PresetNames(), ", "))
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return data, file, filepath.Dir(file), nil
+}
+
+// fetchConfig downloads a shared config
+func fetchConfig(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err != nil {
+		return nil, err
+	}
+	// A truncated file could still parse, with part of its settings missing
+	if len(data) > maxFetchSize {
+		return nil, fmt.Errorf("larger than %d bytes", maxFetchSize)
+	}
+	return data, nil
+}
+
+// verifyChecksum checks the SHA-256 of data against checksum (hex, optionally prefixed with sha256:)
+// unless checksum is empty, and returns the actual one
+func verifyChecksum(data []byte, checksum string) (string, error) {
+	checksum = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if checksum != "" && checksum != actual {
+		return actual, fmt.Errorf("checksum mismatch (expected sha256 %s, got %s)", checksum, actual)
+	}
+	return actual, nil
+}
+
+// replacedLists are keys whose lists replace the extended config's list instead of adding to it
+var replacedLists = map[string]bool{
+	"precedence": true,
+	"fail_on":    true,
+}
+
+// mergeNodes merges the config node over onto base: mappings are merged key by key,
+// lists are concatenated (except replacedLists) and any other value replaces the base's
+func mergeNodes(key string, base *yaml.Node, over *yaml.Node) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && over.Kind == yaml.MappingNode:
+		merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		merged.Content = append(merged.Content, base.Content...)
+		for i := 0; i+1 < len(over.Content); i += 2 {
+			name, value := over.Content[i], over.Content[i+1]
+			found := false
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				if merged.Content[j].Value == name.Value {
+					merged.Content[j+1] = mergeNodes(name.Value, merged.Content[j+1], value)
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged.Content = append(merged.Content, name, value)
+			}
+		}
+		return merged
+	case base.Kind == yaml.SequenceNode && over.Kind == yaml.SequenceNode && !replacedLists[key]:
+		merged := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		merged.Content = append(append(merged.Content, base.Content...), over.Content...)
+		return merged
+	case over.Kind == yaml.ScalarNode && over.Tag == "!!null":
+		return base // An empty section keeps the extended config's
+	default:
+		return over
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestLoadConfig_ExtendsPreset(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(tmpDir, FileName), "extends: go-service\nignores:\n  folders: [tools]\n")
+
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := strings.Join(cfg.Ignores.Folders, ","); got != "vendor,testdata,tools" {
+		t.Errorf("Expected preset folders followed by local ones, got %s", got)
+	}
+	if !cfg.Naming.UpperSnakeCase {
+		t.Error("Expected naming.upper_snake_case from the go-service preset")
+	}
+	if strings.Join(cfg.Extends, ",") != "go-service" {
+		t.Errorf("Unexpected extends: %v", cfg.Extends)
+	}
+
+	for _, name := range PresetNames() {
+		writeConfigFile(t, filepath.Join(tmpDir, FileName), "extends: "+name+"\n")
+		if _, err := LoadConfig(tmpDir); err != nil {
+			t.Errorf("Preset %s is invalid: %v", name, err)
+		}
+	}
+}
+
+func TestLoadConfig_ExtendsFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(tmpDir, "shared", "base.yml"), `
+extends: ./common.yml
+required: [DATABASE_URL]
+precedence: [env, exported]
+naming:
+  prefix: ORG_
+  max_length: 40
+`)
+	writeConfigFile(t, filepath.Join(tmpDir, "shared", "common.yml"), "ignores:\n  missing: [CI]\n")
+	writeConfigFile(t, filepath.Join(tmpDir, FileName), `
+extends:
+  - shared/base.yml
+required: [API_KEY]
+precedence: [exported, env]
+naming:
+  prefix: APP_
+`)
+
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := strings.Join(cfg.Required, ","); got != "DATABASE_URL,API_KEY" {
+		t.Errorf("Expected lists to be concatenated, got %s", got)
+	}
+	if got := strings.Join(cfg.Precedence, ","); got != "exported,env" {
+		t.Errorf("Expected precedence to be replaced, got %s", got)
+	}
+	if cfg.Naming.Prefix != "APP_" || cfg.Naming.MaxLength != 40 {
+		t.Errorf("Expected local values over extended ones, got %+v", cfg.Naming)
+	}
+	if !cfg.ShouldIgnoreMissing("CI") {
+		t.Error("Expected ignores of a nested extends relative to the extended file")
+	}
+}
+
+func TestLoadConfig_ExtendsURL(t *testing.T) {
+	shared := "ignores:\n  unused: [LEGACY_*]\n"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/envgrd.yml":
+			w.Write([]byte(shared))
+		case "/large.yml":
+			w.Write([]byte("ignores:\n  unused: [LEGACY_*]\n" + strings.Repeat("#", maxFetchSize)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	fetchClient = server.Client()
+	defer func() { fetchClient = http.DefaultClient }()
+
+	sum := sha256.Sum256([]byte(shared))
+	pin := pinPrefix + hex.EncodeToString(sum[:])
+	tmpDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(tmpDir, FileName), "extends: "+server.URL+"/envgrd.yml"+pin+"\n")
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.ShouldIgnoreUnused("LEGACY_TOKEN") {
+		t.Error("Expected ignores from the shared config")
+	}
+
+	for ref, wantErr := range map[string]string{
+		server.URL + "/missing.yml" + pin:                 "404",
+		server.URL + "/large.yml" + pin:                   "larger than",
+		server.URL + "/envgrd.yml" + pinPrefix + "abc123": "checksum mismatch",
+		server.URL + "/envgrd.yml":                        "needs its sha256 checksum",
+		"http://config.example.com/envgrd.yml" + pin:      "only https",
+	} {
+		writeConfigFile(t, filepath.Join(tmpDir, FileName), "extends: "+ref+"\n")
+		if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected %q error for %s, got %v", wantErr, ref, err)
+		}
+	}
+}
+
+func TestLoadConfig_ExtendsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(tmpDir, "a.yml"), "extends: b.yml\n")
+	writeConfigFile(t, filepath.Join(tmpDir, "b.yml"), "extends: a.yml\n")
+
+	for content, wantErr := range map[string]string{
+		"extends: a.yml\n":          "extends cycle",
+		"extends: rails\n":          "unknown preset",
+		"extends: missing.yml\n":    "no such file",
+		"extends: {preset: node}\n": "invalid extends",
+	} {
+		writeConfigFile(t, filepath.Join(tmpDir, FileName), content)
+		if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected %q error for %q, got %v", wantErr, content, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the default name of the config file, at the scan root or in any subdirectory
+const FileName = ".envgrd.config"
+
+// FileNames are the config file names searched in a directory, in order
+var FileNames = []string{FileName, ".envgrd.yaml", ".envgrd.yml", "envgrd.config.yaml"}
+
+// Manifests whose envgrd section is used when a directory has none of FileNames
+const (
+	packageJSON = "package.json"   // "envgrd" key
+	pyproject   = "pyproject.toml" // [tool.envgrd] table
+)
+
+// Find returns the path of the config in dir: the first of FileNames, else package.json
+// or pyproject.toml when they have an envgrd section; empty if there is none
+func Find(dir string) (string, error) {
+	for _, name := range FileNames {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	for _, name := range []string{packageJSON, pyproject} {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		data, _, err := readSource(file)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		if data != nil {
+			return file, nil
+		}
+	}
+	return "", nil
+}
+
+// readSource reads a config file as YAML, extracting the envgrd section of package.json and pyproject.toml
+// Returns nil data when a manifest has no envgrd section, and the number of lines before the section
+// (-1 when lines of the returned data don't map back to the file)
+func readSource(file string) ([]byte, int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case filepath.Base(file) == packageJSON:
+		var manifest map[string]json.RawMessage
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+		section, ok := manifest["envgrd"]
+		if !ok {
+			return nil, 0, nil
+		}
+		offset := -1
+		if i := bytes.Index(data, section); i >= 0 {
+			offset = bytes.Count(data[:i], []byte("\n"))
+		}
+		return section, offset, nil // JSON is valid YAML
+	case filepath.Ext(file) == ".toml":
+		table, err := tomlTable(data, "tool.envgrd")
+		if err != nil || table == nil {
+			return nil, 0, err
+		}
+		section, err := yaml.Marshal(table)
+		return section, -1, err
+	default:
+		return data, 0, nil
+	}
+}
+
+// LoadFile loads a config file: YAML, or the envgrd section of a package.json or pyproject.toml
+// References to other files (extends, env_files) are relative to the file's directory
+func LoadFile(file string) (*Config, error) {
+	data, _, err := readSource(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("failed to read config file: %s has no envgrd section", file)
+	}
+
+	node, extends, err := resolveExtends(data, file, filepath.Dir(file), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	var config Config
+	if err := node.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	config.Extends = extends
+	config.File = file
+	config.node = node
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
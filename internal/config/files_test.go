@@ -0,0 +1,139 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"none", nil, ""},
+		{"default name", map[string]string{".envgrd.config": "", ".envgrd.yaml": ""}, ".envgrd.config"},
+		{"yaml", map[string]string{".envgrd.yml": "", "envgrd.config.yaml": ""}, ".envgrd.yml"},
+		{"package.json section", map[string]string{"package.json": `{"name": "app", "envgrd": {"required": ["A"]}}`}, "package.json"},
+		{"package.json without section", map[string]string{"package.json": `{"name": "app"}`}, ""},
+		{"pyproject section", map[string]string{"pyproject.toml": "[tool.envgrd]\nrequired = [\"A\"]\n"}, "pyproject.toml"},
+		{"pyproject without section", map[string]string{"pyproject.toml": "[tool.black]\nline-length = 100\n"}, ""},
+		{"config file over manifest", map[string]string{"package.json": `{"envgrd": {}}`, "envgrd.config.yaml": ""}, "envgrd.config.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, content := range tt.files {
+				writeConfigFile(t, filepath.Join(tmpDir, name), content)
+			}
+			got, err := Find(tmpDir)
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(tmpDir, tt.want)
+			}
+			if got != want {
+				t.Errorf("Find() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Manifests(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(tmpDir, "package.json"), `{
+  "name": "app",
+  "envgrd": {
+    "ignores": {"missing": ["CI_*"]},
+    "skip_unused": true
+  }
+}`)
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.ShouldIgnoreMissing("CI_TOKEN") || cfg.SkipUnused == nil || !*cfg.SkipUnused {
+		t.Errorf("Expected the envgrd section of package.json, got %+v", cfg)
+	}
+
+	writeConfigFile(t, filepath.Join(tmpDir, "package.json"), `{"envgrd": {"ignors": {}}}`)
+	if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), `unknown key "ignors"`) {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+
+	file := filepath.Join(tmpDir, "pyproject.toml")
+	writeConfigFile(t, file, `[project]
+name = "app"
+description = """
+A [multi-line] description
+"""
+dependencies = [
+  "requests>=2",  # HTTP
+  'pyyaml',
+]
+
+[tool.envgrd]
+required = ["DATABASE_URL"]
+concurrency = 4
+
+[tool.envgrd.ignores]
+missing = ["AWS_*", "/^KUBERNETES_/"]
+paths = { tools = ["DEBUG_TOKEN"] }
+`)
+	cfg, err = LoadFile(file)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Required, []string{"DATABASE_URL"}) || cfg.Concurrency == nil || *cfg.Concurrency != 4 {
+		t.Errorf("Unexpected [tool.envgrd] values: %+v", cfg)
+	}
+	if !cfg.ShouldIgnoreMissing("KUBERNETES_PORT") || !reflect.DeepEqual(cfg.Ignores.Paths["tools"], []string{"DEBUG_TOKEN"}) {
+		t.Errorf("Unexpected [tool.envgrd.ignores] values: %+v", cfg.Ignores)
+	}
+	if cfg.File != file {
+		t.Errorf("Expected File %q, got %q", file, cfg.File)
+	}
+}
+
+func TestTomlTable(t *testing.T) {
+	doc := `title = "x" # comment
+[tool.envgrd]
+a = 'literal \n'
+b = "basic \"quoted\""
+c = [1, 2_000, 3.5, true]
+d.e = "dotted"
+"quoted key" = { f = [], g = false }
+
+[[tool.envgrd.list]]
+h = 1
+[[tool.envgrd.list]]
+h = 2
+`
+	got, err := tomlTable([]byte(doc), "tool.envgrd")
+	if err != nil {
+		t.Fatalf("tomlTable failed: %v", err)
+	}
+	want := map[string]any{
+		"a":          `literal \n`,
+		"b":          `basic "quoted"`,
+		"c":          []any{int64(1), int64(2000), 3.5, true},
+		"d":          map[string]any{"e": "dotted"},
+		"quoted key": map[string]any{"f": []any(nil), "g": false},
+		"list":       []any{map[string]any{"h": int64(1)}, map[string]any{"h": int64(2)}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tomlTable() = %#v, want %#v", got, want)
+	}
+
+	if table, err := tomlTable([]byte(doc), "tool.ruff"); err != nil || table != nil {
+		t.Errorf("Expected no table, got %v, %v", table, err)
+	}
+	if _, err := tomlTable([]byte("[tool.envgrd]\na = [1, 2\nb = 3\n"), "tool.envgrd"); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected a syntax error on line 3, got %v", err)
+	}
+}
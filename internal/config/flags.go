@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScanSettings are the defaults of the scan command's flags, named like the flags with underscores
+// Flags given on the command line and ENVGRD_* environment variables take precedence over them
+type ScanSettings struct {
+	Include         StringList `yaml:"include"`          // --include
+	Exclude         StringList `yaml:"exclude"`          // --exclude
+	Format          string     `yaml:"format"`           // --format: text, json or badge; exec:<command> is only taken from the command line
+	FailOn          StringList `yaml:"fail_on"`          // --fail-on
+	SkipUnused      *bool      `yaml:"skip_unused"`      // --skip-unused
+	NoDynamic       *bool      `yaml:"no_dynamic"`       // --no-dynamic
+	MinConfidence   string     `yaml:"min_confidence"`   // --min-confidence
+	Owner           string     `yaml:"owner"`            // --owner
+	GroupBy         string     `yaml:"group_by"`         // --group-by
+	MaxLocations    *int       `yaml:"max_locations"`    // --max-locations
+	ShowAll         *bool      `yaml:"show_all"`         // --show-all
+	ShowValues      string     `yaml:"show_values"`      // --show-values: never, redacted or full
+	Wide            *bool      `yaml:"wide"`             // --wide
+	Blame           *bool      `yaml:"blame"`            // --blame
+	Silent          *bool      `yaml:"silent"`           // --silent
+	NotifyWebhook   string     `yaml:"notify_webhook"`   // --notify-webhook, rejected: only taken from the command line
+	NotifyFormat    string     `yaml:"notify_format"`    // --notify-format: json or slack
+	NotifyOn        string     `yaml:"notify_on"`        // --notify-on: error, warning or info
+	NoHeader        *bool      `yaml:"no_header"`        // --no-header
+	Quiet           *bool      `yaml:"quiet"`            // --quiet
+	NoColor         *bool      `yaml:"no_color"`         // --no-color
+	Concurrency     *int       `yaml:"concurrency"`      // --concurrency
+	FollowSymlinks  *bool      `yaml:"follow_symlinks"`  // --follow-symlinks
+	MaxDepth        *int       `yaml:"max_depth"`        // --max-depth
+	MaxFileSize     string     `yaml:"max_file_size"`    // --max-file-size
+	CacheDir        string     `yaml:"cache_dir"`        // --cache-dir, rejected: only taken from the command line
+	NoCache         *bool      `yaml:"no_cache"`         // --no-cache
+	Stats           *bool      `yaml:"stats"`            // --stats
+	SinceLastRun    *bool      `yaml:"since_last_run"`   // --since-last-run
+	StateFile       string     `yaml:"state_file"`       // --state-file, rejected: only taken from the command line
+	StrictParse     *bool      `yaml:"strict_parse"`     // --strict-parse
+	Timeout         string     `yaml:"timeout"`          // --timeout (e.g., 30s, 5m)
+	CaseInsensitive *bool      `yaml:"case_insensitive"` // --case-insensitive
+	PathSeparator   string     `yaml:"path_separator"`   // --path-separator (native, slash or backslash)
+	ParseTimeout    string     `yaml:"parse_timeout"`    // --parse-timeout (e.g., 10s, 0 disables it)
+	Languages       StringList `yaml:"languages"`        // --lang (e.g., [go, typescript])
+}
+
+// StringList is a list setting that also takes a single value (e.g., fail_on: missing)
+type StringList []string
+
+// UnmarshalYAML decodes a list or a single value
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = nil
+		if node.Tag != "!!null" {
+			*l = StringList{node.Value}
+		}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// Webhook payload formats (--notify-format)
+const (
+	NotifyJSON  = "json"  // Summary of the findings as JSON
+	NotifySlack = "slack" // Slack incoming-webhook message
+)
+
+// ValidateNotifyFormat checks a --notify-format value
+func ValidateNotifyFormat(format string) error {
+	switch format {
+	case "", NotifyJSON, NotifySlack:
+		return nil
+	default:
+		return fmt.Errorf("unknown notify_format %q (supported: json, slack)", format)
+	}
+}
+
+// validate checks the settings that can be checked without the command line
+func (s ScanSettings) validate() error {
+	// A config comes with the checkout it scans, which may be untrusted (e.g., a pull request in CI):
+	// it must not run commands, send findings elsewhere or write files outside of it
+	if strings.HasPrefix(s.Format, "exec:") {
+		return fmt.Errorf("format %q runs a command, which only --format or ENVGRD_FORMAT can set", s.Format)
+	}
+	if s.NotifyWebhook != "" {
+		return fmt.Errorf("notify_webhook can only be set with --notify-webhook or ENVGRD_NOTIFY_WEBHOOK")
+	}
+	if s.CacheDir != "" {
+		return fmt.Errorf("cache_dir can only be set with --cache-dir or ENVGRD_CACHE_DIR")
+	}
+	if s.StateFile != "" {
+		return fmt.Errorf("state_file can only be set with --state-file or ENVGRD_STATE_FILE")
+	}
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", s.Timeout, err)
+		}
+	}
+	if err := ValidateShowValues(s.ShowValues); err != nil {
+		return err
+	}
+	if err := ValidateNotifyFormat(s.NotifyFormat); err != nil {
+		return err
+	}
+	if s.NotifyOn != "" {
+		if _, err := ParseSeverity(s.NotifyOn); err != nil {
+			return fmt.Errorf("invalid notify_on: %w", err)
+		}
+	}
+	if s.Concurrency != nil && *s.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative, got %d", *s.Concurrency)
+	}
+	if s.MaxLocations != nil && *s.MaxLocations < 0 {
+		return fmt.Errorf("max_locations must not be negative, got %d", *s.MaxLocations)
+	}
+	if s.MaxDepth != nil && *s.MaxDepth < 0 {
+		return fmt.Errorf("max_depth must not be negative, got %d", *s.MaxDepth)
+	}
+	if err := validateGlobs(s.Include); err != nil {
+		return fmt.Errorf("invalid include: %w", err)
+	}
+	if err := validateGlobs(s.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude: %w", err)
+	}
+	return nil
+}
+
+// Flags returns the configured settings as flag values by flag name (e.g., "skip-unused": "true")
+// Lists are joined as CSV, the format flags parse them from
+func (s ScanSettings) Flags() map[string]string {
+	flags := make(map[string]string)
+	setList := func(name string, values []string) {
+		if len(values) > 0 {
+			flags[name] = joinCSV(values)
+		}
+	}
+	setString := func(name string, value string) {
+		if value != "" {
+			flags[name] = value
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			flags[name] = strconv.FormatBool(*value)
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			flags[name] = strconv.Itoa(*value)
+		}
+	}
+
+	setList("include", s.Include)
+	setList("exclude", s.Exclude)
+	setList("lang", s.Languages)
+	setString("format", s.Format)
+	setList("fail-on", s.FailOn)
+	setBool("skip-unused", s.SkipUnused)
+	setBool("no-dynamic", s.NoDynamic)
+	setString("min-confidence", s.MinConfidence)
+	setString("owner", s.Owner)
+	setString("group-by", s.GroupBy)
+	setInt("max-locations", s.MaxLocations)
+	setBool("show-all", s.ShowAll)
+	setString("show-values", s.ShowValues)
+	setBool("wide", s.Wide)
+	setBool("blame", s.Blame)
+	setBool("silent", s.Silent)
+	setString("notify-format", s.NotifyFormat)
+	setString("notify-on", s.NotifyOn)
+	setBool("no-header", s.NoHeader)
+	setBool("quiet", s.Quiet)
+	setBool("no-color", s.NoColor)
+	setInt("concurrency", s.Concurrency)
+	setBool("follow-symlinks", s.FollowSymlinks)
+	setInt("max-depth", s.MaxDepth)
+	setString("max-file-size", s.MaxFileSize)
+	setBool("no-cache", s.NoCache)
+	setBool("stats", s.Stats)
+	setBool("since-last-run", s.SinceLastRun)
+	setBool("case-insensitive", s.CaseInsensitive)
+	setString("path-separator", s.PathSeparator)
+	setBool("strict-parse", s.StrictParse)
+	setString("timeout", s.Timeout)
+	setString("parse-timeout", s.ParseTimeout)
+	return flags
+}
+
+// joinCSV joins values as one CSV record, quoting values with commas (e.g., "src/{a,b}/**")
+func joinCSV(values []string) string {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(values)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
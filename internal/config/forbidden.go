@@ -0,0 +1,67 @@
+package config
+
+import "gopkg.in/yaml.v3"
+
+// ForbiddenConfig maps the variables that must never be used or defined (names, globs or /regexes/) to
+// the remediation message shown with them
+// A plain list (forbidden: [AWS_SECRET_ACCESS_KEY]) forbids variables without a message
+type ForbiddenConfig map[string]string
+
+// UnmarshalYAML decodes a mapping of variables to messages, or a list of variables
+func (f *ForbiddenConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var keys []string
+		if err := node.Decode(&keys); err != nil {
+			return err
+		}
+		*f = make(ForbiddenConfig, len(keys))
+		for _, key := range keys {
+			(*f)[key] = ""
+		}
+		return nil
+	}
+	var messages map[string]string
+	if err := node.Decode(&messages); err != nil {
+		return err
+	}
+	*f = messages
+	return nil
+}
+
+// Message returns the remediation message of a forbidden variable
+// Exact names win over globs and regular expressions, which are tried in order
+func (f ForbiddenConfig) Message(varName string) (string, bool) {
+	return lookupName(f, varName)
+}
+
+// validate checks the variable patterns
+func (f ForbiddenConfig) validate() error {
+	for pattern := range f {
+		if err := ValidateNamePatterns([]string{pattern}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForbiddenMessage returns the remediation message of a variable forbidden by the config or its policy
+// (see WithPolicy); the policy's message wins when both forbid it
+func (c *Config) ForbiddenMessage(varName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if c.Policy != nil {
+		if message, ok := c.Policy.Forbidden.Message(varName); ok {
+			if message == "" {
+				message, _ = c.Forbidden.Message(varName)
+			}
+			return message, true
+		}
+	}
+	return c.Forbidden.Message(varName)
+}
+
+// HasForbidden reports whether the config or its policy forbids any variable
+func (c *Config) HasForbidden() bool {
+	return c != nil && (len(c.Forbidden) > 0 || (c.Policy != nil && len(c.Policy.Forbidden) > 0))
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestForbiddenConfig(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte("forbidden: [AWS_SECRET_ACCESS_KEY, \"GITHUB_PAT_*\"]\n"), &cfg); err != nil {
+		t.Fatalf("Failed to decode a list: %v", err)
+	}
+	if message, ok := cfg.ForbiddenMessage("GITHUB_PAT_DEPLOY"); !ok || message != "" {
+		t.Errorf("Expected GITHUB_PAT_DEPLOY to be forbidden without a message, got %q, %v", message, ok)
+	}
+
+	cfg = Config{}
+	if err := yaml.Unmarshal([]byte("forbidden:\n  AWS_SECRET_ACCESS_KEY: use the SDK credential chain\n  \"AWS_*\": use the SDK\n"), &cfg); err != nil {
+		t.Fatalf("Failed to decode a mapping: %v", err)
+	}
+	tests := []struct {
+		key         string
+		wantMessage string
+		wantOK      bool
+	}{
+		{"AWS_SECRET_ACCESS_KEY", "use the SDK credential chain", true},
+		{"AWS_SESSION_TOKEN", "use the SDK", true},
+		{"DATABASE_URL", "", false},
+	}
+	for _, tt := range tests {
+		message, ok := cfg.ForbiddenMessage(tt.key)
+		if message != tt.wantMessage || ok != tt.wantOK {
+			t.Errorf("ForbiddenMessage(%q) = %q, %v, want %q, %v", tt.key, message, ok, tt.wantMessage, tt.wantOK)
+		}
+	}
+
+	enforced := cfg.WithPolicy(&Policy{Forbidden: ForbiddenConfig{"AWS_SESSION_TOKEN": "use IAM roles", "AWS_SECRET_ACCESS_KEY": "", "STRIPE_KEY": ""}})
+	if message, _ := enforced.ForbiddenMessage("AWS_SESSION_TOKEN"); message != "use IAM roles" {
+		t.Errorf("Expected the policy's message to win, got %q", message)
+	}
+	if message, _ := enforced.ForbiddenMessage("AWS_SECRET_ACCESS_KEY"); message != "use the SDK credential chain" {
+		t.Errorf("Expected the local message when the policy has none, got %q", message)
+	}
+	if _, ok := enforced.ForbiddenMessage("STRIPE_KEY"); !ok {
+		t.Error("Expected STRIPE_KEY to be forbidden by the policy")
+	}
+}
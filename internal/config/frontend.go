@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Frontend frameworks whose public env prefix is checked
+const (
+	FrameworkNone = "none" // Disables the check even when package.json names a framework
+	FrameworkVite = "vite"
+	FrameworkNext = "next"
+	FrameworkCRA  = "cra"
+)
+
+// FrontendConfig configures the public-prefix check of client-side code (Vite, Next.js, Create React App)
+type FrontendConfig struct {
+	Framework   string   `yaml:"framework"`    // vite, next, cra or none; detected from package.json when empty
+	ClientDirs  []string `yaml:"client_dirs"`  // Directories with client-side code, relative to the scan root (default depends on the framework)
+	ServerDirs  []string `yaml:"server_dirs"`  // Directories inside client_dirs that only run on the server (e.g., pages/api)
+	SecretWords []string `yaml:"secret_words"` // Name parts that make a public variable look secret (default: SECRET, PASSWORD, TOKEN, ...)
+}
+
+// IsClientFile reports whether a source file (relative to the scan root) is under one of dirs but none of serverDirs
+func IsClientFile(file string, dirs []string, serverDirs []string) bool {
+	file = filepath.ToSlash(file)
+	return underAny(file, dirs) && !underAny(file, serverDirs)
+}
+
+// underAny reports whether file is inside one of the directories ("." matches every file)
+func underAny(file string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = strings.Trim(filepath.ToSlash(dir), "/")
+		if dir == "." || dir == "" || strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the framework name
+func (f *FrontendConfig) validate() error {
+	f.Framework = strings.ToLower(strings.TrimSpace(f.Framework))
+	switch f.Framework {
+	case "", FrameworkNone, FrameworkVite, FrameworkNext, FrameworkCRA:
+		return nil
+	default:
+		return fmt.Errorf("unknown framework %q (supported: vite, next, cra, none)", f.Framework)
+	}
+}
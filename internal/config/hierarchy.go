@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Scope is a subdirectory with its own config file, which applies to the files beneath it
+type Scope struct {
+	Dir     string          // Directory relative to the scan root, with forward slashes
+	Config  *Config         // The directory's config merged over its parent's
+	Defined map[string]bool // Variables defined by the directory's env files, set when they are loaded
+}
+
+// skippedConfigDirs are never searched for nested config files
+var skippedConfigDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+}
+
+// LoadHierarchy loads the config at rootPath and every config file in its subdirectories (see Find)
+// A nested config extends the config of its closest parent directory for the files beneath it:
+// its ignores are added to the parent's, its ignores.paths and ignores.folders are relative to its
+// directory, its env_files (and the env files in its directory) only define variables for the files
+// beneath it, and its required variables are required everywhere; other sections only apply from the root
+// A profile (see WithProfile) is applied to the root config and to the nested configs defining it
+func LoadHierarchy(rootPath string, profile string) (*Config, error) {
+	root, err := LoadConfig(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = root.WithProfile(profile); err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	err = filepath.WalkDir(rootPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || p == rootPath {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, ".") || skippedConfigDirs[name] || matchesAnyName(root.Ignores.Folders, name) {
+			return filepath.SkipDir
+		}
+		if file, err := Find(p); err != nil {
+			return err
+		} else if file != "" {
+			rel, err := filepath.Rel(rootPath, p)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for nested config files: %w", err)
+	}
+
+	// Parents sort before their subdirectories, so each config merges over an already merged parent
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		child, err := LoadConfig(filepath.Join(rootPath, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		if _, ok := child.Profiles[profile]; ok {
+			if child, err = child.WithProfile(profile); err != nil {
+				return nil, fmt.Errorf("%s: %w", dir, err)
+			}
+		}
+		parent := root
+		if scope := root.ScopeOf(dir + "/"); scope != nil {
+			parent = scope.Config
+		}
+		root.Required = append(root.Required, child.Required...)
+		root.Scopes = append(root.Scopes, &Scope{Dir: dir, Config: parent.extend(dir, child)})
+	}
+	return root, nil
+}
+
+// extend returns the config of a subdirectory: c with the ignores and env files of child added
+func (c *Config) extend(dir string, child *Config) *Config {
+	merged := *c
+	merged.Scopes = nil
+	merged.EnvFiles = EnvFilesConfig{
+		Files:   child.EnvFiles.Files,
+		Exclude: append(append([]string{}, c.EnvFiles.Exclude...), child.EnvFiles.Exclude...),
+	}
+	merged.Ignores = IgnoresConfig{
+		Missing: append(append([]string{}, c.Ignores.Missing...), child.Ignores.Missing...),
+		Unused:  append(append([]string{}, c.Ignores.Unused...), child.Ignores.Unused...),
+		Paths:   make(map[string][]string, len(c.Ignores.Paths)+len(child.Ignores.Paths)),
+	}
+	for p, patterns := range c.Ignores.Paths {
+		merged.Ignores.Paths[p] = patterns
+	}
+	for p, patterns := range child.Ignores.Paths {
+		key := path.Join(dir, filepath.ToSlash(p))
+		merged.Ignores.Paths[key] = append(append([]string{}, merged.Ignores.Paths[key]...), patterns...)
+	}
+	for _, folder := range child.Ignores.Folders {
+		merged.Ignores.Folders = append(merged.Ignores.Folders, path.Join(dir, filepath.ToSlash(folder)))
+	}
+	return &merged
+}
+
+// ExcludedFolders returns the ignores.folders of the root config and, relative to the scan root, of every nested config
+func (c *Config) ExcludedFolders() []string {
+	folders := append([]string{}, c.Ignores.Folders...)
+	for _, scope := range c.Scopes {
+		folders = append(folders, scope.Config.Ignores.Folders...)
+	}
+	return folders
+}
+
+// ScopeOf returns the deepest nested config containing file (relative to the scan root), or nil
+func (c *Config) ScopeOf(file string) *Scope {
+	if c == nil {
+		return nil
+	}
+	file = filepath.ToSlash(file)
+	var deepest *Scope
+	for _, scope := range c.Scopes {
+		if strings.HasPrefix(file, scope.Dir+"/") && (deepest == nil || len(scope.Dir) > len(deepest.Dir)) {
+			deepest = scope
+		}
+	}
+	return deepest
+}
+
+// ShouldIgnoreMissingIn checks if a variable used in file is ignored by ignores.paths or by a nested config
+func (c *Config) ShouldIgnoreMissingIn(varName string, file string) bool {
+	if c.Policy.requires(varName) {
+		return false
+	}
+	if scope := c.ScopeOf(file); scope != nil {
+		return scope.Config.ShouldIgnoreMissing(varName) || scope.Config.ShouldIgnoreAt(varName, file)
+	}
+	return c.ShouldIgnoreAt(varName, file)
+}
+
+// ShouldIgnoreUnusedIn checks if a variable defined in an env file is ignored by ignores.paths or by a nested config
+func (c *Config) ShouldIgnoreUnusedIn(varName string, file string) bool {
+	if scope := c.ScopeOf(file); scope != nil {
+		return scope.Config.ShouldIgnoreUnused(varName) || scope.Config.ShouldIgnoreAt(varName, file)
+	}
+	return c.ShouldIgnoreAt(varName, file)
+}
+
+// DefinedIn reports whether the env files of a nested config containing file, or the environment of the
+// service file belongs to, define a variable
+func (c *Config) DefinedIn(varName string, file string) bool {
+	if c == nil {
+		return false
+	}
+	file = filepath.ToSlash(file)
+	for _, scope := range c.Scopes {
+		if scope.Defined[varName] && strings.HasPrefix(file, scope.Dir+"/") {
+			return true
+		}
+	}
+	for _, service := range c.Services {
+		if service.Defined[varName] && service.Contains(file) {
+			return true
+		}
+	}
+	return false
+}
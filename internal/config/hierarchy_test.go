@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadHierarchy(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		FileName: "ignores:\n  missing: [ROOT_*]\n",
+		filepath.Join("services", "api", FileName):       "ignores:\n  missing: [API_TOKEN]\n  unused: [API_FLAG_*]\n  folders: [generated]\n  paths:\n    scripts: [DEBUG]\nrequired: [API_URL]\nenv_files: [config/api.env]\n",
+		filepath.Join("services", "api", "v2", FileName): "ignores:\n  missing: [V2_ONLY]\n",
+		filepath.Join("node_modules", "pkg", FileName):   "ignores:\n  missing: [NEVER]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := LoadHierarchy(root, "")
+	if err != nil {
+		t.Fatalf("LoadHierarchy failed: %v", err)
+	}
+	if len(cfg.Scopes) != 2 || cfg.Scopes[0].Dir != "services/api" || cfg.Scopes[1].Dir != "services/api/v2" {
+		t.Fatalf("Expected the two service configs as scopes, got %+v", cfg.Scopes)
+	}
+	if !cfg.IsRequired("API_URL") {
+		t.Error("Expected required variables of nested configs to be required everywhere")
+	}
+
+	tests := []struct {
+		key  string
+		file string
+		want bool
+	}{
+		{"ROOT_X", "services/api/main.go", true},       // Inherited from the root
+		{"API_TOKEN", "services/api/main.go", true},    // Own ignore
+		{"API_TOKEN", "services/api/v2/main.go", true}, // Inherited from the parent scope
+		{"V2_ONLY", "services/api/main.go", false},     // Child ignores don't apply to the parent
+		{"V2_ONLY", "services/api/v2/main.go", true},
+		{"API_TOKEN", "services/web/main.go", false},   // Other directories use the root config
+		{"DEBUG", "services/api/scripts/run.go", true}, // ignores.paths relative to the nested config
+		{"DEBUG", "scripts/run.go", false},
+	}
+	for _, tt := range tests {
+		if got := cfg.ShouldIgnoreMissingIn(tt.key, tt.file); got != tt.want {
+			t.Errorf("ShouldIgnoreMissingIn(%q, %q) = %v, want %v", tt.key, tt.file, got, tt.want)
+		}
+	}
+
+	if !cfg.ShouldIgnoreUnusedIn("API_FLAG_X", "services/api/config/api.env") || cfg.ShouldIgnoreUnusedIn("API_FLAG_X", ".env") {
+		t.Error("Expected ignores.unused of a nested config to apply to its env files only")
+	}
+	if folders := cfg.ExcludedFolders(); len(folders) != 1 || folders[0] != "services/api/generated" {
+		t.Errorf("Expected nested ignores.folders relative to the scan root, got %v", folders)
+	}
+
+	cfg.Scopes[0].Defined = map[string]bool{"API_DB": true}
+	if !cfg.DefinedIn("API_DB", "services/api/v2/db.go") || cfg.DefinedIn("API_DB", "services/web/db.go") {
+		t.Error("Expected variables of nested env files to be defined only beneath the directory")
+	}
+}
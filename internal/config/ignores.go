@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// compiledPatterns caches the regular expressions of /regex/ name patterns
+var compiledPatterns sync.Map // pattern string -> *regexp.Regexp
+
+// MatchesName reports whether key matches a name pattern: an exact name, a glob (AWS_*)
+// or a regular expression between slashes (/^KUBERNETES_/)
+func MatchesName(pattern string, key string) bool {
+	if isRegexPattern(pattern) {
+		re, err := compileRegexPattern(pattern)
+		return err == nil && re.MatchString(key)
+	}
+	if pattern == key {
+		return true
+	}
+	matched, _ := path.Match(pattern, key)
+	return matched
+}
+
+// matchesAnyName reports whether key matches one of the name patterns
+func matchesAnyName(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if MatchesName(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRegexPattern reports whether a name pattern is a /regular expression/
+func isRegexPattern(pattern string) bool {
+	return len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+// compileRegexPattern compiles a /regular expression/ name pattern, caching the result
+func compileRegexPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// ValidateNamePatterns checks that every glob and regular expression of name patterns compiles
+func ValidateNamePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if isRegexPattern(pattern) {
+			if _, err := compileRegexPattern(pattern); err != nil {
+				return fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+			}
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// ShouldIgnoreUnused checks if a variable should be ignored when reporting as unused
+func (c *Config) ShouldIgnoreUnused(varName string) bool {
+	return c != nil && matchesAnyName(c.Ignores.Unused, varName)
+}
+
+// ShouldIgnoreAt checks if a variable is ignored in a file (relative to the scan root) by ignores.paths
+// For missing variables the file is where the variable is used, for unused ones the env file defining it
+func (c *Config) ShouldIgnoreAt(varName string, file string) bool {
+	if c == nil || file == "" {
+		return false
+	}
+	for dir, patterns := range c.Ignores.Paths {
+		if underAny(file, []string{dir}) && matchesAnyName(patterns, varName) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the name patterns of every ignore list
+func (i *IgnoresConfig) validate() error {
+	if err := ValidateNamePatterns(i.Missing); err != nil {
+		return fmt.Errorf("missing: %w", err)
+	}
+	if err := ValidateNamePatterns(i.Unused); err != nil {
+		return fmt.Errorf("unused: %w", err)
+	}
+	for dir, patterns := range i.Paths {
+		if err := ValidateNamePatterns(patterns); err != nil {
+			return fmt.Errorf("paths %s: %w", dir, err)
+		}
+	}
+	return nil
+}
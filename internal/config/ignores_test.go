@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchesName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"API_KEY", "API_KEY", true},
+		{"API_KEY", "API_KEYS", false},
+		{"AWS_*", "AWS_REGION", true},
+		{"AWS_*", "MY_AWS_REGION", false},
+		{"/^KUBERNETES_/", "KUBERNETES_SERVICE_HOST", true},
+		{"/^KUBERNETES_/", "MY_KUBERNETES_HOST", false},
+		{"/_(URL|URI)$/", "DATABASE_URL", true},
+		{"/", "/", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesName(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("MatchesName(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestIgnores(t *testing.T) {
+	cfg := &Config{Ignores: IgnoresConfig{
+		Missing: []string{"AWS_*"},
+		Unused:  []string{"/^FEATURE_/"},
+		Paths:   map[string][]string{"tools": {"DEBUG_*"}},
+	}}
+
+	if !cfg.ShouldIgnoreMissing("AWS_REGION") || cfg.ShouldIgnoreMissing("FEATURE_X") {
+		t.Error("Expected only AWS_* to be ignored when missing")
+	}
+	if !cfg.ShouldIgnoreUnused("FEATURE_X") || cfg.ShouldIgnoreUnused("AWS_REGION") {
+		t.Error("Expected only FEATURE_* to be ignored when unused")
+	}
+	if !cfg.ShouldIgnoreAt("DEBUG_TOKEN", "tools/seed/main.go") {
+		t.Error("Expected DEBUG_TOKEN to be ignored under tools")
+	}
+	if cfg.ShouldIgnoreAt("DEBUG_TOKEN", "src/main.go") || cfg.ShouldIgnoreAt("DEBUG_TOKEN", "toolshed/main.go") {
+		t.Error("Expected DEBUG_TOKEN to be reported outside tools")
+	}
+
+	var nilConfig *Config
+	if nilConfig.ShouldIgnoreMissing("X") || nilConfig.ShouldIgnoreUnused("X") || nilConfig.ShouldIgnoreAt("X", "a.go") {
+		t.Error("Expected a nil config to ignore nothing")
+	}
+}
+
+func TestLoadConfig_InvalidIgnorePattern(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "ignores:\n  unused:\n    - \"/(unclosed/\"\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".envgrd.config"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), "invalid regular expression") {
+		t.Errorf("Expected an invalid regular expression error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// NamingConfig defines naming-convention rules for variable names, checked in code and env files
+type NamingConfig struct {
+	UpperSnakeCase bool     `yaml:"upper_snake_case"` // Names must be UPPER_SNAKE_CASE
+	Prefix         string   `yaml:"prefix"`           // Names must start with this prefix (e.g., MYAPP_)
+	MaxLength      int      `yaml:"max_length"`       // Longest allowed name (0 = no limit)
+	ForbiddenWords []string `yaml:"forbidden_words"`  // Underscore-separated name parts that are not allowed (case-insensitive)
+	Exempt         []string `yaml:"exempt"`           // Names or globs the rules don't apply to (e.g., NODE_ENV, "AWS_*")
+}
+
+// Enabled reports whether any naming rule is configured
+func (n NamingConfig) Enabled() bool {
+	return n.UpperSnakeCase || n.Prefix != "" || n.MaxLength > 0 || len(n.ForbiddenWords) > 0
+}
+
+// NamingEnabled reports whether the config or its policy configures any naming rule
+func (c *Config) NamingEnabled() bool {
+	return c != nil && (c.Naming.Enabled() || (c.Policy != nil && c.Policy.Naming.Enabled()))
+}
+
+// NamingProblems returns the naming rules key breaks, as human-readable descriptions, including the rules
+// of the policy (see WithPolicy)
+func (c *Config) NamingProblems(key string) []string {
+	if c == nil {
+		return nil
+	}
+	problems := c.Naming.problems(key)
+	if c.Policy != nil {
+		for _, problem := range c.Policy.Naming.problems(key) {
+			if !slices.Contains(problems, problem) {
+				problems = append(problems, problem)
+			}
+		}
+	}
+	return problems
+}
+
+// problems returns the rules key breaks, unless it's exempt
+func (n NamingConfig) problems(key string) []string {
+	if !n.Enabled() {
+		return nil
+	}
+	for _, pattern := range n.Exempt {
+		if matched, _ := path.Match(pattern, key); matched {
+			return nil
+		}
+	}
+
+	var problems []string
+	if n.UpperSnakeCase && !isUpperSnakeCase(key) {
+		problems = append(problems, "not UPPER_SNAKE_CASE")
+	}
+	if n.Prefix != "" && !strings.HasPrefix(key, n.Prefix) {
+		problems = append(problems, fmt.Sprintf("missing prefix %s", n.Prefix))
+	}
+	if n.MaxLength > 0 && len(key) > n.MaxLength {
+		problems = append(problems, fmt.Sprintf("longer than %d characters", n.MaxLength))
+	}
+	for _, part := range strings.Split(key, "_") {
+		for _, word := range n.ForbiddenWords {
+			if strings.EqualFold(part, word) {
+				problems = append(problems, fmt.Sprintf("contains forbidden word %s", strings.ToUpper(word)))
+			}
+		}
+	}
+	return problems
+}
+
+// isUpperSnakeCase reports whether key is made of upper-case letters and digits separated by single underscores
+func isUpperSnakeCase(key string) bool {
+	if key == "" || key[0] == '_' || key[len(key)-1] == '_' || strings.Contains(key, "__") {
+		return false
+	}
+	if key[0] >= '0' && key[0] <= '9' {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks the exempt patterns and the length limit
+func (n *NamingConfig) validate() error {
+	if n.MaxLength < 0 {
+		return fmt.Errorf("max_length must not be negative, got %d", n.MaxLength)
+	}
+	for _, pattern := range n.Exempt {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exempt pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
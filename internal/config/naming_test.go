@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNamingProblems(t *testing.T) {
+	cfg := &Config{Naming: NamingConfig{
+		UpperSnakeCase: true,
+		Prefix:         "MYAPP_",
+		MaxLength:      20,
+		ForbiddenWords: []string{"tmp"},
+		Exempt:         []string{"NODE_ENV", "AWS_*"},
+	}}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"MYAPP_DB_HOST", nil},
+		{"NODE_ENV", nil},
+		{"AWS_REGION", nil},
+		{"MYAPP_dbHost", []string{"not UPPER_SNAKE_CASE"}},
+		{"MYAPP__HOST", []string{"not UPPER_SNAKE_CASE"}},
+		{"DB_HOST", []string{"missing prefix MYAPP_"}},
+		{"MYAPP_A_VERY_LONG_SETTING", []string{"longer than 20 characters"}},
+		{"MYAPP_TMP_DIR", []string{"contains forbidden word TMP"}},
+		{"tmp", []string{"not UPPER_SNAKE_CASE", "missing prefix MYAPP_", "contains forbidden word TMP"}},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.NamingProblems(tt.key); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NamingProblems(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	var nilConfig *Config
+	if got := nilConfig.NamingProblems("lower"); got != nil {
+		t.Errorf("Expected no problems without a config, got %v", got)
+	}
+}
+
+func TestLoadConfig_InvalidNaming(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "naming:\n  max_length: -1\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".envgrd.config"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(tmpDir); err == nil {
+		t.Error("Expected an error for a negative max_length")
+	}
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy holds an organization's central rules, enforced over the local configs of a scan (see WithPolicy):
+// local configs can add to them but not weaken them
+type Policy struct {
+	Required  []string        `yaml:"required"`  // Variables that must be defined, which local ignores can't hide
+	Naming    NamingConfig    `yaml:"naming"`    // Naming rules checked besides the local ones, with their own exempt list
+	Severity  SeverityConfig  `yaml:"severity"`  // Lowest severities of their categories and variables
+	Forbidden ForbiddenConfig `yaml:"forbidden"` // Variables forbidden besides the locally forbidden ones
+
+	Source   string `yaml:"-"` // URL or file the policy was loaded from
+	Checksum string `yaml:"-"` // SHA-256 of the policy file, hex-encoded
+}
+
+// policyType is used to detect unknown keys of policy files
+var policyType = reflect.TypeOf(Policy{})
+
+// LoadPolicy loads a policy file from an https URL or a file path and checks its SHA-256 against checksum
+// (hex, optionally prefixed with sha256:). Policies fetched from URLs must be pinned by a checksum; plain
+// http URLs are refused
+func LoadPolicy(ref string, checksum string) (*Policy, error) {
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(ref, "http://"):
+		return nil, fmt.Errorf("policy %s: only https URLs are supported", ref)
+	case strings.HasPrefix(ref, "https://"):
+		if strings.TrimSpace(checksum) == "" {
+			return nil, fmt.Errorf("policy %s: a policy fetched from a URL needs its sha256 checksum", ref)
+		}
+		data, err = fetchConfig(ref)
+	default:
+		data, err = os.ReadFile(ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy %s: %w", ref, err)
+	}
+
+	actual, err := verifyChecksum(data, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", ref, err)
+	}
+	policy, err := parsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", ref, err)
+	}
+	policy.Source, policy.Checksum = ref, actual
+	return policy, nil
+}
+
+// parsePolicy decodes and validates a policy file
+func parsePolicy(data []byte) (*Policy, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	node, err := documentMapping(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if problems := checkKeys(node, policyType); len(problems) > 0 {
+		return nil, problems[0]
+	}
+	var policy Policy
+	if err := node.Decode(&policy); err != nil {
+		return nil, err
+	}
+	if err := policy.Severity.normalize(); err != nil {
+		return nil, fmt.Errorf("invalid severity: %w", err)
+	}
+	if err := policy.Naming.validate(); err != nil {
+		return nil, fmt.Errorf("invalid naming: %w", err)
+	}
+	if err := policy.Forbidden.validate(); err != nil {
+		return nil, fmt.Errorf("invalid forbidden: %w", err)
+	}
+	return &policy, nil
+}
+
+// WithPolicy returns the config, and its nested configs, enforcing policy (c when policy is nil):
+// the policy's required variables are added and can't be ignored as missing, its naming rules and
+// forbidden variables are checked besides the config's, and severities are raised to the policy's where
+// it sets a higher one
+func (c *Config) WithPolicy(policy *Policy) *Config {
+	if c == nil || policy == nil {
+		return c
+	}
+	enforced := c.enforce(policy)
+	enforced.Scopes = make([]*Scope, 0, len(c.Scopes))
+	for _, scope := range c.Scopes {
+		enforced.Scopes = append(enforced.Scopes, &Scope{Dir: scope.Dir, Config: scope.Config.enforce(policy), Defined: scope.Defined})
+	}
+	return enforced
+}
+
+// enforce returns a copy of the config with the policy attached, its required variables added, and the
+// scan settings that would hide the policy's categories (fail_on, skip_unused, no_dynamic) overridden
+func (c *Config) enforce(policy *Policy) *Config {
+	enforced := *c
+	enforced.Policy = policy
+	enforced.Required = append([]string{}, c.Required...)
+	for _, key := range policy.Required {
+		if !c.IsRequired(key) {
+			enforced.Required = append(enforced.Required, key)
+		}
+	}
+
+	categories := policy.categories()
+	if len(c.FailOn) > 0 {
+		enforced.FailOn = append(StringList{}, c.FailOn...)
+		for _, category := range categories {
+			if !slices.Contains(enforced.FailOn, category) {
+				enforced.FailOn = append(enforced.FailOn, category)
+			}
+		}
+	}
+	if slices.Contains(categories, "unused") {
+		enforced.SkipUnused = nil
+	}
+	if slices.Contains(categories, "dynamic") {
+		enforced.NoDynamic = nil
+	}
+	return &enforced
+}
+
+// severityCategories maps severity categories to the --fail-on categories of their findings
+var severityCategories = map[string]string{
+	CategoryMissing:      "missing",
+	CategoryOptional:     "missing",
+	CategoryTest:         "missing",
+	CategoryUnused:       "unused",
+	CategoryDynamic:      "dynamic",
+	CategoryUndocumented: "example",
+	CategoryStale:        "example",
+	CategoryUnread:       "schema",
+	CategoryUndeclared:   "schema",
+	CategoryUnprefixed:   "frontend",
+	CategoryExposed:      "frontend",
+	CategoryStyle:        "style",
+	CategoryDeprecated:   "deprecated",
+	CategoryType:         "type",
+	CategoryPlaceholder:  "placeholder",
+	CategoryReference:    "reference",
+	CategoryForbidden:    "forbidden",
+}
+
+// categories returns the --fail-on categories the policy's rules report, sorted: missing for required
+// variables, style for naming rules, forbidden for forbidden variables, and the categories it sets a
+// severity of. Per-variable severities only raise severities and don't add categories
+func (p *Policy) categories() []string {
+	var categories []string
+	add := func(category string) {
+		if !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	if len(p.Required) > 0 {
+		add("missing")
+	}
+	if p.Naming.Enabled() {
+		add("style")
+	}
+	if len(p.Forbidden) > 0 {
+		add("forbidden")
+	}
+	severity := p.Severity
+	severity.Variables = nil
+	for category, failOn := range severityCategories {
+		if severity.lookup(category, "") != "" {
+			add(failOn)
+		}
+	}
+	slices.Sort(categories)
+	return categories
+}
+
+// pins reports whether the policy keeps findings for key in category from going below their default
+// severity: missing required variables and forbidden ones
+func (p *Policy) pins(category string, key string) bool {
+	switch category {
+	case CategoryMissing:
+		return p.requires(key)
+	case CategoryForbidden:
+		_, forbidden := p.Forbidden.Message(key)
+		return forbidden
+	default:
+		return false
+	}
+}
+
+// requires reports whether the policy requires a variable
+func (p *Policy) requires(key string) bool {
+	if p == nil {
+		return false
+	}
+	for _, required := range p.Required {
+		if required == key {
+			return true
+		}
+	}
+	return false
+}
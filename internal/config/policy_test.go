@@ -0,0 +1,148 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "policy.yaml")
+	content := "required: [SERVICE_NAME]\nnaming:\n  upper_snake_case: true\nseverity:\n  missing: error\n  style: Error\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+
+	policy, err := LoadPolicy(file, "sha256:"+checksum)
+	if err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if policy.Checksum != checksum || policy.Source != file || policy.Severity.Style != SeverityError || !policy.Naming.UpperSnakeCase {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+	if _, err := LoadPolicy(file, ""); err != nil {
+		t.Errorf("Expected a local policy to load without a checksum, got %v", err)
+	}
+
+	if _, err := LoadPolicy(file, "0000"); err == nil {
+		t.Error("Expected an error for a checksum mismatch")
+	}
+	if _, err := LoadPolicy("https://example.com/policy.yaml", ""); err == nil {
+		t.Error("Expected an error for a URL without a checksum")
+	}
+	if _, err := LoadPolicy("http://example.com/policy.yaml", checksum); err == nil {
+		t.Error("Expected an error for a plain http URL")
+	}
+	if err := os.WriteFile(file, []byte("ignores:\n  missing: [SERVICE_NAME]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(file, ""); err == nil {
+		t.Error("Expected an error for a key policies don't support")
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	cfg := &Config{
+		Required: []string{"DATABASE_URL"},
+		Ignores:  IgnoresConfig{Missing: []string{"SERVICE_*"}},
+		Naming:   NamingConfig{Prefix: "APP_", Exempt: []string{"SERVICE_*"}},
+		Severity: SeverityConfig{
+			Missing:   SeverityInfo,
+			Unused:    SeverityError,
+			Variables: map[string]Severity{"LEGACY_*": SeverityInfo},
+		},
+		Scopes: []*Scope{{Dir: "api", Config: &Config{Ignores: IgnoresConfig{Missing: []string{"SERVICE_NAME"}}}}},
+	}
+	policy := &Policy{
+		Required: []string{"SERVICE_NAME", "DATABASE_URL"},
+		Naming:   NamingConfig{UpperSnakeCase: true},
+		Severity: SeverityConfig{Missing: SeverityError, Unused: SeverityWarning},
+	}
+
+	enforced := cfg.WithPolicy(policy)
+	if !reflect.DeepEqual(enforced.Required, []string{"DATABASE_URL", "SERVICE_NAME"}) {
+		t.Errorf("Expected the policy's required variables to be added, got %v", enforced.Required)
+	}
+	if enforced.ShouldIgnoreMissing("SERVICE_NAME") || enforced.ShouldIgnoreMissingIn("SERVICE_NAME", "api/main.go") {
+		t.Error("Expected local ignores not to hide a variable the policy requires")
+	}
+	if !enforced.ShouldIgnoreMissing("SERVICE_PORT") {
+		t.Error("Expected local ignores to apply to other variables")
+	}
+	if got := enforced.SeverityFor(CategoryMissing, "LEGACY_TOKEN"); got != SeverityError {
+		t.Errorf("Expected the policy to raise the missing severity, got %s", got)
+	}
+	if got := enforced.SeverityFor(CategoryUnused, "KEY"); got != SeverityError {
+		t.Errorf("Expected a stricter local severity to stay, got %s", got)
+	}
+	if got := enforced.SeverityFor(CategoryStyle, "KEY"); got != SeverityWarning {
+		t.Errorf("Expected the default severity where neither sets one, got %s", got)
+	}
+	if got := enforced.NamingProblems("SERVICE_name"); !reflect.DeepEqual(got, []string{"not UPPER_SNAKE_CASE"}) {
+		t.Errorf("Expected the policy's rules without the local exemptions, got %v", got)
+	}
+	if got := enforced.NamingProblems("db"); !reflect.DeepEqual(got, []string{"missing prefix APP_", "not UPPER_SNAKE_CASE"}) {
+		t.Errorf("Expected both the local and the policy's rules, got %v", got)
+	}
+	if enforced.Scopes[0].Config.Policy != policy {
+		t.Error("Expected nested configs to enforce the policy")
+	}
+	if len(cfg.Required) != 1 || cfg.Policy != nil {
+		t.Error("Expected WithPolicy not to modify the config")
+	}
+	if cfg.WithPolicy(nil) != cfg {
+		t.Error("Expected the config itself without a policy")
+	}
+}
+
+func TestWithPolicy_LocalWeakening(t *testing.T) {
+	yes := true
+	cfg := &Config{
+		Severity: SeverityConfig{Missing: SeverityInfo, Forbidden: SeverityInfo, Unused: SeverityInfo},
+		ScanSettings: ScanSettings{
+			FailOn:     StringList{"unused"},
+			SkipUnused: &yes,
+			NoDynamic:  &yes,
+		},
+	}
+	policy := &Policy{
+		Required:  []string{"SERVICE_NAME"},
+		Forbidden: ForbiddenConfig{"AWS_SECRET_ACCESS_KEY": ""},
+		Severity:  SeverityConfig{Unused: SeverityWarning},
+	}
+
+	enforced := cfg.WithPolicy(policy)
+	if got := enforced.SeverityFor(CategoryMissing, "SERVICE_NAME"); got != SeverityError {
+		t.Errorf("Expected a required variable to keep the default missing severity, got %s", got)
+	}
+	if got := enforced.SeverityFor(CategoryForbidden, "AWS_SECRET_ACCESS_KEY"); got != SeverityError {
+		t.Errorf("Expected a forbidden variable to keep the default severity, got %s", got)
+	}
+	if got := enforced.SeverityFor(CategoryMissing, "PORT"); got != SeverityInfo {
+		t.Errorf("Expected the local severity of other variables to stay, got %s", got)
+	}
+	if got := enforced.SeverityFor(CategoryUnused, "PORT"); got != SeverityWarning {
+		t.Errorf("Expected the policy's unused severity, got %s", got)
+	}
+	if want := (StringList{"unused", "forbidden", "missing"}); !reflect.DeepEqual(enforced.FailOn, want) {
+		t.Errorf("Expected fail_on %v, got %v", want, enforced.FailOn)
+	}
+	if enforced.SkipUnused != nil {
+		t.Error("Expected skip_unused to be dropped when the policy sets an unused severity")
+	}
+	if enforced.NoDynamic == nil {
+		t.Error("Expected no_dynamic to stay when the policy doesn't cover dynamic patterns")
+	}
+	if !reflect.DeepEqual(cfg.FailOn, StringList{"unused"}) || cfg.SkipUnused == nil {
+		t.Error("Expected WithPolicy not to modify the config's settings")
+	}
+	if got := cfg.WithPolicy(&Policy{Required: []string{"SERVICE_NAME"}}); got.FailOn == nil || got.SkipUnused == nil {
+		t.Errorf("Expected only the settings hiding the policy's categories to change, got %+v", got.ScanSettings)
+	}
+}
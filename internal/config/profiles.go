@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileNames returns the names of the config's profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns the config with a profile applied over it, like a config extending this one:
+// lists are added to, maps merged and other values replaced. An empty name returns c unchanged
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	if _, ok := c.Profiles[name]; !ok || c.node == nil {
+		if len(c.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q: the config defines no profiles", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q (defined: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	base := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	var profile *yaml.Node
+	for i := 0; i+1 < len(c.node.Content); i += 2 {
+		key, value := c.node.Content[i], c.node.Content[i+1]
+		if key.Value != "profiles" {
+			base.Content = append(base.Content, key, value)
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			if value.Content[j].Value == name {
+				profile = value.Content[j+1]
+			}
+		}
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	var config Config
+	if err := mergeNodes("", base, profile).Decode(&config); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	config.Profiles = c.Profiles
+	config.Extends = c.Extends
+	config.File = c.File
+	config.Profile = name
+	config.node = c.node
+	return &config, nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(tmpDir, FileName), `
+ignores:
+  missing: [A]
+fail_on: [missing, unused]
+concurrency: 2
+profiles:
+  ci:
+    fail_on: missing
+    format: json
+    ignores:
+      missing: [CI_*]
+  local:
+    skip_unused: true
+`)
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if same, err := cfg.WithProfile(""); err != nil || same != cfg {
+		t.Errorf("Expected no profile to return the config unchanged, got %v, %v", same, err)
+	}
+
+	ci, err := cfg.WithProfile("ci")
+	if err != nil {
+		t.Fatalf("WithProfile failed: %v", err)
+	}
+	if !reflect.DeepEqual([]string(ci.FailOn), []string{"missing"}) {
+		t.Errorf("Expected fail_on to be replaced, got %v", ci.FailOn)
+	}
+	if ci.Format != "json" || ci.Concurrency == nil || *ci.Concurrency != 2 || ci.Profile != "ci" {
+		t.Errorf("Unexpected ci profile: %+v", ci.ScanSettings)
+	}
+	if !ci.ShouldIgnoreMissing("A") || !ci.ShouldIgnoreMissing("CI_TOKEN") {
+		t.Errorf("Expected ignores of both the config and the profile, got %v", ci.Ignores.Missing)
+	}
+	if cfg.ShouldIgnoreMissing("CI_TOKEN") || cfg.Format != "" {
+		t.Error("Expected the original config to be unchanged")
+	}
+
+	local, err := cfg.WithProfile("local")
+	if err != nil {
+		t.Fatalf("WithProfile failed: %v", err)
+	}
+	if local.SkipUnused == nil || !*local.SkipUnused || local.Format != "" {
+		t.Errorf("Unexpected local profile: %+v", local.ScanSettings)
+	}
+
+	if _, err := cfg.WithProfile("staging"); err == nil || !strings.Contains(err.Error(), "defined: ci, local") {
+		t.Errorf("Expected an unknown profile error, got %v", err)
+	}
+	empty, _ := LoadConfig(t.TempDir())
+	if _, err := empty.WithProfile("ci"); err == nil || !strings.Contains(err.Error(), "defines no profiles") {
+		t.Errorf("Expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadHierarchy_Profile(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFile(t, filepath.Join(root, FileName), "profiles:\n  ci:\n    ignores:\n      missing: [CI]\n")
+	writeConfigFile(t, filepath.Join(root, "api", FileName), "profiles:\n  ci:\n    ignores:\n      missing: [API_CI]\n")
+	writeConfigFile(t, filepath.Join(root, "web", FileName), "ignores:\n  missing: [WEB]\n")
+
+	cfg, err := LoadHierarchy(root, "ci")
+	if err != nil {
+		t.Fatalf("LoadHierarchy failed: %v", err)
+	}
+	if !cfg.ShouldIgnoreMissing("CI") {
+		t.Error("Expected the root profile to apply")
+	}
+	if !cfg.ShouldIgnoreMissingIn("API_CI", "api/main.go") || !cfg.ShouldIgnoreMissingIn("CI", "api/main.go") {
+		t.Error("Expected the nested profile to apply under api")
+	}
+	if !cfg.ShouldIgnoreMissingIn("WEB", "web/app.js") {
+		t.Error("Expected nested configs without the profile to load")
+	}
+
+	if _, err := LoadHierarchy(root, "local"); err == nil {
+		t.Error("Expected an error for a profile the root config doesn't define")
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// How env file values are shown in reports (--show-values)
+const (
+	ShowValuesNever    = "never"    // Values are left out
+	ShowValuesRedacted = "redacted" // Values are hidden or masked unless they look harmless (default)
+	ShowValuesFull     = "full"     // Values are shown as they are
+)
+
+// Redaction rule actions
+const (
+	RedactHide = "hide" // Replace the value with [REDACTED]
+	RedactMask = "mask" // Show only the first and last character of long values
+	RedactShow = "show" // Show the value as it is
+)
+
+// RedactionRule decides how the values of matching variables are shown, overriding the built-in
+// rules. Rules are checked in order and the first match wins; they apply with --show-values
+// redacted and full, never shows no value at all
+type RedactionRule struct {
+	Keys   []string `yaml:"keys"`   // Variable names, globs (*_SECRET) or /regular expressions/; any variable when empty
+	Values string   `yaml:"values"` // Regular expression the value must match (e.g., ^https?://); any value when empty
+	Action string   `yaml:"action"` // hide, mask or show
+}
+
+// Matches reports whether the rule applies to a variable's value
+func (r RedactionRule) Matches(key string, value string) bool {
+	if len(r.Keys) > 0 && !matchesAnyName(r.Keys, key) {
+		return false
+	}
+	if r.Values != "" {
+		re, err := compileRegexPattern("/" + r.Values + "/")
+		if err != nil || !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateShowValues checks a --show-values mode
+func ValidateShowValues(mode string) error {
+	switch mode {
+	case "", ShowValuesNever, ShowValuesRedacted, ShowValuesFull:
+		return nil
+	default:
+		return fmt.Errorf("unknown show_values %q (supported: never, redacted, full)", mode)
+	}
+}
+
+// validateRedaction checks the patterns and actions of redaction rules
+func validateRedaction(rules []RedactionRule) error {
+	for i, rule := range rules {
+		switch rule.Action {
+		case RedactHide, RedactMask, RedactShow:
+		default:
+			return fmt.Errorf("rule %d: unknown action %q (supported: hide, mask, show)", i+1, rule.Action)
+		}
+		if err := ValidateNamePatterns(rule.Keys); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if _, err := regexp.Compile(rule.Values); err != nil {
+			return fmt.Errorf("rule %d: invalid values expression %q: %w", i+1, rule.Values, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package config
+
+// RemediationHint returns the remediation hint configured for a variable, shown with its findings
+// Exact names win over globs and regular expressions, which are tried in order
+func (c *Config) RemediationHint(varName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	return lookupName(c.Remediation, varName)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/owners"
+)
+
+// ServicesConfig maps the name of each deployable unit to its code and manifests
+type ServicesConfig map[string]ServiceConfig
+
+// ServiceConfig maps a deployable unit (a docker-compose service or a Kubernetes workload) to its code
+// and to the env files its environment comes from
+type ServiceConfig struct {
+	Dirs      StringList `yaml:"dirs"`      // Code directories relative to the scan root, instead of the compose build context
+	Manifests StringList `yaml:"manifests"` // Globs of the env files defining its environment (e.g., k8s/api/*.yaml), besides its compose environment block
+}
+
+// Service is a deployable unit whose environment only applies to its own code, e.g. a docker-compose
+// service built from ./services/api
+type Service struct {
+	Name      string          // Name of the service (e.g., api)
+	Dirs      []string        // Code directories relative to the scan root, with forward slashes
+	Manifests []string        // Env files defining its environment, relative to the scan root
+	Defined   map[string]bool // Variables defined by the service's environment
+}
+
+// validateServices checks the code directories and manifest globs of the services config
+func validateServices(services ServicesConfig) error {
+	for name, service := range services {
+		for _, dir := range service.Dirs {
+			clean := path.Clean(filepath.ToSlash(dir))
+			if dir == "" || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+				return fmt.Errorf("%s: %q is not a directory below the scan root", name, dir)
+			}
+		}
+		if err := validateGlobs(service.Manifests); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ServiceDirs returns the configured code directories of a service, cleaned and with forward slashes,
+// nil when the config doesn't set them
+func (c *Config) ServiceDirs(name string) []string {
+	if c == nil {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range c.ServiceMap[name].Dirs {
+		dirs = append(dirs, path.Clean(filepath.ToSlash(dir)))
+	}
+	return dirs
+}
+
+// ManifestServices returns a function naming the configured services whose manifests match an env file
+// (relative to the scan root), in name order, none for other files
+func (c *Config) ManifestServices() func(file string) []string {
+	type manifest struct {
+		service string
+		re      *regexp.Regexp
+	}
+	var manifests []manifest
+	if c != nil {
+		names := make([]string, 0, len(c.ServiceMap))
+		for name := range c.ServiceMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, glob := range c.ServiceMap[name].Manifests {
+				if re, err := owners.CompilePattern(filepath.ToSlash(glob)); err == nil {
+					manifests = append(manifests, manifest{service: name, re: re})
+				}
+			}
+		}
+	}
+	return func(file string) []string {
+		file = filepath.ToSlash(file)
+		var services []string
+		for _, m := range manifests {
+			if m.re.MatchString(file) && !slices.Contains(services, m.service) {
+				services = append(services, m.service)
+			}
+		}
+		return services
+	}
+}
+
+// Service returns the service with the given name, or nil
+func (c *Config) Service(name string) *Service {
+	if c == nil {
+		return nil
+	}
+	for _, service := range c.Services {
+		if service.Name == name {
+			return service
+		}
+	}
+	return nil
+}
+
+// ServiceNames returns the names of the services, sorted
+func (c *Config) ServiceNames() []string {
+	var names []string
+	if c != nil {
+		for _, service := range c.Services {
+			names = append(names, service.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Contains reports whether file (relative to the scan root) is part of the service's code
+func (s *Service) Contains(file string) bool {
+	file = filepath.ToSlash(file)
+	for _, dir := range s.Dirs {
+		if strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
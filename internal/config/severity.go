@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Severity classifies how serious a finding is
+type Severity string
+
+// Severity levels, from least to most serious
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Finding categories that can be assigned a severity
+const (
+	CategoryMissing  = "missing"
+	CategoryUnused   = "unused"
+	CategoryDynamic  = "dynamic"
+	CategoryOptional = "optional"
+	CategoryTest     = "test" // Missing, but only used in test files
+
+	CategoryUndocumented = "undocumented" // In code or real env files but not in any example file
+	CategoryStale        = "stale"        // In an example file but never used in code
+
+	CategoryUnread     = "unread"     // Required by the schema but never used in code
+	CategoryUndeclared = "undeclared" // Used in code but not declared in the schema
+
+	CategoryUnprefixed = "unprefixed" // Read in client-side code without the framework's public prefix
+	CategoryExposed    = "exposed"    // Secret-looking variable with the framework's public prefix
+
+	CategoryStyle      = "style"      // Name breaks a naming-convention rule
+	CategoryDeprecated = "deprecated" // Deprecated variable still used in code or defined in env files
+
+	CategoryType        = "type"        // Env file value code can't parse as the type it expects
+	CategoryPlaceholder = "placeholder" // Variable read in code with an empty or placeholder value
+	CategoryReference   = "reference"   // Env file value referencing a variable that is neither defined nor exported
+)
+
+const (
+	CategoryForbidden = "forbidden" // Forbidden variable used in code or defined in env files
+)
+
+// SeverityConfig assigns severities to finding categories, with per-variable overrides
+type SeverityConfig struct {
+	Missing      Severity            `yaml:"missing"`      // Default: error
+	Unused       Severity            `yaml:"unused"`       // Default: warning
+	Dynamic      Severity            `yaml:"dynamic"`      // Default: info
+	Optional     Severity            `yaml:"optional"`     // Default: info (missing variables whose lookups all have a default)
+	Test         Severity            `yaml:"test"`         // Default: info (missing variables only used in tests, with the report test mode)
+	Undocumented Severity            `yaml:"undocumented"` // Default: warning (variables missing from example files)
+	Stale        Severity            `yaml:"stale"`        // Default: warning (example variables never used in code)
+	Unread       Severity            `yaml:"unread"`       // Default: warning (required schema variables never used in code)
+	Undeclared   Severity            `yaml:"undeclared"`   // Default: warning (variables used in code but missing from the schema)
+	Unprefixed   Severity            `yaml:"unprefixed"`   // Default: warning (client-side variables without the public prefix)
+	Exposed      Severity            `yaml:"exposed"`      // Default: warning (secret-looking variables with the public prefix)
+	Style        Severity            `yaml:"style"`        // Default: warning (names breaking naming-convention rules)
+	Deprecated   Severity            `yaml:"deprecated"`   // Default: warning (deprecated variables still in use)
+	Type         Severity            `yaml:"type"`         // Default: error (values code can't parse as the type it expects)
+	Placeholder  Severity            `yaml:"placeholder"`  // Default: warning (empty or placeholder values, e.g. changeme)
+	Reference    Severity            `yaml:"reference"`    // Default: warning (${VAR} references to undefined variables)
+	Forbidden    Severity            `yaml:"forbidden"`    // Default: error (forbidden variables used or defined)
+	Variables    map[string]Severity `yaml:"variables"`    // Overrides by variable name or glob (e.g., "LEGACY_*": info)
+}
+
+// Rank orders severities so they can be compared (0 for unknown values)
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityError:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity parses a severity name (case-insensitive)
+func ParseSeverity(value string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(value)))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("unknown severity %q (supported: error, warning, info)", value)
+	}
+	return severity, nil
+}
+
+// DefaultSeverity returns the built-in severity of a finding category
+func DefaultSeverity(category string) Severity {
+	switch category {
+	case CategoryMissing, CategoryType, CategoryForbidden:
+		return SeverityError
+	case CategoryDynamic, CategoryOptional, CategoryTest:
+		return SeverityInfo
+	default:
+		return SeverityWarning
+	}
+}
+
+// SeverityFor returns the severity of a finding for key in the given category
+// Exact variable overrides win over globs, and longer globs over shorter ones; the policy (see WithPolicy)
+// raises the severity where it sets a higher one, and keeps at least the default one for the variables it
+// requires (as missing) or forbids
+func (c *Config) SeverityFor(category string, key string) Severity {
+	if c == nil {
+		return DefaultSeverity(category)
+	}
+	severity := c.Severity.lookup(category, key)
+	if severity == "" {
+		severity = DefaultSeverity(category)
+	}
+	if c.Policy != nil {
+		floor := c.Policy.Severity.lookup(category, key)
+		if floor == "" && c.Policy.pins(category, key) {
+			floor = DefaultSeverity(category)
+		}
+		if floor.Rank() > severity.Rank() {
+			severity = floor
+		}
+	}
+	return severity
+}
+
+// lookup returns the severity configured for a finding for key in the given category, or "" if none is
+func (s SeverityConfig) lookup(category string, key string) Severity {
+	if severity, ok := s.Variables[key]; ok {
+		return severity
+	}
+
+	bestPattern := ""
+	var bestSeverity Severity
+	for pattern, severity := range s.Variables {
+		if matched, _ := path.Match(pattern, key); matched && len(pattern) > len(bestPattern) {
+			bestPattern = pattern
+			bestSeverity = severity
+		}
+	}
+	if bestPattern != "" {
+		return bestSeverity
+	}
+
+	switch category {
+	case CategoryMissing:
+		return s.Missing
+	case CategoryUnused:
+		return s.Unused
+	case CategoryDynamic:
+		return s.Dynamic
+	case CategoryOptional:
+		return s.Optional
+	case CategoryTest:
+		return s.Test
+	case CategoryUndocumented:
+		return s.Undocumented
+	case CategoryStale:
+		return s.Stale
+	case CategoryUnread:
+		return s.Unread
+	case CategoryUndeclared:
+		return s.Undeclared
+	case CategoryUnprefixed:
+		return s.Unprefixed
+	case CategoryExposed:
+		return s.Exposed
+	case CategoryStyle:
+		return s.Style
+	case CategoryDeprecated:
+		return s.Deprecated
+	case CategoryType:
+		return s.Type
+	case CategoryPlaceholder:
+		return s.Placeholder
+	case CategoryReference:
+		return s.Reference
+	case CategoryForbidden:
+		return s.Forbidden
+	}
+	return ""
+}
+
+// normalize validates severity names and lower-cases them
+func (s *SeverityConfig) normalize() error {
+	for _, field := range []*Severity{&s.Missing, &s.Unused, &s.Dynamic, &s.Optional, &s.Test, &s.Undocumented, &s.Stale, &s.Unread, &s.Undeclared, &s.Unprefixed, &s.Exposed, &s.Style, &s.Deprecated, &s.Type, &s.Placeholder, &s.Reference, &s.Forbidden} {
+		if *field == "" {
+			continue
+		}
+		severity, err := ParseSeverity(string(*field))
+		if err != nil {
+			return err
+		}
+		*field = severity
+	}
+	for pattern, value := range s.Variables {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid severity pattern %q: %w", pattern, err)
+		}
+		severity, err := ParseSeverity(string(value))
+		if err != nil {
+			return fmt.Errorf("severity for %q: %w", pattern, err)
+		}
+		s.Variables[pattern] = severity
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSeverityFor(t *testing.T) {
+	cfg := &Config{
+		Severity: SeverityConfig{
+			Unused: SeverityInfo,
+			Variables: map[string]Severity{
+				"LEGACY_*":       SeverityWarning,
+				"LEGACY_DB_*":    SeverityInfo,
+				"LEGACY_DB_HOST": SeverityError,
+			},
+		},
+	}
+
+	tests := []struct {
+		category string
+		key      string
+		want     Severity
+	}{
+		{CategoryMissing, "API_KEY", SeverityError},
+		{CategoryDynamic, "PREFIX_", SeverityInfo},
+		{CategoryUnused, "API_KEY", SeverityInfo},
+		{CategoryMissing, "LEGACY_TOKEN", SeverityWarning},
+		{CategoryMissing, "LEGACY_DB_USER", SeverityInfo},
+		{CategoryUnused, "LEGACY_DB_HOST", SeverityError},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.SeverityFor(tt.category, tt.key); got != tt.want {
+			t.Errorf("SeverityFor(%s, %s) = %q, want %q", tt.category, tt.key, got, tt.want)
+		}
+	}
+
+	var nilConfig *Config
+	if got := nilConfig.SeverityFor(CategoryUnused, "API_KEY"); got != SeverityWarning {
+		t.Errorf("Expected default unused severity on nil config, got %q", got)
+	}
+}
+
+func TestLoadConfig_Severity(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "severity:\n  unused: Error\n  variables:\n    \"LEGACY_*\": info\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".envgrd.config"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Severity.Unused != SeverityError {
+		t.Errorf("Expected unused severity to be normalized to error, got %q", cfg.Severity.Unused)
+	}
+	if cfg.Severity.Variables["LEGACY_*"] != SeverityInfo {
+		t.Errorf("Expected LEGACY_* override, got %v", cfg.Severity.Variables)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, ".envgrd.config"), []byte("severity:\n  missing: fatal\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), "unknown severity") {
+		t.Errorf("Expected unknown severity error, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// SystemVarsConfig controls the built-in allowlist of variables provided by the OS, CI systems and runtimes
+type SystemVarsConfig struct {
+	Disabled bool     `yaml:"disabled"` // Report system variables as missing like any other variable
+	Extra    []string `yaml:"extra"`    // More names or globs to treat as system variables (e.g., "BUILDKITE_*")
+}
+
+// SystemVars are variables set by the OS, CI systems or language runtimes rather than by the project,
+// so they're never reported as missing (globs are matched with path.Match)
+var SystemVars = []string{
+	// OS and shell
+	"PATH", "HOME", "USER", "USERNAME", "LOGNAME", "SHELL", "PWD", "OLDPWD", "HOSTNAME", "TERM", "EDITOR",
+	"LANG", "LANGUAGE", "LC_*", "TZ", "TMPDIR", "TMP", "TEMP", "NO_COLOR", "XDG_*",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	// Windows
+	"APPDATA", "LOCALAPPDATA", "USERPROFILE", "PROGRAMFILES", "SYSTEMROOT", "WINDIR", "COMSPEC", "PATHEXT", "COMPUTERNAME",
+	// CI systems
+	"CI", "BUILD_NUMBER", "GITHUB_*", "RUNNER_*", "GITLAB_CI", "CI_*", "CIRCLECI", "CIRCLE_*", "TRAVIS", "TRAVIS_*",
+	"JENKINS_URL", "JENKINS_HOME", "BUILDKITE", "BUILDKITE_*", "TF_BUILD", "BITBUCKET_*", "CODEBUILD_*",
+	// Containers and orchestrators
+	"KUBERNETES_SERVICE_HOST", "KUBERNETES_SERVICE_PORT", "container",
+	// Language runtimes and toolchains
+	"NODE_ENV", "NODE_OPTIONS", "NODE_PATH", "npm_*",
+	"GOPATH", "GOROOT", "GOOS", "GOARCH", "GOFLAGS", "GOCACHE", "GOMAXPROCS", "GODEBUG", "GOPROXY",
+	"PYTHONPATH", "PYTHONUNBUFFERED", "PYTHONDONTWRITEBYTECODE", "VIRTUAL_ENV",
+	"JAVA_HOME", "JAVA_OPTS", "CARGO_*", "RUSTFLAGS", "OUT_DIR",
+}
+
+// IsSystemVar reports whether key is provided by the OS, CI or a runtime (built-in list plus system_vars.extra)
+// A nil config uses the built-in list
+func (c *Config) IsSystemVar(key string) bool {
+	patterns := SystemVars
+	if c != nil {
+		if c.SystemVars.Disabled {
+			patterns = nil
+		}
+		patterns = append(append([]string{}, patterns...), c.SystemVars.Extra...)
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that the extra patterns are valid globs
+func (s *SystemVarsConfig) validate() error {
+	for _, pattern := range s.Extra {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
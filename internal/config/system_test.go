@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestIsSystemVar(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		key  string
+		want bool
+	}{
+		{"built-in", &Config{}, "PATH", true},
+		{"built-in glob", &Config{}, "GITHUB_SHA", true},
+		{"project variable", &Config{}, "DATABASE_URL", false},
+		{"nil config uses built-ins", nil, "NODE_ENV", true},
+		{"extra", &Config{SystemVars: SystemVarsConfig{Extra: []string{"DEPLOY_*"}}}, "DEPLOY_ENV", true},
+		{"disabled", &Config{SystemVars: SystemVarsConfig{Disabled: true}}, "HOME", false},
+		{"disabled keeps extra", &Config{SystemVars: SystemVarsConfig{Disabled: true, Extra: []string{"HOME"}}}, "HOME", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.IsSystemVar(tt.key); got != tt.want {
+				t.Errorf("IsSystemVar(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// How usages in test files are treated
+const (
+	TestModeInclude = "include" // Like any other usage (default)
+	TestModeExclude = "exclude" // Not counted for missing checks
+	TestModeReport  = "report"  // Variables only used in tests are reported in their own section
+)
+
+// TestsConfig controls how usages in test files are treated
+type TestsConfig struct {
+	Mode     string   `yaml:"mode"`     // include, exclude or report (default: include)
+	Patterns []string `yaml:"patterns"` // More test file globs (e.g., "*.e2e.ts") or directory names ending in a slash (e.g., "fixtures/")
+}
+
+// defaultTestPatterns identify test files of the supported languages
+var defaultTestPatterns = []string{
+	"*_test.go",
+	"*.test.*", "*.spec.*",
+	"test_*.py", "*_test.py", "conftest.py",
+	"*Test.java", "*Tests.java",
+	"test/", "tests/", "__tests__/", "spec/",
+}
+
+// TestMode returns the configured test mode, defaulting to include
+func (c *Config) TestMode() string {
+	if c == nil || c.Tests.Mode == "" {
+		return TestModeInclude
+	}
+	return c.Tests.Mode
+}
+
+// IsTestFile reports whether a source file (relative to the scan root) is test code
+// Glob patterns match the file name or the whole path, directory patterns match any directory of the path
+func (c *Config) IsTestFile(file string) bool {
+	patterns := defaultTestPatterns
+	if c != nil {
+		patterns = append(append([]string{}, patterns...), c.Tests.Patterns...)
+	}
+
+	file = filepath.ToSlash(file)
+	dirs := strings.Split(path.Dir(file), "/")
+	for _, pattern := range patterns {
+		if dir, isDir := strings.CutSuffix(pattern, "/"); isDir {
+			for _, part := range dirs {
+				if part == dir {
+					return true
+				}
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, path.Base(file)); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, file); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the mode and the patterns
+func (t *TestsConfig) validate() error {
+	t.Mode = strings.ToLower(strings.TrimSpace(t.Mode))
+	switch t.Mode {
+	case "", TestModeInclude, TestModeExclude, TestModeReport:
+	default:
+		return fmt.Errorf("unknown mode %q (supported: include, exclude, report)", t.Mode)
+	}
+	for _, pattern := range t.Patterns {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/"), ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestIsTestFile(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		file string
+		want bool
+	}{
+		{"go test", nil, "internal/db/db_test.go", true},
+		{"go source", nil, "internal/db/db.go", false},
+		{"spec", nil, "src/app.spec.ts", true},
+		{"jest", nil, "src/app.test.js", true},
+		{"pytest", nil, "app/test_models.py", true},
+		{"junit", nil, "src/main/java/AppTest.java", true},
+		{"tests dir", nil, "tests/helpers.js", true},
+		{"nested tests dir", nil, "pkg/__tests__/helpers.js", true},
+		{"contest is not a test", nil, "src/contest.js", false},
+		{"extra glob", &Config{Tests: TestsConfig{Patterns: []string{"*.e2e.ts"}}}, "src/login.e2e.ts", true},
+		{"extra dir", &Config{Tests: TestsConfig{Patterns: []string{"fixtures/"}}}, "src/fixtures/env.js", true},
+		{"extra path glob", &Config{Tests: TestsConfig{Patterns: []string{"qa/*.go"}}}, "qa/smoke.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.IsTestFile(tt.file); got != tt.want {
+				t.Errorf("IsTestFile(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestsConfigValidate(t *testing.T) {
+	tests := TestsConfig{Mode: "Report"}
+	if err := tests.validate(); err != nil || tests.Mode != TestModeReport {
+		t.Errorf("Expected mode to be normalized, got %q, %v", tests.Mode, err)
+	}
+	tests = TestsConfig{Mode: "skip"}
+	if err := tests.validate(); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tomlTable extracts a table (e.g., tool.envgrd) and its subtables from a TOML document as nested maps
+// It supports what configs and the rest of a pyproject.toml need: tables, dotted and quoted keys,
+// single- and multi-line strings, numbers, booleans, arrays and inline tables; other values such
+// as dates are kept as strings. Returns nil if the document has no such table
+func tomlTable(data []byte, name string) (map[string]any, error) {
+	p := &tomlParser{s: string(data), line: 1}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+
+	table := root
+	for _, part := range strings.Split(name, ".") {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		table = next
+	}
+	return table, nil
+}
+
+// tomlParser is a recursive-descent parser over a TOML document
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+// parse reads the whole document into nested maps
+func (p *tomlParser) parse() (map[string]any, error) {
+	root := make(map[string]any)
+	current := root
+	for {
+		p.skipSpace(true)
+		if p.pos >= len(p.s) {
+			return root, nil
+		}
+
+		if p.s[p.pos] == '[' {
+			arrayTable := strings.HasPrefix(p.s[p.pos:], "[[")
+			if arrayTable {
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+			path, err := p.keyPath()
+			if err != nil {
+				return nil, err
+			}
+			closing := "]"
+			if arrayTable {
+				closing = "]]"
+			}
+			if !strings.HasPrefix(p.s[p.pos:], closing) {
+				return nil, fmt.Errorf("expected %s after table name", closing)
+			}
+			p.pos += len(closing)
+
+			if current, err = tableAt(root, path, arrayTable); err != nil {
+				return nil, err
+			}
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := p.keyValue(current); err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tableAt returns the table at path below root, creating it; array tables get a new element
+func tableAt(root map[string]any, path []string, arrayTable bool) (map[string]any, error) {
+	table := root
+	for i, part := range path {
+		last := i == len(path)-1
+		switch next := table[part].(type) {
+		case nil:
+			created := make(map[string]any)
+			if last && arrayTable {
+				table[part] = []any{created}
+			} else {
+				table[part] = created
+			}
+			table = created
+		case map[string]any:
+			table = next
+		case []any:
+			if len(next) == 0 {
+				return nil, fmt.Errorf("key %q is not a table", part)
+			}
+			element, ok := next[len(next)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not a table", part)
+			}
+			if last && arrayTable {
+				element = make(map[string]any)
+				table[part] = append(next, element)
+			}
+			table = element
+		default:
+			return nil, fmt.Errorf("key %q is not a table", part)
+		}
+	}
+	return table, nil
+}
+
+// keyValue reads "key = value" into table; dotted keys create subtables
+func (p *tomlParser) keyValue(table map[string]any) error {
+	path, err := p.keyPath()
+	if err != nil {
+		return err
+	}
+	p.skipSpace(false)
+	if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+		return fmt.Errorf("expected = after key %q", strings.Join(path, "."))
+	}
+	p.pos++
+	p.skipSpace(false)
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	parent, err := tableAt(table, path[:len(path)-1], false)
+	if err != nil {
+		return err
+	}
+	parent[path[len(path)-1]] = value
+	return nil
+}
+
+// keyPath reads a dotted key of bare or quoted parts
+func (p *tomlParser) keyPath() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace(false)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unexpected end of document in key")
+		}
+		var part string
+		switch p.s[p.pos] {
+		case '"', '\'':
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			part, _ = value.(string)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("invalid key character %q", p.s[p.pos])
+			}
+			part = p.s[start:p.pos]
+		}
+		path = append(path, part)
+
+		p.skipSpace(false)
+		if p.pos < len(p.s) && p.s[p.pos] == '.' {
+			p.pos++
+			continue
+		}
+		return path, nil
+	}
+}
+
+// isBareKeyChar reports whether c may appear in an unquoted key
+func isBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// value reads a string, array, inline table or scalar
+func (p *tomlParser) value() (any, error) {
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("expected a value")
+	}
+	switch {
+	case strings.HasPrefix(p.s[p.pos:], `"""`), strings.HasPrefix(p.s[p.pos:], `'''`):
+		return p.multilineString()
+	case p.s[p.pos] == '"':
+		end := p.pos + 1
+		for end < len(p.s) && p.s[end] != '"' && p.s[end] != '\n' {
+			if p.s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.s) || p.s[end] != '"' {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		value, err := strconv.Unquote(p.s[p.pos : end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", p.s[p.pos:end+1])
+		}
+		p.pos = end + 1
+		return value, nil
+	case p.s[p.pos] == '\'':
+		end := strings.IndexAny(p.s[p.pos+1:], "'\n")
+		if end < 0 || p.s[p.pos+1+end] != '\'' {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		value := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	case p.s[p.pos] == '[':
+		p.pos++
+		var values []any
+		for {
+			p.skipSpace(true)
+			if p.pos < len(p.s) && p.s[p.pos] == ']' {
+				p.pos++
+				return values, nil
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			p.skipSpace(true)
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+				return nil, fmt.Errorf("expected , or ] in array")
+			}
+		}
+	case p.s[p.pos] == '{':
+		p.pos++
+		table := make(map[string]any)
+		for {
+			p.skipSpace(false)
+			if p.pos < len(p.s) && p.s[p.pos] == '}' {
+				p.pos++
+				return table, nil
+			}
+			if err := p.keyValue(table); err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != '}' {
+				return nil, fmt.Errorf("expected , or } in inline table")
+			}
+		}
+	default:
+		end := p.pos
+		for end < len(p.s) && !strings.ContainsRune(",]}#\n", rune(p.s[end])) {
+			end++
+		}
+		raw := strings.TrimSpace(p.s[p.pos:end])
+		p.pos = end
+		return tomlScalar(raw)
+	}
+}
+
+// multilineString reads a multi-line string, delimited by three double (basic) or single (literal) quotes
+func (p *tomlParser) multilineString() (any, error) {
+	delim := p.s[p.pos : p.pos+3]
+	p.pos += 3
+	end := strings.Index(p.s[p.pos:], delim)
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated multi-line string")
+	}
+	value := strings.TrimPrefix(p.s[p.pos:p.pos+end], "\n")
+	p.line += strings.Count(p.s[p.pos:p.pos+end], "\n")
+	p.pos += end + 3
+	if delim == `"""` {
+		if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(value, "\n", `\n`) + `"`); err == nil {
+			return unquoted, nil
+		}
+	}
+	return value, nil
+}
+
+// tomlScalar converts an unquoted value: booleans and numbers, anything else (dates, times) stays a string
+func tomlScalar(raw string) (any, error) {
+	switch raw {
+	case "":
+		return nil, fmt.Errorf("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	number := strings.ReplaceAll(raw, "_", "")
+	if n, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return f, nil
+	}
+	return raw, nil
+}
+
+// skipSpace skips blanks and comments, and newlines when multiline is set
+func (p *tomlParser) skipSpace(multiline bool) {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && multiline:
+			p.pos++
+			p.line++
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine checks that nothing but a comment follows a statement
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace(false)
+	if p.pos < len(p.s) && p.s[p.pos] != '\n' {
+		return fmt.Errorf("unexpected %q after value", p.s[p.pos])
+	}
+	return nil
+}
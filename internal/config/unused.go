@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/envfile"
+)
+
+// UnusedConfig restricts which env files the unused check reports variables from
+type UnusedConfig struct {
+	Sources         []string `yaml:"sources"`          // Only report unused variables from these files (globs), empty means all
+	ExcludeSources  []string `yaml:"exclude_sources"`  // Never report unused variables from these files (globs)
+	ExcludeExamples bool     `yaml:"exclude_examples"` // Skip example files such as .env.example or env.sample
+}
+
+// ReportsUnusedFrom reports whether an unused variable defined in source should be reported
+// source is the env file path relative to the scan root; patterns without a slash also match the file name
+func (c *Config) ReportsUnusedFrom(source string) bool {
+	if c == nil || source == "" {
+		return true
+	}
+	source = filepath.ToSlash(source)
+
+	if c.Unused.ExcludeExamples && envfile.IsExampleFile(source) {
+		return false
+	}
+	if matchesSource(c.Unused.ExcludeSources, source) {
+		return false
+	}
+	if len(c.Unused.Sources) > 0 && !matchesSource(c.Unused.Sources, source) {
+		return false
+	}
+	return true
+}
+
+// matchesSource reports whether source matches any of the glob patterns
+func matchesSource(patterns []string, source string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		target := source
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(source)
+		}
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that the source patterns are valid globs
+func (u *UnusedConfig) validate() error {
+	for _, pattern := range append(append([]string{}, u.Sources...), u.ExcludeSources...) {
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("invalid source pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportsUnusedFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		unused UnusedConfig
+		source string
+		want   bool
+	}{
+		{"no filters", UnusedConfig{}, "docker-compose.yml", true},
+		{"unknown source", UnusedConfig{Sources: []string{".env"}}, "", true},
+		{"allowed source", UnusedConfig{Sources: []string{".env", ".env.*"}}, ".env.local", true},
+		{"source not allowed", UnusedConfig{Sources: []string{".env"}}, "docker-compose.yml", false},
+		{"excluded by name", UnusedConfig{ExcludeSources: []string{"docker-compose*.yml"}}, "docker-compose.prod.yml", false},
+		{"excluded by path", UnusedConfig{ExcludeSources: []string{"k8s/*"}}, "k8s/deployment.yaml", false},
+		{"path pattern needs the directory", UnusedConfig{ExcludeSources: []string{"k8s/*"}}, "deployment.yaml", true},
+		{"exclude wins over sources", UnusedConfig{Sources: []string{".env*"}, ExcludeSources: []string{".env.ci"}}, ".env.ci", false},
+		{"example excluded", UnusedConfig{ExcludeExamples: true}, ".env.example", false},
+		{"sample excluded", UnusedConfig{ExcludeExamples: true}, "config/env.sample", false},
+		{"examples kept by default", UnusedConfig{}, ".env.example", true},
+		{"not an example", UnusedConfig{ExcludeExamples: true}, ".env.production", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Unused: tt.unused}
+			if got := cfg.ReportsUnusedFrom(tt.source); got != tt.want {
+				t.Errorf("ReportsUnusedFrom(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+
+	var nilConfig *Config
+	if !nilConfig.ReportsUnusedFrom(".env") {
+		t.Error("Expected nil config to report unused variables from every source")
+	}
+}
+
+func TestLoadConfig_Unused(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "unused:\n  sources:\n    - .env\n  exclude_sources:\n    - k8s/*\n  exclude_examples: true\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".envgrd.config"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Unused.Sources) != 1 || len(cfg.Unused.ExcludeSources) != 1 || !cfg.Unused.ExcludeExamples {
+		t.Errorf("Unexpected unused config: %+v", cfg.Unused)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, ".envgrd.config"), []byte("unused:\n  sources:\n    - \"[\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := LoadConfig(tmpDir); err == nil || !strings.Contains(err.Error(), "invalid unused config") {
+		t.Errorf("Expected invalid unused config error, got %v", err)
+	}
+}
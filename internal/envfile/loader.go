@@ -2,16 +2,61 @@ package envfile
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+
+	"github.com/jenian/envgrd/internal/charset"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/owners"
 )
 
+// ExportedSource names the exported shell environment in a precedence list
+const ExportedSource = "exported"
+
+// ExportedSourceFile is recorded as the source of variables whose exported value wins over env files
+const ExportedSourceFile = "exported environment"
+
+// SourceKinds lists the env source kinds that can appear in a precedence list
+var SourceKinds = []string{"env", "envrc", "docker-compose", "k8s", "systemd", "shell", "next-config", "pm2", "nodemon", "ansible", "cloud-run", "env-yaml", "azure-pipelines", "azure-app-settings", ExportedSource}
+
 // Loader handles loading and parsing environment files
 type Loader struct {
 	envFiles   []string
 	autoDetect bool
+	logger     *slog.Logger
+	precedence []string // Source kinds, highest precedence first (nil keeps load order)
+	exclude    []*regexp.Regexp
+}
+
+// Location is where an environment variable is defined
+type Location struct {
+	File string // Path of the env file
+	Line int    // 1-based line of the definition, 0 if unknown
+	Kind string // Source kind of the file (see SourceKinds)
+	// Environment is the environment block the definition belongs to (production for the env_production
+	// block of a PM2 ecosystem file), empty for the default environment and files without blocks
+	Environment string
+	// Service is the docker-compose service whose environment block holds the definition, empty for
+	// files without services
+	Service string
+	// ServiceDir is the absolute directory the service is built from (its build context), empty when unknown
+	ServiceDir string
+}
+
+// Definition is a single definition of an environment variable in an env file
+type Definition struct {
+	Location
+	Value      string
+	Unresolved []string `json:",omitempty"` // Variables of ${VAR} references in the value that can't be resolved, see Resolve
 }
 
 // EnvVarWithSource represents an environment variable with its source file
@@ -25,14 +70,42 @@ func NewLoader() *Loader {
 	return &Loader{
 		envFiles:   []string{".env", ".env.local", "env.example"},
 		autoDetect: true,
+		logger:     logging.Discard(),
 	}
 }
 
+// SetLogger sets the logger used to report loaded and unreadable env files
+func (l *Loader) SetLogger(logger *slog.Logger) {
+	l.logger = logging.OrDiscard(logger)
+}
+
 // SetAutoDetect enables or disables automatic detection of env files
 func (l *Loader) SetAutoDetect(enabled bool) {
 	l.autoDetect = enabled
 }
 
+// SetPrecedence sets which source kinds win when a variable is defined in several sources, highest first
+// Kinds that aren't listed rank below listed ones; within a kind, later files override earlier ones
+// Without a precedence list, files override each other in load order and exported variables never win
+func (l *Loader) SetPrecedence(kinds []string) {
+	l.precedence = kinds
+}
+
+// rank orders source kinds by precedence, higher ranks override lower ones
+func (l *Loader) rank(kind string) int {
+	for i, k := range l.precedence {
+		if k == kind {
+			return len(l.precedence) - i
+		}
+	}
+	return 0
+}
+
+// ExportedWins reports whether an exported variable overrides a definition from a file of the given kind
+func (l *Loader) ExportedWins(kind string) bool {
+	return l.rank(ExportedSource) > l.rank(kind)
+}
+
 // AddEnvFile adds a custom env file to load
 func (l *Loader) AddEnvFile(path string) {
 	l.envFiles = append(l.envFiles, path)
@@ -43,8 +116,86 @@ func (l *Loader) SetEnvFiles(files []string) {
 	l.envFiles = files
 }
 
+// SetExclude sets gitignore-style globs of env files to skip (e.g., ".env.test"), matched against their
+// path relative to the loaded directory (the name of files outside it), see owners.CompilePattern
+func (l *Loader) SetExclude(patterns []string) error {
+	l.exclude = nil
+	for _, pattern := range patterns {
+		re, err := owners.CompilePattern(filepath.ToSlash(pattern))
+		if err != nil {
+			return fmt.Errorf("bad env file glob %q: %w", pattern, err)
+		}
+		l.exclude = append(l.exclude, re)
+	}
+	return nil
+}
+
+// excluded reports whether the env file at path matches a glob of SetExclude
+func (l *Loader) excluded(rootPath, path string) bool {
+	if len(l.exclude) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(rootPath, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(path)
+	}
+	rel = filepath.ToSlash(rel)
+	for _, re := range l.exclude {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFile parses a single environment file of any supported kind, returning its variables and the
+// line each one is defined on
+func ParseFile(path string) (map[string]string, map[string]int, error) {
+	return parseEnvFileWithLines(path)
+}
+
+// Comments returns the comment lines (# ...) directly above the line of each key, without their markers,
+// e.g. the description of a variable in a .env file or a compose file
+func Comments(content []byte, lines map[string]int) map[string]string {
+	fileLines := strings.Split(string(content), "\n")
+	comments := make(map[string]string)
+	for key, line := range lines {
+		var comment []string
+		for i := line - 2; i >= 0 && i < len(fileLines); i-- {
+			text := strings.TrimSpace(fileLines[i])
+			if !strings.HasPrefix(text, "#") || strings.HasPrefix(text, "#!") {
+				break
+			}
+			comment = append([]string{strings.TrimSpace(strings.TrimPrefix(text, "#"))}, comment...)
+		}
+		if len(comment) > 0 {
+			comments[key] = strings.Join(comment, "\n")
+		}
+	}
+	return comments
+}
+
+// FileComments returns the comments above the keys of an env file (see Comments)
+func FileComments(path string) (map[string]string, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, lines, err := parseEnvFileWithLines(path)
+	if err != nil {
+		return nil, err
+	}
+	return Comments(content, lines), nil
+}
+
 // parseEnvFile parses a single environment file using the appropriate parser
 func parseEnvFile(path string) (map[string]string, error) {
+	vars, _, err := parseEnvFileWithLines(path)
+	return vars, err
+}
+
+// parseEnvFileWithLines is like parseEnvFile but also returns the line each key is defined on
+func parseEnvFileWithLines(path string) (map[string]string, map[string]int, error) {
 	fileType := detectFileType(path)
 
 	switch fileType {
@@ -58,6 +209,20 @@ func parseEnvFile(path string) (map[string]string, error) {
 		return parseSystemd(path)
 	case "shell":
 		return parseShellScript(path)
+	case "next-config":
+		return parseNextConfig(path)
+	case "pm2", "nodemon":
+		return parseEnvBlocksWithLines(path)
+	case "ansible":
+		return parseAnsible(path)
+	case "cloud-run":
+		return parseCloudRun(path)
+	case "env-yaml":
+		return parseEnvYAML(path)
+	case "azure-pipelines":
+		return parseAzurePipelines(path)
+	case "azure-app-settings":
+		return parseAzureAppSettings(path)
 	case "env":
 		fallthrough
 	default:
@@ -65,17 +230,62 @@ func parseEnvFile(path string) (map[string]string, error) {
 	}
 }
 
+// readFile reads an env file transcoded to UTF-8 with LF line endings, so files saved as UTF-16 (with
+// a BOM, as Windows editors do), Latin-1 or with CRLF line endings parse like UTF-8 ones
+func readFile(path string) ([]byte, error) {
+	content, _, err := charset.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), nil
+}
+
+// openFile is like readFile for parsers reading line by line
+func openFile(path string) (io.ReadCloser, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// parseDefinitions parses a single environment file into the definitions of each key, in file order
+// Only files with environment blocks (PM2, nodemon), services or containers can define a key more than once
+func parseDefinitions(path string) (map[string][]Definition, error) {
+	kind := detectFileType(path)
+	if kind == "pm2" || kind == "nodemon" {
+		return parseEnvBlocks(path)
+	}
+	if kind == "docker-compose" {
+		return parseComposeServices(path)
+	}
+	if kind == "k8s" {
+		return parseK8sDefinitions(path)
+	}
+
+	vars, lines, err := parseEnvFileWithLines(path)
+	if err != nil {
+		return nil, err
+	}
+	definitions := make(map[string][]Definition, len(vars))
+	for k, v := range vars {
+		definitions[k] = []Definition{{Location: Location{File: path, Line: lines[k], Kind: kind}, Value: v}}
+	}
+	return definitions, nil
+}
+
 // parseDotEnv parses a standard .env file
-func parseDotEnv(path string) (map[string]string, error) {
+func parseDotEnv(path string) (map[string]string, map[string]int, error) {
 	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
 
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		// File doesn't exist, return empty map (not an error)
 		if os.IsNotExist(err) {
-			return vars, nil
+			return vars, lines, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
@@ -111,14 +321,15 @@ func parseDotEnv(path string) (map[string]string, error) {
 
 		if key != "" {
 			vars[key] = value
+			lines[key] = lineNum
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading %s: %w", path, err)
+		return nil, nil, fmt.Errorf("error reading %s: %w", path, err)
 	}
 
-	return vars, nil
+	return vars, lines, nil
 }
 
 // findEnvFiles finds all environment variable files in the directory
@@ -161,8 +372,9 @@ func (l *Loader) findEnvFiles(rootPath string) ([]string, error) {
 			case "envrc":
 				shouldInclude = true
 			case "env":
-				// Include .env.* files (but not ones already in default list)
-				if strings.HasPrefix(name, ".env") {
+				// Include .env.* files (but not ones already in default list, or envgrd's own
+				// .envgrd.config and .envgrd.state files)
+				if strings.HasPrefix(name, ".env") && !strings.HasPrefix(name, ".envgrd.") {
 					// Skip if already in default list
 					alreadyInDefault := false
 					for _, defaultFile := range []string{".env", ".env.local", "env.example"} {
@@ -181,6 +393,9 @@ func (l *Loader) findEnvFiles(rootPath string) ([]string, error) {
 				shouldInclude = true
 			case "systemd":
 				shouldInclude = true
+			case "next-config", "pm2", "nodemon", "ansible", "cloud-run", "env-yaml",
+				"azure-pipelines", "azure-app-settings":
+				shouldInclude = true
 			case "shell":
 				// Include .sh and .bash files
 				if strings.HasSuffix(name, ".sh") || strings.HasSuffix(name, ".bash") {
@@ -202,11 +417,33 @@ func (l *Loader) findEnvFiles(rootPath string) ([]string, error) {
 				}
 			}
 		}
+
+		// Ansible playbooks and role tasks live in subdirectories
+		for _, pattern := range ansiblePatterns {
+			matches, _ := filepath.Glob(filepath.Join(rootPath, pattern))
+			for _, path := range matches {
+				if detectFileType(path) == "ansible" && !slices.Contains(files, path) {
+					files = append(files, path)
+				}
+			}
+		}
 	}
 
+	if len(l.exclude) > 0 {
+		files = slices.DeleteFunc(files, func(path string) bool {
+			if l.excluded(rootPath, path) {
+				l.logger.Debug("skipping excluded env file", "file", path)
+				return true
+			}
+			return false
+		})
+	}
 	return files, nil
 }
 
+// ansiblePatterns locate the Ansible files below the root directory that can set environment variables
+var ansiblePatterns = []string{"playbooks/*.yml", "playbooks/*.yaml", "roles/*/tasks/*.yml", "roles/*/tasks/*.yaml", "roles/*/handlers/*.yml", "roles/*/handlers/*.yaml"}
+
 // Load loads all configured env files and merges them
 // Later files override earlier ones
 func (l *Loader) Load(rootPath string) (map[string]string, error) {
@@ -217,32 +454,116 @@ func (l *Loader) Load(rootPath string) (map[string]string, error) {
 // LoadWithSources loads all configured env files and tracks which file each variable came from
 // Later files override earlier ones, but we track the source file for each variable
 func (l *Loader) LoadWithSources(rootPath string) (map[string]string, map[string]string, error) {
-	allVars := make(map[string]string)
-	sourceMap := make(map[string]string) // Maps variable key to source file path
+	return l.LoadWithSourcesContext(context.Background(), rootPath)
+}
 
-	// Find all env files (explicit + auto-detected)
-	envFiles, err := l.findEnvFiles(rootPath)
+// LoadWithSourcesContext is like LoadWithSources but stops loading files once ctx is cancelled
+func (l *Loader) LoadWithSourcesContext(ctx context.Context, rootPath string) (map[string]string, map[string]string, error) {
+	allVars, locations, err := l.LoadWithLocationsContext(ctx, rootPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return allVars, sourceFiles(locations), nil
+}
+
+// LoadWithLocationsContext loads all configured env files and tracks the file and line each variable is defined on
+// Later files override earlier ones, including the location
+func (l *Loader) LoadWithLocationsContext(ctx context.Context, rootPath string) (map[string]string, map[string]Location, error) {
+	definitions, err := l.LoadDefinitionsContext(ctx, rootPath)
 	if err != nil {
 		return nil, nil, err
 	}
+	allVars, locations := Effective(definitions)
+	return allVars, locations, nil
+}
+
+// LoadDefinitionsContext loads all configured env files and returns every definition of each variable,
+// in load order, so the last definition is the one that takes effect
+// References to other variables in the values are resolved (see Resolve)
+func (l *Loader) LoadDefinitionsContext(ctx context.Context, rootPath string) (map[string][]Definition, error) {
+	envFiles, err := l.Files(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := l.loadDefinitions(ctx, envFiles)
+	if err != nil {
+		return nil, err
+	}
+	return Resolve(definitions), nil
+}
 
+// Files returns the env files loaded from rootPath (explicit and auto-detected ones), in load order
+func (l *Loader) Files(rootPath string) ([]string, error) {
+	envFiles, err := l.findEnvFiles(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load lower precedence files first so higher precedence ones override them
+	sort.SliceStable(envFiles, func(i, j int) bool {
+		return l.rank(detectFileType(envFiles[i])) < l.rank(detectFileType(envFiles[j]))
+	})
+	return envFiles, nil
+}
+
+// loadDefinitions parses envFiles in order and returns every definition of each variable
+func (l *Loader) loadDefinitions(ctx context.Context, envFiles []string) (map[string][]Definition, error) {
+	definitions := make(map[string][]Definition)
 	for _, path := range envFiles {
-		vars, err := parseEnvFile(path)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fileDefinitions, err := parseDefinitions(path)
 		if err != nil {
 			// Log error but continue with other files
+			l.logger.Warn("failed to parse env file", "file", path, "error", err)
 			continue
 		}
+		l.logger.Debug("loaded env file", "file", path, "type", detectFileType(path), "vars", len(fileDefinitions))
 
-		// Merge: later files override earlier ones
-		// Track source file for each variable (only update if not already set, or if this file overrides)
-		for k, v := range vars {
-			allVars[k] = v
-			// Update source file - later files override earlier ones
-			sourceMap[k] = path
+		for k, defs := range fileDefinitions {
+			definitions[k] = append(definitions[k], defs...)
 		}
 	}
 
-	return allVars, sourceMap, nil
+	return definitions, nil
+}
+
+// Effective merges definitions into the value and location of each variable: later files override earlier ones,
+// and definitions for the default environment override environment-specific ones
+func Effective(definitions map[string][]Definition) (map[string]string, map[string]Location) {
+	allVars := make(map[string]string, len(definitions))
+	locations := make(map[string]Location, len(definitions))
+	for k, defs := range definitions {
+		if len(defs) == 0 {
+			continue
+		}
+		def := effectiveDefinition(defs)
+		allVars[k] = def.Value
+		locations[k] = def.Location
+	}
+	return allVars, locations
+}
+
+// effectiveDefinition returns the definition that takes effect: the last one for the default environment,
+// or the last one if every definition is environment-specific
+func effectiveDefinition(defs []Definition) Definition {
+	for i := len(defs) - 1; i >= 0; i-- {
+		if defs[i].Environment == "" {
+			return defs[i]
+		}
+	}
+	return defs[len(defs)-1]
+}
+
+// sourceFiles reduces locations to the source file of each variable
+func sourceFiles(locations map[string]Location) map[string]string {
+	sourceMap := make(map[string]string, len(locations))
+	for k, location := range locations {
+		sourceMap[k] = location.File
+	}
+	return sourceMap
 }
 
 // LoadFromPath loads env files from a specific directory
@@ -261,8 +582,23 @@ func (l *Loader) LoadFromPathWithSources(dirPath string) (map[string]string, map
 //   - fileVarsOnly: Only vars from files (for unused check)
 //   - sourceMap: Maps variable key to source file path
 func (l *Loader) LoadWithExportedEnv(rootPath string) (map[string]string, map[string]string, map[string]string, error) {
-	// Load from files with source tracking
-	fileVars, sourceMap, err := l.LoadWithSources(rootPath)
+	return l.LoadWithExportedEnvContext(context.Background(), rootPath)
+}
+
+// LoadWithExportedEnvContext is like LoadWithExportedEnv but stops loading files once ctx is cancelled
+func (l *Loader) LoadWithExportedEnvContext(ctx context.Context, rootPath string) (map[string]string, map[string]string, map[string]string, error) {
+	allVars, fileVarsOnly, locations, err := l.LoadWithExportedEnvLocationsContext(ctx, rootPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return allVars, fileVarsOnly, sourceFiles(locations), nil
+}
+
+// LoadWithExportedEnvLocationsContext is like LoadWithExportedEnvContext but returns the location of each
+// variable's definition instead of only its source file
+func (l *Loader) LoadWithExportedEnvLocationsContext(ctx context.Context, rootPath string) (map[string]string, map[string]string, map[string]Location, error) {
+	// Load from files with location tracking
+	fileVars, locations, err := l.LoadWithLocationsContext(ctx, rootPath)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -273,8 +609,13 @@ func (l *Loader) LoadWithExportedEnv(rootPath string) (map[string]string, map[st
 		fileVarsOnly[k] = v
 	}
 
-	// Merge with exported environment variables
-	// This prevents false positives when vars are set via shell exports or CI/CD
+	return WithExportedEnv(fileVars), fileVarsOnly, locations, nil
+}
+
+// WithExportedEnv merges exported environment variables into the variables loaded from files
+// This prevents false positives when vars are set via shell exports or CI/CD
+// Env files take precedence for values, and exported values are never stored (for security)
+func WithExportedEnv(fileVars map[string]string) map[string]string {
 	allVars := make(map[string]string)
 	// Start with .env file vars
 	for k, v := range fileVars {
@@ -292,6 +633,5 @@ func (l *Loader) LoadWithExportedEnv(rootPath string) (map[string]string, map[st
 			}
 		}
 	}
-
-	return allVars, fileVarsOnly, sourceMap, nil
+	return allVars
 }
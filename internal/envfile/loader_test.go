@@ -1,9 +1,13 @@
 package envfile
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/jenian/envgrd/internal/charset"
 )
 
 func TestParseEnvFile(t *testing.T) {
@@ -61,6 +65,73 @@ func TestParseEnvFile_NonExistent(t *testing.T) {
 	}
 }
 
+func TestParseEnvFile_Encodings(t *testing.T) {
+	tmpDir := t.TempDir()
+	utf16 := []byte{0xff, 0xfe}
+	for _, c := range []byte("API_KEY=abc\r\nNAME=x\r\n") {
+		utf16 = append(utf16, c, 0)
+	}
+	files := map[string][]byte{
+		".env":        utf16,
+		".env.latin1": []byte("NAME=caf\xe9\n"),
+		".env.binary": {'K', '=', 0xff, 0x01, 0x02},
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	vars, lines, err := ParseFile(filepath.Join(tmpDir, ".env"))
+	if err != nil {
+		t.Fatalf("Failed to parse UTF-16 .env file: %v", err)
+	}
+	if vars["API_KEY"] != "abc" || vars["NAME"] != "x" || lines["NAME"] != 2 {
+		t.Errorf("Unexpected variables of the UTF-16 file: %v %v", vars, lines)
+	}
+
+	vars, _, err = ParseFile(filepath.Join(tmpDir, ".env.latin1"))
+	if err != nil {
+		t.Fatalf("Failed to parse Latin-1 .env file: %v", err)
+	}
+	if vars["NAME"] != "café" {
+		t.Errorf("Expected the Latin-1 value to be transcoded, got %q", vars["NAME"])
+	}
+
+	if _, _, err := ParseFile(filepath.Join(tmpDir, ".env.binary")); !errors.Is(err, charset.ErrUndecodable) {
+		t.Errorf("Expected an undecodable file error, got %v", err)
+	}
+}
+
+func TestParseEnvFile_CRLF(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		".env":           "API_KEY=abc\r\nNAME=\"x y\"\r\n",
+		"next.config.js": "module.exports = {\r\n  env: {\r\n    API_URL: process.env.API_URL ||\r\n      'http://localhost',\r\n  },\r\n}\r\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	vars, lines, err := ParseFile(filepath.Join(tmpDir, ".env"))
+	if err != nil {
+		t.Fatalf("Failed to parse .env: %v", err)
+	}
+	if vars["API_KEY"] != "abc" || vars["NAME"] != "x y" || lines["NAME"] != 2 {
+		t.Errorf("Unexpected variables of the CRLF .env file: %q %v", vars, lines)
+	}
+
+	vars, lines, err = ParseFile(filepath.Join(tmpDir, "next.config.js"))
+	if err != nil {
+		t.Fatalf("Failed to parse next.config.js: %v", err)
+	}
+	if want := "process.env.API_URL ||\n      'http://localhost'"; vars["API_URL"] != want || lines["API_URL"] != 3 {
+		t.Errorf("Expected API_URL = %q on line 3, got %q on line %d", want, vars["API_URL"], lines["API_URL"])
+	}
+}
+
 func TestLoader_Load(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -98,3 +169,542 @@ func TestLoader_Load(t *testing.T) {
 	}
 }
 
+func TestLoader_LoadWithLocations(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		".env":       "# comment\n\nKEY1=value1\nKEY2=value2\n",
+		".env.local": "KEY2=overridden\n",
+		"docker-compose.yml": `services:
+  web:
+    environment:
+      COMPOSE_MAP: one
+  worker:
+    environment:
+      - COMPOSE_LIST=two
+`,
+		"configmap.yaml": "apiVersion: v1\nkind: ConfigMap\ndata:\n  K8S_KEY: value\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	loader := NewLoader()
+	_, locations, err := loader.LoadWithLocationsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+
+	expected := map[string]Location{
+		"KEY1":         {File: filepath.Join(tmpDir, ".env"), Line: 3, Kind: "env"},
+		"KEY2":         {File: filepath.Join(tmpDir, ".env.local"), Line: 1, Kind: "env"},
+		"COMPOSE_MAP":  {File: filepath.Join(tmpDir, "docker-compose.yml"), Line: 4, Kind: "docker-compose", Service: "web"},
+		"COMPOSE_LIST": {File: filepath.Join(tmpDir, "docker-compose.yml"), Line: 7, Kind: "docker-compose", Service: "worker"},
+		"K8S_KEY":      {File: filepath.Join(tmpDir, "configmap.yaml"), Line: 4, Kind: "k8s"},
+	}
+	for key, want := range expected {
+		if got := locations[key]; got != want {
+			t.Errorf("%s: expected %+v, got %+v", key, want, got)
+		}
+	}
+}
+
+func TestLoader_LoadDefinitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("KEY=one\nOTHER=x\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env.local"), []byte("KEY=two\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env.local file: %v", err)
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+
+	defs := definitions["KEY"]
+	if len(defs) != 2 || defs[0].Value != "one" || defs[1].Value != "two" || defs[1].Line != 1 {
+		t.Errorf("Unexpected definitions for KEY: %+v", defs)
+	}
+
+	vars, _ := Effective(definitions)
+	if vars["KEY"] != "two" || vars["OTHER"] != "x" {
+		t.Errorf("Unexpected effective values: %v", vars)
+	}
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("KEY=from-env\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	compose := "services:\n  web:\n    environment:\n      KEY: from-compose\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatalf("Failed to write docker-compose.yml: %v", err)
+	}
+
+	loader := NewLoader()
+	vars, _, err := loader.LoadWithLocationsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	if vars["KEY"] != "from-compose" {
+		t.Errorf("Expected the last loaded file to win by default, got %s", vars["KEY"])
+	}
+	if loader.ExportedWins("env") {
+		t.Error("Expected env files to win over exported variables by default")
+	}
+
+	loader.SetPrecedence([]string{"exported", "env"})
+	vars, locations, err := loader.LoadWithLocationsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	if vars["KEY"] != "from-env" || locations["KEY"].Kind != "env" {
+		t.Errorf("Expected .env to win over unlisted docker-compose, got %s from %+v", vars["KEY"], locations["KEY"])
+	}
+	if !loader.ExportedWins("env") {
+		t.Error("Expected exported variables to win over env files")
+	}
+}
+
+func TestLoader_NextConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "module.exports = {\n  env: {\n    API_URL: 'https://api.example.com',\n    STRIPE_KEY: process.env.STRIPE_KEY,\n  },\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "next.config.js"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write next.config.js: %v", err)
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	if defs := definitions["API_URL"]; len(defs) != 1 || defs[0].Value != "https://api.example.com" || defs[0].Line != 3 || defs[0].Kind != "next-config" {
+		t.Errorf("Unexpected definitions for API_URL: %+v", defs)
+	}
+	if defs := definitions["STRIPE_KEY"]; len(defs) != 1 || defs[0].Value != "process.env.STRIPE_KEY" {
+		t.Errorf("Unexpected definitions for STRIPE_KEY: %+v", defs)
+	}
+}
+
+func TestLoader_EnvBlocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	ecosystem := `module.exports = {
+  apps: [{
+    name: 'api',
+    script: './server.js',
+    env: {
+      PORT: 3000,
+      LOG_LEVEL: 'debug',
+    },
+    env_production: {
+      LOG_LEVEL: 'warn',
+      SENTRY_DSN: 'https://sentry.example.com/1',
+    },
+  }],
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "ecosystem.config.js"), []byte(ecosystem), 0644); err != nil {
+		t.Fatalf("Failed to write ecosystem.config.js: %v", err)
+	}
+	nodemon := "{\n  \"watch\": [\"src\"],\n  \"env\": {\n    \"NODE_ENV\": \"development\"\n  }\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "nodemon.json"), []byte(nodemon), 0644); err != nil {
+		t.Fatalf("Failed to write nodemon.json: %v", err)
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	defs := definitions["LOG_LEVEL"]
+	if len(defs) != 2 || defs[0].Environment != "" || defs[0].Line != 7 || defs[1].Environment != "production" || defs[1].Value != "warn" || defs[1].Kind != "pm2" {
+		t.Errorf("Unexpected definitions for LOG_LEVEL: %+v", defs)
+	}
+	if defs := definitions["NODE_ENV"]; len(defs) != 1 || defs[0].Value != "development" || defs[0].Line != 4 || defs[0].Kind != "nodemon" {
+		t.Errorf("Unexpected definitions for NODE_ENV: %+v", defs)
+	}
+
+	// The default env block takes effect, environment-specific keys are still defined
+	vars, locations := Effective(definitions)
+	if vars["LOG_LEVEL"] != "debug" || vars["PORT"] != "3000" || locations["SENTRY_DSN"].Environment != "production" {
+		t.Errorf("Unexpected effective values: %v", vars)
+	}
+}
+
+func TestLoader_Ansible(t *testing.T) {
+	tmpDir := t.TempDir()
+	tasks := filepath.Join(tmpDir, "roles", "app", "tasks")
+	if err := os.MkdirAll(tasks, 0755); err != nil {
+		t.Fatalf("Failed to create roles directory: %v", err)
+	}
+	content := "- name: Start app\n  command: ./app\n  environment:\n    APP_PORT: \"8080\"\n"
+	if err := os.WriteFile(filepath.Join(tasks, "main.yml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write main.yml: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "site.yml"), []byte("- hosts: all\n  environment:\n    APP_ENV: staging\n"), 0644); err != nil {
+		t.Fatalf("Failed to write site.yml: %v", err)
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	if defs := definitions["APP_PORT"]; len(defs) != 1 || defs[0].Value != "8080" || defs[0].Line != 4 || defs[0].Kind != "ansible" {
+		t.Errorf("Unexpected definitions for APP_PORT: %+v", defs)
+	}
+	if defs := definitions["APP_ENV"]; len(defs) != 1 || defs[0].Value != "staging" || defs[0].Line != 3 {
+		t.Errorf("Unexpected definitions for APP_ENV: %+v", defs)
+	}
+}
+
+func TestLoader_Exclude(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		".env":                  "API_KEY=abc\n",
+		".env.test":             "TEST_KEY=abc\n",
+		".env.staging":          "STAGING_KEY=abc\n",
+		"playbooks/deploy.yml":  "- hosts: all\n  environment:\n    DEPLOY_ENV: prod\n",
+		"roles/app/tasks/a.yml": "- name: Start\n  environment:\n    ROLE_KEY: x\n",
+	} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	loader := NewLoader()
+	if err := loader.SetExclude([]string{".env.test", "roles/**"}); err != nil {
+		t.Fatalf("SetExclude failed: %v", err)
+	}
+	vars, err := loader.Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	for key, want := range map[string]bool{"API_KEY": true, "STAGING_KEY": true, "DEPLOY_ENV": true, "TEST_KEY": false, "ROLE_KEY": false} {
+		if _, ok := vars[key]; ok != want {
+			t.Errorf("Expected %s to be loaded: %v, got %v", key, want, vars)
+		}
+	}
+
+	if err := loader.SetExclude([]string{"[a-"}); err == nil {
+		t.Error("Expected an error for a malformed glob")
+	}
+}
+
+func TestLoader_ComposeServices(t *testing.T) {
+	tmpDir := t.TempDir()
+	compose := `x-common: &common
+  LOG_LEVEL: info
+services:
+  api:
+    build: ./services/api
+    environment:
+      <<: *common
+      API_PORT: 8080
+      PASSTHROUGH:
+  worker:
+    build:
+      context: services/worker
+    environment:
+      - QUEUE_URL=redis://queue
+      - LOG_LEVEL=debug
+  cache:
+    image: redis
+    environment:
+      CACHE_SIZE: "64"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatalf("Failed to write docker-compose.yml: %v", err)
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	tests := []struct {
+		key     string
+		values  []string
+		service string
+		dir     string
+	}{
+		{"API_PORT", []string{"8080"}, "api", filepath.Join(tmpDir, "services", "api")},
+		{"PASSTHROUGH", []string{""}, "api", filepath.Join(tmpDir, "services", "api")},
+		{"LOG_LEVEL", []string{"info", "debug"}, "api", filepath.Join(tmpDir, "services", "api")},
+		{"QUEUE_URL", []string{"redis://queue"}, "worker", filepath.Join(tmpDir, "services", "worker")},
+		{"CACHE_SIZE", []string{"64"}, "cache", ""},
+	}
+	for _, tt := range tests {
+		defs := definitions[tt.key]
+		if len(defs) != len(tt.values) || defs[0].Service != tt.service || defs[0].ServiceDir != tt.dir {
+			t.Errorf("%s: expected %d definitions of service %s in %q, got %+v", tt.key, len(tt.values), tt.service, tt.dir, defs)
+			continue
+		}
+		for i, value := range tt.values {
+			if defs[i].Value != value {
+				t.Errorf("%s: expected value %q, got %q", tt.key, value, defs[i].Value)
+			}
+		}
+	}
+	if defs := definitions["LOG_LEVEL"]; len(defs) == 2 && (defs[0].Line != 2 || defs[1].Service != "worker") {
+		t.Errorf("Expected LOG_LEVEL to come from the anchor and then from worker, got %+v", defs)
+	}
+}
+
+func TestLoader_CloudRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	service := `apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+        - image: gcr.io/project/api
+          env:
+            - name: LOG_LEVEL
+              value: info
+            - name: DB_PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: db-password
+                  key: latest
+`
+	job := `apiVersion: run.googleapis.com/v1
+kind: Job
+spec:
+  template:
+    spec:
+      template:
+        spec:
+          containers:
+            - env:
+                - name: BATCH_SIZE
+                  value: "100"
+`
+	files := map[string]string{
+		"service.yaml":     service,
+		"cloudrun-job.yml": job,
+		".env.yaml":        "API_URL: https://api.example.com\nRETRIES: 3\n",
+		// Not a Cloud Run manifest
+		"cloud-run.yaml": "apiVersion: v1\nkind: Service\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	if defs := definitions["LOG_LEVEL"]; len(defs) != 1 || defs[0].Value != "info" || defs[0].Line != 11 || defs[0].Kind != "cloud-run" {
+		t.Errorf("Unexpected definitions for LOG_LEVEL: %+v", defs)
+	}
+	if defs := definitions["DB_PASSWORD"]; len(defs) != 1 || defs[0].Value != "" {
+		t.Errorf("Unexpected definitions for DB_PASSWORD: %+v", defs)
+	}
+	if defs := definitions["BATCH_SIZE"]; len(defs) != 1 || defs[0].Value != "100" {
+		t.Errorf("Unexpected definitions for BATCH_SIZE: %+v", defs)
+	}
+	if defs := definitions["RETRIES"]; len(defs) != 1 || defs[0].Value != "3" || defs[0].Line != 2 || defs[0].Kind != "env-yaml" {
+		t.Errorf("Unexpected definitions for RETRIES: %+v", defs)
+	}
+	if len(definitions) != 5 {
+		t.Errorf("Expected 5 variables, got %v", definitions)
+	}
+}
+
+func TestLoader_Azure(t *testing.T) {
+	tmpDir := t.TempDir()
+	pipelines := `variables:
+  buildConfiguration: Release
+stages:
+  - stage: Deploy
+    variables:
+      - group: shared-secrets
+      - name: deploy.region
+        value: westeurope
+`
+	bicep := `resource app 'Microsoft.Web/sites@2022-03-01' = {
+  properties: {
+    siteConfig: {
+      appSettings: [
+        {
+          name: 'STORAGE_URL'
+          value: storage.properties.primaryEndpoints.blob
+        }
+      ]
+    }
+  }
+}
+`
+	arm := `{
+  "resources": [{
+    "properties": {
+      "siteConfig": {
+        "appSettings": [
+          { "name": "WEBSITE_NODE_DEFAULT_VERSION", "value": "~20" }
+        ]
+      }
+    }
+  }]
+}
+`
+	files := map[string]string{"azure-pipelines.yml": pipelines, "main.bicep": bicep, "azuredeploy.json": arm}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	definitions, err := NewLoader().LoadDefinitionsContext(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load env files: %v", err)
+	}
+	if defs := definitions["BUILDCONFIGURATION"]; len(defs) != 1 || defs[0].Value != "Release" || defs[0].Line != 2 || defs[0].Kind != "azure-pipelines" {
+		t.Errorf("Unexpected definitions for BUILDCONFIGURATION: %+v", defs)
+	}
+	if defs := definitions["DEPLOY_REGION"]; len(defs) != 1 || defs[0].Value != "westeurope" || defs[0].Line != 7 {
+		t.Errorf("Unexpected definitions for DEPLOY_REGION: %+v", defs)
+	}
+	if defs := definitions["STORAGE_URL"]; len(defs) != 1 || defs[0].Value != "storage.properties.primaryEndpoints.blob" || defs[0].Line != 6 || defs[0].Kind != "azure-app-settings" {
+		t.Errorf("Unexpected definitions for STORAGE_URL: %+v", defs)
+	}
+	if defs := definitions["WEBSITE_NODE_DEFAULT_VERSION"]; len(defs) != 1 || defs[0].Value != "~20" || defs[0].Line != 6 {
+		t.Errorf("Unexpected definitions for WEBSITE_NODE_DEFAULT_VERSION: %+v", defs)
+	}
+	if len(definitions) != 4 {
+		t.Errorf("Expected 4 variables, got %v", definitions)
+	}
+}
+
+func TestComments(t *testing.T) {
+	content := []byte(`#!/bin/sh
+# Database connection
+#   with a pool
+DATABASE_URL=postgres://localhost/app
+
+PORT=3000
+# Not attached
+
+LOG_LEVEL=info
+`)
+	comments := Comments(content, map[string]int{"DATABASE_URL": 4, "PORT": 6, "LOG_LEVEL": 9, "UNKNOWN": 0})
+
+	if got := comments["DATABASE_URL"]; got != "Database connection\nwith a pool" {
+		t.Errorf("Expected the comment above DATABASE_URL, got %q", got)
+	}
+	for _, key := range []string{"PORT", "LOG_LEVEL", "UNKNOWN"} {
+		if _, ok := comments[key]; ok {
+			t.Errorf("Expected no comment for %s, got %q", key, comments[key])
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("ENVGRD_TEST_EXPORTED", "exported")
+	definitions := map[string][]Definition{
+		"DB_HOST":      {{Location: Location{File: ".env", Line: 1, Kind: "env"}, Value: "localhost"}},
+		"DB_USER":      {{Location: Location{File: ".env", Line: 2, Kind: "env"}, Value: ""}},
+		"DATABASE_URL": {{Location: Location{File: ".env", Line: 3, Kind: "env"}, Value: "postgres://${DB_USER:-app}@${DB_HOST}:5432/$DB_NAME"}},
+		"PRICE":        {{Location: Location{File: ".env", Line: 4, Kind: "env"}, Value: `$$5 and \$6`}},
+		"HOME_DIR":     {{Location: Location{File: ".env", Line: 5, Kind: "env"}, Value: "${ENVGRD_TEST_EXPORTED}/home"}},
+		"BROKEN":       {{Location: Location{File: ".env", Line: 6, Kind: "env"}, Value: "${UNDEFINED}-${UNDEFINED}"}},
+		"LOOP_A":       {{Location: Location{File: ".env", Line: 7, Kind: "env"}, Value: "${LOOP_B}"}},
+		"LOOP_B":       {{Location: Location{File: ".env", Line: 8, Kind: "env"}, Value: "${LOOP_A}"}},
+		"TEMPLATE":     {{Location: Location{File: "app.json", Line: 1, Kind: "json"}, Value: "${DB_HOST}"}},
+	}
+
+	resolved := Resolve(definitions)
+	tests := map[string]string{
+		"DATABASE_URL": "postgres://app@localhost:5432/$DB_NAME",
+		"PRICE":        "$5 and $6",
+		"HOME_DIR":     "exported/home",
+		"BROKEN":       "${UNDEFINED}-${UNDEFINED}",
+		"TEMPLATE":     "${DB_HOST}",
+	}
+	for key, want := range tests {
+		if got := resolved[key][0].Value; got != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, got)
+		}
+	}
+	if got := resolved["BROKEN"][0].Unresolved; len(got) != 1 || got[0] != "UNDEFINED" {
+		t.Errorf("Expected BROKEN to reference UNDEFINED once, got %v", got)
+	}
+	if len(resolved["LOOP_A"][0].Unresolved) != 1 || len(resolved["LOOP_B"][0].Unresolved) != 1 {
+		t.Errorf("Expected the reference cycle to be unresolved, got %+v and %+v", resolved["LOOP_A"], resolved["LOOP_B"])
+	}
+	if len(resolved["DATABASE_URL"][0].Unresolved) != 0 {
+		t.Errorf("Expected $DB_NAME not to be reported, got %v", resolved["DATABASE_URL"][0].Unresolved)
+	}
+	if definitions["DATABASE_URL"][0].Value != "postgres://${DB_USER:-app}@${DB_HOST}:5432/$DB_NAME" {
+		t.Error("Expected Resolve not to modify its input")
+	}
+}
+
+func TestParseK8sDefinitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "deployment.yaml")
+	content := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          env:
+            - name: DB_HOST
+              value: db
+            - name: DATABASE_URL
+              value: postgres://$(DB_USER)@$(DB_HOST)/app
+            - name: DB_USER
+              valueFrom:
+                secretKeyRef: {name: db, key: user}
+            - name: LITERAL
+              value: $$(DB_HOST)
+        - name: worker
+          envFrom:
+            - configMapRef: {name: shared}
+          env:
+            - name: QUEUE_URL
+              value: amqp://$(QUEUE_HOST)
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if kind := Kind(path); kind != "k8s" {
+		t.Fatalf("Expected deployment.yaml to be a k8s file, got %s", kind)
+	}
+
+	definitions, err := parseDefinitions(path)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", path, err)
+	}
+	// DB_USER is defined after DATABASE_URL, so the kubelet doesn't expand it
+	url := definitions["DATABASE_URL"][0]
+	if url.Value != "postgres://$(DB_USER)@db/app" || len(url.Unresolved) != 1 || url.Unresolved[0] != "DB_USER" || url.Line != 11 {
+		t.Errorf("Unexpected DATABASE_URL: %+v", url)
+	}
+	if def := definitions["LITERAL"][0]; def.Value != "$(DB_HOST)" || len(def.Unresolved) != 0 {
+		t.Errorf("Expected $$(DB_HOST) to be escaped, got %+v", def)
+	}
+	if def := definitions["QUEUE_URL"][0]; len(def.Unresolved) != 0 {
+		t.Errorf("Expected references of a container with envFrom not to be reported, got %+v", def)
+	}
+	if def := definitions["DB_USER"][0]; def.Value != "" || def.Kind != "k8s" {
+		t.Errorf("Expected DB_USER to be defined from a secret, got %+v", def)
+	}
+}
@@ -3,15 +3,22 @@ package envfile
 import (
 	"bufio"
 	"encoding/base64"
-	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/jenian/envgrd/internal/ansible"
+	"github.com/jenian/envgrd/internal/jsconfig"
 	"gopkg.in/yaml.v3"
 )
 
+// Kind returns the source kind of an env file (see SourceKinds), "env" for files of no other kind
+func Kind(path string) string {
+	return detectFileType(path)
+}
+
 // detectFileType determines the type of environment file based on filename and content
 func detectFileType(path string) string {
 	filename := filepath.Base(path)
@@ -21,11 +28,42 @@ func detectFileType(path string) string {
 		return "envrc"
 	}
 	
+	// YAML env-vars files passed to gcloud with --env-vars-file (.env.yaml, env.yaml, prod.env.yaml)
+	if ext := filepath.Ext(filename); ext == ".yaml" || ext == ".yml" {
+		base := strings.TrimSuffix(filename, ext)
+		if base == "env" || strings.HasPrefix(base, ".env") || strings.HasSuffix(base, ".env") {
+			return "env-yaml"
+		}
+	}
+	
 	// .env.* files
 	if strings.HasPrefix(filename, ".env") {
 		return "env"
 	}
 	
+	// Ansible playbooks and role tasks, whose environment: dictionaries hold the variables
+	if ansible.IsAnsibleFile(path) {
+		return "ansible"
+	}
+	
+	// Cloud Run service and job YAML (gcloud run services replace)
+	if ext := filepath.Ext(filename); ext == ".yaml" || ext == ".yml" {
+		base := strings.ToLower(strings.TrimSuffix(filename, ext))
+		if base == "service" || strings.Contains(strings.NewReplacer("-", "", "_", "").Replace(base), "cloudrun") {
+			return "cloud-run"
+		}
+	}
+	
+	// Azure Pipelines, whose variables are exported to the steps of the pipeline
+	if strings.HasPrefix(filename, "azure-pipelines") && (strings.HasSuffix(filename, ".yml") || strings.HasSuffix(filename, ".yaml")) {
+		return "azure-pipelines"
+	}
+	
+	// Bicep and ARM templates, whose appSettings are the environment of an App Service
+	if strings.HasSuffix(filename, ".bicep") || (strings.HasPrefix(filename, "azuredeploy") && strings.HasSuffix(filename, ".json")) {
+		return "azure-app-settings"
+	}
+	
 	// docker-compose files
 	if filename == "docker-compose.yml" || filename == "docker-compose.yaml" ||
 		strings.HasPrefix(filename, "docker-compose.") {
@@ -35,7 +73,7 @@ func detectFileType(path string) string {
 	// Kubernetes files
 	if strings.HasSuffix(filename, "configmap.yaml") || strings.HasSuffix(filename, "configmap.yml") ||
 		strings.HasSuffix(filename, "secret.yaml") || strings.HasSuffix(filename, "secret.yml") ||
-		strings.Contains(filename, "configmap") || strings.Contains(filename, "secret") {
+		strings.Contains(filename, "configmap") || strings.Contains(filename, "secret") || isK8sWorkloadFile(filename) {
 		// Check if it's YAML
 		if strings.HasSuffix(filename, ".yaml") || strings.HasSuffix(filename, ".yml") {
 			return "k8s"
@@ -47,6 +85,19 @@ func detectFileType(path string) string {
 		return "systemd"
 	}
 	
+	// Next.js config files, whose env block is inlined into the app
+	if jsconfig.IsNextConfig(path) {
+		return "next-config"
+	}
+	
+	// PM2 ecosystem files and nodemon configs, whose env blocks hold the variables
+	switch filename {
+	case "ecosystem.config.js", "ecosystem.config.cjs", "ecosystem.config.mjs", "ecosystem.config.json", "ecosystem.json":
+		return "pm2"
+	case "nodemon.json":
+		return "nodemon"
+	}
+	
 	// Shell scripts - check by extension or shebang
 	if strings.HasSuffix(filename, ".sh") || strings.HasSuffix(filename, ".bash") {
 		return "shell"
@@ -56,24 +107,52 @@ func detectFileType(path string) string {
 	return "env"
 }
 
+// k8sWorkloadMarkers identify the Kubernetes manifests of workloads (e.g., deployment.yaml, api-cronjob.yml)
+var k8sWorkloadMarkers = []string{"pod", "deployment", "statefulset", "daemonset", "replicaset", "job", "cronjob"}
+
+// isK8sWorkloadFile reports whether a file name marks it as the manifest of a Kubernetes workload
+func isK8sWorkloadFile(filename string) bool {
+	parts := strings.FieldsFunc(strings.ToLower(filename), func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	return slices.ContainsFunc(parts, func(part string) bool { return slices.Contains(k8sWorkloadMarkers, part) })
+}
+
+// exampleMarkers identify env files that document variables rather than define them
+var exampleMarkers = []string{"example", "sample", "template", "dist"}
+
+// IsExampleFile reports whether an env file name marks it as an example (e.g., .env.example, env.sample)
+func IsExampleFile(path string) bool {
+	parts := strings.FieldsFunc(strings.ToLower(filepath.Base(path)), func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	for _, part := range parts {
+		for _, marker := range exampleMarkers {
+			if part == marker {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // parseEnvrc parses direnv .envrc files
 // Supports: export VAR=value
-func parseEnvrc(path string) (map[string]string, error) {
+func parseEnvrc(path string) (map[string]string, map[string]int, error) {
 	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
 	
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return vars, nil
+			return vars, lines, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 	
 	scanner := bufio.NewScanner(file)
 	exportRegex := regexp.MustCompile(`^\s*export\s+([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
 	
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 		
 		// Skip empty lines and comments
@@ -92,133 +171,494 @@ func parseEnvrc(path string) (map[string]string, error) {
 			
 			if key != "" {
 				vars[key] = value
+				lines[key] = lineNum
 			}
 		}
 	}
 	
-	return vars, scanner.Err()
+	return vars, lines, scanner.Err()
 }
 
-// parseDockerCompose parses docker-compose.yml files
-func parseDockerCompose(path string) (map[string]string, error) {
+// parseDockerCompose parses docker-compose.yml files, merging the environment blocks of every service
+// into one set of variables where the last service defining a variable wins
+func parseDockerCompose(path string) (map[string]string, map[string]int, error) {
+	definitions, err := parseComposeServices(path)
+	if err != nil {
+		return nil, nil, err
+	}
 	vars := make(map[string]string)
-	
-	file, err := os.Open(path)
+	lines := make(map[string]int) // Line of each key's definition
+	for key, defs := range definitions {
+		def := effectiveDefinition(defs)
+		vars[key] = def.Value
+		lines[key] = def.Line
+	}
+	return vars, lines, nil
+}
+
+// parseComposeServices parses the environment blocks of the services of a docker-compose file (mapping or
+// KEY=value list form, following anchors and merge keys), attributing each definition to its service and
+// to the directory the service is built from
+func parseComposeServices(path string) (map[string][]Definition, error) {
+	definitions := make(map[string][]Definition)
+
+	content, err := readFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return vars, nil
+			return definitions, nil
 		}
 		return nil, err
 	}
-	defer file.Close()
-	
-	var compose map[string]interface{}
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&compose); err != nil {
-		return vars, nil // Not a valid YAML, skip silently
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return definitions, nil // Not a valid YAML, skip silently
 	}
-	
-	// Extract environment variables from services
-	if services, ok := compose["services"].(map[string]interface{}); ok {
-		for _, service := range services {
-			if serviceMap, ok := service.(map[string]interface{}); ok {
-				// Check environment: section
-				if env, ok := serviceMap["environment"].(map[string]interface{}); ok {
-					for k, v := range env {
-						if val, ok := v.(string); ok {
-							vars[k] = val
-						} else {
-							vars[k] = fmt.Sprintf("%v", v)
-						}
+
+	services := yamlMappingValue(yamlDocumentRoot(&doc), "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return definitions, nil
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		service := services.Content[i+1]
+		location := Location{File: path, Kind: "docker-compose", Service: services.Content[i].Value, ServiceDir: composeBuildDir(path, composeField(service, "build"))}
+		composeEnvironment(composeField(service, "environment"), func(key, value string, line int) {
+			location.Line = line
+			definitions[key] = append(definitions[key], Definition{Location: location, Value: value})
+		})
+	}
+	return definitions, nil
+}
+
+// composeField returns the value for key in a compose mapping, looking into the mappings merged with <<,
+// or nil
+func composeField(node *yaml.Node, key string) *yaml.Node {
+	node = yamlResolve(node)
+	if value := yamlMappingValue(node, key); value != nil {
+		return yamlResolve(value)
+	}
+	merged := yamlResolve(yamlMappingValue(node, "<<"))
+	if merged == nil {
+		return nil
+	}
+	if merged.Kind == yaml.SequenceNode {
+		for _, item := range merged.Content {
+			if value := composeField(item, key); value != nil {
+				return value
+			}
+		}
+		return nil
+	}
+	return composeField(merged, key)
+}
+
+// composeEnvironment calls add for each variable of an environment block, merged mappings first
+func composeEnvironment(env *yaml.Node, add func(key, value string, line int)) {
+	env = yamlResolve(env)
+	if env == nil {
+		return
+	}
+	switch env.Kind {
+	case yaml.MappingNode:
+		for j := 0; j+1 < len(env.Content); j += 2 {
+			key, value := env.Content[j], yamlResolve(env.Content[j+1])
+			if key.Value == "<<" {
+				if value.Kind == yaml.SequenceNode {
+					for _, item := range value.Content {
+						composeEnvironment(item, add)
 					}
+				} else {
+					composeEnvironment(value, add)
 				}
-				// Check environment: as array
-				if envList, ok := serviceMap["environment"].([]interface{}); ok {
-					for _, item := range envList {
-						if envStr, ok := item.(string); ok {
-							parts := strings.SplitN(envStr, "=", 2)
-							if len(parts) == 2 {
-								vars[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-							}
-						}
+				continue
+			}
+			if value.Kind == yaml.ScalarNode && value.Tag != "!!null" {
+				add(key.Value, value.Value, key.Line)
+			} else {
+				add(key.Value, "", key.Line) // KEY: with no value passes the variable through from the host
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range env.Content {
+			if key, value, ok := strings.Cut(item.Value, "="); ok {
+				add(strings.TrimSpace(key), strings.TrimSpace(value), item.Line)
+			}
+		}
+	}
+}
+
+// composeBuildDir returns the absolute directory a compose service is built from (build: ./api or
+// build: {context: ./api}), empty without a local build context
+func composeBuildDir(path string, build *yaml.Node) string {
+	if build != nil && build.Kind == yaml.MappingNode {
+		build = composeField(build, "context")
+	}
+	if build == nil || build.Kind != yaml.ScalarNode || build.Value == "" || strings.Contains(build.Value, "://") || strings.HasPrefix(build.Value, "git@") {
+		return "" // Remote contexts (git repositories, URLs) aren't part of the scanned code
+	}
+	dir := build.Value
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(path), filepath.FromSlash(dir))
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir
+}
+
+// yamlResolve follows an alias to the node it refers to
+func yamlResolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// parseK8s parses Kubernetes ConfigMap and Secret YAML files and the container env lists of workloads,
+// where the last definition of a variable wins
+func parseK8s(path string) (map[string]string, map[string]int, error) {
+	definitions, err := parseK8sDefinitions(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := make(map[string]string, len(definitions))
+	lines := make(map[string]int, len(definitions))
+	for k, defs := range definitions {
+		vars[k] = defs[len(defs)-1].Value
+		setLine(lines, k, defs[0].Line)
+	}
+	return vars, lines, nil
+}
+
+// parseK8sDefinitions parses every document of a Kubernetes YAML file: the data of ConfigMaps and Secrets
+// (secrets are base64-decoded) and the env lists of the containers of Pods, workloads and CronJobs
+func parseK8sDefinitions(path string) (map[string][]Definition, error) {
+	definitions := make(map[string][]Definition)
+
+	file, err := openFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return definitions, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	add := func(key string, value string, line int, unresolved []string) {
+		definitions[key] = append(definitions[key], Definition{
+			Location:   Location{File: path, Line: line, Kind: "k8s"},
+			Value:      value,
+			Unresolved: unresolved,
+		})
+	}
+	decoder := yaml.NewDecoder(file)
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			break // End of the file, or not valid YAML: keep the documents parsed so far
+		}
+		root := yamlDocumentRoot(&doc)
+		kind := yamlMappingValue(root, "kind")
+		if kind == nil {
+			continue
+		}
+
+		if kind.Value == "ConfigMap" || kind.Value == "Secret" {
+			data := yamlMappingValue(root, "data")
+			if data == nil || data.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(data.Content); i += 2 {
+				key, value := data.Content[i], data.Content[i+1]
+				if value.Kind != yaml.ScalarNode || value.ShortTag() != "!!str" {
+					continue
+				}
+				val := value.Value
+				if kind.Value == "Secret" {
+					// Secrets are base64 encoded, use the value as-is if decoding fails
+					if decoded, err := base64.StdEncoding.DecodeString(val); err == nil {
+						val = string(decoded)
 					}
 				}
+				add(key.Value, val, key.Line, nil)
 			}
+			continue
+		}
+
+		for _, container := range k8sContainers(root) {
+			parseK8sContainerEnv(container, add)
 		}
 	}
-	
-	return vars, nil
+	return definitions, nil
 }
 
-// parseK8s parses Kubernetes ConfigMap and Secret YAML files
-func parseK8s(path string) (map[string]string, error) {
+// k8sContainers returns the containers and init containers of a Pod, a workload with a pod template
+// (Deployment, StatefulSet, DaemonSet, ReplicaSet, Job) or a CronJob
+func k8sContainers(root *yaml.Node) []*yaml.Node {
+	spec := yamlMappingValue(root, "spec")
+	if jobTemplate := yamlMappingValue(spec, "jobTemplate"); jobTemplate != nil {
+		spec = yamlMappingValue(jobTemplate, "spec")
+	}
+	if template := yamlMappingValue(spec, "template"); template != nil {
+		spec = yamlMappingValue(template, "spec")
+	}
+
+	var containers []*yaml.Node
+	for _, field := range []string{"initContainers", "containers"} {
+		if list := yamlMappingValue(spec, field); list != nil && list.Kind == yaml.SequenceNode {
+			containers = append(containers, list.Content...)
+		}
+	}
+	return containers
+}
+
+// k8sReference matches the $(VAR) references of container env values and their $$(VAR) escapes
+var k8sReference = regexp.MustCompile(`\$\$\(|\$\(([-._a-zA-Z][-._a-zA-Z0-9]*)\)`)
+
+// parseK8sContainerEnv adds the env list of a container, expanding $(VAR) references the way the kubelet
+// does: only variables defined earlier in the same list can be referenced, and other references are kept
+// as written and listed as unresolved. Variables taken from a ConfigMap, a Secret or a field (valueFrom)
+// are defined with an empty value; in containers with envFrom, references to variables not in the list
+// may be defined by the referenced ConfigMaps and Secrets, so they aren't reported
+func parseK8sContainerEnv(container *yaml.Node, add func(key string, value string, line int, unresolved []string)) {
+	env := yamlMappingValue(container, "env")
+	if env == nil || env.Kind != yaml.SequenceNode {
+		return
+	}
+	envFrom := yamlMappingValue(container, "envFrom") != nil
+
+	defined := make(map[string]string)
+	for _, entry := range env.Content {
+		name := yamlMappingValue(entry, "name")
+		if name == nil || name.Value == "" {
+			continue
+		}
+		value, unresolved := "", []string(nil)
+		if node := yamlMappingValue(entry, "value"); node != nil {
+			value = k8sReference.ReplaceAllStringFunc(node.Value, func(match string) string {
+				if match == "$$(" {
+					return "$("
+				}
+				ref := match[2 : len(match)-1]
+				if v, ok := defined[ref]; ok {
+					return v
+				}
+				if !envFrom && !slices.Contains(unresolved, ref) {
+					unresolved = append(unresolved, ref)
+				}
+				return match
+			})
+		}
+		defined[name.Value] = value
+		add(name.Value, value, name.Line, unresolved)
+	}
+}
+
+// cloudRunAPIs are the apiVersion groups of Cloud Run services (Knative serving) and jobs
+var cloudRunAPIs = []string{"serving.knative.dev/", "run.googleapis.com/"}
+
+// parseCloudRun parses the container env lists of a Cloud Run service or job YAML
+// (spec.template.spec.containers[].env, one level deeper for jobs); variables taken from
+// Secret Manager (valueFrom) are defined with an empty value
+func parseCloudRun(path string) (map[string]string, map[string]int, error) {
 	vars := make(map[string]string)
-	
-	file, err := os.Open(path)
+	lines := make(map[string]int) // Line of each key's definition
+
+	file, err := openFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return vars, nil
+			return vars, lines, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
-	
-	var k8sObj map[string]interface{}
+
+	var doc yaml.Node
 	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&k8sObj); err != nil {
-		return vars, nil // Not a valid YAML, skip silently
+	if err := decoder.Decode(&doc); err != nil {
+		return vars, lines, nil // Not a valid YAML, skip silently
 	}
-	
-	kind, _ := k8sObj["kind"].(string)
-	
-	// Handle ConfigMap
-	if kind == "ConfigMap" {
-		if data, ok := k8sObj["data"].(map[string]interface{}); ok {
-			for k, v := range data {
-				if val, ok := v.(string); ok {
-					vars[k] = val
+	root := yamlDocumentRoot(&doc)
+	apiVersion := yamlMappingValue(root, "apiVersion")
+	if apiVersion == nil || !slices.ContainsFunc(cloudRunAPIs, func(api string) bool { return strings.HasPrefix(apiVersion.Value, api) }) {
+		return vars, lines, nil
+	}
+
+	// Services have spec.template.spec.containers, jobs spec.template.spec.template.spec.containers
+	spec := yamlMappingValue(root, "spec")
+	for spec != nil {
+		containers := yamlMappingValue(spec, "containers")
+		if containers != nil && containers.Kind == yaml.SequenceNode {
+			for _, container := range containers.Content {
+				env := yamlMappingValue(container, "env")
+				if env == nil || env.Kind != yaml.SequenceNode {
+					continue
+				}
+				for _, entry := range env.Content {
+					name := yamlMappingValue(entry, "name")
+					if name == nil || name.Value == "" {
+						continue
+					}
+					vars[name.Value] = ""
+					if value := yamlMappingValue(entry, "value"); value != nil {
+						vars[name.Value] = value.Value
+					}
+					setLine(lines, name.Value, name.Line)
 				}
 			}
+			break
 		}
+		spec = yamlMappingValue(yamlMappingValue(spec, "template"), "spec")
 	}
-	
-	// Handle Secret
-	if kind == "Secret" {
-		if data, ok := k8sObj["data"].(map[string]interface{}); ok {
-			for k, v := range data {
-				if val, ok := v.(string); ok {
-					// Secrets are base64 encoded
-					decoded, err := base64.StdEncoding.DecodeString(val)
-					if err == nil {
-						vars[k] = string(decoded)
-					} else {
-						vars[k] = val // Use as-is if decoding fails
+
+	return vars, lines, nil
+}
+
+// parseEnvYAML parses a YAML env-vars file (KEY: value), as passed to gcloud functions deploy or
+// gcloud run deploy with --env-vars-file
+func parseEnvYAML(path string) (map[string]string, map[string]int, error) {
+	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
+
+	file, err := openFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vars, lines, nil
+		}
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var doc yaml.Node
+	decoder := yaml.NewDecoder(file)
+	if err := decoder.Decode(&doc); err != nil {
+		return vars, lines, nil // Not a valid YAML, skip silently
+	}
+	root := yamlDocumentRoot(&doc)
+	if root.Kind != yaml.MappingNode {
+		return vars, lines, nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			continue
+		}
+		vars[key.Value] = value.Value
+		setLine(lines, key.Value, key.Line)
+	}
+
+	return vars, lines, nil
+}
+
+// parseAzurePipelines parses the variables of an Azure Pipelines file, at the pipeline, stage and job levels,
+// in both the mapping (KEY: value) and list (- name: KEY) forms
+// Azure exports a variable as an environment variable named in upper case with . replaced by _
+func parseAzurePipelines(path string) (map[string]string, map[string]int, error) {
+	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
+
+	file, err := openFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vars, lines, nil
+		}
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var doc yaml.Node
+	decoder := yaml.NewDecoder(file)
+	if err := decoder.Decode(&doc); err != nil {
+		return vars, lines, nil // Not a valid YAML, skip silently
+	}
+
+	define := func(name, value *yaml.Node) {
+		if name == nil || name.Value == "" || value == nil || value.Kind != yaml.ScalarNode {
+			return
+		}
+		key := strings.ToUpper(strings.NewReplacer(".", "_", " ", "_").Replace(name.Value))
+		vars[key] = value.Value
+		setLine(lines, key, name.Line)
+	}
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			if variables := yamlMappingValue(node, "variables"); variables != nil {
+				switch variables.Kind {
+				case yaml.MappingNode:
+					for i := 0; i+1 < len(variables.Content); i += 2 {
+						define(variables.Content[i], variables.Content[i+1])
+					}
+				case yaml.SequenceNode:
+					// Variable groups and templates are defined outside of the file
+					for _, variable := range variables.Content {
+						define(yamlMappingValue(variable, "name"), yamlMappingValue(variable, "value"))
 					}
 				}
 			}
 		}
+		for _, child := range node.Content {
+			walk(child)
+		}
 	}
-	
-	return vars, nil
+	walk(yamlDocumentRoot(&doc))
+
+	return vars, lines, nil
+}
+
+// parseAzureAppSettings parses the appSettings arrays ({name, value} objects) of a Bicep or ARM template
+// Values that aren't string literals (parameters, resource properties) are kept as source text
+func parseAzureAppSettings(path string) (map[string]string, map[string]int, error) {
+	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
+
+	content, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vars, lines, nil
+		}
+		return nil, nil, err
+	}
+
+	for _, setting := range jsconfig.FindArrayObjects(content, "appSettings") {
+		var name *jsconfig.Property
+		value := ""
+		for i := range setting {
+			switch setting[i].Key {
+			case "name":
+				name = &setting[i]
+			case "value":
+				value = setting[i].Value
+			}
+		}
+		if name == nil || !name.Literal || name.Value == "" {
+			continue
+		}
+		vars[name.Value] = value
+		setLine(lines, name.Value, name.Line)
+	}
+	return vars, lines, nil
 }
 
 // parseSystemd parses systemd .service files
-func parseSystemd(path string) (map[string]string, error) {
+func parseSystemd(path string) (map[string]string, map[string]int, error) {
 	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
 	
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return vars, nil
+			return vars, lines, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 	
 	scanner := bufio.NewScanner(file)
 	envRegex := regexp.MustCompile(`^\s*Environment\s*=\s*(.+)$`)
 	
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 		
 		// Skip empty lines and comments
@@ -238,31 +678,132 @@ func parseSystemd(path string) (map[string]string, error) {
 				value := strings.TrimSpace(parts[1])
 				if key != "" {
 					vars[key] = value
+					lines[key] = lineNum
 				}
 			}
 		}
 	}
 	
-	return vars, scanner.Err()
+	return vars, lines, scanner.Err()
+}
+
+// parseNextConfig parses the env block of a Next.js config file
+// Values that aren't string literals (process.env.X, computed values) are kept as source text
+func parseNextConfig(path string) (map[string]string, map[string]int, error) {
+	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
+
+	content, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vars, lines, nil
+		}
+		return nil, nil, err
+	}
+
+	for _, prop := range jsconfig.NextEnv(content) {
+		vars[prop.Key] = prop.Value
+		lines[prop.Key] = prop.Line
+	}
+	return vars, lines, nil
+}
+
+// parseEnvBlocks parses the env blocks of a PM2 ecosystem file (env, env_production, ... of each app)
+// or nodemon.json (env), attributing each definition to the environment its block is named after:
+// production for env_production, none for the default env block
+func parseEnvBlocks(path string) (map[string][]Definition, error) {
+	definitions := make(map[string][]Definition)
+
+	content, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return definitions, nil
+		}
+		return nil, err
+	}
+
+	kind := detectFileType(path)
+	for _, block := range jsconfig.FindObjects(content, isEnvBlock) {
+		environment := strings.TrimPrefix(strings.TrimPrefix(block.Key, "env"), "_")
+		for _, prop := range block.Object {
+			if prop.Key == "" || prop.IsObject {
+				continue
+			}
+			definitions[prop.Key] = append(definitions[prop.Key], Definition{
+				Location: Location{File: path, Line: prop.Line, Kind: kind, Environment: environment},
+				Value:    prop.Value,
+			})
+		}
+	}
+	return definitions, nil
+}
+
+// isEnvBlock reports whether key names an env block of a PM2 app (env or env_<environment>)
+func isEnvBlock(key string) bool {
+	return key == "env" || strings.HasPrefix(key, "env_")
+}
+
+// parseEnvBlocksWithLines is like parseEnvBlocks but merges the blocks into one set of variables,
+// where the default env block wins over the environment-specific ones
+func parseEnvBlocksWithLines(path string) (map[string]string, map[string]int, error) {
+	definitions, err := parseEnvBlocks(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
+	for key, defs := range definitions {
+		def := effectiveDefinition(defs)
+		vars[key] = def.Value
+		lines[key] = def.Line
+	}
+	return vars, lines, nil
+}
+
+// parseAnsible parses the environment: dictionaries of an Ansible playbook or role task file
+func parseAnsible(path string) (map[string]string, map[string]int, error) {
+	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
+
+	content, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vars, lines, nil
+		}
+		return nil, nil, err
+	}
+
+	variables, err := ansible.Environment(content)
+	if err != nil {
+		return vars, lines, nil // Not a valid YAML, skip silently
+	}
+	for _, variable := range variables {
+		vars[variable.Name] = variable.Value
+		lines[variable.Name] = variable.Line
+	}
+	return vars, lines, nil
 }
 
 // parseShellScript parses shell scripts for export VAR=value
-func parseShellScript(path string) (map[string]string, error) {
+func parseShellScript(path string) (map[string]string, map[string]int, error) {
 	vars := make(map[string]string)
+	lines := make(map[string]int) // Line of each key's definition
 	
-	file, err := os.Open(path)
+	file, err := openFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return vars, nil
+			return vars, lines, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 	
 	scanner := bufio.NewScanner(file)
 	exportRegex := regexp.MustCompile(`^\s*export\s+([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
 	
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 		
 		// Skip empty lines and comments
@@ -281,11 +822,40 @@ func parseShellScript(path string) (map[string]string, error) {
 			
 			if key != "" {
 				vars[key] = value
+				lines[key] = lineNum
 			}
 		}
 	}
 	
-	return vars, scanner.Err()
+	return vars, lines, scanner.Err()
+}
+
+// yamlDocumentRoot returns the top-level node of a decoded YAML document
+func yamlDocumentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// yamlMappingValue returns the value for key in a mapping node, or nil
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setLine records the line of a key's first definition in a file
+func setLine(lines map[string]int, key string, line int) {
+	if _, ok := lines[key]; !ok && key != "" {
+		lines[key] = line
+	}
 }
 
 // trimQuotes removes surrounding quotes from a string
@@ -0,0 +1,104 @@
+package envfile
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// referenceKinds are the source kinds whose values interpolate other variables, as dotenv loaders, direnv,
+// shells and docker-compose do
+var referenceKinds = map[string]bool{"env": true, "envrc": true, "shell": true, "docker-compose": true}
+
+// valueReference matches $$ and \$ escapes, ${VAR} with an optional default (${VAR:-x}, ${VAR-x}), and $VAR
+var valueReference = regexp.MustCompile(`\$\$|\\\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Resolve returns definitions with the ${VAR} and $VAR references of their values replaced by the value that
+// takes effect for VAR (see Effective), or else by the exported environment or the reference's default
+// A value with a ${VAR} reference that can't be resolved is kept as written and lists VAR in Unresolved;
+// unknown $VAR references are left as they are, since a bare $ is common in passwords
+// definitions isn't modified, so snapshots keep the values as written
+func Resolve(definitions map[string][]Definition) map[string][]Definition {
+	r := &resolver{definitions: definitions, values: make(map[string]*string), resolving: make(map[string]bool)}
+	resolved := make(map[string][]Definition, len(definitions))
+	for key, defs := range definitions {
+		resolved[key] = make([]Definition, len(defs))
+		for i, def := range defs {
+			if referenceKinds[def.Kind] && strings.Contains(def.Value, "$") {
+				value, unresolved := r.expand(def.Value)
+				if len(unresolved) == 0 {
+					def.Value = value
+				}
+				def.Unresolved = unresolved
+			}
+			resolved[key][i] = def
+		}
+	}
+	return resolved
+}
+
+// resolver expands references against the effective values, memoizing them and detecting cycles
+type resolver struct {
+	definitions map[string][]Definition
+	values      map[string]*string // Resolved effective values, nil when the variable can't be resolved
+	resolving   map[string]bool    // Variables being resolved, to break reference cycles
+}
+
+// expand replaces the references of value and returns the names of the ${VAR} ones that can't be resolved
+func (r *resolver) expand(value string) (string, []string) {
+	var unresolved []string
+	expanded := valueReference.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" || match == `\$` {
+			return "$"
+		}
+		m := valueReference.FindStringSubmatch(match)
+		name, operator, fallback := m[1], m[2], m[3]
+		if name == "" {
+			if v, ok := r.lookup(m[4]); ok {
+				return v
+			}
+			return match
+		}
+		v, ok := r.lookup(name)
+		switch {
+		case ok && (operator != ":-" || v != ""):
+			return v
+		case operator != "":
+			return fallback
+		}
+		if !slices.Contains(unresolved, name) {
+			unresolved = append(unresolved, name)
+		}
+		return match
+	})
+	return expanded, unresolved
+}
+
+// lookup returns the resolved value that takes effect for name, or else its exported value
+func (r *resolver) lookup(name string) (string, bool) {
+	if v, done := r.values[name]; done {
+		if v == nil {
+			return os.LookupEnv(name)
+		}
+		return *v, true
+	}
+	defs := r.definitions[name]
+	if len(defs) == 0 || r.resolving[name] {
+		return os.LookupEnv(name)
+	}
+
+	r.resolving[name] = true
+	def := effectiveDefinition(defs)
+	value, unresolved := def.Value, []string(nil)
+	if referenceKinds[def.Kind] {
+		value, unresolved = r.expand(def.Value)
+	}
+	delete(r.resolving, name)
+	if len(unresolved) > 0 {
+		r.values[name] = nil
+		return os.LookupEnv(name)
+	}
+	r.values[name] = &value
+	return value, true
+}
@@ -0,0 +1,122 @@
+package envfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// SnapshotVersion is bumped when the snapshot layout or the parsing of env files changes, so snapshots
+// taken by another version are never reused
+const SnapshotVersion = 2
+
+// Snapshot holds the definitions loaded from env files, so repeated scans (e.g., an editor checking a
+// buffer on every keystroke) don't parse every env file again while none of them changed
+// The exported environment isn't part of it, it is read by each scan
+type Snapshot struct {
+	Version int                      `json:"version"`
+	Loads   map[string]*SnapshotLoad `json:"loads"` // By the root directory of the load
+	changed bool
+}
+
+// SnapshotLoad holds the definitions loaded from a directory and the state of the files they came from
+type SnapshotLoad struct {
+	Files       []FileStamp             `json:"files"` // In load order
+	Definitions map[string][]Definition `json:"definitions"`
+}
+
+// FileStamp identifies the content of an env file by its size and modification time
+type FileStamp struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"` // Unix nanoseconds
+}
+
+// NewSnapshot returns an empty snapshot
+func NewSnapshot() *Snapshot {
+	return &Snapshot{Version: SnapshotVersion, Loads: make(map[string]*SnapshotLoad)}
+}
+
+// Changed reports whether loads were added or refreshed since the snapshot was created or read
+func (s *Snapshot) Changed() bool {
+	return s.changed
+}
+
+// LoadDefinitionsSnapshot is like LoadDefinitionsContext but reuses the definitions snapshot holds for
+// rootPath when the env files to load are the same, unchanged, and records them in snapshot otherwise
+// The snapshot keeps the values as written, references are resolved on each load against the exported environment
+func (l *Loader) LoadDefinitionsSnapshot(ctx context.Context, rootPath string, snapshot *Snapshot) (map[string][]Definition, error) {
+	envFiles, err := l.Files(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	stamps := make([]FileStamp, 0, len(envFiles))
+	for _, path := range envFiles {
+		stamp := FileStamp{Path: path}
+		if info, err := os.Stat(path); err == nil {
+			stamp.Size, stamp.ModTime = info.Size(), info.ModTime().UnixNano()
+		}
+		stamps = append(stamps, stamp)
+	}
+	if load := snapshot.Loads[rootPath]; load != nil && slices.Equal(load.Files, stamps) {
+		l.logger.Debug("using env snapshot", "dir", rootPath, "files", len(envFiles))
+		return Resolve(load.Definitions), nil
+	}
+
+	definitions, err := l.loadDefinitions(ctx, envFiles)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Loads[rootPath] = &SnapshotLoad{Files: stamps, Definitions: definitions}
+	snapshot.changed = true
+	return Resolve(definitions), nil
+}
+
+// ReadSnapshot reads the snapshot written to path by Snapshot.Write
+// A missing, corrupt or outdated snapshot reads as an empty one, which the next loads fill again
+func ReadSnapshot(path string) *Snapshot {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NewSnapshot()
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil || snapshot.Version != SnapshotVersion || snapshot.Loads == nil {
+		return NewSnapshot()
+	}
+	return &snapshot
+}
+
+// Write stores the snapshot in path, creating its directory
+func (s *Snapshot) Write(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode env snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create env snapshot directory: %w", err)
+	}
+
+	// Write to a temp file and rename so concurrent scans never read a partial snapshot
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write env snapshot: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write env snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write env snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write env snapshot: %w", err)
+	}
+	s.changed = false
+	return nil
+}
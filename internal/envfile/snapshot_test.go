@@ -0,0 +1,76 @@
+package envfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadDefinitionsSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("KEY1=value1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	loader := NewLoader()
+	snapshot := NewSnapshot()
+	definitions, err := loader.LoadDefinitionsSnapshot(context.Background(), tmpDir, snapshot)
+	if err != nil {
+		t.Fatalf("LoadDefinitionsSnapshot failed: %v", err)
+	}
+	if len(definitions["KEY1"]) != 1 || !snapshot.Changed() {
+		t.Fatalf("Expected KEY1 to be loaded and recorded, got %v", definitions)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "env", "snapshot.json")
+	if err := snapshot.Write(snapshotPath); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	snapshot = ReadSnapshot(snapshotPath)
+	if snapshot.Changed() || snapshot.Loads[tmpDir] == nil {
+		t.Fatalf("Expected the load of %s to be read back, got %+v", tmpDir, snapshot.Loads)
+	}
+
+	// Unchanged files are served from the snapshot
+	definitions, err = loader.LoadDefinitionsSnapshot(context.Background(), tmpDir, snapshot)
+	if err != nil {
+		t.Fatalf("LoadDefinitionsSnapshot failed: %v", err)
+	}
+	if len(definitions["KEY1"]) != 1 || definitions["KEY1"][0].Value != "value1" || snapshot.Changed() {
+		t.Errorf("Expected KEY1 from the snapshot, got %v", definitions)
+	}
+
+	// Modified files are parsed again
+	if err := os.WriteFile(envPath, []byte("KEY1=value1\nKEY2=value2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(envPath, later, later); err != nil {
+		t.Fatalf("Failed to touch .env: %v", err)
+	}
+	definitions, err = loader.LoadDefinitionsSnapshot(context.Background(), tmpDir, snapshot)
+	if err != nil {
+		t.Fatalf("LoadDefinitionsSnapshot failed: %v", err)
+	}
+	if len(definitions["KEY2"]) != 1 || !snapshot.Changed() {
+		t.Errorf("Expected the modified .env to be parsed again, got %v", definitions)
+	}
+}
+
+func TestReadSnapshot_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if snapshot := ReadSnapshot(path); len(snapshot.Loads) != 0 {
+		t.Errorf("Expected an empty snapshot for a missing file, got %+v", snapshot)
+	}
+	for _, content := range []string{"{not json", `{"version": 0, "loads": {"/": {}}}`} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		if snapshot := ReadSnapshot(path); len(snapshot.Loads) != 0 || snapshot.Version != SnapshotVersion {
+			t.Errorf("Expected an empty snapshot for %q, got %+v", content, snapshot)
+		}
+	}
+}
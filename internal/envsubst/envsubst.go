@@ -0,0 +1,67 @@
+// Package envsubst finds the environment variables referenced by text templates that are rendered
+// with envsubst or a shell, e.g. ${UPSTREAM_HOST} in an nginx.conf template
+package envsubst
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/charset"
+)
+
+// reference matches $VAR, ${VAR} and the parameter expansions of a variable (${VAR:-default}, ${VAR?})
+var reference = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-=?+])[^}]*)?\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// ParseFile reads a template and returns its usages, reported in relPath
+func ParseFile(path string, relPath string) ([]analyzer.EnvUsage, error) {
+	content, _, err := charset.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return Parse(content, relPath), nil
+}
+
+// Parse returns the usages of the variables a template references, reported in file
+// Only upper-case names count, lower-case ones are usually the template's own variables (nginx's
+// $host). $$ escapes a dollar sign, and a reference with a default or an alternative value
+// (${VAR:-x}, ${VAR:+x}) is optional
+func Parse(content []byte, file string) []analyzer.EnvUsage {
+	var usages []analyzer.EnvUsage
+	seen := make(map[string]bool)
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		for _, m := range reference.FindAllSubmatchIndex(line, -1) {
+			if m[0] > 0 && line[m[0]-1] == '$' {
+				continue
+			}
+			name, operator := "", ""
+			if m[2] >= 0 {
+				name = string(line[m[2]:m[3]])
+				if m[4] >= 0 {
+					operator = string(line[m[4]:m[5]])
+				}
+			} else {
+				name = string(line[m[6]:m[7]])
+			}
+			if name != strings.ToUpper(name) {
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%d", name, i+1)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			usages = append(usages, analyzer.EnvUsage{
+				Key:         name,
+				File:        file,
+				Line:        i + 1,
+				CodeSnippet: strings.TrimSpace(string(line)),
+				IsOptional:  operator != "" && !strings.HasSuffix(operator, "?"),
+			})
+		}
+	}
+	return usages
+}
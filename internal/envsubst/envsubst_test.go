@@ -0,0 +1,41 @@
+package envsubst
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	template := `upstream app {
+    server ${UPSTREAM_HOST}:$UPSTREAM_PORT;
+}
+server {
+    listen ${PORT:-80};
+    server_name ${SERVER_NAME?required} $host;
+    set $$ESCAPED 1;
+    add_header X-Release "${RELEASE}-${RELEASE}";
+}
+`
+	type ref struct {
+		Key      string
+		Line     int
+		Optional bool
+	}
+	var got []ref
+	for _, usage := range Parse([]byte(template), "nginx/default.conf.template") {
+		if usage.File != "nginx/default.conf.template" {
+			t.Errorf("Expected the usage to be reported in the template, got %s", usage.File)
+		}
+		got = append(got, ref{usage.Key, usage.Line, usage.IsOptional})
+	}
+	want := []ref{
+		{"UPSTREAM_HOST", 2, false},
+		{"UPSTREAM_PORT", 2, false},
+		{"PORT", 5, true},
+		{"SERVER_NAME", 6, false},
+		{"RELEASE", 8, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,282 @@
+// Package jsconfig reads the env-related sections of JavaScript config files and of formats with the same
+// object literal syntax (JSON, Bicep), such as the env block of next.config.js, the runtimeConfig of
+// nuxt.config.ts, the env blocks of a PM2 ecosystem file or the appSettings of an Azure template
+// Config files are code, so only object literals written out in the file are understood
+package jsconfig
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Property is a property of an object literal
+type Property struct {
+	Key      string
+	Line     int        // 1-based line of the key
+	Value    string     // Source text of the value, or the content of a string literal
+	Literal  bool       // Value is a string literal
+	IsObject bool       // Value is an object literal, see Object
+	Object   []Property // Properties of an object literal value
+}
+
+// configNames are the extensions of framework config files, e.g. next.config.mjs
+var configExtensions = []string{".js", ".mjs", ".cjs", ".ts", ".mts", ".cts"}
+
+// isConfig reports whether path is the config file of a framework, e.g. next.config.js for next
+func isConfig(path string, framework string) bool {
+	name := filepath.Base(path)
+	for _, ext := range configExtensions {
+		if name == framework+".config"+ext {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNextConfig reports whether path is a Next.js config file (next.config.js, .mjs, .ts, ...)
+func IsNextConfig(path string) bool {
+	return isConfig(path, "next")
+}
+
+// IsNuxtConfig reports whether path is a Nuxt config file (nuxt.config.ts, .js, ...)
+func IsNuxtConfig(path string) bool {
+	return isConfig(path, "nuxt")
+}
+
+// NextEnv returns the properties of the env block of a Next.js config
+// Next.js inlines them into client and server code, so each key is a variable process.env can read
+func NextEnv(content []byte) []Property {
+	env, _ := FindObject(content, "env")
+	var props []Property
+	for _, prop := range env {
+		if prop.Key != "" {
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// NuxtVariables returns the environment variables that override the runtimeConfig of a Nuxt config,
+// as properties named after the variable: apiSecret is set by NUXT_API_SECRET and public.apiBase by
+// NUXT_PUBLIC_API_BASE
+func NuxtVariables(content []byte) []Property {
+	runtimeConfig, _ := FindObject(content, "runtimeConfig")
+	var variables []Property
+	var walk func(props []Property, prefix string)
+	walk = func(props []Property, prefix string) {
+		for _, prop := range props {
+			if prop.Key == "" {
+				continue
+			}
+			name := prefix + snakeCase(prop.Key)
+			if prop.IsObject {
+				walk(prop.Object, name+"_")
+				continue
+			}
+			prop.Key = name
+			variables = append(variables, prop)
+		}
+	}
+	walk(runtimeConfig, "NUXT_")
+	return variables
+}
+
+// snakeCase converts a camelCase key to upper snake case, e.g. apiBase to API_BASE and APIKey to API_KEY
+func snakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == '-' {
+			r = '_'
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// FindObject returns the properties of the object literal that is the value of key (key: {...}),
+// preferring the least nested occurrence in the file, and whether there is one
+func FindObject(content []byte, key string) ([]Property, bool) {
+	tokens := tokenize(content)
+	found, bestDepth := -1, 0
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.is("{"), t.is("("), t.is("["):
+			depth++
+		case t.is("}"), t.is(")"), t.is("]"):
+			depth--
+		case (t.kind == identToken || t.kind == stringToken) && t.text == key &&
+			i+2 < len(tokens) && tokens[i+1].is(":") && tokens[i+2].is("{"):
+			if found < 0 || depth < bestDepth {
+				found, bestDepth = i+2, depth
+			}
+		}
+	}
+	if found < 0 {
+		return nil, false
+	}
+	props, _ := parseObject(content, tokens, found)
+	return props, true
+}
+
+// FindObjects returns every object literal that is the value of a key match accepts, as properties
+// with IsObject set, in file order; objects nested in a found one aren't searched
+func FindObjects(content []byte, match func(key string) bool) []Property {
+	tokens := tokenize(content)
+	var objects []Property
+	for i := 0; i+2 < len(tokens); i++ {
+		t := tokens[i]
+		if (t.kind == identToken || t.kind == stringToken) && match(t.text) && tokens[i+1].is(":") && tokens[i+2].is("{") {
+			object := Property{Key: t.text, Line: t.line, IsObject: true}
+			object.Object, i = parseObject(content, tokens, i+2)
+			objects = append(objects, object)
+			i-- // i is the token after the object
+		}
+	}
+	return objects
+}
+
+// FindArrayObjects returns the object literals in every array literal that is the value of key
+// (key: [{...}, {...}]), as the properties of each object, in file order
+func FindArrayObjects(content []byte, key string) [][]Property {
+	tokens := tokenize(content)
+	var objects [][]Property
+	for i := 0; i+2 < len(tokens); i++ {
+		t := tokens[i]
+		if (t.kind != identToken && t.kind != stringToken) || t.text != key || !tokens[i+1].is(":") || !tokens[i+2].is("[") {
+			continue
+		}
+		for i += 3; i < len(tokens) && !tokens[i].is("]"); {
+			switch {
+			case tokens[i].is("{"):
+				var object []Property
+				object, i = parseObject(content, tokens, i)
+				objects = append(objects, object)
+			case tokens[i].is(","):
+				i++
+			default:
+				i = max(skipValue(tokens, i), i+1)
+			}
+		}
+	}
+	return objects
+}
+
+// parseObject parses the object literal starting at tokens[i] ({), returning its properties and the
+// index of the token after it
+func parseObject(content []byte, tokens []token, i int) ([]Property, int) {
+	var props []Property
+	i++
+	for i < len(tokens) {
+		t := tokens[i]
+		switch {
+		case t.is("}"):
+			return props, i + 1
+		case t.is(","):
+			i++
+			continue
+		case t.is(".") && i+2 < len(tokens) && tokens[i+1].is(".") && tokens[i+2].is("."):
+			// Spread properties (...defaults) can't be resolved
+			i = skipValue(tokens, i+3)
+			continue
+		}
+
+		prop := Property{Line: t.line}
+		switch {
+		case t.kind == identToken || t.kind == stringToken || t.kind == otherToken:
+			prop.Key = t.text
+			i++
+		case t.is("["):
+			// Computed keys can't be resolved
+			i = skipBalanced(tokens, i)
+		default:
+			i++
+			continue
+		}
+		if i >= len(tokens) {
+			break
+		}
+
+		switch next := tokens[i]; {
+		case next.is(":"):
+			i++
+			if i < len(tokens) && tokens[i].is("{") {
+				prop.IsObject = true
+				prop.Object, i = parseObject(content, tokens, i)
+				break
+			}
+			start := i
+			i = skipValue(tokens, i)
+			if i > start {
+				if i == start+1 && tokens[start].kind == stringToken {
+					prop.Value, prop.Literal = tokens[start].text, true
+				} else {
+					prop.Value = string(content[tokens[start].start:tokens[i-1].end])
+				}
+			}
+		case next.is("("):
+			// A method, skip its parameters and body
+			i = skipBalanced(tokens, i)
+			if i < len(tokens) && tokens[i].is("{") {
+				i = skipBalanced(tokens, i)
+			}
+			continue
+		default:
+			// Shorthand property ({ apiKey })
+			prop.Value = prop.Key
+		}
+		props = append(props, prop)
+	}
+	return props, i
+}
+
+// skipValue returns the index of the , or closing bracket ending the value starting at tokens[i]
+// A key starting a later line (key:) also ends it, as Bicep separates properties with newlines
+func skipValue(tokens []token, i int) int {
+	depth := 0
+	for start := i; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case depth == 0 && i > start && t.line > tokens[i-1].line && (t.kind == identToken || t.kind == stringToken) &&
+			i+1 < len(tokens) && tokens[i+1].is(":"):
+			return i
+		case t.is("{"), t.is("("), t.is("["):
+			depth++
+		case t.is("}"), t.is(")"), t.is("]"):
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case t.is(",") && depth == 0:
+			return i
+		}
+	}
+	return i
+}
+
+// skipBalanced returns the index of the token after the bracket closing the one at tokens[i]
+func skipBalanced(tokens []token, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.is("{"), t.is("("), t.is("["):
+			depth++
+		case t.is("}"), t.is(")"), t.is("]"):
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
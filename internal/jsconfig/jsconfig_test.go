@@ -0,0 +1,130 @@
+package jsconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNextEnv(t *testing.T) {
+	content := `// @ts-check
+/** @type {import('next').NextConfig} */
+const nextConfig = {
+  reactStrictMode: true,
+  webpack: (config, { env: ignored }) => config,
+  env: {
+    API_URL: 'https://api.example.com', // the public API
+    "STRIPE_KEY": process.env.STRIPE_KEY,
+    BUILD_ID: ` + "`build-${Date.now()}`" + `,
+    ...extra,
+  },
+}
+
+module.exports = nextConfig
+`
+	got := NextEnv([]byte(content))
+	want := []Property{
+		{Key: "API_URL", Line: 7, Value: "https://api.example.com", Literal: true},
+		{Key: "STRIPE_KEY", Line: 8, Value: "process.env.STRIPE_KEY"},
+		{Key: "BUILD_ID", Line: 9, Value: "build-${Date.now()}", Literal: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NextEnv() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNuxtVariables(t *testing.T) {
+	content := `export default defineNuxtConfig({
+  modules: ['@nuxt/content'],
+  runtimeConfig: {
+    apiSecret: '',
+    stripe: { webhookKey: process.env.STRIPE_WEBHOOK },
+    public: {
+      apiBase: '/api',
+      GAId,
+    },
+  },
+})
+`
+	var got []string
+	for _, v := range NuxtVariables([]byte(content)) {
+		got = append(got, v.Key)
+	}
+	want := []string{"NUXT_API_SECRET", "NUXT_STRIPE_WEBHOOK_KEY", "NUXT_PUBLIC_API_BASE", "NUXT_PUBLIC_GA_ID"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NuxtVariables() = %v, want %v", got, want)
+	}
+	if line := NuxtVariables([]byte(content))[2].Line; line != 7 {
+		t.Errorf("Expected apiBase on line 7, got %d", line)
+	}
+}
+
+func TestFindObjects(t *testing.T) {
+	content := `{
+  "apps": [
+    { "name": "api", "env": { "PORT": 3000 }, "env_production": { "PORT": 80 } },
+    { "name": "worker", "env": { "QUEUE": "jobs" } }
+  ]
+}`
+	var got []string
+	for _, object := range FindObjects([]byte(content), func(key string) bool { return key == "env" || key == "env_production" }) {
+		for _, prop := range object.Object {
+			got = append(got, object.Key+"."+prop.Key+"="+prop.Value)
+		}
+	}
+	want := []string{"env.PORT=3000", "env_production.PORT=80", "env.QUEUE=jobs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindObjects() = %v, want %v", got, want)
+	}
+}
+
+func TestFindArrayObjects(t *testing.T) {
+	// Bicep separates properties with newlines instead of commas
+	content := `resource app 'Microsoft.Web/sites@2022-03-01' = {
+  properties: {
+    siteConfig: {
+      appSettings: [
+        {
+          name: 'STORAGE_URL'
+          value: storage.properties.primaryEndpoints.blob
+        }
+        { name: 'LOG_LEVEL', value: 'info' }
+      ]
+    }
+  }
+}
+`
+	var got []string
+	for _, object := range FindArrayObjects([]byte(content), "appSettings") {
+		for _, prop := range object {
+			got = append(got, prop.Key+"="+prop.Value)
+		}
+	}
+	want := []string{"name=STORAGE_URL", "value=storage.properties.primaryEndpoints.blob", "name=LOG_LEVEL", "value=info"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindArrayObjects() = %v, want %v", got, want)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"apiBase":    "API_BASE",
+		"APIKey":     "API_KEY",
+		"oauth2Url":  "OAUTH2_URL",
+		"sentry-dsn": "SENTRY_DSN",
+		"token":      "TOKEN",
+	}
+	for key, want := range tests {
+		if got := snakeCase(key); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestIsConfig(t *testing.T) {
+	if !IsNextConfig("web/next.config.mjs") || !IsNuxtConfig("nuxt.config.ts") {
+		t.Error("Expected Next and Nuxt config files to be recognized")
+	}
+	if IsNextConfig("next.config.json") || IsNuxtConfig("next.config.ts") {
+		t.Error("Expected other files not to be recognized")
+	}
+}
@@ -0,0 +1,90 @@
+package jsconfig
+
+import "strings"
+
+// tokenKind is the kind of a JavaScript token
+type tokenKind int
+
+const (
+	identToken  tokenKind = iota // Identifier or keyword
+	stringToken                  // String or template literal, text is its content
+	punctToken                   // Single punctuation character
+	otherToken                   // Number
+)
+
+// token is a JavaScript token with its byte range and line
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+	line       int
+}
+
+// is reports whether t is the punctuation character p
+func (t token) is(p string) bool {
+	return t.kind == punctToken && t.text == p
+}
+
+// tokenize splits JavaScript source into tokens, dropping whitespace and comments
+// Regular expression literals aren't recognized, config files rarely contain them
+func tokenize(content []byte) []token {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(string(content[i+2:]), "*/")
+			if end < 0 {
+				end = len(content) - i - 4
+			}
+			next := i + 2 + end + 2
+			line += strings.Count(string(content[i:next]), "\n")
+			i = next
+		case c == '"' || c == '\'' || c == '`':
+			start, startLine := i, line
+			var text strings.Builder
+			for i++; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				text.WriteByte(content[i])
+			}
+			i++
+			tokens = append(tokens, token{kind: stringToken, text: text.String(), start: start, end: min(i, len(content)), line: startLine})
+		case isIdentStart(c):
+			start := i
+			for i < len(content) && (isIdentStart(content[i]) || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: identToken, text: string(content[start:i]), start: start, end: i, line: line})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(content) && (isIdentStart(content[i]) || (content[i] >= '0' && content[i] <= '9') || content[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: otherToken, text: string(content[start:i]), start: start, end: i, line: line})
+		default:
+			tokens = append(tokens, token{kind: punctToken, text: string(c), start: i, end: i + 1, line: line})
+			i++
+		}
+	}
+	return tokens
+}
+
+// isIdentStart reports whether c can start a JavaScript identifier
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
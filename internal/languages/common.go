@@ -10,47 +10,32 @@ type EnvVarMatch struct {
 
 // LanguageInfo contains query and extraction function for a language
 type LanguageInfo struct {
-	Query     string
-	Extractor func([]map[string]string) []string // Returns []string for backward compatibility
+	Name       string   // Language name used throughout envgrd (e.g., "go", "typescript")
+	Extensions []string // File extensions (with leading dot) that map to this language
+	Grammar    Grammar  // Loads the Tree-Sitter grammar
+	Query      string
+	Extractor  func([]map[string]string) []string // Returns []string for backward compatibility
 	// For JavaScript/TypeScript, we'll use a special handler
-	ExtractorWithPartial func([]map[string]string) []EnvVarMatch // Returns matches with partial info
+	ExtractorWithPartial Extractor           // Returns matches with partial info
+	HasFallback          FallbackDetector    // Detects lookups with a default value, nil if not supported
+	ExpectedType         TypeDetector        // Infers the type code parses a value as, nil if not supported
+	Constants            ConstantResolver    // Resolves references to string constants, nil if not supported
+	PackageConstants     bool                // Constants are shared by the files of a directory (a Go package)
+	Source               SourceExtractor  // Extracts the embedded code to parse, nil to parse the whole file
+	Patterns             []Pattern        // Replace Grammar and Query in builds without cgo, see Pattern
 }
 
 // GetLanguageInfo returns the query and extractor for a given language
+// Returns nil if no language with that name has been registered
 func GetLanguageInfo(lang string) *LanguageInfo {
-	switch lang {
-	case "javascript", "typescript":
-		return &LanguageInfo{
-			Query:                JavaScriptQuery,
-			Extractor:            nil, // Not used for JS/TS
-			ExtractorWithPartial: ExtractEnvVarsFromJS,
-		}
-	case "go":
-		return &LanguageInfo{
-			Query:                GoQuery,
-			Extractor:            ExtractEnvVarsFromGo, // For backward compatibility
-			ExtractorWithPartial: ExtractEnvVarsFromGoWithPartial,
-		}
-	case "python":
-		return &LanguageInfo{
-			Query:                PythonQuery,
-			Extractor:            ExtractEnvVarsFromPython, // For backward compatibility
-			ExtractorWithPartial: ExtractEnvVarsFromPythonWithPartial,
-		}
-	case "rust":
-		return &LanguageInfo{
-			Query:                RustQuery,
-			Extractor:            ExtractEnvVarsFromRust, // For backward compatibility
-			ExtractorWithPartial: ExtractEnvVarsFromRustWithPartial,
-		}
-	case "java":
-		return &LanguageInfo{
-			Query:                JavaQuery,
-			Extractor:            ExtractEnvVarsFromJava, // For backward compatibility
-			ExtractorWithPartial: ExtractEnvVarsFromJavaWithPartial,
-		}
-	default:
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	info, ok := registry[lang]
+	if !ok {
 		return nil
 	}
+	// Return a copy so callers can't mutate the registry
+	infoCopy := *info
+	return &infoCopy
 }
-
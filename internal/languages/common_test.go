@@ -1,3 +1,5 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
 import (
@@ -78,6 +80,9 @@ func TestGetLanguageInfo(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.expected != nil {
+				skipWithoutLanguage(t, tt.lang)
+			}
 			result := GetLanguageInfo(tt.lang)
 			if tt.expected == nil {
 				if result != nil {
@@ -0,0 +1,64 @@
+package languages
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SourceExtractor returns the code embedded in a file (e.g., the <script> blocks of a .vue file) and,
+// when code lines don't keep their line numbers, the file line (1-based) each code line comes from
+type SourceExtractor func(content []byte) (code []byte, lines []int)
+
+// scriptBlock matches a <script> element, capturing its attributes and its content
+var scriptBlock = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script\s*>`)
+
+// scriptType matches the type attribute of a <script> element
+var scriptType = regexp.MustCompile(`(?i)\btype\s*=\s*["']?([^"'\s>]+)`)
+
+// astroFrontmatter matches the code fence at the top of an Astro component, capturing its content
+var astroFrontmatter = regexp.MustCompile(`(?s)\A\s*---\r?\n(.*?)\r?\n---`)
+
+// scriptBlocks keeps the content of the JavaScript <script> blocks of a component
+// Blocks of other types (e.g., application/ld+json) are dropped
+func scriptBlocks(content []byte) ([]byte, []int) {
+	var regions [][2]int
+	for _, m := range scriptBlock.FindAllSubmatchIndex(content, -1) {
+		if t := scriptType.FindSubmatch(content[m[2]:m[3]]); t != nil && !isScriptType(string(t[1])) {
+			continue
+		}
+		regions = append(regions, [2]int{m[4], m[5]})
+	}
+	return keepRegions(content, regions), nil
+}
+
+// astroScripts keeps the frontmatter and the JavaScript <script> blocks of an Astro component
+func astroScripts(content []byte) ([]byte, []int) {
+	scripts, _ := scriptBlocks(content)
+	if m := astroFrontmatter.FindSubmatchIndex(content); m != nil {
+		copy(scripts[m[2]:m[3]], content[m[2]:m[3]])
+	}
+	return scripts, nil
+}
+
+// isScriptType reports whether a <script> type attribute denotes JavaScript or TypeScript
+func isScriptType(t string) bool {
+	t = strings.ToLower(t)
+	return t == "module" || strings.Contains(t, "javascript") || strings.Contains(t, "typescript") || strings.Contains(t, "ecmascript")
+}
+
+// keepRegions blanks out content outside the given byte ranges, keeping line breaks so that line
+// numbers of the kept code don't change
+func keepRegions(content []byte, regions [][2]int) []byte {
+	out := make([]byte, len(content))
+	for i, c := range content {
+		if c == '\n' || c == '\r' {
+			out[i] = c
+		} else {
+			out[i] = ' '
+		}
+	}
+	for _, r := range regions {
+		copy(out[r[0]:r[1]], content[r[0]:r[1]])
+	}
+	return out
+}
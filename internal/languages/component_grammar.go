@@ -0,0 +1,36 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_typescript
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+// Single-file components embed JavaScript or TypeScript in <script> blocks (and, for Astro, in the
+// frontmatter). Only that code is parsed, with the TypeScript grammar since it also reads plain
+// JavaScript, and queried like any other JavaScript file
+func init() {
+	for _, component := range []struct {
+		name   string
+		ext    string
+		source SourceExtractor
+	}{
+		{"vue", ".vue", scriptBlocks},
+		{"svelte", ".svelte", scriptBlocks},
+		{"astro", ".astro", astroScripts},
+	} {
+		RegisterLanguage(LanguageInfo{
+			Name:       component.name,
+			Extensions: []string{component.ext},
+			Grammar: func() (*sitter.Language, error) {
+				return loadGrammar("TypeScript", tree_sitter_typescript.LanguageTypescript())
+			},
+			Query:                JavaScriptQuery,
+			ExtractorWithPartial: ExtractEnvVarsFromJS,
+			HasFallback:          HasFallbackJS,
+			ExpectedType:         ExpectedTypeJS,
+			Source:               component.source,
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package languages
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScriptBlocks(t *testing.T) {
+	content := `<template>
+  <p>{{ process.env.IN_TEMPLATE }}</p>
+</template>
+<script setup lang="ts">
+const url = process.env.API_URL
+</script>
+<script type="application/ld+json">{"key": "process.env.IN_JSON"}</script>
+<SCRIPT type="module">process.env.MODULE_KEY</SCRIPT>
+`
+	scripts, _ := scriptBlocks([]byte(content))
+	got := string(scripts)
+	if len(got) != len(content) || strings.Count(got, "\n") != strings.Count(content, "\n") {
+		t.Fatalf("Expected offsets and line breaks to be kept, got %q", got)
+	}
+	for _, want := range []string{"const url = process.env.API_URL", "process.env.MODULE_KEY"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q to be kept, got %q", want, got)
+		}
+	}
+	for _, dropped := range []string{"IN_TEMPLATE", "IN_JSON", "<script", "template"} {
+		if strings.Contains(got, dropped) {
+			t.Errorf("Expected %q to be blanked out, got %q", dropped, got)
+		}
+	}
+}
+
+func TestAstroScripts(t *testing.T) {
+	content := `---
+const title = import.meta.env.TITLE ?? process.env.SITE_TITLE;
+---
+<h1>{process.env.IN_MARKUP}</h1>
+<script>console.log(process.env.CLIENT_KEY)</script>
+`
+	scripts, _ := astroScripts([]byte(content))
+	got := string(scripts)
+	for _, want := range []string{"process.env.SITE_TITLE", "process.env.CLIENT_KEY"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q to be kept, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "IN_MARKUP") || strings.Contains(got, "---") {
+		t.Errorf("Expected markup and fences to be blanked out, got %q", got)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build cgo && !envgrd_purego
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ConstantResolver collects the string constants a file defines (name -> value), so that a variable
+// reference to one of them (e.g., System.getenv(Config.DB_URL_KEY)) is reported as a static key
+type ConstantResolver func(root *sitter.Node, content []byte) map[string]string
+
+// walk calls visit for node and all of its descendants, in document order
+func walk(node *sitter.Node, visit func(*sitter.Node)) {
+	visit(node)
+	for i := uint(0); i < node.ChildCount(); i++ {
+		if child := node.Child(i); child != nil {
+			walk(child, visit)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+//go:build cgo && !envgrd_purego
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// FallbackDetector reports whether the environment lookup containing node (the @key capture)
+// provides a default value, making the variable optional (e.g., process.env.X || "y")
+type FallbackDetector func(node *sitter.Node, content []byte) bool
+
+// TypeDetector infers the type the code around the environment lookup containing node (the @key capture)
+// parses its value as (schema.TypeInteger for strconv.Atoi(os.Getenv("PORT"))), or returns "" when it isn't parsed
+type TypeDetector func(node *sitter.Node, content []byte) string
+
+// enclosing returns the nearest ancestor of node with one of the given kinds, or nil
+func enclosing(node *sitter.Node, kinds ...string) *sitter.Node {
+	for current := node.Parent(); current != nil; current = current.Parent() {
+		for _, kind := range kinds {
+			if current.Kind() == kind {
+				return current
+			}
+		}
+	}
+	return nil
+}
+
+// unwrapParens returns the outermost parenthesized expression wrapping node, or node itself
+func unwrapParens(node *sitter.Node, parenKind string) *sitter.Node {
+	for {
+		parent := node.Parent()
+		if parent == nil || parent.Kind() != parenKind {
+			return node
+		}
+		node = parent
+	}
+}
+
+// isLeftOperand reports whether node is the left operand of a binary node using one of the operators
+func isLeftOperand(node *sitter.Node, content []byte, binaryKind string, operators ...string) bool {
+	parent := node.Parent()
+	if parent == nil || parent.Kind() != binaryKind {
+		return false
+	}
+	left := parent.ChildByFieldName("left")
+	if left == nil || !left.Equals(*node) {
+		return false
+	}
+	operator := parent.ChildByFieldName("operator")
+	if operator == nil {
+		return false
+	}
+	text := operator.Utf8Text(content)
+	for _, op := range operators {
+		if text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// wrappingCall returns the call of callKind passing expr as its first argument (in an argsKind list), or nil
+func wrappingCall(expr *sitter.Node, argsKind string, callKind string) *sitter.Node {
+	args := expr.Parent()
+	if args == nil || args.Kind() != argsKind {
+		return nil
+	}
+	if first := args.NamedChild(0); first == nil || !first.Equals(*expr) {
+		return nil
+	}
+	call := args.Parent()
+	if call == nil || call.Kind() != callKind {
+		return nil
+	}
+	return call
+}
+
+// namedArgCount counts the arguments in an argument list, skipping comments
+func namedArgCount(args *sitter.Node) int {
+	count := 0
+	for i := uint(0); i < args.NamedChildCount(); i++ {
+		if child := args.NamedChild(i); child != nil && !child.IsExtra() {
+			count++
+		}
+	}
+	return count
+}
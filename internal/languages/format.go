@@ -0,0 +1,95 @@
+package languages
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// interpolate renders a format string as a concatenation of its literal parts and the operands of
+// its placeholders, e.g. "WORKER_%d_URL" formatted with i gives "WORKER_" + i + "_URL", which the
+// analyzer matches like any concatenation (WORKER_*_URL)
+// placeholders matches the placeholders and escapes of the format; arg returns the operand of the
+// n-th placeholder, or "" with the text an escape stands for (e.g. "%" for %%)
+func interpolate(format string, placeholders *regexp.Regexp, arg func(placeholder []string, n int) (operand string, literal string)) string {
+	var parts []string
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, strconv.Quote(literal.String()))
+			literal.Reset()
+		}
+	}
+
+	end, n := 0, 0
+	for _, loc := range placeholders.FindAllStringSubmatchIndex(format, -1) {
+		literal.WriteString(format[end:loc[0]])
+		end = loc[1]
+
+		placeholder := make([]string, len(loc)/2)
+		for i := range placeholder {
+			if loc[2*i] >= 0 {
+				placeholder[i] = format[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		operand, text := arg(placeholder, n)
+		if operand == "" {
+			literal.WriteString(text)
+			continue
+		}
+		n++
+		flush()
+		parts = append(parts, operand)
+	}
+	literal.WriteString(format[end:])
+	flush()
+	return strings.Join(parts, " + ")
+}
+
+// argAt returns the n-th argument, or the placeholder itself when the call has too few arguments
+func argAt(args []string, n int, placeholder string) string {
+	if n < len(args) && args[n] != "" {
+		return args[n]
+	}
+	return placeholder
+}
+
+// splitArgs splits a comma-separated argument list (without its parentheses) at the top level,
+// leaving commas inside brackets and string literals alone
+func splitArgs(list string) []string {
+	var args []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); last != "" || len(args) > 0 {
+		args = append(args, last)
+	}
+	return args
+}
+
+// callArgs returns the arguments of a call's argument list, e.g. ("A_%s", name) gives "A_%s" and name
+func callArgs(argumentList string) []string {
+	list := strings.TrimSpace(argumentList)
+	list = strings.TrimPrefix(list, "(")
+	list = strings.TrimSuffix(list, ")")
+	return splitArgs(list)
+}
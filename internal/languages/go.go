@@ -1,7 +1,19 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
-// GoQuery is the Tree-Sitter query for finding os.Getenv("KEY") patterns
-// Also supports dynamic patterns like os.Getenv("prefix_" + var) and os.Getenv(var)
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/schema"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// GoQuery is the Tree-Sitter query for finding os.Getenv("KEY") and os.LookupEnv("KEY") patterns
+// Also supports dynamic patterns like os.Getenv("prefix_" + var), os.Getenv(var) and
+// os.Getenv(fmt.Sprintf("WORKER_%d_URL", i))
 // Note: We don't use predicates here, filtering is done in ExtractEnvVarsFromGo
 const GoQuery = `
 [
@@ -26,6 +38,19 @@ const GoQuery = `
     )
     arguments: (argument_list (identifier) @var)
   )
+  (call_expression
+    function: (selector_expression
+      operand: (identifier) @obj
+      field: (field_identifier) @fn
+    )
+    arguments: (argument_list . (call_expression
+      function: (selector_expression
+        operand: (identifier) @format_obj
+        field: (field_identifier) @format_fn
+      )
+      arguments: (argument_list) @format_args
+    ) @key)
+  )
 ]
 `
 
@@ -49,15 +74,30 @@ func ExtractEnvVarsFromGoWithPartial(matches []map[string]string) []EnvVarMatch
 	seen := make(map[string]bool)
 
 	for _, match := range matches {
-		// Validate that this is actually os.Getenv
+		// Validate that this is actually os.Getenv or os.LookupEnv
 		obj, objOk := match["obj"]
 		fn, fnOk := match["fn"]
 
-		if !objOk || !fnOk || obj != "os" || fn != "Getenv" {
+		if !objOk || !fnOk || obj != "os" || (fn != "Getenv" && fn != "LookupEnv") {
 			continue
 		}
 
-		// Case 1: Static key (string literal)
+		// Case 1: fmt.Sprintf("WORKER_%d_URL", i), a static key without verbs and a dynamic pattern otherwise
+		if formatFn, ok := match["format_fn"]; ok {
+			key, partial, ok := goSprintf(match["format_obj"], formatFn, callArgs(match["format_args"]))
+			if !ok || key == "" || seen[key] {
+				continue
+			}
+			if partial {
+				results = append(results, EnvVarMatch{Key: key, IsPartial: true, FullExpr: key})
+			} else {
+				results = append(results, EnvVarMatch{Key: key, IsPartial: false})
+			}
+			seen[key] = true
+			continue
+		}
+
+		// Case 2: Static key (string literal)
 		key, keyOk := match["key"]
 		if keyOk && key != "" {
 			key = trimQuotes(key)
@@ -68,7 +108,7 @@ func ExtractEnvVarsFromGoWithPartial(matches []map[string]string) []EnvVarMatch
 			continue
 		}
 
-		// Case 2: Binary expression (e.g., "prefix_" + var, var + "_suffix", "asdf" + var + "fff")
+		// Case 3: Binary expression (e.g., "prefix_" + var, var + "_suffix", "asdf" + var + "fff")
 		fullExpr, fullExprOk := match["full_expr"]
 		if fullExprOk && fullExpr != "" {
 			if !seen[fullExpr] {
@@ -83,7 +123,7 @@ func ExtractEnvVarsFromGoWithPartial(matches []map[string]string) []EnvVarMatch
 			continue
 		}
 
-		// Case 3: Variable identifier (e.g., os.Getenv(var))
+		// Case 4: Variable identifier (e.g., os.Getenv(var)), resolved by the parser when it names a constant
 		varName, varOk := match["var"]
 		if varOk && varName != "" {
 			if !seen[varName] {
@@ -100,6 +140,180 @@ func ExtractEnvVarsFromGoWithPartial(matches []map[string]string) []EnvVarMatch
 	return results
 }
 
+// goVerb matches the verbs of a fmt format string (%s, %d, %[2]v, %-5s) and its %% escapes
+var goVerb = regexp.MustCompile(`%%|%[-+# 0]*(?:\[(\d+)\])?(?:\*|\d+)?(?:\.(?:\*|\d+))?[a-zA-Z]`)
+
+// goSprintf renders fmt.Sprintf(format, args...) as a concatenation of the format's literal parts and
+// the arguments, e.g. fmt.Sprintf("WORKER_%d_URL", i) gives "WORKER_" + i + "_URL"
+func goSprintf(pkg string, fn string, args []string) (key string, partial bool, ok bool) {
+	if pkg != "fmt" || fn != "Sprintf" || len(args) == 0 {
+		return "", false, false
+	}
+	if !strings.HasPrefix(args[0], `"`) && !strings.HasPrefix(args[0], "`") {
+		return "", false, false
+	}
+	format := trimQuotes(args[0])
+	args = args[1:]
+
+	// Like fmt, an explicit index ([2]) also moves the following verbs to the arguments after it
+	verbs, next := false, 0
+	expr := interpolate(format, goVerb, func(verb []string, _ int) (string, string) {
+		if verb[0] == "%%" {
+			return "", "%"
+		}
+		verbs = true
+		if i, err := strconv.Atoi(verb[1]); err == nil && i > 0 {
+			next = i - 1
+		}
+		next++
+		return argAt(args, next-1, verb[0]), ""
+	})
+	if !verbs {
+		return format, false, true
+	}
+	return expr, true, true
+}
+
+// GoConstants collects the package-level string constants of a file: const declarations whose value
+// is a string literal or a concatenation of literals and constants declared before them
+func GoConstants(root *sitter.Node, content []byte) map[string]string {
+	constants := make(map[string]string)
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		declaration := root.NamedChild(i)
+		if declaration.Kind() != "const_declaration" {
+			continue
+		}
+		for j := uint(0); j < declaration.NamedChildCount(); j++ {
+			spec := declaration.NamedChild(j)
+			if spec.Kind() != "const_spec" {
+				continue
+			}
+			if t := spec.ChildByFieldName("type"); t != nil && t.Utf8Text(content) != "string" {
+				continue
+			}
+			value := spec.ChildByFieldName("value")
+			if value == nil {
+				continue
+			}
+			cursor := spec.Walk()
+			names := spec.ChildrenByFieldName("name", cursor)
+			cursor.Close()
+			for k := range names {
+				if k >= int(value.NamedChildCount()) {
+					break
+				}
+				if resolved, ok := goConstantValue(value.NamedChild(uint(k)), content, constants); ok {
+					constants[names[k].Utf8Text(content)] = resolved
+				}
+			}
+		}
+	}
+	return constants
+}
+
+// goConstantValue evaluates a constant expression made of string literals, + and earlier constants
+func goConstantValue(node *sitter.Node, content []byte, constants map[string]string) (string, bool) {
+	switch node.Kind() {
+	case "interpreted_string_literal":
+		value, err := strconv.Unquote(node.Utf8Text(content))
+		return value, err == nil
+	case "raw_string_literal":
+		return trimQuotes(node.Utf8Text(content)), true
+	case "parenthesized_expression":
+		if node.NamedChildCount() == 1 {
+			return goConstantValue(node.NamedChild(0), content, constants)
+		}
+	case "identifier":
+		value, ok := constants[node.Utf8Text(content)]
+		return value, ok
+	case "binary_expression":
+		operator := node.ChildByFieldName("operator")
+		left, right := node.ChildByFieldName("left"), node.ChildByFieldName("right")
+		if operator == nil || operator.Kind() != "+" || left == nil || right == nil {
+			return "", false
+		}
+		l, ok := goConstantValue(left, content, constants)
+		if !ok {
+			return "", false
+		}
+		r, ok := goConstantValue(right, content, constants)
+		return l + r, ok
+	}
+	return "", false
+}
+
+// HasFallbackGo reports whether an os.Getenv lookup has a default: os.LookupEnv, or a result
+// assigned to a variable that is immediately checked for "" (v := os.Getenv("X"); if v == "" { ... })
+func HasFallbackGo(node *sitter.Node, content []byte) bool {
+	call := enclosing(node, "call_expression")
+	if call == nil {
+		return false
+	}
+	if fn := call.ChildByFieldName("function"); fn != nil {
+		if field := fn.ChildByFieldName("field"); field != nil && field.Utf8Text(content) == "LookupEnv" {
+			return true
+		}
+	}
+
+	values := call.Parent()
+	if values == nil || values.Kind() != "expression_list" || namedArgCount(values) != 1 {
+		return false
+	}
+	assignment := values.Parent()
+	if assignment == nil {
+		return false
+	}
+
+	var name *sitter.Node
+	statement := assignment
+	switch assignment.Kind() {
+	case "short_var_declaration", "assignment_statement":
+		if left := assignment.ChildByFieldName("left"); left != nil && namedArgCount(left) == 1 {
+			name = left.NamedChild(0)
+		}
+	case "var_spec":
+		name = assignment.ChildByFieldName("name")
+		statement = enclosing(assignment, "var_declaration")
+	}
+	if name == nil || name.Kind() != "identifier" || statement == nil {
+		return false
+	}
+
+	// The check is either the if statement the assignment initializes, or the statement right after it
+	var check *sitter.Node
+	if parent := statement.Parent(); parent != nil && parent.Kind() == "if_statement" {
+		check = parent
+	} else {
+		check = statement.NextNamedSibling()
+	}
+	if check == nil || check.Kind() != "if_statement" {
+		return false
+	}
+	condition := check.ChildByFieldName("condition")
+	return condition != nil && comparesWithEmptyString(condition, name.Utf8Text(content), content)
+}
+
+// comparesWithEmptyString reports whether condition is name == "" or name != "" (in either order)
+func comparesWithEmptyString(condition *sitter.Node, name string, content []byte) bool {
+	if condition.Kind() != "binary_expression" {
+		return false
+	}
+	operator := condition.ChildByFieldName("operator")
+	left := condition.ChildByFieldName("left")
+	right := condition.ChildByFieldName("right")
+	if operator == nil || left == nil || right == nil {
+		return false
+	}
+	if op := operator.Utf8Text(content); op != "==" && op != "!=" {
+		return false
+	}
+	isName := func(n *sitter.Node) bool { return n.Kind() == "identifier" && n.Utf8Text(content) == name }
+	isEmpty := func(n *sitter.Node) bool {
+		return (n.Kind() == "interpreted_string_literal" || n.Kind() == "raw_string_literal") && trimQuotes(n.Utf8Text(content)) == ""
+	}
+	return (isName(left) && isEmpty(right)) || (isEmpty(left) && isName(right))
+}
+
 // trimQuotes removes surrounding quotes from a string
 func trimQuotes(s string) string {
 	if len(s) >= 2 {
@@ -111,3 +325,34 @@ func trimQuotes(s string) string {
 	}
 	return s
 }
+
+// goParsers maps strconv functions to the type they parse a value as
+var goParsers = map[string]string{
+	"Atoi":       schema.TypeInteger,
+	"ParseInt":   schema.TypeInteger,
+	"ParseUint":  schema.TypeInteger,
+	"ParseFloat": schema.TypeNumber,
+	"ParseBool":  schema.TypeBoolean,
+}
+
+// ExpectedTypeGo infers the type of an os.Getenv lookup passed straight to a strconv parser,
+// e.g. strconv.Atoi(os.Getenv("PORT"))
+func ExpectedTypeGo(node *sitter.Node, content []byte) string {
+	lookup := enclosing(node, "call_expression")
+	if lookup == nil {
+		return ""
+	}
+	call := wrappingCall(lookup, "argument_list", "call_expression")
+	if call == nil {
+		return ""
+	}
+	fn := call.ChildByFieldName("function")
+	if fn == nil || fn.Kind() != "selector_expression" {
+		return ""
+	}
+	operand, field := fn.ChildByFieldName("operand"), fn.ChildByFieldName("field")
+	if operand == nil || field == nil || operand.Utf8Text(content) != "strconv" {
+		return ""
+	}
+	return goParsers[field.Utf8Text(content)]
+}
@@ -0,0 +1,25 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_go
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+)
+
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "go",
+		Extensions: []string{".go"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("Go", tree_sitter_go.Language())
+		},
+		Query:                GoQuery,
+		Extractor:            ExtractEnvVarsFromGo, // For backward compatibility
+		ExtractorWithPartial: ExtractEnvVarsFromGoWithPartial,
+		HasFallback:          HasFallbackGo,
+		ExpectedType:         ExpectedTypeGo,
+		Constants:            GoConstants,
+		PackageConstants:     true,
+	})
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
 import (
@@ -100,6 +102,44 @@ func TestExtractEnvVarsFromGo_DynamicPatterns(t *testing.T) {
 				{Key: "envVar", IsPartial: true, IsVarRef: true},
 			},
 		},
+		{
+			name: "fmt.Sprintf",
+			matches: []map[string]string{
+				{
+					"obj":         "os",
+					"fn":          "Getenv",
+					"format_obj":  "fmt",
+					"format_fn":   "Sprintf",
+					"format_args": `("WORKER_%d_URL", i)`,
+				},
+				{
+					"obj":         "os",
+					"fn":          "LookupEnv",
+					"format_obj":  "fmt",
+					"format_fn":   "Sprintf",
+					"format_args": "(`%[2]s_%[1]v_%-5s_100%%`, strings.ToUpper(name), env)",
+				},
+				{
+					"obj":         "os",
+					"fn":          "Getenv",
+					"format_obj":  "fmt",
+					"format_fn":   "Sprintf",
+					"format_args": `("PLAIN")`,
+				},
+				{
+					"obj":         "os",
+					"fn":          "Getenv",
+					"format_obj":  "strings",
+					"format_fn":   "ToUpper",
+					"format_args": `("ignored")`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"WORKER_" + i + "_URL"`, IsPartial: true, FullExpr: `"WORKER_" + i + "_URL"`},
+				{Key: `env + "_" + strings.ToUpper(name) + "_" + env + "_100%"`, IsPartial: true, FullExpr: `env + "_" + strings.ToUpper(name) + "_" + env + "_100%"`},
+				{Key: "PLAIN", IsPartial: false},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,14 +1,27 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
-// JavaQuery is the Tree-Sitter query for finding System.getenv("KEY") and System.getenv().get("KEY") patterns
-// Also supports dynamic patterns like System.getenv("prefix_" + var) and System.getenv(var)
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/schema"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// JavaQuery is the Tree-Sitter query for finding System.getenv("KEY"), System.getenv().get("KEY")
+// and System.getenv().getOrDefault("KEY", ...) patterns
+// Also supports dynamic patterns like System.getenv("prefix_" + var), System.getenv(var),
+// System.getenv(String.format("APP_%s", name)) and System.getenv(Constants.DB_URL_KEY)
 // Note: We don't use predicates here, filtering is done in ExtractEnvVarsFromJava
 const JavaQuery = `
 [
   (method_invocation
     object: (identifier) @obj
     name: (identifier) @method
-    arguments: (argument_list (string_literal) @key)
+    arguments: (argument_list . (string_literal) @key)
   )
   (method_invocation
     object: (method_invocation
@@ -16,7 +29,7 @@ const JavaQuery = `
       name: (identifier) @method1
     )
     name: (identifier) @method2
-    arguments: (argument_list (string_literal) @key)
+    arguments: (argument_list . (string_literal) @key)
   )
   (method_invocation
     object: (identifier) @obj
@@ -44,6 +57,40 @@ const JavaQuery = `
     name: (identifier) @method2
     arguments: (argument_list (identifier) @var)
   )
+  (method_invocation
+    object: (identifier) @obj
+    name: (identifier) @method
+    arguments: (argument_list . (field_access) @var)
+  )
+  (method_invocation
+    object: (method_invocation
+      object: (identifier) @obj
+      name: (identifier) @method1
+    )
+    name: (identifier) @method2
+    arguments: (argument_list . (field_access) @var)
+  )
+  (method_invocation
+    object: (identifier) @obj
+    name: (identifier) @method
+    arguments: (argument_list . (method_invocation
+      object: (_) @format_obj
+      name: (identifier) @format_method
+      arguments: (argument_list) @format_args
+    ) @key)
+  )
+  (method_invocation
+    object: (method_invocation
+      object: (identifier) @obj
+      name: (identifier) @method1
+    )
+    name: (identifier) @method2
+    arguments: (argument_list . (method_invocation
+      object: (_) @format_obj
+      name: (identifier) @format_method
+      arguments: (argument_list) @format_args
+    ) @key)
+  )
 ]
 `
 
@@ -80,7 +127,7 @@ func ExtractEnvVarsFromJavaWithPartial(matches []map[string]string) []EnvVarMatc
 		isValidCall := false
 		if methodOk && method == "getenv" {
 			isValidCall = true
-		} else if method1Ok && method2Ok && method1 == "getenv" && method2 == "get" {
+		} else if method1Ok && method2Ok && method1 == "getenv" && (method2 == "get" || method2 == "getOrDefault") {
 			isValidCall = true
 		}
 
@@ -88,7 +135,23 @@ func ExtractEnvVarsFromJavaWithPartial(matches []map[string]string) []EnvVarMatc
 			continue
 		}
 
-		// Case 1: Static key (string literal)
+		// Case 1: String.format("APP_%s", name) or "APP_%s".formatted(name), a static key without
+		// format specifiers and a dynamic pattern otherwise
+		if formatMethod, ok := match["format_method"]; ok {
+			key, partial, ok := javaFormat(match["format_obj"], formatMethod, callArgs(match["format_args"]))
+			if !ok || key == "" || seen[key] {
+				continue
+			}
+			if partial {
+				results = append(results, EnvVarMatch{Key: key, IsPartial: true, FullExpr: key})
+			} else {
+				results = append(results, EnvVarMatch{Key: key, IsPartial: false})
+			}
+			seen[key] = true
+			continue
+		}
+
+		// Case 2: Static key (string literal)
 		key, keyOk := match["key"]
 		if keyOk && key != "" {
 			key = trimQuotes(key)
@@ -99,7 +162,7 @@ func ExtractEnvVarsFromJavaWithPartial(matches []map[string]string) []EnvVarMatc
 			continue
 		}
 
-		// Case 2: Binary expression (e.g., "prefix_" + var, var + "_suffix")
+		// Case 3: Binary expression (e.g., "prefix_" + var, var + "_suffix")
 		fullExpr, fullExprOk := match["full_expr"]
 		if fullExprOk && fullExpr != "" {
 			if !seen[fullExpr] {
@@ -113,7 +176,7 @@ func ExtractEnvVarsFromJavaWithPartial(matches []map[string]string) []EnvVarMatc
 			continue
 		}
 
-		// Case 3: Variable identifier (e.g., System.getenv(var))
+		// Case 4: Variable identifier or constant (e.g., System.getenv(var), System.getenv(Config.KEY))
 		varName, varOk := match["var"]
 		if varOk && varName != "" {
 			if !seen[varName] {
@@ -130,3 +193,223 @@ func ExtractEnvVarsFromJavaWithPartial(matches []map[string]string) []EnvVarMatc
 	return results
 }
 
+// javaFormatSpecifier matches the format specifiers of a Java format string (%s, %2$s, %-5d), its
+// %% and %n escapes
+var javaFormatSpecifier = regexp.MustCompile(`%%|%n|%(?:(\d+)\$)?[-#+ 0,(<]*\d*(?:\.\d+)?[tT]?[a-zA-Z]`)
+
+// javaFormat renders String.format(format, args...) or format.formatted(args...) as a concatenation
+// of the format's literal parts and the arguments, e.g. String.format("APP_%s", name) gives
+// "APP_" + name
+func javaFormat(obj string, method string, args []string) (key string, partial bool, ok bool) {
+	var format string
+	switch {
+	case obj == "String" && method == "format":
+		// String.format(Locale.ROOT, "APP_%s", name) passes a locale first
+		if len(args) > 1 && !strings.HasPrefix(args[0], `"`) {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return "", false, false
+		}
+		format, args = args[0], args[1:]
+	case method == "formatted":
+		format = obj
+	default:
+		return "", false, false
+	}
+	if !strings.HasPrefix(format, `"`) {
+		return "", false, false
+	}
+	format = trimQuotes(format)
+
+	specifiers := false
+	expr := interpolate(format, javaFormatSpecifier, func(specifier []string, n int) (string, string) {
+		switch specifier[0] {
+		case "%%":
+			return "", "%"
+		case "%n":
+			return "", "\n"
+		}
+		specifiers = true
+		if specifier[1] != "" {
+			if i, err := strconv.Atoi(specifier[1]); err == nil && i > 0 {
+				return argAt(args, i-1, specifier[0]), ""
+			}
+		}
+		return argAt(args, n, specifier[0]), ""
+	})
+	if !specifiers {
+		return format, false, true
+	}
+	return expr, true, true
+}
+
+// JavaConstants collects the static final String fields of a file, and the constants of its
+// interfaces, by name and by Class.NAME
+// A constant may be a literal or a concatenation of literals and earlier constants
+func JavaConstants(root *sitter.Node, content []byte) map[string]string {
+	constants := make(map[string]string)
+	walk(root, func(node *sitter.Node) {
+		switch node.Kind() {
+		case "field_declaration":
+			if !javaStaticFinal(node, content) {
+				return
+			}
+		case "constant_declaration":
+		default:
+			return
+		}
+		if t := node.ChildByFieldName("type"); t == nil || t.Utf8Text(content) != "String" {
+			return
+		}
+		owner := ""
+		if class := enclosing(node, "class_declaration", "interface_declaration", "enum_declaration"); class != nil {
+			if name := class.ChildByFieldName("name"); name != nil {
+				owner = name.Utf8Text(content)
+			}
+		}
+		cursor := node.Walk()
+		defer cursor.Close()
+		for _, declarator := range node.ChildrenByFieldName("declarator", cursor) {
+			name, value := declarator.ChildByFieldName("name"), declarator.ChildByFieldName("value")
+			if name == nil || value == nil {
+				continue
+			}
+			resolved, ok := javaConstantValue(value, content, constants, owner)
+			if !ok {
+				continue
+			}
+			constants[name.Utf8Text(content)] = resolved
+			if owner != "" {
+				constants[owner+"."+name.Utf8Text(content)] = resolved
+			}
+		}
+	})
+	return constants
+}
+
+// javaStaticFinal reports whether a field declaration has both the static and final modifiers
+func javaStaticFinal(field *sitter.Node, content []byte) bool {
+	for i := uint(0); i < field.NamedChildCount(); i++ {
+		child := field.NamedChild(i)
+		if child.Kind() != "modifiers" {
+			continue
+		}
+		modifiers := strings.Fields(child.Utf8Text(content))
+		static, final := false, false
+		for _, modifier := range modifiers {
+			static = static || modifier == "static"
+			final = final || modifier == "final"
+		}
+		return static && final
+	}
+	return false
+}
+
+// javaConstantValue evaluates a constant initializer made of string literals, + and constants
+// defined before it (by name, Class.NAME or, within owner, NAME)
+func javaConstantValue(node *sitter.Node, content []byte, constants map[string]string, owner string) (string, bool) {
+	switch node.Kind() {
+	case "string_literal":
+		return trimQuotes(node.Utf8Text(content)), true
+	case "parenthesized_expression":
+		if node.NamedChildCount() == 1 {
+			return javaConstantValue(node.NamedChild(0), content, constants, owner)
+		}
+	case "identifier", "field_access":
+		value, ok := constants[node.Utf8Text(content)]
+		if !ok && owner != "" {
+			value, ok = constants[owner+"."+node.Utf8Text(content)]
+		}
+		return value, ok
+	case "binary_expression":
+		operator := node.ChildByFieldName("operator")
+		left, right := node.ChildByFieldName("left"), node.ChildByFieldName("right")
+		if operator == nil || operator.Kind() != "+" || left == nil || right == nil {
+			return "", false
+		}
+		l, ok := javaConstantValue(left, content, constants, owner)
+		if !ok {
+			return "", false
+		}
+		r, ok := javaConstantValue(right, content, constants, owner)
+		return l + r, ok
+	}
+	return "", false
+}
+
+// HasFallbackJava reports whether a System.getenv lookup has a default, e.g. System.getenv().getOrDefault("X", "y"),
+// Optional.ofNullable(System.getenv("X")).orElse("y") or Objects.requireNonNullElse(System.getenv("X"), "y")
+func HasFallbackJava(node *sitter.Node, content []byte) bool {
+	call := enclosing(node, "method_invocation")
+	if call == nil {
+		return false
+	}
+	if name := call.ChildByFieldName("name"); name != nil && name.Utf8Text(content) == "getOrDefault" {
+		return true
+	}
+
+	args := call.Parent()
+	if args == nil || args.Kind() != "argument_list" {
+		return false
+	}
+	wrapper := args.Parent()
+	if wrapper == nil || wrapper.Kind() != "method_invocation" {
+		return false
+	}
+	name := wrapper.ChildByFieldName("name")
+	if name == nil {
+		return false
+	}
+	switch name.Utf8Text(content) {
+	case "requireNonNullElse", "requireNonNullElseGet":
+		return true
+	case "ofNullable":
+		// The Optional must be unwrapped with a default: Optional.ofNullable(...).orElse(...)
+		outer := wrapper.Parent()
+		if outer == nil || outer.Kind() != "method_invocation" {
+			return false
+		}
+		method := outer.ChildByFieldName("name")
+		if method == nil {
+			return false
+		}
+		switch method.Utf8Text(content) {
+		case "orElse", "orElseGet", "isPresent", "isEmpty", "ifPresent":
+			return true
+		}
+	}
+	return false
+}
+
+// javaParsers maps the parsing methods of the boxed types to the type they parse a value as
+var javaParsers = map[string]string{
+	"Integer.parseInt":     schema.TypeInteger,
+	"Integer.valueOf":      schema.TypeInteger,
+	"Long.parseLong":       schema.TypeInteger,
+	"Long.valueOf":         schema.TypeInteger,
+	"Short.parseShort":     schema.TypeInteger,
+	"Double.parseDouble":   schema.TypeNumber,
+	"Double.valueOf":       schema.TypeNumber,
+	"Float.parseFloat":     schema.TypeNumber,
+	"Boolean.parseBoolean": schema.TypeBoolean,
+	"Boolean.valueOf":      schema.TypeBoolean,
+}
+
+// ExpectedTypeJava infers the type of a System.getenv lookup passed straight to a parsing method,
+// e.g. Integer.parseInt(System.getenv("PORT"))
+func ExpectedTypeJava(node *sitter.Node, content []byte) string {
+	lookup := enclosing(node, "method_invocation")
+	if lookup == nil {
+		return ""
+	}
+	call := wrappingCall(lookup, "argument_list", "method_invocation")
+	if call == nil {
+		return ""
+	}
+	object, name := call.ChildByFieldName("object"), call.ChildByFieldName("name")
+	if object == nil || name == nil {
+		return ""
+	}
+	return javaParsers[object.Utf8Text(content)+"."+name.Utf8Text(content)]
+}
@@ -0,0 +1,24 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_java
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+)
+
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "java",
+		Extensions: []string{".java"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("Java", tree_sitter_java.Language())
+		},
+		Query:                JavaQuery,
+		Extractor:            ExtractEnvVarsFromJava, // For backward compatibility
+		ExtractorWithPartial: ExtractEnvVarsFromJavaWithPartial,
+		HasFallback:          HasFallbackJava,
+		ExpectedType:         ExpectedTypeJava,
+		Constants:            JavaConstants,
+	})
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
 import (
@@ -143,6 +145,73 @@ func TestExtractEnvVarsFromJava_DynamicPatterns(t *testing.T) {
 				{Key: "key", IsPartial: true, IsVarRef: true},
 			},
 		},
+		{
+			name: "String.format and formatted",
+			matches: []map[string]string{
+				{
+					"obj":           "System",
+					"method":        "getenv",
+					"format_obj":    "String",
+					"format_method": "format",
+					"format_args":   `("APP_%s", name)`,
+				},
+				{
+					"obj":           "System",
+					"method1":       "getenv",
+					"method2":       "get",
+					"format_obj":    "String",
+					"format_method": "format",
+					"format_args":   `(Locale.ROOT, "%2$s_%1$05d_%%", port(a, b), env)`,
+				},
+				{
+					"obj":           "System",
+					"method":        "getenv",
+					"format_obj":    `"SERVICE_%s_URL"`,
+					"format_method": "formatted",
+					"format_args":   `(service)`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"APP_" + name`, IsPartial: true, FullExpr: `"APP_" + name`},
+				{Key: `env + "_" + port(a, b) + "_%"`, IsPartial: true, FullExpr: `env + "_" + port(a, b) + "_%"`},
+				{Key: `"SERVICE_" + service + "_URL"`, IsPartial: true, FullExpr: `"SERVICE_" + service + "_URL"`},
+			},
+		},
+		{
+			name: "format without specifiers and other calls",
+			matches: []map[string]string{
+				{
+					"obj":           "System",
+					"method":        "getenv",
+					"format_obj":    "String",
+					"format_method": "format",
+					"format_args":   `("PLAIN")`,
+				},
+				{
+					"obj":           "System",
+					"method":        "getenv",
+					"format_obj":    "config",
+					"format_method": "key",
+					"format_args":   `("IGNORED")`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: "PLAIN", IsPartial: false},
+			},
+		},
+		{
+			name: "constant reference",
+			matches: []map[string]string{
+				{
+					"obj":    "System",
+					"method": "getenv",
+					"var":    "Constants.DB_URL_KEY",
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: "Constants.DB_URL_KEY", IsPartial: true, IsVarRef: true},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,5 +1,12 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
+import (
+	"github.com/jenian/envgrd/internal/schema"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
 // JavaScriptQuery is the Tree-Sitter query for finding process.env.KEY patterns
 // Supports both dot notation (process.env.KEY) and bracket notation (process.env["KEY"])
 // Also supports partial matches for dynamic patterns (process.env["prefix_" + var])
@@ -160,3 +167,46 @@ func extractLastString(expr string) string {
 	}
 	return ""
 }
+
+// HasFallbackJS reports whether a process.env lookup has a default, e.g. process.env.X || "y" or process.env.X ?? "y"
+func HasFallbackJS(node *sitter.Node, content []byte) bool {
+	access := enclosing(node, "member_expression", "subscript_expression")
+	if access == nil {
+		return false
+	}
+	return isLeftOperand(unwrapParens(access, "parenthesized_expression"), content, "binary_expression", "||", "??")
+}
+
+// jsParsers maps global functions to the type they parse a value as
+var jsParsers = map[string]string{
+	"parseInt":          schema.TypeInteger,
+	"Number.parseInt":   schema.TypeInteger,
+	"parseFloat":        schema.TypeNumber,
+	"Number.parseFloat": schema.TypeNumber,
+	"Number":            schema.TypeNumber,
+}
+
+// ExpectedTypeJS infers the type of a process.env lookup passed straight to a number parser,
+// e.g. parseInt(process.env.PORT) or Number(process.env.TIMEOUT), or converted with a unary +
+func ExpectedTypeJS(node *sitter.Node, content []byte) string {
+	access := enclosing(node, "member_expression", "subscript_expression")
+	if access == nil {
+		return ""
+	}
+	expr := unwrapParens(access, "parenthesized_expression")
+	if parent := expr.Parent(); parent != nil && parent.Kind() == "unary_expression" {
+		if operator := parent.ChildByFieldName("operator"); operator != nil && operator.Utf8Text(content) == "+" {
+			return schema.TypeNumber
+		}
+		return ""
+	}
+	call := wrappingCall(expr, "arguments", "call_expression")
+	if call == nil {
+		return ""
+	}
+	fn := call.ChildByFieldName("function")
+	if fn == nil {
+		return ""
+	}
+	return jsParsers[fn.Utf8Text(content)]
+}
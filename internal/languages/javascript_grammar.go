@@ -0,0 +1,22 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_javascript
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+)
+
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "javascript",
+		Extensions: []string{".js", ".jsx", ".mjs"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("JavaScript", tree_sitter_javascript.Language())
+		},
+		Query:                JavaScriptQuery,
+		ExtractorWithPartial: ExtractEnvVarsFromJS,
+		HasFallback:          HasFallbackJS,
+		ExpectedType:         ExpectedTypeJS,
+	})
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
 import (
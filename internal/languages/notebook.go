@@ -0,0 +1,183 @@
+package languages
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// notebookLine is a line of a code cell and the notebook file line it was read from
+type notebookLine struct {
+	text string
+	line int
+}
+
+// nonPythonCellMagics are the cell magics whose cell isn't Python code
+var nonPythonCellMagics = map[string]bool{
+	"%%bash": true, "%%sh": true, "%%script": true, "%%html": true, "%%javascript": true, "%%js": true,
+	"%%latex": true, "%%markdown": true, "%%sql": true, "%%writefile": true, "%%perl": true,
+	"%%ruby": true, "%%svg": true, "%%R": true,
+}
+
+// notebookCells concatenates the code cells of a Python notebook, mapping each code line to the
+// line of the JSON string it comes from
+// IPython magics (%pip, !ls) are blanked out and cells run by another interpreter (%%bash) are skipped;
+// notebooks with another kernel language, or that aren't valid JSON, give no code
+func notebookCells(content []byte) ([]byte, []int) {
+	cells, language, ok := readNotebook(content)
+	if !ok || (language != "" && !strings.EqualFold(language, "python")) {
+		return nil, nil
+	}
+
+	var code strings.Builder
+	var lines []int
+	for _, cell := range cells {
+		if magic := strings.Fields(cell[0].text); len(magic) > 0 && nonPythonCellMagics[magic[0]] {
+			continue
+		}
+		for _, l := range cell {
+			text := l.text
+			if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "%") || strings.HasPrefix(trimmed, "!") {
+				text = ""
+			}
+			code.WriteString(text)
+			code.WriteByte('\n')
+			lines = append(lines, l.line)
+		}
+		// Keep a blank line between cells so one cell's last statement can't run into the next
+		code.WriteByte('\n')
+		lines = append(lines, lines[len(lines)-1])
+	}
+	return []byte(code.String()), lines
+}
+
+// readNotebook reads the code cells (as lines) and the kernel language of a notebook
+// Its source strings are read token by token to know the line each of them is on
+func readNotebook(content []byte) (cells [][]notebookLine, language string, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	// Offsets only grow, so lines are counted incrementally
+	line, offset := 1, 0
+	lineAt := func() int {
+		next := int(dec.InputOffset())
+		line += bytes.Count(content[offset:next], []byte("\n"))
+		offset = next
+		return line
+	}
+
+	if !expectDelim(dec, '{') {
+		return nil, "", false
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, "", false
+		}
+		switch key {
+		case "cells":
+			if !expectDelim(dec, '[') {
+				return nil, "", false
+			}
+			for dec.More() {
+				cell, code, ok := readCell(dec, lineAt)
+				if !ok {
+					return nil, "", false
+				}
+				if code && len(cell) > 0 {
+					cells = append(cells, cell)
+				}
+			}
+			if !expectDelim(dec, ']') {
+				return nil, "", false
+			}
+		case "metadata":
+			var metadata struct {
+				Kernelspec struct {
+					Language string `json:"language"`
+				} `json:"kernelspec"`
+				LanguageInfo struct {
+					Name string `json:"name"`
+				} `json:"language_info"`
+			}
+			if err := dec.Decode(&metadata); err != nil {
+				return nil, "", false
+			}
+			language = metadata.LanguageInfo.Name
+			if language == "" {
+				language = metadata.Kernelspec.Language
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, "", false
+			}
+		}
+	}
+	return cells, language, true
+}
+
+// readCell reads one cell object, returning its source lines and whether it is a code cell
+func readCell(dec *json.Decoder, lineAt func() int) ([]notebookLine, bool, bool) {
+	if !expectDelim(dec, '{') {
+		return nil, false, false
+	}
+	var lines []notebookLine
+	code := false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, false, false
+		}
+		switch key {
+		case "cell_type":
+			var cellType string
+			if err := dec.Decode(&cellType); err != nil {
+				return nil, false, false
+			}
+			code = cellType == "code"
+		case "source":
+			// Source is either a list of lines or a single string
+			token, err := dec.Token()
+			if err != nil {
+				return nil, false, false
+			}
+			if s, ok := token.(string); ok {
+				lines = appendSource(lines, s, lineAt())
+				continue
+			}
+			if token != json.Delim('[') {
+				return nil, false, false
+			}
+			for dec.More() {
+				token, err := dec.Token()
+				s, ok := token.(string)
+				if err != nil || !ok {
+					return nil, false, false
+				}
+				lines = appendSource(lines, s, lineAt())
+			}
+			if !expectDelim(dec, ']') {
+				return nil, false, false
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, false, false
+			}
+		}
+	}
+	return lines, code, expectDelim(dec, '}')
+}
+
+// appendSource splits a source string into lines, all read from the given notebook line
+func appendSource(lines []notebookLine, source string, line int) []notebookLine {
+	for _, text := range strings.Split(strings.TrimSuffix(source, "\n"), "\n") {
+		lines = append(lines, notebookLine{text: strings.TrimSuffix(text, "\r"), line: line})
+	}
+	return lines
+}
+
+// expectDelim reads the next token and reports whether it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) bool {
+	token, err := dec.Token()
+	return err == nil && token == delim
+}
@@ -0,0 +1,26 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_python
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// Jupyter notebooks are JSON documents whose code cells are parsed and queried as Python
+// Usages are reported at the line of the notebook file holding the code, so editors can jump to them
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "jupyter",
+		Extensions: []string{".ipynb"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("Python", tree_sitter_python.Language())
+		},
+		Query:                PythonQuery,
+		Extractor:            ExtractEnvVarsFromPython,
+		ExtractorWithPartial: ExtractEnvVarsFromPythonWithPartial,
+		HasFallback:          HasFallbackPython,
+		ExpectedType:         ExpectedTypePython,
+		Source:               notebookCells,
+	})
+}
@@ -0,0 +1,57 @@
+package languages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotebookCells(t *testing.T) {
+	notebook := `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "source": ["Uses os.getenv(\"IN_MARKDOWN\")"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {},
+   "outputs": [{"output_type": "stream", "text": ["x = 1\n"]}],
+   "source": [
+    "%pip install requests\n",
+    "import os\n",
+    "url = os.environ[\"API_URL\"]"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "source": "%%bash\necho $HOME"
+  },
+  {
+   "cell_type": "code",
+   "source": "if True:\n    token = os.getenv(\"TOKEN\")\n"
+  }
+ ],
+ "metadata": {"kernelspec": {"language": "python"}},
+ "nbformat": 4
+}`
+	code, lines := notebookCells([]byte(notebook))
+	wantCode := "\nimport os\nurl = os.environ[\"API_URL\"]\n\nif True:\n    token = os.getenv(\"TOKEN\")\n\n"
+	if string(code) != wantCode {
+		t.Errorf("Expected code %q, got %q", wantCode, code)
+	}
+	if want := []int{13, 14, 15, 15, 24, 24, 24}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected lines %v, got %v", want, lines)
+	}
+}
+
+func TestNotebookCells_OtherKernels(t *testing.T) {
+	for name, notebook := range map[string]string{
+		"r kernel": `{"cells": [{"cell_type": "code", "source": ["Sys.getenv(\"KEY\")"]}], "metadata": {"language_info": {"name": "R"}}}`,
+		"invalid":  `{"cells": [`,
+	} {
+		if code, _ := notebookCells([]byte(notebook)); len(code) != 0 {
+			t.Errorf("%s: expected no code, got %q", name, code)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package languages
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+)
+
+// Pattern matches environment lookups in a line of code with a regular expression. Patterns stand in
+// for the Tree-Sitter query in builds without cgo, which can't load the grammars (see LanguageInfo)
+// The first group of Regexp captures the key, or with VarRef the variable holding it
+type Pattern struct {
+	Regexp   *regexp.Regexp
+	VarRef   bool // The group captures a variable (e.g., process.env[name]), reported as a dynamic pattern
+	Optional bool // The lookup falls back to a default (e.g., os.getenv("KEY", "x"))
+}
+
+// PatternMatch is a lookup found by MatchPatterns
+type PatternMatch struct {
+	EnvVarMatch
+	Line     int // 1-based
+	Optional bool
+}
+
+// MatchPatterns finds the lookups of patterns in content, line by line and in order
+// A key is reported once per line, with the first pattern that matches it. Unlike Tree-Sitter queries,
+// patterns don't tell code from comments and strings, and don't see lookups spanning several lines,
+// string concatenations or constants
+func MatchPatterns(content []byte, patterns []Pattern) []PatternMatch {
+	var matches []PatternMatch
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		type found struct {
+			PatternMatch
+			column int
+		}
+		var inLine []found
+		seen := make(map[EnvVarMatch]bool)
+		for _, pattern := range patterns {
+			for _, loc := range pattern.Regexp.FindAllSubmatchIndex(line, -1) {
+				if len(loc) < 4 || loc[2] < 0 {
+					continue
+				}
+				match := EnvVarMatch{Key: string(line[loc[2]:loc[3]]), IsPartial: pattern.VarRef, IsVarRef: pattern.VarRef}
+				if seen[match] {
+					continue
+				}
+				seen[match] = true
+				inLine = append(inLine, found{PatternMatch{match, i + 1, pattern.Optional}, loc[0]})
+			}
+		}
+		sort.SliceStable(inLine, func(a, b int) bool { return inLine[a].column < inLine[b].column })
+		for _, f := range inLine {
+			matches = append(matches, f.PatternMatch)
+		}
+	}
+	return matches
+}
+
+// Patterns of the built-in languages, see Pattern
+// Optional patterns come first, so that a lookup with a default is reported as optional
+var (
+	// JavaScriptPatterns find process.env.KEY and process.env["KEY"]
+	JavaScriptPatterns = []Pattern{
+		{Regexp: regexp.MustCompile(`\bprocess\.env\.([A-Za-z_$][\w$]*)\s*(?:\|\||\?\?)`), Optional: true},
+		{Regexp: regexp.MustCompile(`\bprocess\.env\.([A-Za-z_$][\w$]*)`)},
+		{Regexp: regexp.MustCompile("\\bprocess\\.env\\[\\s*[\"'`]([^\"'`]+)[\"'`]\\s*\\]")},
+		{Regexp: regexp.MustCompile(`\bprocess\.env\[\s*([A-Za-z_$][\w$]*)\s*\]`), VarRef: true},
+	}
+
+	// GoPatterns find os.Getenv("KEY") and os.LookupEnv("KEY")
+	GoPatterns = []Pattern{
+		{Regexp: regexp.MustCompile(`\bos\.(?:Getenv|LookupEnv)\(\s*"([^"]+)"\s*\)`)},
+		{Regexp: regexp.MustCompile(`\bos\.(?:Getenv|LookupEnv)\(\s*([A-Za-z_]\w*)\s*\)`), VarRef: true},
+	}
+
+	// PythonPatterns find os.environ["KEY"], os.environ.get("KEY") and os.getenv("KEY")
+	PythonPatterns = []Pattern{
+		{Regexp: regexp.MustCompile(`\bos\.(?:environ\.get|getenv)\(\s*["']([^"']+)["']\s*,`), Optional: true},
+		{Regexp: regexp.MustCompile(`\bos\.environ\[\s*["']([^"']+)["']\s*\]`)},
+		{Regexp: regexp.MustCompile(`\bos\.(?:environ\.get|getenv)\(\s*["']([^"']+)["']\s*\)`)},
+		{Regexp: regexp.MustCompile(`\bos\.environ\[\s*([A-Za-z_]\w*)\s*\]`), VarRef: true},
+		{Regexp: regexp.MustCompile(`\bos\.(?:environ\.get|getenv)\(\s*([A-Za-z_]\w*)\s*[,)]`), VarRef: true},
+	}
+
+	// RustPatterns find env::var("KEY"), env::var_os("KEY"), env!("KEY") and option_env!("KEY")
+	RustPatterns = []Pattern{
+		{Regexp: regexp.MustCompile(`\boption_env!\(\s*"([^"]+)"`), Optional: true},
+		{Regexp: regexp.MustCompile(`\benv::var(?:_os)?\(\s*"([^"]+)"\s*\)`)},
+		{Regexp: regexp.MustCompile(`\benv!\(\s*"([^"]+)"`)},
+		{Regexp: regexp.MustCompile(`\benv::var(?:_os)?\(\s*&?([A-Za-z_]\w*)\s*\)`), VarRef: true},
+	}
+
+	// JavaPatterns find System.getenv("KEY"), System.getenv().get("KEY") and getOrDefault
+	JavaPatterns = []Pattern{
+		{Regexp: regexp.MustCompile(`\bSystem\.getenv\(\)\.getOrDefault\(\s*"([^"]+)"`), Optional: true},
+		{Regexp: regexp.MustCompile(`\bSystem\.getenv\(\)\.get\(\s*"([^"]+)"\s*\)`)},
+		{Regexp: regexp.MustCompile(`\bSystem\.getenv\(\s*"([^"]+)"\s*\)`)},
+		{Regexp: regexp.MustCompile(`\bSystem\.getenv\(\s*([A-Za-z_]\w*)\s*\)`), VarRef: true},
+	}
+)
@@ -0,0 +1,73 @@
+package languages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []Pattern
+		code     string
+		expected []PatternMatch
+	}{
+		{
+			name:     "javascript",
+			patterns: JavaScriptPatterns,
+			code:     "const a = process.env.API_KEY || 'x';\nconst b = process.env['DB_URL'], c = process.env[name];\n// process.env.API_KEY again",
+			expected: []PatternMatch{
+				{EnvVarMatch{Key: "API_KEY"}, 1, true},
+				{EnvVarMatch{Key: "DB_URL"}, 2, false},
+				{EnvVarMatch{Key: "name", IsPartial: true, IsVarRef: true}, 2, false},
+				{EnvVarMatch{Key: "API_KEY"}, 3, false},
+			},
+		},
+		{
+			name:     "go",
+			patterns: GoPatterns,
+			code:     "url := os.Getenv(\"DATABASE_URL\")\nv, ok := os.LookupEnv(key)",
+			expected: []PatternMatch{
+				{EnvVarMatch{Key: "DATABASE_URL"}, 1, false},
+				{EnvVarMatch{Key: "key", IsPartial: true, IsVarRef: true}, 2, false},
+			},
+		},
+		{
+			name:     "python",
+			patterns: PythonPatterns,
+			code:     "a = os.getenv('PORT', '8080')\nb = os.environ[\"SECRET\"] + os.environ.get(\"HOST\")",
+			expected: []PatternMatch{
+				{EnvVarMatch{Key: "PORT"}, 1, true},
+				{EnvVarMatch{Key: "SECRET"}, 2, false},
+				{EnvVarMatch{Key: "HOST"}, 2, false},
+			},
+		},
+		{
+			name:     "rust",
+			patterns: RustPatterns,
+			code:     "let a = std::env::var(\"API_KEY\");\nlet b = option_env!(\"BUILD\"); let c = env!(\"CARGO_PKG_NAME\");",
+			expected: []PatternMatch{
+				{EnvVarMatch{Key: "API_KEY"}, 1, false},
+				{EnvVarMatch{Key: "BUILD"}, 2, true},
+				{EnvVarMatch{Key: "CARGO_PKG_NAME"}, 2, false},
+			},
+		},
+		{
+			name:     "java",
+			patterns: JavaPatterns,
+			code:     "String a = System.getenv(\"API_KEY\");\nString b = System.getenv().getOrDefault(\"PORT\", \"80\");",
+			expected: []PatternMatch{
+				{EnvVarMatch{Key: "API_KEY"}, 1, false},
+				{EnvVarMatch{Key: "PORT"}, 2, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchPatterns([]byte(tt.code), tt.patterns); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("MatchPatterns() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package languages
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin loads a language plugin: a Go plugin (built with go build -buildmode=plugin) whose init
+// registers languages with envgrd.RegisterLanguage, so grammars left out of the binary with the
+// envgrd_nolang_* build tags (or not built in at all) can be added at runtime
+// The plugin must be built with the same Go toolchain and envgrd version as the binary loading it,
+// and Go plugins are only supported on Linux, macOS and FreeBSD
+func LoadPlugin(path string) error {
+	registryMu.RLock()
+	before := registrations
+	registryMu.RUnlock()
+
+	if _, err := plugin.Open(path); err != nil {
+		return fmt.Errorf("failed to load language plugin: %w", err)
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if registrations == before {
+		return fmt.Errorf("language plugin %s registered no language", path)
+	}
+	return nil
+}
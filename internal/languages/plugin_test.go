@@ -0,0 +1,24 @@
+package languages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPlugin_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lang.so")
+	if err := os.WriteFile(path, []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := Names()
+	if err := LoadPlugin(path); err == nil {
+		t.Error("Expected an error for a file that isn't a Go plugin")
+	}
+	if err := LoadPlugin(filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Error("Expected an error for a missing plugin")
+	}
+	if after := Names(); len(after) != len(before) {
+		t.Errorf("Expected no language to be registered, got %v", after)
+	}
+}
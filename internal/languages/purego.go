@@ -0,0 +1,42 @@
+//go:build !cgo || envgrd_purego
+
+package languages
+
+// Builds without cgo (CGO_ENABLED=0, or the envgrd_purego tag) can't load the Tree-Sitter grammars,
+// which are C code, so the built-in languages are registered with Patterns instead
+
+// Grammar loads the Tree-Sitter grammar for a language; builds without cgo can't call it
+type Grammar func() (*Language, error)
+
+// Language stands in for a Tree-Sitter grammar in builds without cgo
+type Language struct{}
+
+// Node stands in for a Tree-Sitter syntax node in builds without cgo
+type Node struct{}
+
+// FallbackDetector reports whether the lookup at node provides a default value, unused without cgo
+type FallbackDetector func(node *Node, content []byte) bool
+
+// TypeDetector infers the type code parses a value as at node, unused without cgo
+type TypeDetector func(node *Node, content []byte) string
+
+// ConstantResolver collects the string constants a file defines, unused without cgo
+type ConstantResolver func(root *Node, content []byte) map[string]string
+
+func init() {
+	for _, info := range []LanguageInfo{
+		{Name: "javascript", Extensions: []string{".js", ".jsx", ".mjs"}, Patterns: JavaScriptPatterns},
+		{Name: "typescript", Extensions: []string{".ts"}, Patterns: JavaScriptPatterns},
+		{Name: "tsx", Extensions: []string{".tsx"}, Patterns: JavaScriptPatterns},
+		{Name: "vue", Extensions: []string{".vue"}, Patterns: JavaScriptPatterns, Source: scriptBlocks},
+		{Name: "svelte", Extensions: []string{".svelte"}, Patterns: JavaScriptPatterns, Source: scriptBlocks},
+		{Name: "astro", Extensions: []string{".astro"}, Patterns: JavaScriptPatterns, Source: astroScripts},
+		{Name: "go", Extensions: []string{".go"}, Patterns: GoPatterns},
+		{Name: "python", Extensions: []string{".py"}, Patterns: PythonPatterns},
+		{Name: "jupyter", Extensions: []string{".ipynb"}, Patterns: PythonPatterns, Source: notebookCells},
+		{Name: "rust", Extensions: []string{".rs"}, Patterns: RustPatterns},
+		{Name: "java", Extensions: []string{".java"}, Patterns: JavaPatterns},
+	} {
+		RegisterLanguage(info)
+	}
+}
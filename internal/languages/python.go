@@ -1,7 +1,19 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
-// PythonQuery is the Tree-Sitter query for finding os.environ["KEY"] and os.getenv("KEY") patterns
-// Also supports dynamic patterns like os.environ["prefix_" + var] and os.getenv(var)
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/schema"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// PythonQuery is the Tree-Sitter query for finding os.environ["KEY"], os.environ.get("KEY") and os.getenv("KEY") patterns
+// Only the first argument of a call is the key, the second one is a default value
+// Also supports dynamic patterns like os.environ["prefix_" + var], os.getenv(var), f-strings
+// (os.environ[f"SERVICE_{name}_URL"]) and % formatting (os.getenv("APP_%s" % env))
 // Note: We don't use predicates here, filtering is done in ExtractEnvVarsFromPython
 const PythonQuery = `
 [
@@ -17,7 +29,17 @@ const PythonQuery = `
       object: (identifier) @obj2
       attribute: (identifier) @fn
     )
-    arguments: (argument_list (string) @key)
+    arguments: (argument_list . (string) @key)
+  )
+  (call
+    function: (attribute
+      object: (attribute
+        object: (identifier) @obj
+        attribute: (identifier) @attr
+      )
+      attribute: (identifier) @method
+    )
+    arguments: (argument_list . (string) @key)
   )
   (subscript
     value: (attribute
@@ -68,6 +90,24 @@ func ExtractEnvVarsFromPython(matches []map[string]string) []string {
 func ExtractEnvVarsFromPythonWithPartial(matches []map[string]string) []EnvVarMatch {
 	var results []EnvVarMatch
 	seen := make(map[string]bool)
+	// addKey adds a string literal key, f-strings with replacement fields are dynamic patterns
+	addKey := func(literal string) {
+		if expr, ok := pythonFString(literal); ok {
+			if !seen[expr] {
+				results = append(results, EnvVarMatch{Key: expr, IsPartial: true, FullExpr: expr})
+				seen[expr] = true
+			}
+			return
+		}
+		key := trimQuotes(literal)
+		if _, content, ok := pythonString(literal); ok {
+			key = content
+		}
+		if key != "" && !seen[key] {
+			results = append(results, EnvVarMatch{Key: key, IsPartial: false})
+			seen[key] = true
+		}
+	}
 
 	for _, match := range matches {
 		key, keyOk := match["key"]
@@ -76,14 +116,13 @@ func ExtractEnvVarsFromPythonWithPartial(matches []map[string]string) []EnvVarMa
 		fn, fnOk := match["fn"]
 		obj2, obj2Ok := match["obj2"]
 
-		// Check for os.environ["KEY"] pattern
+		// Check for os.environ["KEY"] and os.environ.get("KEY") patterns
 		if keyOk && objOk && attrOk && key != "" {
+			if method, methodOk := match["method"]; methodOk && method != "get" {
+				continue
+			}
 			if obj == "os" && attr == "environ" {
-				key = trimQuotes(key)
-				if key != "" && !seen[key] {
-					results = append(results, EnvVarMatch{Key: key, IsPartial: false})
-					seen[key] = true
-				}
+				addKey(key)
 				continue
 			}
 		}
@@ -91,11 +130,7 @@ func ExtractEnvVarsFromPythonWithPartial(matches []map[string]string) []EnvVarMa
 		// Check for os.getenv("KEY") pattern
 		if keyOk && obj2Ok && fnOk && key != "" {
 			if obj2 == "os" && fn == "getenv" {
-				key = trimQuotes(key)
-				if key != "" && !seen[key] {
-					results = append(results, EnvVarMatch{Key: key, IsPartial: false})
-					seen[key] = true
-				}
+				addKey(key)
 				continue
 			}
 		}
@@ -111,6 +146,9 @@ func ExtractEnvVarsFromPythonWithPartial(matches []map[string]string) []EnvVarMa
 				isValid = true
 			}
 
+			if expr, ok := pythonPercentFormat(fullExpr); ok {
+				fullExpr = expr
+			}
 			if isValid && !seen[fullExpr] {
 				results = append(results, EnvVarMatch{
 					Key:       fullExpr,
@@ -147,3 +185,116 @@ func ExtractEnvVarsFromPythonWithPartial(matches []map[string]string) []EnvVarMa
 	return results
 }
 
+// pythonStringLiteral matches a Python string literal with its prefix (e.g. f, rb), capturing the
+// prefix and the content between the quotes
+var pythonStringLiteral = regexp.MustCompile(`(?s)^([rRbBuUfF]{0,2})("""|'''|"|')(.*)("""|'''|"|')$`)
+
+// pythonString splits a Python string literal into its prefix and its content
+func pythonString(literal string) (prefix string, content string, ok bool) {
+	m := pythonStringLiteral.FindStringSubmatch(strings.TrimSpace(literal))
+	if m == nil || m[2] != m[4] {
+		return "", "", false
+	}
+	return m[1], m[3], true
+}
+
+// fStringField matches the replacement fields of an f-string and its {{ and }} escapes
+var fStringField = regexp.MustCompile(`\{\{|\}\}|\{([^{}]*)\}`)
+
+// pythonFString renders an f-string with replacement fields as a concatenation of its literal parts
+// and fields, e.g. f"SERVICE_{name}_URL" gives "SERVICE_" + name + "_URL"
+func pythonFString(literal string) (string, bool) {
+	prefix, content, ok := pythonString(literal)
+	if !ok || !strings.ContainsAny(prefix, "fF") {
+		return "", false
+	}
+	fields := false
+	expr := interpolate(content, fStringField, func(field []string, n int) (string, string) {
+		switch field[0] {
+		case "{{":
+			return "", "{"
+		case "}}":
+			return "", "}"
+		}
+		fields = true
+		// Drop the conversion (!r), format spec (:>10) and self-documenting = of the field
+		operand := field[1]
+		if i := strings.IndexAny(operand, "!:"); i >= 0 {
+			operand = operand[:i]
+		}
+		return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(operand), "=")), ""
+	})
+	return expr, fields
+}
+
+// percentFormat matches a string literal formatted with the % operator, e.g. "APP_%s" % env
+var percentFormat = regexp.MustCompile(`(?s)^([rRbBuU]{0,2}(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'))\s*%\s*(.+)$`)
+
+// percentConversion matches the conversions of a % format (%s, %(name)s, %-5d) and its %% escapes
+var percentConversion = regexp.MustCompile(`%%|%(?:\(([^)]*)\))?[-#0 +]*(?:\*|\d+)?(?:\.(?:\*|\d+))?[diouxXeEfFgGcrsa]`)
+
+// pythonPercentFormat renders % formatting as a concatenation of the format's literal parts and the
+// arguments, e.g. "APP_%s_%s" % (env, region) gives "APP_" + env + "_" + region
+// Mapping keys (%(env)s) stand for themselves
+func pythonPercentFormat(expr string) (string, bool) {
+	m := percentFormat.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return "", false
+	}
+	_, format, _ := pythonString(m[1])
+	rhs := strings.TrimSpace(m[2])
+	args := []string{rhs}
+	if strings.HasPrefix(rhs, "(") && strings.HasSuffix(rhs, ")") {
+		args = callArgs(rhs)
+	}
+	return interpolate(format, percentConversion, func(conversion []string, n int) (string, string) {
+		switch {
+		case conversion[0] == "%%":
+			return "", "%"
+		case conversion[1] != "":
+			return conversion[1], ""
+		default:
+			return argAt(args, n, conversion[0]), ""
+		}
+	}), true
+}
+
+// HasFallbackPython reports whether a lookup has a default, e.g. os.getenv("X", "y"),
+// os.environ.get("X", default="y") or os.getenv("X") or "y"
+func HasFallbackPython(node *sitter.Node, content []byte) bool {
+	call := enclosing(node, "call", "subscript")
+	if call == nil || call.Kind() != "call" {
+		return false
+	}
+	if args := call.ChildByFieldName("arguments"); args != nil {
+		if namedArgCount(args) >= 2 {
+			return true
+		}
+	}
+	return isLeftOperand(unwrapParens(call, "parenthesized_expression"), content, "boolean_operator", "or")
+}
+
+// pythonParsers maps built-in conversions to the type they parse a value as
+var pythonParsers = map[string]string{
+	"int":   schema.TypeInteger,
+	"float": schema.TypeNumber,
+	"bool":  schema.TypeBoolean,
+}
+
+// ExpectedTypePython infers the type of a lookup passed straight to a built-in conversion,
+// e.g. int(os.environ["PORT"]) or float(os.getenv("RATIO", "0.5"))
+func ExpectedTypePython(node *sitter.Node, content []byte) string {
+	lookup := enclosing(node, "call", "subscript")
+	if lookup == nil {
+		return ""
+	}
+	call := wrappingCall(lookup, "argument_list", "call")
+	if call == nil {
+		return ""
+	}
+	fn := call.ChildByFieldName("function")
+	if fn == nil || fn.Kind() != "identifier" {
+		return ""
+	}
+	return pythonParsers[fn.Utf8Text(content)]
+}
@@ -0,0 +1,23 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_python
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "python",
+		Extensions: []string{".py"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("Python", tree_sitter_python.Language())
+		},
+		Query:                PythonQuery,
+		Extractor:            ExtractEnvVarsFromPython, // For backward compatibility
+		ExtractorWithPartial: ExtractEnvVarsFromPythonWithPartial,
+		HasFallback:          HasFallbackPython,
+		ExpectedType:         ExpectedTypePython,
+	})
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
 import (
@@ -139,6 +141,70 @@ func TestExtractEnvVarsFromPython_DynamicPatterns(t *testing.T) {
 				{Key: "key", IsPartial: true, IsVarRef: true},
 			},
 		},
+		{
+			name: "f-string in os.environ",
+			matches: []map[string]string{
+				{
+					"obj":  "os",
+					"attr": "environ",
+					"key":  `f"SERVICE_{name}_URL"`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"SERVICE_" + name + "_URL"`, IsPartial: true, FullExpr: `"SERVICE_" + name + "_URL"`},
+			},
+		},
+		{
+			name: "f-string with conversion, format spec and escapes",
+			matches: []map[string]string{
+				{
+					"obj2": "os",
+					"fn":   "getenv",
+					"key":  `F'{{X}}_{cfg.name!r:>10}'`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"{X}_" + cfg.name`, IsPartial: true, FullExpr: `"{X}_" + cfg.name`},
+			},
+		},
+		{
+			name: "f-string without fields",
+			matches: []map[string]string{
+				{
+					"obj2": "os",
+					"fn":   "getenv",
+					"key":  `f"PLAIN"`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: "PLAIN", IsPartial: false},
+			},
+		},
+		{
+			name: "% formatting",
+			matches: []map[string]string{
+				{
+					"obj2":      "os",
+					"fn":        "getenv",
+					"full_expr": `"APP_%s" % env`,
+				},
+				{
+					"obj":       "os",
+					"attr":      "environ",
+					"full_expr": `"APP_%s_%05d_%%" % (env, get_id(a, b))`,
+				},
+				{
+					"obj2":      "os",
+					"fn":        "getenv",
+					"full_expr": `'%(prefix)s_TOKEN' % values`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"APP_" + env`, IsPartial: true, FullExpr: `"APP_" + env`},
+				{Key: `"APP_" + env + "_" + get_id(a, b) + "_%"`, IsPartial: true, FullExpr: `"APP_" + env + "_" + get_id(a, b) + "_%"`},
+				{Key: `prefix + "_TOKEN"`, IsPartial: true, FullExpr: `prefix + "_TOKEN"`},
+			},
+		},
 	}
 
 	for _, tt := range tests {
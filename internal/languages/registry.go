@@ -0,0 +1,90 @@
+package languages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Extractor converts query captures (capture name -> text) into environment variable matches
+type Extractor func([]map[string]string) []EnvVarMatch
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*LanguageInfo)
+	extensions = make(map[string]string) // Lowercase extension -> language name
+
+	registrations int // Calls to RegisterLanguage, to tell whether a plugin registered a language
+)
+
+// Register adds a language so that files with the given extensions are parsed with grammar
+// and queried with query, and the captures are turned into usages by extractor
+// Registering an existing name replaces it, which lets callers override built-in languages
+func Register(name string, grammar Grammar, query string, extractor Extractor, exts ...string) {
+	RegisterLanguage(LanguageInfo{
+		Name:                 name,
+		Extensions:           exts,
+		Grammar:              grammar,
+		Query:                query,
+		ExtractorWithPartial: extractor,
+	})
+}
+
+// RegisterLanguage adds a fully described language to the registry
+func RegisterLanguage(info LanguageInfo) {
+	if info.Name == "" {
+		panic("languages: Register called with empty name")
+	}
+	if info.Grammar == nil && info.Patterns == nil {
+		panic(fmt.Sprintf("languages: Register called with nil grammar for %s", info.Name))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	// Drop extensions owned by a previous registration of the same name
+	if previous, ok := registry[info.Name]; ok {
+		for _, ext := range previous.Extensions {
+			if extensions[normalizeExtension(ext)] == info.Name {
+				delete(extensions, normalizeExtension(ext))
+			}
+		}
+	}
+
+	registry[info.Name] = &info
+	registrations++
+	for _, ext := range info.Extensions {
+		extensions[normalizeExtension(ext)] = info.Name
+	}
+}
+
+// Names returns the names of all registered languages, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForExtension returns the language registered for a file extension (e.g., ".ts"), or "" if none
+func ForExtension(ext string) string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return extensions[normalizeExtension(ext)]
+}
+
+// normalizeExtension lowercases an extension and ensures it has a leading dot
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,113 @@
+//go:build cgo && !envgrd_purego
+
+package languages
+
+import (
+	"testing"
+)
+
+func TestRegister_CustomLanguage(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	js := GetLanguageInfo("javascript")
+
+	Register("custom-test", js.Grammar, JavaScriptQuery, ExtractEnvVarsFromJS, ".CustomExt", "cext2")
+	t.Cleanup(func() { unregister("custom-test") })
+
+	info := GetLanguageInfo("custom-test")
+	if info == nil {
+		t.Fatal("Expected custom-test to be registered")
+	}
+	if info.Query != JavaScriptQuery || info.ExtractorWithPartial == nil {
+		t.Error("Registered query/extractor not returned")
+	}
+	if info.Extractor != nil {
+		t.Error("Expected no legacy extractor for Register")
+	}
+
+	if got := ForExtension(".customext"); got != "custom-test" {
+		t.Errorf("ForExtension(.customext) = %q, want custom-test", got)
+	}
+	if got := ForExtension(".cext2"); got != "custom-test" {
+		t.Errorf("ForExtension(.cext2) = %q, want custom-test", got)
+	}
+
+	found := false
+	for _, name := range Names() {
+		if name == "custom-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected custom-test in Names()")
+	}
+
+	// Re-registering replaces the previous extensions
+	Register("custom-test", js.Grammar, JavaScriptQuery, ExtractEnvVarsFromJS, ".cext3")
+	if got := ForExtension(".customext"); got != "" {
+		t.Errorf("Expected .customext to be dropped after re-registering, got %q", got)
+	}
+	if got := ForExtension(".cext3"); got != "custom-test" {
+		t.Errorf("ForExtension(.cext3) = %q, want custom-test", got)
+	}
+}
+
+func TestBuiltinGrammarsLoad(t *testing.T) {
+	for _, name := range []string{"javascript", "typescript", "tsx", "vue", "svelte", "astro", "go", "python", "jupyter", "rust", "java"} {
+		info := GetLanguageInfo(name)
+		if info == nil {
+			continue // Left out with its envgrd_nolang_* tag
+		}
+		language, err := info.Grammar()
+		if err != nil || language == nil {
+			t.Errorf("Failed to load %s grammar: %v", name, err)
+		}
+	}
+}
+
+func TestForExtension_Builtins(t *testing.T) {
+	tests := map[string]string{
+		".js":   "javascript",
+		".JSX":  "javascript",
+		".ts":   "typescript",
+		".tsx":  "tsx",
+		".vue":  "vue",
+		".go":   "go",
+		".py":   "python",
+		".rs":   "rust",
+		".java": "java",
+		".txt":  "",
+	}
+	for ext, want := range tests {
+		if want != "" && GetLanguageInfo(want) == nil {
+			want = "" // Languages left out of the build don't claim their extensions
+		}
+		if got := ForExtension(ext); got != want {
+			t.Errorf("ForExtension(%q) = %q, want %q", ext, got, want)
+		}
+	}
+}
+
+// skipWithoutLanguage skips tests of languages left out of the build with their envgrd_nolang_* tag
+func skipWithoutLanguage(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if GetLanguageInfo(name) == nil {
+			t.Skipf("%s is not built in", name)
+		}
+	}
+}
+
+// unregister removes a language registered by a test
+func unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if info, ok := registry[name]; ok {
+		for _, ext := range info.Extensions {
+			if extensions[normalizeExtension(ext)] == name {
+				delete(extensions, normalizeExtension(ext))
+			}
+		}
+		delete(registry, name)
+	}
+}
@@ -1,7 +1,19 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/schema"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
 // RustQuery is the Tree-Sitter query for finding env::var("KEY") and std::env::var("KEY") patterns
-// Also supports dynamic patterns like env::var("prefix_" + var) and env::var(var)
+// Also supports dynamic patterns like env::var("prefix_" + var) and env::var(var), and the
+// concat! and format! macros (env::var(concat!("APP_", "PORT")), env::var(format!("FEATURE_{}", name)))
 // Note: We don't use predicates here, filtering is done in ExtractEnvVarsFromRust
 const RustQuery = `
 [
@@ -56,6 +68,29 @@ const RustQuery = `
     )
     arguments: (arguments (identifier) @var)
   )
+  (call_expression
+    function: (scoped_identifier
+      path: (identifier) @path
+      name: (identifier) @fn
+    )
+    arguments: (arguments (macro_invocation
+      macro: (identifier) @macro
+      (token_tree) @macro_args
+    ) @key)
+  )
+  (call_expression
+    function: (scoped_identifier
+      path: (scoped_identifier
+        path: (identifier) @path1
+        name: (identifier) @path2
+      )
+      name: (identifier) @fn
+    )
+    arguments: (arguments (macro_invocation
+      macro: (identifier) @macro
+      (token_tree) @macro_args
+    ) @key)
+  )
 ]
 `
 
@@ -105,7 +140,23 @@ func ExtractEnvVarsFromRustWithPartial(matches []map[string]string) []EnvVarMatc
 			continue
 		}
 
-		// Case 1: Static key (string literal)
+		// Case 1: Macro key, concat! is resolved statically and format! is a dynamic pattern
+		macro, macroOk := match["macro"]
+		if macroOk && macro != "" {
+			key, partial, ok := rustMacro(macro, match["macro_args"])
+			if !ok || key == "" || seen[key] {
+				continue
+			}
+			if partial {
+				results = append(results, EnvVarMatch{Key: key, IsPartial: true, FullExpr: key})
+			} else {
+				results = append(results, EnvVarMatch{Key: key, IsPartial: false})
+			}
+			seen[key] = true
+			continue
+		}
+
+		// Case 2: Static key (string literal)
 		key, keyOk := match["key"]
 		if keyOk && key != "" {
 			key = trimQuotes(key)
@@ -116,7 +167,7 @@ func ExtractEnvVarsFromRustWithPartial(matches []map[string]string) []EnvVarMatc
 			continue
 		}
 
-		// Case 2: Binary expression (e.g., "prefix_" + var, var + "_suffix")
+		// Case 3: Binary expression (e.g., "prefix_" + var, var + "_suffix")
 		fullExpr, fullExprOk := match["full_expr"]
 		if fullExprOk && fullExpr != "" {
 			if !seen[fullExpr] {
@@ -130,7 +181,7 @@ func ExtractEnvVarsFromRustWithPartial(matches []map[string]string) []EnvVarMatc
 			continue
 		}
 
-		// Case 3: Variable identifier (e.g., env::var(var))
+		// Case 4: Variable identifier (e.g., env::var(var))
 		varName, varOk := match["var"]
 		if varOk && varName != "" {
 			if !seen[varName] {
@@ -147,3 +198,217 @@ func ExtractEnvVarsFromRustWithPartial(matches []map[string]string) []EnvVarMatc
 	return results
 }
 
+// rustMacro resolves the concat! or format! macro used as an env::var key. concat! of literals gives
+// a static key, anything else is rendered as a concatenation, e.g. format!("FEATURE_{}", name) gives
+// "FEATURE_" + name
+func rustMacro(macro string, tokenTree string) (key string, partial bool, ok bool) {
+	args := callArgs(tokenTree)
+	if len(args) == 0 {
+		return "", false, false
+	}
+	switch macro {
+	case "concat":
+		return rustConcat(args)
+	case "format":
+		format, ok := rustString(args[0])
+		if !ok {
+			return "", false, false
+		}
+		expr, fields := rustFormat(format, args[1:])
+		if !fields {
+			return format, false, true
+		}
+		return expr, true, true
+	}
+	return "", false, false
+}
+
+// rustConcat joins the literal arguments of concat!, falling back to a concatenation when an
+// argument is not a literal (e.g. a nested env! or stringify!)
+func rustConcat(args []string) (string, bool, bool) {
+	var parts []string
+	var literal strings.Builder
+	partial := false
+	for _, arg := range args {
+		if arg == "" {
+			continue
+		}
+		if s, ok := rustString(arg); ok {
+			literal.WriteString(s)
+			continue
+		}
+		if rustScalarLiteral.MatchString(arg) {
+			literal.WriteString(strings.Trim(arg, "'"))
+			continue
+		}
+		partial = true
+		if literal.Len() > 0 {
+			parts = append(parts, strconv.Quote(literal.String()))
+			literal.Reset()
+		}
+		parts = append(parts, arg)
+	}
+	if !partial {
+		return literal.String(), false, true
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, strconv.Quote(literal.String()))
+	}
+	return strings.Join(parts, " + "), true, true
+}
+
+// rustScalarLiteral matches the non-string literals concat! accepts: integers, floats, bools and chars
+var rustScalarLiteral = regexp.MustCompile(`^(?:-?\d[\d_]*(?:\.\d[\d_]*)?|true|false|'[^'\\]')$`)
+
+// rustStringLiteral matches a Rust string literal, raw strings (r#"..."#) included
+var rustStringLiteral = regexp.MustCompile(`(?s)^b?(?:"(.*)"|r(#*)"(.*)"(#*))$`)
+
+// rustString returns the content of a Rust string literal
+func rustString(literal string) (string, bool) {
+	m := rustStringLiteral.FindStringSubmatch(strings.TrimSpace(literal))
+	if m == nil {
+		return "", false
+	}
+	if strings.HasPrefix(strings.TrimPrefix(strings.TrimSpace(literal), "b"), "r") {
+		if m[2] != m[4] {
+			return "", false
+		}
+		return m[3], true
+	}
+	return m[1], true
+}
+
+// rustFormatArg matches the arguments of a format string ({}, {0}, {name:?}) and its {{ and }} escapes
+var rustFormatArg = regexp.MustCompile(`\{\{|\}\}|\{([^{}:]*)(?::[^{}]*)?\}`)
+
+// rustFormat renders a format! string as a concatenation of its literal parts and arguments.
+// Positional arguments ({} and {0}) take the call's arguments, named ones ({name}) a name = value
+// argument or the captured variable
+func rustFormat(format string, args []string) (string, bool) {
+	var positional []string
+	named := make(map[string]string)
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && rustIdentifier.MatchString(strings.TrimSpace(name)) && !strings.HasPrefix(value, "=") {
+			named[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	fields := false
+	expr := interpolate(format, rustFormatArg, func(arg []string, n int) (string, string) {
+		switch arg[0] {
+		case "{{":
+			return "", "{"
+		case "}}":
+			return "", "}"
+		}
+		fields = true
+		name := strings.TrimSpace(arg[1])
+		if name == "" {
+			return argAt(positional, n, arg[0]), ""
+		}
+		if i, err := strconv.Atoi(name); err == nil {
+			return argAt(positional, i, arg[0]), ""
+		}
+		if value, ok := named[name]; ok {
+			return value, ""
+		}
+		return name, ""
+	})
+	return expr, fields
+}
+
+// rustIdentifier matches a Rust identifier
+var rustIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// rustFallbackMethods are Result methods that handle an unset variable instead of failing
+var rustFallbackMethods = map[string]bool{
+	"ok":                true,
+	"is_ok":             true,
+	"is_err":            true,
+	"unwrap_or":         true,
+	"unwrap_or_default": true,
+	"unwrap_or_else":    true,
+	"map_or":            true,
+	"map_or_else":       true,
+}
+
+// HasFallbackRust reports whether an env::var lookup has a default, e.g. env::var("X").unwrap_or(...)
+// or if let Ok(v) = env::var("X")
+func HasFallbackRust(node *sitter.Node, content []byte) bool {
+	call := enclosing(node, "call_expression")
+	if call == nil {
+		return false
+	}
+	parent := call.Parent()
+	if parent == nil {
+		return false
+	}
+	switch parent.Kind() {
+	case "let_condition":
+		return true
+	case "field_expression":
+		field := parent.ChildByFieldName("field")
+		return field != nil && rustFallbackMethods[field.Utf8Text(content)]
+	}
+	return false
+}
+
+// rustUnwrapMethods turn the Result of env::var into the value, keeping the chain to parse
+var rustUnwrapMethods = map[string]bool{
+	"unwrap":            true,
+	"expect":            true,
+	"unwrap_or":         true,
+	"unwrap_or_default": true,
+	"unwrap_or_else":    true,
+}
+
+// ExpectedTypeRust infers the type of an env::var lookup parsed with a turbofish,
+// e.g. env::var("PORT").unwrap().parse::<u16>()
+func ExpectedTypeRust(node *sitter.Node, content []byte) string {
+	value := enclosing(node, "call_expression")
+	for value != nil {
+		access := value.Parent()
+		if access == nil || access.Kind() != "field_expression" {
+			return ""
+		}
+		field := access.ChildByFieldName("field")
+		if field == nil {
+			return ""
+		}
+		switch method := field.Utf8Text(content); {
+		case method == "parse":
+			generic := access.Parent()
+			if generic == nil || generic.Kind() != "generic_function" {
+				return ""
+			}
+			args := generic.ChildByFieldName("type_arguments")
+			if args == nil || namedArgCount(args) != 1 {
+				return ""
+			}
+			return rustType(args.NamedChild(0).Utf8Text(content))
+		case rustUnwrapMethods[method]:
+			value = access.Parent()
+			if value == nil || value.Kind() != "call_expression" {
+				return ""
+			}
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// rustType returns the type of a primitive Rust type name, "" for other types
+func rustType(name string) string {
+	switch name {
+	case "i8", "i16", "i32", "i64", "i128", "isize", "u8", "u16", "u32", "u64", "u128", "usize":
+		return schema.TypeInteger
+	case "f32", "f64":
+		return schema.TypeNumber
+	case "bool":
+		return schema.TypeBoolean
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_rust
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_rust "github.com/tree-sitter/tree-sitter-rust/bindings/go"
+)
+
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "rust",
+		Extensions: []string{".rs"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("Rust", tree_sitter_rust.Language())
+		},
+		Query:                RustQuery,
+		Extractor:            ExtractEnvVarsFromRust, // For backward compatibility
+		ExtractorWithPartial: ExtractEnvVarsFromRustWithPartial,
+		HasFallback:          HasFallbackRust,
+		ExpectedType:         ExpectedTypeRust,
+	})
+}
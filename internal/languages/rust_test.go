@@ -1,3 +1,5 @@
+//go:build cgo && !envgrd_purego
+
 package languages
 
 import (
@@ -157,6 +159,83 @@ func TestExtractEnvVarsFromRust_DynamicPatterns(t *testing.T) {
 				{Key: "key", IsPartial: true, IsVarRef: true},
 			},
 		},
+		{
+			name: "format! with positional, indexed and named arguments",
+			matches: []map[string]string{
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "format",
+					"macro_args": `("FEATURE_{}", name)`,
+				},
+				{
+					"path1":      "std",
+					"path2":      "env",
+					"fn":         "var",
+					"macro":      "format",
+					"macro_args": `("{1}_{0:?}_{{X}}", region, env)`,
+				},
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "format",
+					"macro_args": `("SERVICE_{service}_{port}", port = cfg.port(1, 2))`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"FEATURE_" + name`, IsPartial: true, FullExpr: `"FEATURE_" + name`},
+				{Key: `env + "_" + region + "_{X}"`, IsPartial: true, FullExpr: `env + "_" + region + "_{X}"`},
+				{Key: `"SERVICE_" + service + "_" + cfg.port(1, 2)`, IsPartial: true, FullExpr: `"SERVICE_" + service + "_" + cfg.port(1, 2)`},
+			},
+		},
+		{
+			name: "concat! is resolved statically",
+			matches: []map[string]string{
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "concat",
+					"macro_args": `("APP_", "PORT")`,
+				},
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "concat",
+					"macro_args": `(r#"WORKER_"#, 2, '_', "URL",)`,
+				},
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "format",
+					"macro_args": `("PLAIN")`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: "APP_PORT", IsPartial: false},
+				{Key: "WORKER_2_URL", IsPartial: false},
+				{Key: "PLAIN", IsPartial: false},
+			},
+		},
+		{
+			name: "concat! with a nested macro",
+			matches: []map[string]string{
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "concat",
+					"macro_args": `("APP_", env!("STAGE"), "_URL")`,
+				},
+				{
+					"path":       "env",
+					"fn":         "var",
+					"macro":      "vec",
+					"macro_args": `("IGNORED")`,
+				},
+			},
+			expected: []EnvVarMatch{
+				{Key: `"APP_" + env!("STAGE") + "_URL"`, IsPartial: true, FullExpr: `"APP_" + env!("STAGE") + "_URL"`},
+			},
+		},
 	}
 
 	for _, tt := range tests {
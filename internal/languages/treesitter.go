@@ -0,0 +1,21 @@
+//go:build cgo && !envgrd_purego
+
+package languages
+
+import (
+	"fmt"
+	"unsafe"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Grammar loads the Tree-Sitter grammar for a language
+type Grammar func() (*sitter.Language, error)
+
+// loadGrammar wraps the grammar pointer returned by a tree-sitter language binding
+func loadGrammar(displayName string, langPtr unsafe.Pointer) (*sitter.Language, error) {
+	if langPtr == nil {
+		return nil, fmt.Errorf("failed to load %s language grammar", displayName)
+	}
+	return sitter.NewLanguage(langPtr), nil
+}
@@ -0,0 +1,36 @@
+//go:build cgo && !envgrd_purego && !envgrd_nolang_typescript
+
+package languages
+
+import (
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+// TypeScript shares the JavaScript query, process.env access looks the same in both grammars
+// TSX files need their own grammar: the TypeScript one reads <Component> as a type assertion and
+// drops the matches of the JSX it can't parse
+func init() {
+	RegisterLanguage(LanguageInfo{
+		Name:       "typescript",
+		Extensions: []string{".ts"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("TypeScript", tree_sitter_typescript.LanguageTypescript())
+		},
+		Query:                JavaScriptQuery,
+		ExtractorWithPartial: ExtractEnvVarsFromJS,
+		HasFallback:          HasFallbackJS,
+		ExpectedType:         ExpectedTypeJS,
+	})
+	RegisterLanguage(LanguageInfo{
+		Name:       "tsx",
+		Extensions: []string{".tsx"},
+		Grammar: func() (*sitter.Language, error) {
+			return loadGrammar("TSX", tree_sitter_typescript.LanguageTSX())
+		},
+		Query:                JavaScriptQuery,
+		ExtractorWithPartial: ExtractEnvVarsFromJS,
+		HasFallback:          HasFallbackJS,
+		ExpectedType:         ExpectedTypeJS,
+	})
+}
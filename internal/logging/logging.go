@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// LevelTrace is below debug and used for very chatty output (per-match parser dumps, queries)
+const LevelTrace = slog.LevelDebug - 4
+
+// Formats accepted by New
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LevelForVerbosity maps the number of -v flags to a log level
+// 0 = info (progress and warnings), 1 = debug, 2+ = trace
+func LevelForVerbosity(verbosity int) slog.Level {
+	switch {
+	case verbosity <= 0:
+		return slog.LevelInfo
+	case verbosity == 1:
+		return slog.LevelDebug
+	default:
+		return LevelTrace
+	}
+}
+
+// New creates a logger writing to w in the given format ("text" or "json")
+func New(w io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	switch format {
+	case "", FormatText:
+		return slog.New(NewTextHandler(w, level)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.LevelKey && len(groups) == 0 {
+					if lvl, ok := a.Value.Any().(slog.Level); ok && lvl <= LevelTrace {
+						a.Value = slog.StringValue("TRACE")
+					}
+				}
+				return a
+			},
+		})), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (supported: text, json)", format)
+	}
+}
+
+// Discard returns a logger that drops every record
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// OrDiscard returns logger, or a discarding logger if it is nil
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+	return logger
+}
+
+// TextHandler writes records in envgrd's historical stderr style:
+// info records are printed as plain messages, warnings are prefixed with "Warning:",
+// and debug/trace records with "[DEBUG]"/"[TRACE]", followed by key=value attributes
+type TextHandler struct {
+	w      io.Writer
+	level  slog.Leveler
+	attrs  string // Pre-rendered attributes from WithAttrs
+	groups []string
+	mu     *sync.Mutex
+}
+
+// NewTextHandler creates a TextHandler writing records at or above level to w
+func NewTextHandler(w io.Writer, level slog.Leveler) *TextHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &TextHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+// Enabled reports whether records at the given level are written
+func (h *TextHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes a single record
+func (h *TextHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case r.Level >= slog.LevelInfo:
+	case r.Level >= slog.LevelDebug:
+		b.WriteString("[DEBUG] ")
+	default:
+		b.WriteString("[TRACE] ")
+	}
+	b.WriteString(r.Message)
+
+	b.WriteString(h.attrs)
+	prefix := strings.Join(h.groups, ".")
+	r.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&b, prefix, attr)
+		return true
+	})
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that includes attrs in every record
+func (h *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	prefix := strings.Join(h.groups, ".")
+	for _, attr := range attrs {
+		writeAttr(&b, prefix, attr)
+	}
+	clone := *h
+	clone.attrs = h.attrs + b.String()
+	return &clone
+}
+
+// WithGroup returns a handler that prefixes attribute keys with name
+func (h *TextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string{}, h.groups...), name)
+	return &clone
+}
+
+// writeAttr appends " key=value" to b, quoting values that contain spaces
+func writeAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := attr.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, groupAttr := range attr.Value.Group() {
+			writeAttr(b, key, groupAttr)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	fmt.Fprintf(b, " %s=%s", key, value)
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevelForVerbosity(t *testing.T) {
+	tests := []struct {
+		verbosity int
+		want      slog.Level
+	}{
+		{0, slog.LevelInfo},
+		{1, slog.LevelDebug},
+		{2, LevelTrace},
+		{5, LevelTrace},
+	}
+
+	for _, tt := range tests {
+		if got := LevelForVerbosity(tt.verbosity); got != tt.want {
+			t.Errorf("LevelForVerbosity(%d) = %v, want %v", tt.verbosity, got, tt.want)
+		}
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTextHandler(&buf, slog.LevelDebug))
+
+	logger.Info("Scanning /tmp/project...")
+	logger.Warn("failed to parse app.js: boom")
+	logger.Debug("skipping file", "path", "a b.js", "reason", "excluded")
+	logger.Log(nil, LevelTrace, "match", "key", "API_KEY")
+
+	expected := "Scanning /tmp/project...\n" +
+		"Warning: failed to parse app.js: boom\n" +
+		"[DEBUG] skipping file path=\"a b.js\" reason=excluded\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestTextHandler_WithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTextHandler(&buf, LevelTrace)).With("component", "lsp").WithGroup("req")
+
+	logger.Log(nil, LevelTrace, "request", "method", "initialize")
+
+	if got := buf.String(); got != "[TRACE] request component=lsp req.method=initialize\n" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, LevelTrace, FormatJSON)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Log(nil, LevelTrace, "match", "key", "API_KEY")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected JSON log line, got %q: %v", buf.String(), err)
+	}
+	if record["level"] != "TRACE" || record["msg"] != "match" || record["key"] != "API_KEY" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, slog.LevelInfo, "xml"); err == nil || !strings.Contains(err.Error(), "unknown log format") {
+		t.Errorf("Expected unknown log format error, got %v", err)
+	}
+}
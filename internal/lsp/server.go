@@ -0,0 +1,673 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/parser"
+	"github.com/jenian/envgrd/internal/scanner"
+)
+
+// diagnosticSource is shown by editors next to every diagnostic
+const diagnosticSource = "envgrd"
+
+// Server is a Language Server Protocol server that publishes envgrd findings as diagnostics
+type Server struct {
+	conn    *conn
+	version string
+	logger  *slog.Logger
+
+	root     string
+	cfg      *config.Config
+	tsParser *parser.Parser
+
+	envVars      map[string]string // All env vars (from files + exported)
+	fileVars     map[string]string // Only vars from env files (for unused check)
+	envSources   map[string]string // Maps env var key to absolute source file path
+	relSources   map[string]string // Maps env var key to source file path relative to root
+	envFiles     map[string]bool   // Absolute paths of env files that defined at least one variable
+	usages       map[string][]analyzer.EnvUsage
+	documents    map[string]string // Open documents by absolute path
+	published    map[string]bool   // Documents that currently have diagnostics published
+	shutdownSeen bool
+}
+
+// NewServer creates a new LSP server communicating over the given streams
+func NewServer(in io.Reader, out io.Writer, version string) *Server {
+	return &Server{
+		conn:       newConn(in, out),
+		version:    version,
+		logger:     slog.New(logging.NewTextHandler(os.Stderr, slog.LevelInfo)).With("component", "lsp"),
+		cfg:        &config.Config{},
+		tsParser:   parser.NewParser(),
+		envVars:    make(map[string]string),
+		fileVars:   make(map[string]string),
+		envSources: make(map[string]string),
+		relSources: make(map[string]string),
+		envFiles:   make(map[string]bool),
+		usages:     make(map[string][]analyzer.EnvUsage),
+		documents:  make(map[string]string),
+		published:  make(map[string]bool),
+	}
+}
+
+// SetDebug enables or disables debug logging to stderr
+// Kept for backward compatibility, SetLogger gives finer control
+func (s *Server) SetDebug(debug bool) {
+	level := slog.LevelInfo
+	if debug {
+		level = logging.LevelTrace
+	}
+	s.SetLogger(slog.New(logging.NewTextHandler(os.Stderr, level)))
+}
+
+// SetLogger sets the logger used for server diagnostics (never stdout, which carries the protocol)
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logging.OrDiscard(logger).With("component", "lsp")
+	s.tsParser.SetLogger(s.logger)
+}
+
+// Run processes messages until the client sends "exit" or the input stream is closed
+func (s *Server) Run() error {
+	for {
+		body, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if writeErr := s.conn.write(response{JSONRPC: "2.0", Error: &responseError{Code: codeParseError, Message: err.Error()}}); writeErr != nil {
+				return writeErr
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdownSeen {
+				return fmt.Errorf("received exit before shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+		if req.ID == nil {
+			// Notifications never get a response
+			continue
+		}
+		if err := s.conn.write(response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a single request or notification
+func (s *Server) handle(req request) (interface{}, *responseError) {
+	s.logger.Debug("request", "method", req.Method)
+
+	switch req.Method {
+	case "initialize":
+		var params initializeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.initialize(params), nil
+	case "initialized":
+		s.loadWorkspace()
+		s.publishEnvFiles()
+		return nil, nil
+	case "shutdown":
+		s.shutdownSeen = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		s.updateDocument(uriToPath(params.TextDocument.URI), params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// We only advertise full document sync, so the last change holds the whole text
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.updateDocument(uriToPath(params.TextDocument.URI), text)
+		return nil, nil
+	case "textDocument/didSave":
+		var params didSaveParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		path := uriToPath(params.TextDocument.URI)
+		text, ok := s.documents[path]
+		if params.Text != nil {
+			text, ok = *params.Text, true
+		}
+		if ok {
+			s.updateDocument(path, text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		s.closeDocument(uriToPath(params.TextDocument.URI))
+		return nil, nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.hover(uriToPath(params.TextDocument.URI), params.Position), nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.definition(uriToPath(params.TextDocument.URI), params.Position), nil
+	default:
+		if strings.HasPrefix(req.Method, "$/") {
+			// Optional protocol notifications (e.g., $/cancelRequest) can be ignored
+			return nil, nil
+		}
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// initialize records the workspace root and advertises server capabilities
+func (s *Server) initialize(params initializeParams) initializeResult {
+	root := params.RootPath
+	if params.RootURI != "" {
+		root = uriToPath(params.RootURI)
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	if absRoot, err := filepath.Abs(root); err == nil {
+		root = absRoot
+	}
+	s.root = root
+
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:   textDocumentSyncOptions{OpenClose: true, Change: 1, Save: true},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+		},
+		ServerInfo: serverInfo{Name: "envgrd", Version: s.version},
+	}
+}
+
+// loadWorkspace loads config and env files, and parses every source file under the root
+func (s *Server) loadWorkspace() {
+	cfg, err := config.LoadConfig(s.root)
+	if err != nil {
+		s.logf("failed to load .envgrd.config: %v", err)
+		cfg = &config.Config{}
+	}
+	s.cfg = cfg
+
+	s.loadEnv()
+
+	fileScanner := scanner.NewScanner()
+	fileScanner.SetLogger(s.logger)
+	if len(cfg.Ignores.Folders) > 0 {
+		fileScanner.AddExcludeDirs(cfg.Ignores.Folders)
+	}
+	files, err := fileScanner.Scan(s.root)
+	if err != nil {
+		s.logf("failed to scan %s: %v", s.root, err)
+		return
+	}
+
+	for _, file := range files {
+		usages, err := s.tsParser.ParseFile(file.Path, string(file.Language), s.root)
+		if err != nil {
+			s.logf("failed to parse %s: %v", file.Path, err)
+			continue
+		}
+		if file.InIgnoredPath {
+			for i := range usages {
+				usages[i].InIgnoredPath = true
+			}
+		}
+		s.usages[file.Path] = usages
+	}
+}
+
+// loadEnv (re)loads all env files under the root
+func (s *Server) loadEnv() {
+	loader := envfile.NewLoader()
+	loader.SetLogger(s.logger)
+	if s.cfg != nil {
+		loader.SetPrecedence(s.cfg.Precedence)
+		if err := loader.SetExclude(s.cfg.EnvFiles.Exclude); err != nil {
+			s.logf("%v", err)
+		}
+	}
+	envVars, fileVars, sources, err := loader.LoadWithExportedEnv(s.root)
+	if err != nil {
+		s.logf("failed to load env files: %v", err)
+		return
+	}
+
+	s.envVars = envVars
+	s.fileVars = fileVars
+	s.envSources = sources
+	s.relSources = make(map[string]string)
+	s.envFiles = make(map[string]bool)
+	for key, sourcePath := range sources {
+		s.envFiles[sourcePath] = true
+		if rel, err := filepath.Rel(s.root, sourcePath); err == nil {
+			s.relSources[key] = rel
+		} else {
+			s.relSources[key] = filepath.Base(sourcePath)
+		}
+	}
+}
+
+// updateDocument stores the latest text of a document and republishes affected diagnostics
+func (s *Server) updateDocument(path string, text string) {
+	s.documents[path] = text
+
+	if s.isEnvFile(path) {
+		// Env files are read from disk by the loader, so edits only take effect once saved
+		// Reloading on every change is still cheap and picks up saves immediately
+		s.loadEnv()
+		s.publishOpenDocuments()
+		s.publishEnvFiles()
+		return
+	}
+
+	lang := scanner.DetectLanguage(path)
+	if lang == scanner.LanguageUnknown {
+		return
+	}
+
+	usages, err := s.tsParser.ParseContent(path, []byte(text), string(lang), s.root)
+	if err != nil {
+		s.logf("failed to parse %s: %v", path, err)
+		return
+	}
+	s.usages[path] = usages
+
+	s.publishDocument(path)
+	s.publishEnvFiles()
+}
+
+// closeDocument forgets an open document and falls back to its on-disk content
+func (s *Server) closeDocument(path string) {
+	delete(s.documents, path)
+
+	lang := scanner.DetectLanguage(path)
+	if lang != scanner.LanguageUnknown {
+		if usages, err := s.tsParser.ParseFile(path, string(lang), s.root); err == nil {
+			s.usages[path] = usages
+		} else {
+			delete(s.usages, path)
+		}
+	}
+
+	if s.published[path] && !s.envFiles[path] {
+		s.publish(path, []Diagnostic{})
+		delete(s.published, path)
+	}
+	s.publishEnvFiles()
+}
+
+// isEnvFile reports whether a path is (or looks like) an env definition file
+func (s *Server) isEnvFile(path string) bool {
+	if s.envFiles[path] {
+		return true
+	}
+	return strings.HasPrefix(filepath.Base(path), ".env")
+}
+
+// analyze runs the analyzer over the current workspace state
+func (s *Server) analyze() analyzer.ScanResult {
+	var allUsages []analyzer.EnvUsage
+	for _, usages := range s.usages {
+		allUsages = append(allUsages, usages...)
+	}
+	return analyzer.Analyze(allUsages, s.envVars, s.fileVars, s.relSources, s.cfg)
+}
+
+// publishOpenDocuments republishes diagnostics for every open source document
+func (s *Server) publishOpenDocuments() {
+	for path := range s.documents {
+		if !s.isEnvFile(path) {
+			s.publishDocument(path)
+		}
+	}
+}
+
+// publishDocument publishes missing, optional, test-only and dynamic findings for a single source file
+func (s *Server) publishDocument(path string) {
+	result := s.analyze()
+	rel := s.relPath(path)
+	lines := s.documentLines(path)
+
+	diagnostics := []Diagnostic{}
+	for key, usages := range result.Missing {
+		for _, usage := range usages {
+			if usage.File != rel {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    usageRange(lines, usage, key),
+				Severity: diagnosticSeverity(result.SeverityOf(config.CategoryMissing, key)),
+				Code:     "missing",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("Environment variable %s is not defined in any env file", key),
+			})
+		}
+	}
+	for key, usages := range result.OptionalMissing {
+		for _, usage := range usages {
+			if usage.File != rel {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    usageRange(lines, usage, key),
+				Severity: diagnosticSeverity(result.SeverityOf(config.CategoryOptional, key)),
+				Code:     "optional",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("Environment variable %s is not defined in any env file, the default value is used", key),
+			})
+		}
+	}
+	for key, usages := range result.TestMissing {
+		for _, usage := range usages {
+			if usage.File != rel {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    usageRange(lines, usage, key),
+				Severity: diagnosticSeverity(result.SeverityOf(config.CategoryTest, key)),
+				Code:     "test",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("Environment variable %s is not defined in any env file (only used in tests)", key),
+			})
+		}
+	}
+	for key, usages := range result.PartialMatches {
+		for _, usage := range usages {
+			if usage.File != rel {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    usageRange(lines, usage, usage.Key),
+				Severity: diagnosticSeverity(result.SeverityOf(config.CategoryDynamic, key)),
+				Code:     "dynamic",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("Dynamic environment variable access %s cannot be resolved statically", key),
+			})
+		}
+	}
+	sortDiagnostics(diagnostics)
+
+	s.publish(path, diagnostics)
+}
+
+// publishEnvFiles publishes unused findings on the env files that define them
+func (s *Server) publishEnvFiles() {
+	result := s.analyze()
+
+	byFile := make(map[string][]Diagnostic)
+	for envFile := range s.envFiles {
+		byFile[envFile] = []Diagnostic{}
+	}
+	for _, key := range result.Unused {
+		sourcePath := s.envSources[key]
+		if sourcePath == "" {
+			continue
+		}
+		line, col := findDefinition(s.documentLines(sourcePath), key)
+		if line < 0 {
+			line, col = 0, 0
+		}
+		byFile[sourcePath] = append(byFile[sourcePath], Diagnostic{
+			Range: Range{
+				Start: Position{Line: line, Character: col},
+				End:   Position{Line: line, Character: col + len(key)},
+			},
+			Severity: diagnosticSeverity(result.SeverityOf(config.CategoryUnused, key)),
+			Code:     "unused",
+			Source:   diagnosticSource,
+			Message:  fmt.Sprintf("Environment variable %s is defined but never used in code", key),
+		})
+	}
+
+	// Clear diagnostics on env files that no longer define anything
+	for path := range s.published {
+		if _, ok := byFile[path]; !ok && s.isEnvFile(path) {
+			byFile[path] = []Diagnostic{}
+		}
+	}
+
+	for path, diagnostics := range byFile {
+		sortDiagnostics(diagnostics)
+		s.publish(path, diagnostics)
+	}
+}
+
+// publish sends a textDocument/publishDiagnostics notification
+func (s *Server) publish(path string, diagnostics []Diagnostic) {
+	if len(diagnostics) > 0 {
+		s.published[path] = true
+	}
+	err := s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: pathToURI(path), Diagnostics: diagnostics},
+	})
+	if err != nil {
+		s.logf("failed to publish diagnostics for %s: %v", path, err)
+	}
+}
+
+// usageAt returns the static usage under the cursor, if any
+func (s *Server) usageAt(path string, pos Position) (analyzer.EnvUsage, Range, bool) {
+	lines := s.documentLines(path)
+	for _, usage := range s.usages[path] {
+		if usage.Line-1 != pos.Line || usage.IsPartial {
+			continue
+		}
+		r := usageRange(lines, usage, usage.Key)
+		if pos.Character >= r.Start.Character && pos.Character <= r.End.Character {
+			return usage, r, true
+		}
+	}
+	return analyzer.EnvUsage{}, Range{}, false
+}
+
+// hover describes where the variable under the cursor is defined
+func (s *Server) hover(path string, pos Position) *hover {
+	usage, r, ok := s.usageAt(path, pos)
+	if !ok {
+		return nil
+	}
+
+	var value string
+	if sourcePath, ok := s.envSources[usage.Key]; ok {
+		line, _ := findDefinition(s.documentLines(sourcePath), usage.Key)
+		value = fmt.Sprintf("**%s**\n\nDefined in `%s:%d`", usage.Key, s.relPath(sourcePath), line+1)
+	} else if _, ok := s.envVars[usage.Key]; ok {
+		value = fmt.Sprintf("**%s**\n\nProvided by the exported environment", usage.Key)
+	} else if s.cfg.ShouldIgnoreMissing(usage.Key) {
+		value = fmt.Sprintf("**%s**\n\nNot defined in any env file (ignored in .envgrd.config)", usage.Key)
+	} else {
+		value = fmt.Sprintf("**%s**\n\nNot defined in any env file", usage.Key)
+	}
+
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    &r,
+	}
+}
+
+// definition jumps from a usage to the env file line that defines it
+func (s *Server) definition(path string, pos Position) []Location {
+	usage, _, ok := s.usageAt(path, pos)
+	if !ok {
+		return nil
+	}
+
+	sourcePath, ok := s.envSources[usage.Key]
+	if !ok {
+		return nil
+	}
+
+	line, col := findDefinition(s.documentLines(sourcePath), usage.Key)
+	if line < 0 {
+		line, col = 0, 0
+	}
+	return []Location{{
+		URI: pathToURI(sourcePath),
+		Range: Range{
+			Start: Position{Line: line, Character: col},
+			End:   Position{Line: line, Character: col + len(usage.Key)},
+		},
+	}}
+}
+
+// documentLines returns the lines of an open document, or of the file on disk
+func (s *Server) documentLines(path string) []string {
+	if text, ok := s.documents[path]; ok {
+		return strings.Split(text, "\n")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	lineScanner := bufio.NewScanner(file)
+	for lineScanner.Scan() {
+		lines = append(lines, lineScanner.Text())
+	}
+	return lines
+}
+
+// relPath returns the path relative to the workspace root, matching EnvUsage.File
+func (s *Server) relPath(path string) string {
+	if rel, err := filepath.Rel(s.root, path); err == nil && rel != "" {
+		return rel
+	}
+	return path
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	s.logger.Warn(fmt.Sprintf(format, args...))
+}
+
+// diagnosticSeverity maps a configured finding severity to an LSP diagnostic severity
+func diagnosticSeverity(severity config.Severity) int {
+	switch severity {
+	case config.SeverityError:
+		return severityError
+	case config.SeverityWarning:
+		return severityWarning
+	default:
+		return severityInformation
+	}
+}
+
+// usageRange returns the range of a key on the usage's line, or the whole line if not found
+func usageRange(lines []string, usage analyzer.EnvUsage, key string) Range {
+	line := usage.Line - 1
+	if line < 0 || line >= len(lines) {
+		return Range{Start: Position{Line: max(line, 0)}, End: Position{Line: max(line, 0)}}
+	}
+
+	text := strings.TrimRight(lines[line], "\r")
+	if col := strings.Index(text, key); col >= 0 && key != "" {
+		return Range{
+			Start: Position{Line: line, Character: col},
+			End:   Position{Line: line, Character: col + len(key)},
+		}
+	}
+
+	start := len(text) - len(strings.TrimLeft(text, " \t"))
+	return Range{
+		Start: Position{Line: line, Character: start},
+		End:   Position{Line: line, Character: len(text)},
+	}
+}
+
+// findDefinition returns the zero-based line and column where key is defined, or -1 if not found
+// Works across all supported env formats (KEY=value, export KEY=value, KEY: value, - KEY=value)
+func findDefinition(lines []string, key string) (int, int) {
+	pattern := regexp.MustCompile(`(^|[^A-Za-z0-9_])` + regexp.QuoteMeta(key) + `\s*[=:]`)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if loc := pattern.FindStringSubmatchIndex(line); loc != nil {
+			// loc[3] is the end of the leading boundary group, i.e. where the key starts
+			return i, loc[3]
+		}
+	}
+	return -1, -1
+}
+
+// sortDiagnostics orders diagnostics by position for stable output
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.Slice(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+}
+
+// uriToPath converts a file:// URI to a local filesystem path
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	path := parsed.Path
+	// file:///C:/foo parses to /C:/foo on Windows
+	if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+// pathToURI converts a local filesystem path to a file:// URI
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
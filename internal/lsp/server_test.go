@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// frame encodes a JSON-RPC message with a Content-Length header
+func frame(t *testing.T, msg map[string]interface{}) string {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// readMessages decodes every framed message written by the server
+func readMessages(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	c := newConn(bufio.NewReader(out), nil)
+	var messages []map[string]interface{}
+	for {
+		body, err := c.read()
+		if err != nil {
+			break
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("Failed to decode server message: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestServer_DiagnosticsHoverDefinition(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("# comment\nAPI_KEY=abc\nUNUSED_VAR=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	appPath := filepath.Join(tmpDir, "app.js")
+	code := "const a = process.env.API_KEY;\nconst b = process.env.MISSING_VAR;\n"
+	if err := os.WriteFile(appPath, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write app.js: %v", err)
+	}
+	appURI := pathToURI(appPath)
+
+	var in strings.Builder
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]interface{}{"rootUri": pathToURI(tmpDir)}}))
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "method": "initialized", "params": map[string]interface{}{}}))
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": appURI, "languageId": "javascript", "version": 1, "text": code},
+	}}))
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "textDocument/hover", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": appURI}, "position": map[string]interface{}{"line": 0, "character": 24},
+	}}))
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "id": 3, "method": "textDocument/definition", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": appURI}, "position": map[string]interface{}{"line": 0, "character": 24},
+	}}))
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "id": 4, "method": "shutdown"}))
+	in.WriteString(frame(t, map[string]interface{}{"jsonrpc": "2.0", "method": "exit"}))
+
+	var out bytes.Buffer
+	server := NewServer(strings.NewReader(in.String()), &out, "test")
+	if err := server.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var appDiagnostics, envDiagnostics []interface{}
+	responses := make(map[float64]map[string]interface{})
+	for _, msg := range readMessages(t, &out) {
+		if msg["method"] == "textDocument/publishDiagnostics" {
+			params := msg["params"].(map[string]interface{})
+			diagnostics, _ := params["diagnostics"].([]interface{})
+			switch params["uri"] {
+			case appURI:
+				appDiagnostics = diagnostics
+			case pathToURI(filepath.Join(tmpDir, ".env")):
+				envDiagnostics = diagnostics
+			}
+			continue
+		}
+		if id, ok := msg["id"].(float64); ok {
+			responses[id] = msg
+		}
+	}
+
+	if len(appDiagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic for app.js, got %d", len(appDiagnostics))
+	}
+	missing := appDiagnostics[0].(map[string]interface{})
+	if !strings.Contains(missing["message"].(string), "MISSING_VAR") {
+		t.Errorf("Expected missing diagnostic for MISSING_VAR, got %q", missing["message"])
+	}
+	start := missing["range"].(map[string]interface{})["start"].(map[string]interface{})
+	if start["line"].(float64) != 1 || start["character"].(float64) != 22 {
+		t.Errorf("Expected diagnostic at 1:22, got %v:%v", start["line"], start["character"])
+	}
+
+	if len(envDiagnostics) != 1 {
+		t.Fatalf("Expected 1 unused diagnostic for .env, got %d", len(envDiagnostics))
+	}
+	unused := envDiagnostics[0].(map[string]interface{})
+	unusedStart := unused["range"].(map[string]interface{})["start"].(map[string]interface{})
+	if !strings.Contains(unused["message"].(string), "UNUSED_VAR") || unusedStart["line"].(float64) != 2 {
+		t.Errorf("Expected unused diagnostic for UNUSED_VAR on line 2, got %v", unused)
+	}
+
+	hoverResult, ok := responses[2]["result"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected hover result, got %v", responses[2])
+	}
+	hoverValue := hoverResult["contents"].(map[string]interface{})["value"].(string)
+	if !strings.Contains(hoverValue, "API_KEY") || !strings.Contains(hoverValue, ".env:2") {
+		t.Errorf("Unexpected hover content: %q", hoverValue)
+	}
+
+	locations, ok := responses[3]["result"].([]interface{})
+	if !ok || len(locations) != 1 {
+		t.Fatalf("Expected 1 definition location, got %v", responses[3]["result"])
+	}
+	location := locations[0].(map[string]interface{})
+	if location["uri"] != pathToURI(filepath.Join(tmpDir, ".env")) {
+		t.Errorf("Expected definition in .env, got %v", location["uri"])
+	}
+	defStart := location["range"].(map[string]interface{})["start"].(map[string]interface{})
+	if defStart["line"].(float64) != 1 {
+		t.Errorf("Expected definition on line 1, got %v", defStart["line"])
+	}
+}
+
+func TestFindDefinition(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		key      string
+		wantLine int
+		wantCol  int
+	}{
+		{[]string{"FOO=1", "BAR=2"}, "BAR", 1, 0},
+		{[]string{"export FOO=1"}, "FOO", 0, 7},
+		{[]string{"# FOO=commented", "FOO_BAR=1", "  FOO: value"}, "FOO", 2, 2},
+		{[]string{"      - DB_HOST=localhost"}, "DB_HOST", 0, 8},
+		{[]string{"OTHER=1"}, "FOO", -1, -1},
+	}
+
+	for _, tt := range tests {
+		line, col := findDefinition(tt.lines, tt.key)
+		if line != tt.wantLine || col != tt.wantCol {
+			t.Errorf("findDefinition(%v, %q) = %d:%d, want %d:%d", tt.lines, tt.key, line, col, tt.wantLine, tt.wantCol)
+		}
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir with space", "app.js")
+	if got := uriToPath(pathToURI(path)); got != path {
+		t.Errorf("uriToPath(pathToURI(%q)) = %q", path, got)
+	}
+}
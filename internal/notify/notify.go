@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/output"
+)
+
+// timeout bounds the webhook request
+const timeout = 10 * time.Second
+
+// maxKeys is how many variables are listed per category in a Slack message
+const maxKeys = 10
+
+// Webhook posts a summary of the findings to a URL
+type Webhook struct {
+	URL    string
+	Format string          // config.NotifyJSON (default) or config.NotifySlack
+	On     config.Severity // Only notify when a finding is at least this severe (default error)
+	Client *http.Client    // nil uses http.DefaultClient
+}
+
+// Summary is the JSON payload
+type Summary struct {
+	Path            string          `json:"path"`
+	Message         string          `json:"message"` // Finding counts by category, e.g. "3 missing, 1 unused"
+	HighestSeverity config.Severity `json:"highest_severity"`
+	Missing         []string        `json:"missing,omitempty"`
+	Unused          []string        `json:"unused,omitempty"`
+	Dynamic         []string        `json:"dynamic,omitempty"`
+}
+
+// Notify posts the summary of a scan of path when its most severe finding reaches the threshold
+// It reports whether a notification was sent
+func (h Webhook) Notify(ctx context.Context, path string, result analyzer.ScanResult, opts output.Options) (bool, error) {
+	if err := config.ValidateNotifyFormat(h.Format); err != nil {
+		return false, err
+	}
+	on := h.On
+	if on == "" {
+		on = config.SeverityError
+	}
+	highest := output.HighestSeverity(result, opts.SkipUnused, opts.Dynamic)
+	if highest.Rank() < on.Rank() {
+		return false, nil
+	}
+
+	summary := Summary{
+		Path:            path,
+		Message:         output.Summary(result, opts),
+		HighestSeverity: highest,
+		Missing:         sortedKeys(result.Missing),
+	}
+	if !opts.SkipUnused {
+		summary.Unused = append([]string{}, result.Unused...)
+		sort.Strings(summary.Unused)
+	}
+	if opts.Dynamic {
+		summary.Dynamic = sortedKeys(result.PartialMatches)
+	}
+	var payload any = summary
+	if h.Format == config.NotifySlack {
+		payload = slackMessage(summary)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, withoutURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, withoutURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return true, nil
+}
+
+// withoutURL drops the URL from request errors, webhook URLs usually embed a secret token
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// slackMessage renders the summary as a Slack message, with text as the notification fallback
+func slackMessage(summary Summary) map[string]any {
+	text := fmt.Sprintf("envgrd found %s in %s", summary.Message, summary.Path)
+	blocks := []map[string]any{
+		{"type": "section", "text": mrkdwn(fmt.Sprintf("*envgrd found %s* in `%s` (highest severity: %s)", summary.Message, summary.Path, summary.HighestSeverity))},
+	}
+	for _, list := range []struct {
+		title string
+		keys  []string
+	}{
+		{"Missing", summary.Missing},
+		{"Unused", summary.Unused},
+		{"Dynamic", summary.Dynamic},
+	} {
+		if len(list.keys) == 0 {
+			continue
+		}
+		keys := list.keys
+		rest := ""
+		if len(keys) > maxKeys {
+			rest = fmt.Sprintf(" and %d more", len(keys)-maxKeys)
+			keys = keys[:maxKeys]
+		}
+		blocks = append(blocks, map[string]any{
+			"type": "section",
+			"text": mrkdwn(fmt.Sprintf("*%s:* `%s`%s", list.title, strings.Join(keys, "`, `"), rest)),
+		})
+	}
+	return map[string]any{"text": text, "blocks": blocks}
+}
+
+func mrkdwn(text string) map[string]string {
+	return map[string]string{"type": "mrkdwn", "text": text}
+}
+
+func sortedKeys(findings map[string][]analyzer.EnvUsage) []string {
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
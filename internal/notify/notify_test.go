@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/output"
+)
+
+func testResult() analyzer.ScanResult {
+	return analyzer.ScanResult{
+		Missing: map[string][]analyzer.EnvUsage{
+			"DB_HOST": {{Key: "DB_HOST", File: "app.js", Line: 3}},
+		},
+		Unused: []string{"OLD_FLAG"},
+	}
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	sent, err := Webhook{URL: server.URL}.Notify(context.Background(), "app", testResult(), output.Options{})
+	if err != nil || !sent {
+		t.Fatalf("Notify = %v, %v, want a notification", sent, err)
+	}
+	var summary Summary
+	if err := json.Unmarshal([]byte(bodies[0]), &summary); err != nil {
+		t.Fatalf("Invalid JSON payload: %v", err)
+	}
+	if summary.Path != "app" || summary.Message != "1 missing, 1 unused" || summary.HighestSeverity != config.SeverityError ||
+		len(summary.Missing) != 1 || len(summary.Unused) != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	sent, err = Webhook{URL: server.URL, Format: config.NotifySlack}.Notify(context.Background(), "app", testResult(), output.Options{})
+	if err != nil || !sent {
+		t.Fatalf("Notify = %v, %v, want a notification", sent, err)
+	}
+	if !strings.Contains(bodies[1], `"blocks"`) || !strings.Contains(bodies[1], "*Missing:* `DB_HOST`") {
+		t.Errorf("Unexpected Slack payload: %s", bodies[1])
+	}
+
+	// Unused variables are warnings, below the default threshold
+	result := testResult()
+	result.Missing = nil
+	if sent, err := (Webhook{URL: server.URL}).Notify(context.Background(), "app", result, output.Options{}); err != nil || sent {
+		t.Errorf("Notify = %v, %v, want no notification below the threshold", sent, err)
+	}
+	if sent, err := (Webhook{URL: server.URL, On: config.SeverityWarning}).Notify(context.Background(), "app", result, output.Options{}); err != nil || !sent {
+		t.Errorf("Notify = %v, %v, want a notification at warning", sent, err)
+	}
+	if len(bodies) != 3 {
+		t.Errorf("Expected 3 requests, got %d", len(bodies))
+	}
+}
+
+func TestWebhook_NotifyErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if _, err := (Webhook{URL: server.URL}).Notify(context.Background(), "app", testResult(), output.Options{}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a status error, got %v", err)
+	}
+	if _, err := (Webhook{URL: server.URL, Format: "xml"}).Notify(context.Background(), "app", testResult(), output.Options{}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	// The URL, which usually holds a token, stays out of errors
+	url := "http://127.0.0.1:1/services/SECRET-TOKEN"
+	if _, err := (Webhook{URL: url}).Notify(context.Background(), "app", testResult(), output.Options{}); err == nil || strings.Contains(err.Error(), "SECRET-TOKEN") {
+		t.Errorf("Expected a connection error without the URL, got %v", err)
+	}
+}
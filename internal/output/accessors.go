@@ -0,0 +1,344 @@
+package output
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/schema"
+)
+
+// Accessor targets of envgrd generate
+const (
+	AccessorsTypeScript = "ts"
+	AccessorsGo         = "go"
+	AccessorsPython     = "python"
+)
+
+// Value types of accessor fields, declared by the schema or inferred from the effective env file value
+const (
+	accessorString = "string"
+	accessorInt    = "int"
+	accessorFloat  = "float"
+	accessorBool   = "bool"
+)
+
+// schemaAccessorTypes maps the types the schema declares to accessor types
+var schemaAccessorTypes = map[string]string{
+	"string":           accessorString,
+	schema.TypeInteger: accessorInt,
+	schema.TypeNumber:  accessorFloat,
+	schema.TypeBoolean: accessorBool,
+}
+
+// accessorHeader marks generated accessor modules, in the form Go tooling recognizes
+const accessorHeader = "Code generated by envgrd generate; DO NOT EDIT."
+
+// Accessor is a variable of a generated accessor module
+type Accessor struct {
+	Key      string
+	Type     string // string, int, float or bool
+	Optional bool   // Every usage falls back to a default, and the config doesn't require it
+	// Description is the description the schema or an env file comment declares, rendered as a doc comment
+	Description string
+}
+
+// BuildAccessors returns the variables read by the code (and the config's required ones), sorted by key,
+// with the types and descriptions of the scan's schema
+// Variables the schema declares no type for are typed by their env file value
+// Dynamic patterns, usages in ignored folders and variables only used in tests are left out
+func BuildAccessors(result analyzer.ScanResult) []Accessor {
+	optional := make(map[string]bool)
+	for _, usage := range result.CodeKeys {
+		if usage.IsPartial || usage.IsVarRef || usage.InIgnoredPath || usage.InTest {
+			continue
+		}
+		if _, seen := optional[usage.Key]; !seen {
+			optional[usage.Key] = true
+		}
+		optional[usage.Key] = optional[usage.Key] && usage.IsOptional
+	}
+	for _, key := range result.Required {
+		optional[key] = false
+	}
+
+	accessors := make([]Accessor, 0, len(optional))
+	for key, isOptional := range optional {
+		accessor := Accessor{Key: key, Type: accessorType(result.EnvKeys[key]), Optional: isOptional}
+		if result.Schema != nil {
+			declared := result.Schema.Variables[key]
+			accessor.Description = strings.Join(strings.Fields(declared.Description), " ")
+			if typ, ok := schemaAccessorTypes[declared.Type]; ok {
+				accessor.Type = typ
+			}
+		}
+		accessors = append(accessors, accessor)
+	}
+	sort.Slice(accessors, func(i, j int) bool {
+		return accessors[i].Key < accessors[j].Key
+	})
+	return accessors
+}
+
+// accessorType infers the type of a variable from its value, string when unknown
+func accessorType(value string) string {
+	switch {
+	case value == "":
+		return accessorString
+	case value == "true" || value == "false":
+		return accessorBool
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return accessorInt
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil && strings.Contains(value, ".") {
+		return accessorFloat
+	}
+	return accessorString
+}
+
+// WriteAccessors renders a typed accessor module for the target: ts, go or python
+// pkg is the package name of Go modules
+func WriteAccessors(w io.Writer, accessors []Accessor, target string, pkg string) error {
+	var src string
+	switch target {
+	case AccessorsTypeScript:
+		src = typeScriptAccessors(accessors)
+	case AccessorsGo:
+		formatted, err := format.Source([]byte(goAccessors(accessors, pkg)))
+		if err != nil {
+			return fmt.Errorf("failed to format Go accessors: %w", err)
+		}
+		src = string(formatted)
+	case AccessorsPython:
+		src = pythonAccessors(accessors)
+	default:
+		return fmt.Errorf("unknown generate target %q (supported: ts, go, python)", target)
+	}
+	_, err := io.WriteString(w, src)
+	return err
+}
+
+// jsIdentifier matches names that can be used as a TypeScript property without quotes
+var jsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// typeScriptAccessors renders the ProcessEnv declarations and an env object validating required variables
+func typeScriptAccessors(accessors []Accessor) string {
+	var b strings.Builder
+	b.WriteString("// " + accessorHeader + "\n\n")
+
+	b.WriteString("declare global {\n  namespace NodeJS {\n    interface ProcessEnv {\n")
+	for _, a := range accessors {
+		optional := ""
+		if a.Optional {
+			optional = "?"
+		}
+		if a.Description != "" {
+			fmt.Fprintf(&b, "      /** %s */\n", strings.ReplaceAll(a.Description, "*/", "*\\/"))
+		}
+		fmt.Fprintf(&b, "      %s%s: string;\n", tsProperty(a.Key), optional)
+	}
+	b.WriteString("    }\n  }\n}\n\n")
+
+	b.WriteString(`function required(name: string): string {
+  const value = process.env[name];
+  if (value === undefined || value === "") {
+    throw new Error(` + "`Missing required environment variable ${name}`" + `);
+  }
+  return value;
+}
+
+function optional<T>(name: string, parse: (value: string) => T): T | undefined {
+  const value = process.env[name];
+  return value === undefined || value === "" ? undefined : parse(value);
+}
+
+const asString = (value: string): string => value;
+
+function asNumber(value: string): number {
+  const number = Number(value);
+  if (Number.isNaN(number)) {
+    throw new Error(` + "`Expected a number, got ${value}`" + `);
+  }
+  return number;
+}
+
+const asBoolean = (value: string): boolean => value === "true" || value === "1";
+
+`)
+
+	b.WriteString("export const env = {\n")
+	for _, a := range accessors {
+		parse := "asString"
+		switch a.Type {
+		case accessorInt, accessorFloat:
+			parse = "asNumber"
+		case accessorBool:
+			parse = "asBoolean"
+		}
+		if a.Optional {
+			fmt.Fprintf(&b, "  %s: optional(%q, %s),\n", tsProperty(a.Key), a.Key, parse)
+		} else {
+			fmt.Fprintf(&b, "  %s: %s(required(%q)),\n", tsProperty(a.Key), parse, a.Key)
+		}
+	}
+	b.WriteString("} as const;\n")
+	return b.String()
+}
+
+// tsProperty returns key as a TypeScript property name, quoted if needed
+func tsProperty(key string) string {
+	if jsIdentifier.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+// goAccessors renders an Env struct with envconfig tags, unformatted
+func goAccessors(accessors []Accessor, pkg string) string {
+	if pkg == "" {
+		pkg = "config"
+	}
+	var b strings.Builder
+	b.WriteString("// " + accessorHeader + "\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("// Env holds the environment variables read by the code, load it with envconfig.Process(\"\", &env)\n")
+	b.WriteString("type Env struct {\n")
+	names := make(map[string]int)
+	for _, a := range accessors {
+		name := uniqueName(goFieldName(a.Key), names)
+		fieldType := map[string]string{accessorString: "string", accessorInt: "int", accessorFloat: "float64", accessorBool: "bool"}[a.Type]
+		tag := fmt.Sprintf("envconfig:%q", a.Key)
+		if !a.Optional {
+			tag += ` required:"true"`
+		}
+		if a.Description != "" {
+			fmt.Fprintf(&b, "\t// %s\n", a.Description)
+		}
+		fmt.Fprintf(&b, "\t%s %s `%s`\n", name, fieldType, tag)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// goInitialisms are name parts written in upper case in Go identifiers, e.g. DatabaseURL
+var goInitialisms = map[string]bool{
+	"API": true, "AWS": true, "CPU": true, "CSS": true, "DB": true, "DNS": true, "GCP": true, "HTML": true,
+	"HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "JWT": true, "OS": true, "SMTP": true,
+	"SQL": true, "SSH": true, "SSL": true, "TLS": true, "TTL": true, "UI": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// goFieldName converts a variable name to an exported Go field name, e.g. DATABASE_URL to DatabaseURL
+func goFieldName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		upper := strings.ToUpper(part)
+		switch {
+		case goInitialisms[upper]:
+			b.WriteString(upper)
+		case part == upper || part == strings.ToLower(part):
+			b.WriteString(upper[:1] + strings.ToLower(part[1:]))
+		default:
+			// Mixed case (apiKey) keeps its humps
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "Env" + name
+	}
+	return name
+}
+
+// pythonKeywords can't be used as field names, their fields are prefixed with env_ and get an alias
+var pythonKeywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true, "except": true, "false": true,
+	"finally": true, "for": true, "from": true, "global": true, "if": true, "import": true, "in": true,
+	"is": true, "lambda": true, "none": true, "nonlocal": true, "not": true, "or": true, "pass": true,
+	"raise": true, "return": true, "true": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+var (
+	// pythonIdentifier matches names that can be used as a Python field as they are
+	pythonIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	// pythonInvalid matches the characters that can't appear in a Python field name
+	pythonInvalid = regexp.MustCompile(`[^a-z0-9_]`)
+)
+
+// pythonAccessors renders a pydantic Settings class, whose fields match variables case-insensitively
+func pythonAccessors(accessors []Accessor) string {
+	type field struct{ name, annotation, value, description string }
+	var fields []field
+	needsOptional, needsField := false, false
+	names := make(map[string]int)
+	for _, a := range accessors {
+		fieldType := map[string]string{accessorString: "str", accessorInt: "int", accessorFloat: "float", accessorBool: "bool"}[a.Type]
+		name := strings.ToLower(a.Key)
+		alias := false
+		if !pythonIdentifier.MatchString(name) || pythonKeywords[name] {
+			name = "env_" + pythonInvalid.ReplaceAllString(name, "_")
+			alias = true
+		}
+		name = uniqueName(name, names)
+
+		f := field{name: name, annotation: fieldType, description: a.Description}
+		if a.Optional {
+			f.annotation = "Optional[" + fieldType + "]"
+			needsOptional = true
+		}
+		switch {
+		case alias && a.Optional:
+			f.value = fmt.Sprintf("Field(default=None, alias=%q)", a.Key)
+		case alias:
+			f.value = fmt.Sprintf("Field(alias=%q)", a.Key)
+		case a.Optional:
+			f.value = "None"
+		}
+		needsField = needsField || alias
+		fields = append(fields, f)
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + accessorHeader + "\n\n")
+	if needsOptional {
+		b.WriteString("from typing import Optional\n\n")
+	}
+	if needsField {
+		b.WriteString("from pydantic import Field\n")
+	}
+	b.WriteString("from pydantic_settings import BaseSettings\n\n\n")
+	b.WriteString("class Settings(BaseSettings):\n")
+	b.WriteString("    \"\"\"Environment variables read by the code, loaded with Settings().\"\"\"\n")
+	if len(fields) > 0 {
+		b.WriteString("\n")
+	}
+	for _, f := range fields {
+		if f.description != "" {
+			fmt.Fprintf(&b, "    # %s\n", f.description)
+		}
+		if f.value != "" {
+			fmt.Fprintf(&b, "    %s: %s = %s\n", f.name, f.annotation, f.value)
+		} else {
+			fmt.Fprintf(&b, "    %s: %s\n", f.name, f.annotation)
+		}
+	}
+	return b.String()
+}
+
+// uniqueName numbers names that were already used (DbURL, DbURL2), as different variables can map to
+// the same identifier
+func uniqueName(name string, used map[string]int) string {
+	used[name]++
+	if n := used[name]; n > 1 {
+		return fmt.Sprintf("%s%d", name, n)
+	}
+	return name
+}
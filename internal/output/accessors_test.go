@@ -0,0 +1,109 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/schema"
+)
+
+func accessorResult() analyzer.ScanResult {
+	return analyzer.ScanResult{
+		CodeKeys: []analyzer.EnvUsage{
+			{Key: "DATABASE_URL", File: "app.js", Line: 1},
+			{Key: "PORT", File: "app.js", Line: 2},
+			{Key: "LOG_LEVEL", File: "app.js", Line: 3, IsOptional: true},
+			{Key: "TEST_ONLY", File: "app.test.js", Line: 1, InTest: true},
+			{Key: "FEATURE_", File: "app.js", Line: 4, IsPartial: true},
+			{Key: "class", File: "app.js", Line: 5, IsOptional: true},
+			{Key: "WORKERS", File: "app.js", Line: 6},
+			{Key: "RELEASE", File: "app.js", Line: 7},
+		},
+		EnvKeys:  map[string]string{"PORT": "3000", "DEBUG": "false", "DATABASE_URL": "postgres://localhost/app", "RELEASE": "1.0"},
+		Required: []string{"DEBUG"},
+		Schema: &schema.Schema{Variables: map[string]schema.Variable{
+			"DATABASE_URL": {Description: "Primary\n database"},
+			"WORKERS":      {Type: schema.TypeInteger},
+			"RELEASE":      {Type: "string"},
+		}},
+	}
+}
+
+func TestBuildAccessors(t *testing.T) {
+	want := []Accessor{
+		{Key: "DATABASE_URL", Type: "string", Description: "Primary database"},
+		{Key: "DEBUG", Type: "bool"},
+		{Key: "LOG_LEVEL", Type: "string", Optional: true},
+		{Key: "PORT", Type: "int"},
+		{Key: "RELEASE", Type: "string"},
+		{Key: "WORKERS", Type: "int"},
+		{Key: "class", Type: "string", Optional: true},
+	}
+	if got := BuildAccessors(accessorResult()); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildAccessors() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteAccessors(t *testing.T) {
+	accessors := BuildAccessors(accessorResult())
+	tests := map[string][]string{
+		AccessorsTypeScript: {
+			"      /** Primary database */\n      DATABASE_URL: string;\n",
+			"      LOG_LEVEL?: string;\n",
+			"  PORT: asNumber(required(\"PORT\")),\n",
+			"  LOG_LEVEL: optional(\"LOG_LEVEL\", asString),\n",
+			"  WORKERS: asNumber(required(\"WORKERS\")),\n",
+			"  RELEASE: asString(required(\"RELEASE\")),\n",
+		},
+		AccessorsGo: {
+			"package settings\n",
+			"\t// Primary database\n\tDatabaseURL string `envconfig:\"DATABASE_URL\" required:\"true\"`\n",
+			"\tLogLevel    string `envconfig:\"LOG_LEVEL\"`\n",
+			"\tPort        int    `envconfig:\"PORT\" required:\"true\"`\n",
+			"\tWorkers     int    `envconfig:\"WORKERS\" required:\"true\"`\n",
+		},
+		AccessorsPython: {
+			"from typing import Optional\n",
+			"    # Primary database\n    database_url: str\n",
+			"    debug: bool\n",
+			"    workers: int\n",
+			"    log_level: Optional[str] = None\n",
+			"    env_class: Optional[str] = Field(default=None, alias=\"class\")\n",
+		},
+	}
+	for target, lines := range tests {
+		var b strings.Builder
+		if err := WriteAccessors(&b, accessors, target, "settings"); err != nil {
+			t.Fatalf("WriteAccessors(%s) failed: %v", target, err)
+		}
+		if !strings.Contains(b.String(), accessorHeader) {
+			t.Errorf("Expected the generated header in %s output", target)
+		}
+		for _, line := range lines {
+			if !strings.Contains(b.String(), line) {
+				t.Errorf("Expected %q in %s output, got:\n%s", line, target, b.String())
+			}
+		}
+	}
+
+	if err := WriteAccessors(&strings.Builder{}, accessors, "ruby", ""); err == nil {
+		t.Error("Expected an error for an unknown target")
+	}
+}
+
+func TestGoFieldName(t *testing.T) {
+	tests := map[string]string{
+		"DATABASE_URL":  "DatabaseURL",
+		"apiKey":        "ApiKey",
+		"AWS_REGION":    "AWSRegion",
+		"2FA_SECRET":    "Env2faSecret",
+		"STRIPE_KEY_ID": "StripeKeyID",
+	}
+	for key, want := range tests {
+		if got := goFieldName(key); got != want {
+			t.Errorf("goFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// BadgeLabel is the label of the env-health badge
+const BadgeLabel = "env"
+
+// JSONBadge is a shields.io endpoint badge (https://shields.io/badges/endpoint-badge)
+type JSONBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"` // Finding counts by category, e.g. "3 missing, 1 unused", or "ok"
+	Color         string `json:"color"`   // By the highest severity: red (error), yellow (warning), blue (info), brightgreen (none)
+}
+
+// BadgeReporter renders a shields.io endpoint badge summarizing the findings
+type BadgeReporter struct{}
+
+// Report writes the badge JSON
+func (BadgeReporter) Report(w io.Writer, result analyzer.ScanResult, opts Options) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(buildBadge(result, opts))
+}
+
+// Summary counts the reported findings by category in the order of the text report, e.g.
+// "3 missing, 1 unused", or returns "" when nothing is reported
+func Summary(result analyzer.ScanResult, opts Options) string {
+	var parts []string
+	for _, c := range findingCounts(result, opts) {
+		parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findingLabels are the finding categories counted by Summary, in the order of the text report
+var findingLabels = []string{
+	"missing", "dynamic", "unused", "undocumented", "stale", "unread", "undeclared", "frontend", "style",
+	"deprecated", "wrong type", "placeholder", "unresolved", "forbidden",
+}
+
+// findingCount is the number of reported findings of a category
+type findingCount struct {
+	label string
+	n     int
+}
+
+// findingCounts counts the reported findings by category in the order of the text report, leaving out
+// the categories without findings
+func findingCounts(result analyzer.ScanResult, opts Options) []findingCount {
+	var counts []findingCount
+	count := func(n int, label string) {
+		if n > 0 {
+			counts = append(counts, findingCount{label, n})
+		}
+	}
+	count(len(result.Missing), "missing")
+	if opts.Dynamic {
+		count(len(result.PartialMatches), "dynamic")
+	}
+	if !opts.SkipUnused {
+		count(len(result.Unused), "unused")
+	}
+	if drift := result.ExampleDrift; drift != nil {
+		count(len(drift.Undocumented), "undocumented")
+		count(len(drift.Stale), "stale")
+	}
+	if drift := result.SchemaDrift; drift != nil {
+		count(len(drift.Unread), "unread")
+		count(len(drift.Undeclared), "undeclared")
+	}
+	if frontend := result.Frontend; frontend != nil {
+		count(len(frontend.Unprefixed)+len(frontend.Exposed), "frontend")
+	}
+	count(len(result.Style), "style")
+	count(len(result.Deprecated), "deprecated")
+	count(len(result.TypeMismatches), "wrong type")
+	count(len(result.Placeholders), "placeholder")
+	count(len(result.UnresolvedRefs), "unresolved")
+	count(len(result.Forbidden), "forbidden")
+	return counts
+}
+
+// buildBadge summarizes the findings in a badge colored by their highest severity
+func buildBadge(result analyzer.ScanResult, opts Options) JSONBadge {
+	badge := JSONBadge{SchemaVersion: 1, Label: BadgeLabel, Message: "ok", Color: "brightgreen"}
+	if summary := Summary(result, opts); summary != "" {
+		badge.Message = summary
+	}
+	switch HighestSeverity(result, opts.SkipUnused, opts.Dynamic) {
+	case config.SeverityError:
+		badge.Color = "red"
+	case config.SeverityWarning:
+		badge.Color = "yellow"
+	case config.SeverityInfo:
+		badge.Color = "blue"
+	}
+	return badge
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// JSONComparison classifies the findings against a previous report (with --compare-to), keys by category
+type JSONComparison struct {
+	Baseline  string            `json:"baseline"`
+	New       analyzer.Findings `json:"new"`
+	Fixed     analyzer.Findings `json:"fixed"`
+	Unchanged analyzer.Findings `json:"unchanged"`
+}
+
+// ReportFindings returns the keys of the findings of a JSON report by category, see analyzer.Findings
+func ReportFindings(report JSONOutput) analyzer.Findings {
+	findings := make(analyzer.Findings)
+	for _, vars := range [][]MissingVar{report.Missing, report.OptionalMissing, report.TestMissing} {
+		findings.Add(analyzer.FindingMissing, varKeys(vars)...)
+	}
+	findings.Add(analyzer.FindingDynamic, varKeys(report.PartialMatches)...)
+	findings.Add(analyzer.FindingUnused, report.Unused...)
+	if drift := report.ExampleDrift; drift != nil {
+		findings.Add(analyzer.FindingExample, varKeys(drift.Undocumented)...)
+		findings.Add(analyzer.FindingExample, varKeys(drift.Stale)...)
+	}
+	if drift := report.SchemaDrift; drift != nil {
+		findings.Add(analyzer.FindingSchema, varKeys(drift.Unread)...)
+		findings.Add(analyzer.FindingSchema, varKeys(drift.Undeclared)...)
+	}
+	if frontend := report.Frontend; frontend != nil {
+		findings.Add(analyzer.FindingFrontend, varKeys(frontend.Unprefixed)...)
+		findings.Add(analyzer.FindingFrontend, varKeys(frontend.Exposed)...)
+	}
+	for _, violation := range report.Style {
+		findings.Add(analyzer.FindingStyle, violation.Key)
+	}
+	for _, deprecated := range report.Deprecated {
+		findings.Add(analyzer.FindingDeprecated, deprecated.Key)
+	}
+	for _, mismatch := range report.TypeMismatches {
+		findings.Add(analyzer.FindingType, mismatch.Key)
+	}
+	for _, placeholder := range report.Placeholders {
+		findings.Add(analyzer.FindingPlaceholder, placeholder.Key)
+	}
+	for _, ref := range report.UnresolvedRefs {
+		findings.Add(analyzer.FindingReference, ref.Key)
+	}
+	for _, forbidden := range report.Forbidden {
+		findings.Add(analyzer.FindingForbidden, forbidden.Key)
+	}
+	return findings
+}
+
+// varKeys returns the keys of vars
+func varKeys(vars []MissingVar) []string {
+	keys := make([]string, 0, len(vars))
+	for _, v := range vars {
+		keys = append(keys, v.Key)
+	}
+	return keys
+}
+
+// buildJSONComparison converts a comparison, with empty objects rather than nulls
+func buildJSONComparison(comparison *analyzer.Comparison) *JSONComparison {
+	if comparison == nil {
+		return nil
+	}
+	orEmpty := func(findings analyzer.Findings) analyzer.Findings {
+		if findings == nil {
+			return analyzer.Findings{}
+		}
+		return findings
+	}
+	return &JSONComparison{
+		Baseline:  comparison.Baseline,
+		New:       orEmpty(comparison.New),
+		Fixed:     orEmpty(comparison.Fixed),
+		Unchanged: orEmpty(comparison.Unchanged),
+	}
+}
+
+// formatComparison prints the counts of new, fixed and unchanged findings, and lists the new and fixed ones
+func formatComparison(w io.Writer, comparison *analyzer.Comparison, getColor func(string) string) {
+	fmt.Fprintf(w, "%sCompared to %s:%s %d new, %d fixed, %d unchanged\n\n", getColor(colorBold), comparison.Baseline, getColor(colorReset),
+		comparison.New.Count(), comparison.Fixed.Count(), comparison.Unchanged.Count())
+	for _, group := range []struct {
+		title    string
+		color    string
+		findings analyzer.Findings
+		format   string
+	}{
+		{"New", colorRed, comparison.New, "%s"},
+		{"Fixed", colorGreen, comparison.Fixed, "was %s"},
+	} {
+		if group.findings.Count() == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %s%s:%s\n", getColor(colorBold), group.title, getColor(colorReset))
+		for _, category := range analyzer.FindingCategories {
+			for _, key := range group.findings[category] {
+				fmt.Fprintf(w, "    %s%s%s %s(%s)%s\n", getColor(group.color), key, getColor(colorReset), getColor(colorGray), fmt.Sprintf(group.format, category), getColor(colorReset))
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
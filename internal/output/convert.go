@@ -0,0 +1,167 @@
+package output
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Conversion targets of envgrd convert
+const (
+	ConvertConfigMap = "k8s-configmap"
+	ConvertCompose   = "compose"
+	ConvertHelm      = "helm"
+	ConvertEnv       = "env"
+	ConvertShell     = "shell"
+)
+
+// EnvVar is a variable of an env file being converted
+type EnvVar struct {
+	Key     string
+	Value   string
+	Comment string // Comment lines above the definition, without their # markers
+}
+
+// WriteConversion renders variables as deployment configuration, with secret-looking variables
+// (see looksSecret) kept apart:
+//   - k8s-configmap: a ConfigMap named name, and a Secret for the secrets
+//   - compose: the environment of service name, secrets interpolated from the shell (KEY: ${KEY})
+//   - helm: env and secretEnv values
+//   - env, shell: a .env file or a script of export statements, secrets included
+//
+// Comments of the variables are kept above them
+func WriteConversion(w io.Writer, vars []EnvVar, target string, name string) error {
+	switch target {
+	case ConvertEnv, ConvertShell:
+		var b strings.Builder
+		for _, v := range vars {
+			if v.Comment != "" {
+				b.WriteString("# " + strings.ReplaceAll(v.Comment, "\n", "\n# ") + "\n")
+			}
+			if target == ConvertShell {
+				b.WriteString("export " + v.Key + "=" + shellQuote(v.Value) + "\n")
+			} else {
+				b.WriteString(v.Key + "=" + dotenvQuote(v.Value) + "\n")
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	var plain, secrets []EnvVar
+	for _, v := range vars {
+		if looksSecret(v.Key, v.Value) {
+			secrets = append(secrets, v)
+		} else {
+			plain = append(plain, v)
+		}
+	}
+
+	var documents []*yaml.Node
+	switch target {
+	case ConvertConfigMap:
+		if len(plain) > 0 || len(secrets) == 0 {
+			documents = append(documents, manifest("ConfigMap", name, "data", plain, nil))
+		}
+		if len(secrets) > 0 {
+			secretName := strings.TrimSuffix(name, "-config") + "-secret"
+			encode := func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) }
+			documents = append(documents, manifest("Secret", secretName, "data", secrets, encode))
+		}
+	case ConvertCompose:
+		environment := mapping(plain, nil)
+		for _, v := range secrets {
+			// Secrets stay out of the file, compose reads them from the shell or an untracked .env
+			key := str(v.Key)
+			key.HeadComment = v.Comment
+			environment.Content = append(environment.Content, key, str("${"+v.Key+"}"))
+		}
+		documents = append(documents, mapping(nil, nil,
+			str("services"), mapping(nil, nil, str(name), mapping(nil, nil, str("environment"), environment))))
+	case ConvertHelm:
+		values := mapping(nil, nil, str("env"), mapping(plain, nil))
+		if len(secrets) > 0 {
+			values.Content = append(values.Content, str("secretEnv"), mapping(secrets, nil))
+		}
+		documents = append(documents, values)
+	default:
+		return fmt.Errorf("unknown convert target %q (supported: k8s-configmap, compose, helm, env, shell)", target)
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// manifest builds a Kubernetes object of the given kind holding vars under dataKey
+func manifest(kind string, name string, dataKey string, vars []EnvVar, encode func(string) string) *yaml.Node {
+	node := mapping(nil, nil,
+		str("apiVersion"), str("v1"),
+		str("kind"), str(kind),
+		str("metadata"), mapping(nil, nil, str("name"), str(name)),
+	)
+	if kind == "Secret" {
+		node.Content = append(node.Content, str("type"), str("Opaque"))
+	}
+	node.Content = append(node.Content, str(dataKey), mapping(vars, encode))
+	return node
+}
+
+// mapping builds a YAML mapping of vars (values passed through encode when set) followed by content
+func mapping(vars []EnvVar, encode func(string) string, content ...*yaml.Node) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, v := range vars {
+		value := v.Value
+		if encode != nil {
+			value = encode(value)
+		}
+		key := str(v.Key)
+		key.HeadComment = v.Comment
+		node.Content = append(node.Content, key, str(value))
+	}
+	node.Content = append(node.Content, content...)
+	return node
+}
+
+// str builds a YAML string scalar, quoted when it would otherwise read as another type (e.g. "3000")
+func str(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// plainValue matches values that need no quotes in a .env file or a shell script
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@,+%=-]+$`)
+
+// dotenvQuote quotes a .env value when needed: single quotes keep it literal, double quotes with
+// escapes are used for values with single quotes or newlines
+func dotenvQuote(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// shellQuote quotes a value for a POSIX shell when needed, in single quotes
+func shellQuote(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteConversion(t *testing.T) {
+	vars := []EnvVar{
+		{Key: "PORT", Value: "3000"},
+		{Key: "LOG_LEVEL", Value: "info"},
+		{Key: "DB_PASSWORD", Value: "hunter2"},
+	}
+	tests := map[string]string{
+		ConvertConfigMap: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-config
+data:
+  PORT: "3000"
+  LOG_LEVEL: info
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: myapp-secret
+type: Opaque
+data:
+  DB_PASSWORD: aHVudGVyMg==
+`,
+		ConvertCompose: `services:
+  myapp-config:
+    environment:
+      PORT: "3000"
+      LOG_LEVEL: info
+      DB_PASSWORD: ${DB_PASSWORD}
+`,
+		ConvertHelm: `env:
+  PORT: "3000"
+  LOG_LEVEL: info
+secretEnv:
+  DB_PASSWORD: hunter2
+`,
+	}
+	for target, want := range tests {
+		var b strings.Builder
+		if err := WriteConversion(&b, vars, target, "myapp-config"); err != nil {
+			t.Fatalf("WriteConversion(%s) failed: %v", target, err)
+		}
+		if b.String() != want {
+			t.Errorf("WriteConversion(%s) =\n%s\nwant\n%s", target, b.String(), want)
+		}
+	}
+
+	if err := WriteConversion(&strings.Builder{}, vars, "terraform", ""); err == nil {
+		t.Error("Expected an error for an unknown target")
+	}
+}
+
+func TestWriteConversion_NoSecrets(t *testing.T) {
+	var b strings.Builder
+	if err := WriteConversion(&b, []EnvVar{{Key: "PORT", Value: "3000"}}, ConvertConfigMap, "app"); err != nil {
+		t.Fatalf("WriteConversion failed: %v", err)
+	}
+	if strings.Contains(b.String(), "kind: Secret") || !strings.Contains(b.String(), "kind: ConfigMap") {
+		t.Errorf("Expected only a ConfigMap, got:\n%s", b.String())
+	}
+}
+
+func TestWriteConversion_EnvAndShell(t *testing.T) {
+	vars := []EnvVar{
+		{Key: "PORT", Value: "3000", Comment: "HTTP port\nof the API"},
+		{Key: "GREETING", Value: "hello world"},
+		{Key: "QUOTE", Value: "it's"},
+		{Key: "EMPTY", Value: ""},
+	}
+
+	tests := map[string]string{
+		ConvertEnv: `# HTTP port
+# of the API
+PORT=3000
+GREETING='hello world'
+QUOTE="it's"
+EMPTY=''
+`,
+		ConvertShell: `# HTTP port
+# of the API
+export PORT=3000
+export GREETING='hello world'
+export QUOTE='it'\''s'
+export EMPTY=''
+`,
+		ConvertHelm: `env:
+  # HTTP port
+  # of the API
+  PORT: "3000"
+  GREETING: hello world
+  QUOTE: it's
+  EMPTY: ""
+`,
+	}
+	for target, want := range tests {
+		var b strings.Builder
+		if err := WriteConversion(&b, vars, target, "app"); err != nil {
+			t.Fatalf("WriteConversion(%s) failed: %v", target, err)
+		}
+		if b.String() != want {
+			t.Errorf("WriteConversion(%s) =\n%s\nwant\n%s", target, b.String(), want)
+		}
+	}
+}
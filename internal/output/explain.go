@@ -0,0 +1,118 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// JSONExplanation is what a scan knows about one variable, see analyzer.Explanation
+type JSONExplanation struct {
+	Key         string           `json:"key"`
+	Declared    bool             `json:"declared"` // Declared in the schema file or in env file comments
+	Description string           `json:"description,omitempty"`
+	Type        string           `json:"type,omitempty"`
+	Format      string           `json:"format,omitempty"`
+	Enum        []string         `json:"enum,omitempty"`
+	Required    bool             `json:"required"`
+	Definitions []JSONDefinition `json:"definitions"`
+	Usages      []string         `json:"usages"`
+	Findings    []string         `json:"findings"` // Finding categories reporting the variable
+}
+
+// WriteExplanation writes what a scan knows about a variable, as text or as JSON, with values shown
+// through redactor (DefaultRedactor when nil)
+func WriteExplanation(w io.Writer, e analyzer.Explanation, jsonOutput bool, redactor Redactor) error {
+	opts := Options{Redactor: redactor}
+	report := JSONExplanation{
+		Key:         e.Key,
+		Definitions: []JSONDefinition{},
+		Usages:      usageLocations(e.Usages),
+		Findings:    append([]string{}, e.Findings...),
+	}
+	if v := e.Declaration; v != nil {
+		report.Declared = true
+		report.Description, report.Type, report.Format, report.Enum, report.Required = v.Description, v.Type, v.Format, v.Enum, v.Required
+	}
+	effective := len(e.Definitions) - 1
+	for i := len(e.Definitions) - 1; i >= 0; i-- {
+		if e.Definitions[i].Environment == "" {
+			effective = i
+			break
+		}
+	}
+	for i, def := range e.Definitions {
+		report.Definitions = append(report.Definitions, JSONDefinition{
+			File:        def.File,
+			Line:        def.Line,
+			Environment: def.Environment,
+			Value:       opts.redact(e.Key, def.Value),
+			Effective:   i == effective,
+		})
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Fprintln(w, report.Key)
+	if report.Description != "" {
+		fmt.Fprintf(w, "  %s\n", report.Description)
+	}
+	fmt.Fprintln(w)
+	if report.Declared {
+		var facts []string
+		if report.Type != "" {
+			facts = append(facts, "type "+report.Type)
+		}
+		if report.Format != "" {
+			facts = append(facts, "format "+report.Format)
+		}
+		if len(report.Enum) > 0 {
+			facts = append(facts, "one of "+strings.Join(report.Enum, ", "))
+		}
+		if report.Required {
+			facts = append(facts, "required")
+		} else {
+			facts = append(facts, "optional")
+		}
+		fmt.Fprintf(w, "Declared: %s\n", strings.Join(facts, ", "))
+	} else {
+		fmt.Fprintln(w, "Declared: no")
+	}
+
+	fmt.Fprintln(w, "Defined in:")
+	if len(report.Definitions) == 0 {
+		fmt.Fprintln(w, "  (nowhere)")
+	}
+	for i, def := range report.Definitions {
+		location := definitionLocations([]analyzer.Definition{e.Definitions[i]})[0]
+		if def.Value != "" {
+			location += " = " + def.Value
+		}
+		if def.Effective && len(report.Definitions) > 1 {
+			location += " (effective)"
+		}
+		fmt.Fprintf(w, "  %s\n", location)
+	}
+
+	fmt.Fprintln(w, "Read in:")
+	if len(report.Usages) == 0 {
+		fmt.Fprintln(w, "  (nowhere)")
+	}
+	for _, location := range report.Usages {
+		fmt.Fprintf(w, "  %s\n", location)
+	}
+
+	findings := "none"
+	if len(report.Findings) > 0 {
+		findings = strings.Join(report.Findings, ", ")
+	}
+	_, err := fmt.Fprintf(w, "Findings: %s\n", findings)
+	return err
+}
@@ -3,11 +3,15 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/blame"
+	"github.com/jenian/envgrd/internal/config"
 	"golang.org/x/term"
 )
 
@@ -29,37 +33,240 @@ const (
 
 // initColorSupport initializes color support for the terminal
 func initColorSupport() bool {
+	enabled, _ := ColorSupport()
+	return enabled
+}
+
+// ColorSupport reports whether human-readable reports on stdout are colored, and why
+func ColorSupport() (bool, string) {
+	// NO_COLOR disables colors whatever its value (https://no-color.org)
+	if os.Getenv("NO_COLOR") != "" {
+		return false, "NO_COLOR is set"
+	}
+
 	// Check if stdout is a terminal
 	if !term.IsTerminal(int(os.Stdout.Fd())) {
-		return false
+		return false, "stdout is not a terminal"
 	}
 
 	// On Windows, enable ANSI escape sequences (handled in formatter_windows.go)
 	// On Unix-like systems, colors are supported if it's a terminal
-	return enableANSI()
-}
-
-// getColor returns the color code if colors are enabled, empty string otherwise
-func getColor(code string) string {
-	if colorEnabled {
-		return code
+	if !enableANSI() {
+		return false, "the console doesn't support ANSI escape sequences"
 	}
-	return ""
+	return true, "stdout is a terminal"
 }
 
 // JSONOutput represents the JSON output format
 type JSONOutput struct {
-	Missing            []MissingVar `json:"missing"`
-	PartialMatches     []MissingVar `json:"partial_matches"`
-	Unused             []string     `json:"unused"`
-	IgnoredMissing     int          `json:"ignored_missing"`
-	IgnoredFromFolders int          `json:"ignored_from_folders"`
+	Missing            []MissingVar               `json:"missing"`
+	PartialMatches     []MissingVar               `json:"partial_matches"`
+	DynamicMatches     map[string][]string        `json:"dynamic_matches"` // Dynamic patterns resolved to the defined variables they can match
+	OptionalMissing    []MissingVar               `json:"optional_missing"`
+	TestMissing        []MissingVar               `json:"test_missing"`
+	Unused             []string                   `json:"unused"`
+	Defined            []string                   `json:"defined"` // Variables defined in env files, which envgrd merge needs to combine unused variables
+	IgnoredMissing     int                        `json:"ignored_missing"`
+	IgnoredUnused      int                        `json:"ignored_unused"`
+	IgnoredFromFolders int                        `json:"ignored_from_folders"`
+	IgnoredSystem      int                        `json:"ignored_system"`
+	IgnoredTests       int                        `json:"ignored_tests"`
+	IgnoredDynamic     int                        `json:"ignored_dynamic"`
+	UnusedSeverities   map[string]config.Severity `json:"unused_severities"`
+	UnusedLocations    map[string]JSONLocation    `json:"unused_locations"`
+	UnusedOwners       map[string][]string        `json:"unused_owners,omitempty"` // CODEOWNERS owners of the env file defining each unused variable
+	UnusedBlame        map[string]JSONBlame       `json:"unused_blame,omitempty"`  // Only with --blame
+	HighestSeverity    config.Severity            `json:"highest_severity,omitempty"`
+	Stats              *JSONStats                 `json:"stats,omitempty"`
+	Fixed              *JSONFixed                 `json:"fixed,omitempty"`
+	Comparison         *JSONComparison            `json:"comparison,omitempty"` // Only with --compare-to
+	ParseErrors        []JSONParseError           `json:"parse_errors"`
+	Conflicts          []JSONConflict             `json:"conflicts"`
+	CaseMismatches     []JSONCaseMismatch         `json:"case_mismatches"` // Only found with --case-insensitive
+	ExampleDrift       *JSONExampleDrift          `json:"example_drift,omitempty"`
+	SchemaDrift        *JSONSchemaDrift           `json:"schema_drift,omitempty"`
+	Frontend           *JSONFrontend              `json:"frontend,omitempty"`
+	Style              []JSONStyleViolation       `json:"style"`
+	Deprecated         []JSONDeprecated           `json:"deprecated"`
+	TypeMismatches     []JSONTypeMismatch         `json:"type_mismatches"`
+	Placeholders       []JSONPlaceholder          `json:"placeholders"`
+	UnresolvedRefs     []JSONUnresolvedReference  `json:"unresolved_references"`
+	Forbidden          []JSONForbidden            `json:"forbidden"`
+	ByOwner            map[string]JSONOwner       `json:"by_owner,omitempty"`    // Only with --group-by owner
+	Policy             *JSONPolicy                `json:"policy,omitempty"`      // Only with --policy
+	Remediation        map[string]string          `json:"remediation,omitempty"` // Configured remediation hints of the reported variables
+}
+
+// JSONPolicy identifies the policy a scan enforced
+type JSONPolicy struct {
+	Source string `json:"source"`
+	SHA256 string `json:"sha256"`
+}
+
+// JSONBlame is the last change of an unused variable's definition
+type JSONBlame struct {
+	Commit  string    `json:"commit"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	AgeDays int       `json:"age_days"`
+}
+
+// JSONOwner lists the findings attributed to one CODEOWNERS owner
+type JSONOwner struct {
+	Missing []string `json:"missing"`
+	Unused  []string `json:"unused"`
+	Dynamic []string `json:"dynamic"`
+}
+
+// JSONDeprecated is a deprecated variable that is still used or defined
+type JSONDeprecated struct {
+	Key         string          `json:"key"`
+	Severity    config.Severity `json:"severity"`
+	Hint        string          `json:"hint"`
+	Usages      []string        `json:"usages"`                 // Code usages to migrate
+	Definitions []string        `json:"definitions"`            // Env file definitions to remove
+	Truncated   bool            `json:"truncated,omitempty"`    // Usages were cut down to --max-locations
+	TotalUsages int             `json:"total_usages,omitempty"` // Number of usages before truncation, only set when truncated
+}
+
+// JSONForbidden is a forbidden variable that is used or defined
+type JSONForbidden struct {
+	Key         string          `json:"key"`
+	Severity    config.Severity `json:"severity"`
+	Message     string          `json:"message"`                // Configured remediation message
+	Usages      []string        `json:"usages"`                 // Code usages to remove
+	Definitions []string        `json:"definitions"`            // Env file definitions to remove
+	Truncated   bool            `json:"truncated,omitempty"`    // Usages were cut down to --max-locations
+	TotalUsages int             `json:"total_usages,omitempty"` // Number of usages before truncation, only set when truncated
+}
+
+// JSONTypeMismatch is a variable whose env file values code can't parse as the type it expects
+type JSONTypeMismatch struct {
+	Key         string             `json:"key"`
+	Type        string             `json:"type"` // Type code parses the value as, e.g. integer
+	Severity    config.Severity    `json:"severity"`
+	Usages      []string           `json:"usages"`                 // Code usages parsing the value
+	Values      []JSONInvalidValue `json:"values"`                 // Env file values that don't parse
+	Truncated   bool               `json:"truncated,omitempty"`    // Usages were cut down to --max-locations
+	TotalUsages int                `json:"total_usages,omitempty"` // Number of usages before truncation, only set when truncated
+}
+
+// JSONInvalidValue is an env file value of the wrong type
+type JSONInvalidValue struct {
+	File  string `json:"file"`
+	Line  int    `json:"line,omitempty"`
+	Value string `json:"value,omitempty"` // Omitted with --show-values never
+}
+
+// JSONPlaceholder is a variable read in code with an empty or placeholder value
+type JSONPlaceholder struct {
+	Key         string          `json:"key"`
+	Severity    config.Severity `json:"severity"`
+	File        string          `json:"file"`
+	Line        int             `json:"line,omitempty"`
+	Value       string          `json:"value,omitempty"`        // Omitted for empty values and with --show-values never
+	Empty       bool            `json:"empty"`                  // The value is empty rather than a placeholder
+	Usages      []string        `json:"usages"`                 // Code usages reading the value
+	Truncated   bool            `json:"truncated,omitempty"`    // Usages were cut down to --max-locations
+	TotalUsages int             `json:"total_usages,omitempty"` // Number of usages before truncation, only set when truncated
+}
+
+// JSONUnresolvedReference is an env file value with ${VAR} references to undefined variables
+type JSONUnresolvedReference struct {
+	Key        string          `json:"key"`
+	Severity   config.Severity `json:"severity"`
+	File       string          `json:"file"`
+	Line       int             `json:"line,omitempty"`
+	Value      string          `json:"value,omitempty"` // Value as written, omitted with --show-values never
+	References []string        `json:"references"`      // Referenced variables that can't be resolved
+}
+
+// JSONStyleViolation is a variable name that breaks naming-convention rules
+type JSONStyleViolation struct {
+	Key            string          `json:"key"`
+	Severity       config.Severity `json:"severity"`
+	Problems       []string        `json:"problems"`
+	Locations      []string        `json:"locations"`                 // Code usages and env file definitions
+	Truncated      bool            `json:"truncated,omitempty"`       // Locations were cut down to --max-locations
+	TotalLocations int             `json:"total_locations,omitempty"` // Number of locations before truncation, only set when truncated
+}
+
+// JSONFrontend lists the public-prefix findings of client-side code, only set when a frontend framework is used
+type JSONFrontend struct {
+	Framework  string       `json:"framework"`
+	Prefix     string       `json:"prefix"`
+	Unprefixed []MissingVar `json:"unprefixed"` // Locations are client-side usages
+	Exposed    []MissingVar `json:"exposed"`    // Locations are code usages and env file definitions
+}
+
+// JSONExampleDrift lists the differences between example env files and code, only set when an example file was loaded
+type JSONExampleDrift struct {
+	Examples     []string     `json:"examples"`
+	Undocumented []MissingVar `json:"undocumented"` // Locations are code usages and real env file definitions
+	Stale        []MissingVar `json:"stale"`        // Locations are the example file definitions
+}
+
+// JSONSchemaDrift lists the differences between the variable schema and code, only set when a schema was loaded
+type JSONSchemaDrift struct {
+	Schema     string       `json:"schema"`
+	Unread     []MissingVar `json:"unread"`     // No locations, the schema file has no line numbers
+	Undeclared []MissingVar `json:"undeclared"` // Locations are code usages
+}
+
+// JSONConflict is a variable defined with different values in several env files
+type JSONConflict struct {
+	Key         string           `json:"key"`
+	Definitions []JSONDefinition `json:"definitions"`
+}
+
+// JSONCaseMismatch is a variable used in code with a different case than its definition
+type JSONCaseMismatch struct {
+	Key         string   `json:"key"`     // Name used in code
+	Defined     string   `json:"defined"` // Name in the env files
+	Usages      []string `json:"usages"`
+	Definitions []string `json:"definitions"`
+}
+
+// JSONDefinition is one definition of a conflicting variable, with its value redacted
+type JSONDefinition struct {
+	File        string `json:"file"`
+	Line        int    `json:"line,omitempty"`
+	Environment string `json:"environment,omitempty"` // Environment block of the definition, e.g. production
+	Value       string `json:"value,omitempty"`       // Omitted with --show-values never
+	Effective   bool   `json:"effective"`             // True for the definition that takes effect
+}
+
+// JSONLocation is where an environment variable is defined
+type JSONLocation struct {
+	File string `json:"file"`
+	Line int    `json:"line,omitempty"` // Omitted when the line is unknown
+}
+
+// JSONParseError is a file that could not be analyzed
+type JSONParseError struct {
+	File     string `json:"file"`
+	Language string `json:"language"`
+	Error    string `json:"error"`
+}
+
+// JSONFixed lists findings of the previous run that are gone (with --since-last-run)
+type JSONFixed struct {
+	Missing []string `json:"missing"`
+	Unused  []string `json:"unused"`
+	Dynamic []string `json:"dynamic"`
 }
 
 // MissingVar represents a missing environment variable with its locations
 type MissingVar struct {
-	Key       string   `json:"key"`
-	Locations []string `json:"locations"`
+	Key        string          `json:"key"`
+	Severity   config.Severity `json:"severity"`
+	Required   bool            `json:"required,omitempty"`
+	Confidence string          `json:"confidence,omitempty"` // Dynamic patterns only: high, medium or low
+	Owners     []string        `json:"owners,omitempty"`     // CODEOWNERS owners of the files using the variable
+	Locations  []string        `json:"locations"`
+	// Truncated is set when the locations were cut down to --max-locations, TotalLocations counts all of them
+	Truncated      bool `json:"truncated,omitempty"`
+	TotalLocations int  `json:"total_locations,omitempty"`
 }
 
 // Format formats the scan results according to the specified format
@@ -69,21 +276,228 @@ func Format(result analyzer.ScanResult, jsonOutput bool, silent bool, skipUnused
 		return nil
 	}
 
+	var reporter Reporter = NewTextReporter()
 	if jsonOutput {
-		return formatJSON(result, skipUnused, dynamic)
+		reporter = JSONReporter{}
 	}
 
-	return formatHumanReadable(result, skipUnused, dynamic)
+	return reporter.Report(os.Stdout, result, Options{SkipUnused: skipUnused, Dynamic: dynamic})
 }
 
-// formatJSON outputs results in JSON format
-func formatJSON(result analyzer.ScanResult, skipUnused bool, dynamic bool) error {
+// buildJSONOutput converts results to the JSON output structure
+func buildJSONOutput(result analyzer.ScanResult, opts Options) JSONOutput {
+	skipUnused, dynamic, groupBy := opts.SkipUnused, opts.Dynamic, opts.GroupBy
 	output := JSONOutput{
 		Missing:            []MissingVar{},
 		PartialMatches:     []MissingVar{},
+		DynamicMatches:     map[string][]string{},
+		OptionalMissing:    []MissingVar{},
+		TestMissing:        []MissingVar{},
 		Unused:             []string{},
+		Defined:            make([]string, 0, len(result.EnvKeys)),
 		IgnoredMissing:     result.IgnoredMissing,
+		IgnoredUnused:      result.IgnoredUnused,
 		IgnoredFromFolders: result.IgnoredFromFolders,
+		IgnoredSystem:      result.IgnoredSystem,
+		IgnoredTests:       result.IgnoredTests,
+		IgnoredDynamic:     result.IgnoredDynamic,
+		UnusedSeverities:   map[string]config.Severity{},
+		UnusedLocations:    map[string]JSONLocation{},
+		UnusedOwners:       map[string][]string{},
+		HighestSeverity:    HighestSeverity(result, skipUnused, dynamic),
+		Stats:              buildJSONStats(result.Stats),
+		ParseErrors:        []JSONParseError{},
+		Conflicts:          []JSONConflict{},
+		CaseMismatches:     []JSONCaseMismatch{},
+		Style:              []JSONStyleViolation{},
+		Deprecated:         []JSONDeprecated{},
+		TypeMismatches:     []JSONTypeMismatch{},
+		Placeholders:       []JSONPlaceholder{},
+		UnresolvedRefs:     []JSONUnresolvedReference{},
+		Forbidden:          []JSONForbidden{},
+	}
+
+	for key := range result.EnvKeys {
+		output.Defined = append(output.Defined, key)
+	}
+	sort.Strings(output.Defined)
+
+	for _, deprecated := range result.Deprecated {
+		output.Deprecated = append(output.Deprecated, JSONDeprecated{
+			Key:         deprecated.Key,
+			Severity:    deprecated.Severity,
+			Hint:        deprecated.Hint,
+			Usages:      usageLocations(deprecated.Usages),
+			Definitions: definitionLocations(deprecated.Definitions),
+		})
+	}
+
+	for _, mismatch := range result.TypeMismatches {
+		jsonMismatch := JSONTypeMismatch{
+			Key:      mismatch.Key,
+			Type:     mismatch.Type,
+			Severity: mismatch.Severity,
+			Usages:   usageLocations(mismatch.Usages),
+			Values:   []JSONInvalidValue{},
+		}
+		for _, def := range mismatch.Definitions {
+			jsonMismatch.Values = append(jsonMismatch.Values, JSONInvalidValue{File: def.File, Line: def.Line, Value: opts.redact(mismatch.Key, def.Value)})
+		}
+		output.TypeMismatches = append(output.TypeMismatches, jsonMismatch)
+	}
+
+	for _, placeholder := range result.Placeholders {
+		output.Placeholders = append(output.Placeholders, JSONPlaceholder{
+			Key:      placeholder.Key,
+			Severity: placeholder.Severity,
+			File:     placeholder.Definition.File,
+			Line:     placeholder.Definition.Line,
+			Value:    opts.redact(placeholder.Key, placeholder.Definition.Value),
+			Empty:    placeholder.Empty,
+			Usages:   usageLocations(placeholder.Usages),
+		})
+	}
+
+	for _, ref := range result.UnresolvedRefs {
+		output.UnresolvedRefs = append(output.UnresolvedRefs, JSONUnresolvedReference{
+			Key:        ref.Key,
+			Severity:   ref.Severity,
+			File:       ref.Definition.File,
+			Line:       ref.Definition.Line,
+			Value:      opts.redact(ref.Key, ref.Definition.Value),
+			References: append([]string{}, ref.References...),
+		})
+	}
+
+	for _, forbidden := range result.Forbidden {
+		output.Forbidden = append(output.Forbidden, JSONForbidden{
+			Key:         forbidden.Key,
+			Severity:    forbidden.Severity,
+			Message:     forbidden.Message,
+			Usages:      usageLocations(forbidden.Usages),
+			Definitions: definitionLocations(forbidden.Definitions),
+		})
+	}
+
+	for _, violation := range result.Style {
+		output.Style = append(output.Style, JSONStyleViolation{
+			Key:       violation.Key,
+			Severity:  violation.Severity,
+			Problems:  append([]string{}, violation.Problems...),
+			Locations: append(usageLocations(violation.Usages), definitionLocations(violation.Definitions)...),
+		})
+	}
+
+	for _, conflict := range result.Conflicts {
+		jsonConflict := JSONConflict{Key: conflict.Key, Definitions: []JSONDefinition{}}
+		for i, def := range conflict.Definitions {
+			jsonConflict.Definitions = append(jsonConflict.Definitions, JSONDefinition{
+				File:        def.File,
+				Line:        def.Line,
+				Environment: def.Environment,
+				Value:       opts.redact(conflict.Key, def.Value),
+				Effective:   i == conflict.Effective,
+			})
+		}
+		output.Conflicts = append(output.Conflicts, jsonConflict)
+	}
+
+	for _, mismatch := range result.CaseMismatches {
+		output.CaseMismatches = append(output.CaseMismatches, JSONCaseMismatch{
+			Key:         mismatch.Key,
+			Defined:     mismatch.Defined,
+			Usages:      usageLocations(mismatch.Usages),
+			Definitions: definitionLocations(mismatch.Definitions),
+		})
+	}
+
+	if drift := result.ExampleDrift; drift != nil {
+		output.ExampleDrift = &JSONExampleDrift{
+			Examples:     append([]string{}, drift.Examples...),
+			Undocumented: []MissingVar{},
+			Stale:        []MissingVar{},
+		}
+		for key, usages := range drift.Undocumented {
+			locations := append(usageLocations(usages), definitionLocations(drift.Definitions[key])...)
+			output.ExampleDrift.Undocumented = append(output.ExampleDrift.Undocumented, MissingVar{
+				Key:       key,
+				Severity:  drift.SeverityOf(config.CategoryUndocumented, key),
+				Locations: locations,
+			})
+		}
+		sort.Slice(output.ExampleDrift.Undocumented, func(i, j int) bool {
+			return output.ExampleDrift.Undocumented[i].Key < output.ExampleDrift.Undocumented[j].Key
+		})
+		for _, key := range drift.Stale {
+			output.ExampleDrift.Stale = append(output.ExampleDrift.Stale, MissingVar{
+				Key:       key,
+				Severity:  drift.SeverityOf(config.CategoryStale, key),
+				Locations: definitionLocations(drift.Definitions[key]),
+			})
+		}
+	}
+
+	if drift := result.SchemaDrift; drift != nil {
+		output.SchemaDrift = &JSONSchemaDrift{
+			Schema:     drift.Schema,
+			Unread:     []MissingVar{},
+			Undeclared: []MissingVar{},
+		}
+		for _, key := range drift.Unread {
+			output.SchemaDrift.Unread = append(output.SchemaDrift.Unread, MissingVar{
+				Key:       key,
+				Severity:  drift.SeverityOf(config.CategoryUnread, key),
+				Locations: []string{},
+			})
+		}
+		for _, key := range sortedKeys(drift.Undeclared) {
+			output.SchemaDrift.Undeclared = append(output.SchemaDrift.Undeclared, MissingVar{
+				Key:       key,
+				Severity:  drift.SeverityOf(config.CategoryUndeclared, key),
+				Locations: usageLocations(drift.Undeclared[key]),
+			})
+		}
+	}
+
+	if frontend := result.Frontend; frontend != nil {
+		output.Frontend = &JSONFrontend{
+			Framework:  frontend.Framework,
+			Prefix:     frontend.Prefix,
+			Unprefixed: []MissingVar{},
+			Exposed:    []MissingVar{},
+		}
+		for _, key := range sortedKeys(frontend.Unprefixed) {
+			output.Frontend.Unprefixed = append(output.Frontend.Unprefixed, MissingVar{
+				Key:       key,
+				Severity:  frontend.SeverityOf(config.CategoryUnprefixed, key),
+				Locations: usageLocations(frontend.Unprefixed[key]),
+			})
+		}
+		for _, key := range sortedKeys(frontend.Exposed) {
+			output.Frontend.Exposed = append(output.Frontend.Exposed, MissingVar{
+				Key:       key,
+				Severity:  frontend.SeverityOf(config.CategoryExposed, key),
+				Locations: append(usageLocations(frontend.Exposed[key]), definitionLocations(frontend.Definitions[key])...),
+			})
+		}
+	}
+
+	for _, parseError := range result.ParseErrors {
+		output.ParseErrors = append(output.ParseErrors, JSONParseError{
+			File:     parseError.File,
+			Language: parseError.Language,
+			Error:    parseError.Error,
+		})
+	}
+
+	output.Comparison = buildJSONComparison(result.Comparison)
+
+	if result.Fixed != nil {
+		output.Fixed = &JSONFixed{
+			Missing: append([]string{}, result.Fixed.Missing...),
+			Unused:  append([]string{}, result.Fixed.Unused...),
+			Dynamic: append([]string{}, result.Fixed.Dynamic...),
+		}
 	}
 
 	// Convert missing vars
@@ -99,6 +513,9 @@ func formatJSON(result analyzer.ScanResult, skipUnused bool, dynamic bool) error
 		sort.Strings(locations)
 		output.Missing = append(output.Missing, MissingVar{
 			Key:       key,
+			Severity:  result.SeverityOf(config.CategoryMissing, key),
+			Required:  result.IsRequired(key),
+			Owners:    usageOwners(usages),
 			Locations: locations,
 		})
 	}
@@ -108,6 +525,29 @@ func formatJSON(result analyzer.ScanResult, skipUnused bool, dynamic bool) error
 		return output.Missing[i].Key < output.Missing[j].Key
 	})
 
+	// Convert optional vars (every usage has a default in code)
+	for key, usages := range result.OptionalMissing {
+		output.OptionalMissing = append(output.OptionalMissing, MissingVar{
+			Key:       key,
+			Severity:  result.SeverityOf(config.CategoryOptional, key),
+			Owners:    usageOwners(usages),
+			Locations: usageLocations(usages),
+		})
+	}
+	sort.Slice(output.OptionalMissing, func(i, j int) bool {
+		return output.OptionalMissing[i].Key < output.OptionalMissing[j].Key
+	})
+
+	// Convert test-only vars (with the report test mode)
+	for _, key := range sortedKeys(result.TestMissing) {
+		output.TestMissing = append(output.TestMissing, MissingVar{
+			Key:       key,
+			Severity:  result.SeverityOf(config.CategoryTest, key),
+			Owners:    usageOwners(result.TestMissing[key]),
+			Locations: usageLocations(result.TestMissing[key]),
+		})
+	}
+
 	// Convert partial matches
 	for key, usages := range result.PartialMatches {
 		locations := make([]string, 0, len(usages))
@@ -120,11 +560,18 @@ func formatJSON(result analyzer.ScanResult, skipUnused bool, dynamic bool) error
 		}
 		sort.Strings(locations)
 		output.PartialMatches = append(output.PartialMatches, MissingVar{
-			Key:       key,
-			Locations: locations,
+			Key:        key,
+			Severity:   result.SeverityOf(config.CategoryDynamic, key),
+			Confidence: string(result.Confidence[key]),
+			Owners:     usageOwners(usages),
+			Locations:  locations,
 		})
 	}
 
+	for key, matches := range result.DynamicMatches {
+		output.DynamicMatches[key] = matches
+	}
+
 	// Sort partial matches by key
 	sort.Slice(output.PartialMatches, func(i, j int) bool {
 		return output.PartialMatches[i].Key < output.PartialMatches[j].Key
@@ -140,21 +587,185 @@ func formatJSON(result analyzer.ScanResult, skipUnused bool, dynamic bool) error
 		output.Unused = make([]string, len(result.Unused))
 		copy(output.Unused, result.Unused)
 		sort.Strings(output.Unused)
+		for _, key := range output.Unused {
+			output.UnusedSeverities[key] = result.SeverityOf(config.CategoryUnused, key)
+			if source := result.EnvKeySources[key]; source != "" {
+				output.UnusedLocations[key] = JSONLocation{File: source, Line: result.EnvKeyLines[key]}
+			}
+			if owners := result.EnvKeyOwners[key]; len(owners) > 0 {
+				output.UnusedOwners[key] = owners
+			}
+			if line, ok := result.UnusedBlame[key]; ok {
+				if output.UnusedBlame == nil {
+					output.UnusedBlame = make(map[string]JSONBlame)
+				}
+				output.UnusedBlame[key] = JSONBlame{
+					Commit:  line.Commit,
+					Author:  line.Author,
+					Date:    line.Time.UTC(),
+					AgeDays: int(time.Since(line.Time).Hours() / 24),
+				}
+			}
+		}
+	}
+
+	if groupBy == GroupByOwner {
+		output.ByOwner = make(map[string]JSONOwner)
+		for owner, findings := range result.ByOwner(skipUnused, dynamic) {
+			output.ByOwner[owner] = JSONOwner{
+				Missing: append([]string{}, findings.Missing...),
+				Unused:  append([]string{}, findings.Unused...),
+				Dynamic: append([]string{}, findings.Dynamic...),
+			}
+		}
+	}
+
+	if policy := result.Policy; policy != nil {
+		output.Policy = &JSONPolicy{Source: policy.Source, SHA256: policy.Checksum}
+	}
+	output.Remediation = reportedRemediation(result, skipUnused, dynamic)
+
+	if opts.MaxLocations > 0 {
+		truncateJSONLocations(&output, opts.MaxLocations)
+	}
+	return output
+}
+
+// truncateJSONLocations cuts the locations of every finding down to maxLocations, marking the findings that lost some
+func truncateJSONLocations(output *JSONOutput, maxLocations int) {
+	truncateVars := func(vars []MissingVar) {
+		for i := range vars {
+			if total := len(vars[i].Locations); total > maxLocations {
+				vars[i].Locations = vars[i].Locations[:maxLocations]
+				vars[i].Truncated = true
+				vars[i].TotalLocations = total
+			}
+		}
+	}
+	truncateVars(output.Missing)
+	truncateVars(output.PartialMatches)
+	truncateVars(output.OptionalMissing)
+	truncateVars(output.TestMissing)
+	if output.ExampleDrift != nil {
+		truncateVars(output.ExampleDrift.Undocumented)
+		truncateVars(output.ExampleDrift.Stale)
+	}
+	if output.SchemaDrift != nil {
+		truncateVars(output.SchemaDrift.Undeclared)
+	}
+	if output.Frontend != nil {
+		truncateVars(output.Frontend.Unprefixed)
+		truncateVars(output.Frontend.Exposed)
 	}
+	for i := range output.Style {
+		if total := len(output.Style[i].Locations); total > maxLocations {
+			output.Style[i].Locations = output.Style[i].Locations[:maxLocations]
+			output.Style[i].Truncated = true
+			output.Style[i].TotalLocations = total
+		}
+	}
+	for i := range output.Deprecated {
+		if total := len(output.Deprecated[i].Usages); total > maxLocations {
+			output.Deprecated[i].Usages = output.Deprecated[i].Usages[:maxLocations]
+			output.Deprecated[i].Truncated = true
+			output.Deprecated[i].TotalUsages = total
+		}
+	}
+	for i := range output.Forbidden {
+		if total := len(output.Forbidden[i].Usages); total > maxLocations {
+			output.Forbidden[i].Usages = output.Forbidden[i].Usages[:maxLocations]
+			output.Forbidden[i].Truncated = true
+			output.Forbidden[i].TotalUsages = total
+		}
+	}
+	for i := range output.TypeMismatches {
+		if total := len(output.TypeMismatches[i].Usages); total > maxLocations {
+			output.TypeMismatches[i].Usages = output.TypeMismatches[i].Usages[:maxLocations]
+			output.TypeMismatches[i].Truncated = true
+			output.TypeMismatches[i].TotalUsages = total
+		}
+	}
+	for i := range output.Placeholders {
+		if total := len(output.Placeholders[i].Usages); total > maxLocations {
+			output.Placeholders[i].Usages = output.Placeholders[i].Usages[:maxLocations]
+			output.Placeholders[i].Truncated = true
+			output.Placeholders[i].TotalUsages = total
+		}
+	}
+}
 
-	encoder := json.NewEncoder(os.Stdout)
+// formatJSON outputs results in JSON format
+func formatJSON(w io.Writer, result analyzer.ScanResult, opts Options) error {
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
+	return encoder.Encode(buildJSONOutput(result, opts))
 }
 
 // formatHumanReadable outputs results in human-readable format
-func formatHumanReadable(result analyzer.ScanResult, skipUnused bool, dynamic bool) error {
+// color enables ANSI escape codes, which should only be used when w is a terminal
+// width is the terminal width to fit usage lines into, 0 when unknown
+func formatHumanReadable(w io.Writer, result analyzer.ScanResult, opts Options, color bool, width int) error {
+	skipUnused, dynamic, groupBy, maxLocations := opts.SkipUnused, opts.Dynamic, opts.GroupBy, opts.MaxLocations
+	getColor := func(code string) string {
+		if color {
+			return code
+		}
+		return ""
+	}
+	// severityTag marks findings whose severity was changed from the category default in .envgrd.config
+	severityTag := func(category string, key string) string {
+		severity := result.SeverityOf(category, key)
+		if severity == config.DefaultSeverity(category) {
+			return ""
+		}
+		return fmt.Sprintf(" %s[%s]%s", getColor(colorGray), severity, getColor(colorReset))
+	}
+	// ownerTag names the CODEOWNERS owners of a usage or env file
+	ownerTag := func(owners []string) string {
+		if len(owners) == 0 {
+			return ""
+		}
+		return fmt.Sprintf(" %s(owned by %s)%s", getColor(colorGray), strings.Join(owners, ", "), getColor(colorReset))
+	}
+	// shown returns the usages of a finding to list, at most maxLocations
+	shown := func(usages []analyzer.EnvUsage) []analyzer.EnvUsage {
+		if maxLocations > 0 && len(usages) > maxLocations {
+			return usages[:maxLocations]
+		}
+		return usages
+	}
+	// more counts the usages left out by shown
+	more := func(usages []analyzer.EnvUsage) {
+		if maxLocations > 0 && len(usages) > maxLocations {
+			fmt.Fprintf(w, "    %s+%d more%s\n", getColor(colorGray), len(usages)-maxLocations, getColor(colorReset))
+		}
+	}
+	// snippet writes a code snippet after the location line written so far, cut to fit the terminal
+	// A location that leaves too little room gets the snippet on a line of its own
+	snippet := func(line string, code string) {
+		if code == "" {
+			return
+		}
+		switch {
+		case opts.Wide:
+		case width <= 0:
+			code = cut(code, maxSnippet)
+		default:
+			room := width - visibleLen(line) - 1
+			if room < minSnippet {
+				fmt.Fprintf(w, "\n%s", snippetIndent)
+				room = width - len(snippetIndent) - 1
+			}
+			code = cut(code, room)
+		}
+		fmt.Fprintf(w, " %s%s%s", getColor(colorGray), code, getColor(colorReset))
+	}
 	hasIssues := false
 
 	// Missing variables
 	if len(result.Missing) > 0 {
 		hasIssues = true
-		fmt.Printf("%s%sMissing environment variables:%s\n\n", getColor(colorBold), getColor(colorRed), getColor(colorReset))
+		fmt.Fprintf(w, "%s%sMissing environment variables:%s\n\n", getColor(colorBold), getColor(colorRed), getColor(colorReset))
 		keys := make([]string, 0, len(result.Missing))
 		for key := range result.Missing {
 			keys = append(keys, key)
@@ -163,31 +774,64 @@ func formatHumanReadable(result analyzer.ScanResult, skipUnused bool, dynamic bo
 
 		for _, key := range keys {
 			usages := result.Missing[key]
-			fmt.Printf("  %s%s%s\n", getColor(colorRed), key, getColor(colorReset))
-			for _, usage := range usages {
+			fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorRed), key, getColor(colorReset), severityTag(config.CategoryMissing, key))
+			if result.IsRequired(key) {
+				fmt.Fprintf(w, "    %srequired in .envgrd.config%s\n", getColor(colorGray), getColor(colorReset))
+			}
+			for _, usage := range shown(usages) {
 				filePath := usage.File
 				if filePath == "" {
 					filePath = "<unknown>"
 				}
-				fmt.Printf("    %sused in:%s %s%s%s:%s%d%s", getColor(colorGray), getColor(colorReset), getColor(colorCyan), filePath, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
-				if usage.CodeSnippet != "" {
-					// Truncate long snippets
-					snippet := usage.CodeSnippet
-					if len(snippet) > 80 {
-						snippet = snippet[:77] + "..."
-					}
-					fmt.Printf(" %s%s%s", getColor(colorGray), snippet, getColor(colorReset))
-				}
-				fmt.Println()
+				line := fmt.Sprintf("    %sused in:%s %s%s%s:%s%d%s%s", getColor(colorGray), getColor(colorReset), getColor(colorCyan), filePath, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset), ownerTag(usage.Owners))
+				fmt.Fprint(w, line)
+				snippet(line, usage.CodeSnippet)
+				fmt.Fprintln(w)
 			}
-			fmt.Println()
+			more(usages)
+			fmt.Fprintln(w)
 		}
 	}
 
+	// Optional variables are informational: the code falls back to a default when they're unset
+	if len(result.OptionalMissing) > 0 {
+		fmt.Fprintf(w, "%s%sOptional variables not set (code falls back to a default):%s\n\n", getColor(colorBold), getColor(colorCyan), getColor(colorReset))
+		keys := make([]string, 0, len(result.OptionalMissing))
+		for key := range result.OptionalMissing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorCyan), key, getColor(colorReset), severityTag(config.CategoryOptional, key))
+			for _, usage := range shown(result.OptionalMissing[key]) {
+				line := fmt.Sprintf("    %sused in:%s %s%s%s:%s%d%s%s", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset), ownerTag(usage.Owners))
+				fmt.Fprint(w, line)
+				snippet(line, usage.CodeSnippet)
+				fmt.Fprintln(w)
+			}
+			more(result.OptionalMissing[key])
+			fmt.Fprintln(w)
+		}
+	}
+
+	// Variables only used in tests, so they don't need to be set in production
+	if len(result.TestMissing) > 0 {
+		fmt.Fprintf(w, "%s%sMissing variables only used in tests:%s\n\n", getColor(colorBold), getColor(colorCyan), getColor(colorReset))
+		for _, key := range sortedKeys(result.TestMissing) {
+			fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorCyan), key, getColor(colorReset), severityTag(config.CategoryTest, key))
+			for _, usage := range shown(result.TestMissing[key]) {
+				fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset), ownerTag(usage.Owners))
+			}
+			more(result.TestMissing[key])
+		}
+		fmt.Fprintln(w)
+	}
+
 	// Partial matches (dynamic patterns) - only show if dynamic mode is enabled
 	if dynamic && len(result.PartialMatches) > 0 {
 		hasIssues = true
-		fmt.Printf("%s%sDynamic patterns (runtime-evaluated expressions):%s\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		fmt.Fprintf(w, "%s%sDynamic patterns (runtime-evaluated expressions):%s\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
 		keys := make([]string, 0, len(result.PartialMatches))
 		for key := range result.PartialMatches {
 			keys = append(keys, key)
@@ -197,99 +841,614 @@ func formatHumanReadable(result analyzer.ScanResult, skipUnused bool, dynamic bo
 		for _, key := range keys {
 			usages := result.PartialMatches[key]
 			// Display the key directly (which is the full expression for dynamic patterns)
-			fmt.Printf("  %s%s%s\n", getColor(colorYellow), key, getColor(colorReset))
-			for _, usage := range usages {
+			confidence := ""
+			if c := result.Confidence[key]; c != "" {
+				confidence = fmt.Sprintf(" %s(%s confidence)%s", getColor(colorGray), c, getColor(colorReset))
+			}
+			fmt.Fprintf(w, "  %s%s%s%s%s\n", getColor(colorYellow), key, getColor(colorReset), severityTag(config.CategoryDynamic, key), confidence)
+			for _, usage := range shown(usages) {
 				filePath := usage.File
 				if filePath == "" {
 					filePath = "<unknown>"
 				}
-				fmt.Printf("    %sused in:%s %s%s%s:%s%d%s", getColor(colorGray), getColor(colorReset), getColor(colorCyan), filePath, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
-				if usage.CodeSnippet != "" {
-					// Truncate long snippets
-					snippet := usage.CodeSnippet
-					if len(snippet) > 80 {
-						snippet = snippet[:77] + "..."
-					}
-					fmt.Printf(" %s%s%s", getColor(colorGray), snippet, getColor(colorReset))
-				}
-				fmt.Println()
+				line := fmt.Sprintf("    %sused in:%s %s%s%s:%s%d%s%s", getColor(colorGray), getColor(colorReset), getColor(colorCyan), filePath, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset), ownerTag(usage.Owners))
+				fmt.Fprint(w, line)
+				snippet(line, usage.CodeSnippet)
+				fmt.Fprintln(w)
 			}
-			fmt.Println()
+			more(usages)
+			fmt.Fprintln(w)
 		}
 	}
 
+	// Dynamic patterns that resolve to defined variables are informational, those variables aren't reported as unused
+	if dynamic && len(result.DynamicMatches) > 0 {
+		fmt.Fprintf(w, "%s%sDynamic patterns resolved to defined variables:%s\n", getColor(colorBold), getColor(colorGray), getColor(colorReset))
+		keys := make([]string, 0, len(result.DynamicMatches))
+		for key := range result.DynamicMatches {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s%s%s\n", getColor(colorCyan), key, getColor(colorReset))
+			fmt.Fprintf(w, "    %sused via dynamic access:%s %s\n", getColor(colorGray), getColor(colorReset), strings.Join(result.DynamicMatches[key], ", "))
+		}
+		fmt.Fprintln(w)
+	}
+
 	// Unused variables
 	if !skipUnused && len(result.Unused) > 0 {
 		hasIssues = true
-		fmt.Printf("%s%sUnused variables:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		fmt.Fprintf(w, "%s%sUnused variables:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
 		sort.Strings(result.Unused)
 		for _, key := range result.Unused {
-			value := result.EnvKeys[key]
-			// Redact the value
-			redactedValue := redactValue(value)
+			value := opts.redact(key, result.EnvKeys[key])
+			if value != "" {
+				value = "=" + getColor(colorGray) + value + getColor(colorReset)
+			}
 			// Get source file, default to ".env" if not found
 			sourceFile := result.EnvKeySources[key]
 			if sourceFile == "" {
 				sourceFile = ".env"
 			}
-			fmt.Printf("  %s%s%s=%s%s%s %s(in %s)%s\n", getColor(colorYellow), key, getColor(colorReset), getColor(colorGray), redactedValue, getColor(colorReset), getColor(colorGray), sourceFile, getColor(colorReset))
+			if line := result.EnvKeyLines[key]; line > 0 {
+				sourceFile = fmt.Sprintf("%s:%d", sourceFile, line)
+			}
+			fmt.Fprintf(w, "  %s%s%s%s %s(in %s)%s%s%s\n", getColor(colorYellow), key, getColor(colorReset), value, getColor(colorGray), sourceFile, getColor(colorReset), ownerTag(result.EnvKeyOwners[key]), severityTag(config.CategoryUnused, key))
+			if line, ok := result.UnusedBlame[key]; ok {
+				fmt.Fprintf(w, "    %slast modified %s ago by %s (%s)%s\n", getColor(colorGray), blame.Age(line.Time, time.Now()), line.Author, line.Commit, getColor(colorReset))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Example env files that drifted from the code
+	if drift := result.ExampleDrift; drift.Count() > 0 {
+		hasIssues = true
+		if len(drift.Undocumented) > 0 {
+			fmt.Fprintf(w, "%s%sVariables missing from example files (%s):%s\n\n", getColor(colorBold), getColor(colorYellow), strings.Join(drift.Examples, ", "), getColor(colorReset))
+			keys := make([]string, 0, len(drift.Undocumented))
+			for key := range drift.Undocumented {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorYellow), key, getColor(colorReset), driftSeverityTag(drift, config.CategoryUndocumented, key, getColor))
+				for _, usage := range shown(drift.Undocumented[key]) {
+					fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+				}
+				more(drift.Undocumented[key])
+				for _, location := range definitionLocations(drift.Definitions[key]) {
+					fmt.Fprintf(w, "    %sdefined in:%s %s%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), location, getColor(colorReset))
+				}
+			}
+			fmt.Fprintln(w)
+		}
+		if len(drift.Stale) > 0 {
+			fmt.Fprintf(w, "%s%sExample variables never used in code:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+			for _, key := range drift.Stale {
+				fmt.Fprintf(w, "  %s%s%s %s(in %s)%s%s\n", getColor(colorYellow), key, getColor(colorReset), getColor(colorGray), strings.Join(definitionLocations(drift.Definitions[key]), ", "), getColor(colorReset), driftSeverityTag(drift, config.CategoryStale, key, getColor))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	// Schema entries no code reads, and variables code reads without an entry
+	if drift := result.SchemaDrift; drift.Count() > 0 {
+		hasIssues = true
+		if len(drift.Unread) > 0 {
+			fmt.Fprintf(w, "%s%sSchema variables never used in code (%s):%s\n\n", getColor(colorBold), getColor(colorYellow), drift.Schema, getColor(colorReset))
+			for _, key := range drift.Unread {
+				fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorYellow), key, getColor(colorReset), schemaSeverityTag(drift, config.CategoryUnread, key, getColor))
+			}
+			fmt.Fprintln(w)
+		}
+		if len(drift.Undeclared) > 0 {
+			fmt.Fprintf(w, "%s%sVariables missing from the schema (%s):%s\n\n", getColor(colorBold), getColor(colorYellow), drift.Schema, getColor(colorReset))
+			for _, key := range sortedKeys(drift.Undeclared) {
+				fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorYellow), key, getColor(colorReset), schemaSeverityTag(drift, config.CategoryUndeclared, key, getColor))
+				for _, usage := range shown(drift.Undeclared[key]) {
+					fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+				}
+				more(drift.Undeclared[key])
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	// Client-side code reading variables the bundler won't expose, and secrets it would expose
+	if frontend := result.Frontend; frontend.Count() > 0 {
+		hasIssues = true
+		if len(frontend.Unprefixed) > 0 {
+			fmt.Fprintf(w, "%s%sClient-side variables without the %s prefix (%s):%s\n\n", getColor(colorBold), getColor(colorYellow), frontend.Prefix, frontend.Framework, getColor(colorReset))
+			for _, key := range sortedKeys(frontend.Unprefixed) {
+				fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorYellow), key, getColor(colorReset), frontendSeverityTag(frontend, config.CategoryUnprefixed, key, getColor))
+				for _, usage := range shown(frontend.Unprefixed[key]) {
+					fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+				}
+				more(frontend.Unprefixed[key])
+			}
+			fmt.Fprintln(w)
+		}
+		if len(frontend.Exposed) > 0 {
+			fmt.Fprintf(w, "%s%sSecret-looking variables exposed to the browser by the %s prefix:%s\n\n", getColor(colorBold), getColor(colorRed), frontend.Prefix, getColor(colorReset))
+			for _, key := range sortedKeys(frontend.Exposed) {
+				fmt.Fprintf(w, "  %s%s%s%s\n", getColor(colorRed), key, getColor(colorReset), frontendSeverityTag(frontend, config.CategoryExposed, key, getColor))
+				for _, usage := range shown(frontend.Exposed[key]) {
+					fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+				}
+				more(frontend.Exposed[key])
+				for _, location := range definitionLocations(frontend.Definitions[key]) {
+					fmt.Fprintf(w, "    %sdefined in:%s %s%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), location, getColor(colorReset))
+				}
+			}
+			fmt.Fprintln(w)
 		}
-		fmt.Println()
+	}
+
+	// Names breaking the naming rules of .envgrd.config
+	if len(result.Style) > 0 {
+		hasIssues = true
+		fmt.Fprintf(w, "%s%sNaming convention violations:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		for _, violation := range result.Style {
+			tag := ""
+			if violation.Severity != config.DefaultSeverity(config.CategoryStyle) {
+				tag = fmt.Sprintf(" %s[%s]%s", getColor(colorGray), violation.Severity, getColor(colorReset))
+			}
+			fmt.Fprintf(w, "  %s%s%s %s(%s)%s%s\n", getColor(colorYellow), violation.Key, getColor(colorReset), getColor(colorGray), strings.Join(violation.Problems, ", "), getColor(colorReset), tag)
+			for _, usage := range shown(violation.Usages) {
+				fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+			}
+			more(violation.Usages)
+			for _, location := range definitionLocations(violation.Definitions) {
+				fmt.Fprintf(w, "    %sdefined in:%s %s%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), location, getColor(colorReset))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Deprecated variables with their migration hint
+	if len(result.Deprecated) > 0 {
+		hasIssues = true
+		fmt.Fprintf(w, "%s%sDeprecated variables:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		for _, deprecated := range result.Deprecated {
+			tag := ""
+			if deprecated.Severity != config.DefaultSeverity(config.CategoryDeprecated) {
+				tag = fmt.Sprintf(" %s[%s]%s", getColor(colorGray), deprecated.Severity, getColor(colorReset))
+			}
+			hint := ""
+			if deprecated.Hint != "" {
+				hint = fmt.Sprintf(" %s(%s)%s", getColor(colorGray), deprecated.Hint, getColor(colorReset))
+			}
+			fmt.Fprintf(w, "  %s%s%s%s%s\n", getColor(colorYellow), deprecated.Key, getColor(colorReset), hint, tag)
+			for _, usage := range shown(deprecated.Usages) {
+				fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+			}
+			more(deprecated.Usages)
+			for _, location := range definitionLocations(deprecated.Definitions) {
+				fmt.Fprintf(w, "    %sremove from:%s %s%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), location, getColor(colorReset))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Env file values code can't parse as the type it expects
+	if len(result.TypeMismatches) > 0 {
+		hasIssues = true
+		fmt.Fprintf(w, "%s%sValues of the wrong type:%s\n\n", getColor(colorBold), getColor(colorRed), getColor(colorReset))
+		for _, mismatch := range result.TypeMismatches {
+			tag := ""
+			if mismatch.Severity != config.DefaultSeverity(config.CategoryType) {
+				tag = fmt.Sprintf(" %s[%s]%s", getColor(colorGray), mismatch.Severity, getColor(colorReset))
+			}
+			for _, def := range mismatch.Definitions {
+				assignment := mismatch.Key
+				if value := opts.redact(mismatch.Key, def.Value); value != "" {
+					assignment = mismatch.Key + "=" + value
+				}
+				location := definitionLocations([]analyzer.Definition{def})[0]
+				fmt.Fprintf(w, "  %s%s%s %s(%s)%s but code expects %s%s\n", getColor(colorRed), assignment, getColor(colorReset), getColor(colorCyan), location, getColor(colorReset), withArticle(mismatch.Type), tag)
+			}
+			for _, usage := range shown(mismatch.Usages) {
+				fmt.Fprintf(w, "    %sparsed in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+			}
+			more(mismatch.Usages)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Variables set to an empty or placeholder value, which pass the missing check but break at runtime
+	if len(result.Placeholders) > 0 {
+		hasIssues = true
+		fmt.Fprintf(w, "%s%sEmpty or placeholder values:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		for _, placeholder := range result.Placeholders {
+			tag := ""
+			if placeholder.Severity != config.DefaultSeverity(config.CategoryPlaceholder) {
+				tag = fmt.Sprintf(" %s[%s]%s", getColor(colorGray), placeholder.Severity, getColor(colorReset))
+			}
+			assignment, reason := placeholder.Key+"=", "empty"
+			if !placeholder.Empty {
+				assignment, reason = placeholder.Key, "placeholder"
+				if value := opts.redact(placeholder.Key, placeholder.Definition.Value); value != "" {
+					assignment = placeholder.Key + "=" + value
+				}
+			}
+			location := definitionLocations([]analyzer.Definition{placeholder.Definition})[0]
+			fmt.Fprintf(w, "  %s%s%s %s(%s)%s %s%s\n", getColor(colorYellow), assignment, getColor(colorReset), getColor(colorCyan), location, getColor(colorReset), reason, tag)
+			for _, usage := range shown(placeholder.Usages) {
+				fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+			}
+			more(placeholder.Usages)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Env file values referencing variables that are neither defined nor exported, which expand to nothing
+	if len(result.UnresolvedRefs) > 0 {
+		hasIssues = true
+		fmt.Fprintf(w, "%s%sUnresolved references:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		for _, ref := range result.UnresolvedRefs {
+			tag := ""
+			if ref.Severity != config.DefaultSeverity(config.CategoryReference) {
+				tag = fmt.Sprintf(" %s[%s]%s", getColor(colorGray), ref.Severity, getColor(colorReset))
+			}
+			assignment := ref.Key
+			if value := opts.redact(ref.Key, ref.Definition.Value); value != "" {
+				assignment = ref.Key + "=" + value
+			}
+			location := definitionLocations([]analyzer.Definition{ref.Definition})[0]
+			fmt.Fprintf(w, "  %s%s%s %s(%s)%s references undefined %s%s\n", getColor(colorYellow), assignment, getColor(colorReset), getColor(colorCyan), location, getColor(colorReset), strings.Join(ref.References, ", "), tag)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Forbidden variables with their remediation message
+	if len(result.Forbidden) > 0 {
+		hasIssues = true
+		fmt.Fprintf(w, "%s%sForbidden variables:%s\n\n", getColor(colorBold), getColor(colorRed), getColor(colorReset))
+		for _, forbidden := range result.Forbidden {
+			tag := ""
+			if forbidden.Severity != config.DefaultSeverity(config.CategoryForbidden) {
+				tag = fmt.Sprintf(" %s[%s]%s", getColor(colorGray), forbidden.Severity, getColor(colorReset))
+			}
+			message := ""
+			if forbidden.Message != "" {
+				message = fmt.Sprintf(" %s(%s)%s", getColor(colorGray), forbidden.Message, getColor(colorReset))
+			}
+			fmt.Fprintf(w, "  %s%s%s%s%s\n", getColor(colorRed), forbidden.Key, getColor(colorReset), message, tag)
+			for _, usage := range shown(forbidden.Usages) {
+				fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+			}
+			more(forbidden.Usages)
+			for _, location := range definitionLocations(forbidden.Definitions) {
+				fmt.Fprintf(w, "    %sdefined in:%s %s%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), location, getColor(colorReset))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Variables defined differently in several env files, the effective one is marked
+	if len(result.Conflicts) > 0 {
+		fmt.Fprintf(w, "%s%sConflicting definitions:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(w, "  %s%s%s\n", getColor(colorYellow), conflict.Key, getColor(colorReset))
+			for i, def := range conflict.Definitions {
+				location := definitionLocations([]analyzer.Definition{def})[0]
+				fmt.Fprintf(w, "    %s%s%s", getColor(colorCyan), location, getColor(colorReset))
+				if value := opts.redact(conflict.Key, def.Value); value != "" {
+					fmt.Fprintf(w, " = %s%s%s", getColor(colorGray), value, getColor(colorReset))
+				}
+				if i == conflict.Effective {
+					fmt.Fprintf(w, " %s(effective)%s", getColor(colorGreen), getColor(colorReset))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Variables only found by ignoring case (--case-insensitive), they break on case-sensitive systems
+	if len(result.CaseMismatches) > 0 {
+		fmt.Fprintf(w, "%s%sCase-only mismatches:%s\n\n", getColor(colorBold), getColor(colorYellow), getColor(colorReset))
+		for _, mismatch := range result.CaseMismatches {
+			fmt.Fprintf(w, "  %s%s%s %s(defined as %s)%s\n", getColor(colorYellow), mismatch.Key, getColor(colorReset), getColor(colorGray), mismatch.Defined, getColor(colorReset))
+			for _, usage := range shown(mismatch.Usages) {
+				fmt.Fprintf(w, "    %sused in:%s %s%s%s:%s%d%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), usage.File, getColor(colorReset), getColor(colorYellow), usage.Line, getColor(colorReset))
+			}
+			more(mismatch.Usages)
+			for _, location := range definitionLocations(mismatch.Definitions) {
+				fmt.Fprintf(w, "    %sdefined in:%s %s%s%s\n", getColor(colorGray), getColor(colorReset), getColor(colorCyan), location, getColor(colorReset))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Files that couldn't be analyzed, so their usages are unknown
+	if len(result.ParseErrors) > 0 {
+		fmt.Fprintf(w, "%s%sFiles that could not be analyzed:%s\n\n", getColor(colorBold), getColor(colorRed), getColor(colorReset))
+		for _, parseError := range result.ParseErrors {
+			fmt.Fprintf(w, "  %s%s%s %s(%s)%s: %s\n", getColor(colorCyan), parseError.File, getColor(colorReset), getColor(colorGray), parseError.Language, getColor(colorReset), parseError.Error)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Configured remediation hints of the reported variables
+	if hints := reportedRemediation(result, skipUnused, dynamic); len(hints) > 0 {
+		fmt.Fprintf(w, "%s%sHow to fix:%s\n\n", getColor(colorBold), getColor(colorGreen), getColor(colorReset))
+		keys := make([]string, 0, len(hints))
+		width := 0
+		for key := range hints {
+			keys = append(keys, key)
+			width = max(width, len(key))
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s%-*s%s  %s\n", getColor(colorYellow), width, key, getColor(colorReset), hints[key])
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Findings resolved since the previous run (--since-last-run)
+	if result.Fixed != nil && result.Fixed.Count() > 0 {
+		fmt.Fprintf(w, "%s%sFixed since last run:%s\n\n", getColor(colorBold), getColor(colorGreen), getColor(colorReset))
+		for _, fixed := range []struct {
+			category string
+			keys     []string
+		}{
+			{"missing", result.Fixed.Missing},
+			{"unused", result.Fixed.Unused},
+			{"dynamic", result.Fixed.Dynamic},
+		} {
+			for _, key := range fixed.keys {
+				fmt.Fprintf(w, "  %s%s%s %s(was %s)%s\n", getColor(colorGreen), key, getColor(colorReset), getColor(colorGray), fixed.category, getColor(colorReset))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Findings classified against a previous report (--compare-to)
+	if result.Comparison != nil {
+		formatComparison(w, result.Comparison, getColor)
+	}
+
+	// Findings per CODEOWNERS owner, to route fixes in a monorepo
+	if groupBy == GroupByOwner && hasIssues {
+		formatByOwner(w, result.ByOwner(skipUnused, dynamic), getColor)
 	}
 
 	// Show ignored missing variables count
 	if result.IgnoredMissing > 0 {
-		fmt.Printf("%s%sNote:%s %d missing variable(s) were ignored (configured in .envgrd.config)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredMissing)
+		fmt.Fprintf(w, "%s%sNote:%s %d missing variable(s) were ignored (configured in .envgrd.config)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredMissing)
+	}
+
+	// Show ignored unused variables count (not with --skip-unused, which hides the whole check)
+	if !skipUnused && result.IgnoredUnused > 0 {
+		fmt.Fprintf(w, "%s%sNote:%s %d unused variable(s) were ignored (configured in .envgrd.config)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredUnused)
+	}
+
+	// Show missing variables only used in tests (with the exclude test mode)
+	if result.IgnoredTests > 0 {
+		fmt.Fprintf(w, "%s%sNote:%s %d missing variable(s) only used in tests were excluded (configured in .envgrd.config)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredTests)
 	}
 
 	// Show ignored variables from ignored folders
 	if result.IgnoredFromFolders > 0 {
-		fmt.Printf("%s%sNote:%s %d variable(s) found in ignored folders were excluded from the scan (configured in .envgrd.config)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredFromFolders)
+		fmt.Fprintf(w, "%s%sNote:%s %d variable(s) found in ignored folders were excluded from the scan (configured in .envgrd.config)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredFromFolders)
 	}
 
-	if result.IgnoredMissing > 0 || result.IgnoredFromFolders > 0 {
-		fmt.Println()
+	// Show system variables that weren't reported as missing
+	if result.IgnoredSystem > 0 {
+		fmt.Fprintf(w, "%s%sNote:%s %d well-known system variable(s) (e.g., PATH, CI) were not reported as missing\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), result.IgnoredSystem)
+	}
+
+	// Show dynamic patterns hidden by --min-confidence
+	ignoredDynamic := result.IgnoredDynamic
+	if !dynamic {
+		ignoredDynamic = 0
+	}
+	if ignoredDynamic > 0 {
+		fmt.Fprintf(w, "%s%sNote:%s %d dynamic pattern(s) below the minimum confidence were hidden (--min-confidence)\n", getColor(colorGray), getColor(colorBold), getColor(colorReset), ignoredDynamic)
+	}
+
+	ignoredUnused := result.IgnoredUnused
+	if skipUnused {
+		ignoredUnused = 0
+	}
+	if result.IgnoredMissing > 0 || ignoredUnused > 0 || result.IgnoredFromFolders > 0 || result.IgnoredSystem > 0 || result.IgnoredTests > 0 || ignoredDynamic > 0 {
+		fmt.Fprintln(w)
 	}
 
 	// No issues found
-	if !hasIssues {
-		ignoredCount := result.IgnoredMissing + result.IgnoredFromFolders
+	if !hasIssues && result.Fixed != nil {
+		fmt.Fprintf(w, "%s%s✓ No new issues since the last run.%s\n", getColor(colorGreen), getColor(colorBold), getColor(colorReset))
+	} else if !hasIssues {
+		ignoredCount := result.IgnoredMissing + ignoredUnused + result.IgnoredFromFolders + result.IgnoredSystem + result.IgnoredTests + ignoredDynamic
 		if ignoredCount > 0 {
 			var parts []string
 			if result.IgnoredMissing > 0 {
 				parts = append(parts, fmt.Sprintf("%d ignored via config", result.IgnoredMissing))
 			}
+			if ignoredUnused > 0 {
+				parts = append(parts, fmt.Sprintf("%d unused ignored via config", ignoredUnused))
+			}
 			if result.IgnoredFromFolders > 0 {
 				parts = append(parts, fmt.Sprintf("%d from ignored folders", result.IgnoredFromFolders))
 			}
-			fmt.Printf("%s%s✓ No issues found (excluding %s).%s\n", getColor(colorGreen), getColor(colorBold), strings.Join(parts, ", "), getColor(colorReset))
+			if result.IgnoredSystem > 0 {
+				parts = append(parts, fmt.Sprintf("%d system variables", result.IgnoredSystem))
+			}
+			if result.IgnoredTests > 0 {
+				parts = append(parts, fmt.Sprintf("%d only used in tests", result.IgnoredTests))
+			}
+			if ignoredDynamic > 0 {
+				parts = append(parts, fmt.Sprintf("%d low-confidence dynamic patterns", ignoredDynamic))
+			}
+			fmt.Fprintf(w, "%s%s✓ No issues found (excluding %s).%s\n", getColor(colorGreen), getColor(colorBold), strings.Join(parts, ", "), getColor(colorReset))
 		} else {
-			fmt.Printf("%s%s✓ No issues found. All environment variables are properly configured.%s\n", getColor(colorGreen), getColor(colorBold), getColor(colorReset))
+			fmt.Fprintf(w, "%s%s✓ No issues found. All environment variables are properly configured.%s\n", getColor(colorGreen), getColor(colorBold), getColor(colorReset))
+		}
+	}
+
+	// Statistics are only collected when requested (--stats)
+	if result.Stats != nil {
+		if !hasIssues {
+			fmt.Fprintln(w)
 		}
+		formatStats(w, result.Stats, getColor)
 	}
 
 	return nil
 }
 
-// redactValue redacts sensitive values while showing the type
-func redactValue(value string) string {
-	if value == "" {
-		return `""`
+// usageLocations renders usages as sorted "file:line (snippet)" strings
+func usageLocations(usages []analyzer.EnvUsage) []string {
+	locations := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		loc := fmt.Sprintf("%s:%d", usage.File, usage.Line)
+		if usage.CodeSnippet != "" {
+			loc += fmt.Sprintf(" (%s)", usage.CodeSnippet)
+		}
+		locations = append(locations, loc)
+	}
+	sort.Strings(locations)
+	return locations
+}
+
+// usageOwners returns the CODEOWNERS owners of the files of usages, sorted
+func usageOwners(usages []analyzer.EnvUsage) []string {
+	seen := make(map[string]bool)
+	var owners []string
+	for _, usage := range usages {
+		for _, owner := range usage.Owners {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// formatByOwner prints the missing, unused and dynamic findings of each owner, unowned ones last
+func formatByOwner(w io.Writer, groups map[string]*analyzer.OwnerFindings, getColor func(string) string) {
+	owners := make([]string, 0, len(groups))
+	for owner := range groups {
+		if owner != analyzer.NoOwner {
+			owners = append(owners, owner)
+		}
+	}
+	sort.Strings(owners)
+	if groups[analyzer.NoOwner] != nil {
+		owners = append(owners, analyzer.NoOwner)
+	}
+
+	fmt.Fprintf(w, "%s%sFindings by owner:%s\n\n", getColor(colorBold), getColor(colorCyan), getColor(colorReset))
+	for _, owner := range owners {
+		findings := groups[owner]
+		fmt.Fprintf(w, "  %s%s%s\n", getColor(colorBold), owner, getColor(colorReset))
+		for _, list := range []struct {
+			label string
+			keys  []string
+		}{
+			{"missing", findings.Missing},
+			{"unused", findings.Unused},
+			{"dynamic", findings.Dynamic},
+		} {
+			if len(list.keys) > 0 {
+				fmt.Fprintf(w, "    %s%s:%s %s\n", getColor(colorGray), list.label, getColor(colorReset), strings.Join(list.keys, ", "))
+			}
+		}
 	}
-	// If it looks like a secret (long, random-looking), redact it
-	if len(value) > 20 {
-		return "[REDACTED]"
+	fmt.Fprintln(w)
+}
+
+// definitionLocations renders env file definitions as "file:line" strings, in load order, followed by
+// the environment of environment-specific definitions ("ecosystem.config.js:12 [production]")
+func definitionLocations(definitions []analyzer.Definition) []string {
+	locations := make([]string, 0, len(definitions))
+	for _, def := range definitions {
+		location := def.File
+		if def.Line > 0 {
+			location = fmt.Sprintf("%s:%d", def.File, def.Line)
+		}
+		if def.Environment != "" {
+			location += " [" + def.Environment + "]"
+		}
+		locations = append(locations, location)
 	}
-	// If it contains special characters that suggest it's a secret
-	if strings.ContainsAny(value, "=+/") && len(value) > 10 {
-		return "[REDACTED]"
+	return locations
+}
+
+// driftSeverityTag marks example drift findings whose severity was changed from the category default
+func driftSeverityTag(drift *analyzer.ExampleDrift, category string, key string, getColor func(string) string) string {
+	severity := drift.SeverityOf(category, key)
+	if severity == config.DefaultSeverity(category) {
+		return ""
 	}
-	// For short values, show first and last char
-	if len(value) > 4 {
-		return string(value[0]) + "..." + string(value[len(value)-1])
+	return fmt.Sprintf(" %s[%s]%s", getColor(colorGray), severity, getColor(colorReset))
+}
+
+// schemaSeverityTag marks schema drift findings whose severity was changed from the category default
+func schemaSeverityTag(drift *analyzer.SchemaDrift, category string, key string, getColor func(string) string) string {
+	severity := drift.SeverityOf(category, key)
+	if severity == config.DefaultSeverity(category) {
+		return ""
+	}
+	return fmt.Sprintf(" %s[%s]%s", getColor(colorGray), severity, getColor(colorReset))
+}
+
+// frontendSeverityTag marks frontend findings whose severity was changed from the category default
+func frontendSeverityTag(frontend *analyzer.FrontendLeaks, category string, key string, getColor func(string) string) string {
+	severity := frontend.SeverityOf(category, key)
+	if severity == config.DefaultSeverity(category) {
+		return ""
+	}
+	return fmt.Sprintf(" %s[%s]%s", getColor(colorGray), severity, getColor(colorReset))
+}
+
+// sortedKeys returns the keys of a findings map in order
+func sortedKeys(findings map[string][]analyzer.EnvUsage) []string {
+	keys := mapKeys(findings)
+	sort.Strings(keys)
+	return keys
+}
+
+// withArticle prefixes a type name with its indefinite article (e.g., "an integer")
+func withArticle(typ string) string {
+	if strings.IndexAny(typ, "aeiou") == 0 {
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+// redact renders an env file value with the options' redactor, "" leaves the value out
+func (o Options) redact(key string, value string) string {
+	if o.Redactor == nil {
+		return DefaultRedactor.Redact(key, value)
+	}
+	return o.Redactor.Redact(key, value)
+}
+
+// reportedRemediation returns the remediation hints of the variables with reported findings, nil when none
+// have one: unused variables don't count with skipUnused, dynamic patterns without dynamic
+func reportedRemediation(result analyzer.ScanResult, skipUnused bool, dynamic bool) map[string]string {
+	var hints map[string]string
+	for category, keys := range result.Findings() {
+		if (category == analyzer.FindingUnused && skipUnused) || (category == analyzer.FindingDynamic && !dynamic) {
+			continue
+		}
+		for _, key := range keys {
+			if hint, ok := result.Remediation[key]; ok {
+				if hints == nil {
+					hints = make(map[string]string)
+				}
+				hints[key] = hint
+			}
+		}
 	}
-	// For very short values, just show asterisks
-	return "***"
+	return hints
 }
 
 // HasIssues returns true if there are any issues in the scan result
@@ -0,0 +1,202 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// Graph formats
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// How consuming code is grouped in the graph
+const (
+	ConsumersFile = "file" // One node per source file (default)
+	ConsumersDir  = "dir"  // One node per top-level directory, e.g. a service of a monorepo
+)
+
+// Kinds of graph nodes
+const (
+	nodeSource   = "source"
+	nodeVariable = "variable"
+	nodeConsumer = "consumer"
+)
+
+// Graph is the dependency graph of env definition sources, variables and the code consuming them
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// GraphNode is a definition source, a variable or a consumer
+type GraphNode struct {
+	ID     string // Stable identifier, unique across kinds
+	Label  string // File, directory or variable name
+	Kind   string // source, variable or consumer
+	Status string // Variables only: missing, unused or empty when defined and used
+}
+
+// GraphEdge links a source to the variables it defines, or a variable to the code reading it
+type GraphEdge struct {
+	From string
+	To   string
+}
+
+// BuildGraph builds the source -> variable -> consumer graph of a scan
+// Static usages are included, dynamic patterns can't be attributed to a variable
+func BuildGraph(result analyzer.ScanResult, consumers string) *Graph {
+	g := &Graph{}
+	ids := make(map[string]string)
+	node := func(kind, label, status string) string {
+		key := kind + "\x00" + label
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[key] = id
+		g.Nodes = append(g.Nodes, GraphNode{ID: id, Label: label, Kind: kind, Status: status})
+		return id
+	}
+	edges := make(map[GraphEdge]bool)
+	edge := func(from, to string) {
+		e := GraphEdge{From: from, To: to}
+		if !edges[e] {
+			edges[e] = true
+			g.Edges = append(g.Edges, e)
+		}
+	}
+
+	unused := make(map[string]bool, len(result.Unused))
+	for _, key := range result.Unused {
+		unused[key] = true
+	}
+	status := func(key string) string {
+		switch {
+		case result.Missing[key] != nil:
+			return "missing"
+		case unused[key]:
+			return "unused"
+		}
+		return ""
+	}
+
+	// Sorted input keeps node IDs stable between runs
+	keys := make([]string, 0, len(result.Definitions))
+	for key := range result.Definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		variable := node(nodeVariable, key, status(key))
+		for _, def := range result.Definitions[key] {
+			edge(node(nodeSource, def.File, ""), variable)
+		}
+	}
+
+	usages := append([]analyzer.EnvUsage{}, result.CodeKeys...)
+	sort.SliceStable(usages, func(i, j int) bool {
+		if usages[i].Key != usages[j].Key {
+			return usages[i].Key < usages[j].Key
+		}
+		return usages[i].File < usages[j].File
+	})
+	for _, usage := range usages {
+		if usage.IsPartial || usage.InIgnoredPath {
+			continue
+		}
+		edge(node(nodeVariable, usage.Key, status(usage.Key)), node(nodeConsumer, consumerOf(usage.File, consumers), ""))
+	}
+	return g
+}
+
+// consumerOf returns the consumer node label of a source file
+func consumerOf(file string, consumers string) string {
+	file = filepath.ToSlash(file)
+	if consumers != ConsumersDir {
+		return file
+	}
+	if dir, _, found := strings.Cut(file, "/"); found {
+		return dir + "/"
+	}
+	return path.Dir(file) + "/"
+}
+
+// WriteGraph renders the graph in the given format: dot or mermaid
+func WriteGraph(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case GraphDOT, "":
+		return writeDOT(w, g)
+	case GraphMermaid:
+		return writeMermaid(w, g)
+	default:
+		return fmt.Errorf("unknown graph format %q (supported: dot, mermaid)", format)
+	}
+}
+
+// writeDOT renders the graph for Graphviz, left to right from sources to consumers
+func writeDOT(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph envgrd {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{fmt.Sprintf("label=%q", n.Label)}
+		switch n.Kind {
+		case nodeSource:
+			attrs = append(attrs, "shape=folder")
+		case nodeVariable:
+			switch n.Status {
+			case "missing":
+				attrs = append(attrs, "shape=box", "style=rounded", "color=red", "fontcolor=red")
+			case "unused":
+				attrs = append(attrs, "shape=box", "style=\"rounded,dashed\"", "color=gray", "fontcolor=gray")
+			default:
+				attrs = append(attrs, "shape=box", "style=rounded")
+			}
+		case nodeConsumer:
+			attrs = append(attrs, "shape=note")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", n.ID, strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaid renders the graph as a Mermaid flowchart, e.g. for Markdown docs
+func writeMermaid(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		label := strings.ReplaceAll(n.Label, `"`, "#quot;")
+		switch n.Kind {
+		case nodeSource:
+			fmt.Fprintf(&b, "  %s[(\"%s\")]\n", n.ID, label)
+		case nodeVariable:
+			fmt.Fprintf(&b, "  %s(\"%s\")\n", n.ID, label)
+		default:
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", n.ID, label)
+		}
+		if n.Status != "" {
+			fmt.Fprintf(&b, "  class %s %s\n", n.ID, n.Status)
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", e.From, e.To)
+	}
+	b.WriteString("  classDef missing stroke:#d00,color:#d00\n")
+	b.WriteString("  classDef unused stroke:#888,color:#888,stroke-dasharray:4\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
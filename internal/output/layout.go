@@ -0,0 +1,47 @@
+package output
+
+import (
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	// maxSnippet is the length code snippets are cut to when the terminal width is unknown
+	maxSnippet = 80
+	// minSnippet is the least room a snippet gets next to its location before it moves to a line of its own
+	minSnippet = 20
+	// snippetIndent indents snippets moved below their location
+	snippetIndent = "     "
+)
+
+// terminalWidth returns the width of the terminal on stdout, or 0 when stdout isn't a terminal
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 0
+	}
+	return width
+}
+
+// ansiCodes matches the color escape codes of the text report
+var ansiCodes = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// visibleLen is the number of characters s takes up on screen, leaving out color codes
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(ansiCodes.ReplaceAllString(s, ""))
+}
+
+// cut shortens s to at most n characters, ending it with "..." when it's cut
+func cut(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
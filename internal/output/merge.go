@@ -0,0 +1,778 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/blame"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// ReadReport reads a JSON report written by envgrd scan --json
+func ReadReport(r io.Reader) (JSONOutput, error) {
+	var report JSONOutput
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return JSONOutput{}, fmt.Errorf("invalid JSON report: %w", err)
+	}
+	return report, nil
+}
+
+// MergeReports combines the JSON reports of separate scans (e.g., per-language shards or per-service
+// scans in parallel CI jobs) into one, deduplicating the findings several of them report:
+//   - findings of a variable are merged into one, with the union of their locations and owners and
+//     the highest severity
+//   - a variable is only unused when every report defining it finds it unused, so a variable read by
+//     another shard is not reported
+//   - a stale example key is only stale when every report comparing the same example files finds it,
+//     and an unread schema key when every report comparing the same schema finds it
+//   - ignored counts are added up, except ignored_unused (counted once per env file) which is the highest
+//
+// Stats, per-owner groups and comparisons are left out, as they can't be combined
+func MergeReports(reports []JSONOutput) JSONOutput {
+	merged := JSONOutput{
+		DynamicMatches:   map[string][]string{},
+		Unused:           []string{},
+		UnusedSeverities: map[string]config.Severity{},
+		UnusedLocations:  map[string]JSONLocation{},
+		UnusedOwners:     map[string][]string{},
+		ParseErrors:      []JSONParseError{},
+		Conflicts:        []JSONConflict{},
+		CaseMismatches:   []JSONCaseMismatch{},
+		Style:            []JSONStyleViolation{},
+		Deprecated:       []JSONDeprecated{},
+		TypeMismatches:   []JSONTypeMismatch{},
+		Placeholders:     []JSONPlaceholder{},
+		UnresolvedRefs:   []JSONUnresolvedReference{},
+		Forbidden:        []JSONForbidden{},
+	}
+
+	var missing, partial, optional, test, undocumented, undeclared, unprefixed, exposed [][]MissingVar
+	var examples, stale []string
+	var schemas, unread []string
+	unreadVars := make(map[string]MissingVar)
+	staleVars := make(map[string]MissingVar)
+	parseErrors := make(map[JSONParseError]bool)
+	conflicts := make(map[string]int)
+	mismatches := make(map[[2]string]int)
+	style := make(map[string]int)
+	deprecated := make(map[string]int)
+	typeMismatches := make(map[[2]string]int)
+	placeholders := make(map[string]int)
+	unresolvedRefs := make(map[JSONLocation]bool)
+	forbidden := make(map[string]int)
+	defined := make(map[string]bool)
+	for _, report := range reports {
+		missing = append(missing, report.Missing)
+		partial = append(partial, report.PartialMatches)
+		optional = append(optional, report.OptionalMissing)
+		test = append(test, report.TestMissing)
+
+		merged.IgnoredMissing += report.IgnoredMissing
+		merged.IgnoredUnused = max(merged.IgnoredUnused, report.IgnoredUnused)
+		merged.IgnoredFromFolders += report.IgnoredFromFolders
+		merged.IgnoredSystem += report.IgnoredSystem
+		merged.IgnoredTests += report.IgnoredTests
+		merged.IgnoredDynamic += report.IgnoredDynamic
+
+		for key, matches := range report.DynamicMatches {
+			merged.DynamicMatches[key] = union(merged.DynamicMatches[key], matches)
+		}
+		for _, key := range report.Defined {
+			defined[key] = true
+		}
+		for key, hint := range report.Remediation {
+			if merged.Remediation == nil {
+				merged.Remediation = make(map[string]string)
+			}
+			if _, seen := merged.Remediation[key]; !seen {
+				merged.Remediation[key] = hint
+			}
+		}
+		for _, key := range report.Unused {
+			if usedElsewhere(reports, key) {
+				continue
+			}
+			if !slices.Contains(merged.Unused, key) {
+				merged.Unused = append(merged.Unused, key)
+			}
+			if severity, ok := report.UnusedSeverities[key]; ok && severity.Rank() >= merged.UnusedSeverities[key].Rank() {
+				merged.UnusedSeverities[key] = severity
+			}
+			if location, ok := report.UnusedLocations[key]; ok {
+				if _, seen := merged.UnusedLocations[key]; !seen {
+					merged.UnusedLocations[key] = location
+				}
+			}
+			if owners := report.UnusedOwners[key]; len(owners) > 0 {
+				merged.UnusedOwners[key] = union(merged.UnusedOwners[key], owners)
+			}
+			if blame, ok := report.UnusedBlame[key]; ok {
+				if merged.UnusedBlame == nil {
+					merged.UnusedBlame = make(map[string]JSONBlame)
+				}
+				merged.UnusedBlame[key] = blame
+			}
+		}
+
+		if report.Fixed != nil {
+			if merged.Fixed == nil {
+				merged.Fixed = &JSONFixed{Missing: []string{}, Unused: []string{}, Dynamic: []string{}}
+			}
+			merged.Fixed.Missing = union(merged.Fixed.Missing, report.Fixed.Missing)
+			merged.Fixed.Unused = union(merged.Fixed.Unused, report.Fixed.Unused)
+			merged.Fixed.Dynamic = union(merged.Fixed.Dynamic, report.Fixed.Dynamic)
+		}
+
+		for _, parseError := range report.ParseErrors {
+			if !parseErrors[parseError] {
+				parseErrors[parseError] = true
+				merged.ParseErrors = append(merged.ParseErrors, parseError)
+			}
+		}
+
+		for _, conflict := range report.Conflicts {
+			i, seen := conflicts[conflict.Key]
+			if !seen {
+				conflicts[conflict.Key] = len(merged.Conflicts)
+				merged.Conflicts = append(merged.Conflicts, JSONConflict{Key: conflict.Key, Definitions: slices.Clone(conflict.Definitions)})
+				continue
+			}
+			for _, def := range conflict.Definitions {
+				if !slices.ContainsFunc(merged.Conflicts[i].Definitions, func(d JSONDefinition) bool {
+					return d.File == def.File && d.Line == def.Line && d.Environment == def.Environment
+				}) {
+					merged.Conflicts[i].Definitions = append(merged.Conflicts[i].Definitions, def)
+				}
+			}
+		}
+
+		for _, mismatch := range report.CaseMismatches {
+			id := [2]string{mismatch.Key, mismatch.Defined}
+			i, seen := mismatches[id]
+			if !seen {
+				mismatches[id] = len(merged.CaseMismatches)
+				merged.CaseMismatches = append(merged.CaseMismatches, JSONCaseMismatch{Key: mismatch.Key, Defined: mismatch.Defined, Usages: []string{}, Definitions: []string{}})
+				i = len(merged.CaseMismatches) - 1
+			}
+			merged.CaseMismatches[i].Usages = union(merged.CaseMismatches[i].Usages, mismatch.Usages)
+			merged.CaseMismatches[i].Definitions = union(merged.CaseMismatches[i].Definitions, mismatch.Definitions)
+		}
+
+		if drift := report.ExampleDrift; drift != nil {
+			examples = union(examples, drift.Examples)
+			undocumented = append(undocumented, drift.Undocumented)
+			for _, v := range drift.Stale {
+				if staleElsewhere(reports, drift.Examples, v.Key) {
+					stale = union(stale, []string{v.Key})
+					staleVars[v.Key] = mergeVar(staleVars[v.Key], v)
+				}
+			}
+		}
+
+		if drift := report.SchemaDrift; drift != nil {
+			schemas = union(schemas, []string{drift.Schema})
+			undeclared = append(undeclared, drift.Undeclared)
+			for _, v := range drift.Unread {
+				if unreadElsewhere(reports, drift.Schema, v.Key) {
+					unread = union(unread, []string{v.Key})
+					unreadVars[v.Key] = mergeVar(unreadVars[v.Key], v)
+				}
+			}
+		}
+
+		if frontend := report.Frontend; frontend != nil {
+			if merged.Frontend == nil {
+				merged.Frontend = &JSONFrontend{Framework: frontend.Framework, Prefix: frontend.Prefix}
+			}
+			unprefixed = append(unprefixed, frontend.Unprefixed)
+			exposed = append(exposed, frontend.Exposed)
+		}
+
+		for _, violation := range report.Style {
+			i, seen := style[violation.Key]
+			if !seen {
+				style[violation.Key] = len(merged.Style)
+				violation.Problems = slices.Clone(violation.Problems)
+				violation.Locations = slices.Clone(violation.Locations)
+				merged.Style = append(merged.Style, violation)
+				continue
+			}
+			existing := &merged.Style[i]
+			existing.Problems = union(existing.Problems, violation.Problems)
+			existing.Locations, existing.Truncated, existing.TotalLocations = mergeLocations(
+				existing.Locations, existing.Truncated, existing.TotalLocations,
+				violation.Locations, violation.Truncated, violation.TotalLocations)
+			existing.Severity = highestOf(existing.Severity, violation.Severity)
+		}
+
+		for _, dep := range report.Deprecated {
+			i, seen := deprecated[dep.Key]
+			if !seen {
+				deprecated[dep.Key] = len(merged.Deprecated)
+				dep.Usages = slices.Clone(dep.Usages)
+				dep.Definitions = slices.Clone(dep.Definitions)
+				merged.Deprecated = append(merged.Deprecated, dep)
+				continue
+			}
+			existing := &merged.Deprecated[i]
+			existing.Usages, existing.Truncated, existing.TotalUsages = mergeLocations(
+				existing.Usages, existing.Truncated, existing.TotalUsages,
+				dep.Usages, dep.Truncated, dep.TotalUsages)
+			existing.Definitions = union(existing.Definitions, dep.Definitions)
+			existing.Severity = highestOf(existing.Severity, dep.Severity)
+		}
+
+		for _, mismatch := range report.TypeMismatches {
+			i, seen := typeMismatches[[2]string{mismatch.Key, mismatch.Type}]
+			if !seen {
+				typeMismatches[[2]string{mismatch.Key, mismatch.Type}] = len(merged.TypeMismatches)
+				mismatch.Usages = slices.Clone(mismatch.Usages)
+				mismatch.Values = slices.Clone(mismatch.Values)
+				merged.TypeMismatches = append(merged.TypeMismatches, mismatch)
+				continue
+			}
+			existing := &merged.TypeMismatches[i]
+			existing.Usages, existing.Truncated, existing.TotalUsages = mergeLocations(
+				existing.Usages, existing.Truncated, existing.TotalUsages,
+				mismatch.Usages, mismatch.Truncated, mismatch.TotalUsages)
+			for _, value := range mismatch.Values {
+				if !slices.Contains(existing.Values, value) {
+					existing.Values = append(existing.Values, value)
+				}
+			}
+			existing.Severity = highestOf(existing.Severity, mismatch.Severity)
+		}
+
+		for _, placeholder := range report.Placeholders {
+			i, seen := placeholders[placeholder.Key]
+			if !seen {
+				placeholders[placeholder.Key] = len(merged.Placeholders)
+				placeholder.Usages = slices.Clone(placeholder.Usages)
+				merged.Placeholders = append(merged.Placeholders, placeholder)
+				continue
+			}
+			existing := &merged.Placeholders[i]
+			existing.Usages, existing.Truncated, existing.TotalUsages = mergeLocations(
+				existing.Usages, existing.Truncated, existing.TotalUsages,
+				placeholder.Usages, placeholder.Truncated, placeholder.TotalUsages)
+			existing.Severity = highestOf(existing.Severity, placeholder.Severity)
+		}
+
+		for _, ref := range report.UnresolvedRefs {
+			location := JSONLocation{File: ref.File, Line: ref.Line}
+			if !unresolvedRefs[location] {
+				unresolvedRefs[location] = true
+				merged.UnresolvedRefs = append(merged.UnresolvedRefs, ref)
+			}
+		}
+
+		for _, v := range report.Forbidden {
+			i, seen := forbidden[v.Key]
+			if !seen {
+				forbidden[v.Key] = len(merged.Forbidden)
+				v.Usages = slices.Clone(v.Usages)
+				v.Definitions = slices.Clone(v.Definitions)
+				merged.Forbidden = append(merged.Forbidden, v)
+				continue
+			}
+			existing := &merged.Forbidden[i]
+			existing.Usages, existing.Truncated, existing.TotalUsages = mergeLocations(
+				existing.Usages, existing.Truncated, existing.TotalUsages,
+				v.Usages, v.Truncated, v.TotalUsages)
+			existing.Definitions = union(existing.Definitions, v.Definitions)
+			existing.Severity = highestOf(existing.Severity, v.Severity)
+		}
+	}
+
+	merged.Missing = mergeVars(missing)
+	merged.PartialMatches = mergeVars(partial)
+	merged.OptionalMissing = mergeVars(optional)
+	merged.TestMissing = mergeVars(test)
+	sort.Strings(merged.Unused)
+	merged.Defined = make([]string, 0, len(defined))
+	for key := range defined {
+		merged.Defined = append(merged.Defined, key)
+	}
+	sort.Strings(merged.Defined)
+	sort.Slice(merged.ParseErrors, func(i, j int) bool {
+		return merged.ParseErrors[i].File < merged.ParseErrors[j].File
+	})
+	sort.Slice(merged.Conflicts, func(i, j int) bool {
+		return merged.Conflicts[i].Key < merged.Conflicts[j].Key
+	})
+	sort.Slice(merged.CaseMismatches, func(i, j int) bool {
+		return merged.CaseMismatches[i].Key < merged.CaseMismatches[j].Key
+	})
+	sort.Slice(merged.Style, func(i, j int) bool {
+		return merged.Style[i].Key < merged.Style[j].Key
+	})
+	sort.Slice(merged.Deprecated, func(i, j int) bool {
+		return merged.Deprecated[i].Key < merged.Deprecated[j].Key
+	})
+	sort.Slice(merged.TypeMismatches, func(i, j int) bool {
+		if merged.TypeMismatches[i].Key != merged.TypeMismatches[j].Key {
+			return merged.TypeMismatches[i].Key < merged.TypeMismatches[j].Key
+		}
+		return merged.TypeMismatches[i].Type < merged.TypeMismatches[j].Type
+	})
+	sort.Slice(merged.Placeholders, func(i, j int) bool {
+		return merged.Placeholders[i].Key < merged.Placeholders[j].Key
+	})
+	sort.Slice(merged.UnresolvedRefs, func(i, j int) bool {
+		a, b := merged.UnresolvedRefs[i], merged.UnresolvedRefs[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	sort.Slice(merged.Forbidden, func(i, j int) bool {
+		return merged.Forbidden[i].Key < merged.Forbidden[j].Key
+	})
+	if examples != nil {
+		merged.ExampleDrift = &JSONExampleDrift{Examples: examples, Undocumented: mergeVars(undocumented), Stale: []MissingVar{}}
+		for _, key := range stale {
+			merged.ExampleDrift.Stale = append(merged.ExampleDrift.Stale, staleVars[key])
+		}
+	}
+	if schemas != nil {
+		merged.SchemaDrift = &JSONSchemaDrift{Schema: strings.Join(schemas, ", "), Unread: []MissingVar{}, Undeclared: mergeVars(undeclared)}
+		for _, key := range unread {
+			merged.SchemaDrift.Unread = append(merged.SchemaDrift.Unread, unreadVars[key])
+		}
+	}
+	if merged.Frontend != nil {
+		merged.Frontend.Unprefixed = mergeVars(unprefixed)
+		merged.Frontend.Exposed = mergeVars(exposed)
+	}
+	if merged.Remediation != nil {
+		// Drop the hints of variables whose findings the merge resolved, like unused ones read by another report
+		reported := make(map[string]bool)
+		for _, keys := range ReportFindings(merged) {
+			for _, key := range keys {
+				reported[key] = true
+			}
+		}
+		for key := range merged.Remediation {
+			if !reported[key] {
+				delete(merged.Remediation, key)
+			}
+		}
+	}
+	merged.HighestSeverity = mergedSeverity(merged)
+	return merged
+}
+
+// usedElsewhere reports whether a report defining key doesn't find it unused, i.e. its code reads it
+func usedElsewhere(reports []JSONOutput, key string) bool {
+	for _, report := range reports {
+		if slices.Contains(report.Defined, key) && !slices.Contains(report.Unused, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// staleElsewhere reports whether every report comparing one of examples lists key as stale
+func staleElsewhere(reports []JSONOutput, examples []string, key string) bool {
+	for _, report := range reports {
+		drift := report.ExampleDrift
+		if drift == nil || !slices.ContainsFunc(drift.Examples, func(example string) bool { return slices.Contains(examples, example) }) {
+			continue
+		}
+		if !slices.ContainsFunc(drift.Stale, func(v MissingVar) bool { return v.Key == key }) {
+			return false
+		}
+	}
+	return true
+}
+
+// unreadElsewhere reports whether every report comparing schema lists key as unread
+func unreadElsewhere(reports []JSONOutput, schema string, key string) bool {
+	for _, report := range reports {
+		drift := report.SchemaDrift
+		if drift == nil || drift.Schema != schema {
+			continue
+		}
+		if !slices.ContainsFunc(drift.Unread, func(v MissingVar) bool { return v.Key == key }) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeVars merges the findings of several reports by variable, sorted by key
+func mergeVars(lists [][]MissingVar) []MissingVar {
+	byKey := make(map[string]MissingVar)
+	for _, vars := range lists {
+		for _, v := range vars {
+			byKey[v.Key] = mergeVar(byKey[v.Key], v)
+		}
+	}
+	merged := make([]MissingVar, 0, len(byKey))
+	for _, v := range byKey {
+		merged = append(merged, v)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Key < merged[j].Key
+	})
+	return merged
+}
+
+// mergeVar merges finding v into existing, which is empty for the first finding of a variable
+func mergeVar(existing MissingVar, v MissingVar) MissingVar {
+	if existing.Key == "" {
+		v.Owners = slices.Clone(v.Owners)
+		v.Locations = slices.Clone(v.Locations)
+		return v
+	}
+	existing.Severity = highestOf(existing.Severity, v.Severity)
+	existing.Required = existing.Required || v.Required
+	if confidenceRank[v.Confidence] > confidenceRank[existing.Confidence] {
+		existing.Confidence = v.Confidence
+	}
+	existing.Owners = union(existing.Owners, v.Owners)
+	existing.Locations, existing.Truncated, existing.TotalLocations = mergeLocations(
+		existing.Locations, existing.Truncated, existing.TotalLocations,
+		v.Locations, v.Truncated, v.TotalLocations)
+	return existing
+}
+
+// confidenceRank orders the confidences of dynamic patterns
+var confidenceRank = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// mergeLocations returns the union of two location lists, which may have been cut down to --max-locations
+// The total of truncated lists is a lower bound, as the cut locations can't be deduplicated
+func mergeLocations(a []string, aTruncated bool, aTotal int, b []string, bTruncated bool, bTotal int) ([]string, bool, int) {
+	locations := union(a, b)
+	if !aTruncated && !bTruncated {
+		return locations, false, 0
+	}
+	return locations, true, max(aTotal, bTotal, len(locations))
+}
+
+// union returns the sorted, deduplicated values of a and b
+func union(a []string, b []string) []string {
+	values := append(slices.Clone(a), b...)
+	sort.Strings(values)
+	return slices.Compact(values)
+}
+
+// highestOf returns the more severe of a and b
+func highestOf(a config.Severity, b config.Severity) config.Severity {
+	if b.Rank() > a.Rank() {
+		return b
+	}
+	return a
+}
+
+// mergedSeverity returns the highest severity of the findings of a merged report
+func mergedSeverity(report JSONOutput) config.Severity {
+	var highest config.Severity
+	vars := [][]MissingVar{report.Missing, report.PartialMatches, report.OptionalMissing, report.TestMissing}
+	if drift := report.ExampleDrift; drift != nil {
+		vars = append(vars, drift.Undocumented, drift.Stale)
+	}
+	if drift := report.SchemaDrift; drift != nil {
+		vars = append(vars, drift.Unread, drift.Undeclared)
+	}
+	if frontend := report.Frontend; frontend != nil {
+		vars = append(vars, frontend.Unprefixed, frontend.Exposed)
+	}
+	for _, list := range vars {
+		for _, v := range list {
+			highest = highestOf(highest, v.Severity)
+		}
+	}
+	for _, key := range report.Unused {
+		highest = highestOf(highest, report.UnusedSeverities[key])
+	}
+	for _, violation := range report.Style {
+		highest = highestOf(highest, violation.Severity)
+	}
+	for _, dep := range report.Deprecated {
+		highest = highestOf(highest, dep.Severity)
+	}
+	for _, mismatch := range report.TypeMismatches {
+		highest = highestOf(highest, mismatch.Severity)
+	}
+	for _, placeholder := range report.Placeholders {
+		highest = highestOf(highest, placeholder.Severity)
+	}
+	for _, ref := range report.UnresolvedRefs {
+		highest = highestOf(highest, ref.Severity)
+	}
+	for _, v := range report.Forbidden {
+		highest = highestOf(highest, v.Severity)
+	}
+	return highest
+}
+
+// WriteMergedText prints a merged report with the text reporter of envgrd scan
+// The values of the report were redacted by the scans, so they're shown as they are
+func WriteMergedText(w io.Writer, report JSONOutput, text TextReporter) error {
+	shown := RedactorFunc(func(_ string, value string) string { return value })
+	return text.Report(w, ReportResult(report), Options{Dynamic: true, Redactor: shown})
+}
+
+// ReportResult turns a JSON report back into the scan result it describes, so a merged report is
+// rendered and checked (see ExitCode) like a scan
+// Findings listing usages and definitions together tell them apart by the code snippet of usages,
+// and owners are only kept for unused variables
+func ReportResult(report JSONOutput) analyzer.ScanResult {
+	result := analyzer.ScanResult{
+		EnvKeys:            make(map[string]string, len(report.Defined)),
+		EnvKeySources:      map[string]string{},
+		EnvKeyLines:        map[string]int{},
+		EnvKeyOwners:       map[string][]string{},
+		Confidence:         map[string]analyzer.Confidence{},
+		DynamicMatches:     report.DynamicMatches,
+		Unused:             slices.Clone(report.Unused),
+		IgnoredMissing:     report.IgnoredMissing,
+		IgnoredUnused:      report.IgnoredUnused,
+		IgnoredFromFolders: report.IgnoredFromFolders,
+		IgnoredSystem:      report.IgnoredSystem,
+		IgnoredTests:       report.IgnoredTests,
+		IgnoredDynamic:     report.IgnoredDynamic,
+		Severities:         map[string]config.Severity{},
+		Remediation:        report.Remediation,
+	}
+	findings := func(vars []MissingVar) map[string][]analyzer.EnvUsage {
+		byKey := make(map[string][]analyzer.EnvUsage, len(vars))
+		for _, v := range vars {
+			byKey[v.Key] = reportUsages(v.Key, v.Locations)
+			result.Severities[v.Key] = v.Severity
+			if v.Required {
+				result.Required = append(result.Required, v.Key)
+			}
+			if v.Confidence != "" {
+				result.Confidence[v.Key] = analyzer.Confidence(v.Confidence)
+			}
+		}
+		return byKey
+	}
+	result.Missing = findings(report.Missing)
+	result.PartialMatches = findings(report.PartialMatches)
+	result.OptionalMissing = findings(report.OptionalMissing)
+	result.TestMissing = findings(report.TestMissing)
+	sort.Strings(result.Required)
+
+	for _, key := range report.Defined {
+		result.EnvKeys[key] = ""
+	}
+	for _, key := range report.Unused {
+		if severity, ok := report.UnusedSeverities[key]; ok {
+			result.Severities[key] = severity
+		}
+		if location, ok := report.UnusedLocations[key]; ok {
+			result.EnvKeySources[key] = location.File
+			result.EnvKeyLines[key] = location.Line
+		}
+		if owners := report.UnusedOwners[key]; len(owners) > 0 {
+			result.EnvKeyOwners[key] = owners
+		}
+		if line, ok := report.UnusedBlame[key]; ok {
+			if result.UnusedBlame == nil {
+				result.UnusedBlame = make(map[string]blame.Line)
+			}
+			result.UnusedBlame[key] = blame.Line{Commit: line.Commit, Author: line.Author, Time: line.Date}
+		}
+	}
+
+	if drift := report.ExampleDrift; drift != nil {
+		result.ExampleDrift = &analyzer.ExampleDrift{
+			Examples:     drift.Examples,
+			Undocumented: make(map[string][]analyzer.EnvUsage, len(drift.Undocumented)),
+			Definitions:  make(map[string][]analyzer.Definition),
+			Severities:   make(map[string]config.Severity),
+		}
+		for _, v := range drift.Undocumented {
+			result.ExampleDrift.Undocumented[v.Key], result.ExampleDrift.Definitions[v.Key] = splitLocations(v.Key, v.Locations)
+			result.ExampleDrift.Severities[v.Key] = v.Severity
+		}
+		for _, v := range drift.Stale {
+			result.ExampleDrift.Stale = append(result.ExampleDrift.Stale, v.Key)
+			result.ExampleDrift.Definitions[v.Key] = reportDefinitions(v.Locations)
+			result.ExampleDrift.Severities[v.Key] = v.Severity
+		}
+	}
+
+	if drift := report.SchemaDrift; drift != nil {
+		result.SchemaDrift = &analyzer.SchemaDrift{
+			Schema:     drift.Schema,
+			Undeclared: make(map[string][]analyzer.EnvUsage, len(drift.Undeclared)),
+			Severities: make(map[string]config.Severity),
+		}
+		for _, v := range drift.Unread {
+			result.SchemaDrift.Unread = append(result.SchemaDrift.Unread, v.Key)
+			result.SchemaDrift.Severities[v.Key] = v.Severity
+		}
+		for _, v := range drift.Undeclared {
+			result.SchemaDrift.Undeclared[v.Key] = reportUsages(v.Key, v.Locations)
+			result.SchemaDrift.Severities[v.Key] = v.Severity
+		}
+	}
+
+	if frontend := report.Frontend; frontend != nil {
+		result.Frontend = &analyzer.FrontendLeaks{
+			Framework:   frontend.Framework,
+			Prefix:      frontend.Prefix,
+			Unprefixed:  make(map[string][]analyzer.EnvUsage, len(frontend.Unprefixed)),
+			Exposed:     make(map[string][]analyzer.EnvUsage, len(frontend.Exposed)),
+			Definitions: make(map[string][]analyzer.Definition),
+			Severities:  make(map[string]config.Severity),
+		}
+		for _, v := range frontend.Unprefixed {
+			result.Frontend.Unprefixed[v.Key] = reportUsages(v.Key, v.Locations)
+			result.Frontend.Severities[v.Key] = v.Severity
+		}
+		for _, v := range frontend.Exposed {
+			result.Frontend.Exposed[v.Key], result.Frontend.Definitions[v.Key] = splitLocations(v.Key, v.Locations)
+			result.Frontend.Severities[v.Key] = v.Severity
+		}
+	}
+
+	for _, violation := range report.Style {
+		usages, definitions := splitLocations(violation.Key, violation.Locations)
+		result.Style = append(result.Style, analyzer.StyleViolation{
+			Key:         violation.Key,
+			Problems:    violation.Problems,
+			Severity:    violation.Severity,
+			Usages:      usages,
+			Definitions: definitions,
+		})
+	}
+	for _, deprecated := range report.Deprecated {
+		result.Deprecated = append(result.Deprecated, analyzer.DeprecatedVar{
+			Key:         deprecated.Key,
+			Hint:        deprecated.Hint,
+			Severity:    deprecated.Severity,
+			Usages:      reportUsages(deprecated.Key, deprecated.Usages),
+			Definitions: reportDefinitions(deprecated.Definitions),
+		})
+	}
+	for _, mismatch := range report.TypeMismatches {
+		definitions := make([]analyzer.Definition, 0, len(mismatch.Values))
+		for _, value := range mismatch.Values {
+			definitions = append(definitions, analyzer.Definition{File: value.File, Line: value.Line, Value: value.Value})
+		}
+		result.TypeMismatches = append(result.TypeMismatches, analyzer.TypeMismatch{
+			Key:         mismatch.Key,
+			Type:        mismatch.Type,
+			Severity:    mismatch.Severity,
+			Usages:      reportUsages(mismatch.Key, mismatch.Usages),
+			Definitions: definitions,
+		})
+	}
+	for _, placeholder := range report.Placeholders {
+		result.Placeholders = append(result.Placeholders, analyzer.PlaceholderValue{
+			Key:        placeholder.Key,
+			Definition: analyzer.Definition{File: placeholder.File, Line: placeholder.Line, Value: placeholder.Value},
+			Empty:      placeholder.Empty,
+			Severity:   placeholder.Severity,
+			Usages:     reportUsages(placeholder.Key, placeholder.Usages),
+		})
+	}
+	for _, ref := range report.UnresolvedRefs {
+		result.UnresolvedRefs = append(result.UnresolvedRefs, analyzer.UnresolvedReference{
+			Key:        ref.Key,
+			References: ref.References,
+			Definition: analyzer.Definition{File: ref.File, Line: ref.Line, Value: ref.Value},
+			Severity:   ref.Severity,
+		})
+	}
+	for _, forbidden := range report.Forbidden {
+		result.Forbidden = append(result.Forbidden, analyzer.ForbiddenVar{
+			Key:         forbidden.Key,
+			Message:     forbidden.Message,
+			Severity:    forbidden.Severity,
+			Usages:      reportUsages(forbidden.Key, forbidden.Usages),
+			Definitions: reportDefinitions(forbidden.Definitions),
+		})
+	}
+
+	for _, conflict := range report.Conflicts {
+		resolved := analyzer.Conflict{Key: conflict.Key}
+		for i, def := range conflict.Definitions {
+			resolved.Definitions = append(resolved.Definitions, analyzer.Definition{File: def.File, Line: def.Line, Environment: def.Environment, Value: def.Value})
+			if def.Effective {
+				resolved.Effective = i
+			}
+		}
+		result.Conflicts = append(result.Conflicts, resolved)
+	}
+	for _, mismatch := range report.CaseMismatches {
+		result.CaseMismatches = append(result.CaseMismatches, analyzer.CaseMismatch{
+			Key:         mismatch.Key,
+			Defined:     mismatch.Defined,
+			Usages:      reportUsages(mismatch.Key, mismatch.Usages),
+			Definitions: reportDefinitions(mismatch.Definitions),
+		})
+	}
+	for _, parseError := range report.ParseErrors {
+		result.ParseErrors = append(result.ParseErrors, analyzer.ParseError{File: parseError.File, Language: parseError.Language, Error: parseError.Error})
+	}
+
+	if fixed := report.Fixed; fixed != nil {
+		result.Fixed = &analyzer.FixedFindings{Missing: fixed.Missing, Unused: fixed.Unused, Dynamic: fixed.Dynamic}
+	}
+	if policy := report.Policy; policy != nil {
+		result.Policy = &config.Policy{Source: policy.Source, Checksum: policy.SHA256}
+	}
+	return result
+}
+
+// usageLocation matches the "file:line (snippet)" locations of usages, see usageLocations
+var usageLocation = regexp.MustCompile(`^(.*?):(\d+)(?: \((.*)\))?$`)
+
+// definitionLocation matches the "file:line [environment]" locations of definitions, see definitionLocations
+var definitionLocation = regexp.MustCompile(`^(.*?)(?::(\d+))?(?: \[(.*)\])?$`)
+
+// reportUsages parses the usage locations of a finding for key
+func reportUsages(key string, locations []string) []analyzer.EnvUsage {
+	usages := make([]analyzer.EnvUsage, 0, len(locations))
+	for _, location := range locations {
+		usage := analyzer.EnvUsage{Key: key, File: location}
+		if m := usageLocation.FindStringSubmatch(location); m != nil {
+			usage.File, usage.CodeSnippet = m[1], m[3]
+			usage.Line, _ = strconv.Atoi(m[2])
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// reportDefinitions parses the definition locations of a finding
+func reportDefinitions(locations []string) []analyzer.Definition {
+	definitions := make([]analyzer.Definition, 0, len(locations))
+	for _, location := range locations {
+		m := definitionLocation.FindStringSubmatch(location)
+		def := analyzer.Definition{File: m[1], Environment: m[3]}
+		def.Line, _ = strconv.Atoi(m[2])
+		definitions = append(definitions, def)
+	}
+	return definitions
+}
+
+// splitLocations parses the locations of a finding listing code usages and env file definitions together
+// Usages are the locations with a code snippet, definitions have none
+func splitLocations(key string, locations []string) ([]analyzer.EnvUsage, []analyzer.Definition) {
+	var usages, definitions []string
+	for _, location := range locations {
+		if m := usageLocation.FindStringSubmatch(location); m != nil && m[3] != "" {
+			usages = append(usages, location)
+		} else {
+			definitions = append(definitions, location)
+		}
+	}
+	return reportUsages(key, usages), reportDefinitions(definitions)
+}
@@ -0,0 +1,182 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+func TestMergeReports(t *testing.T) {
+	js := JSONOutput{
+		Missing: []MissingVar{
+			{Key: "JS_ONLY", Severity: config.SeverityWarning, Locations: []string{"src/app.js:2"}},
+			{Key: "SHARED", Severity: config.SeverityWarning, Locations: []string{"src/app.js:3"}},
+		},
+		Unused:           []string{"GO_ONLY", "UNUSED"},
+		Defined:          []string{"GO_ONLY", "JS_USED", "UNUSED"},
+		UnusedSeverities: map[string]config.Severity{"GO_ONLY": config.SeverityWarning, "UNUSED": config.SeverityWarning},
+		UnusedLocations:  map[string]JSONLocation{"GO_ONLY": {File: ".env", Line: 1}, "UNUSED": {File: ".env", Line: 3}},
+		IgnoredMissing:   1,
+		IgnoredUnused:    2,
+		ParseErrors:      []JSONParseError{{File: "broken.js", Language: "javascript", Error: "syntax error"}},
+	}
+	golang := JSONOutput{
+		Missing: []MissingVar{
+			{Key: "SHARED", Severity: config.SeverityError, Locations: []string{"main.go:5", "src/app.js:3"}},
+		},
+		Unused:           []string{"JS_USED", "UNUSED"},
+		Defined:          []string{"GO_ONLY", "JS_USED", "UNUSED"},
+		UnusedSeverities: map[string]config.Severity{"JS_USED": config.SeverityWarning, "UNUSED": config.SeverityWarning},
+		IgnoredMissing:   2,
+		IgnoredUnused:    2,
+		ParseErrors:      []JSONParseError{{File: "broken.js", Language: "javascript", Error: "syntax error"}},
+	}
+	// Another service, defining variables in its own env file
+	service := JSONOutput{
+		Unused:           []string{"BILLING_KEY"},
+		Defined:          []string{"BILLING_KEY"},
+		UnusedSeverities: map[string]config.Severity{"BILLING_KEY": config.SeverityInfo},
+	}
+
+	merged := MergeReports([]JSONOutput{js, golang, service})
+
+	if len(merged.Missing) != 2 || merged.Missing[0].Key != "JS_ONLY" || merged.Missing[1].Key != "SHARED" {
+		t.Fatalf("Expected JS_ONLY and SHARED to be missing, got %+v", merged.Missing)
+	}
+	shared := merged.Missing[1]
+	if shared.Severity != config.SeverityError || !reflect.DeepEqual(shared.Locations, []string{"main.go:5", "src/app.js:3"}) {
+		t.Errorf("Expected SHARED with the highest severity and deduplicated locations, got %+v", shared)
+	}
+	// GO_ONLY and JS_USED are read by the other shard
+	if !reflect.DeepEqual(merged.Unused, []string{"BILLING_KEY", "UNUSED"}) {
+		t.Errorf("Expected only BILLING_KEY and UNUSED to be unused, got %v", merged.Unused)
+	}
+	if merged.UnusedLocations["UNUSED"] != (JSONLocation{File: ".env", Line: 3}) {
+		t.Errorf("Expected the location of UNUSED, got %+v", merged.UnusedLocations)
+	}
+	if !reflect.DeepEqual(merged.Defined, []string{"BILLING_KEY", "GO_ONLY", "JS_USED", "UNUSED"}) {
+		t.Errorf("Expected every defined variable, got %v", merged.Defined)
+	}
+	if merged.IgnoredMissing != 3 || merged.IgnoredUnused != 2 {
+		t.Errorf("Expected ignored counts 3 and 2, got %d and %d", merged.IgnoredMissing, merged.IgnoredUnused)
+	}
+	if len(merged.ParseErrors) != 1 {
+		t.Errorf("Expected the parse error once, got %+v", merged.ParseErrors)
+	}
+	if merged.HighestSeverity != config.SeverityError {
+		t.Errorf("Expected highest severity error, got %q", merged.HighestSeverity)
+	}
+}
+
+func TestMergeReports_StaleExamples(t *testing.T) {
+	drift := func(stale ...string) *JSONExampleDrift {
+		d := &JSONExampleDrift{Examples: []string{".env.example"}}
+		for _, key := range stale {
+			d.Stale = append(d.Stale, MissingVar{Key: key, Severity: config.SeverityWarning, Locations: []string{".env.example:1"}})
+		}
+		return d
+	}
+
+	merged := MergeReports([]JSONOutput{{ExampleDrift: drift("OLD", "GO_USED")}, {ExampleDrift: drift("OLD")}})
+
+	if len(merged.ExampleDrift.Stale) != 1 || merged.ExampleDrift.Stale[0].Key != "OLD" {
+		t.Errorf("Expected only OLD to be stale, got %+v", merged.ExampleDrift.Stale)
+	}
+}
+
+func TestWriteMergedText(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMergedText(&b, MergeReports(nil), TextReporter{}); err != nil {
+		t.Fatalf("WriteMergedText failed: %v", err)
+	}
+	if !strings.Contains(b.String(), "No issues found") {
+		t.Errorf("Expected no issues, got %q", b.String())
+	}
+
+	b.Reset()
+	report := JSONOutput{
+		Missing:          []MissingVar{{Key: "API_KEY", Severity: config.SeverityError, Locations: []string{"src/app.js:1 (process.env.API_KEY)"}}},
+		Unused:           []string{"OLD"},
+		Defined:          []string{"OLD"},
+		UnusedSeverities: map[string]config.Severity{"OLD": config.SeverityWarning},
+		UnusedLocations:  map[string]JSONLocation{"OLD": {File: ".env", Line: 2}},
+		Placeholders:     []JSONPlaceholder{{Key: "TOKEN", Severity: config.SeverityWarning, File: ".env", Line: 3, Value: "ch****me"}},
+	}
+	if err := WriteMergedText(&b, report, TextReporter{}); err != nil {
+		t.Fatalf("WriteMergedText failed: %v", err)
+	}
+	for _, want := range []string{
+		"Missing environment variables:\n\n  API_KEY\n    used in: src/app.js:1 process.env.API_KEY\n",
+		"Unused variables:\n\n  OLD (in .env:2)\n",
+		// Values were redacted by the scan
+		"TOKEN=ch****me (.env:3) placeholder",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, b.String())
+		}
+	}
+}
+
+func TestReportResult(t *testing.T) {
+	usage := func(key string, file string, line int) analyzer.EnvUsage {
+		return analyzer.EnvUsage{Key: key, File: file, Line: line, CodeSnippet: `os.Getenv("` + key + `")`}
+	}
+	result := analyzer.ScanResult{
+		EnvKeys:        map[string]string{"OLD": "", "PORT": "abc", "LEGACY": "1"},
+		EnvKeySources:  map[string]string{"OLD": "config/.env"},
+		EnvKeyLines:    map[string]int{"OLD": 4},
+		Missing:        map[string][]analyzer.EnvUsage{"API_KEY": {usage("API_KEY", "main.go", 3)}, "DB_URL": {}},
+		PartialMatches: map[string][]analyzer.EnvUsage{`"APP_" + name`: {usage(`"APP_" + name`, "app.go", 9)}},
+		Confidence:     map[string]analyzer.Confidence{`"APP_" + name`: analyzer.ConfidenceHigh},
+		Unused:         []string{"OLD"},
+		Required:       []string{"DB_URL"},
+		Severities:     map[string]config.Severity{"API_KEY": config.SeverityWarning},
+		IgnoredSystem:  2,
+		ExampleDrift: &analyzer.ExampleDrift{
+			Examples:     []string{".env.example"},
+			Undocumented: map[string][]analyzer.EnvUsage{"API_KEY": {usage("API_KEY", "main.go", 3)}},
+			Stale:        []string{"GONE"},
+			Definitions: map[string][]analyzer.Definition{
+				"API_KEY": {{File: ".env.local", Line: 1}},
+				"GONE":    {{File: ".env.example", Line: 7}},
+			},
+		},
+		Deprecated: []analyzer.DeprecatedVar{{
+			Key: "LEGACY", Hint: "use MODERN", Severity: config.SeverityError,
+			Usages:      []analyzer.EnvUsage{usage("LEGACY", "main.go", 5)},
+			Definitions: []analyzer.Definition{{File: "ecosystem.config.js", Line: 2, Environment: "production"}},
+		}},
+		TypeMismatches: []analyzer.TypeMismatch{{
+			Key: "PORT", Type: "integer", Severity: config.SeverityError,
+			Usages:      []analyzer.EnvUsage{usage("PORT", "main.go", 6)},
+			Definitions: []analyzer.Definition{{File: ".env", Line: 1, Value: "abc"}},
+		}},
+		Conflicts: []analyzer.Conflict{{
+			Key:         "PORT",
+			Definitions: []analyzer.Definition{{File: ".env", Line: 1, Value: "abc"}, {File: ".env.local", Line: 2, Value: "80"}},
+			Effective:   1,
+		}},
+		ParseErrors: []analyzer.ParseError{{File: "broken.js", Language: "javascript", Error: "syntax error"}},
+		Remediation: map[string]string{"API_KEY": "ask the platform team"},
+	}
+	opts := Options{Dynamic: true, Redactor: RedactorFunc(func(_ string, value string) string { return value })}
+
+	var want, got strings.Builder
+	if err := formatHumanReadable(&want, result, opts, false, 0); err != nil {
+		t.Fatalf("formatHumanReadable failed: %v", err)
+	}
+	if err := formatHumanReadable(&got, ReportResult(buildJSONOutput(result, opts)), opts, false, 0); err != nil {
+		t.Fatalf("formatHumanReadable failed: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("Report of the JSON output =\n%s\nwant\n%s", got.String(), want.String())
+	}
+
+	failOn := FailOn{Missing: true, Deprecated: true}
+	if code := ExitCode(ReportResult(buildJSONOutput(result, opts)), failOn, false, true); code != ExitCode(result, failOn, false, true) {
+		t.Errorf("Expected the exit code of the scan, got %d", code)
+	}
+}
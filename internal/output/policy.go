@@ -0,0 +1,224 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// Exit codes returned by the CLI
+const (
+	ExitOK            = 0
+	ExitMissing       = 2  // Missing variables are the most severe failing findings
+	ExitUnused        = 3  // Unused variables are the most severe failing findings
+	ExitDynamic       = 4  // Dynamic patterns are the most severe failing findings
+	ExitParseErrors   = 5  // Some files could not be analyzed (with --strict-parse)
+	ExitExampleDrift  = 6  // Example env files drifted from code (undocumented or stale variables) are the most severe failing findings
+	ExitFrontend      = 7  // Client-side variables without the public prefix, or secrets exposed with it, are the most severe failing findings
+	ExitStyle         = 8  // Names breaking naming-convention rules are the most severe failing findings
+	ExitDeprecated    = 9  // Deprecated variables still in use are the most severe failing findings
+	ExitInternalError = 10 // The scan could not complete
+	ExitSchema        = 11 // Variables drifted from the schema (unread or undeclared) are the most severe failing findings
+	ExitType          = 12 // Env file values code can't parse as the type it expects are the most severe failing findings
+	ExitPlaceholder   = 13 // Variables with empty or placeholder values are the most severe failing findings
+	ExitReference     = 14 // Env file values with unresolved ${VAR} references are the most severe failing findings
+	ExitForbidden     = 15 // Forbidden variables used or defined are the most severe failing findings
+)
+
+// FailOn selects which finding categories make a run fail
+type FailOn struct {
+	Missing     bool
+	Unused      bool
+	Dynamic     bool
+	Example     bool // Undocumented and stale variables of example env files
+	Schema      bool // Unread and undeclared variables of the schema
+	Frontend    bool // Unprefixed and exposed variables of client-side code
+	Style       bool // Names breaking naming-convention rules
+	Deprecated  bool // Deprecated variables still used or defined
+	Type        bool // Env file values code can't parse as the type it expects
+	Placeholder bool // Variables read in code with empty or placeholder values
+	Reference   bool // Env file values with ${VAR} references to undefined variables
+	Forbidden   bool // Forbidden variables used or defined
+}
+
+// FailOnAny fails on every category (the default)
+var FailOnAny = FailOn{Missing: true, Unused: true, Dynamic: true, Example: true, Schema: true, Frontend: true, Style: true, Deprecated: true, Type: true, Placeholder: true, Reference: true, Forbidden: true}
+
+// ParseFailOn parses --fail-on values: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference, forbidden, any or none
+// Values may be repeated or comma-separated; an empty list means "any"
+func ParseFailOn(values []string) (FailOn, error) {
+	if len(values) == 0 {
+		return FailOnAny, nil
+	}
+
+	var failOn FailOn
+	for _, value := range values {
+		for _, category := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(category)) {
+			case "missing":
+				failOn.Missing = true
+			case "unused":
+				failOn.Unused = true
+			case "dynamic":
+				failOn.Dynamic = true
+			case "example":
+				failOn.Example = true
+			case "schema":
+				failOn.Schema = true
+			case "frontend":
+				failOn.Frontend = true
+			case "style":
+				failOn.Style = true
+			case "deprecated":
+				failOn.Deprecated = true
+			case "type":
+				failOn.Type = true
+			case "placeholder":
+				failOn.Placeholder = true
+			case "reference":
+				failOn.Reference = true
+			case "forbidden":
+				failOn.Forbidden = true
+			case "any":
+				failOn = FailOnAny
+			case "none":
+				failOn = FailOn{}
+			default:
+				return FailOn{}, fmt.Errorf("unknown --fail-on category %q (supported: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference, forbidden, any, none)", category)
+			}
+		}
+	}
+	return failOn, nil
+}
+
+// failCategory is a category of findings that can fail a run
+type failCategory struct {
+	code    int                      // Exit code when its findings are the most severe failing ones
+	enabled func(failOn FailOn) bool // Whether --fail-on includes it
+	// severities returns the severities of its reported findings
+	severities func(result analyzer.ScanResult, skipUnused bool, dynamic bool) []config.Severity
+}
+
+// failCategories are the categories of findings in precedence order: at equal severity, the earlier one picks the exit code
+var failCategories = []failCategory{
+	{ExitMissing, func(f FailOn) bool { return f.Missing }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return slices.Concat(keySeverities(r.SeverityOf, config.CategoryMissing, mapKeys(r.Missing)),
+			keySeverities(r.SeverityOf, config.CategoryOptional, mapKeys(r.OptionalMissing)),
+			keySeverities(r.SeverityOf, config.CategoryTest, mapKeys(r.TestMissing)))
+	}},
+	{ExitDynamic, func(f FailOn) bool { return f.Dynamic }, func(r analyzer.ScanResult, _, dynamic bool) []config.Severity {
+		if !dynamic {
+			return nil
+		}
+		return keySeverities(r.SeverityOf, config.CategoryDynamic, mapKeys(r.PartialMatches))
+	}},
+	{ExitUnused, func(f FailOn) bool { return f.Unused }, func(r analyzer.ScanResult, skipUnused, _ bool) []config.Severity {
+		if skipUnused {
+			return nil
+		}
+		return keySeverities(r.SeverityOf, config.CategoryUnused, r.Unused)
+	}},
+	{ExitExampleDrift, func(f FailOn) bool { return f.Example }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		if r.ExampleDrift == nil {
+			return nil
+		}
+		return slices.Concat(keySeverities(r.ExampleDrift.SeverityOf, config.CategoryUndocumented, mapKeys(r.ExampleDrift.Undocumented)),
+			keySeverities(r.ExampleDrift.SeverityOf, config.CategoryStale, r.ExampleDrift.Stale))
+	}},
+	{ExitSchema, func(f FailOn) bool { return f.Schema }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		if r.SchemaDrift == nil {
+			return nil
+		}
+		return slices.Concat(keySeverities(r.SchemaDrift.SeverityOf, config.CategoryUnread, r.SchemaDrift.Unread),
+			keySeverities(r.SchemaDrift.SeverityOf, config.CategoryUndeclared, mapKeys(r.SchemaDrift.Undeclared)))
+	}},
+	{ExitFrontend, func(f FailOn) bool { return f.Frontend }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		if r.Frontend == nil {
+			return nil
+		}
+		return slices.Concat(keySeverities(r.Frontend.SeverityOf, config.CategoryUnprefixed, mapKeys(r.Frontend.Unprefixed)),
+			keySeverities(r.Frontend.SeverityOf, config.CategoryExposed, mapKeys(r.Frontend.Exposed)))
+	}},
+	{ExitStyle, func(f FailOn) bool { return f.Style }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return findingSeverities(r.Style, func(v analyzer.StyleViolation) config.Severity { return v.Severity })
+	}},
+	{ExitDeprecated, func(f FailOn) bool { return f.Deprecated }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return findingSeverities(r.Deprecated, func(d analyzer.DeprecatedVar) config.Severity { return d.Severity })
+	}},
+	{ExitType, func(f FailOn) bool { return f.Type }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return findingSeverities(r.TypeMismatches, func(m analyzer.TypeMismatch) config.Severity { return m.Severity })
+	}},
+	{ExitPlaceholder, func(f FailOn) bool { return f.Placeholder }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return findingSeverities(r.Placeholders, func(p analyzer.PlaceholderValue) config.Severity { return p.Severity })
+	}},
+	{ExitReference, func(f FailOn) bool { return f.Reference }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return findingSeverities(r.UnresolvedRefs, func(u analyzer.UnresolvedReference) config.Severity { return u.Severity })
+	}},
+	{ExitForbidden, func(f FailOn) bool { return f.Forbidden }, func(r analyzer.ScanResult, _, _ bool) []config.Severity {
+		return findingSeverities(r.Forbidden, func(f analyzer.ForbiddenVar) config.Severity { return f.Severity })
+	}},
+}
+
+// keySeverities returns the severities of the findings of a category, keyed by variable
+func keySeverities(severityOf func(category string, key string) config.Severity, category string, keys []string) []config.Severity {
+	severities := make([]config.Severity, len(keys))
+	for i, key := range keys {
+		severities[i] = severityOf(category, key)
+	}
+	return severities
+}
+
+// findingSeverities returns the severities of findings that carry their own
+func findingSeverities[T any](findings []T, severity func(T) config.Severity) []config.Severity {
+	severities := make([]config.Severity, len(findings))
+	for i, finding := range findings {
+		severities[i] = severity(finding)
+	}
+	return severities
+}
+
+// ExitCode returns the exit code for a result under the given policy
+// Failing findings are the reported ones of failOn's categories above info severity
+// The category of the most severe failing finding picks the code; optional and test-only variables count as missing
+// At equal severity the category listed first in failCategories wins, from missing, dynamic and unused down to forbidden
+func ExitCode(result analyzer.ScanResult, failOn FailOn, skipUnused bool, dynamic bool) int {
+	code := ExitOK
+	highest := config.SeverityInfo.Rank()
+	for _, category := range failCategories {
+		if !category.enabled(failOn) {
+			continue
+		}
+		for _, severity := range category.severities(result, skipUnused, dynamic) {
+			if rank := severity.Rank(); rank > highest {
+				highest = rank
+				code = category.code
+			}
+		}
+	}
+	return code
+}
+
+// HighestSeverity returns the most severe reported finding, or "" when nothing is reported
+func HighestSeverity(result analyzer.ScanResult, skipUnused bool, dynamic bool) config.Severity {
+	var highest config.Severity
+	for _, category := range failCategories {
+		for _, severity := range category.severities(result, skipUnused, dynamic) {
+			if severity.Rank() > highest.Rank() {
+				highest = severity
+			}
+		}
+	}
+	return highest
+}
+
+// mapKeys returns the keys of a findings map
+func mapKeys(findings map[string][]analyzer.EnvUsage) []string {
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,153 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+func TestParseFailOn(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    FailOn
+		wantErr bool
+	}{
+		{nil, FailOnAny, false},
+		{[]string{"any"}, FailOnAny, false},
+		{[]string{"none"}, FailOn{}, false},
+		{[]string{"missing"}, FailOn{Missing: true}, false},
+		{[]string{"missing,dynamic"}, FailOn{Missing: true, Dynamic: true}, false},
+		{[]string{"Unused", "missing"}, FailOn{Missing: true, Unused: true}, false},
+		{[]string{"example"}, FailOn{Example: true}, false},
+		{[]string{"frontend,missing"}, FailOn{Missing: true, Frontend: true}, false},
+		{[]string{"type"}, FailOn{Type: true}, false},
+		{[]string{"placeholder,type"}, FailOn{Type: true, Placeholder: true}, false},
+		{[]string{"reference"}, FailOn{Reference: true}, false},
+		{[]string{"forbidden,reference"}, FailOn{Reference: true, Forbidden: true}, false},
+		{[]string{"everything"}, FailOn{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFailOn(tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFailOn(%v) error = %v, wantErr %v", tt.values, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFailOn(%v) = %+v, want %+v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	full := testResult()
+	unusedOnly := analyzer.ScanResult{Unused: []string{"UNUSED_VAR"}}
+	dynamicAndUnused := analyzer.ScanResult{PartialMatches: full.PartialMatches, Unused: full.Unused}
+	dynamicWarning := dynamicAndUnused
+	dynamicWarning.Severities = map[string]config.Severity{"PREFIX_": config.SeverityWarning}
+	unusedError := full
+	unusedError.Severities = map[string]config.Severity{"MISSING_VAR": config.SeverityWarning, "UNUSED_VAR": config.SeverityError}
+	dynamicOnly := analyzer.ScanResult{PartialMatches: full.PartialMatches}
+	optionalOnly := analyzer.ScanResult{OptionalMissing: map[string][]analyzer.EnvUsage{"PORT": {{Key: "PORT", IsOptional: true}}}}
+	optionalError := optionalOnly
+	optionalError.Severities = map[string]config.Severity{"PORT": config.SeverityError}
+	exampleDrift := analyzer.ScanResult{ExampleDrift: &analyzer.ExampleDrift{Stale: []string{"OLD_FLAG"}}}
+	schemaDrift := analyzer.ScanResult{SchemaDrift: &analyzer.SchemaDrift{Unread: []string{"OLD_FLAG"}}}
+	style := analyzer.ScanResult{Style: []analyzer.StyleViolation{{Key: "apiKey", Severity: config.SeverityWarning}}}
+	deprecated := analyzer.ScanResult{Deprecated: []analyzer.DeprecatedVar{{Key: "OLD_DB_URL", Severity: config.SeverityWarning}}}
+	typeMismatch := analyzer.ScanResult{TypeMismatches: []analyzer.TypeMismatch{{Key: "PORT", Type: "integer", Severity: config.SeverityError}}}
+	placeholder := analyzer.ScanResult{Placeholders: []analyzer.PlaceholderValue{{Key: "API_KEY", Severity: config.SeverityWarning}}}
+	reference := analyzer.ScanResult{UnresolvedRefs: []analyzer.UnresolvedReference{{Key: "DATABASE_URL", References: []string{"DB_HOST"}, Severity: config.SeverityWarning}}}
+	forbidden := analyzer.ScanResult{Forbidden: []analyzer.ForbiddenVar{{Key: "AWS_SECRET_ACCESS_KEY", Severity: config.SeverityError}}}
+	frontend := analyzer.ScanResult{Frontend: &analyzer.FrontendLeaks{Exposed: map[string][]analyzer.EnvUsage{"VITE_SECRET": {}}}}
+
+	tests := []struct {
+		name       string
+		result     analyzer.ScanResult
+		failOn     FailOn
+		skipUnused bool
+		dynamic    bool
+		want       int
+	}{
+		{"missing wins", full, FailOnAny, false, true, ExitMissing},
+		{"dynamic is info by default", dynamicAndUnused, FailOnAny, false, true, ExitUnused},
+		{"info never fails", dynamicOnly, FailOnAny, false, true, ExitOK},
+		{"optional is info by default", optionalOnly, FailOnAny, false, true, ExitOK},
+		{"optional counts as missing", optionalError, FailOn{Missing: true}, false, true, ExitMissing},
+		{"dynamic over unused at equal severity", dynamicWarning, FailOnAny, false, true, ExitDynamic},
+		{"highest severity wins", unusedError, FailOnAny, false, true, ExitUnused},
+		{"unused only", unusedOnly, FailOnAny, false, true, ExitUnused},
+		{"missing only policy ignores unused", unusedOnly, FailOn{Missing: true}, false, true, ExitOK},
+		{"unused policy skips missing", full, FailOn{Unused: true}, false, true, ExitUnused},
+		{"skip unused", unusedOnly, FailOnAny, true, true, ExitOK},
+		{"dynamic disabled", dynamicWarning, FailOn{Dynamic: true}, false, false, ExitOK},
+		{"example drift", exampleDrift, FailOnAny, false, true, ExitExampleDrift},
+		{"example drift ignored", exampleDrift, FailOn{Missing: true}, false, true, ExitOK},
+		{"frontend", frontend, FailOnAny, false, true, ExitFrontend},
+		{"frontend ignored", frontend, FailOn{Example: true}, false, true, ExitOK},
+		{"schema", schemaDrift, FailOnAny, false, true, ExitSchema},
+		{"schema ignored", schemaDrift, FailOn{Example: true}, false, true, ExitOK},
+		{"style", style, FailOnAny, false, true, ExitStyle},
+		{"style ignored", style, FailOn{Missing: true}, false, true, ExitOK},
+		{"deprecated", deprecated, FailOnAny, false, true, ExitDeprecated},
+		{"deprecated info", analyzer.ScanResult{Deprecated: []analyzer.DeprecatedVar{{Key: "OLD", Severity: config.SeverityInfo}}}, FailOnAny, false, true, ExitOK},
+		{"type mismatch", typeMismatch, FailOnAny, false, true, ExitType},
+		{"type mismatch ignored", typeMismatch, FailOn{Deprecated: true}, false, true, ExitOK},
+		{"placeholder", placeholder, FailOnAny, false, true, ExitPlaceholder},
+		{"placeholder ignored", placeholder, FailOn{Type: true}, false, true, ExitOK},
+		{"reference", reference, FailOnAny, false, true, ExitReference},
+		{"reference ignored", reference, FailOn{Placeholder: true}, false, true, ExitOK},
+		{"forbidden", forbidden, FailOnAny, false, true, ExitForbidden},
+		{"forbidden ignored", forbidden, FailOn{Reference: true}, false, true, ExitOK},
+		{"none", full, FailOn{}, false, true, ExitOK},
+		{"clean", analyzer.ScanResult{}, FailOnAny, false, true, ExitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.result, tt.failOn, tt.skipUnused, tt.dynamic); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighestSeverity(t *testing.T) {
+	result := testResult()
+	if got := HighestSeverity(result, false, true); got != config.SeverityError {
+		t.Errorf("Expected error, got %q", got)
+	}
+
+	result.Missing = nil
+	if got := HighestSeverity(result, false, true); got != config.SeverityWarning {
+		t.Errorf("Expected warning, got %q", got)
+	}
+	if got := HighestSeverity(result, true, true); got != config.SeverityInfo {
+		t.Errorf("Expected info with unused skipped, got %q", got)
+	}
+	if got := HighestSeverity(result, true, false); got != "" {
+		t.Errorf("Expected no severity, got %q", got)
+	}
+}
+
+func TestFailCategories(t *testing.T) {
+	// Every --fail-on category has an entry, with its own exit code
+	codes := make(map[int]bool)
+	for _, category := range failCategories {
+		if !category.enabled(FailOnAny) || category.enabled(FailOn{}) {
+			t.Errorf("Expected exit code %d to follow --fail-on", category.code)
+		}
+		if codes[category.code] {
+			t.Errorf("Exit code %d is listed twice", category.code)
+		}
+		codes[category.code] = true
+		if severities := category.severities(analyzer.ScanResult{}, false, true); len(severities) != 0 {
+			t.Errorf("Expected no findings in an empty result for exit code %d, got %v", category.code, severities)
+		}
+	}
+	if want := reflect.TypeOf(FailOn{}).NumField(); len(codes) != want {
+		t.Errorf("Expected the %d --fail-on categories, got %d", want, len(codes))
+	}
+}
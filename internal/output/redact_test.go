@@ -0,0 +1,57 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/jenian/envgrd/internal/config"
+)
+
+func TestRedactor(t *testing.T) {
+	rules := []config.RedactionRule{
+		{Keys: []string{"*_SECRET"}, Action: config.RedactHide},
+		{Keys: []string{"PUBLIC_URL"}, Action: config.RedactShow},
+		{Values: `^sk_live_`, Action: config.RedactHide},
+	}
+	tests := []struct {
+		mode  string
+		key   string
+		value string
+		want  string
+	}{
+		{"", "NODE_ENV", "production", "production"},
+		{"", "PORT", "3000", "3000"},
+		{"", "DEBUG", "True", "True"},
+		{"", "DB_PASSWORD", "hunter2", "[REDACTED]"},
+		{"", "API_KEY", "abc", "[REDACTED]"},
+		{"", "DATABASE_URL", "postgres://app:s3cret@db:5432/app", "[REDACTED]"},
+		{"", "PUBLIC_URL", "https://example.com/app", "https://example.com/app"},
+		{"", "GREETING", "Hello, Bob", "H...b"},
+		{"", "WORD", "Hello", "***"},
+		{"", "SHOUT", "Hello!", "***"},
+		{"", "SHOUTS", "Hello!!", "***"},
+		{"", "NAME", "Bob", "***"},
+		{"", "HOMEPAGE", "https://example.com", "[REDACTED]"},
+		{"", "LONG", "Hello there, friend of mine", "[REDACTED]"},
+		{"", "EMPTY", "", `""`},
+		{"", "WEBHOOK_SECRET", "short", "[REDACTED]"},
+		{"", "PAYMENT", "sk_live_123", "[REDACTED]"},
+		{"full", "HOMEPAGE", "https://example.com", "https://example.com"},
+		{"full", "DB_PASSWORD", "hunter2", "hunter2"},
+		{"full", "STRIPE_SECRET", "abc", "[REDACTED]"},
+		{"never", "NODE_ENV", "production", ""},
+	}
+
+	for _, tt := range tests {
+		redactor, err := NewRedactor(tt.mode, rules)
+		if err != nil {
+			t.Fatalf("NewRedactor(%q) failed: %v", tt.mode, err)
+		}
+		if got := redactor.Redact(tt.key, tt.value); got != tt.want {
+			t.Errorf("Redact(%s, %s) with %q = %q, want %q", tt.key, tt.value, tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := NewRedactor("sometimes", nil); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,118 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// Options controls which findings a reporter includes
+type Options struct {
+	SkipUnused bool   // Don't report unused variables
+	Dynamic    bool   // Report partial matches from dynamic patterns
+	GroupBy    string // Also group findings, only GroupByOwner is supported (empty disables grouping)
+	// MaxLocations limits the locations listed per finding, the rest are counted (0 lists all of them)
+	MaxLocations int
+	// Redactor decides how env file values are shown (nil uses DefaultRedactor)
+	Redactor Redactor
+	// Wide keeps code snippets whole instead of cutting them to the terminal width (e.g., for output piped into files)
+	Wide bool
+}
+
+// GroupByOwner groups findings by the CODEOWNERS owners of their files
+const GroupByOwner = "owner"
+
+// Reporter renders a scan result to a writer
+// Implement it to plug a custom output format into the CLI or the library
+type Reporter interface {
+	Report(w io.Writer, result analyzer.ScanResult, opts Options) error
+}
+
+// ReporterFunc adapts a plain function to the Reporter interface
+type ReporterFunc func(w io.Writer, result analyzer.ScanResult, opts Options) error
+
+// Report calls f(w, result, opts)
+func (f ReporterFunc) Report(w io.Writer, result analyzer.ScanResult, opts Options) error {
+	return f(w, result, opts)
+}
+
+// TextReporter renders the human-readable report
+type TextReporter struct {
+	Color bool // Emit ANSI color codes
+	Width int  // Terminal width to fit usage lines into; when 0, code snippets are cut at 80 characters
+}
+
+// NewTextReporter creates a text reporter with colors enabled when stdout is a color-capable terminal
+// and lines fitted to its width
+func NewTextReporter() TextReporter {
+	return TextReporter{Color: colorEnabled, Width: terminalWidth()}
+}
+
+// Report writes the human-readable report
+func (r TextReporter) Report(w io.Writer, result analyzer.ScanResult, opts Options) error {
+	return formatHumanReadable(w, result, opts, r.Color, r.Width)
+}
+
+// JSONReporter renders the JSON report
+type JSONReporter struct{}
+
+// Report writes the JSON report
+func (JSONReporter) Report(w io.Writer, result analyzer.ScanResult, opts Options) error {
+	return formatJSON(w, result, opts)
+}
+
+// ExecReporter pipes the JSON report into an external command and copies the command's stdout to w
+// This lets users plug in their own formatter (e.g., a jq filter or a script) without writing Go
+type ExecReporter struct {
+	Command string // Shell command line to run
+}
+
+// Report runs the command with the JSON report on stdin
+func (r ExecReporter) Report(w io.Writer, result analyzer.ScanResult, opts Options) error {
+	var input bytes.Buffer
+	if err := formatJSON(&input, result, opts); err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", r.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", r.Command)
+	}
+	cmd.Stdin = &input
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("reporter command %q failed: %w", r.Command, err)
+	}
+	return nil
+}
+
+// NewReporter returns the reporter for a --format value
+// Supported formats: text (default), json, badge, exec:<command>
+func NewReporter(format string) (Reporter, error) {
+	switch {
+	case format == "" || format == "text":
+		return NewTextReporter(), nil
+	case format == "json":
+		return JSONReporter{}, nil
+	case format == "badge":
+		return BadgeReporter{}, nil
+	case strings.HasPrefix(format, "exec:"):
+		command := strings.TrimSpace(strings.TrimPrefix(format, "exec:"))
+		if command == "" {
+			return nil, fmt.Errorf("--format exec: requires a command (e.g. exec:./my-formatter.sh)")
+		}
+		return ExecReporter{Command: command}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (supported: text, json, badge, exec:<command>)", format)
+	}
+}
@@ -0,0 +1,715 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/stats"
+)
+
+func testResult() analyzer.ScanResult {
+	return analyzer.ScanResult{
+		EnvKeys:       map[string]string{"UNUSED_VAR": "value"},
+		EnvKeySources: map[string]string{"UNUSED_VAR": ".env"},
+		Missing: map[string][]analyzer.EnvUsage{
+			"MISSING_VAR": {{Key: "MISSING_VAR", File: "app.js", Line: 3, CodeSnippet: "process.env.MISSING_VAR"}},
+		},
+		PartialMatches: map[string][]analyzer.EnvUsage{
+			"PREFIX_": {{Key: "PREFIX_", File: "app.js", Line: 5, IsPartial: true}},
+		},
+		Unused: []string{"UNUSED_VAR"},
+	}
+}
+
+func TestNewReporter(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    interface{}
+		wantErr bool
+	}{
+		{"", TextReporter{}, false},
+		{"text", TextReporter{}, false},
+		{"json", JSONReporter{}, false},
+		{"badge", BadgeReporter{}, false},
+		{"exec:cat", ExecReporter{Command: "cat"}, false},
+		{"exec:", nil, true},
+		{"xml", nil, true},
+	}
+
+	for _, tt := range tests {
+		reporter, err := NewReporter(tt.format)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewReporter(%q) expected error", tt.format)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewReporter(%q) returned error: %v", tt.format, err)
+			continue
+		}
+		switch tt.want.(type) {
+		case TextReporter:
+			if _, ok := reporter.(TextReporter); !ok {
+				t.Errorf("NewReporter(%q) = %T, want TextReporter", tt.format, reporter)
+			}
+		case JSONReporter:
+			if _, ok := reporter.(JSONReporter); !ok {
+				t.Errorf("NewReporter(%q) = %T, want JSONReporter", tt.format, reporter)
+			}
+		case BadgeReporter:
+			if _, ok := reporter.(BadgeReporter); !ok {
+				t.Errorf("NewReporter(%q) = %T, want BadgeReporter", tt.format, reporter)
+			}
+		case ExecReporter:
+			if reporter != tt.want {
+				t.Errorf("NewReporter(%q) = %#v, want %#v", tt.format, reporter, tt.want)
+			}
+		}
+	}
+}
+
+func TestTextReporter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (TextReporter{}).Report(&buf, testResult(), Options{Dynamic: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Missing environment variables:", "MISSING_VAR", "used in: app.js:3", "PREFIX_", "UNUSED_VAR=value (in .env)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\033[") {
+		t.Error("Expected no ANSI codes with Color disabled")
+	}
+}
+
+func TestTextReporter_SeverityOverrides(t *testing.T) {
+	result := testResult()
+	result.Severities = map[string]config.Severity{"MISSING_VAR": config.SeverityError, "UNUSED_VAR": config.SeverityError}
+
+	var buf bytes.Buffer
+	if err := (TextReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "UNUSED_VAR=value (in .env) [error]") {
+		t.Errorf("Expected overridden severity tag on UNUSED_VAR, got:\n%s", out)
+	}
+	if strings.Contains(out, "MISSING_VAR [error]") {
+		t.Errorf("Expected no tag for default severity, got:\n%s", out)
+	}
+}
+
+func TestJSONReporter_Severities(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, testResult(), Options{Dynamic: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if decoded.Missing[0].Severity != config.SeverityError || decoded.PartialMatches[0].Severity != config.SeverityInfo {
+		t.Errorf("Unexpected finding severities: %+v %+v", decoded.Missing, decoded.PartialMatches)
+	}
+	if decoded.UnusedSeverities["UNUSED_VAR"] != config.SeverityWarning {
+		t.Errorf("Unexpected unused severities: %v", decoded.UnusedSeverities)
+	}
+	if decoded.HighestSeverity != config.SeverityError {
+		t.Errorf("Expected highest severity error, got %q", decoded.HighestSeverity)
+	}
+}
+
+func TestJSONReporter_RespectsOptions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, testResult(), Options{SkipUnused: true, Dynamic: false}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(decoded.Missing) != 1 || decoded.Missing[0].Key != "MISSING_VAR" {
+		t.Errorf("Unexpected missing: %v", decoded.Missing)
+	}
+	if len(decoded.PartialMatches) != 0 {
+		t.Errorf("Expected no partial matches with dynamic disabled, got %v", decoded.PartialMatches)
+	}
+	if len(decoded.Unused) != 0 {
+		t.Errorf("Expected no unused with SkipUnused, got %v", decoded.Unused)
+	}
+}
+
+func TestReporters_Stats(t *testing.T) {
+	result := testResult()
+	result.Stats = &stats.Stats{
+		Parse:        2 * time.Millisecond,
+		FilesParsed:  1,
+		Languages:    []stats.LanguageStats{{Language: "javascript", Files: 1, Duration: 2 * time.Millisecond}},
+		SlowestFiles: []stats.FileTiming{{Path: "app.js", Language: "javascript", Duration: 2 * time.Millisecond}},
+		PeakMemory:   3 << 20,
+	}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	for _, want := range []string{"Scan statistics:", "Parsing:      2ms (1 files)", "javascript: 1 files, 2ms cumulative", "Peak memory:  3.0 MB", "app.js  2ms"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected text output to contain %q, got:\n%s", want, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if decoded.Stats == nil || decoded.Stats.ParseMs != 2 || len(decoded.Stats.SlowestFiles) != 1 || decoded.Stats.PeakMemory != 3<<20 {
+		t.Errorf("Unexpected JSON stats: %+v", decoded.Stats)
+	}
+
+	// Stats are omitted unless collected
+	buf.Reset()
+	if err := (JSONReporter{}).Report(&buf, testResult(), Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if strings.Contains(buf.String(), `"stats"`) {
+		t.Errorf("Expected no stats in JSON output, got:\n%s", buf.String())
+	}
+}
+
+func TestReporters_ParseErrors(t *testing.T) {
+	result := testResult()
+	result.ParseErrors = []analyzer.ParseError{{File: "src/bad.js", Language: "javascript", Error: "tree-sitter failed to parse the file"}}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "Files that could not be analyzed:\n\n  src/bad.js (javascript): tree-sitter failed to parse the file") {
+		t.Errorf("Expected parse errors section, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(decoded.ParseErrors) != 1 || decoded.ParseErrors[0].File != "src/bad.js" {
+		t.Errorf("Unexpected JSON parse errors: %+v", decoded.ParseErrors)
+	}
+}
+
+func TestReporterFunc(t *testing.T) {
+	reporter := ReporterFunc(func(w io.Writer, result analyzer.ScanResult, opts Options) error {
+		_, err := io.WriteString(w, "custom")
+		return err
+	})
+
+	var buf bytes.Buffer
+	if err := reporter.Report(&buf, testResult(), Options{}); err != nil || buf.String() != "custom" {
+		t.Errorf("Expected custom output, got %q (err %v)", buf.String(), err)
+	}
+}
+
+func TestExecReporter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	var buf bytes.Buffer
+	reporter := ExecReporter{Command: `grep -c '"key": "MISSING_VAR"'`}
+	if err := reporter.Report(&buf, testResult(), Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "1" {
+		t.Errorf("Expected command to receive the JSON report, got %q", buf.String())
+	}
+
+	if err := (ExecReporter{Command: "exit 3"}).Report(&buf, testResult(), Options{}); err == nil {
+		t.Error("Expected error for failing command")
+	}
+}
+
+func TestReporters_Required(t *testing.T) {
+	result := testResult()
+	result.Missing["TF_VAR_region"] = []analyzer.EnvUsage{}
+	result.Required = []string{"TF_VAR_region"}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "TF_VAR_region\n    required in .envgrd.config") {
+		t.Errorf("Expected required note, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	for _, missing := range decoded.Missing {
+		if missing.Required != (missing.Key == "TF_VAR_region") {
+			t.Errorf("Unexpected required flag for %s: %v", missing.Key, missing.Required)
+		}
+	}
+}
+
+func TestReporters_OptionalMissing(t *testing.T) {
+	result := testResult()
+	result.OptionalMissing = map[string][]analyzer.EnvUsage{
+		"PORT": {{Key: "PORT", File: "server.js", Line: 2, CodeSnippet: "process.env.PORT || 3000", IsOptional: true}},
+	}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "Optional variables not set (code falls back to a default):\n\n  PORT\n    used in: server.js:2 process.env.PORT || 3000") {
+		t.Errorf("Expected optional section, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(decoded.OptionalMissing) != 1 || decoded.OptionalMissing[0].Severity != config.SeverityInfo {
+		t.Errorf("Unexpected JSON optional variables: %+v", decoded.OptionalMissing)
+	}
+	for _, missing := range decoded.Missing {
+		if missing.Key == "PORT" {
+			t.Error("Optional variable should not be reported as missing")
+		}
+	}
+}
+
+func TestReporters_UnusedLocations(t *testing.T) {
+	result := testResult()
+	result.EnvKeyLines = map[string]int{"UNUSED_VAR": 14}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "UNUSED_VAR=value (in .env:14)") {
+		t.Errorf("Expected unused location, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if got := decoded.UnusedLocations["UNUSED_VAR"]; got != (JSONLocation{File: ".env", Line: 14}) {
+		t.Errorf("Unexpected JSON unused location: %+v", got)
+	}
+}
+
+func TestReporters_MaxLocations(t *testing.T) {
+	result := testResult()
+	for line := 1; line <= 7; line++ {
+		result.Missing["MISSING_VAR"] = append(result.Missing["MISSING_VAR"], analyzer.EnvUsage{Key: "MISSING_VAR", File: "lib.js", Line: line * 10})
+	}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{Dynamic: true, MaxLocations: 5}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if got := strings.Count(text.String(), "used in:"); got != 6 { // 5 of MISSING_VAR, 1 of PREFIX_
+		t.Errorf("Expected 6 usages, got %d:\n%s", got, text.String())
+	}
+	if !strings.Contains(text.String(), "lib.js:40\n    +3 more\n") {
+		t.Errorf("Expected the remaining usages to be counted, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{Dynamic: true, MaxLocations: 5}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if missing := decoded.Missing[0]; len(missing.Locations) != 5 || !missing.Truncated || missing.TotalLocations != 8 {
+		t.Errorf("Expected 5 of 8 locations, got %+v", missing)
+	}
+	if partial := decoded.PartialMatches[0]; partial.Truncated || partial.TotalLocations != 0 {
+		t.Errorf("Expected untruncated partial match, got %+v", partial)
+	}
+
+	text.Reset()
+	if err := (TextReporter{}).Report(&text, result, Options{Dynamic: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if strings.Contains(text.String(), "more") || strings.Count(text.String(), "used in:") != 9 {
+		t.Errorf("Expected every usage without a limit, got:\n%s", text.String())
+	}
+}
+
+func TestReporters_Conflicts(t *testing.T) {
+	result := testResult()
+	result.Conflicts = []analyzer.Conflict{{
+		Key: "API_KEY",
+		Definitions: []analyzer.Definition{
+			{File: ".env", Line: 1, Value: "development"},
+			{File: "docker-compose.yml", Line: 9, Value: "production"},
+		},
+		Effective: 1,
+	}}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	expected := "Conflicting definitions:\n\n  API_KEY\n    .env:1 = [REDACTED]\n    docker-compose.yml:9 = [REDACTED] (effective)\n"
+	if !strings.Contains(text.String(), expected) {
+		t.Errorf("Expected conflicts section, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(decoded.Conflicts) != 1 || len(decoded.Conflicts[0].Definitions) != 2 {
+		t.Fatalf("Unexpected JSON conflicts: %+v", decoded.Conflicts)
+	}
+	if def := decoded.Conflicts[0].Definitions[1]; !def.Effective || def.Value != "[REDACTED]" {
+		t.Errorf("Expected redacted effective definition, got %+v", def)
+	}
+	if strings.Contains(buf.String(), "production") {
+		t.Error("Conflict values must be redacted in JSON output")
+	}
+}
+
+func TestReporters_ConflictEnvironments(t *testing.T) {
+	result := testResult()
+	result.Conflicts = []analyzer.Conflict{{
+		Key: "LOG_LEVEL",
+		Definitions: []analyzer.Definition{
+			{File: "ecosystem.config.js", Line: 6, Value: "debug"},
+			{File: "ecosystem.config.js", Line: 9, Value: "warn", Environment: "production"},
+			{File: ".env", Line: 1, Value: "info"},
+		},
+		Effective: 2,
+	}}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "    ecosystem.config.js:9 [production] = warn\n") {
+		t.Errorf("Expected the environment after the location, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if defs := decoded.Conflicts[0].Definitions; defs[0].Environment != "" || defs[1].Environment != "production" {
+		t.Errorf("Unexpected JSON definitions: %+v", defs)
+	}
+}
+
+func TestReporters_Comparison(t *testing.T) {
+	result := testResult()
+
+	var previous bytes.Buffer
+	if err := (JSONReporter{}).Report(&previous, analyzer.ScanResult{
+		Missing: map[string][]analyzer.EnvUsage{"MISSING_VAR": {{Key: "MISSING_VAR"}}, "FIXED_VAR": {{Key: "FIXED_VAR"}}},
+	}, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	report, err := ReadReport(&previous)
+	if err != nil {
+		t.Fatalf("ReadReport failed: %v", err)
+	}
+	result.Comparison = analyzer.Compare(result.Findings(), ReportFindings(report), "previous.json")
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{Dynamic: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	for _, want := range []string{
+		"Compared to previous.json: 2 new, 1 fixed, 1 unchanged\n",
+		"    PREFIX_ (dynamic)\n    UNUSED_VAR (unused)\n",
+		"    FIXED_VAR (was missing)\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected %q in the text report, got:\n%s", want, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{Dynamic: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	comparison := decoded.Comparison
+	if comparison == nil || comparison.Baseline != "previous.json" || len(comparison.New[analyzer.FindingUnused]) != 1 ||
+		len(comparison.Fixed[analyzer.FindingMissing]) != 1 || len(comparison.Unchanged[analyzer.FindingMissing]) != 1 {
+		t.Errorf("Unexpected JSON comparison: %+v", comparison)
+	}
+}
+
+func TestReporters_CaseMismatches(t *testing.T) {
+	result := analyzer.ScanResult{
+		CaseMismatches: []analyzer.CaseMismatch{{
+			Key:         "Path",
+			Defined:     "PATH",
+			Usages:      []analyzer.EnvUsage{{Key: "Path", File: "main.go", Line: 4}},
+			Definitions: []analyzer.Definition{{File: ".env", Line: 2}},
+		}},
+	}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	for _, want := range []string{"Case-only mismatches:", "Path (defined as PATH)", "used in: main.go:4", "defined in: .env:2"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected %q in the text report, got:\n%s", want, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	want := []JSONCaseMismatch{{Key: "Path", Defined: "PATH", Usages: []string{"main.go:4"}, Definitions: []string{".env:2"}}}
+	if !reflect.DeepEqual(decoded.CaseMismatches, want) {
+		t.Errorf("Expected case mismatches %+v, got %+v", want, decoded.CaseMismatches)
+	}
+}
+
+func TestReporters_Remediation(t *testing.T) {
+	result := analyzer.ScanResult{
+		Missing:     map[string][]analyzer.EnvUsage{"DATABASE_URL": {{Key: "DATABASE_URL", File: "db.go", Line: 3}}},
+		Unused:      []string{"OLD_TOKEN"},
+		Remediation: map[string]string{"DATABASE_URL": "Request access via #infra", "OLD_TOKEN": "Remove it from the vault"},
+	}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{SkipUnused: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "How to fix:") || !strings.Contains(text.String(), "DATABASE_URL  Request access via #infra") {
+		t.Errorf("Expected the DATABASE_URL hint in the text report, got:\n%s", text.String())
+	}
+	if strings.Contains(text.String(), "OLD_TOKEN") {
+		t.Errorf("Expected no hint for unused variables with SkipUnused, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Remediation, result.Remediation) {
+		t.Errorf("Expected remediation %v, got %v", result.Remediation, decoded.Remediation)
+	}
+}
+
+func TestReporters_IgnoredUnused(t *testing.T) {
+	result := analyzer.ScanResult{IgnoredUnused: 2}
+
+	var text bytes.Buffer
+	if err := (TextReporter{}).Report(&text, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "2 unused variable(s) were ignored") || !strings.Contains(text.String(), "excluding 2 unused ignored via config") {
+		t.Errorf("Expected ignored unused note, got:\n%s", text.String())
+	}
+
+	text.Reset()
+	if err := (TextReporter{}).Report(&text, result, Options{SkipUnused: true}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if strings.Contains(text.String(), "unused") {
+		t.Errorf("Expected no unused note with SkipUnused, got:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, result, Options{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	var decoded JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if decoded.IgnoredUnused != 2 {
+		t.Errorf("Expected 2 ignored unused variables in JSON, got %d", decoded.IgnoredUnused)
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	result := testResult()
+	result.Definitions = map[string][]analyzer.Definition{
+		"UNUSED_VAR": {{File: ".env", Line: 1}},
+		"API_KEY":    {{File: ".env", Line: 2}, {File: "deploy/.env.prod", Line: 1}},
+	}
+	result.CodeKeys = []analyzer.EnvUsage{
+		{Key: "API_KEY", File: "api/server.go", Line: 1},
+		{Key: "API_KEY", File: "worker/main.go", Line: 1},
+		{Key: "MISSING_VAR", File: "api/client.go", Line: 2},
+		{Key: "PREFIX_", File: "api/client.go", Line: 3, IsPartial: true},
+	}
+
+	var dot bytes.Buffer
+	if err := WriteGraph(&dot, BuildGraph(result, ConsumersFile), GraphDOT); err != nil {
+		t.Fatalf("WriteGraph failed: %v", err)
+	}
+	for _, want := range []string{`label="deploy/.env.prod", shape=folder`, `label="MISSING_VAR", shape=box, style=rounded, color=red`, `label="worker/main.go"`, "n1 -> n0;", "n0 -> n5;"} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", want, dot.String())
+		}
+	}
+	if strings.Contains(dot.String(), "PREFIX_") {
+		t.Error("Expected dynamic patterns to be left out of the graph")
+	}
+
+	g := BuildGraph(result, ConsumersDir)
+	var consumers []string
+	for _, n := range g.Nodes {
+		if n.Kind == nodeConsumer {
+			consumers = append(consumers, n.Label)
+		}
+	}
+	if strings.Join(consumers, ",") != "api/,worker/" {
+		t.Errorf("Expected consumers grouped by directory, got %v", consumers)
+	}
+
+	var mermaid bytes.Buffer
+	if err := WriteGraph(&mermaid, g, GraphMermaid); err != nil {
+		t.Fatalf("WriteGraph failed: %v", err)
+	}
+	if !strings.HasPrefix(mermaid.String(), "flowchart LR\n") || !strings.Contains(mermaid.String(), "class ") {
+		t.Errorf("Unexpected Mermaid output:\n%s", mermaid.String())
+	}
+	if err := WriteGraph(io.Discard, g, "svg"); err == nil {
+		t.Error("Expected an unknown graph format to be rejected")
+	}
+}
+
+func TestTextReporter_SnippetWidth(t *testing.T) {
+	code := "const url = process.env.API_URL || " + strings.Repeat("x", 100)
+	result := analyzer.ScanResult{
+		Missing: map[string][]analyzer.EnvUsage{
+			"API_URL": {
+				{Key: "API_URL", File: "src/client.js", Line: 3, CodeSnippet: code},
+				{Key: "API_URL", File: "src/" + strings.Repeat("nested/", 4) + "client.js", Line: 12, CodeSnippet: code},
+			},
+		},
+	}
+	report := func(r TextReporter, opts Options) []string {
+		var buf bytes.Buffer
+		if err := r.Report(&buf, result, opts); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		return strings.Split(buf.String(), "\n")
+	}
+
+	// Without a terminal width, snippets are cut at 80 characters
+	for _, line := range report(TextReporter{}, Options{}) {
+		if strings.Contains(line, "const url") && !strings.HasSuffix(line, " "+code[:77]+"...") {
+			t.Errorf("Expected the snippet cut at 80 characters, got %q", line)
+		}
+	}
+
+	// Lines fit the terminal, a long location moves its snippet to the next line
+	lines := report(TextReporter{Width: 60}, Options{})
+	snippets := 0
+	for _, line := range lines {
+		if len(line) > 60 {
+			t.Errorf("Expected lines of at most 60 characters, got %q", line)
+		}
+		if strings.Contains(line, "const url") {
+			snippets++
+		}
+	}
+	if snippets != 2 || !strings.Contains(strings.Join(lines, "\n"), "client.js:12\n      const url") {
+		t.Errorf("Expected the second snippet below its location, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// --wide keeps snippets whole
+	lines = report(TextReporter{Width: 60}, Options{Wide: true})
+	if got := strings.Count(strings.Join(lines, "\n"), code); got != 2 {
+		t.Errorf("Expected 2 whole snippets with Wide, got %d", got)
+	}
+}
+
+func TestBadgeReporter(t *testing.T) {
+	tests := []struct {
+		result analyzer.ScanResult
+		opts   Options
+		want   JSONBadge
+	}{
+		{testResult(), Options{Dynamic: true}, JSONBadge{1, "env", "1 missing, 1 dynamic, 1 unused", "red"}},
+		{testResult(), Options{SkipUnused: true}, JSONBadge{1, "env", "1 missing", "red"}},
+		{analyzer.ScanResult{Unused: []string{"UNUSED_VAR"}}, Options{}, JSONBadge{1, "env", "1 unused", "yellow"}},
+		{analyzer.ScanResult{}, Options{Dynamic: true}, JSONBadge{1, "env", "ok", "brightgreen"}},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := (BadgeReporter{}).Report(&buf, tt.result, tt.opts); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		var got JSONBadge
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Invalid badge JSON: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Badge = %+v, want %+v", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+// RepoReport is the outcome of one repository of a multi-repo scan
+type RepoReport struct {
+	Name     string
+	Source   string               // Path or git URL of the repository
+	Result   *analyzer.ScanResult // nil when Err is set
+	Err      error                // Why the repository couldn't be cloned or scanned
+	ExitCode int                  // Exit code a scan of the repository alone would return
+}
+
+// JSONRepos is the combined report of a multi-repo scan
+type JSONRepos struct {
+	Repos []JSONRepo      `json:"repos"`
+	Stats JSONReposTotals `json:"stats"`
+}
+
+// JSONRepo is the section of a repository in JSONRepos
+type JSONRepo struct {
+	Name     string      `json:"name"`
+	Source   string      `json:"source"`
+	ExitCode int         `json:"exit_code"`
+	Summary  string      `json:"summary"`         // Finding counts by category, e.g. "3 missing, 1 unused", or "ok"
+	Error    string      `json:"error,omitempty"` // Set when the repository couldn't be scanned
+	Report   *JSONOutput `json:"report,omitempty"`
+}
+
+// JSONReposTotals aggregates the repositories of a multi-repo scan
+type JSONReposTotals struct {
+	Repos           int             `json:"repos"`
+	Failed          int             `json:"failed"`   // Repositories that couldn't be cloned or scanned
+	Failing         int             `json:"failing"`  // Repositories whose findings fail the run
+	Clean           int             `json:"clean"`    // Repositories without reported findings
+	Findings        map[string]int  `json:"findings"` // Reported findings by category across repositories, as counted by Summary
+	HighestSeverity config.Severity `json:"highest_severity"`
+}
+
+// ReposExitCode returns the exit code of a multi-repo scan: ExitInternalError when a repository couldn't
+// be scanned, else the exit code of the first failing repository
+func ReposExitCode(reports []RepoReport) int {
+	code := ExitOK
+	for _, report := range reports {
+		if report.Err != nil {
+			return ExitInternalError
+		}
+		if code == ExitOK {
+			code = report.ExitCode
+		}
+	}
+	return code
+}
+
+// BuildRepos builds the combined report of a multi-repo scan
+func BuildRepos(reports []RepoReport, opts Options) JSONRepos {
+	combined := JSONRepos{
+		Repos: make([]JSONRepo, 0, len(reports)),
+		Stats: JSONReposTotals{Repos: len(reports), Findings: map[string]int{}},
+	}
+	for _, report := range reports {
+		repo := JSONRepo{Name: report.Name, Source: report.Source, ExitCode: report.ExitCode}
+		if report.Err != nil {
+			repo.Error = report.Err.Error()
+			combined.Stats.Failed++
+			combined.Repos = append(combined.Repos, repo)
+			continue
+		}
+		output := buildJSONOutput(*report.Result, opts)
+		repo.Report = &output
+		repo.Summary = Summary(*report.Result, opts)
+		if repo.Summary == "" {
+			repo.Summary = "ok"
+			combined.Stats.Clean++
+		}
+		if report.ExitCode != ExitOK {
+			combined.Stats.Failing++
+		}
+		for _, c := range findingCounts(*report.Result, opts) {
+			combined.Stats.Findings[c.label] += c.n
+		}
+		combined.Stats.HighestSeverity = highestOf(combined.Stats.HighestSeverity, output.HighestSeverity)
+		combined.Repos = append(combined.Repos, repo)
+	}
+	return combined
+}
+
+// WriteRepos writes the combined report of a multi-repo scan: as JSON (see JSONRepos), or as one section
+// per repository rendered by text followed by the aggregate stats
+func WriteRepos(w io.Writer, reports []RepoReport, jsonOutput bool, text TextReporter, opts Options) error {
+	combined := BuildRepos(reports, opts)
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(combined)
+	}
+
+	for _, report := range reports {
+		fmt.Fprintf(w, "=== %s (%s) ===\n\n", report.Name, report.Source)
+		if report.Err != nil {
+			fmt.Fprintf(w, "Failed to scan: %v\n\n", report.Err)
+			continue
+		}
+		if err := text.Report(w, *report.Result, opts); err != nil {
+			return err
+		}
+	}
+
+	stats := combined.Stats
+	fmt.Fprintf(w, "=== %d repositories: %d clean, %d failing, %d failed to scan ===\n", stats.Repos, stats.Clean, stats.Failing, stats.Failed)
+	width := 0
+	for _, repo := range combined.Repos {
+		width = max(width, len(repo.Name))
+	}
+	for _, repo := range combined.Repos {
+		summary := repo.Summary
+		if repo.Error != "" {
+			summary = "failed to scan"
+		}
+		fmt.Fprintf(w, "  %-*s  %s\n", width, repo.Name, summary)
+	}
+	var totals []string
+	for _, label := range findingLabels {
+		if n := stats.Findings[label]; n > 0 {
+			totals = append(totals, fmt.Sprintf("%d %s", n, label))
+		}
+	}
+	if len(totals) > 0 {
+		fmt.Fprintf(w, "Total: %s\n", strings.Join(totals, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+)
+
+func TestWriteRepos(t *testing.T) {
+	billing := analyzer.ScanResult{
+		Missing:    map[string][]analyzer.EnvUsage{"STRIPE_KEY": {{Key: "STRIPE_KEY", File: "main.go", Line: 6}}},
+		Severities: map[string]config.Severity{"STRIPE_KEY": config.SeverityError},
+	}
+	reports := []RepoReport{
+		{Name: "billing", Source: "services/billing", Result: &billing, ExitCode: ExitMissing},
+		{Name: "api", Source: "services/api", Result: &analyzer.ScanResult{}},
+		{Name: "web", Source: "https://github.com/acme/web.git", Err: errors.New("git clone failed")},
+	}
+
+	combined := BuildRepos(reports, Options{})
+	stats := combined.Stats
+	if stats.Repos != 3 || stats.Clean != 1 || stats.Failing != 1 || stats.Failed != 1 || stats.Findings["missing"] != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if combined.Repos[0].Summary != "1 missing" || combined.Repos[1].Summary != "ok" || combined.Repos[2].Report != nil {
+		t.Errorf("Unexpected repos: %+v", combined.Repos)
+	}
+	if code := ReposExitCode(reports); code != ExitInternalError {
+		t.Errorf("Expected a failed repo to exit with %d, got %d", ExitInternalError, code)
+	}
+	if code := ReposExitCode(reports[:2]); code != ExitMissing {
+		t.Errorf("Expected the exit code of the failing repo, got %d", code)
+	}
+
+	var b strings.Builder
+	if err := WriteRepos(&b, reports, false, TextReporter{}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	text := b.String()
+	for _, want := range []string{"=== billing (services/billing) ===", "STRIPE_KEY", "Failed to scan: git clone failed", "=== 3 repositories: 1 clean, 1 failing, 1 failed to scan ===", "Total: 1 missing"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, text)
+		}
+	}
+}
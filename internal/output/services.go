@@ -0,0 +1,73 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// JSONServiceGap is a deployable unit and the variables its environment lacks, see analyzer.ServiceGap
+type JSONServiceGap struct {
+	Service   string                `json:"service"`
+	Dirs      []string              `json:"dirs"`
+	Manifests []string              `json:"manifests"`
+	Defined   int                   `json:"defined"`
+	Missing   []JSONServiceVariable `json:"missing"`
+}
+
+// JSONServiceVariable is a variable a service reads without defining it, with where its code reads it
+type JSONServiceVariable struct {
+	Key       string   `json:"key"`
+	Locations []string `json:"locations"` // file:line
+}
+
+// WriteServiceGaps writes the variables each service reads but doesn't define, as text or as JSON
+func WriteServiceGaps(w io.Writer, gaps []analyzer.ServiceGap, jsonOutput bool) error {
+	report := make([]JSONServiceGap, 0, len(gaps))
+	for _, gap := range gaps {
+		entry := JSONServiceGap{Service: gap.Service, Dirs: gap.Dirs, Manifests: gap.Manifests, Defined: gap.Defined, Missing: []JSONServiceVariable{}}
+		keys := make([]string, 0, len(gap.Missing))
+		for key := range gap.Missing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			variable := JSONServiceVariable{Key: key}
+			for _, usage := range gap.Missing[key] {
+				variable.Locations = append(variable.Locations, fmt.Sprintf("%s:%d", usage.File, usage.Line))
+			}
+			entry.Missing = append(entry.Missing, variable)
+		}
+		report = append(report, entry)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	if len(report) == 0 {
+		_, err := fmt.Fprintln(w, "No services: no docker-compose service is built from the scanned code and the config has no services")
+		return err
+	}
+	for i, gap := range report {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%s)\n", gap.Service, strings.Join(gap.Dirs, ", "))
+		fmt.Fprintf(w, "  Environment: %d variables from %s\n", gap.Defined, strings.Join(gap.Manifests, ", "))
+		if len(gap.Missing) == 0 {
+			fmt.Fprintln(w, "  ✓ Defines every variable its code reads")
+			continue
+		}
+		fmt.Fprintf(w, "  ✗ Missing %d variables:\n", len(gap.Missing))
+		for _, variable := range gap.Missing {
+			fmt.Fprintf(w, "    %s  %s\n", variable.Key, strings.Join(variable.Locations, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jenian/envgrd/internal/stats"
+)
+
+// JSONStats is the JSON form of scan statistics, with durations in milliseconds
+type JSONStats struct {
+	DiscoveryMs  float64             `json:"discovery_ms"`
+	EnvLoadingMs float64             `json:"env_loading_ms"`
+	ParseMs      float64             `json:"parse_ms"`
+	AnalysisMs   float64             `json:"analysis_ms"`
+	TotalMs      float64             `json:"total_ms"`
+	FilesParsed  int                 `json:"files_parsed"`
+	Languages    []JSONLanguageStats `json:"languages"`
+	SlowestFiles []JSONFileTiming    `json:"slowest_files"`
+	PeakMemory   uint64              `json:"peak_memory_bytes"`
+}
+
+// JSONLanguageStats is the cumulative parse time of one language
+type JSONLanguageStats struct {
+	Language string  `json:"language"`
+	Files    int     `json:"files"`
+	ParseMs  float64 `json:"parse_ms"`
+}
+
+// JSONFileTiming is the parse time of one file
+type JSONFileTiming struct {
+	Path     string  `json:"path"`
+	Language string  `json:"language"`
+	ParseMs  float64 `json:"parse_ms"`
+}
+
+// buildJSONStats converts scan statistics to their JSON form (nil stays nil)
+func buildJSONStats(s *stats.Stats) *JSONStats {
+	if s == nil {
+		return nil
+	}
+
+	out := &JSONStats{
+		DiscoveryMs:  milliseconds(s.Discovery),
+		EnvLoadingMs: milliseconds(s.EnvLoading),
+		ParseMs:      milliseconds(s.Parse),
+		AnalysisMs:   milliseconds(s.Analysis),
+		TotalMs:      milliseconds(s.Total),
+		FilesParsed:  s.FilesParsed,
+		Languages:    []JSONLanguageStats{},
+		SlowestFiles: []JSONFileTiming{},
+		PeakMemory:   s.PeakMemory,
+	}
+	for _, lang := range s.Languages {
+		out.Languages = append(out.Languages, JSONLanguageStats{Language: lang.Language, Files: lang.Files, ParseMs: milliseconds(lang.Duration)})
+	}
+	for _, file := range s.SlowestFiles {
+		out.SlowestFiles = append(out.SlowestFiles, JSONFileTiming{Path: file.Path, Language: file.Language, ParseMs: milliseconds(file.Duration)})
+	}
+	return out
+}
+
+// formatStats writes the human-readable statistics section
+func formatStats(w io.Writer, s *stats.Stats, getColor func(string) string) {
+	fmt.Fprintf(w, "%s%sScan statistics:%s\n\n", getColor(colorBold), getColor(colorCyan), getColor(colorReset))
+	fmt.Fprintf(w, "  Discovery:    %s\n", formatDuration(s.Discovery))
+	fmt.Fprintf(w, "  Env loading:  %s\n", formatDuration(s.EnvLoading))
+	fmt.Fprintf(w, "  Parsing:      %s (%d files)\n", formatDuration(s.Parse), s.FilesParsed)
+	for _, lang := range s.Languages {
+		fmt.Fprintf(w, "    %s%s: %d files, %s cumulative%s\n", getColor(colorGray), lang.Language, lang.Files, formatDuration(lang.Duration), getColor(colorReset))
+	}
+	fmt.Fprintf(w, "  Analysis:     %s\n", formatDuration(s.Analysis))
+	fmt.Fprintf(w, "  Total:        %s\n", formatDuration(s.Total))
+	if s.PeakMemory > 0 {
+		fmt.Fprintf(w, "  Peak memory:  %s\n", formatBytes(s.PeakMemory))
+	}
+
+	if len(s.SlowestFiles) > 0 {
+		fmt.Fprintf(w, "\n  Slowest files:\n")
+		width := 0
+		for _, file := range s.SlowestFiles {
+			width = max(width, len(file.Path))
+		}
+		for _, file := range s.SlowestFiles {
+			fmt.Fprintf(w, "    %s%s%s%s  %s\n", getColor(colorCyan), file.Path, getColor(colorReset), strings.Repeat(" ", width-len(file.Path)), formatDuration(file.Duration))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatDuration rounds a duration for display
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// UsagesSchemaVersion is the version of the usages file format written by envgrd list --json
+const UsagesSchemaVersion = 1
+
+// JSONUsages is a usages file: the usages extracted from source files, to be analyzed later with
+// envgrd scan --usages-file instead of parsing the files again
+type JSONUsages struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Usages        []JSONUsage `json:"usages"`
+}
+
+// JSONUsage is a single usage of an environment variable in code, see analyzer.EnvUsage
+type JSONUsage struct {
+	Key         string `json:"key"`                    // Variable name, or the static part of a dynamic pattern
+	File        string `json:"file"`                   // Relative to the scan root
+	Line        int    `json:"line"`                   // 1-based
+	Snippet     string `json:"snippet,omitempty"`      // Code of the line
+	Partial     bool   `json:"partial,omitempty"`      // Dynamic pattern (e.g., "prefix_" + name), Key is the prefix or suffix
+	VarRef      bool   `json:"var_ref,omitempty"`      // Lookup through a variable (e.g., process.env[name])
+	Expression  string `json:"expression,omitempty"`   // Full expression of dynamic patterns
+	Optional    bool   `json:"optional,omitempty"`     // The lookup falls back to a default
+	Type        string `json:"type,omitempty"`         // Type code parses the value as (integer, number or boolean)
+	IgnoredPath bool   `json:"ignored_path,omitempty"` // The file is in an ignored folder
+}
+
+// WriteUsages writes usages as a usages file
+// Test files and CODEOWNERS owners are left out, the scan reading the file derives them again
+func WriteUsages(w io.Writer, usages []analyzer.EnvUsage) error {
+	file := JSONUsages{SchemaVersion: UsagesSchemaVersion, Usages: make([]JSONUsage, 0, len(usages))}
+	for _, usage := range usages {
+		file.Usages = append(file.Usages, newJSONUsage(usage))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+// UsagesStream writes a usages file as newline-delimited JSON (NDJSON), one usage per line after a
+// {"schemaVersion":1} header line, so that usages are written as they are found instead of all at the end
+// ReadUsages reads both forms
+type UsagesStream struct {
+	encoder *json.Encoder
+	header  bool
+}
+
+// NewUsagesStream returns a stream writing to w, the header is written with the first usages
+func NewUsagesStream(w io.Writer) *UsagesStream {
+	return &UsagesStream{encoder: json.NewEncoder(w)}
+}
+
+// Write writes usages, one per line
+func (s *UsagesStream) Write(usages []analyzer.EnvUsage) error {
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+	for _, usage := range usages {
+		if err := s.encoder.Encode(newJSONUsage(usage)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the header if no usages were written, so that an empty stream is still a usages file
+func (s *UsagesStream) Close() error {
+	return s.writeHeader()
+}
+
+func (s *UsagesStream) writeHeader() error {
+	if s.header {
+		return nil
+	}
+	s.header = true
+	return s.encoder.Encode(struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}{UsagesSchemaVersion})
+}
+
+func newJSONUsage(usage analyzer.EnvUsage) JSONUsage {
+	return JSONUsage{
+		Key:         usage.Key,
+		File:        usage.File,
+		Line:        usage.Line,
+		Snippet:     usage.CodeSnippet,
+		Partial:     usage.IsPartial,
+		VarRef:      usage.IsVarRef,
+		Expression:  usage.FullExpr,
+		Optional:    usage.IsOptional,
+		Type:        usage.Type,
+		IgnoredPath: usage.InIgnoredPath,
+	}
+}
+
+// ReadUsages reads a usages file, as written by WriteUsages, UsagesStream or another tool following its schema
+func ReadUsages(r io.Reader) ([]analyzer.EnvUsage, error) {
+	var file JSONUsages
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid usages file: %w", err)
+	}
+	if file.SchemaVersion != UsagesSchemaVersion {
+		return nil, fmt.Errorf("unsupported usages file schemaVersion %d (supported: %d)", file.SchemaVersion, UsagesSchemaVersion)
+	}
+	// NDJSON: the header line is followed by one usage per line
+	if file.Usages == nil {
+		for decoder.More() {
+			var usage JSONUsage
+			if err := decoder.Decode(&usage); err != nil {
+				return nil, fmt.Errorf("invalid usages file: usage %d: %w", len(file.Usages)+1, err)
+			}
+			file.Usages = append(file.Usages, usage)
+		}
+	}
+
+	usages := make([]analyzer.EnvUsage, 0, len(file.Usages))
+	for i, usage := range file.Usages {
+		if usage.File == "" || (usage.Key == "" && !usage.Partial && !usage.VarRef) {
+			return nil, fmt.Errorf("invalid usages file: usage %d needs a key and a file", i+1)
+		}
+		usages = append(usages, analyzer.EnvUsage{
+			Key:           usage.Key,
+			File:          usage.File,
+			Line:          usage.Line,
+			CodeSnippet:   usage.Snippet,
+			IsPartial:     usage.Partial,
+			IsVarRef:      usage.VarRef,
+			FullExpr:      usage.Expression,
+			IsOptional:    usage.Optional,
+			Type:          usage.Type,
+			InIgnoredPath: usage.IgnoredPath,
+		})
+	}
+	return usages, nil
+}
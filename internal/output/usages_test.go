@@ -0,0 +1,86 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+func TestUsages_RoundTrip(t *testing.T) {
+	usages := []analyzer.EnvUsage{
+		{Key: "API_KEY", File: "src/app.js", Line: 3, CodeSnippet: "process.env.API_KEY", IsOptional: true},
+		{Key: "PREFIX_", File: "src/app.js", Line: 4, IsPartial: true, FullExpr: `"PREFIX_" + name`},
+		{Key: "LEGACY", File: "vendor/lib.js", Line: 1, InIgnoredPath: true},
+	}
+
+	var b bytes.Buffer
+	if err := WriteUsages(&b, usages); err != nil {
+		t.Fatalf("WriteUsages failed: %v", err)
+	}
+	got, err := ReadUsages(&b)
+	if err != nil {
+		t.Fatalf("ReadUsages failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, usages) {
+		t.Errorf("Expected the usages back, got %+v", got)
+	}
+}
+
+func TestUsagesStream_RoundTrip(t *testing.T) {
+	usages := []analyzer.EnvUsage{
+		{Key: "API_KEY", File: "src/app.js", Line: 3, CodeSnippet: "process.env.API_KEY", IsOptional: true},
+		{Key: "PREFIX_", File: "src/app.js", Line: 4, IsPartial: true, FullExpr: `"PREFIX_" + name`},
+		{Key: "LEGACY", File: "vendor/lib.js", Line: 1, InIgnoredPath: true},
+	}
+
+	var b bytes.Buffer
+	stream := NewUsagesStream(&b)
+	if err := stream.Write(usages[:2]); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := stream.Write(usages[2:]); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if lines := strings.Count(b.String(), "\n"); lines != 4 {
+		t.Errorf("Expected a header line and one line per usage, got %d lines:\n%s", lines, b.String())
+	}
+	got, err := ReadUsages(&b)
+	if err != nil {
+		t.Fatalf("ReadUsages failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, usages) {
+		t.Errorf("Expected the usages back, got %+v", got)
+	}
+
+	// An empty stream is still a usages file
+	b.Reset()
+	if err := NewUsagesStream(&b).Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got, err := ReadUsages(&b); err != nil || len(got) != 0 {
+		t.Errorf("Expected no usages, got %v, %v", got, err)
+	}
+}
+
+func TestReadUsages_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not JSON":      `[`,
+		"old version":   `{"schemaVersion": 2, "usages": []}`,
+		"unknown field": `{"schemaVersion": 1, "usages": [{"key": "A", "file": "a.js", "column": 3}]}`,
+		"missing file":  `{"schemaVersion": 1, "usages": [{"key": "A"}]}`,
+		"missing key":   `{"schemaVersion": 1, "usages": [{"file": "a.js"}]}`,
+		"bad NDJSON":    "{\"schemaVersion\": 1}\n{\"key\": \"A\", \"file\": \"a.js\"}\n{\"key\": ",
+		"NDJSON field":  "{\"schemaVersion\": 1}\n{\"key\": \"A\", \"file\": \"a.js\", \"column\": 3}\n",
+	}
+	for name, content := range tests {
+		if _, err := ReadUsages(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
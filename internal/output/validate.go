@@ -0,0 +1,62 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// JSONValidationProblem is an env file value that fails validation, see analyzer.InvalidValue
+type JSONValidationProblem struct {
+	Key      string `json:"key"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Value    string `json:"value,omitempty"` // Omitted with --show-values never
+	Rule     string `json:"rule"`            // Format (e.g., url), type or enum
+	Inferred bool   `json:"inferred"`        // The format was inferred from the name
+	Problem  string `json:"problem"`
+}
+
+// WriteInvalidValues writes the env file values failing validation, as text or as JSON, with values
+// shown through redactor (DefaultRedactor when nil)
+func WriteInvalidValues(w io.Writer, invalid []analyzer.InvalidValue, jsonOutput bool, redactor Redactor) error {
+	opts := Options{Redactor: redactor}
+	report := make([]JSONValidationProblem, 0, len(invalid))
+	for _, v := range invalid {
+		report = append(report, JSONValidationProblem{
+			Key:      v.Key,
+			File:     v.Definition.File,
+			Line:     v.Definition.Line,
+			Value:    opts.redact(v.Key, v.Definition.Value),
+			Rule:     v.Rule,
+			Inferred: v.Inferred,
+			Problem:  v.Problem,
+		})
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	if len(report) == 0 {
+		_, err := fmt.Fprintln(w, "✓ Every env file value is valid")
+		return err
+	}
+	fmt.Fprintf(w, "✗ %d invalid values:\n", len(report))
+	for i, problem := range report {
+		assignment := problem.Key
+		if problem.Value != "" {
+			assignment += "=" + problem.Value
+		}
+		rule := problem.Rule
+		if problem.Inferred {
+			rule += ", inferred from the name"
+		}
+		location := definitionLocations([]analyzer.Definition{invalid[i].Definition})[0]
+		fmt.Fprintf(w, "  %s (%s): %s [%s]\n", assignment, location, problem.Problem, rule)
+	}
+	return nil
+}
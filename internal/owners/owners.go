@@ -0,0 +1,216 @@
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locations searched for a CODEOWNERS file, in GitHub's order
+var Locations = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+}
+
+// Rules are the parsed rules of a CODEOWNERS file
+type Rules struct {
+	File  string // Path of the CODEOWNERS file, relative to the scan root
+	rules []rule
+}
+
+// rule is one pattern line; a pattern without owners makes matching files unowned
+type rule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// Load reads the CODEOWNERS file of the repository at root, returning nil if there is none
+func Load(root string) (*Rules, error) {
+	for _, location := range Locations {
+		f, err := os.Open(filepath.Join(root, location))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+		rules.File = filepath.ToSlash(location)
+		return rules, nil
+	}
+	return nil, nil
+}
+
+// Parse reads CODEOWNERS rules: one gitignore-style pattern per line followed by its owners
+func Parse(r io.Reader) (*Rules, error) {
+	rules := &Rules{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		pattern, err := CompilePattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		rules.rules = append(rules.rules, rule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules, scanner.Err()
+}
+
+// CompilePattern converts a gitignore-style pattern into a regular expression matching file paths
+// Patterns with a leading or inner slash are anchored to the root, others match at any depth,
+// and a pattern matching a directory matches every file below it
+// Like doublestar globs, ** matches any number of directories, [a-z] and [!a-z] a character of a
+// class, and {a,b} either alternative
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(.*/)?")
+	}
+	if err := translatePattern(&expr, pattern, 0); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if dirOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
+
+// translatePattern writes the regular expression of a glob pattern to expr
+// depth is the nesting of {} alternatives, inside which commas separate alternatives
+func translatePattern(expr *strings.Builder, pattern string, depth int) error {
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			i += end + 1
+			if negated := strings.TrimLeft(class, "!^"); len(negated) < len(class) {
+				class = "^/" + negated // Like *, a negated class never matches a separator
+			}
+			if class == "" || class == "^/" {
+				return fmt.Errorf("empty character class")
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+		case c == '{':
+			end, err := closingBrace(pattern, i)
+			if err != nil {
+				return err
+			}
+			expr.WriteString("(?:")
+			for j, alternative := range splitAlternatives(pattern[i+1 : end]) {
+				if j > 0 {
+					expr.WriteString("|")
+				}
+				if err := translatePattern(expr, alternative, depth+1); err != nil {
+					return err
+				}
+			}
+			expr.WriteString(")")
+			i = end
+		case c == '}' && depth == 0:
+			return fmt.Errorf("unmatched }")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return nil
+}
+
+// closingBrace returns the index of the } closing the { at start
+func closingBrace(pattern string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated {")
+}
+
+// splitAlternatives splits the inside of {} at its top-level commas
+func splitAlternatives(inner string) []string {
+	var alternatives []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, inner[start:])
+}
+
+// Of returns the owners of a file (relative to the scan root); the last matching rule wins
+// A nil Rules owns nothing
+func (r *Rules) Of(file string) []string {
+	if r == nil || file == "" {
+		return nil
+	}
+	file = strings.TrimPrefix(filepath.ToSlash(file), "./")
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		if r.rules[i].pattern.MatchString(file) {
+			return r.rules[i].owners
+		}
+	}
+	return nil
+}
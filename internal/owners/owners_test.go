@@ -0,0 +1,99 @@
+package owners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRulesOf(t *testing.T) {
+	rules, err := Parse(strings.NewReader(`
+# Default owners
+*                   @org/platform
+*.js                @org/frontend
+/services/billing/  @org/billing @alice
+docs/**/*.md        @org/docs
+services/billing/vendor/
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"main.go", "@org/platform"},
+		{"web/src/app.js", "@org/frontend"},
+		{"services/billing/main.go", "@org/billing,@alice"},
+		{"services/billing/web/app.js", "@org/billing,@alice"},
+		{"services/billing/vendor/lib.go", ""},
+		{"docs/guide/setup.md", "@org/docs"},
+		{"other/docs/guide.md", "@org/platform"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(rules.Of(tt.file), ","); got != tt.want {
+			t.Errorf("Of(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+
+	var none *Rules
+	if none.Of("main.go") != nil {
+		t.Error("Expected nil rules to own nothing")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if rules, err := Load(dir); err != nil || rules != nil {
+		t.Fatalf("Expected no rules without a CODEOWNERS file, got %v (%v)", rules, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("* @org/team\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Load(dir)
+	if err != nil || rules == nil || rules.File != ".github/CODEOWNERS" {
+		t.Fatalf("Expected .github/CODEOWNERS to be loaded, got %+v (%v)", rules, err)
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"*.ts", "src/app.ts", true},
+		{"src/generated/**", "src/generated/api/client.ts", true},
+		{"src/generated/**", "lib/src/generated/client.ts", false},
+		{"**/*.gen.ts", "src/api/client.gen.ts", true},
+		{"src/**/test_*.py", "src/test_app.py", true},
+		{"*.{js,ts}", "web/app.js", true},
+		{"*.{js,ts}", "web/app.py", false},
+		{"src/{api,web/{a,b}}/*.go", "src/web/b/main.go", true},
+		{"file[0-9].go", "file7.go", true},
+		{"file[!0-9].go", "filex.go", true},
+		{"file[!0-9].go", "file7.go", false},
+		{`\*.go`, "*.go", true},
+	}
+	for _, tt := range tests {
+		re, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompilePattern(%q) failed: %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.file); got != tt.want {
+			t.Errorf("CompilePattern(%q) matching %q = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"file[0-9.go", "*.{js,ts", "src/}", "[]"} {
+		if _, err := CompilePattern(pattern); err == nil {
+			t.Errorf("Expected an error for %q", pattern)
+		}
+	}
+}
@@ -1,13 +1,33 @@
+//go:build cgo && !envgrd_purego
+
 package parser
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/charset"
+	"github.com/jenian/envgrd/internal/languages"
 )
 
+// skipWithoutLanguage skips tests of languages left out of the build with their envgrd_nolang_* tag
+func skipWithoutLanguage(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if languages.GetLanguageInfo(name) == nil {
+			t.Skipf("%s is not built in", name)
+		}
+	}
+}
+
 func TestParser_JavaScript_StaticPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.js")
 	
@@ -48,6 +68,7 @@ const secret = process.env['SECRET_KEY'];
 }
 
 func TestParser_JavaScript_DynamicPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.js")
 	
@@ -93,6 +114,7 @@ const key3 = process.env[varName];
 }
 
 func TestParser_TypeScript_StaticPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "typescript")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.ts")
 	
@@ -127,7 +149,116 @@ const dbUrl = process.env["DATABASE_URL"];
 	}
 }
 
+func TestParser_TSX_JSX(t *testing.T) {
+	skipWithoutLanguage(t, "tsx")
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "App.tsx")
+	code := `
+export function App(): JSX.Element {
+	const title = <Header />;
+	return (
+		<div className="app">
+			<a href={process.env.API_URL}>{title}</a>
+			{process.env["FEATURE_FLAG"] && <Beta />}
+		</div>
+	);
+}
+
+export const apiKey = process.env.API_KEY;
+`
+	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	usages, err := NewParser().ParseFile(filePath, "tsx", tmpDir)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	keys := make(map[string]int)
+	for _, usage := range usages {
+		keys[usage.Key] = usage.Line
+	}
+	expected := map[string]int{"API_URL": 6, "FEATURE_FLAG": 7, "API_KEY": 12}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestParser_Components(t *testing.T) {
+	tests := []struct {
+		file     string
+		lang     string
+		code     string
+		expected map[string]int
+	}{
+		{
+			file: "App.vue",
+			lang: "vue",
+			code: `<template>
+  <a :href="apiUrl">{{ title }}</a>
+</template>
+
+<script setup lang="ts">
+const apiUrl: string = process.env.API_URL ?? "";
+</script>
+`,
+			expected: map[string]int{"API_URL": 6},
+		},
+		{
+			file: "App.svelte",
+			lang: "svelte",
+			code: `<script context="module">
+  export const key = process.env.MODULE_KEY;
+</script>
+
+<script>
+  let token = process.env["TOKEN"];
+</script>
+
+<p>{token}</p>
+`,
+			expected: map[string]int{"MODULE_KEY": 2, "TOKEN": 6},
+		},
+		{
+			file: "index.astro",
+			lang: "astro",
+			code: `---
+const site = process.env.SITE_URL;
+---
+<html><body>{site}</body></html>
+<script>
+  console.log(process.env.CLIENT_ID);
+</script>
+`,
+			expected: map[string]int{"SITE_URL": 2, "CLIENT_ID": 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			skipWithoutLanguage(t, tt.lang)
+			tmpDir := t.TempDir()
+			filePath := filepath.Join(tmpDir, tt.file)
+			if err := os.WriteFile(filePath, []byte(tt.code), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			usages, err := NewParser().ParseFile(filePath, tt.lang, tmpDir)
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			keys := make(map[string]int)
+			for _, usage := range usages {
+				keys[usage.Key] = usage.Line
+			}
+			if !reflect.DeepEqual(keys, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
+
 func TestParser_Go_StaticPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "go")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.go")
 	
@@ -169,6 +300,7 @@ func main() {
 }
 
 func TestParser_Go_DynamicPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "go")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.go")
 	
@@ -219,7 +351,57 @@ func main() {
 	}
 }
 
+func TestParser_Go_SprintfAndConstants(t *testing.T) {
+	skipWithoutLanguage(t, "go")
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "test.go")
+	code := `
+package main
+
+import "os"
+
+const envDatabaseURL = "DATABASE_URL"
+
+const (
+	prefix        = "APP_"
+	envPort string = prefix + ` + "`PORT`" + `
+	retries       = 3
+)
+
+func main() {
+	db := os.Getenv(envDatabaseURL)
+	port := os.Getenv(envPort)
+	worker := os.Getenv(fmt.Sprintf("WORKER_%d_URL", i))
+	other := os.Getenv(retries)
+}
+`
+	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	usages, err := NewParser().ParseFile(filePath, "go", tmpDir)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	got := make(map[string]analyzer.EnvUsage)
+	for _, usage := range usages {
+		got[usage.Key] = usage
+	}
+	for _, key := range []string{"DATABASE_URL", "APP_PORT"} {
+		if usage, ok := got[key]; !ok || usage.IsPartial || usage.IsVarRef {
+			t.Errorf("Expected static key %s, got %+v", key, usages)
+		}
+	}
+	if usage, ok := got["retries"]; !ok || !usage.IsVarRef {
+		t.Errorf("Expected a variable reference to retries, got %+v", usages)
+	}
+	if usage, ok := got[`"WORKER_" + i + "_URL"`]; !ok || !usage.IsPartial || usage.Line != 17 {
+		t.Errorf("Expected dynamic pattern \"WORKER_\" + i + \"_URL\" on line 17, got %+v", usages)
+	}
+}
+
 func TestParser_Python_StaticPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "python")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.py")
 	
@@ -258,6 +440,7 @@ secret = os.environ['SECRET_KEY']
 }
 
 func TestParser_Python_DynamicPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "python")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.py")
 	
@@ -305,7 +488,76 @@ key4 = os.getenv(varName)
 	}
 }
 
+func TestParser_Python_FormattedPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "python")
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "test.py")
+	code := `
+import os
+
+url = os.environ[f"SERVICE_{name}_URL"]
+app = os.getenv("APP_%s" % env)
+`
+	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	usages, err := NewParser().ParseFile(filePath, "python", tmpDir)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	want := map[string]bool{`"SERVICE_" + name + "_URL"`: true, `"APP_" + env`: true}
+	for _, usage := range usages {
+		if !usage.IsPartial || !want[usage.FullExpr] {
+			t.Errorf("Unexpected usage %+v", usage)
+		}
+		delete(want, usage.FullExpr)
+	}
+	if len(want) > 0 {
+		t.Errorf("Expected dynamic patterns %v", want)
+	}
+}
+
+func TestParser_Jupyter(t *testing.T) {
+	skipWithoutLanguage(t, "jupyter")
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "analysis.ipynb")
+	notebook := `{
+ "cells": [
+  {
+   "cell_type": "code",
+   "metadata": {},
+   "source": [
+    "import os\n",
+    "db = os.environ[\"DATABASE_URL\"]\n",
+    "region = os.getenv(\"REGION\", \"eu\")"
+   ]
+  }
+ ],
+ "metadata": {"language_info": {"name": "python"}},
+ "nbformat": 4
+}`
+	if err := os.WriteFile(filePath, []byte(notebook), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	usages, err := NewParser().ParseFile(filePath, "jupyter", tmpDir)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	if len(usages) != 2 {
+		t.Fatalf("Expected 2 usages, got %+v", usages)
+	}
+	if u := usages[0]; u.Key != "DATABASE_URL" || u.Line != 8 || u.CodeSnippet != `db = os.environ["DATABASE_URL"]` {
+		t.Errorf("Expected DATABASE_URL on line 8 with the cell's code, got %+v", u)
+	}
+	if u := usages[1]; u.Key != "REGION" || u.Line != 9 || !u.IsOptional {
+		t.Errorf("Expected optional REGION on line 9, got %+v", u)
+	}
+}
+
 func TestParser_Rust_StaticPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "rust")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.rs")
 	
@@ -346,6 +598,7 @@ fn main() {
 }
 
 func TestParser_Rust_DynamicPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "rust")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.rs")
 	
@@ -394,7 +647,39 @@ fn main() {
 	}
 }
 
+func TestParser_Rust_MacroPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "rust")
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "test.rs")
+	code := `
+use std::env;
+
+fn main() {
+	let port = env::var(concat!("APP_", "PORT")).unwrap();
+	let flag = std::env::var(format!("FEATURE_{}", name)).unwrap();
+}
+`
+	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	usages, err := NewParser().ParseFile(filePath, "rust", tmpDir)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	if len(usages) != 2 {
+		t.Fatalf("Expected 2 usages, got %+v", usages)
+	}
+	if usages[0].Key != "APP_PORT" || usages[0].IsPartial {
+		t.Errorf("Expected static key APP_PORT, got %+v", usages[0])
+	}
+	if usages[1].FullExpr != `"FEATURE_" + name` || !usages[1].IsPartial {
+		t.Errorf("Expected dynamic pattern \"FEATURE_\" + name, got %+v", usages[1])
+	}
+}
+
 func TestParser_Java_StaticPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "java")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "Test.java")
 	
@@ -433,7 +718,56 @@ public class Test {
 	}
 }
 
+func TestParser_Java_FormatAndConstants(t *testing.T) {
+	skipWithoutLanguage(t, "java")
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "Test.java")
+	code := `
+public class Constants {
+	public static final String PREFIX = "APP_";
+	public static final String DB_URL_KEY = PREFIX + "DB_URL";
+	static String mutable = "NOT_A_CONSTANT";
+
+	interface Keys {
+		String TOKEN = "API_TOKEN";
+	}
+
+	void load(String name) {
+		String db = System.getenv(Constants.DB_URL_KEY);
+		String token = System.getenv(Keys.TOKEN);
+		String prefix = System.getenv(PREFIX);
+		String other = System.getenv(mutable);
+		String app = System.getenv(String.format("APP_%s", name));
+	}
+}
+`
+	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	usages, err := NewParser().ParseFile(filePath, "java", tmpDir)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	got := make(map[string]analyzer.EnvUsage)
+	for _, usage := range usages {
+		got[usage.Key] = usage
+	}
+	for _, key := range []string{"APP_DB_URL", "API_TOKEN", "APP_"} {
+		if usage, ok := got[key]; !ok || usage.IsPartial || usage.IsVarRef {
+			t.Errorf("Expected static key %s, got %+v", key, usages)
+		}
+	}
+	if usage, ok := got["mutable"]; !ok || !usage.IsVarRef {
+		t.Errorf("Expected a variable reference to mutable, got %+v", usages)
+	}
+	if usage, ok := got[`"APP_" + name`]; !ok || !usage.IsPartial || usage.Line != 16 {
+		t.Errorf("Expected dynamic pattern \"APP_\" + name on line 16, got %+v", usages)
+	}
+}
+
 func TestParser_Java_DynamicPatterns(t *testing.T) {
+	skipWithoutLanguage(t, "java")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "Test.java")
 	
@@ -483,6 +817,7 @@ public class Test {
 }
 
 func TestParser_LineNumbers(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.js")
 	
@@ -518,6 +853,7 @@ const key3 = process.env.KEY3;
 }
 
 func TestParser_CodeSnippets(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.js")
 	
@@ -547,6 +883,7 @@ func TestParser_CodeSnippets(t *testing.T) {
 }
 
 func TestParser_RelativePaths(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
 	tmpDir := t.TempDir()
 	subDir := filepath.Join(tmpDir, "src")
 	if err := os.MkdirAll(subDir, 0755); err != nil {
@@ -593,6 +930,239 @@ func TestParser_InvalidLanguage(t *testing.T) {
 	}
 }
 
+func TestParser_LoadsOnlyUsedLanguages(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	parser := NewParser()
+	if loaded := parser.LoadedLanguages(); len(loaded) != 0 {
+		t.Fatalf("Expected no grammar before parsing, got %v", loaded)
+	}
+	for i := 0; i < 2; i++ {
+		usages, err := parser.ParseContent("test.js", []byte("process.env.API_KEY;"), "javascript", "")
+		if err != nil || len(usages) != 1 {
+			t.Fatalf("Expected one usage, got %+v, %v", usages, err)
+		}
+	}
+	if loaded := parser.LoadedLanguages(); !reflect.DeepEqual(loaded, []string{"javascript"}) {
+		t.Errorf("Expected only the JavaScript grammar, got %v", loaded)
+	}
+	if len(parser.grammars.queries) != 1 {
+		t.Errorf("Expected the query to be compiled once, got %d queries", len(parser.grammars.queries))
+	}
+}
+
+func TestParser_CancelledContext(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	parser := NewParser()
+	_, err := parser.ParseContentContext(ctx, "test.js", []byte("process.env.API_KEY;"), "javascript", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParser_OptionalUsages(t *testing.T) {
+	tests := []struct {
+		lang     string
+		file     string
+		code     string
+		expected map[string]bool // key -> optional
+	}{
+		{
+			lang: "javascript",
+			file: "test.js",
+			code: `
+const port = process.env.PORT || 3000;
+const host = (process.env["HOST"]) ?? "localhost";
+const secret = process.env.SECRET;
+const flag = process.env.FLAG && true;
+`,
+			expected: map[string]bool{"PORT": true, "HOST": true, "SECRET": false, "FLAG": false},
+		},
+		{
+			lang: "typescript",
+			file: "test.ts",
+			code: `const port: string = process.env.PORT ?? "3000";`,
+			expected: map[string]bool{"PORT": true},
+		},
+		{
+			lang: "go",
+			file: "main.go",
+			code: `package main
+
+import "os"
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	if host := os.Getenv("HOST"); host != "" {
+		println(host)
+	}
+	region, ok := os.LookupEnv("REGION")
+	secret := os.Getenv("SECRET")
+	println(region, ok, secret)
+}
+`,
+			expected: map[string]bool{"PORT": true, "HOST": true, "REGION": true, "SECRET": false},
+		},
+		{
+			lang: "python",
+			file: "test.py",
+			code: `import os
+port = os.getenv("PORT", "8080")
+host = os.environ.get("HOST", default="localhost")
+debug = os.getenv("DEBUG") or "0"
+name = os.environ.get("NAME")
+secret = os.environ["SECRET"]
+`,
+			expected: map[string]bool{"PORT": true, "HOST": true, "DEBUG": true, "NAME": false, "SECRET": false},
+		},
+		{
+			lang: "rust",
+			file: "main.rs",
+			code: `use std::env;
+fn main() {
+    let port = env::var("PORT").unwrap_or("8080".to_string());
+    let level = std::env::var("LOG_LEVEL").ok();
+    if let Ok(host) = env::var("HOST") {}
+    let secret = env::var("SECRET").unwrap();
+}
+`,
+			expected: map[string]bool{"PORT": true, "LOG_LEVEL": true, "HOST": true, "SECRET": false},
+		},
+		{
+			lang: "java",
+			file: "Main.java",
+			code: `public class Main {
+    public static void main(String[] args) {
+        String port = System.getenv().getOrDefault("PORT", "8080");
+        String host = Optional.ofNullable(System.getenv("HOST")).orElse("localhost");
+        String region = Objects.requireNonNullElse(System.getenv("REGION"), "eu");
+        String secret = System.getenv("SECRET");
+    }
+}
+`,
+			expected: map[string]bool{"PORT": true, "HOST": true, "REGION": true, "SECRET": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			skipWithoutLanguage(t, tt.lang)
+			parser := NewParser()
+			usages, err := parser.ParseContent(tt.file, []byte(tt.code), tt.lang, "")
+			if err != nil {
+				t.Fatalf("ParseContent failed: %v", err)
+			}
+
+			got := make(map[string]bool)
+			for _, usage := range usages {
+				got[usage.Key] = usage.IsOptional
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParser_ExpectedTypes(t *testing.T) {
+	tests := []struct {
+		lang     string
+		file     string
+		code     string
+		expected map[string]string
+	}{
+		{
+			lang: "go",
+			file: "main.go",
+			code: `package main
+
+import (
+	"os"
+	"strconv"
+)
+
+func main() {
+	port, _ := strconv.Atoi(os.Getenv("PORT"))
+	ratio, _ := strconv.ParseFloat(os.Getenv("RATIO"), 64)
+	debug, _ := strconv.ParseBool(os.Getenv("DEBUG"))
+	name := os.Getenv("NAME")
+	println(port, ratio, debug, name)
+}
+`,
+			expected: map[string]string{"PORT": "integer", "RATIO": "number", "DEBUG": "boolean", "NAME": ""},
+		},
+		{
+			lang: "javascript",
+			file: "app.js",
+			code: `const port = parseInt(process.env.PORT, 10);
+const ratio = Number(process.env["RATIO"]);
+const timeout = +process.env.TIMEOUT;
+const name = process.env.NAME;
+`,
+			expected: map[string]string{"PORT": "integer", "RATIO": "number", "TIMEOUT": "number", "NAME": ""},
+		},
+		{
+			lang: "python",
+			file: "app.py",
+			code: `import os
+port = int(os.environ["PORT"])
+ratio = float(os.getenv("RATIO", "0.5"))
+name = str(os.getenv("NAME"))
+`,
+			expected: map[string]string{"PORT": "integer", "RATIO": "number", "NAME": ""},
+		},
+		{
+			lang: "java",
+			file: "Main.java",
+			code: `public class Main {
+    public static void main(String[] args) {
+        int port = Integer.parseInt(System.getenv("PORT"));
+        boolean debug = Boolean.parseBoolean(System.getenv("DEBUG"));
+        String name = System.getenv("NAME");
+    }
+}
+`,
+			expected: map[string]string{"PORT": "integer", "DEBUG": "boolean", "NAME": ""},
+		},
+		{
+			lang: "rust",
+			file: "main.rs",
+			code: `use std::env;
+fn main() {
+    let port = env::var("PORT").unwrap().parse::<u16>().unwrap();
+    let ratio = env::var("RATIO").expect("ratio").parse::<f64>().unwrap();
+    let name = env::var("NAME").unwrap();
+}
+`,
+			expected: map[string]string{"PORT": "integer", "RATIO": "number", "NAME": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			skipWithoutLanguage(t, tt.lang)
+			parser := NewParser()
+			usages, err := parser.ParseContent(tt.file, []byte(tt.code), tt.lang, "")
+			if err != nil {
+				t.Fatalf("ParseContent failed: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, usage := range usages {
+				got[usage.Key] = usage.Type
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 || 
 		(len(s) > len(substr) && (s[:len(substr)] == substr || 
@@ -609,3 +1179,61 @@ func containsMiddle(s, substr string) bool {
 	return false
 }
 
+
+func TestParser_Encodings(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	utf16 := []byte{0xff, 0xfe}
+	for _, c := range []byte("// r\xe9sum\xe9\r\nconst key = process.env.API_KEY;\r\n") {
+		utf16 = append(utf16, c, 0)
+	}
+	files := map[string][]byte{
+		"utf16.js":  utf16,
+		"latin1.js": []byte("// caf\xe9\nconst key = process.env.API_KEY;\n"),
+		"bom.js":    []byte("\xef\xbb\xbfconst key = process.env.API_KEY;\n"),
+	}
+
+	parser := NewParser()
+	for name, content := range files {
+		filePath := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(filePath, content, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		usages, err := parser.ParseFile(filePath, "javascript", tmpDir)
+		if err != nil {
+			t.Fatalf("%s: ParseFile failed: %v", name, err)
+		}
+		if len(usages) != 1 || usages[0].Key != "API_KEY" {
+			t.Errorf("%s: expected a usage of API_KEY, got %+v", name, usages)
+		}
+	}
+
+	filePath := filepath.Join(tmpDir, "binary.js")
+	if err := os.WriteFile(filePath, []byte{'a', 0xff, 0x01}, 0644); err != nil {
+		t.Fatalf("Failed to write binary.js: %v", err)
+	}
+	if _, err := parser.ParseFile(filePath, "javascript", tmpDir); !errors.Is(err, charset.ErrUndecodable) {
+		t.Errorf("Expected an undecodable file error, got %v", err)
+	}
+}
+
+func TestParser_CRLF(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	code := "const key = process.env[\n  \"PREFIX_\" +\n  name\n];\nconst url = process.env.API_URL;\n"
+
+	parser := NewParser()
+	lf, err := parser.ParseContent("app.js", []byte(code), "javascript", "")
+	if err != nil {
+		t.Fatalf("ParseContent failed: %v", err)
+	}
+	crlf, err := parser.ParseContent("app.js", []byte(strings.ReplaceAll(code, "\n", "\r\n")), "javascript", "")
+	if err != nil {
+		t.Fatalf("ParseContent failed: %v", err)
+	}
+	if len(lf) != 2 {
+		t.Fatalf("Expected 2 usages, got %+v", lf)
+	}
+	if !reflect.DeepEqual(crlf, lf) {
+		t.Errorf("Expected the same usages with CRLF line endings:\n%+v\n%+v", crlf, lf)
+	}
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/languages"
+)
+
+// parsePatterns extracts usages with the regular expressions of a language instead of its Tree-Sitter
+// query, in builds without cgo; see languages.MatchPatterns for what they can't see
+func (p *Parser) parsePatterns(ctx context.Context, filePath string, content []byte, info *languages.LanguageInfo, scanRoot string) ([]analyzer.EnvUsage, error) {
+	if len(info.Patterns) == 0 {
+		return nil, fmt.Errorf("language %s needs a Tree-Sitter grammar, which builds without cgo can't load", info.Name)
+	}
+
+	// Files that embed code are reduced to it, see parseContent
+	var lineMap []int
+	if info.Source != nil {
+		content, lineMap = info.Source(content)
+	}
+	lines := bytes.Split(content, []byte("\n"))
+
+	relPath := relativePath(filePath, scanRoot)
+	var usages []analyzer.EnvUsage
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, match := range languages.MatchPatterns(content, info.Patterns) {
+		line := match.Line
+		if line <= len(lineMap) {
+			line = lineMap[line-1]
+		}
+		usages = append(usages, analyzer.EnvUsage{
+			Key:         match.Key,
+			File:        relPath,
+			Line:        line,
+			CodeSnippet: strings.TrimSpace(string(lines[match.Line-1])),
+			IsPartial:   match.IsPartial,
+			IsVarRef:    match.IsVarRef,
+			IsOptional:  match.Optional,
+		})
+	}
+	return usages, nil
+}
@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/languages"
+)
+
+func TestParser_Patterns(t *testing.T) {
+	info := &languages.LanguageInfo{Name: "patterns-test", Patterns: languages.JavaScriptPatterns}
+	content := []byte("const a = process.env.API_KEY ?? 'x';\n\nconst b = process.env[name];\n")
+
+	usages, err := NewParser().parsePatterns(context.Background(), filepath.Join("root", "src", "app.js"), content, info, "root")
+	if err != nil {
+		t.Fatalf("parsePatterns failed: %v", err)
+	}
+	expected := []analyzer.EnvUsage{
+		{Key: "API_KEY", File: filepath.Join("src", "app.js"), Line: 1, CodeSnippet: "const a = process.env.API_KEY ?? 'x';", IsOptional: true},
+		{Key: "name", File: filepath.Join("src", "app.js"), Line: 3, CodeSnippet: "const b = process.env[name];", IsPartial: true, IsVarRef: true},
+	}
+	if !reflect.DeepEqual(usages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, usages)
+	}
+
+	// A language without patterns can't be parsed without its grammar
+	if _, err := NewParser().parsePatterns(context.Background(), "app.js", content, &languages.LanguageInfo{Name: "custom"}, ""); err == nil {
+		t.Error("Expected an error for a language without patterns")
+	}
+}
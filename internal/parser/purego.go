@@ -0,0 +1,49 @@
+//go:build !cgo || envgrd_purego
+
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/languages"
+)
+
+// grammars is empty in builds without cgo, which match the patterns of languages instead
+type grammars struct{}
+
+func newGrammars() grammars {
+	return grammars{}
+}
+
+// LoadedLanguages returns the languages whose grammar has been loaded, none in builds without cgo
+func (p *Parser) LoadedLanguages() []string {
+	return nil
+}
+
+// CheckGrammar reports whether a language can be parsed, which builds without cgo do with its patterns
+func CheckGrammar(lang string) error {
+	info := languages.GetLanguageInfo(lang)
+	if info == nil {
+		return fmt.Errorf("unsupported language: %s", lang)
+	}
+	if len(info.Patterns) == 0 {
+		return fmt.Errorf("language %s needs a Tree-Sitter grammar, which builds without cgo can't load", lang)
+	}
+	return nil
+}
+
+// Constants returns nil, builds without cgo don't resolve constants
+func (p *Parser) Constants(filePath string, lang string) (map[string]string, error) {
+	return nil, nil
+}
+
+// parseContent extracts usages with the patterns of the language, bypassing the cache
+func (p *Parser) parseContent(ctx context.Context, filePath string, content []byte, lang string, scanRoot string) ([]analyzer.EnvUsage, error) {
+	info := languages.GetLanguageInfo(lang)
+	if info == nil {
+		return nil, fmt.Errorf("unsupported language: %s", lang)
+	}
+	return p.parsePatterns(ctx, filePath, content, info, scanRoot)
+}
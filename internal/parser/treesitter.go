@@ -0,0 +1,440 @@
+//go:build cgo && !envgrd_purego
+
+package parser
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/charset"
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// grammars holds the Tree-Sitter grammars and compiled queries of the languages parsed so far, so that
+// only the languages of the scanned files are loaded
+type grammars struct {
+	languages map[string]*sitter.Language
+	queries   map[string]*sitter.Query // By language and query text
+}
+
+func newGrammars() grammars {
+	return grammars{languages: make(map[string]*sitter.Language), queries: make(map[string]*sitter.Query)}
+}
+
+// getLanguage returns a language grammar for the given language, loading it if needed
+func (p *Parser) getLanguage(lang string) (*sitter.Language, error) {
+	p.mu.RLock()
+	if language, ok := p.grammars.languages[lang]; ok {
+		p.mu.RUnlock()
+		return language, nil
+	}
+	p.mu.RUnlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Double-check after acquiring write lock
+	if language, ok := p.grammars.languages[lang]; ok {
+		return language, nil
+	}
+
+	// Load language grammar
+	language, err := loadLanguage(lang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load language %s: %w", lang, err)
+	}
+
+	p.logger.Debug("loaded grammar", "language", lang)
+	p.grammars.languages[lang] = language
+	return language, nil
+}
+
+// getQuery returns the compiled query of a language, compiling it on first use
+// Queries are only read while matching, so one compiled query serves all files of the language
+func (p *Parser) getQuery(lang string, language *sitter.Language, queryStr string) (*sitter.Query, error) {
+	key := lang + "\x00" + queryStr
+	p.mu.RLock()
+	query, ok := p.grammars.queries[key]
+	p.mu.RUnlock()
+	if ok {
+		return query, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if query, ok := p.grammars.queries[key]; ok {
+		return query, nil
+	}
+	query, queryErr := sitter.NewQuery(language, queryStr)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	p.grammars.queries[key] = query
+	return query, nil
+}
+
+// LoadedLanguages returns the languages whose grammar has been loaded, sorted
+func (p *Parser) LoadedLanguages() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Sorted(maps.Keys(p.grammars.languages))
+}
+
+// Constants returns the string constants defined in a file, or nil when its language doesn't
+// resolve constants
+func (p *Parser) Constants(filePath string, lang string) (map[string]string, error) {
+	langInfo := languages.GetLanguageInfo(lang)
+	if langInfo == nil || langInfo.Constants == nil {
+		return nil, nil
+	}
+	language, err := p.getLanguage(lang)
+	if err != nil {
+		return nil, err
+	}
+	content, _, err := charset.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	tsParser := sitter.NewParser()
+	defer tsParser.Close()
+	if err := tsParser.SetLanguage(language); err != nil {
+		return nil, fmt.Errorf("failed to set language: %w", err)
+	}
+	tree := tsParser.Parse(content, nil)
+	if tree == nil {
+		return nil, fmt.Errorf("tree-sitter failed to parse the file")
+	}
+	defer tree.Close()
+	return langInfo.Constants(tree.RootNode(), content), nil
+}
+
+// parseContent parses content with Tree-Sitter and extracts usages, bypassing the cache
+func (p *Parser) parseContent(ctx context.Context, filePath string, content []byte, lang string, scanRoot string) ([]analyzer.EnvUsage, error) {
+	// Get language grammar
+	language, err := p.getLanguage(lang)
+	if err != nil {
+		p.logger.Debug("failed to load language", "file", filePath, "language", lang, "error", err)
+		return nil, err
+	}
+	if language == nil {
+		return nil, fmt.Errorf("no grammar available for language: %s", lang)
+	}
+
+	// Parse the file using the official tree-sitter API
+	// Create a new parser for each file to avoid CGO concurrency issues
+	// Tree-sitter parsers are not thread-safe when used concurrently
+	tsParser := sitter.NewParser()
+	defer tsParser.Close()
+	if err := tsParser.SetLanguage(language); err != nil {
+		return []analyzer.EnvUsage{}, fmt.Errorf("failed to set language: %w", err)
+	}
+
+	// Files that embed code (e.g., the <script> blocks of a .vue file) are reduced to it, keeping line numbers
+	// lineMap maps the lines of the extracted code back to the file when they moved (e.g., notebook cells)
+	var lineMap []int
+	if info := languages.GetLanguageInfo(lang); info != nil && info.Source != nil {
+		content, lineMap = info.Source(content)
+	}
+
+	// Tree-sitter polls the progress callback while parsing, returning true aborts the parse
+	cancelled := func() bool { return ctx.Err() != nil }
+	contentLen := len(content)
+	readContent := func(offset int, _ sitter.Point) []byte {
+		if offset < contentLen {
+			return content[offset:]
+		}
+		return []byte{}
+	}
+
+	var rootNode *sitter.Node
+	tree := tsParser.ParseWithOptions(readContent, nil, &sitter.ParseOptions{
+		ProgressCallback: func(sitter.ParseState) bool { return cancelled() },
+	})
+	if err := ctx.Err(); err != nil {
+		if tree != nil {
+			tree.Close()
+		}
+		return nil, err
+	}
+	if tree != nil {
+		rootNode = tree.RootNode()
+		defer tree.Close()
+	} else {
+		p.logger.Debug("parse returned nil tree", "file", filePath, "language", lang)
+	}
+
+	// Without a syntax tree nothing can be extracted, report the file as unanalyzed
+	if rootNode == nil {
+		return nil, fmt.Errorf("tree-sitter failed to parse the file")
+	}
+
+	// Get language-specific query and extractor
+	langInfo := languages.GetLanguageInfo(lang)
+	if langInfo == nil {
+		return nil, fmt.Errorf("unsupported language: %s", lang)
+	}
+
+	// Create query - trim whitespace to avoid parsing issues
+	queryStr := strings.TrimSpace(langInfo.Query)
+	if queryStr == "" {
+		return nil, fmt.Errorf("empty query for language: %s", lang)
+	}
+
+	query, queryErr := p.getQuery(lang, language, queryStr)
+	if queryErr != nil {
+		// Query creation failed - this might be due to grammar compatibility
+		// Return an error so the scan records the file as unanalyzed and continues
+		p.logger.Debug("query creation failed", "file", filePath, "language", lang, "error", queryErr,
+			"root_node", rootNode.GrammarName(), "children", rootNode.ChildCount())
+		p.logger.Log(ctx, logging.LevelTrace, "query text", "language", lang, "query", queryStr)
+		return nil, fmt.Errorf("failed to compile %s query: %w", lang, queryErr)
+	}
+
+	// String constants the file defines, to resolve variable references to them
+	var constants map[string]string
+	if langInfo.Constants != nil {
+		constants = langInfo.Constants(rootNode, content)
+	}
+
+	// Execute query using QueryCursor
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	matches := cursor.MatchesWithOptions(query, rootNode, content, sitter.QueryCursorOptions{
+		ProgressCallback: func(sitter.QueryCursorState) bool { return cancelled() },
+	})
+
+	// Collect matches with node information
+	type matchInfo struct {
+		key         string
+		node        *sitter.Node
+		codeSnippet string
+		isPartial   bool
+		isVarRef    bool
+		isOptional  bool
+		typ         string
+		fullExpr    string
+	}
+	var matchInfos []matchInfo
+
+	// Get capture names for lookup
+	captureNames := query.CaptureNames()
+
+	for {
+		match := matches.Next()
+		if match == nil {
+			break
+		}
+
+		matchMap := make(map[string]string)
+		var keyNode *sitter.Node
+		var objNode *sitter.Node
+		var propNode *sitter.Node
+		var fullMatchNode *sitter.Node
+		var leftStrNode *sitter.Node
+		var rightStrNode *sitter.Node
+		var varNode *sitter.Node
+		var fullExprNode *sitter.Node
+
+		for _, capture := range match.Captures {
+			// Get capture name from index
+			captureIndex := capture.Index
+			if int(captureIndex) < len(captureNames) {
+				captureName := captureNames[captureIndex]
+				captureNode := &capture.Node // Convert to pointer
+				captureText := string(content[captureNode.StartByte():captureNode.EndByte()])
+				matchMap[captureName] = captureText
+
+				switch captureName {
+				case "key":
+					keyNode = captureNode
+				case "obj":
+					objNode = captureNode
+				case "prop":
+					propNode = captureNode
+				case "left_str":
+					leftStrNode = captureNode
+				case "right_str":
+					rightStrNode = captureNode
+				case "var":
+					varNode = captureNode
+				case "full_expr":
+					fullExprNode = captureNode
+				}
+
+				// Get the full member_expression/subscript_expression node for context
+				if captureName == "key" || captureName == "left_str" || captureName == "right_str" || captureName == "var" || captureName == "full_expr" {
+					// Use the match node itself for context
+					if fullMatchNode == nil {
+						fullMatchNode = captureNode
+					}
+				}
+			}
+		}
+
+		// Extract keys from this match
+		// For JavaScript/TypeScript, use the special extractor that returns partial match info
+		var matches []languages.EnvVarMatch
+		if langInfo.ExtractorWithPartial != nil {
+			matches = langInfo.ExtractorWithPartial([]map[string]string{matchMap})
+		} else if langInfo.Extractor != nil {
+			// For other languages, convert string results to EnvVarMatch
+			keys := langInfo.Extractor([]map[string]string{matchMap})
+			for _, key := range keys {
+				matches = append(matches, languages.EnvVarMatch{Key: key, IsPartial: false})
+			}
+		}
+
+		for _, match := range matches {
+			keyNode := keyNode
+			// A reference to a string constant is a static key
+			if value, ok := constants[match.Key]; ok && match.IsVarRef && value != "" && varNode != nil {
+				match = languages.EnvVarMatch{Key: value}
+				keyNode = varNode
+			}
+			key := match.Key
+			isPartial := match.IsPartial
+
+			// Determine which node to use for line number and context
+			var nodeForContext *sitter.Node
+			if isPartial {
+				// For partial matches, prefer the full expression node, then string node, then var node
+				if fullExprNode != nil {
+					nodeForContext = fullExprNode
+				} else if leftStrNode != nil {
+					nodeForContext = leftStrNode
+				} else if rightStrNode != nil {
+					nodeForContext = rightStrNode
+				} else if varNode != nil {
+					nodeForContext = varNode
+				} else {
+					nodeForContext = keyNode
+				}
+			} else {
+				nodeForContext = keyNode
+			}
+
+			// For variable references, if we don't have a specific node, use the full match node
+			if nodeForContext == nil && match.IsVarRef && fullMatchNode != nil {
+				nodeForContext = fullMatchNode
+			}
+
+			if nodeForContext != nil {
+				// Get code context around the match
+				startByte := nodeForContext.StartByte()
+				endByte := nodeForContext.EndByte()
+				if fullMatchNode != nil {
+					startByte = fullMatchNode.StartByte()
+					endByte = fullMatchNode.EndByte()
+				}
+
+				// Get surrounding context (100 chars before and after)
+				contextStart := int(startByte) - 100
+				if contextStart < 0 {
+					contextStart = 0
+				}
+				contextEnd := int(endByte) + 100
+				if contextEnd > len(content) {
+					contextEnd = len(content)
+				}
+
+				// Get code snippet from the line
+				startPos := nodeForContext.StartPosition()
+				lineNum := int(startPos.Row)
+				lineStart := 0
+				for i := 0; i < len(content) && lineNum > 0; i++ {
+					if content[i] == '\n' {
+						lineNum--
+						lineStart = i + 1
+					}
+				}
+				lineEnd := lineStart
+				for lineEnd < len(content) && content[lineEnd] != '\n' {
+					lineEnd++
+				}
+				codeSnippet := string(content[lineStart:lineEnd])
+				// Trim whitespace
+				codeSnippet = strings.TrimSpace(codeSnippet)
+
+				// Log the match for debugging (only if trace logging is enabled)
+				if p.logger.Enabled(ctx, logging.LevelTrace) {
+					line := int(startPos.Row) + 1
+					fullText := string(content[startByte:endByte])
+					context := string(content[contextStart:contextEnd])
+					attrs := []any{"file", filePath, "line", line, "full_match", fullText, "key", key}
+					if objNode != nil {
+						attrs = append(attrs, "object", string(content[objNode.StartByte():objNode.EndByte()]))
+					}
+					if propNode != nil {
+						attrs = append(attrs, "property", string(content[propNode.StartByte():propNode.EndByte()]))
+					}
+					attrs = append(attrs, "context", context)
+					p.logger.Log(ctx, logging.LevelTrace, "match", attrs...)
+				}
+
+				matchInfos = append(matchInfos, matchInfo{
+					key:         key,
+					node:        nodeForContext,
+					codeSnippet: codeSnippet,
+					isPartial:   isPartial,
+					isVarRef:    match.IsVarRef,
+					isOptional:  !isPartial && keyNode != nil && langInfo.HasFallback != nil && langInfo.HasFallback(keyNode, content),
+					typ:         expectedType(langInfo, keyNode, content, isPartial),
+					fullExpr:    match.FullExpr,
+				})
+			}
+		}
+	}
+
+	// A cancelled query stops early, so the matches collected so far are incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Convert to EnvUsage with line numbers
+	var usages []analyzer.EnvUsage
+	seen := make(map[string]bool)
+
+	relPath := relativePath(filePath, scanRoot)
+
+	for _, matchInfo := range matchInfos {
+		// Get line number from node (1-indexed)
+		startPos := matchInfo.node.StartPosition()
+		line := int(startPos.Row) + 1
+		if line <= len(lineMap) {
+			line = lineMap[line-1]
+		}
+
+		usageKey := fmt.Sprintf("%s:%s:%d", relPath, matchInfo.key, line)
+		if !seen[usageKey] {
+			usages = append(usages, analyzer.EnvUsage{
+				Key:         matchInfo.key,
+				File:        relPath,
+				Line:        line,
+				CodeSnippet: matchInfo.codeSnippet,
+				IsPartial:   matchInfo.isPartial,
+				IsVarRef:    matchInfo.isVarRef,
+				FullExpr:    matchInfo.fullExpr,
+				IsOptional:  matchInfo.isOptional,
+				Type:        matchInfo.typ,
+			})
+			seen[usageKey] = true
+		}
+	}
+
+	return usages, nil
+}
+
+// expectedType returns the type the code around a lookup parses its value as, empty when unknown
+func expectedType(langInfo *languages.LanguageInfo, keyNode *sitter.Node, content []byte, isPartial bool) string {
+	if isPartial || keyNode == nil || langInfo.ExpectedType == nil {
+		return ""
+	}
+	return langInfo.ExpectedType(keyNode, content)
+}
@@ -1,9 +1,23 @@
 package scanner
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/jenian/envgrd/internal/ansible"
+	"github.com/jenian/envgrd/internal/charset"
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/owners"
 )
 
 // Language represents a programming language
@@ -12,32 +26,65 @@ type Language string
 const (
 	LanguageJavaScript Language = "javascript"
 	LanguageTypeScript Language = "typescript"
+	LanguageTSX        Language = "tsx"
+	LanguageVue        Language = "vue"
+	LanguageSvelte     Language = "svelte"
+	LanguageAstro      Language = "astro"
 	LanguageGo         Language = "go"
 	LanguagePython     Language = "python"
+	LanguageJupyter    Language = "jupyter"
 	LanguageRust       Language = "rust"
 	LanguageJava       Language = "java"
+	LanguageTemplate   Language = "template" // Text template with ${VAR} references, see SetTemplateGlobs
+	LanguageAnsible    Language = "ansible"  // Ansible playbook or role tasks with env lookups
 	LanguageUnknown    Language = "unknown"
 )
 
+// DefaultMaxFileSize is the size above which files are skipped unless configured otherwise
+const DefaultMaxFileSize = 5 * 1024 * 1024
+
+// sniffSize is how many leading bytes are inspected to detect binary content
+const sniffSize = 8000
+
 // FileInfo contains information about a file to be scanned
 type FileInfo struct {
 	Path          string
 	Language      Language
-	InIgnoredPath bool // True if this file is in a folder that should be ignored
+	InIgnoredPath bool   // True if this file is in a folder that should be ignored
+	Content       []byte // UTF-8 content parsed instead of the file at Path when not nil, see Scanner.Buffer
+}
+
+// Decision records why discovery included or left out a file, or didn't walk a directory, see SetDecisions
+type Decision struct {
+	Path     string   `json:"path"`
+	Dir      bool     `json:"dir,omitempty"`      // A directory left out with everything below it
+	Included bool     `json:"included"`           // The file is parsed
+	Language Language `json:"language,omitempty"` // Language of an included file
+	Reason   string   `json:"reason"`             // Why the file was included or left out (e.g., "matches exclude glob *.gen.ts")
 }
 
 // Scanner handles file discovery and filtering
 type Scanner struct {
-	excludeDirs  map[string]bool // Directory names to exclude (e.g., "node_modules")
-	excludePaths []string        // Path patterns to exclude (e.g., "src/config", "k8s/*")
-	excludeGlobs []string
-	includeGlobs []string
-	scanRoot     string // Root path being scanned (for relative path matching)
+	excludeDirs    map[string]bool // Directory names to exclude (e.g., "node_modules")
+	excludePaths   []string        // Path patterns to exclude (e.g., "src/config", "k8s/*")
+	excludeGlobs   []glob
+	includeGlobs   []glob
+	templateGlobs  []glob
+	languages      map[Language]bool   // Languages to discover, all when empty
+	extensions     map[string]Language // Extra extensions (lowercase, with the dot), LanguageUnknown to skip them
+	scanRoot       string              // Root path being scanned (for relative path matching)
+	maxFileSize    int64               // Files larger than this are skipped (0 = no limit)
+	maxDepth       int                 // Maximum depth of files below the scan root (0 = no limit)
+	followSymlinks bool                // Follow symlinked files and directories
+	decisions      func(Decision)      // Receives every decision of discovery, nil when not wanted
+	logger         *slog.Logger
 }
 
 // NewScanner creates a new scanner with default exclusions
 func NewScanner() *Scanner {
 	return &Scanner{
+		logger:      logging.Discard(),
+		maxFileSize: DefaultMaxFileSize,
 		excludeDirs: map[string]bool{
 			// JavaScript/TypeScript
 			"node_modules":    true,
@@ -95,14 +142,107 @@ func NewScanner() *Scanner {
 	}
 }
 
-// SetExcludeGlobs sets glob patterns to exclude
-func (s *Scanner) SetExcludeGlobs(globs []string) {
+// glob is a compiled include, exclude or template pattern
+type glob struct {
+	pattern string
+	re      *regexp.Regexp
+	negated bool // A !pattern, which takes the files it matches back out of the list
+}
+
+// compileGlobs compiles gitignore-style patterns with doublestar semantics (see owners.CompilePattern),
+// matched against paths relative to the scan root: a pattern without a slash matches the file name at
+// any depth (*.gen.ts), others match from the root (src/generated/**), and a directory every file below it
+// Like in a .gitignore file, the last pattern matching a file decides, and a pattern starting with ! negates
+// the ones before it (\! matches a leading !)
+func compileGlobs(patterns []string) ([]glob, error) {
+	globs := make([]glob, 0, len(patterns))
+	for _, pattern := range patterns {
+		expr, negated := strings.CutPrefix(pattern, "!")
+		re, err := owners.CompilePattern(filepath.ToSlash(expr))
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob{pattern: pattern, re: re, negated: negated})
+	}
+	return globs, nil
+}
+
+// SetExcludeGlobs sets glob patterns to exclude, see compileGlobs
+func (s *Scanner) SetExcludeGlobs(patterns []string) error {
+	globs, err := compileGlobs(patterns)
 	s.excludeGlobs = globs
+	return err
 }
 
-// SetIncludeGlobs sets glob patterns to include (overrides excludes)
-func (s *Scanner) SetIncludeGlobs(globs []string) {
+// SetIncludeGlobs sets glob patterns to include (overrides excludes), see compileGlobs
+func (s *Scanner) SetIncludeGlobs(patterns []string) error {
+	globs, err := compileGlobs(patterns)
 	s.includeGlobs = globs
+	return err
+}
+
+// SetTemplateGlobs sets glob patterns of text templates (e.g., "*.tpl") to scan for ${VAR} references,
+// see compileGlobs
+func (s *Scanner) SetTemplateGlobs(patterns []string) error {
+	globs, err := compileGlobs(patterns)
+	s.templateGlobs = globs
+	return err
+}
+
+// SetLanguages restricts discovery to files of the given languages, all when empty
+// Names are those of the languages registry plus template and ansible; typescript includes tsx
+func (s *Scanner) SetLanguages(names []string) error {
+	enabled := make(map[Language]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if err := checkLanguage(name); err != nil {
+			return err
+		}
+		enabled[Language(name)] = true
+		if Language(name) == LanguageTypeScript {
+			enabled[LanguageTSX] = true
+		}
+	}
+	s.languages = nil
+	if len(enabled) > 0 {
+		s.languages = enabled
+	}
+	return nil
+}
+
+// SkipExtension maps an extension to no language in SetExtensions, leaving its files out
+const SkipExtension = "skip"
+
+// SetExtensions maps more file extensions (e.g., ".mts") to languages, named like in SetLanguages,
+// or to SkipExtension; they take precedence over the extensions of the languages registry
+func (s *Scanner) SetExtensions(extensions map[string]string) error {
+	s.extensions = make(map[string]Language, len(extensions))
+	for ext, name := range extensions {
+		name = strings.ToLower(strings.TrimSpace(name))
+		ext = "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+		if name == SkipExtension {
+			s.extensions[ext] = LanguageUnknown
+			continue
+		}
+		if err := checkLanguage(name); err != nil {
+			return fmt.Errorf("%s: %w", ext, err)
+		}
+		s.extensions[ext] = Language(name)
+	}
+	return nil
+}
+
+// checkLanguage checks that a language of SetLanguages or SetExtensions exists
+func checkLanguage(name string) error {
+	supported := append(languages.Names(), string(LanguageTemplate), string(LanguageAnsible))
+	if !slices.Contains(supported, name) {
+		slices.Sort(supported)
+		return fmt.Errorf("unknown language %q (supported: %s)", name, strings.Join(supported, ", "))
+	}
+	return nil
 }
 
 // AddExcludeDirs adds additional directories to exclude from scanning
@@ -119,59 +259,164 @@ func (s *Scanner) AddExcludeDirs(dirs []string) {
 	}
 }
 
+// SetLogger sets the logger used to trace skipped directories and files at debug level
+func (s *Scanner) SetLogger(logger *slog.Logger) {
+	s.logger = logging.OrDiscard(logger)
+}
+
+// SetMaxFileSize sets the size in bytes above which files are skipped with a warning (0 disables the limit)
+func (s *Scanner) SetMaxFileSize(size int64) {
+	s.maxFileSize = size
+}
+
+// SetFollowSymlinks enables following symlinked files and directories
+// Each real directory is walked at most once, so symlink cycles are safe
+func (s *Scanner) SetFollowSymlinks(follow bool) {
+	s.followSymlinks = follow
+}
+
+// SetMaxDepth limits how deep below the scan root files are discovered (0 disables the limit)
+// Depth 1 means only files directly in the root, like find -maxdepth
+func (s *Scanner) SetMaxDepth(depth int) {
+	s.maxDepth = depth
+}
+
+// SetDecisions makes discovery pass every file it includes or leaves out, and every directory it doesn't walk,
+// to record, in walk order, e.g. to explain why a file is or isn't scanned
+func (s *Scanner) SetDecisions(record func(Decision)) {
+	s.decisions = record
+}
+
+// decide records a decision of discovery
+func (s *Scanner) decide(decision Decision) {
+	if s.decisions != nil {
+		s.decisions(decision)
+	}
+}
+
 // SetScanRoot sets the root path being scanned (for relative path matching)
 func (s *Scanner) SetScanRoot(root string) {
 	s.scanRoot = root
 }
 
 // detectLanguage determines the language from file extension
+// Extensions come from the languages registry, so registered custom languages are detected too
 func detectLanguage(path string) Language {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".js", ".jsx", ".mjs":
-		return LanguageJavaScript
-	case ".ts", ".tsx":
-		return LanguageTypeScript
-	case ".go":
-		return LanguageGo
-	case ".py":
-		return LanguagePython
-	case ".rs":
-		return LanguageRust
-	case ".java":
-		return LanguageJava
-	default:
+	if lang := languages.ForExtension(filepath.Ext(path)); lang != "" {
+		return Language(lang)
+	}
+	return LanguageUnknown
+}
+
+// interpreters maps the interpreters of shebang lines, without version suffixes, to languages
+var interpreters = map[string]Language{
+	"python":      LanguagePython,
+	"node":        LanguageJavaScript,
+	"nodejs":      LanguageJavaScript,
+	"bun":         LanguageJavaScript,
+	"deno":        LanguageTypeScript,
+	"ts-node":     LanguageTypeScript,
+	"tsx":         LanguageTypeScript,
+	"rust-script": LanguageRust,
+}
+
+// detectShebang determines the language of an extensionless script (e.g., bin/deploy) from its shebang
+// line, such as #!/usr/bin/env python3 or #!/usr/local/bin/node --no-warnings
+// Returns LanguageUnknown for other interpreters (e.g., sh) and languages left out of the build
+func detectShebang(path string) Language {
+	file, err := os.Open(path)
+	if err != nil {
 		return LanguageUnknown
 	}
+	defer file.Close()
+
+	line, err := bufio.NewReaderSize(file, 256).ReadSlice('\n')
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return LanguageUnknown
+	}
+	return shebangLanguage(line)
 }
 
-// matchesGlob checks if a path matches any of the glob patterns
-func matchesGlob(path string, globs []string) bool {
+// shebangLanguage determines the language of a script from its first line, see detectShebang
+func shebangLanguage(line []byte) Language {
+	if !bytes.HasPrefix(line, []byte("#!")) {
+		return LanguageUnknown
+	}
+	fields := strings.Fields(string(line[2:]))
+	if len(fields) > 0 && filepath.Base(fields[0]) == "env" {
+		// Skip the options (e.g., -S) and variable assignments of env
+		fields = slices.DeleteFunc(fields[1:], func(field string) bool {
+			return strings.HasPrefix(field, "-") || strings.Contains(field, "=")
+		})
+	}
+	if len(fields) == 0 {
+		return LanguageUnknown
+	}
+	// python3.12 -> python
+	name := strings.TrimRight(filepath.Base(fields[0]), "0123456789.")
+	lang, ok := interpreters[name]
+	if !ok || languages.GetLanguageInfo(string(lang)) == nil {
+		return LanguageUnknown
+	}
+	return lang
+}
+
+// DetectLanguage determines the language of a single file from its extension
+// Used by callers that handle files outside of a directory walk (e.g., editor documents)
+func DetectLanguage(path string) Language {
+	return detectLanguage(path)
+}
+
+// matchingGlob reports whether the path of a file, relative to the scan root, is matched by the glob
+// patterns, and returns the last pattern matching it, which decides (empty when none does)
+func (s *Scanner) matchingGlob(path string, globs []glob) (string, bool) {
+	if len(globs) == 0 {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.scanRoot, path)
+	if err != nil || rel == "." {
+		// The scan root itself is a file
+		rel = filepath.Base(path)
+	}
+	rel = filepath.ToSlash(rel)
+	pattern, matched := "", false
 	for _, glob := range globs {
-		matched, _ := filepath.Match(glob, filepath.Base(path))
-		if matched {
-			return true
-		}
-		// Also try matching against full path
-		matched, _ = filepath.Match(glob, path)
-		if matched {
-			return true
+		if glob.re.MatchString(rel) {
+			pattern, matched = glob.pattern, !glob.negated
 		}
 	}
-	return false
+	return pattern, matched
 }
 
-// shouldInclude checks if a file should be included based on include/exclude globs
-func (s *Scanner) shouldInclude(path string) bool {
-	// If include globs are specified, file must match at least one
+// isTemplate checks if a file matches one of the template globs
+func (s *Scanner) isTemplate(path string) bool {
+	_, matched := s.matchingGlob(path, s.templateGlobs)
+	return matched
+}
+
+// shouldInclude checks if a file should be included based on include/exclude globs, and returns the
+// glob deciding it (empty when there are none)
+func (s *Scanner) shouldInclude(path string) (bool, string) {
+	// If include globs are specified, file must be matched by them
 	if len(s.includeGlobs) > 0 {
-		return matchesGlob(path, s.includeGlobs)
+		glob, ok := s.matchingGlob(path, s.includeGlobs)
+		switch {
+		case ok:
+			return true, "matches include glob " + glob
+		case glob != "":
+			return false, "taken out of the include globs by " + glob
+		}
+		return false, "doesn't match any include glob"
 	}
-	// If exclude globs are specified, file must not match any
-	if len(s.excludeGlobs) > 0 {
-		return !matchesGlob(path, s.excludeGlobs)
+	// If exclude globs are specified, file must not be matched by them
+	glob, ok := s.matchingGlob(path, s.excludeGlobs)
+	switch {
+	case ok:
+		return false, "matches exclude glob " + glob
+	case glob != "":
+		return true, "taken back out of the exclude globs by " + glob
 	}
-	return true
+	return true, ""
 }
 
 // isInIgnoredPath checks if a file path is within an ignored folder
@@ -213,29 +458,86 @@ func (s *Scanner) isInIgnoredPath(filePath string) bool {
 	return false
 }
 
+// language determines the language of a discovered file, LanguageUnknown to leave it out, and how
+// shebang detects the language of an extensionless file from its first line
+func (s *Scanner) language(ctx context.Context, path string, shebang func() Language) (Language, string) {
+	// Detect language - only process files with recognized extensions (whitelist approach)
+	ext := strings.ToLower(filepath.Ext(path))
+	lang, mapped := s.extensions[ext]
+	if mapped && lang == LanguageUnknown {
+		s.logger.Log(ctx, logging.LevelTrace, "skipping file with a skipped extension", "path", path)
+		return LanguageUnknown, fmt.Sprintf("extension %s is skipped in the extensions config", ext)
+	}
+	reason := "extension " + ext
+	if mapped {
+		reason = fmt.Sprintf("extension %s in the extensions config", ext)
+	} else {
+		lang = detectLanguage(path)
+	}
+	if lang == LanguageUnknown && s.isTemplate(path) {
+		lang, reason = LanguageTemplate, "matches a template glob"
+	}
+	if lang == LanguageUnknown && ansible.IsAnsibleFile(path) {
+		lang, reason = LanguageAnsible, "Ansible playbook or role"
+	}
+	if lang == LanguageUnknown && ext == "" {
+		lang, reason = shebang(), "shebang"
+	}
+	if lang == LanguageUnknown {
+		s.logger.Log(ctx, logging.LevelTrace, "skipping file with unsupported extension", "path", path)
+		if ext == "" {
+			return LanguageUnknown, "no extension or shebang of a supported language"
+		}
+		return LanguageUnknown, fmt.Sprintf("unsupported extension %s", ext)
+	}
+	if s.languages != nil && !s.languages[lang] {
+		s.logger.Log(ctx, logging.LevelTrace, "skipping file of a disabled language", "path", path, "language", string(lang))
+		return LanguageUnknown, fmt.Sprintf("%s isn't an enabled language", lang)
+	}
+	return lang, reason
+}
+
+// Buffer returns the file to parse for content, the unsaved state of the file at path below rootPath
+// (e.g., an editor buffer piped to stdin), with the language and filters discovery would apply
+// ok is false when discovery would leave the file out; content isn't checked for size or binary data
+func (s *Scanner) Buffer(rootPath string, path string, content []byte) (_ FileInfo, ok bool) {
+	s.scanRoot = rootPath
+	if ok, _ := s.shouldInclude(path); !ok {
+		s.logger.Debug("skipping file excluded by glob", "path", path)
+		return FileInfo{}, false
+	}
+	rel, err := filepath.Rel(rootPath, path)
+	if err == nil {
+		for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+			if s.excludeDirs[dir] {
+				s.logger.Debug("skipping file in an excluded directory", "path", path, "directory", dir)
+				return FileInfo{}, false
+			}
+		}
+	}
+	lang, _ := s.language(context.Background(), path, func() Language {
+		line, _, _ := bytes.Cut(content, []byte("\n"))
+		return shebangLanguage(line)
+	})
+	if lang == LanguageUnknown {
+		return FileInfo{}, false
+	}
+	return FileInfo{Path: path, Language: lang, InIgnoredPath: s.isInIgnoredPath(path), Content: content}, true
+}
+
 // Scan recursively walks a directory and returns files to parse
 func (s *Scanner) Scan(rootPath string) ([]FileInfo, error) {
+	return s.ScanContext(context.Background(), rootPath)
+}
+
+// ScanContext is like Scan but stops walking as soon as ctx is cancelled
+func (s *Scanner) ScanContext(ctx context.Context, rootPath string) ([]FileInfo, error) {
 	var files []FileInfo
 
 	// Set scan root for relative path matching
 	s.scanRoot = rootPath
 
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories that should be excluded (by name, not by path)
-		// We want to scan files in ignored paths to track variables
-		if info.IsDir() {
-			// Only skip if it's excluded by name (like node_modules, vendor, etc.)
-			// Don't skip if it's only in an ignored path - we want to scan those files
-			if s.excludeDirs[info.Name()] {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
+	visitFile := func(path string, info os.FileInfo) {
 		// Check if this file is in an ignored path
 		inIgnoredPath := s.isInIgnoredPath(path)
 
@@ -243,24 +545,162 @@ func (s *Scanner) Scan(rootPath string) ([]FileInfo, error) {
 		// but we'll exclude them from the missing report
 
 		// Check include/exclude globs
-		if !s.shouldInclude(path) {
-			return nil
+		included, globReason := s.shouldInclude(path)
+		if !included {
+			s.logger.Debug("skipping file excluded by glob", "path", path)
+			s.decide(Decision{Path: path, Reason: globReason})
+			return
 		}
 
-		// Detect language - only process files with recognized extensions (whitelist approach)
-		lang := detectLanguage(path)
+		lang, reason := s.language(ctx, path, func() Language { return detectShebang(path) })
 		if lang == LanguageUnknown {
-			return nil
+			s.decide(Decision{Path: path, Reason: reason})
+			return
+		}
+
+		// Skip oversized files (e.g., minified bundles) and binaries with a source extension
+		if s.maxFileSize > 0 && info.Size() > s.maxFileSize {
+			s.logger.Warn(fmt.Sprintf("skipping %s: file size %d bytes exceeds the %d byte limit", path, info.Size(), s.maxFileSize))
+			s.decide(Decision{Path: path, Reason: fmt.Sprintf("file size %d bytes exceeds the %d byte limit", info.Size(), s.maxFileSize)})
+			return
+		}
+		if binary, err := isBinaryFile(path); err != nil {
+			s.logger.Debug("failed to inspect file", "path", path, "error", err)
+		} else if binary {
+			s.logger.Warn(fmt.Sprintf("skipping %s: file looks binary", path))
+			s.decide(Decision{Path: path, Reason: "file looks binary"})
+			return
 		}
 
+		if globReason != "" {
+			reason += ", " + globReason
+		}
+		if inIgnoredPath {
+			reason += ", in an ignored folder (its usages are never reported missing)"
+		}
+		s.decide(Decision{Path: path, Included: true, Language: lang, Reason: reason})
+
 		files = append(files, FileInfo{
 			Path:          path,
 			Language:      lang,
 			InIgnoredPath: inIgnoredPath,
 		})
+	}
 
-		return nil
-	})
+	info, err := os.Stat(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		visitFile(rootPath, info)
+		return files, nil
+	}
+
+	visited := make(map[string]bool)
+	if s.followSymlinks {
+		// Remember real directory paths so symlink cycles are only walked once
+		if realRoot, err := filepath.EvalSymlinks(rootPath); err == nil {
+			visited[realRoot] = true
+		}
+	}
 
+	err = s.walk(ctx, rootPath, 0, visited, visitFile)
 	return files, err
 }
+
+// walk visits the entries of dir in lexical order, recursing into subdirectories
+// depth is the depth of dir below the scan root (0 for the root itself)
+func (s *Scanner) walk(ctx context.Context, dir string, depth int, visited map[string]bool, visitFile func(string, os.FileInfo)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// Abort the walk if the caller gave up (timeout, Ctrl-C)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !s.followSymlinks {
+				s.logger.Debug("skipping symlink", "path", path)
+				s.decide(Decision{Path: path, Reason: "symlink, not followed without following symlinks"})
+				continue
+			}
+			target, err := os.Stat(path)
+			if err != nil {
+				s.logger.Debug("skipping broken symlink", "path", path, "error", err)
+				s.decide(Decision{Path: path, Reason: "broken symlink"})
+				continue
+			}
+			info = target
+		}
+
+		if !info.IsDir() {
+			visitFile(path, info)
+			continue
+		}
+
+		// Skip directories that should be excluded (by name, not by path)
+		// We want to scan files in ignored paths to track variables
+		if s.excludeDirs[entry.Name()] {
+			s.logger.Debug("skipping excluded directory", "path", path)
+			s.decide(Decision{Path: path, Dir: true, Reason: "excluded directory " + entry.Name()})
+			continue
+		}
+		// Entries of this directory are at depth+2, which must not exceed the limit
+		if s.maxDepth > 0 && depth+1 >= s.maxDepth {
+			s.logger.Debug("skipping directory beyond max depth", "path", path, "max_depth", s.maxDepth)
+			s.decide(Decision{Path: path, Dir: true, Reason: fmt.Sprintf("beyond the max depth of %d", s.maxDepth)})
+			continue
+		}
+		if s.followSymlinks {
+			realPath, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				s.logger.Debug("skipping unresolvable directory", "path", path, "error", err)
+				s.decide(Decision{Path: path, Dir: true, Reason: "unresolvable directory"})
+				continue
+			}
+			if visited[realPath] {
+				s.logger.Debug("skipping already visited directory (symlink cycle or duplicate)", "path", path, "target", realPath)
+				s.decide(Decision{Path: path, Dir: true, Reason: "already walked as " + realPath})
+				continue
+			}
+			visited[realPath] = true
+		}
+
+		if err := s.walk(ctx, path, depth+1, visited, visitFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isBinaryFile reports whether the start of the file isn't text in an encoding charset can decode
+func isBinaryFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	sample := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return looksBinary(sample[:n]), nil
+}
+
+// looksBinary reports whether sample, the start of a file, isn't UTF-8, UTF-16 or Latin-1 text
+func looksBinary(sample []byte) bool {
+	return charset.Detect(sample) == ""
+}
@@ -1,11 +1,31 @@
 package scanner
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
 )
 
+// skipWithoutLanguage skips tests of languages left out of the build with their envgrd_nolang_* tag
+func skipWithoutLanguage(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if languages.GetLanguageInfo(name) == nil {
+			t.Skipf("%s is not built in", name)
+		}
+	}
+}
+
 func TestDetectLanguage(t *testing.T) {
 	tests := []struct {
 		path     string
@@ -15,15 +35,22 @@ func TestDetectLanguage(t *testing.T) {
 		{"test.jsx", LanguageJavaScript},
 		{"test.mjs", LanguageJavaScript},
 		{"test.ts", LanguageTypeScript},
-		{"test.tsx", LanguageTypeScript},
+		{"test.tsx", LanguageTSX},
+		{"App.vue", LanguageVue},
+		{"App.svelte", LanguageSvelte},
+		{"index.astro", LanguageAstro},
 		{"test.go", LanguageGo},
 		{"test.py", LanguagePython},
+		{"analysis.ipynb", LanguageJupyter},
 		{"test.txt", LanguageUnknown},
 		{"test", LanguageUnknown},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.path, func(t *testing.T) {
+			if tt.expected != LanguageUnknown {
+				skipWithoutLanguage(t, string(tt.expected))
+			}
 			result := detectLanguage(tt.path)
 			if result != tt.expected {
 				t.Errorf("detectLanguage(%q) = %v, want %v", tt.path, result, tt.expected)
@@ -33,6 +60,7 @@ func TestDetectLanguage(t *testing.T) {
 }
 
 func TestScanner_Scan(t *testing.T) {
+	skipWithoutLanguage(t, "javascript", "go", "python")
 	tmpDir := t.TempDir()
 
 	// Create test files
@@ -84,6 +112,7 @@ func TestScanner_Scan(t *testing.T) {
 }
 
 func TestScanner_ExcludeGlobs(t *testing.T) {
+	skipWithoutLanguage(t, "javascript", "go")
 	tmpDir := t.TempDir()
 
 	if err := os.WriteFile(filepath.Join(tmpDir, "test.js"), []byte("test"), 0644); err != nil {
@@ -111,3 +140,537 @@ func TestScanner_ExcludeGlobs(t *testing.T) {
 	}
 }
 
+func TestScanner_DoublestarGlobs(t *testing.T) {
+	skipWithoutLanguage(t, "typescript")
+	tmpDir := t.TempDir()
+	for _, name := range []string{"src/app.ts", "src/generated/api/client.ts", "lib/src/generated/util.ts", "src/app.test.ts"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	scanFiles := func(scanner *Scanner) []string {
+		files, err := scanner.Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		var paths []string
+		for _, file := range files {
+			rel, _ := filepath.Rel(tmpDir, file.Path)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return paths
+	}
+
+	// Globs with a slash are anchored to the scan root, the others match file names at any depth
+	scanner := NewScanner()
+	if err := scanner.SetExcludeGlobs([]string{"src/generated/**", "*.test.ts"}); err != nil {
+		t.Fatalf("SetExcludeGlobs failed: %v", err)
+	}
+	if got, want := scanFiles(scanner), []string{"lib/src/generated/util.ts", "src/app.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	scanner = NewScanner()
+	if err := scanner.SetIncludeGlobs([]string{"**/generated/*/*.{ts,js}"}); err != nil {
+		t.Fatalf("SetIncludeGlobs failed: %v", err)
+	}
+	if got, want := scanFiles(scanner), []string{"src/generated/api/client.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if err := NewScanner().SetExcludeGlobs([]string{"src/[a-"}); err == nil {
+		t.Error("Expected an error for an invalid glob")
+	}
+	if err := NewScanner().SetExcludeGlobs([]string{"!"}); err == nil {
+		t.Error("Expected an error for an empty negated glob")
+	}
+}
+
+func TestScanner_NegatedGlobs(t *testing.T) {
+	skipWithoutLanguage(t, "typescript")
+	tmpDir := t.TempDir()
+	for _, name := range []string{"src/app.ts", "src/api.generated.ts", "src/important.generated.ts", "src/app.test.ts"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		includes []string
+		excludes []string
+		want     []string
+		reason   string // Of src/important.generated.ts
+	}{
+		{"exclude then negate", nil, []string{"*.generated.ts", "!important.generated.ts"},
+			[]string{"src/app.test.ts", "src/app.ts", "src/important.generated.ts"}, "extension .ts, taken back out of the exclude globs by !important.generated.ts"},
+		{"last match wins", nil, []string{"!important.generated.ts", "*.generated.ts"},
+			[]string{"src/app.test.ts", "src/app.ts"}, "matches exclude glob *.generated.ts"},
+		{"include then negate", []string{"src/**", "!*.test.ts", "!*.generated.ts"}, nil,
+			[]string{"src/app.ts"}, "taken out of the include globs by !*.generated.ts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner()
+			if err := scanner.SetIncludeGlobs(tt.includes); err != nil {
+				t.Fatalf("SetIncludeGlobs failed: %v", err)
+			}
+			if err := scanner.SetExcludeGlobs(tt.excludes); err != nil {
+				t.Fatalf("SetExcludeGlobs failed: %v", err)
+			}
+			var decisions []Decision
+			scanner.SetDecisions(func(decision Decision) { decisions = append(decisions, decision) })
+			files, err := scanner.Scan(tmpDir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			var got []string
+			for _, file := range files {
+				rel, _ := filepath.Rel(tmpDir, file.Path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			for _, decision := range decisions {
+				if filepath.Base(decision.Path) == "important.generated.ts" && decision.Reason != tt.reason {
+					t.Errorf("Expected reason %q, got %q", tt.reason, decision.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestScanner_Decisions(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	for _, name := range []string{"app.js", "app.gen.js", "README.md", filepath.Join("node_modules", "lib.js")} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmpDir, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	scanner := NewScanner()
+	scanner.SetExcludeGlobs([]string{"*.gen.js"})
+	var decisions []Decision
+	scanner.SetDecisions(func(decision Decision) {
+		decision.Path, _ = filepath.Rel(tmpDir, decision.Path)
+		decisions = append(decisions, decision)
+	})
+	if _, err := scanner.Scan(tmpDir); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	expected := []Decision{
+		{Path: "README.md", Reason: "unsupported extension .md"},
+		{Path: "app.gen.js", Reason: "matches exclude glob *.gen.js"},
+		{Path: "app.js", Included: true, Language: LanguageJavaScript, Reason: "extension .js"},
+		{Path: "node_modules", Dir: true, Reason: "excluded directory node_modules"},
+	}
+	if !reflect.DeepEqual(decisions, expected) {
+		t.Errorf("Expected decisions %+v, got %+v", expected, decisions)
+	}
+}
+
+func TestScanner_Languages(t *testing.T) {
+	// Only the languages this build registers have their files scanned (envgrd_nolang_* tags leave some out)
+	samples := map[Language]string{
+		LanguageGo:         "main.go",
+		LanguageTypeScript: "app.ts",
+		LanguageTSX:        "view.tsx",
+		LanguagePython:     "tools/build.py",
+		LanguageJavaScript: "web/app.js",
+		LanguageRust:       "src/main.rs",
+		LanguageJava:       "Main.java",
+	}
+	tmpDir := t.TempDir()
+	var registered []string
+	written := 0
+	for lang, name := range samples {
+		if languages.GetLanguageInfo(string(lang)) == nil {
+			continue
+		}
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		written++
+		if lang != LanguageTSX {
+			registered = append(registered, string(lang))
+		}
+	}
+	if len(registered) < 3 {
+		t.Skipf("Needs three built-in languages to leave one out, got %v", registered)
+	}
+	slices.Sort(registered)
+	// Prefer typescript, which also enables tsx
+	if i := slices.Index(registered, string(LanguageTypeScript)); i > 0 {
+		registered[0], registered[i] = registered[i], registered[0]
+	}
+	selected := registered[:2]
+
+	scanner := NewScanner()
+	if err := scanner.SetLanguages([]string{selected[0], " " + strings.ToUpper(selected[1]) + " "}); err != nil {
+		t.Fatalf("SetLanguages failed: %v", err)
+	}
+	files, err := scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	want := make(map[Language]bool)
+	for _, name := range selected {
+		want[Language(name)] = true
+		// typescript includes tsx
+		if Language(name) == LanguageTypeScript && languages.GetLanguageInfo(string(LanguageTSX)) != nil {
+			want[LanguageTSX] = true
+		}
+	}
+	found := make(map[Language]bool)
+	for _, f := range files {
+		found[f.Language] = true
+	}
+	if !reflect.DeepEqual(found, want) || len(files) != len(want) {
+		t.Errorf("Expected the files of %v, got %+v", want, files)
+	}
+
+	if err := scanner.SetLanguages([]string{"cobol"}); err == nil || !strings.Contains(err.Error(), `unknown language "cobol"`) {
+		t.Errorf("Expected an unknown language error, got %v", err)
+	}
+	// No languages enables all of them again
+	if err := scanner.SetLanguages(nil); err != nil {
+		t.Fatalf("SetLanguages failed: %v", err)
+	}
+	if files, _ := scanner.Scan(tmpDir); len(files) != written {
+		t.Errorf("Expected all %d files, got %+v", written, files)
+	}
+}
+
+func TestScanner_Extensions(t *testing.T) {
+	skipWithoutLanguage(t, "typescript", "go", "python")
+	tmpDir := t.TempDir()
+	for _, name := range []string{"main.go", "app.MTS", "tool.pyw", "page.gohtml", "legacy.js"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	scanner := NewScanner()
+	if err := scanner.SetExtensions(map[string]string{".mts": "typescript", "pyw": "Python", ".gohtml": SkipExtension, ".js": SkipExtension}); err != nil {
+		t.Fatalf("SetExtensions failed: %v", err)
+	}
+	files, err := scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	got := make(map[string]Language)
+	for _, f := range files {
+		got[filepath.Base(f.Path)] = f.Language
+	}
+	// Mapped extensions take precedence over the built-in ones, skip leaves files out
+	want := map[string]Language{"main.go": LanguageGo, "app.MTS": LanguageTypeScript, "tool.pyw": LanguagePython}
+	if len(got) != len(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	for name, lang := range want {
+		if got[name] != lang {
+			t.Errorf("%s: expected %s, got %s", name, lang, got[name])
+		}
+	}
+
+	if err := scanner.SetExtensions(map[string]string{".cob": "cobol"}); err == nil || !strings.Contains(err.Error(), `unknown language "cobol"`) {
+		t.Errorf("Expected an unknown language error, got %v", err)
+	}
+}
+
+func TestScanner_Shebang(t *testing.T) {
+	skipWithoutLanguage(t, "python", "javascript")
+	tmpDir := t.TempDir()
+	scripts := map[string]string{
+		"deploy":  "#!/usr/bin/env python3\nimport os\n",
+		"serve":   "#!/usr/bin/env -S NODE_ENV=production node --no-warnings\n",
+		"migrate": "#!/usr/local/bin/python3.12 -u\n",
+		"run":     "#!/bin/sh\necho $HOME\n",
+		"notes":   "python3 deploy\n",
+		"app.cfg": "#!/usr/bin/env python3\n",
+	}
+	for name, content := range scripts {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0755); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	files, err := NewScanner().Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	got := make(map[string]Language)
+	for _, f := range files {
+		got[filepath.Base(f.Path)] = f.Language
+	}
+	// Only extensionless files are sniffed, and shell scripts aren't supported
+	want := map[string]Language{"deploy": LanguagePython, "serve": LanguageJavaScript, "migrate": LanguagePython}
+	if len(got) != len(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	for name, lang := range want {
+		if got[name] != lang {
+			t.Errorf("%s: expected %s, got %s", name, lang, got[name])
+		}
+	}
+}
+
+func TestScanner_Buffer(t *testing.T) {
+	skipWithoutLanguage(t, "typescript", "javascript", "python")
+	root := t.TempDir()
+	scanner := NewScanner()
+	scanner.SetExcludeGlobs([]string{"*.gen.ts"})
+
+	file, ok := scanner.Buffer(root, filepath.Join(root, "src", "app.ts"), []byte("process.env.KEY"))
+	if !ok || file.Language != LanguageTypeScript || string(file.Content) != "process.env.KEY" {
+		t.Errorf("Expected a typescript buffer, got %+v (ok %v)", file, ok)
+	}
+	file, ok = scanner.Buffer(root, filepath.Join(root, "scripts", "deploy"), []byte("#!/usr/bin/env python3\nimport os\n"))
+	if !ok || file.Language != LanguagePython {
+		t.Errorf("Expected a python buffer from its shebang, got %+v (ok %v)", file, ok)
+	}
+	// Left out like discovery would
+	for _, path := range []string{"notes.txt", "api.gen.ts", filepath.Join("node_modules", "pkg", "index.js")} {
+		if file, ok := scanner.Buffer(root, filepath.Join(root, path), []byte("x")); ok {
+			t.Errorf("Expected %s to be left out, got %+v", path, file)
+		}
+	}
+}
+
+func TestScanner_TemplateGlobs(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	for _, name := range []string{"app.tpl", filepath.Join("nginx", "nginx.conf"), "other.conf", "app.js"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmpDir, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("${KEY}"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	scanner := NewScanner()
+	scanner.SetTemplateGlobs([]string{"*.tpl", "nginx/*.conf", "*.js"})
+	files, err := scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := make(map[string]Language)
+	for _, f := range files {
+		rel, _ := filepath.Rel(tmpDir, f.Path)
+		got[filepath.ToSlash(rel)] = f.Language
+	}
+	// Source files keep their language even when a template glob matches them
+	want := map[string]Language{"app.tpl": LanguageTemplate, "nginx/nginx.conf": LanguageTemplate, "app.js": LanguageJavaScript}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestScanner_Ansible(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"site.yml", filepath.Join("roles", "app", "tasks", "main.yml"), filepath.Join("k8s", "deployment.yaml")} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmpDir, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("- hosts: all\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	files, err := NewScanner().Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := make(map[string]Language)
+	for _, f := range files {
+		rel, _ := filepath.Rel(tmpDir, f.Path)
+		got[filepath.ToSlash(rel)] = f.Language
+	}
+	want := map[string]Language{"site.yml": LanguageAnsible, "roles/app/tasks/main.yml": LanguageAnsible}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestScanner_ScanContextCancelled(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "app.js"), []byte("console.log('test');"), 0644); err != nil {
+		t.Fatalf("Failed to write app.js: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewScanner().ScanContext(ctx, tmpDir)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestScanner_SkipsLargeAndBinaryFiles(t *testing.T) {
+	skipWithoutLanguage(t, "javascript", "go", "python")
+	tmpDir := t.TempDir()
+
+	files := map[string][]byte{
+		"app.js":    []byte("process.env.API_KEY;"),
+		"bundle.js": []byte(strings.Repeat("x", 2048)),
+		"blob.go":   {'p', 'k', 'g', 0, 1, 2},
+		"latin1.py": {'#', ' ', 0xe9, '\n'},
+		"utf16.py":  {0xff, 0xfe, '#', 0, '\n', 0},
+		"ctrl.js":   {'a', 0xff, 0x01, 'b'},
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var log bytes.Buffer
+	scanner := NewScanner()
+	scanner.SetMaxFileSize(1024)
+	scanner.SetLogger(slog.New(logging.NewTextHandler(&log, slog.LevelInfo)))
+
+	result, err := scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result) != 3 {
+		t.Errorf("Expected app.js, latin1.py and utf16.py, got %v", result)
+	}
+	for _, want := range []string{"bundle.js: file size 2048 bytes exceeds the 1024 byte limit", "blob.go: file looks binary", "ctrl.js: file looks binary"} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("Expected warning %q, got %q", want, log.String())
+		}
+	}
+
+	// Disabling the limit includes the large file again
+	scanner.SetMaxFileSize(0)
+	result, err = scanner.Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result) != 4 {
+		t.Errorf("Expected 4 files without a size limit, got %v", result)
+	}
+}
+
+func TestLooksBinary(t *testing.T) {
+	euro := []byte("€") // 3-byte rune
+
+	tests := []struct {
+		name     string
+		sample   []byte
+		expected bool
+	}{
+		{"text", []byte("const a = 1;"), false},
+		{"utf8", append([]byte("x = "), euro...), false},
+		{"nul", []byte("a\x00b"), true},
+		{"invalid utf8 with control characters", []byte{'a', 0xff, 0x01}, true},
+		{"latin1", []byte{'a', 0xe9, 'b'}, false},
+		{"rune cut by sample", append([]byte("x = "), euro[:2]...), false},
+		{"utf16 with BOM", []byte{0xff, 0xfe, 'a', 0, 0xac, 0x20}, false},
+		{"utf16 without BOM", []byte{0, 'a', 0, '=', 0, '1'}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := looksBinary(tt.sample); got != tt.expected {
+				t.Errorf("looksBinary() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScanner_MaxDepth(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	for _, path := range []string{"root.js", "a/one.js", "a/b/two.js"} {
+		full := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("process.env.A;"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	for depth, expected := range map[int]int{0: 3, 1: 1, 2: 2, 3: 3} {
+		scanner := NewScanner()
+		scanner.SetMaxDepth(depth)
+		files, err := scanner.Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if len(files) != expected {
+			t.Errorf("max depth %d: expected %d files, got %d", depth, expected, len(files))
+		}
+	}
+}
+
+func TestScanner_Symlinks(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "repo")
+	shared := filepath.Join(tmpDir, "shared")
+	for _, dir := range []string{filepath.Join(root, "src"), shared} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "app.js"), []byte("process.env.A;"), 0644); err != nil {
+		t.Fatalf("Failed to write app.js: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(shared, "lib.js"), []byte("process.env.B;"), 0644); err != nil {
+		t.Fatalf("Failed to write lib.js: %v", err)
+	}
+
+	// A link to a directory outside the root, and a cycle back to the root
+	if err := os.Symlink(shared, filepath.Join(root, "shared")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "src", "loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	files, err := NewScanner().Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected symlinks to be skipped by default, got %v", files)
+	}
+
+	scanner := NewScanner()
+	scanner.SetFollowSymlinks(true)
+	files, err = scanner.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected app.js and shared/lib.js exactly once, got %v", files)
+	}
+}
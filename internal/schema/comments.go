@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+)
+
+// commentTypes maps the type names accepted in comments to the types values are checked against
+var commentTypes = map[string]string{
+	"int": TypeInteger, "integer": TypeInteger,
+	"float": TypeNumber, "number": TypeNumber,
+	"bool": TypeBoolean, "boolean": TypeBoolean,
+	"string": "string",
+}
+
+// FromComments returns the variables declared by the comments above their keys in env files (see
+// envfile.Comments), one convention per line:
+//
+//	# required              (or # optional)
+//	# type: int             (int, integer, float, number, bool, boolean or string)
+//	# format: url           (see Formats)
+//	# description: Primary database
+//
+// Other comment lines are ignored, and variables without any convention aren't declared. Unlike schema file
+// entries, variables declared in comments are optional unless marked required
+func FromComments(comments map[string]string) map[string]Variable {
+	variables := make(map[string]Variable)
+	for key, comment := range comments {
+		var v Variable
+		declared := false
+		for _, line := range strings.Split(comment, "\n") {
+			name, value, hasValue := strings.Cut(line, ":")
+			name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+			switch {
+			case !hasValue && (name == "required" || name == "optional"):
+				v.Required, declared = name == "required", true
+			case hasValue && name == "type" && commentTypes[strings.ToLower(value)] != "":
+				v.Type, declared = commentTypes[strings.ToLower(value)], true
+			case hasValue && name == "format" && slices.Contains(Formats, value):
+				v.Format, declared = value, true
+			case hasValue && name == "description" && value != "":
+				v.Description, declared = value, true
+			}
+		}
+		if declared {
+			variables[key] = v
+		}
+	}
+	return variables
+}
+
+// Merge returns the schema with the variables declared elsewhere (e.g., by FromComments) that it doesn't
+// declare itself; it returns s when there are none, and a schema without a file when s is nil
+func (s *Schema) Merge(variables map[string]Variable) *Schema {
+	if len(variables) == 0 {
+		return s
+	}
+	merged := &Schema{Variables: make(map[string]Variable, len(variables))}
+	if s != nil {
+		merged.File = s.File
+		for key, v := range s.Variables {
+			merged.Variables[key] = v
+		}
+	}
+	for key, v := range variables {
+		if _, ok := merged.Variables[key]; !ok {
+			merged.Variables[key] = v
+		}
+	}
+	return merged
+}
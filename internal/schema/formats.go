@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formats values are validated against, set with "format" in the schema or inferred from the name
+const (
+	FormatURL      = "url"       // Absolute URL with a scheme (e.g., postgres://db:5432/app)
+	FormatHostPort = "host:port" // Network address (e.g., localhost:8080 or :8080)
+	FormatPort     = "port"      // Port number between 1 and 65535
+	FormatPath     = "path"      // Existing file or directory, relative paths from the scanned directory
+	FormatBase64   = "base64"    // Standard or URL-safe base64, padded or not
+	FormatUUID     = "uuid"      // UUID such as 123e4567-e89b-12d3-a456-426614174000
+	FormatNone     = "none"      // Disables the format inferred from the name
+)
+
+// Formats lists the supported formats
+var Formats = []string{FormatURL, FormatHostPort, FormatPort, FormatPath, FormatBase64, FormatUUID, FormatNone}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// InferFormat returns the format a variable name implies: url for *_URL and *_URI, port for *_PORT and
+// uuid for *_UUID, or "" when the name implies none
+func InferFormat(key string) string {
+	name := "_" + strings.ToUpper(key)
+	switch {
+	case strings.HasSuffix(name, "_URL"), strings.HasSuffix(name, "_URI"):
+		return FormatURL
+	case strings.HasSuffix(name, "_PORT"):
+		return FormatPort
+	case strings.HasSuffix(name, "_UUID"):
+		return FormatUUID
+	}
+	return ""
+}
+
+// CheckFormat validates value against format and returns what's wrong with it, "" when it's valid
+// Relative paths are resolved from dir; unknown formats accept every value
+func CheckFormat(format string, value string, dir string) string {
+	value = strings.TrimSpace(value)
+	switch format {
+	case FormatURL:
+		return checkURL(value)
+	case FormatHostPort:
+		host, port, err := net.SplitHostPort(value)
+		if err != nil || strings.ContainsAny(host, " /") {
+			return "expected host:port (e.g., localhost:8080)"
+		}
+		return checkPort(port)
+	case FormatPort:
+		return checkPort(value)
+	case FormatPath:
+		path := value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Sprintf("%s does not exist", value)
+		}
+	case FormatBase64:
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if _, err := encoding.DecodeString(value); err == nil {
+				return ""
+			}
+		}
+		return "not valid base64"
+	case FormatUUID:
+		if !uuidPattern.MatchString(value) {
+			return "expected a UUID (e.g., 123e4567-e89b-12d3-a456-426614174000)"
+		}
+	}
+	return ""
+}
+
+// checkURL requires a scheme and a host or path, e.g. https://example.com or sqlite:///data/app.db
+// The value isn't repeated in the problem, since URLs may hold credentials
+func checkURL(value string) string {
+	if !strings.Contains(value, "://") {
+		return "missing scheme (e.g., https://)"
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "invalid URL: " + err.Error()
+	}
+	if u.Scheme == "" {
+		return "missing scheme (e.g., https://)"
+	}
+	if u.Host == "" && u.Path == "" {
+		return "missing host"
+	}
+	return ""
+}
+
+// checkPort requires a port number in the 1-65535 range
+func checkPort(value string) string {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Sprintf("port %q is not a number", value)
+	}
+	if port < 1 || port > 65535 {
+		return fmt.Sprintf("port %d is out of range 1-65535", port)
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileName is the default name of the schema file, at the scan root
+const FileName = ".envgrd.schema.json"
+
+// Types values are checked against, inferred from how code parses a variable or declared in the schema
+const (
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// Matches reports whether value is valid for typ; every value matches unknown types (e.g., string)
+func Matches(typ string, value string) bool {
+	value = strings.TrimSpace(value)
+	switch typ {
+	case TypeInteger:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case TypeNumber:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case TypeBoolean:
+		switch strings.ToLower(value) {
+		case "true", "false", "1", "0", "yes", "no", "on", "off", "t", "f":
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// Schema declares the environment variables of a project, keyed by name
+type Schema struct {
+	File      string // Path of the file the schema was loaded from
+	Variables map[string]Variable
+}
+
+// Variable is the declaration of one variable
+// In the schema file it's a type name ("PORT": "number"), a list of allowed values
+// ("LOG_LEVEL": ["debug", "info"]) or an object with the fields below
+type Variable struct {
+	Type        string   `json:"type"`        // Expected type of the value (e.g., string, number, boolean), empty for any
+	Enum        []string `json:"enum"`        // Allowed values, empty for any
+	Format      string   `json:"format"`      // Validator of the value (see Formats), inferred from the name when empty
+	Required    bool     `json:"required"`    // Whether the variable must be set (default: true)
+	Description string   `json:"description"` // What the variable is for
+}
+
+// UnmarshalJSON decodes a type name, a list of allowed values or an object
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) > 0 && data[0] == '"':
+		var typ string
+		if err := json.Unmarshal(data, &typ); err != nil {
+			return err
+		}
+		*v = Variable{Type: typ, Required: true}
+		return nil
+	case len(data) > 0 && data[0] == '[':
+		var enum []string
+		if err := json.Unmarshal(data, &enum); err != nil {
+			return fmt.Errorf("allowed values must be strings: %w", err)
+		}
+		*v = Variable{Enum: enum, Required: true}
+		return nil
+	}
+	type plain Variable
+	decoded := plain{Required: true}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*v = Variable(decoded)
+	return nil
+}
+
+// Find returns the path of the schema file in dir, empty if there is none
+func Find(dir string) string {
+	file := filepath.Join(dir, FileName)
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+	return file
+}
+
+// Load reads a schema file
+func Load(file string) (*Schema, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var variables map[string]Variable
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", filepath.Base(file), err)
+	}
+	if variables == nil {
+		variables = make(map[string]Variable)
+	}
+	for key, v := range variables {
+		if v.Format != "" && !slices.Contains(Formats, v.Format) {
+			return nil, fmt.Errorf("invalid schema %s: unknown format %q of %s (supported: %s)", filepath.Base(file), v.Format, key, strings.Join(Formats, ", "))
+		}
+	}
+	return &Schema{File: file, Variables: variables}, nil
+}
+
+// Declares reports whether the schema declares key
+func (s *Schema) Declares(key string) bool {
+	_, ok := s.Variables[key]
+	return ok
+}
+
+// Format returns the format values of key are validated against: the one the schema declares, or else the
+// one its name implies (see InferFormat), "" for none
+func (s *Schema) Format(key string) string {
+	if s != nil {
+		if v, ok := s.Variables[key]; ok && v.Format != "" {
+			if v.Format == FormatNone {
+				return ""
+			}
+			return v.Format
+		}
+	}
+	return InferFormat(key)
+}
+
+// Required returns the names of the required variables, sorted
+func (s *Schema) Required() []string {
+	var keys []string
+	for key, v := range s.Variables {
+		if v.Required {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	content := `{
+  "PORT": "number",
+  "LOG_LEVEL": ["debug", "info"],
+  "SENTRY_DSN": {"type": "string", "required": false, "description": "Error reporting"},
+  "DATABASE_URL": {"description": "Primary database"}
+}`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := Find(dir)
+	if file == "" {
+		t.Fatal("Expected the schema file to be found")
+	}
+	s, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if v := s.Variables["PORT"]; v.Type != "number" || !v.Required {
+		t.Errorf("Unexpected PORT: %+v", v)
+	}
+	if v := s.Variables["LOG_LEVEL"]; !reflect.DeepEqual(v.Enum, []string{"debug", "info"}) || !v.Required {
+		t.Errorf("Unexpected LOG_LEVEL: %+v", v)
+	}
+	if v := s.Variables["SENTRY_DSN"]; v.Required || v.Description != "Error reporting" {
+		t.Errorf("Unexpected SENTRY_DSN: %+v", v)
+	}
+	if got, want := s.Required(), []string{"DATABASE_URL", "LOG_LEVEL", "PORT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected required %v, got %v", want, got)
+	}
+	if !s.Declares("SENTRY_DSN") || s.Declares("OTHER") {
+		t.Error("Declares doesn't match the schema's variables")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if Find(dir) != "" {
+		t.Error("Expected no schema file in an empty directory")
+	}
+	file := filepath.Join(dir, FileName)
+	if err := os.WriteFile(file, []byte(`{"LOG_LEVEL": [1, 2]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Error("Expected an error for non-string allowed values")
+	}
+	if err := os.WriteFile(file, []byte(`{"PORT": {"format": "portnumber"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		want  bool
+	}{
+		{TypeInteger, "8080", true},
+		{TypeInteger, "-1", true},
+		{TypeInteger, "abc", false},
+		{TypeInteger, "1.5", false},
+		{TypeNumber, "1.5", true},
+		{TypeNumber, "1e3", true},
+		{TypeNumber, "fast", false},
+		{TypeBoolean, "TRUE", true},
+		{TypeBoolean, "off", true},
+		{TypeBoolean, "enabled", false},
+		{"string", "anything", true},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.typ, tt.value); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.typ, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format string
+		value  string
+		valid  bool
+	}{
+		{FormatURL, "postgres://user:pass@db:5432/app", true},
+		{FormatURL, "sqlite:///data/app.db", true},
+		{FormatURL, "db.example.com", false},
+		{FormatURL, "https://", false},
+		{FormatHostPort, "localhost:8080", true},
+		{FormatHostPort, ":8080", true},
+		{FormatHostPort, "localhost", false},
+		{FormatHostPort, "localhost:99999", false},
+		{FormatPort, "8080", true},
+		{FormatPort, "0", false},
+		{FormatPort, "http", false},
+		{FormatPath, "key.pem", true},
+		{FormatPath, "missing.pem", false},
+		{FormatBase64, "aGVsbG8=", true},
+		{FormatBase64, "aGVsbG8", true},
+		{FormatBase64, "not base64!", false},
+		{FormatUUID, "123e4567-e89b-12d3-a456-426614174000", true},
+		{FormatUUID, "123e4567", false},
+		{"unknown", "anything", true},
+	}
+	for _, tt := range tests {
+		if problem := CheckFormat(tt.format, tt.value, dir); (problem == "") != tt.valid {
+			t.Errorf("CheckFormat(%q, %q) = %q, want valid %v", tt.format, tt.value, problem, tt.valid)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	s := &Schema{Variables: map[string]Variable{
+		"CALLBACK_URL": {Format: FormatNone},
+		"LISTEN":       {Format: FormatHostPort},
+	}}
+	tests := map[string]string{
+		"DATABASE_URL": FormatURL,
+		"REDIS_URI":    FormatURL,
+		"PORT":         FormatPort,
+		"TENANT_UUID":  FormatUUID,
+		"CALLBACK_URL": "",
+		"LISTEN":       FormatHostPort,
+		"URLS":         "",
+	}
+	for key, want := range tests {
+		if got := s.Format(key); got != want {
+			t.Errorf("Format(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := (*Schema)(nil).Format("API_URL"); got != FormatURL {
+		t.Errorf("Expected the format to be inferred without a schema, got %q", got)
+	}
+}
+
+func TestFromComments(t *testing.T) {
+	variables := FromComments(map[string]string{
+		"PORT":         "required\ntype: int",
+		"DATABASE_URL": "description: Primary database\nformat: url",
+		"DEBUG":        "optional\ntype: Bool",
+		"SECTION":      "Database settings",
+		"RETRIES":      "type: counter",
+	})
+	want := map[string]Variable{
+		"PORT":         {Type: TypeInteger, Required: true},
+		"DATABASE_URL": {Format: FormatURL, Description: "Primary database"},
+		"DEBUG":        {Type: TypeBoolean},
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("FromComments() = %+v, want %+v", variables, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	s := &Schema{File: FileName, Variables: map[string]Variable{"PORT": {Type: TypeNumber, Required: true}}}
+	merged := s.Merge(map[string]Variable{"PORT": {Type: TypeInteger}, "DEBUG": {Type: TypeBoolean}})
+	if merged.File != FileName || merged.Variables["PORT"].Type != TypeNumber || merged.Variables["DEBUG"].Type != TypeBoolean {
+		t.Errorf("Expected the schema's declarations to win, got %+v", merged)
+	}
+	if len(s.Variables) != 1 {
+		t.Error("Expected Merge not to modify the schema")
+	}
+	if got := (*Schema)(nil).Merge(nil); got != nil {
+		t.Errorf("Expected no schema without declarations, got %+v", got)
+	}
+	if got := (*Schema)(nil).Merge(map[string]Variable{"DEBUG": {}}); got == nil || got.File != "" || !got.Declares("DEBUG") {
+		t.Errorf("Expected a schema without a file, got %+v", got)
+	}
+}
@@ -0,0 +1,180 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+// DefaultFileName is the state file written to the scan root by default
+const DefaultFileName = ".envgrd.state"
+
+// formatVersion is bumped when the state file layout changes incompatibly
+const formatVersion = 1
+
+// State is a snapshot of the findings of one scan, persisted between runs
+type State struct {
+	Version   int       `json:"version"`
+	ScannedAt time.Time `json:"scanned_at"`
+	Missing   []string  `json:"missing"`
+	Unused    []string  `json:"unused"`
+	Dynamic   []string  `json:"dynamic"`
+}
+
+// FromResult captures the findings of a scan result
+func FromResult(result analyzer.ScanResult) *State {
+	return &State{
+		Version:   formatVersion,
+		ScannedAt: time.Now().UTC(),
+		Missing:   sortedKeys(result.Missing),
+		Unused:    sortedCopy(result.Unused),
+		Dynamic:   sortedKeys(result.PartialMatches),
+	}
+}
+
+// Load reads a state file, returning nil without an error if it doesn't exist yet
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported state file version %d in %s (delete it to start over)", s.Version, path)
+	}
+	return &s, nil
+}
+
+// Save writes the state file
+func Save(path string, s *State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// Add records a finding (kind missing, unused or dynamic) in the state file at path, creating it
+// if needed, so the next --since-last-run scan treats it as known instead of new
+func Add(path string, kind string, key string) error {
+	s, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		s = &State{Version: formatVersion, ScannedAt: time.Now().UTC(), Missing: []string{}, Unused: []string{}, Dynamic: []string{}}
+	}
+
+	var keys *[]string
+	switch kind {
+	case "missing":
+		keys = &s.Missing
+	case "unused":
+		keys = &s.Unused
+	case "dynamic":
+		keys = &s.Dynamic
+	default:
+		return fmt.Errorf("unknown finding kind %q (supported: missing, unused, dynamic)", kind)
+	}
+	if toSet(*keys)[key] {
+		return nil
+	}
+	*keys = append(*keys, key)
+	sort.Strings(*keys)
+	return Save(path, s)
+}
+
+// Since returns a copy of result that only contains findings absent from prev,
+// with Fixed listing the findings of prev that are gone
+// A nil prev means there is no baseline yet, so every finding is new
+func Since(result analyzer.ScanResult, prev *State) analyzer.ScanResult {
+	if prev == nil {
+		prev = &State{}
+	}
+
+	filtered := result
+	filtered.Missing = newFindings(result.Missing, prev.Missing)
+	filtered.PartialMatches = newFindings(result.PartialMatches, prev.Dynamic)
+
+	previouslyUnused := toSet(prev.Unused)
+	filtered.Unused = []string{}
+	for _, key := range result.Unused {
+		if !previouslyUnused[key] {
+			filtered.Unused = append(filtered.Unused, key)
+		}
+	}
+
+	current := FromResult(result)
+	filtered.Fixed = &analyzer.FixedFindings{
+		Missing: fixedFindings(prev.Missing, current.Missing),
+		Unused:  fixedFindings(prev.Unused, current.Unused),
+		Dynamic: fixedFindings(prev.Dynamic, current.Dynamic),
+	}
+	return filtered
+}
+
+// newFindings keeps the findings whose key isn't in previous
+func newFindings(findings map[string][]analyzer.EnvUsage, previous []string) map[string][]analyzer.EnvUsage {
+	seen := toSet(previous)
+	filtered := make(map[string][]analyzer.EnvUsage)
+	for key, usages := range findings {
+		if !seen[key] {
+			filtered[key] = usages
+		}
+	}
+	return filtered
+}
+
+// fixedFindings returns the keys of previous that are no longer in current
+func fixedFindings(previous []string, current []string) []string {
+	remaining := toSet(current)
+	fixed := []string{}
+	for _, key := range previous {
+		if !remaining[key] {
+			fixed = append(fixed, key)
+		}
+	}
+	sort.Strings(fixed)
+	return fixed
+}
+
+func toSet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+func sortedKeys(findings map[string][]analyzer.EnvUsage) []string {
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedCopy(keys []string) []string {
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+
+	prev, err := Load(path)
+	if err != nil || prev != nil {
+		t.Fatalf("Expected no state before the first save, got %v, %v", prev, err)
+	}
+
+	result := analyzer.ScanResult{
+		Missing: map[string][]analyzer.EnvUsage{"B": nil, "A": nil},
+		Unused:  []string{"Z", "Y"},
+	}
+	if err := Save(path, FromResult(result)); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Missing, []string{"A", "B"}) || !reflect.DeepEqual(loaded.Unused, []string{"Y", "Z"}) || len(loaded.Dynamic) != 0 {
+		t.Errorf("Unexpected state: %+v", loaded)
+	}
+}
+
+func TestSince(t *testing.T) {
+	prev := &State{
+		Missing: []string{"STILL_MISSING", "FIXED_MISSING"},
+		Unused:  []string{"FIXED_UNUSED"},
+		Dynamic: []string{"PREFIX_"},
+	}
+	result := analyzer.ScanResult{
+		Missing: map[string][]analyzer.EnvUsage{
+			"STILL_MISSING": {{Key: "STILL_MISSING"}},
+			"NEW_MISSING":   {{Key: "NEW_MISSING"}},
+		},
+		PartialMatches: map[string][]analyzer.EnvUsage{"PREFIX_": {{Key: "PREFIX_"}}},
+		Unused:         []string{"NEW_UNUSED"},
+	}
+
+	since := Since(result, prev)
+
+	if len(since.Missing) != 1 || since.Missing["NEW_MISSING"] == nil {
+		t.Errorf("Expected only NEW_MISSING, got %v", since.Missing)
+	}
+	if len(since.PartialMatches) != 0 {
+		t.Errorf("Expected no new dynamic patterns, got %v", since.PartialMatches)
+	}
+	if !reflect.DeepEqual(since.Unused, []string{"NEW_UNUSED"}) {
+		t.Errorf("Expected only NEW_UNUSED, got %v", since.Unused)
+	}
+	expectedFixed := &analyzer.FixedFindings{Missing: []string{"FIXED_MISSING"}, Unused: []string{"FIXED_UNUSED"}, Dynamic: []string{}}
+	if !reflect.DeepEqual(since.Fixed, expectedFixed) {
+		t.Errorf("Expected fixed %+v, got %+v", expectedFixed, since.Fixed)
+	}
+
+	// The original result is left untouched
+	if len(result.Missing) != 2 {
+		t.Errorf("Expected original result to keep 2 missing, got %v", result.Missing)
+	}
+}
+
+func TestSince_NoBaseline(t *testing.T) {
+	result := analyzer.ScanResult{Missing: map[string][]analyzer.EnvUsage{"A": nil}, Unused: []string{"B"}}
+
+	since := Since(result, nil)
+	if len(since.Missing) != 1 || len(since.Unused) != 1 || since.Fixed.Count() != 0 {
+		t.Errorf("Expected every finding to be new, got %+v", since)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+
+	if err := Add(path, "missing", "B"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	for _, key := range []string{"A", "B"} {
+		if err := Add(path, "missing", key); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := Add(path, "dynamic", "PREFIX_"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(s.Missing, []string{"A", "B"}) || !reflect.DeepEqual(s.Dynamic, []string{"PREFIX_"}) || len(s.Unused) != 0 {
+		t.Errorf("Unexpected state: %+v", s)
+	}
+	if err := Add(path, "example", "A"); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+}
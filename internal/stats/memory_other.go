@@ -0,0 +1,13 @@
+//go:build !unix
+
+package stats
+
+import "runtime"
+
+// PeakMemory returns the memory obtained from the OS by the Go runtime in bytes
+// Without getrusage this is the closest portable high-water mark, but it excludes memory allocated by C code
+func PeakMemory() uint64 {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return mem.Sys
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package stats
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// PeakMemory returns the maximum resident set size of the process in bytes
+func PeakMemory() uint64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	// ru_maxrss is reported in bytes on Apple platforms and in kilobytes elsewhere
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return uint64(usage.Maxrss)
+	}
+	return uint64(usage.Maxrss) * 1024
+}
@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSlowestFiles is how many of the slowest files are reported
+const DefaultSlowestFiles = 10
+
+// Stats contains timings and resource usage of a scan
+type Stats struct {
+	Discovery    time.Duration   // Walking the tree to find source files
+	EnvLoading   time.Duration   // Loading env files and the exported environment
+	Parse        time.Duration   // Wall-clock time of the parse phase
+	Analysis     time.Duration   // Comparing usages with definitions
+	Total        time.Duration   // Whole scan
+	FilesParsed  int             // Number of files parsed (including cache hits)
+	Languages    []LanguageStats // Parse time per language, slowest first
+	SlowestFiles []FileTiming    // Slowest files to parse, slowest first
+	PeakMemory   uint64          // High-water mark of process memory in bytes (0 if unavailable)
+}
+
+// LanguageStats is the cumulative parse time of all files in a language
+// Files are parsed in parallel, so the sum can exceed the wall-clock parse time
+type LanguageStats struct {
+	Language string
+	Files    int
+	Duration time.Duration
+}
+
+// FileTiming is the parse time of a single file
+type FileTiming struct {
+	Path     string
+	Language string
+	Duration time.Duration
+}
+
+// Collector gathers per-file parse timings from concurrent workers
+type Collector struct {
+	mu    sync.Mutex
+	files []FileTiming
+}
+
+// RecordFile records how long a file took to parse
+func (c *Collector) RecordFile(path string, language string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = append(c.files, FileTiming{Path: path, Language: language, Duration: duration})
+}
+
+// Summarize fills the per-file parts of s: files parsed, per-language totals and the slowest files
+func (c *Collector) Summarize(s *Stats, slowest int) {
+	c.mu.Lock()
+	files := append([]FileTiming(nil), c.files...)
+	c.mu.Unlock()
+
+	s.FilesParsed = len(files)
+
+	byLanguage := make(map[string]*LanguageStats)
+	for _, file := range files {
+		lang := byLanguage[file.Language]
+		if lang == nil {
+			lang = &LanguageStats{Language: file.Language}
+			byLanguage[file.Language] = lang
+		}
+		lang.Files++
+		lang.Duration += file.Duration
+	}
+	s.Languages = make([]LanguageStats, 0, len(byLanguage))
+	for _, lang := range byLanguage {
+		s.Languages = append(s.Languages, *lang)
+	}
+	sort.Slice(s.Languages, func(i, j int) bool {
+		if s.Languages[i].Duration != s.Languages[j].Duration {
+			return s.Languages[i].Duration > s.Languages[j].Duration
+		}
+		return s.Languages[i].Language < s.Languages[j].Language
+	})
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Duration != files[j].Duration {
+			return files[i].Duration > files[j].Duration
+		}
+		return files[i].Path < files[j].Path
+	})
+	if len(files) > slowest {
+		files = files[:slowest]
+	}
+	s.SlowestFiles = files
+}
@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollector_Summarize(t *testing.T) {
+	var collector Collector
+	collector.RecordFile("a.js", "javascript", 3*time.Millisecond)
+	collector.RecordFile("b.js", "javascript", 1*time.Millisecond)
+	collector.RecordFile("main.go", "go", 5*time.Millisecond)
+
+	var s Stats
+	collector.Summarize(&s, 2)
+
+	if s.FilesParsed != 3 {
+		t.Errorf("Expected 3 files parsed, got %d", s.FilesParsed)
+	}
+	if len(s.SlowestFiles) != 2 || s.SlowestFiles[0].Path != "main.go" || s.SlowestFiles[1].Path != "a.js" {
+		t.Errorf("Unexpected slowest files: %+v", s.SlowestFiles)
+	}
+
+	expected := []LanguageStats{
+		{Language: "go", Files: 1, Duration: 5 * time.Millisecond},
+		{Language: "javascript", Files: 2, Duration: 4 * time.Millisecond},
+	}
+	if len(s.Languages) != len(expected) {
+		t.Fatalf("Expected %d languages, got %+v", len(expected), s.Languages)
+	}
+	for i, want := range expected {
+		if s.Languages[i] != want {
+			t.Errorf("Languages[%d] = %+v, want %+v", i, s.Languages[i], want)
+		}
+	}
+}
+
+func TestPeakMemory(t *testing.T) {
+	if PeakMemory() == 0 {
+		t.Error("Expected a non-zero peak memory")
+	}
+}
@@ -0,0 +1,352 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultEndpoint is the OTLP/HTTP endpoint of a local collector
+const defaultEndpoint = "http://localhost:4318"
+
+// defaultTimeout bounds the export request
+const defaultTimeout = 10 * time.Second
+
+// Tracer records spans in memory and exports them to an OpenTelemetry collector with Flush
+// Spans are exported over OTLP/HTTP with JSON encoding
+type Tracer struct {
+	Endpoint string            // Traces URL, e.g. http://localhost:4318/v1/traces
+	Headers  map[string]string // Extra request headers, e.g. for authentication
+	Timeout  time.Duration     // Export request timeout (default 10s)
+	Resource map[string]string // Resource attributes, including service.name
+	Scope    string            // Instrumentation scope name
+	Version  string            // Instrumentation scope version
+	TraceID  string            // Trace to add spans to, a new one when empty
+	ParentID string            // Span the root spans are children of, e.g. from TRACEPARENT
+	Client   *http.Client      // nil uses http.DefaultClient
+	mu       sync.Mutex
+	spans    []*Span
+}
+
+// Span is one timed operation of a trace
+// All methods are no-ops on a nil span, which Start returns when tracing is off
+type Span struct {
+	tracer     *Tracer
+	traceID    string
+	id         string
+	parentID   string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	err        error
+}
+
+// FromEnv returns a tracer configured by the standard OpenTelemetry environment variables, or nil
+// when tracing is off. Tracing is on when OTEL_TRACES_EXPORTER is otlp or an OTLP endpoint is set;
+// OTEL_SDK_DISABLED=true or OTEL_TRACES_EXPORTER=none turn it off. Also read: OTEL_SERVICE_NAME,
+// OTEL_RESOURCE_ATTRIBUTES, OTEL_EXPORTER_OTLP_[TRACES_]{ENDPOINT,HEADERS,TIMEOUT,PROTOCOL}
+// and TRACEPARENT, which makes the spans children of a span of the calling pipeline
+func FromEnv(service string, version string) (*Tracer, error) {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return nil, nil
+	}
+	exporter := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")))
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	switch {
+	case exporter == "none":
+		return nil, nil
+	case exporter != "" && exporter != "otlp":
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (supported: otlp, none)", exporter)
+	case exporter == "" && endpoint == "" && base == "":
+		return nil, nil
+	}
+
+	if protocol := envFirst("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"); protocol != "" && protocol != "http/json" {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q (supported: http/json)", protocol)
+	}
+	if endpoint == "" {
+		if base == "" {
+			base = defaultEndpoint
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+
+	t := &Tracer{
+		Endpoint: endpoint,
+		Headers:  parseList(envFirst("OTEL_EXPORTER_OTLP_TRACES_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS")),
+		Timeout:  defaultTimeout,
+		Resource: parseList(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")),
+		Scope:    service,
+		Version:  version,
+	}
+	if timeout := envFirst("OTEL_EXPORTER_OTLP_TRACES_TIMEOUT", "OTEL_EXPORTER_OTLP_TIMEOUT"); timeout != "" {
+		ms, err := strconv.Atoi(timeout)
+		if err != nil || ms < 0 {
+			return nil, fmt.Errorf("invalid OTLP timeout %q (milliseconds)", timeout)
+		}
+		t.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		t.Resource["service.name"] = name
+	} else if t.Resource["service.name"] == "" {
+		t.Resource["service.name"] = service
+	}
+	if traceparent := os.Getenv("TRACEPARENT"); traceparent != "" {
+		traceID, parentID, ok := parseTraceparent(traceparent)
+		if !ok {
+			return nil, fmt.Errorf("invalid TRACEPARENT %q", traceparent)
+		}
+		t.TraceID, t.ParentID = traceID, parentID
+	}
+	return t, nil
+}
+
+// envFirst returns the first of the environment variables that is set
+func envFirst(names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseList parses the key=value,key2=value2 lists of OTEL_*_HEADERS and OTEL_RESOURCE_ATTRIBUTES
+// Values are URL-decoded; malformed entries are skipped
+func parseList(value string) map[string]string {
+	list := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(val)); err == nil {
+			list[key] = decoded
+		}
+	}
+	return list
+}
+
+// parseTraceparent parses a W3C traceparent header value, e.g. 00-<trace id>-<span id>-01
+func parseTraceparent(value string) (string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	for _, id := range parts[1:3] {
+		if _, err := hex.DecodeString(id); err != nil || strings.Trim(id, "0") == "" {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(parts[1]), strings.ToLower(parts[2]), true
+}
+
+type contextKey int
+
+const (
+	tracerKey contextKey = iota
+	spanKey
+)
+
+// WithTracer returns a context whose Start calls record spans with t
+func WithTracer(ctx context.Context, t *Tracer) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tracerKey, t)
+}
+
+// Start starts a span named name, a child of the span in ctx if there is one, and returns a
+// context holding it. Without a tracer in ctx, it returns ctx and a nil span
+// attributes are key/value pairs, e.g. Start(ctx, "parse", "files", 12)
+func Start(ctx context.Context, name string, attributes ...any) (context.Context, *Span) {
+	t, _ := ctx.Value(tracerKey).(*Tracer)
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, name: name, start: time.Now(), id: newID(8)}
+	if parent, _ := ctx.Value(spanKey).(*Span); parent != nil {
+		span.traceID, span.parentID = parent.traceID, parent.id
+	} else {
+		t.mu.Lock()
+		if t.TraceID == "" {
+			t.TraceID = newID(16)
+		}
+		span.traceID, span.parentID = t.TraceID, t.ParentID
+		t.mu.Unlock()
+	}
+	span.SetAttributes(attributes...)
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// newID returns a random hex span (8 bytes) or trace (16 bytes) ID
+func newID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// SetAttributes adds key/value pairs to the span; values are strings, bools, ints or floats
+func (s *Span) SetAttributes(attributes ...any) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]any)
+	}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if key, ok := attributes[i].(string); ok {
+			s.attributes[key] = attributes[i+1]
+		}
+	}
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.err = err
+}
+
+// End ends the span, which is exported with the next Flush
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.end = time.Now()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// Flush exports the ended spans and forgets them
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	body, err := json.Marshal(t.request(spans))
+	t.mu.Unlock()
+	if err != nil || len(spans) == 0 {
+		return err
+	}
+
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.Headers {
+		req.Header.Set(key, value)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("failed to export spans to %s: %w", t.Endpoint, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to export spans to %s: unexpected status %s", t.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+// request builds the OTLP ExportTraceServiceRequest of spans in its JSON encoding
+func (t *Tracer) request(spans []*Span) map[string]any {
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		otlpSpan := map[string]any{
+			"traceId":           span.traceID,
+			"spanId":            span.id,
+			"name":              span.name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+			"attributes":        attributes(span.attributes),
+		}
+		if span.parentID != "" {
+			otlpSpan["parentSpanId"] = span.parentID
+		}
+		if span.err != nil {
+			otlpSpan["status"] = map[string]any{"code": 2, "message": span.err.Error()} // STATUS_CODE_ERROR
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+
+	resource := make(map[string]any, len(t.Resource))
+	for key, value := range t.Resource {
+		resource[key] = value
+	}
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{"attributes": attributes(resource)},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": t.Scope, "version": t.Version},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+// attributes converts attributes to OTLP key/value pairs, sorted by key
+func attributes(values map[string]any) []map[string]any {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]any
+		switch v := values[key].(type) {
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		list = append(list, map[string]any{"key": key, "value": value})
+	}
+	return list
+}
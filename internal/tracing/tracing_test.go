@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	for _, name := range []string{"OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TIMEOUT", "OTEL_SERVICE_NAME", "OTEL_RESOURCE_ATTRIBUTES", "TRACEPARENT"} {
+		t.Setenv(name, "")
+	}
+
+	if tracer, err := FromEnv("envgrd", "1.0"); tracer != nil || err != nil {
+		t.Errorf("Expected tracing off without OTEL_* variables, got %v, %v", tracer, err)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20abc, x-team=platform")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "2500")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=ci")
+	t.Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tracer, err := FromEnv("envgrd", "1.0")
+	if err != nil || tracer == nil {
+		t.Fatalf("FromEnv = %v, %v", tracer, err)
+	}
+	if tracer.Endpoint != "http://collector:4318/v1/traces" || tracer.Timeout != 2500*time.Millisecond {
+		t.Errorf("Unexpected endpoint %q or timeout %s", tracer.Endpoint, tracer.Timeout)
+	}
+	if tracer.Headers["Authorization"] != "Bearer abc" || tracer.Headers["x-team"] != "platform" {
+		t.Errorf("Unexpected headers %v", tracer.Headers)
+	}
+	if tracer.Resource["service.name"] != "envgrd" || tracer.Resource["deployment.environment"] != "ci" {
+		t.Errorf("Unexpected resource %v", tracer.Resource)
+	}
+	if tracer.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tracer.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("Expected the trace of TRACEPARENT, got %s/%s", tracer.TraceID, tracer.ParentID)
+	}
+
+	for name, value := range map[string]string{
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc",
+		"OTEL_TRACES_EXPORTER":        "zipkin",
+		"TRACEPARENT":                 "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := FromEnv("envgrd", "1.0"); err == nil {
+				t.Errorf("Expected an error for %s=%s", name, value)
+			}
+		})
+	}
+
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	if tracer, err := FromEnv("envgrd", "1.0"); tracer != nil || err != nil {
+		t.Errorf("Expected tracing off with OTEL_SDK_DISABLED, got %v, %v", tracer, err)
+	}
+}
+
+func TestTracer_Flush(t *testing.T) {
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Attributes   []struct {
+						Key   string         `json:"key"`
+						Value map[string]any `json:"value"`
+					} `json:"attributes"`
+					Status *struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("x-team") != "platform" {
+			t.Errorf("Unexpected request to %s with headers %v", r.URL.Path, r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Invalid OTLP JSON: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	tracer := &Tracer{Endpoint: server.URL + "/v1/traces", Headers: map[string]string{"x-team": "platform"}, Scope: "envgrd"}
+	ctx := WithTracer(context.Background(), tracer)
+	ctx, root := Start(ctx, "envgrd.scan", "envgrd.path", "/repo")
+	_, child := Start(ctx, "parse", "envgrd.files", 3)
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	parse, scan := spans[0], spans[1]
+	if parse.Name != "parse" || scan.Name != "envgrd.scan" || parse.TraceID != scan.TraceID || parse.ParentSpanID != scan.SpanID || scan.ParentSpanID != "" {
+		t.Errorf("Expected parse to be a child of envgrd.scan, got %+v and %+v", parse, scan)
+	}
+	if len(parse.Attributes) != 1 || parse.Attributes[0].Key != "envgrd.files" || parse.Attributes[0].Value["intValue"] != "3" {
+		t.Errorf("Unexpected attributes %+v", parse.Attributes)
+	}
+	if parse.Status == nil || parse.Status.Code != 2 || parse.Status.Message != "boom" {
+		t.Errorf("Expected an error status, got %+v", parse.Status)
+	}
+}
+
+func TestStart_WithoutTracer(t *testing.T) {
+	ctx, span := Start(context.Background(), "parse")
+	if span != nil || ctx != context.Background() {
+		t.Error("Expected no span without a tracer")
+	}
+	// Spans are nil-safe
+	span.SetAttributes("key", "value")
+	span.RecordError(errors.New("boom"))
+	span.End()
+	if err := (*Tracer)(nil).Flush(context.Background()); err != nil {
+		t.Errorf("Flush of a nil tracer failed: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package triage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/state"
+)
+
+// Finding kinds, named like the lists of the config's ignores and of the state file
+const (
+	Missing = "missing"
+	Unused  = "unused"
+	Dynamic = "dynamic"
+)
+
+// Action is what to do about a finding
+type Action string
+
+const (
+	Ignore   Action = "ignore"   // Add the key to the config's ignores
+	Example  Action = "example"  // Add a placeholder to the example env file
+	Baseline Action = "baseline" // Record the finding in the state file as known
+	Skip     Action = "skip"     // Leave the finding as is
+	Quit     Action = "quit"     // Stop, leaving the remaining findings as is
+)
+
+// shortcuts are the keys answering the prompt, in prompt order
+var shortcuts = []struct {
+	key    string
+	action Action
+}{
+	{"i", Ignore},
+	{"e", Example},
+	{"b", Baseline},
+	{"s", Skip},
+	{"q", Quit},
+}
+
+// maxLocations is how many usages are shown for a finding
+const maxLocations = 3
+
+// Finding is one finding to triage
+type Finding struct {
+	Kind   string
+	Key    string              // Variable name, or the pattern of a dynamic finding
+	Usages []analyzer.EnvUsage // Where the key is used in code, empty for unused findings
+	Source string              // Env file defining an unused key, relative to the scan root
+}
+
+// Actions returns the actions offered for the finding, in prompt order
+// Dynamic patterns can't be ignored in the config, and only missing keys belong in an example file
+func (f Finding) Actions() []Action {
+	switch f.Kind {
+	case Missing:
+		return []Action{Ignore, Example, Baseline, Skip, Quit}
+	case Unused:
+		return []Action{Ignore, Baseline, Skip, Quit}
+	default:
+		return []Action{Baseline, Skip, Quit}
+	}
+}
+
+// Findings lists the findings of a scan result to triage: missing keys, then unused keys and
+// dynamic patterns, each sorted, leaving out the categories that aren't reported
+func Findings(result analyzer.ScanResult, skipUnused bool, dynamic bool) []Finding {
+	var findings []Finding
+	for _, key := range sortedKeys(result.Missing) {
+		findings = append(findings, Finding{Kind: Missing, Key: key, Usages: result.Missing[key]})
+	}
+	if !skipUnused {
+		unused := append([]string{}, result.Unused...)
+		sort.Strings(unused)
+		for _, key := range unused {
+			findings = append(findings, Finding{Kind: Unused, Key: key, Source: result.EnvKeySources[key]})
+		}
+	}
+	if dynamic {
+		for _, key := range sortedKeys(result.PartialMatches) {
+			findings = append(findings, Finding{Kind: Dynamic, Key: key, Usages: result.PartialMatches[key]})
+		}
+	}
+	return findings
+}
+
+// Session walks through findings one by one, asking what to do about each
+type Session struct {
+	In          io.Reader
+	Out         io.Writer
+	ConfigFile  string // Config file to add ignores to, created if it doesn't exist
+	ExampleFile string // Example env file to add placeholders to, created if it doesn't exist
+	StateFile   string // State file of --since-last-run to record baselined findings in
+}
+
+// Summary counts the findings by the action taken on them
+type Summary map[Action]int
+
+// Run asks about each finding in turn and applies the answer right away, so quitting keeps the
+// actions taken so far. An action that fails is reported and the finding asked about again
+// Findings left after quitting or the end of the input are counted as skipped
+func (s *Session) Run(findings []Finding) Summary {
+	summary := make(Summary)
+	in := bufio.NewScanner(s.In)
+	for i := 0; i < len(findings); i++ {
+		action := s.ask(in, findings[i], i+1, len(findings))
+		if action == Quit {
+			summary[Skip] += len(findings) - i
+			break
+		}
+		if err := s.apply(findings[i], action); err != nil {
+			fmt.Fprintf(s.Out, "Error: %v\n", err)
+			i--
+			continue
+		}
+		summary[action]++
+	}
+	return summary
+}
+
+// ask prints a finding and reads answers until one is valid, returning Quit at the end of the input
+func (s *Session) ask(in *bufio.Scanner, finding Finding, n int, total int) Action {
+	fmt.Fprintf(s.Out, "\n[%d/%d] %s: %s\n", n, total, describe(finding.Kind), finding.Key)
+	for i, usage := range finding.Usages {
+		if i == maxLocations {
+			fmt.Fprintf(s.Out, "  ... and %d more\n", len(finding.Usages)-maxLocations)
+			break
+		}
+		fmt.Fprintf(s.Out, "  %s:%d\n", usage.File, usage.Line)
+	}
+	if finding.Source != "" {
+		fmt.Fprintf(s.Out, "  defined in %s\n", finding.Source)
+	}
+
+	actions := finding.Actions()
+	var choices []string
+	for _, action := range actions {
+		choices = append(choices, s.label(action))
+	}
+	for {
+		fmt.Fprintf(s.Out, "%s: ", strings.Join(choices, ", "))
+		if !in.Scan() {
+			fmt.Fprintln(s.Out)
+			return Quit
+		}
+		answer := strings.ToLower(strings.TrimSpace(in.Text()))
+		for _, shortcut := range shortcuts {
+			if answer != shortcut.key && answer != string(shortcut.action) {
+				continue
+			}
+			for _, action := range actions {
+				if action == shortcut.action {
+					return action
+				}
+			}
+		}
+		fmt.Fprintf(s.Out, "Unknown answer %q\n", answer)
+	}
+}
+
+// label is the prompt text of an action with the file it edits, e.g. "[i]gnore (.envgrd.config)"
+func (s *Session) label(action Action) string {
+	name := string(action)
+	label := "[" + name[:1] + "]" + name[1:]
+	switch action {
+	case Ignore:
+		return fmt.Sprintf("%s (%s)", label, filepath.Base(s.ConfigFile))
+	case Example:
+		return fmt.Sprintf("%s (%s)", label, filepath.Base(s.ExampleFile))
+	case Baseline:
+		return fmt.Sprintf("%s (%s)", label, filepath.Base(s.StateFile))
+	}
+	return label
+}
+
+// apply takes an action on a finding
+func (s *Session) apply(finding Finding, action Action) error {
+	switch action {
+	case Ignore:
+		if err := config.AddIgnore(s.ConfigFile, finding.Kind, finding.Key); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.Out, "Added %s to ignores.%s in %s\n", finding.Key, finding.Kind, s.ConfigFile)
+	case Example:
+		if err := AddPlaceholder(s.ExampleFile, finding.Key); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.Out, "Added %s= to %s\n", finding.Key, s.ExampleFile)
+	case Baseline:
+		if err := state.Add(s.StateFile, finding.Kind, finding.Key); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.Out, "Recorded %s in %s\n", finding.Key, s.StateFile)
+	}
+	return nil
+}
+
+// AddPlaceholder appends "KEY=" to an env file, creating it if it doesn't exist
+// Keys the file already defines (also as "export KEY=") are left alone
+func AddPlaceholder(path string, key string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defined := regexp.MustCompile(`(?m)^\s*(export\s+)?` + regexp.QuoteMeta(key) + `\s*=`)
+	if defined.Match(data) {
+		return nil
+	}
+
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	data = append(data, key+"=\n"...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// describe names a finding kind for the prompt
+func describe(kind string) string {
+	switch kind {
+	case Missing:
+		return "Missing variable"
+	case Unused:
+		return "Unused variable"
+	default:
+		return "Dynamic pattern"
+	}
+}
+
+func sortedKeys(findings map[string][]analyzer.EnvUsage) []string {
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package triage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/state"
+)
+
+func TestFindings(t *testing.T) {
+	result := analyzer.ScanResult{
+		Missing:        map[string][]analyzer.EnvUsage{"B": nil, "A": nil},
+		Unused:         []string{"Z", "Y"},
+		EnvKeySources:  map[string]string{"Y": ".env"},
+		PartialMatches: map[string][]analyzer.EnvUsage{"PREFIX_": nil},
+	}
+
+	var got []string
+	for _, finding := range Findings(result, false, true) {
+		got = append(got, finding.Kind+":"+finding.Key)
+	}
+	want := []string{"missing:A", "missing:B", "unused:Y", "unused:Z", "dynamic:PREFIX_"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Findings() = %v, want %v", got, want)
+	}
+	if findings := Findings(result, true, false); len(findings) != 2 {
+		t.Errorf("Expected only the missing findings, got %v", findings)
+	}
+}
+
+func TestSessionRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	session := &Session{
+		In:          strings.NewReader("i\nexample\nx\nb\ns\ni\nq\n"),
+		Out:         &bytes.Buffer{},
+		ConfigFile:  filepath.Join(tmpDir, config.FileName),
+		ExampleFile: filepath.Join(tmpDir, ".env.example"),
+		StateFile:   filepath.Join(tmpDir, state.DefaultFileName),
+	}
+	findings := []Finding{
+		{Kind: Missing, Key: "A", Usages: []analyzer.EnvUsage{{File: "main.go", Line: 3}}},
+		{Kind: Missing, Key: "B"},
+		{Kind: Dynamic, Key: "PREFIX_"}, // "x" is no answer and dynamic patterns can't be ignored
+		{Kind: Unused, Key: "C", Source: ".env"},
+		{Kind: Unused, Key: "D"},
+		{Kind: Missing, Key: "E"},
+		{Kind: Missing, Key: "F"},
+	}
+
+	summary := session.Run(findings)
+	want := Summary{Ignore: 2, Example: 1, Baseline: 1, Skip: 3}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("Run() = %v, want %v", summary, want)
+	}
+
+	cfg, err := config.LoadFile(session.ConfigFile)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !cfg.ShouldIgnoreMissing("A") || !reflect.DeepEqual(cfg.Ignores.Unused, []string{"D"}) {
+		t.Errorf("Unexpected ignores: %+v", cfg.Ignores)
+	}
+	example, _ := os.ReadFile(session.ExampleFile)
+	if string(example) != "B=\n" {
+		t.Errorf("Unexpected example file: %q", example)
+	}
+	s, err := state.Load(session.StateFile)
+	if err != nil || s == nil || !reflect.DeepEqual(s.Dynamic, []string{"PREFIX_"}) {
+		t.Errorf("Expected PREFIX_ in the state file, got %+v, %v", s, err)
+	}
+
+	out := session.Out.(*bytes.Buffer).String()
+	for _, text := range []string{"[1/7] Missing variable: A", "main.go:3", "defined in .env", `Unknown answer "x"`, "[b]aseline (.envgrd.state)"} {
+		if !strings.Contains(out, text) {
+			t.Errorf("Expected %q in the output:\n%s", text, out)
+		}
+	}
+}
+
+func TestAddPlaceholder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env.example")
+	if err := os.WriteFile(path, []byte("# Example\nexport A=1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"A", "B", "B"} {
+		if err := AddPlaceholder(path, key); err != nil {
+			t.Fatalf("AddPlaceholder failed: %v", err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "# Example\nexport A=1\nB=\n" {
+		t.Errorf("Unexpected example file: %q", data)
+	}
+}
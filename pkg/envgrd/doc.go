@@ -0,0 +1,17 @@
+// Package envgrd is the public Go API of envgrd.
+//
+// It exposes the same analysis the envgrd CLI runs: discover source files,
+// extract environment variable usages with Tree-Sitter, load env definition
+// files (.env, docker-compose, Kubernetes, systemd, shell scripts) plus the
+// exported environment, and compare the two.
+//
+// Typical use:
+//
+//	result, err := envgrd.Scan(ctx, envgrd.Options{Path: "./service"})
+//	if err != nil {
+//		return err
+//	}
+//	for key, usages := range result.Missing {
+//		fmt.Printf("%s is missing (used in %d places)\n", key, len(usages))
+//	}
+package envgrd
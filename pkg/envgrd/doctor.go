@@ -0,0 +1,98 @@
+package envgrd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/parser"
+)
+
+// GrammarCheck is the outcome of loading the grammar of a registered language
+type GrammarCheck struct {
+	Language string
+	Err      error // Why the language can't be parsed, nil when it can
+}
+
+// CheckGrammars loads the grammar and compiles the query of every registered language (including
+// those of plugins), as the first file of each language would, and returns the outcome sorted by name
+func CheckGrammars() []GrammarCheck {
+	var checks []GrammarCheck
+	for _, name := range languages.Names() {
+		checks = append(checks, GrammarCheck{Language: name, Err: parser.CheckGrammar(name)})
+	}
+	return checks
+}
+
+// EnvSource is an env file a scan loads definitions from
+type EnvSource struct {
+	// File is the path of the env file, relative to the scanned directory when below it
+	File string
+	// Kind is the source kind of the file (e.g., env, docker-compose, k8s)
+	Kind string
+	// Scope is the directory of the nested config loading the file, relative to the scanned directory,
+	// empty for the env files of the scanned directory
+	Scope string
+	// Variables is the number of variables the file defines (0 in a Plan, which doesn't parse env files)
+	Variables int
+	// Err is why the file couldn't be parsed, nil when it could
+	Err error
+}
+
+// EnvSources returns the env files a scan with opts loads, those of the scanned directory first and then
+// those of each nested config, each in load order: a variable defined in several files takes the value of
+// the last one, unless the precedence of the config ranks the exported environment above the file's kind
+func EnvSources(ctx context.Context, opts Options) ([]EnvSource, error) {
+	logger := logging.OrDiscard(opts.Logger)
+	absPath, err := resolveRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	return envSources(ctx, absPath, opts, loadScanConfig(opts, absPath, logger), true, logger)
+}
+
+// envSources returns the env files a scan of absPath loads, parsed to count their variables when parse is set
+func envSources(ctx context.Context, absPath string, opts Options, cfg *config.Config, parse bool, logger *slog.Logger) ([]EnvSource, error) {
+	var sources []EnvSource
+	addSources := func(loader *envfile.Loader, dir string, scope string) error {
+		files, err := loader.Files(dir)
+		if err != nil {
+			return fmt.Errorf("failed to find env files: %w", err)
+		}
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			source := EnvSource{File: relativeSource(absPath, file), Kind: envfile.Kind(file), Scope: scope}
+			if parse {
+				var vars map[string]string
+				vars, _, source.Err = envfile.ParseFile(file)
+				source.Variables = len(vars)
+			}
+			sources = append(sources, source)
+		}
+		return nil
+	}
+	loader, err := newEnvLoader(opts, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := addSources(loader, absPath, ""); err != nil {
+		return nil, err
+	}
+	for _, scope := range cfg.Scopes {
+		loader, err := newScopeLoader(opts, cfg, scope, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := addSources(loader, filepath.Join(absPath, filepath.FromSlash(scope.Dir)), scope.Dir); err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
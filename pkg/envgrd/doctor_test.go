@@ -0,0 +1,69 @@
+package envgrd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckGrammars(t *testing.T) {
+	checks := CheckGrammars()
+	if len(checks) == 0 {
+		t.Fatal("Expected the built-in languages to be checked")
+	}
+	for _, check := range checks {
+		if check.Err != nil {
+			t.Errorf("Expected the grammar of %s to load, got %v", check.Language, check.Err)
+		}
+	}
+}
+
+func TestEnvSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\nDB_URL=postgres://\n")
+	writeFile(t, filepath.Join(tmpDir, "docker-compose.yml"), "services:\n  api:\n    environment:\n      - PORT=8080\n")
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.config"), "precedence: [docker-compose, env]\n")
+	writeFile(t, filepath.Join(tmpDir, "api", ".envgrd.config"), "ignores:\n  missing: []\n")
+	writeFile(t, filepath.Join(tmpDir, "api", ".env"), "API_ONLY=1\n")
+
+	sources, err := EnvSources(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("EnvSources failed: %v", err)
+	}
+	expected := []EnvSource{
+		{File: ".env", Kind: "env", Variables: 2},
+		{File: "docker-compose.yml", Kind: "docker-compose", Variables: 1},
+		{File: filepath.Join("api", ".env"), Kind: "env", Scope: "api", Variables: 1},
+	}
+	if len(sources) != len(expected) {
+		t.Fatalf("Expected %d sources, got %+v", len(expected), sources)
+	}
+	for i, source := range sources {
+		if source != expected[i] {
+			t.Errorf("Source %d: expected %+v, got %+v", i, expected[i], source)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	skipWithoutLanguage(t, "typescript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.ts"), "process.env.API_KEY;\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.test.ts"), "process.env.TEST_KEY;\n")
+
+	plan, err := Plan(context.Background(), Options{Path: tmpDir, ExcludeGlobs: []string{"*.test.ts"}})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	included := map[string]bool{}
+	for _, file := range plan.Files {
+		included[file.Path] = file.Included
+	}
+	if !included[filepath.Join("src", "app.ts")] || included[filepath.Join("src", "app.test.ts")] || len(plan.Files) != 3 {
+		t.Errorf("Expected src/app.ts to be the only included file, got %+v", plan.Files)
+	}
+	if len(plan.EnvSources) != 1 || plan.EnvSources[0].File != ".env" || plan.EnvSources[0].Variables != 0 {
+		t.Errorf("Expected .env to be listed without being parsed, got %+v", plan.EnvSources)
+	}
+}
@@ -0,0 +1,406 @@
+package envgrd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/ansible"
+	"github.com/jenian/envgrd/internal/cache"
+	"github.com/jenian/envgrd/internal/charset"
+	"github.com/jenian/envgrd/internal/envsubst"
+	"github.com/jenian/envgrd/internal/jsconfig"
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/parser"
+	"github.com/jenian/envgrd/internal/scanner"
+	"github.com/jenian/envgrd/internal/stats"
+	"github.com/jenian/envgrd/internal/tracing"
+)
+
+// Engine extracts environment variable usages from files with a bounded pool of workers
+// It can be reused across scans (e.g., by long-running integrations) to keep loaded grammars warm
+type Engine struct {
+	parser       *parser.Parser
+	concurrency  int
+	parseTimeout time.Duration // 0 disables the per-file timeout
+	logger       *slog.Logger
+}
+
+// NewEngine creates an engine configured from opts.Concurrency, opts.ParseTimeout, opts.CacheDir and opts.Logger
+func NewEngine(opts Options) *Engine {
+	logger := logging.OrDiscard(opts.Logger)
+
+	tsParser := parser.NewParser()
+	tsParser.SetLogger(logger)
+	if opts.CacheDir != "" {
+		tsParser.SetCache(cache.New(opts.CacheDir))
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	parseTimeout := opts.ParseTimeout
+	if parseTimeout == 0 {
+		parseTimeout = DefaultParseTimeout
+	}
+
+	return &Engine{
+		parser:       tsParser,
+		concurrency:  concurrency,
+		parseTimeout: max(parseTimeout, 0),
+		logger:       logger,
+	}
+}
+
+// Concurrency returns the number of files parsed in parallel
+func (e *Engine) Concurrency() int {
+	return e.concurrency
+}
+
+// FileUsages is the outcome of parsing a single file, see Engine.StreamFiles
+type FileUsages struct {
+	File     FileInfo
+	Usages   []EnvUsage    // Usages in the file, empty when it could not be analyzed
+	Error    *ParseError   // Why the file could not be analyzed, nil when it was parsed
+	Duration time.Duration // Time spent parsing the file
+}
+
+// ParseFiles parses all files in parallel and returns their environment variable usages
+// root is the scan root used to make usage paths relative
+// Files that fail to parse, time out or make the parser panic are logged and returned as parse errors
+// (sorted by file); once ctx is cancelled no new files are started
+func (e *Engine) ParseFiles(ctx context.Context, files []FileInfo, root string) ([]EnvUsage, []ParseError) {
+	return e.parseFiles(ctx, files, root, nil)
+}
+
+// parseFiles is ParseFiles, additionally recording per-file timings in collector when it is not nil
+func (e *Engine) parseFiles(ctx context.Context, files []FileInfo, root string, collector *stats.Collector) ([]EnvUsage, []ParseError) {
+	var allUsages []analyzer.EnvUsage
+	parseErrors := []analyzer.ParseError{}
+	e.StreamFiles(ctx, files, root, func(result FileUsages) error {
+		if result.Error != nil {
+			parseErrors = append(parseErrors, *result.Error)
+			return nil
+		}
+		if collector != nil {
+			collector.RecordFile(relativeTo(root, result.File.Path), string(result.File.Language), result.Duration)
+		}
+		allUsages = append(allUsages, result.Usages...)
+		return nil
+	})
+	return allUsages, sortParseErrors(parseErrors)
+}
+
+// StreamFiles parses files like ParseFiles, but hands the outcome of each file to emit as soon as it is
+// parsed instead of collecting every usage, so that memory stays flat however large the repository is
+// emit is called from a single goroutine, for one file at a time and in no particular order; workers
+// wait for it to return once a few results are pending. An error from emit stops the parse and is returned
+func (e *Engine) StreamFiles(ctx context.Context, files []FileInfo, root string, emit func(FileUsages) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan FileInfo)
+	results := make(chan FileUsages, e.concurrency)
+	constants := newPackageConstants(e, files)
+
+	// Hand out files until all are taken, or stop once cancelled
+	go func() {
+		defer close(jobs)
+		for _, f := range files {
+			select {
+			case jobs <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(e.concurrency, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				if result, ok := e.parseOne(ctx, f, root, constants); ok {
+					results <- result
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Keep draining after an error so that no worker stays blocked on a full channel
+	var err error
+	for result := range results {
+		if err == nil {
+			if err = emit(result); err != nil {
+				cancel()
+			}
+		}
+	}
+	return err
+}
+
+// parseOne parses a single file for StreamFiles, ok is false when the parse was cancelled
+func (e *Engine) parseOne(ctx context.Context, f FileInfo, root string, constants *packageConstants) (_ FileUsages, ok bool) {
+	start := time.Now()
+	_, span := tracing.Start(ctx, "parse file", "code.filepath", relativeTo(root, f.Path), "envgrd.language", string(f.Language))
+	defer span.End()
+	usages, err := isolate(ctx, e.parseTimeout, func(ctx context.Context) ([]EnvUsage, error) {
+		return e.parseFile(ctx, f, root)
+	})
+	span.RecordError(err)
+	span.SetAttributes("envgrd.usages", len(usages))
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cancellation is reported once by the caller, not per file
+			return FileUsages{}, false
+		}
+		// Log and record the error but continue
+		e.logger.Warn(fmt.Sprintf("failed to parse %s: %v", f.Path, err))
+		return FileUsages{File: f, Error: &analyzer.ParseError{
+			File:     relativeTo(root, f.Path),
+			Language: string(f.Language),
+			Error:    err.Error(),
+		}}, true
+	}
+	elapsed := time.Since(start)
+	e.logger.Debug("parsed file", "file", f.Path, "language", string(f.Language), "usages", len(usages), "duration", elapsed)
+
+	// Mark usages from ignored folders
+	if f.InIgnoredPath {
+		for i := range usages {
+			usages[i].InIgnoredPath = true
+		}
+	}
+	return FileUsages{File: f, Usages: constants.resolve(ctx, f, usages), Duration: elapsed}, true
+}
+
+// parseFile extracts the usages of a single file, or of its Content when set
+func (e *Engine) parseFile(ctx context.Context, f FileInfo, root string) ([]EnvUsage, error) {
+	var usages []EnvUsage
+	var err error
+	switch {
+	case f.Language == scanner.LanguageTemplate && f.Content != nil:
+		usages = envsubst.Parse(f.Content, relativeTo(root, f.Path))
+	case f.Language == scanner.LanguageTemplate:
+		usages, err = envsubst.ParseFile(f.Path, relativeTo(root, f.Path))
+	case f.Language == scanner.LanguageAnsible:
+		usages, err = ansibleUsages(f, relativeTo(root, f.Path))
+	case f.Content != nil:
+		usages, err = e.parser.ParseContentContext(ctx, f.Path, f.Content, string(f.Language), root)
+	default:
+		usages, err = e.parser.ParseFileContext(ctx, f.Path, string(f.Language), root)
+	}
+	if err == nil && jsconfig.IsNuxtConfig(f.Path) {
+		// The runtimeConfig keys are read from NUXT_* variables at runtime
+		var runtime []EnvUsage
+		runtime, err = nuxtUsages(f, relativeTo(root, f.Path))
+		usages = append(usages, runtime...)
+	}
+	return usages, err
+}
+
+// isolate runs parse with a timeout (0 disables it) and turns panics of the parser (e.g., in
+// tree-sitter's bindings) into errors, so one pathological file can't hang or crash the whole scan
+// parse is abandoned on timeout: tree-sitter stops once its context is done, other code runs to its end
+// in the background
+func isolate[T any](ctx context.Context, timeout time.Duration, parse func(ctx context.Context) (T, error)) (T, error) {
+	parseCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		parseCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type parsed struct {
+		value T
+		err   error
+	}
+	done := make(chan parsed, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- parsed{err: fmt.Errorf("parser panicked: %v", r)}
+			}
+		}()
+		value, err := parse(parseCtx)
+		done <- parsed{value, err}
+	}()
+
+	var result parsed
+	select {
+	case result = <-done:
+	case <-parseCtx.Done():
+		result.err = parseCtx.Err()
+	}
+	if result.err != nil && ctx.Err() == nil && errors.Is(parseCtx.Err(), context.DeadlineExceeded) {
+		var zero T
+		return zero, fmt.Errorf("parsing timed out after %s", timeout)
+	}
+	return result.value, result.err
+}
+
+// packageConstants turns variable references to string constants declared in another file of the same
+// package (directory) into static keys, for languages whose constants are package-wide
+// References to constants of the file itself were already resolved by the parser
+type packageConstants struct {
+	engine   *Engine
+	packages map[sourcePackage][]FileInfo // Files of each package, in scan order
+
+	mu     sync.Mutex
+	loaded map[sourcePackage]*constantValues
+}
+
+// sourcePackage is a directory of source files in one language
+type sourcePackage struct{ dir, lang string }
+
+// constantValues are the constants of a package, collected once the first file needs them
+type constantValues struct {
+	once   sync.Once
+	values map[string]string
+}
+
+func newPackageConstants(e *Engine, files []FileInfo) *packageConstants {
+	c := &packageConstants{engine: e, packages: make(map[sourcePackage][]FileInfo), loaded: make(map[sourcePackage]*constantValues)}
+	for _, f := range files {
+		if info := languages.GetLanguageInfo(string(f.Language)); info != nil && info.PackageConstants {
+			p := sourcePackage{filepath.Dir(f.Path), string(f.Language)}
+			c.packages[p] = append(c.packages[p], f)
+		}
+	}
+	return c
+}
+
+// resolve resolves the variable references of usages, all found in f
+func (c *packageConstants) resolve(ctx context.Context, f FileInfo, usages []EnvUsage) []EnvUsage {
+	if len(c.packages) == 0 || !slices.ContainsFunc(usages, func(u EnvUsage) bool { return u.IsVarRef }) {
+		return usages
+	}
+	p := sourcePackage{filepath.Dir(f.Path), string(f.Language)}
+	if _, ok := c.packages[p]; !ok {
+		return usages
+	}
+
+	values := c.load(ctx, p)
+	for i, usage := range usages {
+		if !usage.IsVarRef {
+			continue
+		}
+		if value := values[usage.Key]; value != "" {
+			usages[i].Key = value
+			usages[i].IsPartial = false
+			usages[i].IsVarRef = false
+		}
+	}
+	return usages
+}
+
+// load returns the constants declared in the files of package p, later files overriding earlier ones
+func (c *packageConstants) load(ctx context.Context, p sourcePackage) map[string]string {
+	c.mu.Lock()
+	loaded, ok := c.loaded[p]
+	if !ok {
+		loaded = &constantValues{}
+		c.loaded[p] = loaded
+	}
+	c.mu.Unlock()
+
+	loaded.once.Do(func() {
+		loaded.values = make(map[string]string)
+		for _, f := range c.packages[p] {
+			if ctx.Err() != nil {
+				return
+			}
+			fileConstants, err := isolate(ctx, c.engine.parseTimeout, func(context.Context) (map[string]string, error) {
+				return c.engine.parser.Constants(f.Path, string(f.Language))
+			})
+			if err != nil {
+				c.engine.logger.Debug("failed to collect constants", "file", f.Path, "error", err)
+				continue
+			}
+			maps.Copy(loaded.values, fileConstants)
+		}
+	})
+	return loaded.values
+}
+
+// sortParseErrors orders parse errors by file for stable output
+func sortParseErrors(parseErrors []ParseError) []ParseError {
+	sort.Slice(parseErrors, func(i, j int) bool {
+		return parseErrors[i].File < parseErrors[j].File
+	})
+	return parseErrors
+}
+
+// relativeTo returns path relative to root, or path itself if that fails
+func relativeTo(root string, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// readSource returns the content of a file transcoded to UTF-8, or its Content when set
+func readSource(f FileInfo) ([]byte, error) {
+	if f.Content != nil {
+		return f.Content, nil
+	}
+	content, _, err := charset.ReadFile(f.Path)
+	return content, err
+}
+
+// nuxtUsages reads a Nuxt config file and returns the variables overriding its runtimeConfig as
+// optional usages, since runtimeConfig holds the default the variable overrides
+func nuxtUsages(f FileInfo, file string) ([]EnvUsage, error) {
+	content, err := readSource(f)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	var usages []EnvUsage
+	for _, variable := range jsconfig.NuxtVariables(content) {
+		usage := EnvUsage{Key: variable.Key, File: file, Line: variable.Line, IsOptional: true}
+		if variable.Line <= len(lines) {
+			usage.CodeSnippet = strings.TrimSpace(lines[variable.Line-1])
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// ansibleUsages reads an Ansible file and returns the controller variables its env lookups read
+func ansibleUsages(f FileInfo, file string) ([]EnvUsage, error) {
+	content, err := readSource(f)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	var usages []EnvUsage
+	for _, variable := range ansible.Lookups(content) {
+		usages = append(usages, EnvUsage{
+			Key:         variable.Name,
+			File:        file,
+			Line:        variable.Line,
+			CodeSnippet: strings.TrimSpace(lines[variable.Line-1]),
+			IsOptional:  variable.Optional,
+		})
+	}
+	return usages, nil
+}
@@ -0,0 +1,184 @@
+package envgrd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenian/envgrd/internal/scanner"
+)
+
+func TestNewEngine_DefaultConcurrency(t *testing.T) {
+	if got := NewEngine(Options{}).Concurrency(); got != runtime.NumCPU() {
+		t.Errorf("Expected default concurrency %d, got %d", runtime.NumCPU(), got)
+	}
+	if got := NewEngine(Options{Concurrency: 3}).Concurrency(); got != 3 {
+		t.Errorf("Expected concurrency 3, got %d", got)
+	}
+}
+
+func TestEngine_ParseFiles(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	var files []FileInfo
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("app%d.js", i))
+		writeFile(t, path, fmt.Sprintf("process.env.VAR_%d;\n", i))
+		files = append(files, FileInfo{Path: path, Language: scanner.LanguageJavaScript})
+	}
+
+	// A single worker must still process every file
+	usages, parseErrors := NewEngine(Options{Concurrency: 1}).ParseFiles(context.Background(), files, tmpDir)
+	if len(usages) != 5 {
+		t.Fatalf("Expected 5 usages, got %d: %+v", len(usages), usages)
+	}
+	if len(parseErrors) != 0 {
+		t.Errorf("Expected no parse errors, got %+v", parseErrors)
+	}
+}
+
+func TestEngine_StreamFiles(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	var files []FileInfo
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("app%d.js", i))
+		writeFile(t, path, fmt.Sprintf("process.env.VAR_%d;\n", i))
+		files = append(files, FileInfo{Path: path, Language: scanner.LanguageJavaScript})
+	}
+	engine := NewEngine(Options{Concurrency: 4})
+
+	// Every file is emitted once, from a single goroutine
+	seen := make(map[string]bool)
+	err := engine.StreamFiles(context.Background(), files, tmpDir, func(file FileUsages) error {
+		if seen[file.File.Path] || file.Error != nil || len(file.Usages) != 1 {
+			t.Errorf("Unexpected result %+v", file)
+		}
+		seen[file.File.Path] = true
+		return nil
+	})
+	if err != nil || len(seen) != len(files) {
+		t.Fatalf("Expected all %d files, got %d and error %v", len(files), len(seen), err)
+	}
+
+	// An emit error stops the stream and is returned
+	stop := errors.New("stop")
+	emitted := 0
+	err = engine.StreamFiles(context.Background(), files, tmpDir, func(FileUsages) error {
+		emitted++
+		return stop
+	})
+	if !errors.Is(err, stop) || emitted != 1 {
+		t.Errorf("Expected the stream to stop after the first file, got %d files and error %v", emitted, err)
+	}
+}
+
+func TestEngine_ParseFiles_Templates(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "nginx", "default.conf.template")
+	writeFile(t, path, "server {\n    proxy_pass http://${UPSTREAM_HOST};\n}\n")
+
+	usages, parseErrors := NewEngine(Options{}).ParseFiles(context.Background(), []FileInfo{{Path: path, Language: scanner.LanguageTemplate}}, tmpDir)
+	if len(parseErrors) != 0 {
+		t.Fatalf("Expected no parse errors, got %+v", parseErrors)
+	}
+	if len(usages) != 1 || usages[0].Key != "UPSTREAM_HOST" || usages[0].Line != 2 || usages[0].File != filepath.Join("nginx", "default.conf.template") {
+		t.Errorf("Expected UPSTREAM_HOST on line 2 of the template, got %+v", usages)
+	}
+}
+
+func TestEngine_ParseFiles_NuxtRuntimeConfig(t *testing.T) {
+	skipWithoutLanguage(t, "typescript")
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "nuxt.config.ts")
+	writeFile(t, path, "export default defineNuxtConfig({\n  runtimeConfig: {\n    apiSecret: process.env.API_SECRET,\n    public: { apiBase: '/api' },\n  },\n})\n")
+
+	usages, parseErrors := NewEngine(Options{}).ParseFiles(context.Background(), []FileInfo{{Path: path, Language: scanner.LanguageTypeScript}}, tmpDir)
+	if len(parseErrors) != 0 {
+		t.Fatalf("Expected no parse errors, got %+v", parseErrors)
+	}
+	found := make(map[string]EnvUsage)
+	for _, usage := range usages {
+		found[usage.Key] = usage
+	}
+	if _, ok := found["API_SECRET"]; !ok || len(found) != 3 {
+		t.Fatalf("Expected API_SECRET and the runtimeConfig variables, got %+v", usages)
+	}
+	if usage := found["NUXT_API_SECRET"]; usage.Line != 3 || !usage.IsOptional {
+		t.Errorf("Expected an optional NUXT_API_SECRET usage on line 3, got %+v", usage)
+	}
+	if usage := found["NUXT_PUBLIC_API_BASE"]; usage.Line != 4 || usage.CodeSnippet != "public: { apiBase: '/api' }," {
+		t.Errorf("Unexpected NUXT_PUBLIC_API_BASE usage: %+v", usage)
+	}
+}
+
+func TestEngine_ParseFiles_Ansible(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "site.yml")
+	writeFile(t, path, "- hosts: all\n  vars:\n    token: \"{{ lookup('env', 'DEPLOY_TOKEN') }}\"\n")
+
+	usages, parseErrors := NewEngine(Options{}).ParseFiles(context.Background(), []FileInfo{{Path: path, Language: scanner.LanguageAnsible}}, tmpDir)
+	if len(parseErrors) != 0 {
+		t.Fatalf("Expected no parse errors, got %+v", parseErrors)
+	}
+	if len(usages) != 1 || usages[0].Key != "DEPLOY_TOKEN" || usages[0].Line != 3 || usages[0].CodeSnippet != `token: "{{ lookup('env', 'DEPLOY_TOKEN') }}"` {
+		t.Errorf("Expected DEPLOY_TOKEN on line 3, got %+v", usages)
+	}
+}
+
+func TestEngine_ParseFiles_Timeout(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "app.js")
+	writeFile(t, path, "process.env.API_KEY;\n")
+	files := []FileInfo{{Path: path, Language: scanner.LanguageJavaScript}}
+
+	usages, parseErrors := NewEngine(Options{ParseTimeout: time.Nanosecond}).ParseFiles(context.Background(), files, tmpDir)
+	if len(usages) != 0 || len(parseErrors) != 1 {
+		t.Fatalf("Expected the file to time out, got usages %+v and parse errors %+v", usages, parseErrors)
+	}
+	if parseErrors[0].File != "app.js" || !strings.Contains(parseErrors[0].Error, "parsing timed out after 1ns") {
+		t.Errorf("Unexpected parse error: %+v", parseErrors[0])
+	}
+
+	// A negative timeout disables the limit
+	usages, parseErrors = NewEngine(Options{ParseTimeout: -1}).ParseFiles(context.Background(), files, tmpDir)
+	if len(usages) != 1 || len(parseErrors) != 0 {
+		t.Errorf("Expected the file to be parsed without a timeout, got usages %+v and parse errors %+v", usages, parseErrors)
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	// A panic becomes an error
+	_, err := isolate(context.Background(), time.Second, func(context.Context) ([]EnvUsage, error) {
+		panic("stack overflow in the grammar")
+	})
+	if err == nil || err.Error() != "parser panicked: stack overflow in the grammar" {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+
+	// A parse that ignores its context is abandoned once the timeout expires
+	block := make(chan struct{})
+	defer close(block)
+	_, err = isolate(context.Background(), 10*time.Millisecond, func(context.Context) ([]EnvUsage, error) {
+		<-block
+		return nil, nil
+	})
+	if err == nil || err.Error() != "parsing timed out after 10ms" {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+
+	// Cancelling the scan is not reported as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = isolate(ctx, time.Second, func(ctx context.Context) ([]EnvUsage, error) {
+		return nil, ctx.Err()
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation error, got %v", err)
+	}
+}
@@ -0,0 +1,1290 @@
+package envgrd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/blame"
+	"github.com/jenian/envgrd/internal/cache"
+	"github.com/jenian/envgrd/internal/charset"
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/envfile"
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/output"
+	"github.com/jenian/envgrd/internal/owners"
+	"github.com/jenian/envgrd/internal/scanner"
+	"github.com/jenian/envgrd/internal/schema"
+	"github.com/jenian/envgrd/internal/state"
+	"github.com/jenian/envgrd/internal/stats"
+	"github.com/jenian/envgrd/internal/tracing"
+)
+
+// EnvUsage is a single usage of an environment variable in code
+type EnvUsage = analyzer.EnvUsage
+
+// ScanResult contains the analysis results (missing, unused, dynamic patterns)
+type ScanResult = analyzer.ScanResult
+
+// Config is the .envgrd.config configuration
+type Config = config.Config
+
+// ConfigFileName is the default name of the config file, see FindConfig for the others
+const ConfigFileName = config.FileName
+
+// ScanSettings are the defaults of the scan flags set in the config
+type ScanSettings = config.ScanSettings
+
+// IgnoresConfig contains ignore rules for environment variables
+type IgnoresConfig = config.IgnoresConfig
+
+// SeverityConfig assigns severities to finding categories and variables
+type SeverityConfig = config.SeverityConfig
+
+// Severity classifies a finding as error, warning or info
+type Severity = config.Severity
+
+// Stats contains scan timings and resource usage, see Options.Stats
+type Stats = stats.Stats
+
+// State is a snapshot of a scan's findings, persisted between runs
+type State = state.State
+
+// DefaultStateFile is the state file name used by --since-last-run, relative to the scan root
+const DefaultStateFile = state.DefaultFileName
+
+// LoadState reads a state file written by SaveState, returning nil if it doesn't exist yet
+func LoadState(path string) (*State, error) {
+	return state.Load(path)
+}
+
+// SaveState records the findings of result in a state file
+func SaveState(path string, result *Result) error {
+	return state.Save(path, state.FromResult(result.ScanResult))
+}
+
+// ReadUsages reads a usages file (see Options.Usages), as written by WriteUsages, UsagesStream,
+// envgrd list --json or envgrd list --ndjson
+func ReadUsages(r io.Reader) ([]EnvUsage, error) {
+	return output.ReadUsages(r)
+}
+
+// WriteUsages writes usages as a usages file, e.g. the CodeKeys of a result
+func WriteUsages(w io.Writer, usages []EnvUsage) error {
+	return output.WriteUsages(w, usages)
+}
+
+// UsagesStream writes a usages file as newline-delimited JSON, usage by usage, see NewUsagesStream
+type UsagesStream = output.UsagesStream
+
+// NewUsagesStream returns a stream writing a usages file to w, e.g. the usages of Engine.StreamFiles
+// as files are parsed; Close it to write the header of an empty file
+func NewUsagesStream(w io.Writer) *UsagesStream {
+	return output.NewUsagesStream(w)
+}
+
+// ParseError records a source file that could not be analyzed
+type ParseError = analyzer.ParseError
+
+// Conflict is a variable defined with different values in several env files
+type Conflict = analyzer.Conflict
+
+// Definition is one definition of a variable in an env file
+type Definition = analyzer.Definition
+
+// ExampleDrift compares example env files (e.g., .env.example) with code usages and the real env files
+type ExampleDrift = analyzer.ExampleDrift
+
+// SchemaDrift compares the variables declared in the schema with code usages
+type SchemaDrift = analyzer.SchemaDrift
+
+// FrontendLeaks lists client-side usages without the framework's public prefix, and secrets exposed with it
+type FrontendLeaks = analyzer.FrontendLeaks
+
+// StyleViolation is a variable name that breaks the config's naming rules
+type StyleViolation = analyzer.StyleViolation
+
+// DeprecatedVar is a deprecated variable that is still used in code or defined in env files
+type DeprecatedVar = analyzer.DeprecatedVar
+
+// TypeMismatch is a variable whose env file values code can't parse as the type it expects
+type TypeMismatch = analyzer.TypeMismatch
+
+// PlaceholderValue is a variable read in code whose effective env file value is empty or a placeholder
+type PlaceholderValue = analyzer.PlaceholderValue
+
+// UnresolvedReference is an env file value with ${VAR} references to variables that are neither defined nor exported
+type UnresolvedReference = analyzer.UnresolvedReference
+
+// ForbiddenVar is a forbidden variable that is used in code or defined in env files
+type ForbiddenVar = analyzer.ForbiddenVar
+
+// ServiceGap is a deployable unit with the variables its code reads that its own environment doesn't define
+type ServiceGap = analyzer.ServiceGap
+
+// Confidence estimates how likely an unresolved dynamic pattern refers to a real environment variable
+type Confidence = analyzer.Confidence
+
+// Confidence levels of dynamic patterns
+const (
+	ConfidenceHigh   = analyzer.ConfidenceHigh
+	ConfidenceMedium = analyzer.ConfidenceMedium
+	ConfidenceLow    = analyzer.ConfidenceLow
+)
+
+// Path separators of Result.ConvertPaths
+const (
+	SeparatorNative    = analyzer.SeparatorNative
+	SeparatorSlash     = analyzer.SeparatorSlash
+	SeparatorBackslash = analyzer.SeparatorBackslash
+)
+
+// PathSeparators lists the separators Result.ConvertPaths accepts
+var PathSeparators = analyzer.PathSeparators
+
+// ConvertPath returns path with the separator, e.g. for usages reported outside of a Result
+func ConvertPath(path string, separator string) string {
+	return analyzer.ConvertPath(path, separator)
+}
+
+// ParseConfidence parses a --min-confidence value: high, medium or low
+func ParseConfidence(value string) (Confidence, error) {
+	return analyzer.ParseConfidence(value)
+}
+
+// FileInfo describes a discovered source file
+type FileInfo = scanner.FileInfo
+
+// Grammar loads the Tree-Sitter grammar for a language
+type Grammar = languages.Grammar
+
+// Extractor converts Tree-Sitter query captures into environment variable matches
+type Extractor = languages.Extractor
+
+// EnvVarMatch is a single match returned by an Extractor
+type EnvVarMatch = languages.EnvVarMatch
+
+// RegisterLanguage adds a custom language (or replaces a built-in one) for all subsequent scans
+// Files with the given extensions are parsed with grammar, queried with query, and the captures
+// are passed to extractor
+func RegisterLanguage(name string, grammar Grammar, query string, extractor Extractor, extensions ...string) {
+	languages.Register(name, grammar, query, extractor, extensions...)
+}
+
+// LoadLanguagePlugin loads a Go plugin whose init calls RegisterLanguage, to add grammars at runtime
+// instead of building them in; it must be built with the same Go and envgrd versions as the program
+func LoadLanguagePlugin(path string) error {
+	return languages.LoadPlugin(path)
+}
+
+// Reporter renders a ScanResult to a writer; implement it to add a custom output format
+type Reporter = output.Reporter
+
+// ReporterFunc adapts a plain function to the Reporter interface
+type ReporterFunc = output.ReporterFunc
+
+// ReportOptions controls which findings a Reporter includes
+type ReportOptions = output.Options
+
+// NewReporter returns a built-in reporter: "text", "json", "badge", or "exec:<command>"
+func NewReporter(format string) (Reporter, error) {
+	return output.NewReporter(format)
+}
+
+// Redactor decides how env file values are shown in reports; set ReportOptions.Redactor to plug in
+// custom redaction
+type Redactor = output.Redactor
+
+// RedactorFunc adapts a plain function to the Redactor interface
+type RedactorFunc = output.RedactorFunc
+
+// RedactionRule overrides the built-in redaction for matching variables, see Config.Redaction
+type RedactionRule = config.RedactionRule
+
+// NewRedactor returns the built-in redactor for a --show-values mode (never, redacted or full)
+// with rules checked before the built-in ones
+func NewRedactor(mode string, rules []RedactionRule) (Redactor, error) {
+	return output.NewRedactor(mode, rules)
+}
+
+// FailOn selects which finding categories make a run fail
+type FailOn = output.FailOn
+
+// FailOnAny fails on every finding category
+var FailOnAny = output.FailOnAny
+
+// Exit codes used by the envgrd CLI
+const (
+	ExitOK            = output.ExitOK
+	ExitMissing       = output.ExitMissing
+	ExitUnused        = output.ExitUnused
+	ExitDynamic       = output.ExitDynamic
+	ExitParseErrors   = output.ExitParseErrors
+	ExitExampleDrift  = output.ExitExampleDrift
+	ExitFrontend      = output.ExitFrontend
+	ExitStyle         = output.ExitStyle
+	ExitDeprecated    = output.ExitDeprecated
+	ExitInternalError = output.ExitInternalError
+	ExitSchema        = output.ExitSchema
+	ExitType          = output.ExitType
+	ExitPlaceholder   = output.ExitPlaceholder
+	ExitReference     = output.ExitReference
+	ExitForbidden     = output.ExitForbidden
+)
+
+// ParseFailOn parses --fail-on style values: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference, forbidden, any or none
+func ParseFailOn(values []string) (FailOn, error) {
+	return output.ParseFailOn(values)
+}
+
+// DefaultMaxFileSize is the size in bytes above which source files are skipped by default
+const DefaultMaxFileSize = scanner.DefaultMaxFileSize
+
+// DefaultParseTimeout is how long a single file may take to parse by default
+const DefaultParseTimeout = 30 * time.Second
+
+// Options controls a scan
+type Options struct {
+	// Path is the directory to scan (default: current directory)
+	Path string
+	// EnvFiles are additional env files to load, relative to Path or absolute
+	EnvFiles []string
+	// IgnoreEnvFiles are globs of env files to skip (e.g., .env.test), relative to Path, on top of the
+	// env_files.exclude config
+	IgnoreEnvFiles []string
+	// Schema is the schema file declaring the variables, relative to Path or absolute, instead of the config's
+	// schema or the .envgrd.schema.json file of Path
+	Schema string
+	// IncludeGlobs restricts scanning to files matching at least one pattern
+	IncludeGlobs []string
+	// ExcludeGlobs skips files matching any pattern
+	ExcludeGlobs []string
+	// Languages restricts scanning to files of these languages (e.g., go, typescript), all when empty
+	// Names are those of the registered languages plus template and ansible; typescript includes tsx
+	Languages []string
+	// Config overrides the .envgrd.config file in Path when set
+	Config *Config
+	// Policy is enforced over the config (see LoadPolicy and Config.WithPolicy), also when Config is set
+	Policy *Policy
+	// Profile applies a profile of the config found in Path (ignored when Config is set, see Config.WithProfile)
+	Profile string
+	// FollowSymlinks follows symlinked files and directories (each real directory is walked once)
+	FollowSymlinks bool
+	// MaxDepth limits how deep below Path files are discovered (0 = no limit, 1 = only files in Path)
+	MaxDepth int
+	// MaxFileSize skips source files larger than this many bytes (0 = DefaultMaxFileSize, negative = no limit)
+	MaxFileSize int64
+	// Concurrency is the number of files parsed in parallel (default: number of CPUs)
+	Concurrency int
+	// ParseTimeout records a file as a parse error when parsing it takes longer (0 = DefaultParseTimeout,
+	// negative = no limit), so a pathological file (e.g., deeply nested minified code) can't hang the scan
+	ParseTimeout time.Duration
+	// CacheDir enables the parse cache in this directory, so unchanged files are not re-parsed (empty disables it)
+	CacheDir string
+	// EnvSnapshot reads the definitions of unchanged env files from the env snapshot of Path in CacheDir
+	// (see CacheEnv) instead of parsing them, and refreshes the snapshot when they changed
+	EnvSnapshot bool
+	// MinConfidence drops unresolved dynamic patterns below this confidence (empty keeps all of them)
+	MinConfidence Confidence
+	// Owner keeps only the findings in files owned by this CODEOWNERS owner (e.g., @org/backend)
+	Owner string
+	// Service checks only the code of this service (a docker-compose service built from a directory of the scan,
+	// or an entry of the services config) against its environment, reporting the variables of its environment
+	// it doesn't read as unused
+	Service string
+	// Only keeps the findings of these categories: missing, unused, dynamic, example, schema, frontend, style, deprecated, type, placeholder, reference or forbidden
+	Only []string
+	// Keys keeps only the findings of variables matching a name pattern (e.g., STRIPE_*)
+	Keys []string
+	// ExcludeKeys drops the findings of variables matching a name pattern
+	ExcludeKeys []string
+	// InFiles keeps only the usages and definitions in files matching a gitignore-style pattern (e.g., src/payments/**)
+	InFiles []string
+	// Blame runs git blame on the env files defining unused variables, see ScanResult.UnusedBlame
+	Blame bool
+	// Stats records phase timings, the slowest files and peak memory in the result's Stats
+	Stats bool
+	// CaseInsensitive matches variable names regardless of case, as Windows does (Path in code reads PATH),
+	// and reports the case-only mismatches in the result's CaseMismatches
+	CaseInsensitive bool
+	// Usages are analyzed instead of the usages of the source files when not nil (e.g., read from a
+	// usages file with ReadUsages), so files are neither discovered nor parsed
+	Usages []EnvUsage
+	// Source is parsed instead of the files under Path when not nil, as the content of SourceFile (e.g.,
+	// an unsaved editor buffer read from stdin), and only the findings in SourceFile are reported
+	Source []byte
+	// SourceFile is the path of Source, relative to Path or absolute; it selects the language and needn't exist
+	SourceFile string
+	// Logger receives progress messages, warnings and debug/trace output (nil discards them)
+	Logger *slog.Logger
+}
+
+// Result is the outcome of a scan
+type Result struct {
+	ScanResult
+	// Root is the absolute path that was scanned
+	Root string
+	// Files are the source files that were parsed
+	Files []FileInfo
+}
+
+// envVarData holds processed environment variable data
+type envVarData struct {
+	envVars              map[string]string                // All env vars (from files + exported)
+	envVarsFromFilesOnly map[string]string                // Only vars from .env files (for unused check)
+	relEnvKeySources     map[string]string                // Relative paths to source files
+	envKeyLines          map[string]int                   // Line of each variable's definition in its source file
+	conflicts            []analyzer.Conflict              // Variables defined with different values in several files
+	definitions          map[string][]analyzer.Definition // Every definition of each variable, in load order
+	services             []*config.Service                // docker-compose services built from a subdirectory
+}
+
+// DefaultCacheDir returns the parse cache directory used by the CLI (e.g., ~/.cache/envgrd on Linux)
+func DefaultCacheDir() (string, error) {
+	return cache.DefaultDir()
+}
+
+// Tracer records the spans of scans and exports them to an OpenTelemetry collector (OTLP/HTTP JSON)
+type Tracer = tracing.Tracer
+
+// TracerFromEnv returns a tracer configured by the standard OTEL_* environment variables, or nil
+// when they don't enable tracing
+func TracerFromEnv(service string, version string) (*Tracer, error) {
+	return tracing.FromEnv(service, version)
+}
+
+// WithTracer returns a context that makes Scan record spans with t; call t.Flush to export them
+func WithTracer(ctx context.Context, t *Tracer) context.Context {
+	return tracing.WithTracer(ctx, t)
+}
+
+// ClearCache removes every cached parse result from dir
+func ClearCache(dir string) error {
+	return cache.New(dir).Clear()
+}
+
+// EnvCache describes the env snapshot written by CacheEnv
+type EnvCache struct {
+	// File is the path of the snapshot
+	File string
+	// EnvFiles is the number of env files whose definitions it holds
+	EnvFiles int
+	// Variables is the number of variables they define
+	Variables int
+}
+
+// CacheEnv loads the env files a scan with opts reads (those of Path and of its nested configs) and writes
+// their definitions to the env snapshot of Path in opts.CacheDir, so that scans with EnvSnapshot set (e.g.,
+// single-file scans of an editor on every keystroke) don't parse them until one of them changes
+func CacheEnv(ctx context.Context, opts Options) (*EnvCache, error) {
+	if opts.CacheDir == "" {
+		return nil, fmt.Errorf("caching the env definitions needs a cache directory")
+	}
+	logger := logging.OrDiscard(opts.Logger)
+	absPath, err := resolveRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg := loadScanConfig(opts, absPath, logger)
+
+	envLoader, err := newEnvLoader(opts, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := envfile.NewSnapshot()
+	envData, err := loadEnvironmentVariables(ctx, envLoader, absPath, cfg, snapshot)
+	if err == nil {
+		err = loadScopedEnvironments(ctx, absPath, opts, cfg, envData, snapshot, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &EnvCache{File: cache.New(opts.CacheDir).EnvSnapshotPath(absPath), Variables: len(envData.definitions)}
+	for _, load := range snapshot.Loads {
+		info.EnvFiles += len(load.Files)
+	}
+	if err := snapshot.Write(info.File); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// LoadConfig loads the config file of the given directory (see FindConfig)
+func LoadConfig(rootPath string) (*Config, error) {
+	return config.LoadConfig(rootPath)
+}
+
+// Policy holds an organization's central rules, which local configs can add to but not weaken
+type Policy = config.Policy
+
+// LoadPolicy loads a policy file from an https URL, pinned by its sha256 checksum (hex, optionally prefixed
+// with sha256:), or from a file path, whose checksum is checked when given
+func LoadPolicy(ref string, checksum string) (*Policy, error) {
+	return config.LoadPolicy(ref, checksum)
+}
+
+// ConfigProblem is a mistake found by CheckConfig
+type ConfigProblem = config.Problem
+
+// FindConfig returns the path of the config file of a directory: .envgrd.config, .envgrd.yaml, .envgrd.yml,
+// envgrd.config.yaml, or a package.json or pyproject.toml with an envgrd section; empty if there is none
+func FindConfig(dir string) (string, error) {
+	return config.Find(dir)
+}
+
+// LoadConfigFile loads a config file, e.g. one given with --config
+func LoadConfigFile(file string) (*Config, error) {
+	return config.LoadFile(file)
+}
+
+// CheckConfig validates a config file and returns every problem found
+// (syntax errors, unknown keys, wrong types and invalid settings), with line numbers
+func CheckConfig(file string) ([]ConfigProblem, error) {
+	return config.Check(file)
+}
+
+// Discover finds the source files a scan with opts would parse, without parsing them, and returns them
+// with the absolute scan root; pass both to Engine.StreamFiles to process the usages file by file
+func Discover(ctx context.Context, opts Options) (root string, files []FileInfo, err error) {
+	logger := logging.OrDiscard(opts.Logger)
+	root, err = resolveRoot(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	fileScanner, err := newFileScanner(opts, loadScanConfig(opts, root, logger), logger)
+	if err != nil {
+		return "", nil, err
+	}
+	files, err = fileScanner.ScanContext(ctx, root)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", nil, fmt.Errorf("scan aborted: %w", ctxErr)
+		}
+		return "", nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+	logger.Info(reportFileCounts(files))
+	return root, files, nil
+}
+
+// FileDecision records why discovery includes a file in a scan or leaves it out, see Plan
+type FileDecision = scanner.Decision
+
+// ScanPlan is what a scan would read, see Plan
+type ScanPlan struct {
+	// Root is the absolute path that would be scanned
+	Root string
+	// Files are the files discovery considered in walk order, those it would parse and those it leaves
+	// out, and the directories it wouldn't walk, each with the reason; paths are relative to Root
+	Files []FileDecision
+	// EnvSources are the env files that would be loaded, in load order (see EnvSources), not parsed
+	EnvSources []EnvSource
+}
+
+// Plan resolves the source files and env files a scan with opts would read without parsing any of them,
+// to explain why a file or an env file is or isn't picked up
+func Plan(ctx context.Context, opts Options) (*ScanPlan, error) {
+	logger := logging.OrDiscard(opts.Logger)
+	root, err := resolveRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg := loadScanConfig(opts, root, logger)
+	fileScanner, err := newFileScanner(opts, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	plan := &ScanPlan{Root: root}
+	fileScanner.SetDecisions(func(decision FileDecision) {
+		decision.Path = relativeTo(root, decision.Path)
+		plan.Files = append(plan.Files, decision)
+	})
+	if _, err := fileScanner.ScanContext(ctx, root); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("scan aborted: %w", ctxErr)
+		}
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+	if plan.EnvSources, err = envSources(ctx, root, opts, cfg, false, logger); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Scan discovers source files under opts.Path, extracts environment variable usages,
+// loads env definitions and compares them
+// With a tracer in ctx (see WithTracer), the scan and each of its phases are recorded as spans
+func Scan(ctx context.Context, opts Options) (_ *Result, err error) {
+	ctx, span := tracing.Start(ctx, "envgrd.scan")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	logger := logging.OrDiscard(opts.Logger)
+	scanStart := time.Now()
+
+	absPath, err := resolveRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	inFiles := opts.InFiles
+	var sourcePath string
+	if opts.Source != nil && opts.Usages == nil {
+		if opts.SourceFile == "" {
+			return nil, fmt.Errorf("scanning a source needs its file name")
+		}
+		if sourcePath = opts.SourceFile; !filepath.IsAbs(sourcePath) {
+			sourcePath = filepath.Join(absPath, sourcePath)
+		}
+		// Only the source's findings, those of other files would be stale or missing
+		inFiles = append(slices.Clone(inFiles), "/"+filepath.ToSlash(relativeTo(absPath, sourcePath)))
+	}
+	filter, err := analyzer.NewFilter(opts.Only, opts.Keys, opts.ExcludeKeys, inFiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	engine := NewEngine(opts)
+
+	cfg := loadScanConfig(opts, absPath, logger)
+	fileScanner, err := newFileScanner(opts, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	envLoader, err := newEnvLoader(opts, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	variableSchema, err := loadSchema(opts, cfg, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("Scanning %s...", absPath))
+	if opts.Policy != nil {
+		logger.Info(fmt.Sprintf("Enforcing policy %s (sha256 %s)", opts.Policy.Source, opts.Policy.Checksum))
+	}
+	span.SetAttributes("envgrd.path", absPath)
+	phaseStart := time.Now()
+	var files []FileInfo
+	if sourcePath != "" {
+		content, _, err := charset.Decode(opts.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", opts.SourceFile, err)
+		}
+		if file, ok := fileScanner.Buffer(absPath, sourcePath, content); ok {
+			files = append(files, file)
+		} else {
+			logger.Info(fmt.Sprintf("Skipping %s: not a source file of a scanned language", opts.SourceFile))
+		}
+	} else if opts.Usages == nil {
+		_, phase := tracing.Start(ctx, "discovery")
+		files, err = fileScanner.ScanContext(ctx, absPath)
+		phase.SetAttributes("envgrd.files", len(files))
+		phase.RecordError(err)
+		phase.End()
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, fmt.Errorf("scan aborted: %w", ctxErr)
+			}
+			return nil, fmt.Errorf("failed to scan directory: %w", err)
+		}
+		logger.Info(reportFileCounts(files))
+	} else {
+		logger.Info(fmt.Sprintf("Analyzing %d pre-extracted usages", len(opts.Usages)))
+	}
+	discovery := time.Since(phaseStart)
+
+	phaseStart = time.Now()
+	sourcesCtx, phase := tracing.Start(ctx, "sources")
+	var snapshot *envfile.Snapshot
+	var snapshotPath string
+	if opts.EnvSnapshot && opts.CacheDir != "" {
+		snapshotPath = cache.New(opts.CacheDir).EnvSnapshotPath(absPath)
+		snapshot = envfile.ReadSnapshot(snapshotPath)
+	}
+	envData, err := loadEnvironmentVariables(sourcesCtx, envLoader, absPath, cfg, snapshot)
+	if err == nil {
+		err = loadScopedEnvironments(sourcesCtx, absPath, opts, cfg, envData, snapshot, logger)
+	}
+	if err != nil {
+		phase.RecordError(err)
+		phase.End()
+		return nil, err
+	}
+	if snapshot != nil && snapshot.Changed() {
+		// The scan is fine without the snapshot, the next one parses the env files again
+		if err := snapshot.Write(snapshotPath); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+	cfg.Services = envData.services
+	var service *config.Service
+	if opts.Service != "" {
+		if service = cfg.Service(opts.Service); service == nil {
+			phase.End()
+			return nil, unknownService(opts.Service, cfg)
+		}
+		envData.envVarsFromFilesOnly = serviceVariables(envData.envVarsFromFilesOnly, service)
+	}
+	phase.SetAttributes("envgrd.variables", len(envData.envVars))
+	phase.End()
+	envLoading := time.Since(phaseStart)
+
+	var collector *stats.Collector
+	if opts.Stats {
+		collector = &stats.Collector{}
+	}
+
+	phaseStart = time.Now()
+	allUsages, parseErrors := slices.Clone(opts.Usages), []analyzer.ParseError{}
+	if opts.Usages == nil {
+		logger.Debug("parsing files", "files", len(files), "concurrency", engine.Concurrency())
+		parseCtx, phase := tracing.Start(ctx, "parse", "envgrd.files", len(files), "envgrd.concurrency", engine.Concurrency())
+		allUsages, parseErrors = engine.parseFiles(parseCtx, files, absPath, collector)
+		phase.SetAttributes("envgrd.usages", len(allUsages), "envgrd.parse_errors", len(parseErrors))
+		phase.End()
+	}
+	parse := time.Since(phaseStart)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("scan aborted: %w", err)
+	}
+
+	if service != nil {
+		allUsages = slices.DeleteFunc(allUsages, func(usage analyzer.EnvUsage) bool {
+			return !service.Contains(usage.File)
+		})
+	}
+
+	codeOwners, err := owners.Load(absPath)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("failed to load CODEOWNERS: %v", err))
+	}
+	if codeOwners == nil && opts.Owner != "" {
+		return nil, fmt.Errorf("filtering by owner %s requires a CODEOWNERS file", opts.Owner)
+	}
+	if codeOwners != nil {
+		logger.Debug("attributing findings to owners", "file", codeOwners.File)
+		for i := range allUsages {
+			allUsages[i].Owners = codeOwners.Of(allUsages[i].File)
+		}
+	}
+
+	phaseStart = time.Now()
+	_, phase = tracing.Start(ctx, "analysis")
+	var caseMismatches []analyzer.CaseMismatch
+	if opts.CaseInsensitive {
+		allUsages, caseMismatches = analyzer.MatchCase(allUsages, envData.envVars)
+		for i := range caseMismatches {
+			caseMismatches[i].Definitions = envData.definitions[caseMismatches[i].Defined]
+		}
+	}
+	result := analyzer.AnalyzeWithLogger(logger, allUsages, envData.envVars, envData.envVarsFromFilesOnly, envData.relEnvKeySources, cfg)
+	result.ParseErrors = parseErrors
+	result.EnvKeyLines = envData.envKeyLines
+	result.Definitions = envData.definitions
+	if codeOwners != nil {
+		result.EnvKeyOwners = make(map[string][]string, len(envData.relEnvKeySources))
+		for key, source := range envData.relEnvKeySources {
+			if source != envfile.ExportedSourceFile {
+				result.EnvKeyOwners[key] = codeOwners.Of(source)
+			}
+		}
+	}
+	result.Conflicts = envData.conflicts
+	result.CaseMismatches = caseMismatches
+	result.ExampleDrift = analyzer.DetectExampleDrift(allUsages, envData.definitions, cfg)
+	variableSchema = variableSchema.Merge(commentDeclarations(absPath, envData.definitions, logger))
+	if variableSchema != nil && variableSchema.File != "" {
+		result.SchemaDrift = analyzer.DetectSchemaDrift(allUsages, variableSchema, relativeSource(absPath, variableSchema.File), cfg)
+	}
+	result.Schema = variableSchema
+	result.Policy = opts.Policy
+	if framework, ok := frontendFramework(absPath, cfg, logger); ok {
+		result.Frontend = analyzer.DetectFrontendLeaks(framework, allUsages, envData.definitions, cfg)
+	}
+	result.Style = analyzer.DetectStyleViolations(allUsages, envData.definitions, cfg)
+	result.Deprecated = analyzer.DetectDeprecated(allUsages, envData.definitions, cfg)
+	result.TypeMismatches = analyzer.DetectTypeMismatches(allUsages, envData.definitions, cfg)
+	result.Placeholders = analyzer.DetectPlaceholders(allUsages, envData.definitions, cfg)
+	result.UnresolvedRefs = analyzer.DetectUnresolvedReferences(envData.definitions, cfg)
+	result.Forbidden = analyzer.DetectForbidden(allUsages, envData.definitions, cfg)
+	result.ServiceGaps = analyzer.DetectServiceGaps(allUsages, cfg)
+	if opts.MinConfidence != "" {
+		result.FilterConfidence(opts.MinConfidence)
+	}
+	if opts.Owner != "" {
+		result.FilterOwner(opts.Owner)
+	}
+	result.Filter(filter)
+	result.Remediation = analyzer.DetectRemediation(result.Findings(), cfg)
+	phase.SetAttributes("envgrd.missing", len(result.Missing), "envgrd.unused", len(result.Unused), "envgrd.dynamic", len(result.PartialMatches))
+	phase.End()
+	if opts.Blame {
+		result.UnusedBlame = blameUnused(ctx, absPath, result, logger)
+	}
+
+	if collector != nil {
+		result.Stats = &stats.Stats{
+			Discovery:  discovery,
+			EnvLoading: envLoading,
+			Parse:      parse,
+			Analysis:   time.Since(phaseStart),
+			Total:      time.Since(scanStart),
+			PeakMemory: stats.PeakMemory(),
+		}
+		collector.Summarize(result.Stats, stats.DefaultSlowestFiles)
+	}
+
+	return &Result{
+		ScanResult: result,
+		Root:       absPath,
+		Files:      files,
+	}, nil
+}
+
+// resolveRoot returns the absolute path of the scanned directory
+func resolveRoot(opts Options) (string, error) {
+	path := opts.Path
+	if path == "" {
+		path = "."
+	}
+
+	// Resolve absolute path
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	// Check if path exists
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("path does not exist: %s", absPath)
+	}
+	return absPath, nil
+}
+
+// loadSchema loads the schema file of opts, else the config's schema, else the .envgrd.schema.json file of
+// absPath; it returns nil when there is none
+func loadSchema(opts Options, cfg *config.Config, absPath string) (*schema.Schema, error) {
+	file := opts.Schema
+	switch {
+	case file != "":
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(absPath, file)
+		}
+	case cfg.Schema != "":
+		// Relative to the config's directory, which differs from the scanned one with --config
+		file = cfg.Schema
+		if !filepath.IsAbs(file) {
+			dir := absPath
+			if cfg.File != "" {
+				dir = filepath.Dir(cfg.File)
+			}
+			file = filepath.Join(dir, file)
+		}
+	default:
+		if file = schema.Find(absPath); file == "" {
+			return nil, nil
+		}
+	}
+	return schema.Load(file)
+}
+
+// commentDeclarations returns the variables declared by comments in the .env and .envrc files of definitions
+// (see schema.FromComments); files are read in name order with example files first, so the comments of the
+// files that take effect win
+func commentDeclarations(absPath string, definitions map[string][]analyzer.Definition, logger *slog.Logger) map[string]schema.Variable {
+	var files []string
+	for _, defs := range definitions {
+		for _, def := range defs {
+			if (def.Kind == "env" || def.Kind == "envrc") && !slices.Contains(files, def.File) {
+				files = append(files, def.File)
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if example := envfile.IsExampleFile(files[i]); example != envfile.IsExampleFile(files[j]) {
+			return example
+		}
+		return files[i] < files[j]
+	})
+
+	declarations := make(map[string]schema.Variable)
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(absPath, path)
+		}
+		comments, err := envfile.FileComments(path)
+		if err != nil {
+			logger.Debug("failed to read env file comments", "file", file, "error", err)
+			continue
+		}
+		for key, v := range schema.FromComments(comments) {
+			declarations[key] = v
+		}
+	}
+	return declarations
+}
+
+// loadScanConfig returns opts.Config, or else the config of the scanned directory
+func loadScanConfig(opts Options, absPath string, logger *slog.Logger) *config.Config {
+	if opts.Config != nil {
+		return opts.Config.WithPolicy(opts.Policy)
+	}
+	cfg, err := config.LoadHierarchy(absPath, opts.Profile)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("failed to load .envgrd.config: %v", err))
+		// Continue with default config
+		cfg = &config.Config{}
+	}
+	return cfg.WithPolicy(opts.Policy)
+}
+
+// newFileScanner returns the scanner discovering the source files of a scan
+func newFileScanner(opts Options, cfg *config.Config, logger *slog.Logger) (*scanner.Scanner, error) {
+	fileScanner := scanner.NewScanner()
+	fileScanner.SetLogger(logger)
+	fileScanner.SetFollowSymlinks(opts.FollowSymlinks)
+	fileScanner.SetMaxDepth(opts.MaxDepth)
+	if opts.MaxFileSize != 0 {
+		fileScanner.SetMaxFileSize(max(opts.MaxFileSize, 0))
+	}
+	if err := fileScanner.SetIncludeGlobs(opts.IncludeGlobs); err != nil {
+		return nil, fmt.Errorf("invalid include glob: %w", err)
+	}
+	if err := fileScanner.SetExcludeGlobs(opts.ExcludeGlobs); err != nil {
+		return nil, fmt.Errorf("invalid exclude glob: %w", err)
+	}
+	if err := fileScanner.SetTemplateGlobs(cfg.TemplateGlobs()); err != nil {
+		return nil, fmt.Errorf("invalid templates config: %w", err)
+	}
+	if folders := cfg.ExcludedFolders(); len(folders) > 0 {
+		fileScanner.AddExcludeDirs(folders)
+	}
+	if err := fileScanner.SetLanguages(opts.Languages); err != nil {
+		return nil, fmt.Errorf("invalid languages: %w", err)
+	}
+	if err := fileScanner.SetExtensions(cfg.Extensions); err != nil {
+		return nil, fmt.Errorf("invalid extensions config: %w", err)
+	}
+	return fileScanner, nil
+}
+
+// HasIssues returns true if the result contains findings that should fail a run
+// Ignored missing variables don't count as issues
+func (r *Result) HasIssues(skipUnused bool, dynamic bool) bool {
+	if len(r.Missing) > 0 {
+		return true
+	}
+	if dynamic && len(r.PartialMatches) > 0 {
+		return true
+	}
+	if !skipUnused && len(r.Unused) > 0 {
+		return true
+	}
+	return false
+}
+
+// ExitCode returns the CLI exit code for the result: ExitMissing, ExitDynamic or ExitUnused
+// for the most severe failing category under failOn, or ExitOK
+func (r *Result) ExitCode(failOn FailOn, skipUnused bool, dynamic bool) int {
+	return output.ExitCode(r.ScanResult, failOn, skipUnused, dynamic)
+}
+
+// Since returns a copy of the result with only the findings that are new compared to prev,
+// and Fixed listing the findings of prev that are gone (a nil prev treats every finding as new)
+func (r *Result) Since(prev *State) *Result {
+	since := *r
+	since.ScanResult = state.Since(r.ScanResult, prev)
+	return &since
+}
+
+// CompareTo returns a copy of the result whose Comparison classifies its findings as new, fixed or
+// unchanged compared to the JSON report of a previous scan (envgrd scan --json) at path
+func (r *Result) CompareTo(path string) (*Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous report: %w", err)
+	}
+	defer file.Close()
+	report, err := output.ReadReport(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	compared := *r
+	compared.Comparison = analyzer.Compare(r.Findings(), output.ReportFindings(report), path)
+	return &compared, nil
+}
+
+// NewOnly returns a copy of the result that only keeps the findings its Comparison classifies as new,
+// e.g. to fail a run on regressions only (a result without a Comparison is returned as it is)
+func (r *Result) NewOnly() *Result {
+	if r.Comparison == nil {
+		return r
+	}
+	newOnly := *r
+	newOnly.Keep(r.Comparison.New)
+	return &newOnly
+}
+
+// reportFileCounts generates a formatted report string of file counts by language
+func reportFileCounts(files []scanner.FileInfo) string {
+	// Count files by language
+	langCounts := make(map[string]int)
+	for _, file := range files {
+		lang := string(file.Language)
+		if lang == "" {
+			lang = "unknown"
+		}
+		langCounts[lang]++
+	}
+
+	// Build report string
+	var reportParts []string
+	langOrder := []string{"javascript", "typescript", "tsx", "vue", "svelte", "astro", "go", "python", "jupyter", "rust", "java", "template", "ansible"}
+	for _, lang := range langOrder {
+		if count, ok := langCounts[lang]; ok && count > 0 {
+			// Use short names for display
+			shortName := lang
+			switch lang {
+			case "javascript":
+				shortName = "js"
+			case "typescript":
+				shortName = "ts"
+			}
+			reportParts = append(reportParts, fmt.Sprintf("%s: %d", shortName, count))
+			delete(langCounts, lang)
+		}
+	}
+	// Add any remaining languages
+	for lang, count := range langCounts {
+		if count > 0 {
+			reportParts = append(reportParts, fmt.Sprintf("%s: %d", lang, count))
+		}
+	}
+
+	if len(reportParts) > 0 {
+		reportStr := ""
+		for i, part := range reportParts {
+			if i > 0 {
+				reportStr += ", "
+			}
+			reportStr += part
+		}
+		return fmt.Sprintf("Found %d files (%s)", len(files), reportStr)
+	}
+	return fmt.Sprintf("Found %d files to parse", len(files))
+}
+
+// newEnvLoader returns the loader of the env files of opts and of the config's env_files, without the
+// excluded ones
+func newEnvLoader(opts Options, cfg *config.Config, logger *slog.Logger) (*envfile.Loader, error) {
+	envLoader := envfile.NewLoader()
+	envLoader.SetLogger(logger)
+	for _, envFile := range opts.EnvFiles {
+		envLoader.AddEnvFile(envFile)
+	}
+	for _, envFile := range cfg.EnvFiles.Files {
+		// Relative to the config's directory, which differs from the scanned one with --config
+		if !filepath.IsAbs(envFile) && cfg.File != "" {
+			if abs, err := filepath.Abs(filepath.Join(filepath.Dir(cfg.File), envFile)); err == nil {
+				envFile = abs
+			}
+		}
+		envLoader.AddEnvFile(envFile)
+	}
+	envLoader.SetPrecedence(cfg.Precedence)
+	if err := envLoader.SetExclude(append(append([]string{}, cfg.EnvFiles.Exclude...), opts.IgnoreEnvFiles...)); err != nil {
+		return nil, fmt.Errorf("invalid ignored env file: %w", err)
+	}
+	return envLoader, nil
+}
+
+// newScopeLoader returns the loader of the env files of a nested config: its env_files and those in its directory,
+// without the excluded ones
+func newScopeLoader(opts Options, cfg *config.Config, scope *config.Scope, logger *slog.Logger) (*envfile.Loader, error) {
+	loader := envfile.NewLoader()
+	loader.SetLogger(logger)
+	loader.SetPrecedence(cfg.Precedence)
+	for _, envFile := range scope.Config.EnvFiles.Files {
+		loader.AddEnvFile(envFile)
+	}
+	if err := loader.SetExclude(append(append([]string{}, scope.Config.EnvFiles.Exclude...), opts.IgnoreEnvFiles...)); err != nil {
+		return nil, fmt.Errorf("invalid ignored env file: %w", err)
+	}
+	return loader, nil
+}
+
+// loadDefinitions loads every definition of the env files of dir, through snapshot when not nil
+func loadDefinitions(ctx context.Context, envLoader *envfile.Loader, dir string, snapshot *envfile.Snapshot) (map[string][]envfile.Definition, error) {
+	if snapshot == nil {
+		return envLoader.LoadDefinitionsContext(ctx, dir)
+	}
+	return envLoader.LoadDefinitionsSnapshot(ctx, dir, snapshot)
+}
+
+// loadEnvironmentVariables loads and processes environment variables from files and exported env
+func loadEnvironmentVariables(ctx context.Context, envLoader *envfile.Loader, absPath string, cfg *config.Config, snapshot *envfile.Snapshot) (*envVarData, error) {
+	// Load every definition from files, the last one of each variable takes effect
+	definitions, err := loadDefinitions(ctx, envLoader, absPath, snapshot)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("scan aborted: %w", ctxErr)
+		}
+		return nil, fmt.Errorf("failed to load env files: %w", err)
+	}
+	shared, services := splitServices(absPath, definitions, cfg)
+	envVarsFromFilesOnly, envKeyLocations := envfile.Effective(shared)
+
+	// Make source file paths relative to scan root for better display
+	relEnvKeySources := make(map[string]string)
+	envKeyLines := make(map[string]int)
+	for k, location := range envKeyLocations {
+		if _, exported := os.LookupEnv(k); exported && envLoader.ExportedWins(location.Kind) {
+			relEnvKeySources[k] = envfile.ExportedSourceFile
+			continue
+		}
+		relEnvKeySources[k] = relativeSource(absPath, location.File)
+		envKeyLines[k] = location.Line
+	}
+
+	// Exported variables are present either way, the precedence only decides which source is reported
+	envVars := envfile.WithExportedEnv(envVarsFromFilesOnly)
+
+	// Variables only defined by services are checked for being unused like any other definition
+	for k, defs := range definitions {
+		if _, defined := envVarsFromFilesOnly[k]; !defined {
+			def := defs[len(defs)-1]
+			envVarsFromFilesOnly[k] = def.Value
+			relEnvKeySources[k] = relativeSource(absPath, def.File)
+			envKeyLines[k] = def.Line
+		}
+	}
+
+	relDefinitions := make(map[string][]analyzer.Definition, len(definitions))
+	for k, defs := range definitions {
+		for _, def := range defs {
+			relDefinitions[k] = append(relDefinitions[k], analyzer.Definition{
+				File:        relativeSource(absPath, def.File),
+				Line:        def.Line,
+				Value:       def.Value,
+				Kind:        def.Kind,
+				Environment: def.Environment,
+				Service:     def.Service,
+				Unresolved:  def.Unresolved,
+			})
+		}
+	}
+
+	return &envVarData{
+		envVars:              envVars,
+		envVarsFromFilesOnly: envVarsFromFilesOnly,
+		relEnvKeySources:     relEnvKeySources,
+		envKeyLines:          envKeyLines,
+		conflicts:            analyzer.DetectConflicts(relDefinitions),
+		definitions:          relDefinitions,
+		services:             services,
+	}, nil
+}
+
+// splitServices separates the definitions of the deployable units with code in the scan (docker-compose
+// services built from a subdirectory of absPath, and the services config), which only define variables for
+// the code of their service, from the shared ones
+func splitServices(absPath string, definitions map[string][]envfile.Definition, cfg *config.Config) (map[string][]envfile.Definition, []*config.Service) {
+	shared := make(map[string][]envfile.Definition, len(definitions))
+	manifestServices := cfg.ManifestServices()
+	byName := make(map[string]*config.Service)
+	var services []*config.Service
+	for k, defs := range definitions {
+		for _, def := range defs {
+			file := relativeSource(absPath, def.File)
+			names := manifestServices(file)
+			if def.Service != "" {
+				names = []string{def.Service}
+			}
+			scoped := false
+			for _, name := range names {
+				service := byName[name]
+				if service == nil {
+					dirs := cfg.ServiceDirs(name)
+					if dir, ok := serviceDir(absPath, def.ServiceDir); ok && dirs == nil {
+						dirs = []string{dir}
+					}
+					if len(dirs) == 0 {
+						continue
+					}
+					service = &config.Service{Name: name, Dirs: dirs, Defined: make(map[string]bool)}
+					byName[name] = service
+					services = append(services, service)
+				}
+				service.Defined[k] = true
+				if !slices.Contains(service.Manifests, file) {
+					service.Manifests = append(service.Manifests, file)
+				}
+				scoped = true
+			}
+			if !scoped {
+				shared[k] = append(shared[k], def)
+			}
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	for _, service := range services {
+		sort.Strings(service.Manifests)
+	}
+	return shared, services
+}
+
+// serviceDir returns the directory of a service relative to absPath, with forward slashes, when the service
+// is built from a subdirectory of it
+func serviceDir(absPath string, dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(absPath, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// serviceVariables keeps the variables defined by the environment of service
+func serviceVariables(vars map[string]string, service *config.Service) map[string]string {
+	kept := make(map[string]string, len(service.Defined))
+	for k, v := range vars {
+		if service.Defined[k] {
+			kept[k] = v
+		}
+	}
+	return kept
+}
+
+// unknownService describes a --service that no service of the scan is named after
+func unknownService(name string, cfg *config.Config) error {
+	names := cfg.ServiceNames()
+	if len(names) == 0 {
+		return fmt.Errorf("unknown service %q: no service has code in the scan (a docker-compose build context or dirs in the services config)", name)
+	}
+	return fmt.Errorf("unknown service %q (services: %s)", name, strings.Join(names, ", "))
+}
+
+// loadScopedEnvironments loads the env files of each nested config (its env_files and the env files in its directory)
+// Their variables only satisfy usages beneath the directory, so they're recorded in the scope rather than in
+// envData.envVars, but they're checked for being unused like any other definition
+func loadScopedEnvironments(ctx context.Context, absPath string, opts Options, cfg *config.Config, envData *envVarData, snapshot *envfile.Snapshot, logger *slog.Logger) error {
+	for _, scope := range cfg.Scopes {
+		loader, err := newScopeLoader(opts, cfg, scope, logger)
+		if err != nil {
+			return err
+		}
+		definitions, err := loadDefinitions(ctx, loader, filepath.Join(absPath, filepath.FromSlash(scope.Dir)), snapshot)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return fmt.Errorf("scan aborted: %w", ctxErr)
+			}
+			return fmt.Errorf("failed to load env files of %s: %w", scope.Dir, err)
+		}
+
+		scope.Defined = make(map[string]bool, len(definitions))
+		values, locations := envfile.Effective(definitions)
+		for key, defs := range definitions {
+			scope.Defined[key] = true
+			for _, def := range defs {
+				envData.definitions[key] = append(envData.definitions[key], analyzer.Definition{
+					File:        relativeSource(absPath, def.File),
+					Line:        def.Line,
+					Value:       def.Value,
+					Kind:        def.Kind,
+					Environment: def.Environment,
+					Service:     def.Service,
+					Unresolved:  def.Unresolved,
+				})
+			}
+			if _, defined := envData.envVarsFromFilesOnly[key]; !defined {
+				envData.envVarsFromFilesOnly[key] = values[key]
+				envData.relEnvKeySources[key] = relativeSource(absPath, locations[key].File)
+				envData.envKeyLines[key] = locations[key].Line
+			}
+		}
+		logger.Debug("loaded env files of nested config", "dir", scope.Dir, "variables", len(definitions))
+	}
+	return nil
+}
+
+// frontendFramework returns the framework set in the config, or the first one package.json depends on
+func frontendFramework(absPath string, cfg *config.Config, logger *slog.Logger) (analyzer.Framework, bool) {
+	if cfg.Frontend.Framework == config.FrameworkNone {
+		return analyzer.Framework{}, false
+	}
+	if cfg.Frontend.Framework != "" {
+		return analyzer.FrameworkByName(cfg.Frontend.Framework)
+	}
+
+	data, err := os.ReadFile(filepath.Join(absPath, "package.json"))
+	if err != nil {
+		return analyzer.Framework{}, false
+	}
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		logger.Warn(fmt.Sprintf("failed to parse package.json: %v", err))
+		return analyzer.Framework{}, false
+	}
+	for _, framework := range analyzer.Frameworks {
+		_, dep := pkg.Dependencies[framework.Package]
+		_, devDep := pkg.DevDependencies[framework.Package]
+		if dep || devDep {
+			logger.Debug("detected frontend framework", "framework", framework.Name)
+			return framework, true
+		}
+	}
+	return analyzer.Framework{}, false
+}
+
+// blameUnused looks up the last change of each unused variable's definition, running git blame once per env file
+// Files git can't blame (e.g., a gitignored .env) are skipped
+func blameUnused(ctx context.Context, absPath string, result analyzer.ScanResult, logger *slog.Logger) map[string]blame.Line {
+	byFile := make(map[string][]string)
+	for _, key := range result.Unused {
+		if source := result.EnvKeySources[key]; source != envfile.ExportedSourceFile && result.EnvKeyLines[key] > 0 {
+			byFile[source] = append(byFile[source], key)
+		}
+	}
+
+	blamed := make(map[string]blame.Line)
+	for file, keys := range byFile {
+		lines, err := blame.File(ctx, absPath, file)
+		if err != nil {
+			logger.Debug("skipping blame of env file", "file", file, "error", err)
+			continue
+		}
+		for _, key := range keys {
+			if line, ok := lines[result.EnvKeyLines[key]]; ok {
+				blamed[key] = line
+			}
+		}
+	}
+	return blamed
+}
+
+// relativeSource returns an env file path relative to the scan root
+func relativeSource(absPath string, sourcePath string) string {
+	if rel, err := filepath.Rel(absPath, sourcePath); err == nil && rel != "" {
+		return rel
+	}
+	// Fallback to just the filename if relative path fails
+	return filepath.Base(sourcePath)
+}
@@ -0,0 +1,832 @@
+package envgrd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/config"
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/logging"
+	"github.com/jenian/envgrd/internal/output"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// skipWithoutLanguage skips tests of languages left out of the build with their envgrd_nolang_* tag
+func skipWithoutLanguage(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if languages.GetLanguageInfo(name) == nil {
+			t.Skipf("%s is not built in", name)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	skipWithoutLanguage(t, "javascript", "go")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\nUNUSED_VAR=1\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.API_KEY;\nprocess.env.ENVGRD_TEST_MISSING;\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "main.go"), "package main\n\nimport \"os\"\n\nfunc main() { os.Getenv(\"API_KEY\") }\n")
+
+	var log bytes.Buffer
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Logger: slog.New(logging.NewTextHandler(&log, slog.LevelInfo))})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if result.Root != tmpDir {
+		t.Errorf("Expected root %s, got %s", tmpDir, result.Root)
+	}
+	if len(result.Files) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(result.Files))
+	}
+	if _, ok := result.Missing["ENVGRD_TEST_MISSING"]; !ok || len(result.Missing) != 1 {
+		t.Errorf("Expected only ENVGRD_TEST_MISSING to be missing, got %v", result.Missing)
+	}
+	if len(result.Unused) != 1 || result.Unused[0] != "UNUSED_VAR" {
+		t.Errorf("Expected UNUSED_VAR to be unused, got %v", result.Unused)
+	}
+	if !result.HasIssues(false, true) {
+		t.Error("Expected HasIssues to be true")
+	}
+	if !strings.Contains(log.String(), "Found 2 files (js: 1, go: 1)") {
+		t.Errorf("Expected file count report in log, got %q", log.String())
+	}
+}
+
+func TestScan_Usages(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\nUNUSED_VAR=1\n")
+	// Not parsed, the usages file replaces the code
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.IN_CODE;\n")
+
+	var file bytes.Buffer
+	if err := WriteUsages(&file, []EnvUsage{{Key: "API_KEY", File: "src/app.js", Line: 1}, {Key: "ENVGRD_TEST_MISSING", File: "src/app.test.js", Line: 2}}); err != nil {
+		t.Fatalf("WriteUsages failed: %v", err)
+	}
+	usages, err := ReadUsages(&file)
+	if err != nil {
+		t.Fatalf("ReadUsages failed: %v", err)
+	}
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Usages: usages})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected no files to be discovered, got %v", result.Files)
+	}
+	if len(result.Missing) != 1 || len(result.Missing["ENVGRD_TEST_MISSING"]) != 1 {
+		t.Errorf("Expected only ENVGRD_TEST_MISSING to be missing, got %v", result.Missing)
+	}
+	if !result.Missing["ENVGRD_TEST_MISSING"][0].InTest {
+		t.Error("Expected the usage in src/app.test.js to be classified as a test usage")
+	}
+	if len(result.Unused) != 1 || result.Unused[0] != "UNUSED_VAR" {
+		t.Errorf("Expected UNUSED_VAR to be unused, got %v", result.Unused)
+	}
+}
+
+func TestScan_CaseInsensitive(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.Api_Key;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Missing["Api_Key"]) != 1 || len(result.Unused) != 1 || len(result.CaseMismatches) != 0 {
+		t.Errorf("Expected Api_Key to be missing and API_KEY unused by default, got missing %v, unused %v", result.Missing, result.Unused)
+	}
+
+	result, err = Scan(context.Background(), Options{Path: tmpDir, CaseInsensitive: true})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Missing) != 0 || len(result.Unused) != 0 {
+		t.Errorf("Expected Api_Key to match API_KEY, got missing %v, unused %v", result.Missing, result.Unused)
+	}
+	if len(result.CaseMismatches) != 1 {
+		t.Fatalf("Expected 1 case-only mismatch, got %+v", result.CaseMismatches)
+	}
+	mismatch := result.CaseMismatches[0]
+	if mismatch.Key != "Api_Key" || mismatch.Defined != "API_KEY" || len(mismatch.Usages) != 1 || len(mismatch.Definitions) != 1 {
+		t.Errorf("Unexpected case-only mismatch: %+v", mismatch)
+	}
+}
+
+func TestScan_Languages(t *testing.T) {
+	// Usages in the languages this build registers (envgrd_nolang_* tags leave some out), by language
+	samples := map[string]struct{ file, code, key string }{
+		"go":         {"main.go", "package main\n\nimport \"os\"\n\nvar _ = os.Getenv(\"GO_KEY\")\n", "GO_KEY"},
+		"python":     {filepath.Join("scripts", "release.py"), "import os\nos.environ[\"PY_KEY\"]\n", "PY_KEY"},
+		"javascript": {filepath.Join("web", "app.js"), "process.env.JS_KEY;\n", "JS_KEY"},
+		"rust":       {filepath.Join("src", "main.rs"), "fn main() {\n    std::env::var(\"RS_KEY\");\n}\n", "RS_KEY"},
+		"java":       {"Main.java", "class Main {\n    String key = System.getenv(\"JAVA_KEY\");\n}\n", "JAVA_KEY"},
+	}
+	tmpDir := t.TempDir()
+	var registered []string
+	for name, sample := range samples {
+		if languages.GetLanguageInfo(name) != nil {
+			writeFile(t, filepath.Join(tmpDir, sample.file), sample.code)
+			registered = append(registered, name)
+		}
+	}
+	if len(registered) < 2 {
+		t.Skipf("Needs two built-in languages to leave one out, got %v", registered)
+	}
+	slices.Sort(registered)
+	selected := samples[registered[0]]
+
+	all, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(all.Missing) != len(registered) {
+		t.Fatalf("Expected a usage in each of %v, got %v", registered, all.Missing)
+	}
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Languages: registered[:1]})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Missing) != 1 || len(result.Missing[selected.key]) != 1 {
+		t.Errorf("Expected only the %s usage, got %v", registered[0], result.Missing)
+	}
+
+	if _, err := Scan(context.Background(), Options{Path: tmpDir, Languages: []string{"golang"}}); err == nil || !strings.Contains(err.Error(), `unknown language "golang"`) {
+		t.Errorf("Expected an unknown language error, got %v", err)
+	}
+}
+
+func TestScan_Source(t *testing.T) {
+	skipWithoutLanguage(t, "typescript", "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\nUNUSED_KEY=x\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.ts"), "process.env.ENVGRD_TEST_SAVED;\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "other.js"), "process.env.ENVGRD_TEST_OTHER;\n")
+
+	// The buffer replaces the saved file, and only its findings are reported
+	source := []byte("process.env.API_KEY;\nprocess.env.ENVGRD_TEST_EDITED;\n")
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Source: source, SourceFile: filepath.Join("src", "app.ts")})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Missing) != 1 || len(result.Missing["ENVGRD_TEST_EDITED"]) != 1 {
+		t.Errorf("Expected only the buffer's missing variable, got %v", result.Missing)
+	}
+	if usage := result.Missing["ENVGRD_TEST_EDITED"]; len(usage) == 1 && (usage[0].File != filepath.Join("src", "app.ts") || usage[0].Line != 2) {
+		t.Errorf("Expected the usage at src/app.ts:2, got %+v", usage[0])
+	}
+	if len(result.Unused) != 0 || len(result.Files) != 1 {
+		t.Errorf("Expected no unused variables and a single file, got %v and %v", result.Unused, result.Files)
+	}
+
+	// A buffer that isn't a source file has no findings
+	result, err = Scan(context.Background(), Options{Path: tmpDir, Source: source, SourceFile: "notes.txt"})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Missing) != 0 || len(result.Files) != 0 {
+		t.Errorf("Expected no findings for notes.txt, got %v", result.Missing)
+	}
+
+	if _, err := Scan(context.Background(), Options{Path: tmpDir, Source: source}); err == nil {
+		t.Error("Expected an error for a source without a file name")
+	}
+}
+
+func TestResult_CompareTo(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.API_KEY;\nprocess.env.ENVGRD_TEST_OLD;\n")
+
+	previous, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	reporter, _ := NewReporter("json")
+	var report bytes.Buffer
+	if err := reporter.Report(&report, previous.ScanResult, ReportOptions{}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	reportPath := filepath.Join(t.TempDir(), "previous.json")
+	writeFile(t, reportPath, report.String())
+
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.API_KEY;\nprocess.env.ENVGRD_TEST_OLD;\nprocess.env.ENVGRD_TEST_NEW;\n")
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	compared, err := result.CompareTo(reportPath)
+	if err != nil {
+		t.Fatalf("CompareTo failed: %v", err)
+	}
+	if compared.Comparison.New.Count() != 1 || compared.Comparison.Unchanged.Count() != 1 {
+		t.Errorf("Expected 1 new and 1 unchanged finding, got %+v", compared.Comparison)
+	}
+	if len(compared.Missing) != 2 {
+		t.Errorf("Expected the report to keep every finding, got %v", compared.Missing)
+	}
+
+	newOnly := compared.NewOnly()
+	if _, ok := newOnly.Missing["ENVGRD_TEST_NEW"]; !ok || len(newOnly.Missing) != 1 {
+		t.Errorf("Expected only ENVGRD_TEST_NEW to be kept, got %v", newOnly.Missing)
+	}
+	if len(compared.Missing) != 2 {
+		t.Error("Expected NewOnly to leave the compared result alone")
+	}
+
+	if _, err := result.CompareTo(filepath.Join(tmpDir, "nope.json")); err == nil {
+		t.Error("Expected an error for a missing report")
+	}
+}
+
+func TestScan_ConfigOverride(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.js"), "process.env.ENVGRD_TEST_IGNORED;\n")
+
+	cfg := &Config{Ignores: IgnoresConfig{Missing: []string{"ENVGRD_TEST_IGNORED"}}}
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(result.Missing) != 0 {
+		t.Errorf("Expected no missing variables, got %v", result.Missing)
+	}
+	if result.IgnoredMissing != 1 {
+		t.Errorf("Expected 1 ignored missing variable, got %d", result.IgnoredMissing)
+	}
+	if result.HasIssues(false, true) {
+		t.Error("Expected HasIssues to be false")
+	}
+}
+
+func TestScan_InvalidPath(t *testing.T) {
+	_, err := Scan(context.Background(), Options{Path: filepath.Join(t.TempDir(), "does-not-exist")})
+	if err == nil {
+		t.Fatal("Expected error for non-existent path")
+	}
+}
+
+func TestScan_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.js"), "process.env.API_KEY;\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Scan(ctx, Options{Path: tmpDir})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestScan_Cache(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.ENVGRD_TEST_CACHED;\n")
+
+	var log bytes.Buffer
+	opts := Options{Path: tmpDir, CacheDir: cacheDir, Logger: slog.New(logging.NewTextHandler(&log, slog.LevelDebug))}
+	if _, err := Scan(context.Background(), opts); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if strings.Contains(log.String(), "using cached usages") {
+		t.Fatal("Expected first scan to parse without the cache")
+	}
+
+	// Identical content under a new path is served from the cache with the new location
+	if err := os.Rename(filepath.Join(tmpDir, "src"), filepath.Join(tmpDir, "lib")); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	log.Reset()
+	result, err := Scan(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !strings.Contains(log.String(), "using cached usages") {
+		t.Errorf("Expected second scan to use the cache, got log %q", log.String())
+	}
+	usages := result.Missing["ENVGRD_TEST_CACHED"]
+	if len(usages) != 1 || usages[0].File != filepath.Join("lib", "app.js") {
+		t.Errorf("Expected cached usage in lib/app.js, got %+v", usages)
+	}
+
+	if err := ClearCache(cacheDir); err != nil {
+		t.Fatalf("ClearCache failed: %v", err)
+	}
+}
+
+func TestScan_EnvSnapshot(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+	envPath := filepath.Join(tmpDir, ".env")
+	writeFile(t, envPath, "API_KEY=abc\n")
+	source := []byte("process.env.API_KEY;\nprocess.env.NEW_KEY;\n")
+
+	info, err := CacheEnv(context.Background(), Options{Path: tmpDir, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatalf("CacheEnv failed: %v", err)
+	}
+	if info.Variables != 1 || info.EnvFiles == 0 {
+		t.Errorf("Expected 1 cached variable, got %+v", info)
+	}
+
+	var log bytes.Buffer
+	opts := Options{Path: tmpDir, CacheDir: cacheDir, EnvSnapshot: true, Source: source, SourceFile: "app.js",
+		Logger: slog.New(logging.NewTextHandler(&log, slog.LevelDebug))}
+	result, err := Scan(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !strings.Contains(log.String(), "using env snapshot") {
+		t.Errorf("Expected the scan to use the snapshot, got log %q", log.String())
+	}
+	if len(result.Missing) != 1 || result.Missing["NEW_KEY"] == nil {
+		t.Errorf("Expected only NEW_KEY to be missing, got %v", result.Missing)
+	}
+
+	// A changed env file is parsed again
+	writeFile(t, envPath, "API_KEY=abc\nNEW_KEY=def\n")
+	log.Reset()
+	if result, err = Scan(context.Background(), opts); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if strings.Contains(log.String(), "using env snapshot") || len(result.Missing) != 0 {
+		t.Errorf("Expected the changed env file to be parsed, got %v and log %q", result.Missing, log.String())
+	}
+
+	if _, err := CacheEnv(context.Background(), Options{Path: tmpDir}); err == nil {
+		t.Error("Expected an error without a cache directory")
+	}
+}
+
+func TestScan_Stats(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.API_KEY;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Stats != nil {
+		t.Error("Expected no stats unless requested")
+	}
+
+	result, err = Scan(context.Background(), Options{Path: tmpDir, Stats: true})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Stats == nil {
+		t.Fatal("Expected stats")
+	}
+	if result.Stats.FilesParsed != 1 || len(result.Stats.SlowestFiles) != 1 || result.Stats.SlowestFiles[0].Path != filepath.Join("src", "app.js") {
+		t.Errorf("Unexpected stats: %+v", result.Stats)
+	}
+	if result.Stats.Total < result.Stats.Parse {
+		t.Errorf("Expected total %s to include parse time %s", result.Stats.Total, result.Stats.Parse)
+	}
+}
+
+func TestScan_Precedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "ENVGRD_TEST_SHARED=from-env\nENVGRD_TEST_EXPORTED=from-env\n")
+	writeFile(t, filepath.Join(tmpDir, "docker-compose.yml"), "services:\n  web:\n    environment:\n      ENVGRD_TEST_SHARED: from-compose\n")
+	t.Setenv("ENVGRD_TEST_EXPORTED", "from-shell")
+
+	// Without a precedence list the auto-detected compose file is loaded last and wins
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Config: &Config{}})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got := result.EnvKeySources["ENVGRD_TEST_SHARED"]; got != "docker-compose.yml" {
+		t.Errorf("Expected docker-compose.yml to win by default, got %q", got)
+	}
+	if got := result.EnvKeySources["ENVGRD_TEST_EXPORTED"]; got != ".env" {
+		t.Errorf("Expected env files to win over exported variables by default, got %q", got)
+	}
+
+	cfg := &Config{Precedence: []string{"exported", "env", "docker-compose"}}
+	result, err = Scan(context.Background(), Options{Path: tmpDir, Config: cfg})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got := result.EnvKeySources["ENVGRD_TEST_SHARED"]; got != ".env" {
+		t.Errorf("Expected .env to win with precedence, got %q", got)
+	}
+	if got := result.EnvKeys["ENVGRD_TEST_SHARED"]; got != "from-env" {
+		t.Errorf("Expected the .env value to take effect, got %q", got)
+	}
+	if got := result.EnvKeySources["ENVGRD_TEST_EXPORTED"]; got != "exported environment" {
+		t.Errorf("Expected the exported variable to win, got %q", got)
+	}
+}
+
+func TestScan_FrontendFramework(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "package.json"), `{"devDependencies": {"vite": "^5.0.0"}}`)
+	writeFile(t, filepath.Join(tmpDir, ".env"), "VITE_API_URL=http://localhost\nVITE_STRIPE_SECRET=sk_live\nDB_HOST=db\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.VITE_API_URL;\nprocess.env.DB_HOST;\nprocess.env.NODE_ENV;\n")
+	writeFile(t, filepath.Join(tmpDir, "server", "index.js"), "process.env.DB_HOST;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Frontend == nil || result.Frontend.Framework != "vite" {
+		t.Fatalf("Expected vite to be detected, got %+v", result.Frontend)
+	}
+	if usages := result.Frontend.Unprefixed["DB_HOST"]; len(result.Frontend.Unprefixed) != 1 || len(usages) != 1 || usages[0].File != filepath.Join("src", "app.js") {
+		t.Errorf("Expected only the client-side DB_HOST usage, got %v", result.Frontend.Unprefixed)
+	}
+	if _, ok := result.Frontend.Exposed["VITE_STRIPE_SECRET"]; !ok || len(result.Frontend.Exposed) != 1 {
+		t.Errorf("Expected VITE_STRIPE_SECRET to be exposed, got %v", result.Frontend.Exposed)
+	}
+
+	result, err = Scan(context.Background(), Options{Path: tmpDir, Config: &Config{Frontend: config.FrontendConfig{Framework: config.FrameworkNone}}})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.Frontend != nil {
+		t.Errorf("Expected the check to be disabled, got %+v", result.Frontend)
+	}
+}
+
+func TestScan_Tracing(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "src", "app.js"), "process.env.API_KEY;\n")
+
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						Name string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Invalid OTLP JSON: %v", err)
+			return
+		}
+		for _, span := range request.ResourceSpans[0].ScopeSpans[0].Spans {
+			names = append(names, span.Name)
+		}
+	}))
+	defer server.Close()
+
+	tracer := &Tracer{Endpoint: server.URL, Scope: "envgrd"}
+	if _, err := Scan(WithTracer(context.Background(), tracer), Options{Path: tmpDir}); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if got := strings.Join(names, ","); got != "discovery,sources,parse file,parse,analysis,envgrd.scan" {
+		t.Errorf("Unexpected spans %s", got)
+	}
+}
+
+func TestScan_IgnoreEnvFiles(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=abc\n")
+	writeFile(t, filepath.Join(tmpDir, ".env.test"), "TEST_KEY=abc\n")
+	writeFile(t, filepath.Join(tmpDir, ".env.ci"), "CI_KEY=abc\n")
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.config"), "env_files:\n  exclude: [.env.test]\n")
+	writeFile(t, filepath.Join(tmpDir, "app.js"), "process.env.API_KEY;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Unused) != 1 || result.Unused[0] != "CI_KEY" {
+		t.Errorf("Expected only CI_KEY to be unused with .env.test excluded, got %v", result.Unused)
+	}
+
+	result, err = Scan(context.Background(), Options{Path: tmpDir, IgnoreEnvFiles: []string{".env.ci"}})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Unused) != 0 {
+		t.Errorf("Expected no unused variables with .env.ci ignored too, got %v", result.Unused)
+	}
+
+	if _, err := Scan(context.Background(), Options{Path: tmpDir, IgnoreEnvFiles: []string{".env.["}}); err == nil {
+		t.Error("Expected an error for a malformed --ignore-env-file glob")
+	}
+}
+
+func TestScan_ComposeServices(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "docker-compose.yml"), `services:
+  api:
+    build: ./api
+    environment:
+      API_PORT: "8080"
+      API_UNUSED: "1"
+  worker:
+    build:
+      context: ./worker
+    environment:
+      QUEUE_URL: redis://queue
+  db:
+    image: postgres
+    environment:
+      DATABASE_URL: postgres://db
+`)
+	writeFile(t, filepath.Join(tmpDir, "api", "server.js"), "process.env.API_PORT;\nprocess.env.QUEUE_URL;\nprocess.env.DATABASE_URL;\n")
+	writeFile(t, filepath.Join(tmpDir, "worker", "main.js"), "process.env.QUEUE_URL;\nprocess.env.API_PORT;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	// Services only define variables for their own code, db isn't built from the repository so its
+	// environment is shared
+	if usages := result.Missing["QUEUE_URL"]; len(usages) != 1 || usages[0].File != filepath.Join("api", "server.js") {
+		t.Errorf("Expected QUEUE_URL to be missing in api only, got %+v", usages)
+	}
+	if usages := result.Missing["API_PORT"]; len(usages) != 1 || usages[0].File != filepath.Join("worker", "main.js") {
+		t.Errorf("Expected API_PORT to be missing in worker only, got %+v", usages)
+	}
+	if len(result.Missing) != 2 || len(result.Unused) != 1 || result.Unused[0] != "API_UNUSED" {
+		t.Errorf("Expected 2 missing and API_UNUSED unused, got %v and %v", result.Missing, result.Unused)
+	}
+
+	result, err = Scan(context.Background(), Options{Path: tmpDir, Service: "worker"})
+	if err != nil {
+		t.Fatalf("Scan of worker failed: %v", err)
+	}
+	if len(result.Missing) != 1 || result.Missing["API_PORT"] == nil || len(result.Unused) != 0 {
+		t.Errorf("Expected only API_PORT to be missing in worker, got %v and %v", result.Missing, result.Unused)
+	}
+
+	if _, err := Scan(context.Background(), Options{Path: tmpDir, Service: "db"}); err == nil || !strings.Contains(err.Error(), "services: api, worker") {
+		t.Errorf("Expected an unknown service error listing api and worker, got %v", err)
+	}
+}
+
+func TestScan_ServiceGaps(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "docker-compose.yml"), "services:\n  api:\n    build: ./api\n    environment:\n      API_PORT: \"8080\"\n")
+	writeFile(t, filepath.Join(tmpDir, "worker-configmap.yaml"), "apiVersion: v1\nkind: ConfigMap\ndata:\n  QUEUE_URL: redis://queue\n")
+	writeFile(t, filepath.Join(tmpDir, ".env"), "DATABASE_URL=postgres://localhost\n")
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.config"), "services:\n  worker:\n    dirs: [jobs]\n    manifests: [\"worker-*.yaml\"]\n")
+	writeFile(t, filepath.Join(tmpDir, "api", "server.js"), "process.env.API_PORT;\nprocess.env.DATABASE_URL;\nprocess.env.HOME;\n")
+	writeFile(t, filepath.Join(tmpDir, "jobs", "main.js"), "process.env.QUEUE_URL;\nprocess.env.DATABASE_URL;\nprocess.env.API_PORT || 80;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	// DATABASE_URL comes from the shared .env, which no service's manifests include
+	if len(result.Missing) != 0 {
+		t.Errorf("Expected nothing missing from the repository as a whole, got %v", result.Missing)
+	}
+	if len(result.ServiceGaps) != 2 {
+		t.Fatalf("Expected api and worker, got %+v", result.ServiceGaps)
+	}
+	api, worker := result.ServiceGaps[0], result.ServiceGaps[1]
+	if api.Service != "api" || len(api.Missing) != 1 || api.Missing["DATABASE_URL"] == nil || api.Manifests[0] != "docker-compose.yml" {
+		t.Errorf("Expected api to miss DATABASE_URL only, got %+v", api)
+	}
+	if worker.Service != "worker" || worker.Dirs[0] != "jobs" || worker.Defined != 1 || len(worker.Missing) != 1 || worker.Missing["DATABASE_URL"] == nil {
+		t.Errorf("Expected worker to miss DATABASE_URL only, got %+v", worker)
+	}
+}
+
+func TestScan_SchemaDrift(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.schema.json"), `{"PORT": "number", "OLD_FLAG": "boolean", "SENTRY_DSN": {"required": false}}`)
+	writeFile(t, filepath.Join(tmpDir, ".env"), "PORT=8080\nDATABASE_URL=postgres://localhost\n")
+	writeFile(t, filepath.Join(tmpDir, "main.js"), "process.env.PORT;\nprocess.env.DATABASE_URL;\nprocess.env.HOME;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	drift := result.SchemaDrift
+	if drift == nil || drift.Schema != ".envgrd.schema.json" {
+		t.Fatalf("Expected the schema to be compared, got %+v", drift)
+	}
+	// SENTRY_DSN is optional, HOME a system variable
+	if len(drift.Unread) != 1 || drift.Unread[0] != "OLD_FLAG" {
+		t.Errorf("Expected OLD_FLAG to be unread, got %v", drift.Unread)
+	}
+	if len(drift.Undeclared) != 1 || drift.Undeclared["DATABASE_URL"] == nil {
+		t.Errorf("Expected DATABASE_URL to be undeclared, got %v", drift.Undeclared)
+	}
+
+	writeFile(t, filepath.Join(tmpDir, "config", "vars.json"), `{"PORT": "number", "DATABASE_URL": "string"}`)
+	result, err = Scan(context.Background(), Options{Path: tmpDir, Schema: "config/vars.json"})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if drift := result.SchemaDrift; drift.Count() != 0 || drift.Schema != filepath.Join("config", "vars.json") {
+		t.Errorf("Expected no drift from config/vars.json, got %+v", drift)
+	}
+}
+
+func TestScan_Placeholders(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "API_KEY=changeme\nSENTRY_DSN=\nREGION=eu-west-1\n")
+	writeFile(t, filepath.Join(tmpDir, "main.js"), "process.env.API_KEY;\nprocess.env.SENTRY_DSN;\nprocess.env.REGION;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Missing) != 0 {
+		t.Errorf("Expected no missing variables, got %v", result.Missing)
+	}
+	if len(result.Placeholders) != 2 || result.Placeholders[0].Key != "API_KEY" || !result.Placeholders[1].Empty {
+		t.Fatalf("Expected API_KEY and an empty SENTRY_DSN, got %+v", result.Placeholders)
+	}
+	if code := result.ExitCode(FailOnAny, false, false); code != ExitPlaceholder {
+		t.Errorf("Expected exit code %d, got %d", ExitPlaceholder, code)
+	}
+}
+
+func TestScan_Forbidden(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.config"), "forbidden:\n  AWS_SECRET_ACCESS_KEY: use the SDK credential chain\n")
+	writeFile(t, filepath.Join(tmpDir, ".env"), "AWS_SECRET_ACCESS_KEY=secret\nAWS_REGION=eu-west-1\n")
+	writeFile(t, filepath.Join(tmpDir, "main.js"), "process.env.AWS_SECRET_ACCESS_KEY;\nprocess.env.AWS_REGION;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Forbidden) != 1 || result.Forbidden[0].Message != "use the SDK credential chain" || len(result.Forbidden[0].Usages) != 1 {
+		t.Fatalf("Expected AWS_SECRET_ACCESS_KEY to be forbidden, got %+v", result.Forbidden)
+	}
+	if code := result.ExitCode(FailOnAny, false, false); code != ExitForbidden {
+		t.Errorf("Expected exit code %d, got %d", ExitForbidden, code)
+	}
+}
+
+func TestScan_Remediation(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.config"), "remediation:\n  DATABASE_URL: \"Request access via #infra\"\n  PORT: \"Defaults to 8080\"\n")
+	writeFile(t, filepath.Join(tmpDir, ".env"), "PORT=8080\n")
+	writeFile(t, filepath.Join(tmpDir, "main.js"), "process.env.DATABASE_URL;\nprocess.env.PORT;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.Remediation) != 1 || result.Remediation["DATABASE_URL"] != "Request access via #infra" {
+		t.Errorf("Expected only the hint of the missing DATABASE_URL, got %v", result.Remediation)
+	}
+}
+
+func TestScan_K8sReferences(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "deployment.yaml"), `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          env:
+            - name: API_URL
+              value: http://$(API_HOST):8080
+            - name: API_HOST
+              value: api
+            - name: CALLBACK_URL
+              value: $(API_URL)/callback
+`)
+	writeFile(t, filepath.Join(tmpDir, "main.js"), "process.env.API_URL;\nprocess.env.API_HOST;\nprocess.env.CALLBACK_URL;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.UnresolvedRefs) != 1 || result.UnresolvedRefs[0].Key != "API_URL" || result.UnresolvedRefs[0].References[0] != "API_HOST" {
+		t.Fatalf("Expected API_URL to reference API_HOST before its definition, got %+v", result.UnresolvedRefs)
+	}
+	if code := result.ExitCode(FailOnAny, false, false); code != ExitReference {
+		t.Errorf("Expected exit code %d, got %d", ExitReference, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.schema.json"), `{"TLS_CERT": {"format": "path"}}`)
+	writeFile(t, filepath.Join(tmpDir, ".env"), "DATABASE_URL=postgres://db/app\nAPI_PORT=http\nTLS_CERT=certs/missing.pem\n")
+
+	invalid, err := Validate(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(invalid) != 2 || invalid[0].Key != "API_PORT" || invalid[1].Key != "TLS_CERT" {
+		t.Fatalf("Expected API_PORT and TLS_CERT to be invalid, got %+v", invalid)
+	}
+	if invalid[1].Inferred || invalid[1].Definition.Line != 3 {
+		t.Errorf("Expected the declared path format at .env:3, got %+v", invalid[1])
+	}
+}
+
+func TestScan_CommentDeclarations(t *testing.T) {
+	skipWithoutLanguage(t, "python")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "# description: Number of worker processes\n# type: int\n# required\nWORKERS=four\n\n# Section header\nREGION=eu-west-1\n")
+	writeFile(t, filepath.Join(tmpDir, "main.py"), "import os\nworkers = os.environ['WORKERS']\nregion = os.environ['REGION']\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if result.SchemaDrift != nil {
+		t.Errorf("Expected no schema drift without a schema file, got %+v", result.SchemaDrift)
+	}
+	explanation := result.Explain("WORKERS")
+	if v := explanation.Declaration; v == nil || v.Description != "Number of worker processes" || v.Type != "integer" || !v.Required {
+		t.Fatalf("Expected WORKERS to be declared by its comments, got %+v", v)
+	}
+	if len(explanation.Definitions) != 1 || len(explanation.Usages) != 1 {
+		t.Errorf("Expected one definition and one usage, got %+v", explanation)
+	}
+	if result.Explain("REGION").Declaration != nil {
+		t.Error("Expected REGION not to be declared by a plain comment")
+	}
+	// The declared type wins over the value's in generated accessors
+	if accessors := output.BuildAccessors(result.ScanResult); len(accessors) != 2 || accessors[1].Key != "WORKERS" ||
+		accessors[1].Type != "int" || accessors[1].Description != "Number of worker processes" {
+		t.Errorf("Expected a documented int accessor for WORKERS, got %+v", accessors)
+	}
+
+	invalid, err := Validate(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(invalid) != 1 || invalid[0].Key != "WORKERS" || invalid[0].Rule != "type" {
+		t.Errorf("Expected WORKERS to fail its declared type, got %+v", invalid)
+	}
+}
+
+func TestScan_Policy(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".envgrd.config"), "ignores:\n  missing: [SERVICE_NAME]\nseverity:\n  missing: info\n")
+	writeFile(t, filepath.Join(tmpDir, "main.py"), "import os\nkey = os.environ['API_KEY']\n")
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	writeFile(t, policyFile, "required: [SERVICE_NAME]\nseverity:\n  missing: error\n")
+
+	policy, err := LoadPolicy(policyFile, "")
+	if err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	result, err := Scan(context.Background(), Options{Path: tmpDir, Policy: policy})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if _, ok := result.Missing["SERVICE_NAME"]; !ok {
+		t.Errorf("Expected the variable the policy requires to be missing, got %v", result.Missing)
+	}
+	if severity := result.SeverityOf(config.CategoryMissing, "API_KEY"); severity != config.SeverityError {
+		t.Errorf("Expected the policy to raise the severity of missing variables, got %s", severity)
+	}
+	if result.Policy != policy {
+		t.Error("Expected the result to record the policy")
+	}
+}
@@ -0,0 +1,210 @@
+package envgrd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jenian/envgrd/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRepoConcurrency is the number of repositories ScanRepos scans at a time by default
+const DefaultRepoConcurrency = 4
+
+// Repo is a repository of a multi-repo scan: a local path, or a git URL cloned for the scan
+type Repo struct {
+	Name string `yaml:"name"` // Name of the repository in reports (default: the base name of the path or URL)
+	Path string `yaml:"path"` // Local path, relative to the repos file
+	URL  string `yaml:"url"`  // Git URL, shallow-cloned into a temporary directory
+	Ref  string `yaml:"ref"`  // Branch or tag to clone (default: the remote's default branch)
+}
+
+// UnmarshalYAML decodes an entry, which can also be a single path or URL
+func (r *Repo) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = Repo{Path: node.Value}
+		if isGitURL(node.Value) {
+			*r = Repo{URL: node.Value}
+		}
+		return nil
+	}
+	type plain Repo
+	return node.Decode((*plain)(r))
+}
+
+// Source returns the URL or path of the repository
+func (r Repo) Source() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	return r.Path
+}
+
+// reposFile is the content of a repos file
+type reposFile struct {
+	Repos       []Repo `yaml:"repos"`
+	Concurrency int    `yaml:"concurrency"`
+}
+
+// LoadRepos reads a repos file listing the repositories of a multi-repo scan, with the number of them to
+// scan at a time (0 when the file doesn't set it):
+//
+//	concurrency: 8
+//	repos:
+//	  - ../billing
+//	  - https://github.com/acme/api.git
+//	  - name: web
+//	    url: git@github.com:acme/web.git
+//	    ref: main
+//
+// Paths are resolved relative to the file, and names default to the base name of the path or URL
+func LoadRepos(file string) ([]Repo, int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read repos file: %w", err)
+	}
+	var content reposFile
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return nil, 0, fmt.Errorf("invalid repos file %s: %w", file, err)
+	}
+	if content.Concurrency < 0 {
+		return nil, 0, fmt.Errorf("invalid repos file %s: concurrency must not be negative, got %d", file, content.Concurrency)
+	}
+	if len(content.Repos) == 0 {
+		return nil, 0, fmt.Errorf("invalid repos file %s: no repos listed", file)
+	}
+
+	names := make(map[string]bool, len(content.Repos))
+	for i := range content.Repos {
+		repo := &content.Repos[i]
+		switch {
+		case repo.Path == "" && repo.URL == "":
+			return nil, 0, fmt.Errorf("invalid repos file %s: entry %d has neither a path nor a url", file, i+1)
+		case repo.Path != "" && repo.URL != "":
+			return nil, 0, fmt.Errorf("invalid repos file %s: entry %d has both a path and a url", file, i+1)
+		case repo.Ref != "" && repo.URL == "":
+			return nil, 0, fmt.Errorf("invalid repos file %s: entry %d sets a ref without a url", file, i+1)
+		}
+		if repo.Path != "" && !filepath.IsAbs(repo.Path) {
+			repo.Path = filepath.Join(filepath.Dir(file), repo.Path)
+		}
+		if repo.Name == "" {
+			repo.Name = repoName(*repo)
+		}
+		if names[repo.Name] {
+			return nil, 0, fmt.Errorf("invalid repos file %s: several repos are named %s, set a name to tell them apart", file, repo.Name)
+		}
+		names[repo.Name] = true
+	}
+	return content.Repos, content.Concurrency, nil
+}
+
+// RepoResult is the outcome of scanning one repository of a multi-repo scan
+type RepoResult struct {
+	Repo   Repo
+	Result *Result // nil when Err is set
+	Err    error   // Why the repository couldn't be cloned or scanned
+}
+
+// ScanRepos scans repos, at most concurrency of them at a time (DefaultRepoConcurrency when 0), and returns
+// their results in the order of repos. Each scan runs with opts and Path set to the repository; unless
+// opts.Config is set, each repository's own config applies. Git URLs are cloned into temporary
+// directories, which are removed once scanned. A repository that can't be scanned doesn't stop the others
+func ScanRepos(ctx context.Context, repos []Repo, opts Options, concurrency int) []RepoResult {
+	if concurrency <= 0 {
+		concurrency = DefaultRepoConcurrency
+	}
+	logger := logging.OrDiscard(opts.Logger)
+	results := make([]RepoResult, len(repos))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			repoOpts := opts
+			repoOpts.Logger = logger.With("repo", repo.Name)
+			result, err := scanRepo(ctx, repo, repoOpts)
+			if err != nil {
+				repoOpts.Logger.Warn(fmt.Sprintf("Failed to scan %s: %v", repo.Name, err))
+			}
+			results[i] = RepoResult{Repo: repo, Result: result, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// scanRepo scans a repository, cloning it first when it's a git URL
+func scanRepo(ctx context.Context, repo Repo, opts Options) (*Result, error) {
+	opts.Path = repo.Path
+	if repo.URL != "" {
+		dir, err := os.MkdirTemp("", "envgrd-repo-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create clone directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		if err := cloneRepo(ctx, repo, dir); err != nil {
+			return nil, err
+		}
+		opts.Path = dir
+	}
+	return Scan(ctx, opts)
+}
+
+// cloneRepo shallow-clones the repository at repo.URL into dir
+func cloneRepo(ctx context.Context, repo Repo, dir string) error {
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if repo.Ref != "" {
+		args = append(args, "--branch", repo.Ref)
+	}
+	args = append(args, "--", repo.URL, dir)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// Fail instead of waiting for credentials nobody can type in a batch job
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git clone %s: %s", repo.URL, msg)
+		}
+		return fmt.Errorf("git clone %s: %w", repo.URL, err)
+	}
+	return nil
+}
+
+// isGitURL reports whether source is a git URL (https://..., ssh://..., git@host:path) rather than a path
+func isGitURL(source string) bool {
+	if strings.Contains(source, "://") {
+		return true
+	}
+	// scp-like syntax: user@host:path
+	at, colon := strings.Index(source, "@"), strings.Index(source, ":")
+	return at > 0 && colon > at && !strings.ContainsAny(source[:at], `/\`)
+}
+
+// repoName returns the default name of a repository: the base name of its path or URL, without .git
+func repoName(repo Repo) string {
+	if repo.URL != "" {
+		source := strings.TrimSuffix(strings.TrimRight(repo.URL, "/"), ".git")
+		if i := strings.LastIndex(source, ":"); i > strings.LastIndex(source, "/") {
+			source = source[i+1:]
+		}
+		return path.Base(source)
+	}
+	name := filepath.Base(repo.Path)
+	if abs, err := filepath.Abs(repo.Path); err == nil {
+		name = filepath.Base(abs)
+	}
+	return name
+}
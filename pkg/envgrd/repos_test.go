@@ -0,0 +1,75 @@
+package envgrd
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRepos(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "repos.yaml")
+	writeFile(t, file, `concurrency: 2
+repos:
+  - services/billing
+  - https://github.com/acme/api.git
+  - git@github.com:acme/web.git
+  - name: legacy
+    url: https://github.com/acme/web-legacy.git
+    ref: v1
+`)
+	repos, concurrency, err := LoadRepos(file)
+	if err != nil {
+		t.Fatalf("LoadRepos failed: %v", err)
+	}
+	expected := []Repo{
+		{Name: "billing", Path: filepath.Join(tmpDir, "services", "billing")},
+		{Name: "api", URL: "https://github.com/acme/api.git"},
+		{Name: "web", URL: "git@github.com:acme/web.git"},
+		{Name: "legacy", URL: "https://github.com/acme/web-legacy.git", Ref: "v1"},
+	}
+	if !reflect.DeepEqual(repos, expected) || concurrency != 2 {
+		t.Errorf("LoadRepos() = %+v, %d, want %+v, 2", repos, concurrency, expected)
+	}
+
+	for _, content := range []string{
+		"repos: []\n",
+		"repos:\n  - name: empty\n",
+		"repos:\n  - path: a\n    ref: main\n",
+		"repos:\n  - a/api\n  - b/api\n",
+	} {
+		writeFile(t, file, content)
+		if _, _, err := LoadRepos(file); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestScanRepos(t *testing.T) {
+	skipWithoutLanguage(t, "go", "javascript")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "billing", "main.go"), "package main\n\nimport \"os\"\n\nfunc main() {\n\t_ = os.Getenv(\"STRIPE_KEY\")\n}\n")
+	writeFile(t, filepath.Join(tmpDir, "api", "app.js"), "const port = process.env.PORT;\n")
+	writeFile(t, filepath.Join(tmpDir, "api", ".env"), "PORT=8080\n")
+	repos := []Repo{
+		{Name: "billing", Path: filepath.Join(tmpDir, "billing")},
+		{Name: "api", Path: filepath.Join(tmpDir, "api")},
+		{Name: "gone", URL: "file://" + filepath.ToSlash(filepath.Join(tmpDir, "gone.git"))},
+	}
+
+	results := ScanRepos(context.Background(), repos, Options{}, 2)
+	if len(results) != 3 {
+		t.Fatalf("Expected a result per repo, got %+v", results)
+	}
+	if r := results[0]; r.Err != nil || len(r.Result.Missing) != 1 || r.Result.Missing["STRIPE_KEY"] == nil {
+		t.Errorf("Expected STRIPE_KEY to be missing in billing, got %+v", r)
+	}
+	if r := results[1]; r.Err != nil || len(r.Result.Missing) != 0 || r.Repo.Name != "api" {
+		t.Errorf("Expected api to be clean, got %+v", r)
+	}
+	if _, err := exec.LookPath("git"); err == nil && results[2].Err == nil {
+		t.Error("Expected an error for a repository that can't be cloned")
+	}
+}
@@ -0,0 +1,109 @@
+//go:build cgo && !envgrd_purego
+
+package envgrd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenian/envgrd/internal/languages"
+	"github.com/jenian/envgrd/internal/scanner"
+)
+
+// Package constants are only resolved by the tree-sitter parsers, not by the regex ones of purego builds
+func TestEngine_ParseFiles_PackageConstants(t *testing.T) {
+	skipWithoutLanguage(t, "go")
+	tmpDir := t.TempDir()
+	keys := filepath.Join(tmpDir, "config", "keys.go")
+	config := filepath.Join(tmpDir, "config", "config.go")
+	other := filepath.Join(tmpDir, "other", "main.go")
+	writeFile(t, keys, "package config\n\nconst envDatabaseURL = \"DATABASE_URL\"\n")
+	writeFile(t, config, "package config\n\nimport \"os\"\n\nvar url = os.Getenv(envDatabaseURL)\n")
+	writeFile(t, other, "package main\n\nimport \"os\"\n\nvar url = os.Getenv(envDatabaseURL)\n")
+	var files []FileInfo
+	for _, path := range []string{keys, config, other} {
+		files = append(files, FileInfo{Path: path, Language: scanner.LanguageGo})
+	}
+
+	usages, _ := NewEngine(Options{}).ParseFiles(context.Background(), files, tmpDir)
+	if len(usages) != 2 {
+		t.Fatalf("Expected 2 usages, got %+v", usages)
+	}
+	for _, usage := range usages {
+		// Constants only resolve within their own package
+		resolved := usage.File == filepath.Join("config", "config.go")
+		if resolved && (usage.Key != "DATABASE_URL" || usage.IsVarRef) {
+			t.Errorf("Expected envDatabaseURL to resolve to DATABASE_URL, got %+v", usage)
+		}
+		if !resolved && (usage.Key != "envDatabaseURL" || !usage.IsVarRef) {
+			t.Errorf("Expected an unresolved variable reference in another package, got %+v", usage)
+		}
+	}
+}
+
+// Languages registered with a grammar need tree-sitter
+func TestScan_ParseErrors(t *testing.T) {
+	skipWithoutLanguage(t, "javascript")
+	// A language whose query doesn't compile makes every file of it unanalyzable
+	RegisterLanguage("broken-test", languages.GetLanguageInfo("javascript").Grammar, "((unclosed", nil, ".brokentest")
+
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "app.js"), "process.env.API_KEY;\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "bad.brokentest"), "process.env.OTHER;\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(result.Missing) != 1 {
+		t.Errorf("Expected app.js to still be analyzed, got missing %v", result.Missing)
+	}
+	if len(result.ParseErrors) != 1 {
+		t.Fatalf("Expected 1 parse error, got %+v", result.ParseErrors)
+	}
+	parseError := result.ParseErrors[0]
+	if parseError.File != filepath.Join("src", "bad.brokentest") || parseError.Language != "broken-test" || !strings.Contains(parseError.Error, "failed to compile broken-test query") {
+		t.Errorf("Unexpected parse error: %+v", parseError)
+	}
+}
+
+// The types code parses values as are only inferred by the tree-sitter parsers
+func TestScan_TypeMismatches(t *testing.T) {
+	skipWithoutLanguage(t, "python")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "PORT=abc\nWORKERS=4\n")
+	writeFile(t, filepath.Join(tmpDir, "main.py"), "import os\nport = int(os.environ['PORT'])\nworkers = int(os.environ['WORKERS'])\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.TypeMismatches) != 1 || result.TypeMismatches[0].Key != "PORT" || result.TypeMismatches[0].Type != "integer" {
+		t.Fatalf("Expected PORT to mismatch integer, got %+v", result.TypeMismatches)
+	}
+	if code := result.ExitCode(FailOnAny, false, false); code != ExitType {
+		t.Errorf("Expected exit code %d, got %d", ExitType, code)
+	}
+}
+
+// Resolved values are checked through the type PORT is parsed as
+func TestScan_ValueReferences(t *testing.T) {
+	skipWithoutLanguage(t, "python")
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".env"), "BASE_PORT=80abc\nPORT=${BASE_PORT}\nWORKERS=${WORKER_COUNT:-4}\nDATABASE_URL=postgres://${DB_HOST}/app\n")
+	writeFile(t, filepath.Join(tmpDir, "main.py"), "import os\nport = int(os.environ['PORT'])\nworkers = int(os.environ['WORKERS'])\nurl = os.environ['DATABASE_URL']\n")
+
+	result, err := Scan(context.Background(), Options{Path: tmpDir})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(result.TypeMismatches) != 1 || result.TypeMismatches[0].Key != "PORT" || result.TypeMismatches[0].Definitions[0].Value != "80abc" {
+		t.Errorf("Expected PORT to resolve to a value that isn't an integer, got %+v", result.TypeMismatches)
+	}
+	if len(result.UnresolvedRefs) != 1 || result.UnresolvedRefs[0].Key != "DATABASE_URL" || result.UnresolvedRefs[0].References[0] != "DB_HOST" {
+		t.Fatalf("Expected DATABASE_URL to reference an undefined DB_HOST, got %+v", result.UnresolvedRefs)
+	}
+}
@@ -0,0 +1,42 @@
+package envgrd
+
+import (
+	"context"
+
+	"github.com/jenian/envgrd/internal/analyzer"
+	"github.com/jenian/envgrd/internal/logging"
+)
+
+// InvalidValue is an env file value that fails the validation of its variable
+type InvalidValue = analyzer.InvalidValue
+
+// Validate loads the env files a scan with opts reads (those of Path and of its nested configs) and checks
+// their values against the schema (see Options.Schema), the declarations of env file comments (# type: int)
+// and the formats names imply (e.g., url for *_URL, port for *_PORT), without parsing any code. It returns
+// the invalid values sorted by key
+func Validate(ctx context.Context, opts Options) ([]InvalidValue, error) {
+	logger := logging.OrDiscard(opts.Logger)
+	absPath, err := resolveRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg := loadScanConfig(opts, absPath, logger)
+
+	variableSchema, err := loadSchema(opts, cfg, absPath)
+	if err != nil {
+		return nil, err
+	}
+	envLoader, err := newEnvLoader(opts, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	envData, err := loadEnvironmentVariables(ctx, envLoader, absPath, cfg, nil)
+	if err == nil {
+		err = loadScopedEnvironments(ctx, absPath, opts, cfg, envData, nil, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	variableSchema = variableSchema.Merge(commentDeclarations(absPath, envData.definitions, logger))
+	return analyzer.ValidateValues(envData.definitions, variableSchema, absPath), nil
+}